@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+// configEncryptionKeyring is an OffchainKeyring that can only decrypt the
+// shared secret. The OffchainPublicKey merely identifies the oracle in the
+// config, we don't hold the corresponding secret key.
+type configEncryptionKeyring struct {
+	offchainPublicKey types.OffchainPublicKey
+	secretKey         [curve25519.ScalarSize]byte
+}
+
+var _ types.OffchainKeyring = configEncryptionKeyring{}
+
+func (k configEncryptionKeyring) OffchainSign(msg []byte) ([]byte, error) {
+	return nil, fmt.Errorf("configEncryptionKeyring cannot sign")
+}
+
+func (k configEncryptionKeyring) ConfigDiffieHellman(point [curve25519.PointSize]byte) ([curve25519.PointSize]byte, error) {
+	var sharedPoint [curve25519.PointSize]byte
+	p, err := curve25519.X25519(k.secretKey[:], point[:])
+	if err != nil {
+		return sharedPoint, err
+	}
+	copy(sharedPoint[:], p)
+	return sharedPoint, nil
+}
+
+func (k configEncryptionKeyring) OffchainPublicKey() types.OffchainPublicKey {
+	return k.offchainPublicKey
+}
+
+func (k configEncryptionKeyring) ConfigEncryptionPublicKey() types.ConfigEncryptionPublicKey {
+	var pk types.ConfigEncryptionPublicKey
+	p, err := curve25519.X25519(k.secretKey[:], curve25519.Basepoint)
+	if err != nil {
+		// assertion
+		panic(fmt.Sprintf("unexpected error during curve25519.X25519: %v", err))
+	}
+	copy(pk[:], p)
+	return pk
+}
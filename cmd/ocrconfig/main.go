@@ -0,0 +1,238 @@
+// Command ocrconfig decodes, diffs and re-encrypts OCR2/OCR3 contract configs.
+//
+// Usage:
+//
+//	ocrconfig decode [-skip-resource-exhaustion-checks] <config.json>
+//	ocrconfig diff [-skip-resource-exhaustion-checks] <old.json> <new.json>
+//	ocrconfig reencrypt -keys <keys.json> (-oracle <index> -secret-key <file> | -fresh-secret) <config.json>
+//
+// Config files contain a ContractConfig as JSON, see
+// configinspect.ContractConfigJSON. Use - to read a config from stdin.
+//
+// reencrypt writes a new config to stdout, which is identical to the given
+// one except that its shared secret is encrypted for the
+// ConfigEncryptionPublicKeys in keys.json, a JSON array of hex strings with
+// one key per oracle. The existing shared secret is decrypted with the hex
+// X25519 secret key in the -secret-key file, which must belong to the oracle
+// at the given index. With -fresh-secret, a new shared secret is generated
+// instead, which is necessary if the replaced key was compromised. A diff
+// against the given config is written to stderr for review.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/configinspect"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage:\n")
+	fmt.Fprintf(os.Stderr, "  %s decode [-skip-resource-exhaustion-checks] <config.json>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s diff [-skip-resource-exhaustion-checks] <old.json> <new.json>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s reencrypt -keys <keys.json> (-oracle <index> -secret-key <file> | -fresh-secret) <config.json>\n", os.Args[0])
+	os.Exit(2)
+}
+
+func readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
+
+func readContractConfig(path string) (types.ContractConfig, error) {
+	b, err := readFile(path)
+	if err != nil {
+		return types.ContractConfig{}, err
+	}
+	contractConfig, err := configinspect.ParseContractConfigJSON(b)
+	if err != nil {
+		return types.ContractConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return contractConfig, nil
+}
+
+func readConfig(path string, skipResourceExhaustionChecks bool) (configinspect.Config, error) {
+	contractConfig, err := readContractConfig(path)
+	if err != nil {
+		return configinspect.Config{}, err
+	}
+	config, err := configinspect.Decode(skipResourceExhaustionChecks, contractConfig)
+	if err != nil {
+		return configinspect.Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func readConfigEncryptionPublicKeys(path string) ([]types.ConfigEncryptionPublicKey, error) {
+	b, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hexKeys []string
+	if err := json.Unmarshal(b, &hexKeys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	keys := []types.ConfigEncryptionPublicKey{}
+	for i, hexKey := range hexKeys {
+		key, err := decodeHex(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid key %d: %w", path, i, err)
+		}
+		var pk types.ConfigEncryptionPublicKey
+		if len(key) != len(pk) {
+			return nil, fmt.Errorf("%s: key %d has length %d, expected %d", path, i, len(key), len(pk))
+		}
+		copy(pk[:], key)
+		keys = append(keys, pk)
+	}
+	return keys, nil
+}
+
+func readKeyring(path string, oracle int, config configinspect.Config) (configEncryptionKeyring, error) {
+	if !(0 <= oracle && oracle < len(config.OracleIdentities)) {
+		return configEncryptionKeyring{}, fmt.Errorf("oracle index %d out of range, config has %d oracles", oracle, len(config.OracleIdentities))
+	}
+	b, err := readFile(path)
+	if err != nil {
+		return configEncryptionKeyring{}, err
+	}
+	sk, err := decodeHex(string(b))
+	if err != nil {
+		return configEncryptionKeyring{}, fmt.Errorf("%s: %w", path, err)
+	}
+	k := configEncryptionKeyring{offchainPublicKey: config.OracleIdentities[oracle].OffchainPublicKey}
+	if len(sk) != len(k.secretKey) {
+		return configEncryptionKeyring{}, fmt.Errorf("%s: secret key has length %d, expected %d", path, len(sk), len(k.secretKey))
+	}
+	copy(k.secretKey[:], sk)
+	return k, nil
+}
+
+func decode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	skipResourceExhaustionChecks := fs.Bool("skip-resource-exhaustion-checks", false, "decode configs that oracles would reject for exhausting resources")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	config, err := readConfig(fs.Arg(0), *skipResourceExhaustionChecks)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	skipResourceExhaustionChecks := fs.Bool("skip-resource-exhaustion-checks", false, "decode configs that oracles would reject for exhausting resources")
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+
+	from, err := readConfig(fs.Arg(0), *skipResourceExhaustionChecks)
+	if err != nil {
+		return err
+	}
+	to, err := readConfig(fs.Arg(1), *skipResourceExhaustionChecks)
+	if err != nil {
+		return err
+	}
+	fmt.Print(configinspect.FormatDiff(from, to))
+	return nil
+}
+
+func reencrypt(args []string) error {
+	fs := flag.NewFlagSet("reencrypt", flag.ExitOnError)
+	keysPath := fs.String("keys", "", "JSON file with the ConfigEncryptionPublicKeys of all oracles")
+	oracle := fs.Int("oracle", -1, "index of the oracle whose secret key is given")
+	secretKeyPath := fs.String("secret-key", "", "file with the hex X25519 config encryption secret key of the oracle")
+	freshSecret := fs.Bool("fresh-secret", false, "generate a new shared secret instead of re-encrypting the existing one")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || *keysPath == "" || (*freshSecret == (*secretKeyPath != "")) {
+		usage()
+	}
+
+	contractConfig, err := readContractConfig(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	keys, err := readConfigEncryptionPublicKeys(*keysPath)
+	if err != nil {
+		return err
+	}
+
+	var (
+		newContractConfig types.ContractConfig
+		diff              string
+	)
+	if *freshSecret {
+		newContractConfig, diff, err = configinspect.FreshSharedSecret(contractConfig, keys, rand.Reader)
+	} else {
+		var from configinspect.Config
+		from, err = configinspect.Decode(true, contractConfig)
+		if err != nil {
+			return err
+		}
+		var keyring configEncryptionKeyring
+		keyring, err = readKeyring(*secretKeyPath, *oracle, from)
+		if err != nil {
+			return err
+		}
+		newContractConfig, diff, err = configinspect.ReencryptSharedSecret(contractConfig, keyring, keys)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := configinspect.ContractConfigToJSON(newContractConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	fmt.Fprint(os.Stderr, diff)
+	return nil
+}
+
+func run(args []string) error {
+	if len(args) < 1 {
+		usage()
+	}
+	switch args[0] {
+	case "decode":
+		return decode(args[1:])
+	case "diff":
+		return diff(args[1:])
+	case "reencrypt":
+		return reencrypt(args[1:])
+	default:
+		usage()
+		return nil
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+// keyFile is the format of the key file. All keys are hex-encoded.
+type keyFile struct {
+	// Ed25519 seed
+	OffchainSigningKey string `json:"offchainSigningKey"`
+	// X25519 scalar
+	ConfigEncryptionKey string `json:"configEncryptionKey"`
+	// secp256k1 secret key
+	EVMSigningKey string `json:"evmSigningKey"`
+}
+
+type keys struct {
+	offchainSigningKey  ed25519.PrivateKey
+	configEncryptionKey [curve25519.ScalarSize]byte
+	evmSigningKey       *ecdsa.PrivateKey
+}
+
+func decodeFixedHex(name string, s string, size int) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("%s has length %d, expected %d", name, len(b), size)
+	}
+	return b, nil
+}
+
+func readKeyFile(path string) (keys, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return keys{}, err
+	}
+	var kf keyFile
+	if err := json.Unmarshal(b, &kf); err != nil {
+		return keys{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seed, err := decodeFixedHex("offchainSigningKey", kf.OffchainSigningKey, ed25519.SeedSize)
+	if err != nil {
+		return keys{}, err
+	}
+	configEncryptionKey, err := decodeFixedHex("configEncryptionKey", kf.ConfigEncryptionKey, curve25519.ScalarSize)
+	if err != nil {
+		return keys{}, err
+	}
+	evmSigningKey, err := crypto.HexToECDSA(kf.EVMSigningKey)
+	if err != nil {
+		return keys{}, fmt.Errorf("invalid evmSigningKey: %w", err)
+	}
+
+	k := keys{
+		ed25519.NewKeyFromSeed(seed),
+		[curve25519.ScalarSize]byte{},
+		evmSigningKey,
+	}
+	copy(k.configEncryptionKey[:], configEncryptionKey)
+	return k, nil
+}
+
+// generateKeyFile writes fresh keys to path, which must not exist yet.
+func generateKeyFile(path string) error {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return err
+	}
+	configEncryptionKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(configEncryptionKey); err != nil {
+		return err
+	}
+	evmSigningKey, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(keyFile{
+		hex.EncodeToString(seed),
+		hex.EncodeToString(configEncryptionKey),
+		hex.EncodeToString(crypto.FromECDSA(evmSigningKey)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// offchainKeyring is a types.OffchainKeyring backed by keys from the key file.
+type offchainKeyring struct {
+	signingKey    ed25519.PrivateKey
+	encryptionKey [curve25519.ScalarSize]byte
+}
+
+var _ types.OffchainKeyring = offchainKeyring{}
+
+func (kr offchainKeyring) OffchainSign(msg []byte) (signature []byte, err error) {
+	return ed25519.Sign(kr.signingKey, msg), nil
+}
+
+func (kr offchainKeyring) ConfigDiffieHellman(point [curve25519.PointSize]byte) (sharedPoint [curve25519.PointSize]byte, err error) {
+	p, err := curve25519.X25519(kr.encryptionKey[:], point[:])
+	if err != nil {
+		return [curve25519.PointSize]byte{}, err
+	}
+	copy(sharedPoint[:], p)
+	return sharedPoint, nil
+}
+
+func (kr offchainKeyring) OffchainPublicKey() types.OffchainPublicKey {
+	var pk types.OffchainPublicKey
+	copy(pk[:], kr.signingKey.Public().(ed25519.PublicKey))
+	return pk
+}
+
+func (kr offchainKeyring) ConfigEncryptionPublicKey() types.ConfigEncryptionPublicKey {
+	pk, err := curve25519.X25519(kr.encryptionKey[:], curve25519.Basepoint)
+	if err != nil {
+		// assertion
+		panic(err)
+	}
+	var result types.ConfigEncryptionPublicKey
+	copy(result[:], pk)
+	return result
+}
@@ -0,0 +1,190 @@
+// Command ocrsignerd is a reference remote signer daemon for oracles using
+// remotesigner.Client. It serves the keys in a key file on a Unix socket,
+// refuses to sign conflicting reports for the same (ConfigDigest, epoch,
+// round) or, for OCR3, (ConfigDigest, seqNr) and appends every operation to
+// an audit log. The reports signed are recorded in a watermark file (see
+// signguard), so conflicting reports are refused across restarts.
+//
+// Usage:
+//
+//	ocrsignerd -generate-key-file <keys.json>
+//	ocrsignerd -create-watermark-file <watermarks>
+//	ocrsignerd -key-file <keys.json> -socket <path> -audit-log <path> -watermark-file <watermarks> \
+//		[-approver <network>:<address> ... -approval-threshold <k> [-approval-timeout <duration>]]
+//	ocrsignerd -approve-only -socket <path> -audit-log <path> -watermark-file <watermarks>
+//
+// For threshold signing, run n approvers with -approve-only, each with its
+// own watermark file and ideally on its own host, and pass them to the key
+// holder with -approver, e.g. -approver unix:/run/approver1.sock. The key
+// holder then only signs onchain reports approved by at least
+// -approval-threshold approvers (see remotesigner.Approvals).
+//
+// The socket is only accessible to the user running ocrsignerd, so the
+// oracle should run as the same user or access the socket through a group
+// set up by the operator. Onchain signatures use secp256k1 as expected by
+// OCR2Aggregator-style EVM contracts. OCR3 reports are mapped onto report
+// contexts with evmutil.SeqNrReportContext, so oracles must use
+// remotesigner.NewOCR3OnchainKeyring with the same encoding.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/chains/evmutil"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/remotesigner"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/signguard"
+	"github.com/smartcontractkit/libocr/ragep2p/loggers"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// listenUnix listens on a Unix socket at path that only the current user can
+// access. A stale socket left behind by an earlier run is removed.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	oldUmask := syscall.Umask(0o177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldUmask)
+	return listener, err
+}
+
+// approverFlag collects the values of the repeatable -approver flag.
+type approverFlag []remotesigner.ApproverAddress
+
+func (f *approverFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *approverFlag) Set(value string) error {
+	network, address, ok := strings.Cut(value, ":")
+	if !ok || network == "" || address == "" {
+		return fmt.Errorf("approver %q isn't of the form <network>:<address>", value)
+	}
+	*f = append(*f, remotesigner.ApproverAddress{network, address})
+	return nil
+}
+
+func run() error {
+	generateKeyFilePath := flag.String("generate-key-file", "", "generate a new key file at this path and exit")
+	keyFilePath := flag.String("key-file", "", "path of the key file")
+	socketPath := flag.String("socket", "", "path of the Unix socket to listen on")
+	auditLogPath := flag.String("audit-log", "", "path of the audit log")
+	createWatermarkFilePath := flag.String("create-watermark-file", "", "create a new watermark file at this path and exit")
+	watermarkFilePath := flag.String("watermark-file", "", "path of the watermark file used to refuse conflicting reports")
+	approveOnly := flag.Bool("approve-only", false, "hold no keys and only approve signings for another ocrsignerd")
+	var approvers approverFlag
+	flag.Var(&approvers, "approver", "<network>:<address> of an approver, may be repeated")
+	approvalThreshold := flag.Int("approval-threshold", 0, "number of approvers that must approve every onchain signing")
+	approvalTimeout := flag.Duration("approval-timeout", 5*time.Second, "timeout for dialing and calling an approver")
+	flag.Parse()
+
+	logger := loggers.MakeLogrusLogger()
+
+	if *generateKeyFilePath != "" {
+		return generateKeyFile(*generateKeyFilePath)
+	}
+	if *createWatermarkFilePath != "" {
+		guard, err := signguard.Create(*createWatermarkFilePath, logger)
+		if err != nil {
+			return err
+		}
+		return guard.Close()
+	}
+	if *socketPath == "" || *auditLogPath == "" || *watermarkFilePath == "" ||
+		*approveOnly != (*keyFilePath == "") || (*approveOnly && len(approvers) != 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	auditLog, err := remotesigner.OpenFileAuditLog(*auditLogPath)
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+
+	guard, err := signguard.Open(*watermarkFilePath, logger)
+	if err != nil {
+		return err
+	}
+	defer guard.Close()
+
+	var server *remotesigner.Server
+	logFields := commontypes.LogFields{"socket": *socketPath}
+	if *approveOnly {
+		server, err = remotesigner.NewApprover(guard, auditLog, logger)
+		if err != nil {
+			return err
+		}
+		logFields["approveOnly"] = true
+	} else {
+		keys, err := readKeyFile(*keyFilePath)
+		if err != nil {
+			return err
+		}
+		offchainKeyring := offchainKeyring{keys.offchainSigningKey, keys.configEncryptionKey}
+		onchainKeyring := evmutil.NewEVMOnchainKeyring(keys.evmSigningKey)
+		server, err = remotesigner.NewServer(
+			offchainKeyring,
+			onchainKeyring,
+			evmutil.NewOCR3OnchainKeyring[struct{}](onchainKeyring, evmutil.SeqNrReportContext[struct{}]),
+			guard,
+			remotesigner.Approvals{approvers, *approvalThreshold, *approvalTimeout},
+			auditLog,
+			logger,
+		)
+		if err != nil {
+			return err
+		}
+
+		offchainPublicKey := offchainKeyring.OffchainPublicKey()
+		configEncryptionPublicKey := offchainKeyring.ConfigEncryptionPublicKey()
+		logFields["offchainPublicKey"] = fmt.Sprintf("%x", offchainPublicKey[:])
+		logFields["configEncryptionPublicKey"] = fmt.Sprintf("%x", configEncryptionPublicKey[:])
+		logFields["onchainPublicKey"] = fmt.Sprintf("%x", []byte(onchainKeyring.PublicKey()))
+		logFields["approvalThreshold"] = *approvalThreshold
+	}
+
+	listener, err := listenUnix(*socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socketPath)
+
+	logger.Info("Remote signer serving", logFields)
+
+	chSignals := make(chan os.Signal, 1)
+	signal.Notify(chSignals, syscall.SIGINT, syscall.SIGTERM)
+	chServeErr := make(chan error, 1)
+	go func() {
+		chServeErr <- server.Serve(listener)
+	}()
+
+	select {
+	case sig := <-chSignals:
+		logger.Info("Remote signer shutting down", commontypes.LogFields{"signal": sig.String()})
+		return server.Close()
+	case err := <-chServeErr:
+		server.Close()
+		return err
+	}
+}
@@ -23,7 +23,9 @@ type BootstrapperLocator struct {
 	PeerID string
 
 	// Addrs contains the addresses of the bootstrapper. An address must be of the form "<host>:<port>",
-	// such as "52.49.198.28:80" or "chain.link:443".
+	// such as "52.49.198.28:80" or "chain.link:443", or the name of an SRV record, such as
+	// "_ragep2p._tcp.chain.link". Hostnames and SRV records are re-resolved periodically, and all
+	// addresses they resolve to are dialed.
 	Addrs []string
 }
 
@@ -32,6 +34,9 @@ func NewBootstrapperLocator(peerID string, addrs []string) (*BootstrapperLocator
 		return nil, fmt.Errorf("invalid peer id (%q): %w", peerID, err)
 	}
 	for _, address := range addrs {
+		if isSRVName(address) {
+			continue
+		}
 		_, _, err := net.SplitHostPort(address)
 		if err != nil {
 			return nil, fmt.Errorf("invalid address (%q) for bootstrapper (%q): %w", address, peerID, err)
@@ -40,6 +45,12 @@ func NewBootstrapperLocator(peerID string, addrs []string) (*BootstrapperLocator
 	return &BootstrapperLocator{peerID, addrs}, nil
 }
 
+// SRV names start with an underscore and have no port, e.g.
+// "_ragep2p._tcp.chain.link".
+func isSRVName(address string) bool {
+	return strings.HasPrefix(address, "_") && !strings.Contains(address, ":") && len(address) > 1
+}
+
 func (b *BootstrapperLocator) MarshalText() ([]byte, error) {
 	var bs bytes.Buffer
 	bs.WriteString(b.PeerID)
@@ -0,0 +1,636 @@
+package networking
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	ocr2types "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/ragep2p"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+	"github.com/smartcontractkit/libocr/subprocesses"
+)
+
+// MultiplexingConfigV2 configures multiplexing of OCR instances. Without
+// multiplexing, every OCR instance has its own ragep2p stream to every other
+// oracle. With multiplexing, all OCR instances share a single ragep2p stream
+// per remote peer, and outgoing messages of different instances are coalesced
+// into batches. This saves a lot of per-stream and per-message overhead on
+// nodes that run many OCR instances.
+//
+// Multiplexing must be enabled on both ends: a peer with multiplexing enabled
+// cannot communicate with a peer that has it disabled.
+type MultiplexingConfigV2 struct {
+	Enabled bool
+
+	// Outgoing messages are held back for up to BatchInterval, so that they
+	// can be coalesced with messages of other instances. Zero means that we
+	// only coalesce messages that are already queued.
+	BatchInterval time.Duration
+
+	// MaxBatchLength is the maximum length of a batch in bytes. Must be large
+	// enough to hold the largest message of any instance. Zero means
+	// DefaultMultiplexingMaxBatchLength.
+	//
+	// The traffic with a remote peer, including the length of batches, is
+	// additionally limited by the combined BinaryNetworkEndpointLimits of
+	// the instances with that peer.
+	MaxBatchLength int
+}
+
+const DefaultMultiplexingMaxBatchLength = 64 * 1024 * 1024
+
+func (c MultiplexingConfigV2) withDefaults() MultiplexingConfigV2 {
+	if c.MaxBatchLength == 0 {
+		c.MaxBatchLength = DefaultMultiplexingMaxBatchLength
+	}
+	return c
+}
+
+func (c MultiplexingConfigV2) check() error {
+	if c.BatchInterval < 0 {
+		return fmt.Errorf("BatchInterval (%v) must not be negative", c.BatchInterval)
+	}
+	if !(0 <= c.MaxBatchLength && c.MaxBatchLength <= ragep2p.MaxMessageLength) {
+		return fmt.Errorf("MaxBatchLength (%v) must be between 0 and %v", c.MaxBatchLength, ragep2p.MaxMessageLength)
+	}
+	return nil
+}
+
+const multiplexedStreamName = "ocr/mux"
+
+// A batch is a concatenation of entries. Each entry consists of the config
+// digest of the instance, the big-endian uint32 length of the message, and
+// the message itself.
+const multiplexedEntryHeaderSize = len(ocr2types.ConfigDigest{}) + 4
+
+func appendMultiplexedEntry(batch []byte, configDigest ocr2types.ConfigDigest, msg []byte) []byte {
+	batch = append(batch, configDigest[:]...)
+	batch = binary.BigEndian.AppendUint32(batch, uint32(len(msg)))
+	return append(batch, msg...)
+}
+
+// multiplexedStreamLimits are the limits of the shared stream with a remote
+// peer.
+type multiplexedStreamLimits struct {
+	maxBatchLength int
+	batchesLimit   ragep2p.TokenBucketParams
+	bytesLimit     ragep2p.TokenBucketParams
+}
+
+func clampCapacity(capacity float64) uint32 {
+	return uint32(min(capacity, math.MaxUint32))
+}
+
+// deriveMultiplexedStreamLimits sums up the limits of instances. Every batch
+// contains at least one message and every message comes with an entry
+// header.
+//
+// ragep2p enforces these limits before reading a batch, just like it enforces
+// the limits of an instance's own stream without multiplexing. The limits of
+// each instance are enforced again when dispatching the entries of a batch,
+// so that one instance can't use up the others' share. The limits are
+// updated whenever an instance is added or removed. While two peers have
+// different sets of instances, e.g. during a config change, some batches may
+// exceed the limits of the receiver and are dropped.
+func deriveMultiplexedStreamLimits(config MultiplexingConfigV2, instances []BinaryNetworkEndpointLimits) multiplexedStreamLimits {
+	var messagesRate, messagesCapacity, bytesRate, bytesCapacity float64
+	maxEntryLength := 0
+	for _, limits := range instances {
+		messagesRate += limits.MessagesRatePerOracle
+		messagesCapacity += float64(limits.MessagesCapacityPerOracle)
+		bytesRate += limits.BytesRatePerOracle + float64(multiplexedEntryHeaderSize)*limits.MessagesRatePerOracle
+		bytesCapacity += float64(limits.BytesCapacityPerOracle + multiplexedEntryHeaderSize*limits.MessagesCapacityPerOracle)
+		maxEntryLength = max(maxEntryLength, multiplexedEntryHeaderSize+limits.MaxMessageLength)
+	}
+	// a batch holding just the largest message must always be possible,
+	// like without multiplexing
+	maxBatchLength := max(int(min(bytesCapacity, float64(config.MaxBatchLength))), maxEntryLength)
+	return multiplexedStreamLimits{
+		maxBatchLength,
+		ragep2p.TokenBucketParams{messagesRate, clampCapacity(messagesCapacity)},
+		ragep2p.TokenBucketParams{bytesRate, clampCapacity(bytesCapacity)},
+	}
+}
+
+func decodeMultiplexedEntry(batch []byte) (configDigest ocr2types.ConfigDigest, msg []byte, rest []byte, err error) {
+	if len(batch) < multiplexedEntryHeaderSize {
+		return ocr2types.ConfigDigest{}, nil, nil, fmt.Errorf("truncated entry header")
+	}
+	copy(configDigest[:], batch)
+	length := binary.BigEndian.Uint32(batch[len(configDigest):multiplexedEntryHeaderSize])
+	batch = batch[multiplexedEntryHeaderSize:]
+	if uint64(len(batch)) < uint64(length) {
+		return ocr2types.ConfigDigest{}, nil, nil, fmt.Errorf("truncated entry payload")
+	}
+	return configDigest, batch[:length], batch[length:], nil
+}
+
+// multiplexerV2 owns the shared streams of a concretePeerV2 with multiplexing
+// enabled.
+type multiplexerV2 struct {
+	config                  MultiplexingConfigV2
+	host                    *ragep2p.Host
+	outgoingBatchBufferSize int
+	incomingBatchBufferSize int
+	logger                  loghelper.LoggerWithContext
+
+	mutex  sync.Mutex
+	peers  map[ragetypes.PeerID]*multiplexedPeerV2
+	closed bool
+}
+
+func newMultiplexerV2(config MultiplexingConfigV2, host *ragep2p.Host, endpointConfig EndpointConfigV2, logger loghelper.LoggerWithContext) *multiplexerV2 {
+	return &multiplexerV2{
+		config.withDefaults(),
+		host,
+		endpointConfig.OutgoingMessageBufferSize,
+		endpointConfig.IncomingMessageBufferSize,
+		logger.MakeChild(commontypes.LogFields{"id": "MultiplexerV2"}),
+
+		sync.Mutex{},
+		make(map[ragetypes.PeerID]*multiplexedPeerV2),
+		false,
+	}
+}
+
+// newStream returns a virtual stream for the instance with configDigest to
+// the remote peer other. The virtual stream is backed by the shared stream
+// to other, which is opened if needed.
+func (m *multiplexerV2) newStream(
+	other ragetypes.PeerID,
+	configDigest ocr2types.ConfigDigest,
+	outgoingBufferSize int,
+	incomingBufferSize int,
+	limits BinaryNetworkEndpointLimits,
+) (*multiplexedStreamV2, error) {
+	if m.config.MaxBatchLength < multiplexedEntryHeaderSize+limits.MaxMessageLength {
+		return nil, fmt.Errorf("MaxMessageLength %v does not fit into MaxBatchLength %v", limits.MaxMessageLength, m.config.MaxBatchLength)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return nil, fmt.Errorf("multiplexer is closed")
+	}
+
+	p, ok := m.peers[other]
+	if !ok {
+		streamLimits := deriveMultiplexedStreamLimits(m.config, []BinaryNetworkEndpointLimits{limits})
+		stream, err := m.host.NewStream(
+			other,
+			multiplexedStreamName,
+			m.outgoingBatchBufferSize,
+			m.incomingBatchBufferSize,
+			streamLimits.maxBatchLength,
+			streamLimits.batchesLimit,
+			streamLimits.bytesLimit,
+		)
+		if err != nil {
+			return nil, err
+		}
+		p = newMultiplexedPeerV2(m.config, stream, streamLimits.maxBatchLength, m.logger)
+		m.peers[other] = p
+	}
+
+	instance, err := p.addInstance(configDigest, outgoingBufferSize, incomingBufferSize, limits)
+	if err != nil {
+		if !ok {
+			// don't keep a peer without instances around
+			delete(m.peers, other)
+			_ = p.close()
+		}
+		return nil, err
+	}
+	return &multiplexedStreamV2{m, p, configDigest, instance, sync.Once{}}, nil
+}
+
+func (m *multiplexerV2) closeStream(s *multiplexedStreamV2) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if s.peer.removeInstance(s.configDigest) || m.closed {
+		// if the multiplexer is closed, the peer is already closed, too
+		return nil
+	}
+	delete(m.peers, s.peer.stream.Other())
+	return s.peer.close()
+}
+
+// close closes the shared streams with all remote peers. Virtual streams
+// that are still open stop sending and receiving, but must still be closed.
+func (m *multiplexerV2) close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return fmt.Errorf("multiplexer already closed")
+	}
+	m.closed = true
+	var err error
+	for other, p := range m.peers {
+		err = errors.Join(err, p.close())
+		delete(m.peers, other)
+	}
+	return err
+}
+
+// multiplexedPeerV2 schedules the outgoing messages of all instances over the
+// shared stream to one remote peer and dispatches incoming messages to the
+// instances.
+type multiplexedPeerV2 struct {
+	config MultiplexingConfigV2
+	stream *ragep2p.Stream
+	logger loghelper.LoggerWithContext
+
+	mutex  sync.Mutex
+	closed bool
+	// the maxBatchLength of the current multiplexedStreamLimits
+	maxBatchLength int
+	instances      map[ocr2types.ConfigDigest]*multiplexedInstanceV2
+	// order of instances for round-robin scheduling
+	order []ocr2types.ConfigDigest
+	// index into order of the instance that goes first in the next batch
+	next int
+	// We taper some logs to prevent an adversary from spamming our logs
+	limitsExceededTaper  loghelper.LogarithmicTaper
+	unknownInstanceTaper loghelper.LogarithmicTaper
+
+	chPending    chan struct{}
+	chClose      chan struct{}
+	subprocesses subprocesses.Subprocesses
+}
+
+// multiplexedInstanceV2 is the state of one instance with one remote peer.
+// Protected by the mutex of the multiplexedPeerV2.
+type multiplexedInstanceV2 struct {
+	limits             BinaryNetworkEndpointLimits
+	outgoing           [][]byte
+	outgoingBufferSize int
+	chIncoming         chan []byte
+	messagesLimiter    *rate.Limiter
+	bytesLimiter       *rate.Limiter
+	messagesSent       uint64
+	messagesReceived   uint64
+}
+
+func newMultiplexedInstanceV2(outgoingBufferSize int, incomingBufferSize int, limits BinaryNetworkEndpointLimits) *multiplexedInstanceV2 {
+	return &multiplexedInstanceV2{
+		limits,
+		nil,
+		outgoingBufferSize,
+		make(chan []byte, incomingBufferSize),
+		rate.NewLimiter(rate.Limit(limits.MessagesRatePerOracle), limits.MessagesCapacityPerOracle),
+		rate.NewLimiter(rate.Limit(limits.BytesRatePerOracle), limits.BytesCapacityPerOracle),
+		0,
+		0,
+	}
+}
+
+func newMultiplexedPeerV2(config MultiplexingConfigV2, stream *ragep2p.Stream, maxBatchLength int, logger loghelper.LoggerWithContext) *multiplexedPeerV2 {
+	p := &multiplexedPeerV2{
+		config,
+		stream,
+		logger.MakeChild(commontypes.LogFields{"remotePeerID": stream.Other()}),
+
+		sync.Mutex{},
+		false,
+		maxBatchLength,
+		make(map[ocr2types.ConfigDigest]*multiplexedInstanceV2),
+		nil,
+		0,
+		loghelper.LogarithmicTaper{},
+		loghelper.LogarithmicTaper{},
+
+		make(chan struct{}, 1),
+		make(chan struct{}),
+		subprocesses.Subprocesses{},
+	}
+	p.subprocesses.Go(p.sendLoop)
+	p.subprocesses.Go(p.receiveLoop)
+	return p
+}
+
+func (p *multiplexedPeerV2) addInstance(
+	configDigest ocr2types.ConfigDigest,
+	outgoingBufferSize int,
+	incomingBufferSize int,
+	limits BinaryNetworkEndpointLimits,
+) (*multiplexedInstanceV2, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.instances[configDigest]; ok {
+		return nil, fmt.Errorf("instance with config digest %v is already multiplexed to %v", configDigest, p.stream.Other())
+	}
+	instance := newMultiplexedInstanceV2(outgoingBufferSize, incomingBufferSize, limits)
+	p.instances[configDigest] = instance
+	p.order = append(p.order, configDigest)
+	if err := p.updateLimitsLocked(); err != nil {
+		delete(p.instances, configDigest)
+		p.order = p.order[:len(p.order)-1]
+		return nil, err
+	}
+	return instance, nil
+}
+
+// updateLimitsLocked sets the limits of the shared stream to those derived
+// from the current instances. Must be called with mutex held and at least
+// one instance.
+func (p *multiplexedPeerV2) updateLimitsLocked() error {
+	instanceLimits := make([]BinaryNetworkEndpointLimits, 0, len(p.instances))
+	for _, configDigest := range p.order {
+		instanceLimits = append(instanceLimits, p.instances[configDigest].limits)
+	}
+	streamLimits := deriveMultiplexedStreamLimits(p.config, instanceLimits)
+	if err := p.stream.UpdateLimits(streamLimits.maxBatchLength, streamLimits.batchesLimit, streamLimits.bytesLimit); err != nil {
+		return fmt.Errorf("failed to update limits of shared stream to %v: %w", p.stream.Other(), err)
+	}
+	p.maxBatchLength = streamLimits.maxBatchLength
+	return nil
+}
+
+// removeInstance removes the instance with configDigest and closes its
+// incoming channel. Returns whether any instances remain.
+func (p *multiplexedPeerV2) removeInstance(configDigest ocr2types.ConfigDigest) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if instance, ok := p.instances[configDigest]; ok {
+		close(instance.chIncoming)
+		delete(p.instances, configDigest)
+	}
+	for i, cd := range p.order {
+		if cd == configDigest {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			if p.next > i {
+				p.next--
+			}
+			break
+		}
+	}
+	if p.next >= len(p.order) {
+		p.next = 0
+	}
+	if len(p.instances) == 0 || p.closed {
+		return len(p.instances) > 0
+	}
+	if err := p.updateLimitsLocked(); err != nil {
+		// the old, larger limits remain in place
+		p.logger.Warn("MultiplexerV2: Failed to lower limits after removing instance", commontypes.LogFields{
+			"error": err,
+		})
+	}
+	return true
+}
+
+func (p *multiplexedPeerV2) close() error {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	close(p.chClose)
+	p.subprocesses.Wait()
+	return p.stream.Close()
+}
+
+// send enqueues msg for the instance with configDigest. If the instance's
+// outgoing buffer is full, the oldest message is dropped.
+func (p *multiplexedPeerV2) send(configDigest ocr2types.ConfigDigest, msg []byte) {
+	p.mutex.Lock()
+	instance, ok := p.instances[configDigest]
+	if ok {
+		if len(instance.outgoing) >= instance.outgoingBufferSize && len(instance.outgoing) > 0 {
+			instance.outgoing = instance.outgoing[1:]
+		}
+		instance.outgoing = append(instance.outgoing, msg)
+	}
+	p.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+	select {
+	case p.chPending <- struct{}{}:
+	default:
+	}
+}
+
+func (p *multiplexedPeerV2) sendLoop() {
+	for {
+		select {
+		case <-p.chPending:
+		case <-p.chClose:
+			return
+		}
+
+		if p.config.BatchInterval > 0 {
+			select {
+			case <-time.After(p.config.BatchInterval):
+			case <-p.chClose:
+				return
+			}
+		}
+
+		for {
+			batch := p.nextBatch()
+			if batch == nil {
+				break
+			}
+			p.stream.SendMessage(batch)
+		}
+	}
+}
+
+// nextBatch takes messages from the instances' outgoing buffers in
+// round-robin order, one message per instance and round, until the batch is
+// full or all buffers are empty. Returns nil if all buffers are empty.
+func (p *multiplexedPeerV2) nextBatch() []byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.order) == 0 {
+		return nil
+	}
+
+	var batch []byte
+	start := p.next
+	for {
+		progress := false
+		for i := 0; i < len(p.order); i++ {
+			j := (start + i) % len(p.order)
+			configDigest := p.order[j]
+			instance := p.instances[configDigest]
+			if len(instance.outgoing) == 0 {
+				continue
+			}
+			msg := instance.outgoing[0]
+			if len(batch) > 0 && len(batch)+multiplexedEntryHeaderSize+len(msg) > p.maxBatchLength {
+				// the instance that didn't fit goes first in the next batch
+				p.next = j
+				return batch
+			}
+			batch = appendMultiplexedEntry(batch, configDigest, msg)
+			instance.outgoing[0] = nil
+			instance.outgoing = instance.outgoing[1:]
+			instance.messagesSent++
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+	p.next = (start + 1) % len(p.order)
+	return batch
+}
+
+func (p *multiplexedPeerV2) receiveLoop() {
+	chReceive := p.stream.ReceiveMessages()
+	for {
+		select {
+		case batch, ok := <-chReceive:
+			if !ok {
+				return
+			}
+			p.dispatch(batch)
+		case <-p.chClose:
+			return
+		}
+	}
+}
+
+// dispatch enforces the limits of each instance and hands each message in
+// batch to its instance. Messages exceeding limits and messages for unknown
+// instances are dropped.
+func (p *multiplexedPeerV2) dispatch(batch []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for len(batch) > 0 {
+		configDigest, msg, rest, err := decodeMultiplexedEntry(batch)
+		if err != nil {
+			p.logger.Warn("MultiplexerV2: Dropping rest of malformed batch", commontypes.LogFields{
+				"error": err,
+			})
+			return
+		}
+		batch = rest
+
+		instance, ok := p.instances[configDigest]
+		if !ok {
+			p.unknownInstanceTaper.Trigger(func(count uint64) {
+				p.logger.Warn("MultiplexerV2: Dropping message for unknown instance", commontypes.LogFields{
+					"configDigest":                configDigest,
+					"unknownInstanceDroppedCount": count,
+				})
+			})
+			continue
+		}
+		if len(msg) > instance.limits.MaxMessageLength {
+			p.limitsExceededTaper.Trigger(func(count uint64) {
+				p.logger.Warn("MultiplexerV2: Message too big, dropping message", commontypes.LogFields{
+					"configDigest":               configDigest,
+					"messageLength":              len(msg),
+					"maxMessageLength":           instance.limits.MaxMessageLength,
+					"limitsExceededDroppedCount": count,
+				})
+			})
+			continue
+		}
+		if !instance.messagesLimiter.AllowN(now, 1) || !instance.bytesLimiter.AllowN(now, len(msg)) {
+			p.limitsExceededTaper.Trigger(func(count uint64) {
+				p.logger.Warn("MultiplexerV2: Rate limit exceeded, dropping message", commontypes.LogFields{
+					"configDigest":               configDigest,
+					"messageLength":              len(msg),
+					"limitsExceededDroppedCount": count,
+				})
+			})
+			continue
+		}
+		p.limitsExceededTaper.Reset(func(oldCount uint64) {
+			p.logger.Info("MultiplexerV2: Limits are no longer being exceeded", commontypes.LogFields{
+				"droppedCount": oldCount,
+			})
+		})
+
+		// Like the ragep2p demuxer, we drop the oldest message if the
+		// incoming buffer is full. We are the only sender on chIncoming.
+		select {
+		case instance.chIncoming <- msg:
+		default:
+			select {
+			case <-instance.chIncoming:
+				p.logger.Trace("MultiplexerV2: Incoming buffer is overflowing, dropping oldest message", commontypes.LogFields{
+					"configDigest": configDigest,
+				})
+			default:
+			}
+			select {
+			case instance.chIncoming <- msg:
+			default:
+			}
+		}
+		instance.messagesReceived++
+	}
+}
+
+// multiplexedStreamV2 is the virtual stream of one instance with one remote
+// peer. It behaves like a ragep2p.Stream.
+type multiplexedStreamV2 struct {
+	multiplexer  *multiplexerV2
+	peer         *multiplexedPeerV2
+	configDigest ocr2types.ConfigDigest
+	instance     *multiplexedInstanceV2
+	closeOnce    sync.Once
+}
+
+var _ ocrEndpointStream = (*multiplexedStreamV2)(nil)
+
+func (s *multiplexedStreamV2) Other() ragetypes.PeerID {
+	return s.peer.stream.Other()
+}
+
+func (s *multiplexedStreamV2) Status() ragep2p.StreamStatus {
+	status := s.peer.stream.Status()
+
+	s.peer.mutex.Lock()
+	defer s.peer.mutex.Unlock()
+	status.MessagesSent = s.instance.messagesSent
+	status.MessagesReceived = s.instance.messagesReceived
+	return status
+}
+
+// Best effort sending of messages. May fail without returning an error.
+func (s *multiplexedStreamV2) SendMessage(data []byte) {
+	if len(data) > s.instance.limits.MaxMessageLength {
+		s.peer.logger.Error("MultiplexerV2: Dropping outgoing message exceeding MaxMessageLength", commontypes.LogFields{
+			"configDigest":     s.configDigest,
+			"messageLength":    len(data),
+			"maxMessageLength": s.instance.limits.MaxMessageLength,
+		})
+		return
+	}
+	s.peer.send(s.configDigest, data)
+}
+
+// The returned channel is closed when the stream is closed.
+func (s *multiplexedStreamV2) ReceiveMessages() <-chan []byte {
+	return s.instance.chIncoming
+}
+
+func (s *multiplexedStreamV2) Close() error {
+	err := fmt.Errorf("already closed stream")
+	s.closeOnce.Do(func() {
+		err = s.multiplexer.closeStream(s)
+	})
+	return err
+}
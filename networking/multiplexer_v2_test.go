@@ -0,0 +1,330 @@
+package networking
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	ocr2types "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/ragep2p"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+var testLimits = BinaryNetworkEndpointLimits{
+	1000,
+	100,
+	10,
+	100_000,
+	10_000,
+}
+
+// staticDiscoverer resolves every peer to the in-memory address equal to its
+// peer ID.
+type staticDiscoverer struct{}
+
+func (staticDiscoverer) Start(*ragep2p.Host, ed25519.PrivateKey, loghelper.LoggerWithContext) error {
+	return nil
+}
+
+func (staticDiscoverer) Close() error {
+	return nil
+}
+
+func (staticDiscoverer) FindPeer(peer ragetypes.PeerID) ([]ragetypes.Address, error) {
+	return []ragetypes.Address{ragetypes.Address(peer.String())}, nil
+}
+
+func newTestHost(t *testing.T, transport *ragep2p.InMemoryTransport) *ragep2p.Host {
+	_, secretKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := ragetypes.PeerIDFromPrivateKey(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, err := ragep2p.NewHost(
+		ragep2p.HostConfig{DurationBetweenDials: 10 * time.Millisecond, Transport: transport},
+		secretKey,
+		[]string{id.String()},
+		staticDiscoverer{},
+		nopLogger{},
+		prometheus.NewRegistry(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := host.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = host.Close() })
+	return host
+}
+
+func newTestMultiplexer(t *testing.T, config MultiplexingConfigV2, host *ragep2p.Host) *multiplexerV2 {
+	return newMultiplexerV2(config, host, EndpointConfigV2{100, 100}, loghelper.MakeRootLoggerWithContext(nopLogger{}))
+}
+
+func receive(t *testing.T, s *multiplexedStreamV2) []byte {
+	t.Helper()
+	select {
+	case msg := <-s.ReceiveMessages():
+		return msg
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+func TestMultiplexerDeliversToInstances(t *testing.T) {
+	transport := ragep2p.NewInMemoryTransport()
+	hostA, hostB := newTestHost(t, transport), newTestHost(t, transport)
+	config := MultiplexingConfigV2{true, 50 * time.Millisecond, 0}
+	muxA, muxB := newTestMultiplexer(t, config, hostA), newTestMultiplexer(t, config, hostB)
+
+	configDigests := []ocr2types.ConfigDigest{{1}, {2}}
+	var streamsA, streamsB []*multiplexedStreamV2
+	for _, configDigest := range configDigests {
+		streamA, err := muxA.newStream(hostB.ID(), configDigest, 100, 100, testLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamB, err := muxB.newStream(hostA.ID(), configDigest, 100, 100, testLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamsA = append(streamsA, streamA)
+		streamsB = append(streamsB, streamB)
+	}
+	if _, err := muxA.newStream(hostB.ID(), configDigests[0], 100, 100, testLimits); err == nil {
+		t.Error("second stream for the same instance was created")
+	}
+
+	// messages sent within BatchInterval are coalesced
+	const messagesPerInstance = 5
+	for i := 0; i < messagesPerInstance; i++ {
+		for j, s := range streamsA {
+			s.SendMessage([]byte(fmt.Sprintf("instance %v message %v", j, i)))
+		}
+	}
+	for i := 0; i < messagesPerInstance; i++ {
+		for j, s := range streamsB {
+			if msg, expected := receive(t, s), fmt.Sprintf("instance %v message %v", j, i); string(msg) != expected {
+				t.Fatalf("received %q, expected %q", msg, expected)
+			}
+		}
+	}
+	peerA := streamsA[0].peer
+	if sent := peerA.stream.Status().MessagesSent; sent >= messagesPerInstance*uint64(len(configDigests)) {
+		t.Errorf("%v messages were sent in %v batches", messagesPerInstance*len(configDigests), sent)
+	}
+	for _, s := range streamsA {
+		if status := s.Status(); status.MessagesSent != messagesPerInstance {
+			t.Errorf("instance reports %v messages sent, expected %v", status.MessagesSent, messagesPerInstance)
+		}
+	}
+
+	// closing the last virtual stream closes the shared stream
+	for _, s := range streamsA {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err == nil {
+			t.Error("closing a stream twice succeeded")
+		}
+	}
+	if _, ok := <-streamsA[0].ReceiveMessages(); ok {
+		t.Error("channel of closed stream is open")
+	}
+	if len(muxA.peers) != 0 {
+		t.Error("multiplexer keeps peer without instances")
+	}
+
+	// closing the multiplexer closes the remaining streams
+	if err := muxB.close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := muxB.newStream(hostA.ID(), ocr2types.ConfigDigest{3}, 100, 100, testLimits); err == nil {
+		t.Error("closed multiplexer created a stream")
+	}
+	for _, s := range streamsB {
+		if err := s.Close(); err != nil {
+			t.Errorf("closing stream after multiplexer failed: %v", err)
+		}
+	}
+	if err := muxB.close(); err == nil {
+		t.Error("closing multiplexer twice succeeded")
+	}
+}
+
+// newUnconnectedPeer returns a multiplexedPeerV2 without stream or loops for
+// exercising batching and dispatching directly.
+func newUnconnectedPeer(maxBatchLength int, instances map[ocr2types.ConfigDigest]BinaryNetworkEndpointLimits, order []ocr2types.ConfigDigest) *multiplexedPeerV2 {
+	p := &multiplexedPeerV2{
+		config:         MultiplexingConfigV2{true, 0, maxBatchLength},
+		logger:         loghelper.MakeRootLoggerWithContext(nopLogger{}),
+		maxBatchLength: maxBatchLength,
+		instances:      map[ocr2types.ConfigDigest]*multiplexedInstanceV2{},
+		order:          order,
+	}
+	for configDigest, limits := range instances {
+		p.instances[configDigest] = newMultiplexedInstanceV2(1000, 1000, limits)
+	}
+	return p
+}
+
+func decodeBatch(t *testing.T, batch []byte) (configDigests []ocr2types.ConfigDigest, msgs [][]byte) {
+	t.Helper()
+	for len(batch) > 0 {
+		configDigest, msg, rest, err := decodeMultiplexedEntry(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configDigests = append(configDigests, configDigest)
+		msgs = append(msgs, msg)
+		batch = rest
+	}
+	return configDigests, msgs
+}
+
+func TestMultiplexerBatchingIsFair(t *testing.T) {
+	a, b, c := ocr2types.ConfigDigest{0xa}, ocr2types.ConfigDigest{0xb}, ocr2types.ConfigDigest{0xc}
+	// room for exactly three entries with 10 byte messages
+	p := newUnconnectedPeer(3*(multiplexedEntryHeaderSize+10), map[ocr2types.ConfigDigest]BinaryNetworkEndpointLimits{
+		a: testLimits, b: testLimits, c: testLimits,
+	}, []ocr2types.ConfigDigest{a, b, c})
+
+	// a has a large backlog, b and c have one message each
+	for i := 0; i < 20; i++ {
+		p.send(a, bytes.Repeat([]byte{'a'}, 10))
+	}
+	p.send(b, bytes.Repeat([]byte{'b'}, 10))
+	p.send(c, bytes.Repeat([]byte{'c'}, 10))
+
+	configDigests, _ := decodeBatch(t, p.nextBatch())
+	if len(configDigests) != 3 || configDigests[0] != a || configDigests[1] != b || configDigests[2] != c {
+		t.Fatalf("first batch is %v, expected one entry of each instance", configDigests)
+	}
+
+	total := 3
+	for {
+		batch := p.nextBatch()
+		if batch == nil {
+			break
+		}
+		if len(batch) > p.maxBatchLength {
+			t.Fatalf("batch of length %v exceeds %v", len(batch), p.maxBatchLength)
+		}
+		configDigests, msgs := decodeBatch(t, batch)
+		for i := range configDigests {
+			if configDigests[i] != a || !bytes.Equal(msgs[i], bytes.Repeat([]byte{'a'}, 10)) {
+				t.Fatalf("unexpected entry %v %q", configDigests[i], msgs[i])
+			}
+		}
+		total += len(configDigests)
+	}
+	if total != 22 {
+		t.Errorf("batched %v messages, expected 22", total)
+	}
+
+	// when not every instance fits into a batch, the instance that didn't
+	// fit goes first in the next one, so all instances with a backlog get
+	// the same share of batches
+	p.maxBatchLength = 2 * (multiplexedEntryHeaderSize + 10)
+	for i := 0; i < 10; i++ {
+		for _, configDigest := range []ocr2types.ConfigDigest{a, b, c} {
+			p.send(configDigest, bytes.Repeat([]byte{'x'}, 10))
+		}
+	}
+	counts := map[ocr2types.ConfigDigest]int{}
+	for i := 0; i < 15; i++ {
+		configDigests, _ := decodeBatch(t, p.nextBatch())
+		if len(configDigests) != 2 || configDigests[0] == configDigests[1] {
+			t.Fatalf("batch %v favors an instance", configDigests)
+		}
+		for _, configDigest := range configDigests {
+			counts[configDigest]++
+		}
+		if (i+1)%3 == 0 && (counts[a] != counts[b] || counts[b] != counts[c]) {
+			t.Fatalf("after %v batches, instances have sent %v messages", i+1, counts)
+		}
+	}
+	if batch := p.nextBatch(); batch != nil {
+		t.Errorf("unexpected batch after backlog was drained")
+	}
+}
+
+func TestMultiplexerEnforcesInstanceLimits(t *testing.T) {
+	known, unknown := ocr2types.ConfigDigest{1}, ocr2types.ConfigDigest{2}
+	p := newUnconnectedPeer(DefaultMultiplexingMaxBatchLength, map[ocr2types.ConfigDigest]BinaryNetworkEndpointLimits{
+		known: {10, 0.001, 2, 1_000, 1_000},
+	}, []ocr2types.ConfigDigest{known})
+	instance := p.instances[known]
+
+	var batch []byte
+	batch = appendMultiplexedEntry(batch, unknown, []byte("unknown"))
+	batch = appendMultiplexedEntry(batch, known, []byte("too long message"))
+	batch = appendMultiplexedEntry(batch, known, []byte("first"))
+	batch = appendMultiplexedEntry(batch, known, []byte("second"))
+	batch = appendMultiplexedEntry(batch, known, []byte("third"))
+	p.dispatch(batch)
+
+	var received []string
+	for len(instance.chIncoming) > 0 {
+		received = append(received, string(<-instance.chIncoming))
+	}
+	// the message capacity of 2 lets the first two messages through
+	if len(received) != 2 || received[0] != "first" || received[1] != "second" {
+		t.Errorf("received %q, expected first and second", received)
+	}
+
+	// the rest of a malformed batch is dropped
+	batch = appendMultiplexedEntry(nil, known, []byte("x"))
+	p.dispatch(batch[:len(batch)-1])
+	if len(instance.chIncoming) != 0 {
+		t.Error("message from truncated batch was delivered")
+	}
+}
+
+func TestDeriveMultiplexedStreamLimits(t *testing.T) {
+	config := MultiplexingConfigV2{true, 0, 0}.withDefaults()
+	one := deriveMultiplexedStreamLimits(config, []BinaryNetworkEndpointLimits{testLimits})
+	two := deriveMultiplexedStreamLimits(config, []BinaryNetworkEndpointLimits{testLimits, testLimits})
+
+	if one.batchesLimit.Rate != testLimits.MessagesRatePerOracle || one.batchesLimit.Capacity != uint32(testLimits.MessagesCapacityPerOracle) {
+		t.Errorf("batches limit %v doesn't match the instance's messages limit", one.batchesLimit)
+	}
+	expectedBytesCapacity := uint32(testLimits.BytesCapacityPerOracle + multiplexedEntryHeaderSize*testLimits.MessagesCapacityPerOracle)
+	if one.bytesLimit.Capacity != expectedBytesCapacity {
+		t.Errorf("bytes capacity is %v, expected %v", one.bytesLimit.Capacity, expectedBytesCapacity)
+	}
+	if two.batchesLimit.Rate != 2*one.batchesLimit.Rate || two.bytesLimit.Rate != 2*one.bytesLimit.Rate ||
+		two.batchesLimit.Capacity != 2*one.batchesLimit.Capacity || two.bytesLimit.Capacity != 2*one.bytesLimit.Capacity {
+		t.Errorf("limits of two instances %v aren't twice those of one %v", two, one)
+	}
+	// batches are limited by the bytes capacity, not just MaxBatchLength
+	if one.maxBatchLength != int(expectedBytesCapacity) {
+		t.Errorf("maxBatchLength is %v, expected %v", one.maxBatchLength, expectedBytesCapacity)
+	}
+
+	// but always fit the largest message
+	tiny := BinaryNetworkEndpointLimits{1000, 1, 1, 1, 1}
+	if l := deriveMultiplexedStreamLimits(config, []BinaryNetworkEndpointLimits{tiny}); l.maxBatchLength != multiplexedEntryHeaderSize+1000 {
+		t.Errorf("maxBatchLength %v doesn't fit the largest message", l.maxBatchLength)
+	}
+}
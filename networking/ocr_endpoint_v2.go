@@ -18,6 +18,7 @@ import (
 
 var (
 	_ commontypes.BinaryNetworkEndpoint = &ocrEndpointV2{}
+	_ ocr2types.PeerStatusReporter      = &ocrEndpointV2{}
 )
 
 type ocrEndpointState int
@@ -44,6 +45,19 @@ type EndpointConfigV2 struct {
 	OutgoingMessageBufferSize int
 }
 
+// ocrEndpointStream is the subset of the ragep2p.Stream API used by
+// ocrEndpointV2. It is implemented by ragep2p.Stream and, if multiplexing is
+// enabled, by multiplexedStreamV2.
+type ocrEndpointStream interface {
+	Other() ragetypes.PeerID
+	Status() ragep2p.StreamStatus
+	SendMessage(data []byte)
+	ReceiveMessages() <-chan []byte
+	Close() error
+}
+
+var _ ocrEndpointStream = (*ragep2p.Stream)(nil)
+
 // ocrEndpointV2 represents a member of a particular feed oracle group
 type ocrEndpointV2 struct {
 	// configuration and settings
@@ -52,7 +66,6 @@ type ocrEndpointV2 struct {
 	peerMapping         map[commontypes.OracleID]ragetypes.PeerID
 	reversedPeerMapping map[ragetypes.PeerID]commontypes.OracleID
 	peer                *concretePeerV2
-	host                *ragep2p.Host
 	configDigest        ocr2types.ConfigDigest
 	bootstrappers       []ragetypes.PeerInfo
 	f                   int
@@ -61,7 +74,7 @@ type ocrEndpointV2 struct {
 	// internal and state management
 	chSendToSelf chan commontypes.BinaryMessageWithSender
 	chClose      chan struct{}
-	streams      map[commontypes.OracleID]*ragep2p.Stream
+	streams      map[commontypes.OracleID]ocrEndpointStream
 	registration io.Closer
 	state        ocrEndpointState
 
@@ -128,14 +141,13 @@ func newOCREndpointV2(
 		peerMapping,
 		reversedPeerMapping,
 		peer,
-		peer.host,
 		configDigest,
 		v2bootstrappers,
 		f,
 		ownOracleID,
 		chSendToSelf,
 		make(chan struct{}),
-		make(map[commontypes.OracleID]*ragep2p.Stream),
+		make(map[commontypes.OracleID]ocrEndpointStream),
 		registration,
 		ocrEndpointUnstarted,
 		sync.RWMutex{},
@@ -171,21 +183,12 @@ func (o *ocrEndpointV2) Start() error {
 		if oid == o.ownOracleID {
 			continue
 		}
-		streamName := streamNameFromConfigDigest(o.configDigest)
-		stream, err := o.host.NewStream(
+		stream, err := o.peer.newStream(
 			pid,
-			streamName,
+			o.configDigest,
 			o.config.OutgoingMessageBufferSize,
 			o.config.IncomingMessageBufferSize,
-			o.limits.MaxMessageLength,
-			ragep2p.TokenBucketParams{
-				o.limits.MessagesRatePerOracle,
-				uint32(o.limits.MessagesCapacityPerOracle),
-			},
-			ragep2p.TokenBucketParams{
-				o.limits.BytesRatePerOracle,
-				uint32(o.limits.BytesCapacityPerOracle),
-			},
+			o.limits,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create stream for oracle %v (peer id: %q): %w", oid, pid, err)
@@ -337,3 +340,31 @@ func (o *ocrEndpointV2) Broadcast(payload []byte) {
 func (o *ocrEndpointV2) Receive() <-chan commontypes.BinaryMessageWithSender {
 	return o.recv
 }
+
+// PeerStatuses reports the status of the streams to the other oracles. Empty
+// unless the endpoint is started.
+func (o *ocrEndpointV2) PeerStatuses() []ocr2types.PeerStatus {
+	o.stateMu.RLock()
+	defer o.stateMu.RUnlock()
+	if o.state != ocrEndpointStarted {
+		return nil
+	}
+
+	statuses := make([]ocr2types.PeerStatus, 0, len(o.streams))
+	for oid := 0; oid < len(o.peerIDs); oid++ {
+		stream, ok := o.streams[commontypes.OracleID(oid)]
+		if !ok {
+			continue
+		}
+		streamStatus := stream.Status()
+		statuses = append(statuses, ocr2types.PeerStatus{
+			commontypes.OracleID(oid),
+			stream.Other().String(),
+			streamStatus.Connected,
+			streamStatus.LastStateChange,
+			streamStatus.MessagesSent,
+			streamStatus.MessagesReceived,
+		})
+	}
+	return statuses
+}
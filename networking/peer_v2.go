@@ -39,8 +39,44 @@ type PeerConfig struct {
 
 	V2DiscovererDatabase nettypes.DiscovererDatabase
 
+	// V2Discoverer overrides the discoverer used for finding the addresses of
+	// peers, e.g. with a ragedisco.StaticDiscoverer for private deployments.
+	// nil means gossip-based discovery with a ragedisco.Ragep2pDiscoverer, in
+	// which case V2DeltaReconcile, V2AnnounceAddresses and
+	// V2DiscovererDatabase are used.
+	V2Discoverer ragedisco.GroupDiscoverer
+
 	V2EndpointConfig EndpointConfigV2
 
+	// V2Multiplexing determines whether OCR instances share ragep2p streams.
+	// Multiplexing is disabled by default.
+	V2Multiplexing MultiplexingConfigV2
+
+	// V2Transport is the transport used by ragep2p. nil means TCP.
+	V2Transport ragep2p.Transport
+
+	// V2Proxy configures outbound SOCKS5 or HTTP CONNECT proxies through
+	// which peers are dialed. Proxies are only supported with the TCP
+	// transport. The zero value disables proxying.
+	V2Proxy ragep2p.ProxyConfig
+
+	// V2ConnectionPolicy is consulted by ragep2p for incoming connections and
+	// dials, see ragep2p.BasicConnectionPolicy. nil allows all connections
+	// with known peers.
+	V2ConnectionPolicy ragep2p.ConnectionPolicy
+
+	// V2HappyEyeballsDelay enables racing dials to all known addresses of a
+	// peer, started this long after one another. This is useful when
+	// bootstrapper hostnames or SRV records resolve to several addresses.
+	// Zero disables racing.
+	V2HappyEyeballsDelay time.Duration
+
+	// V2PingInterval is the interval at which ragep2p pings peers to measure
+	// round trip times, see PeerHealth. Zero disables pings. Peers running
+	// older versions close connections upon receiving a ping, so only enable
+	// this once all peers have been upgraded.
+	V2PingInterval time.Duration
+
 	MetricsRegisterer prometheus.Registerer
 }
 
@@ -48,10 +84,12 @@ type PeerConfig struct {
 type concretePeerV2 struct {
 	peerID            ragetypes.PeerID
 	host              *ragep2p.Host
-	discoverer        *ragedisco.Ragep2pDiscoverer
+	discoverer        ragedisco.GroupDiscoverer
 	metricsRegisterer prometheus.Registerer
 	logger            loghelper.LoggerWithContext
 	endpointConfig    EndpointConfigV2
+	// nil unless multiplexing is enabled
+	multiplexer *multiplexerV2
 }
 
 // Users are expected to create (using the OCR*Factory() methods) and close endpoints and bootstrappers before calling
@@ -61,6 +99,10 @@ func NewPeer(c PeerConfig) (*concretePeerV2, error) {
 		return nil, fmt.Errorf("ed25519 sanity check failed: %w", err)
 	}
 
+	if err := c.V2Multiplexing.check(); err != nil {
+		return nil, fmt.Errorf("invalid V2Multiplexing: %w", err)
+	}
+
 	peerID, err := ragetypes.PeerIDFromPrivateKey(c.PrivKey)
 	if err != nil {
 		return nil, fmt.Errorf("error extracting v2 peer ID from private key: %w", err)
@@ -78,9 +120,12 @@ func NewPeer(c PeerConfig) (*concretePeerV2, error) {
 
 	metricsRegistererWrapper := metricshelper.NewPrometheusRegistererWrapper(c.MetricsRegisterer, c.Logger)
 
-	discoverer := ragedisco.NewRagep2pDiscoverer(c.V2DeltaReconcile, announceAddresses, c.V2DiscovererDatabase, metricsRegistererWrapper)
+	discoverer := c.V2Discoverer
+	if discoverer == nil {
+		discoverer = ragedisco.NewRagep2pDiscoverer(c.V2DeltaReconcile, announceAddresses, c.V2DiscovererDatabase, metricsRegistererWrapper)
+	}
 	host, err := ragep2p.NewHost(
-		ragep2p.HostConfig{c.V2DeltaDial},
+		ragep2p.HostConfig{c.V2DeltaDial, c.V2Transport, c.V2Proxy, c.V2ConnectionPolicy, c.V2HappyEyeballsDelay, c.V2PingInterval},
 		c.PrivKey,
 		c.V2ListenAddresses,
 		discoverer,
@@ -97,6 +142,11 @@ func NewPeer(c PeerConfig) (*concretePeerV2, error) {
 
 	logger.Info("PeerV2: ragep2p host booted", nil)
 
+	var multiplexer *multiplexerV2
+	if c.V2Multiplexing.Enabled {
+		multiplexer = newMultiplexerV2(c.V2Multiplexing, host, c.V2EndpointConfig, logger)
+	}
+
 	return &concretePeerV2{
 		peerID,
 		host,
@@ -104,6 +154,7 @@ func NewPeer(c PeerConfig) (*concretePeerV2, error) {
 		metricsRegistererWrapper,
 		logger,
 		c.V2EndpointConfig,
+		multiplexer,
 	}, nil
 }
 
@@ -141,12 +192,81 @@ func (p2 *concretePeerV2) register(configDigest ocr2types.ConfigDigest, oracles
 	}), nil
 }
 
+// newStream creates the stream used by the endpoint for configDigest to
+// communicate with the remote peer other. If multiplexing is enabled, this is
+// a virtual stream backed by the stream shared by all endpoints.
+func (p2 *concretePeerV2) newStream(
+	other ragetypes.PeerID,
+	configDigest ocr2types.ConfigDigest,
+	outgoingBufferSize int,
+	incomingBufferSize int,
+	limits BinaryNetworkEndpointLimits,
+) (ocrEndpointStream, error) {
+	if p2.multiplexer != nil {
+		stream, err := p2.multiplexer.newStream(other, configDigest, outgoingBufferSize, incomingBufferSize, limits)
+		if err != nil {
+			return nil, err
+		}
+		return stream, nil
+	}
+
+	stream, err := p2.host.NewStream(
+		other,
+		streamNameFromConfigDigest(configDigest),
+		outgoingBufferSize,
+		incomingBufferSize,
+		limits.MaxMessageLength,
+		ragep2p.TokenBucketParams{
+			limits.MessagesRatePerOracle,
+			uint32(limits.MessagesCapacityPerOracle),
+		},
+		ragep2p.TokenBucketParams{
+			limits.BytesRatePerOracle,
+			uint32(limits.BytesCapacityPerOracle),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
 func (p2 *concretePeerV2) PeerID() string {
 	return p2.peerID.String()
 }
 
+// PeerHealth returns the connection health and per-stream traffic counters
+// for the peer with the given ID. It returns an error if the peer ID is
+// invalid or if we don't have any streams with the peer, e.g. because it isn't
+// part of any of our OCR instances.
+func (p2 *concretePeerV2) PeerHealth(peerID string) (ragep2p.PeerHealth, error) {
+	var other ragetypes.PeerID
+	if err := other.UnmarshalText([]byte(peerID)); err != nil {
+		return ragep2p.PeerHealth{}, fmt.Errorf("invalid peer ID %q: %w", peerID, err)
+	}
+	health, ok := p2.host.PeerHealth(other)
+	if !ok {
+		return ragep2p.PeerHealth{}, fmt.Errorf("no streams with peer %q", peerID)
+	}
+	return health, nil
+}
+
+// AllPeerHealth returns the connection health of all peers we have streams
+// with, keyed by peer ID.
+func (p2 *concretePeerV2) AllPeerHealth() map[string]ragep2p.PeerHealth {
+	result := make(map[string]ragep2p.PeerHealth)
+	for pid, health := range p2.host.AllPeerHealth() {
+		result[pid.String()] = health
+	}
+	return result
+}
+
 func (p2 *concretePeerV2) Close() error {
-	return p2.host.Close()
+	var err error
+	if p2.multiplexer != nil {
+		err = p2.multiplexer.close()
+	}
+	return multierr.Combine(err, p2.host.Close())
 }
 func decodev2Bootstrappers(v2bootstrappers []commontypes.BootstrapperLocator) (infos []ragetypes.PeerInfo, err error) {
 	for _, b := range v2bootstrappers {
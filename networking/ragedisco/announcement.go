@@ -304,3 +304,29 @@ var (
 	_ WrappableMessage = &reconcile{}
 	_ WrappableMessage = &Announcement{}
 )
+
+// NewSignedAnnouncement creates an Announcement of addrs signed with sk, e.g.
+// for distributing it as a pre-signed address card. Peers prefer
+// announcements with higher counters.
+func NewSignedAnnouncement(sk ed25519.PrivateKey, addrs []ragetypes.Address, counter uint64) (Announcement, error) {
+	return unsignedAnnouncement{addrs, counter}.sign(sk)
+}
+
+// Verify checks that the Announcement is valid and correctly signed.
+func (ann Announcement) Verify() error {
+	return ann.verify()
+}
+
+func (ann Announcement) MarshalBinary() ([]byte, error) {
+	return ann.serialize()
+}
+
+// UnmarshalBinary does not verify the Announcement, use Verify for that.
+func (ann *Announcement) UnmarshalBinary(data []byte) error {
+	result, err := deserializeSignedAnnouncement(data)
+	if err != nil {
+		return err
+	}
+	*ann = result
+	return nil
+}
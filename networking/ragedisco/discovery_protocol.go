@@ -67,6 +67,9 @@ type discoveryProtocol struct {
 
 	db nettypes.DiscovererDatabase
 
+	// resolves the DNS names among the addresses in locked.bootstrappers
+	resolver *bootstrapperResolver
+
 	processes subprocesses.Subprocesses
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -119,6 +122,7 @@ func newDiscoveryProtocol(
 			make(map[ragetypes.PeerID]int),
 		},
 		db,
+		newBootstrapperResolver(newDNSResolver(), logger),
 		subprocesses.Subprocesses{},
 		ctx,
 		ctxCancel,
@@ -152,6 +156,7 @@ func (p *discoveryProtocol) Start() error {
 	p.processes.Go(p.sendLoop)
 	p.processes.Go(p.saveLoop)
 	p.processes.Go(p.statusReportLoop)
+	p.processes.Go(func() { p.resolver.run(p.ctx) })
 	succeeded = true
 	return nil
 }
@@ -267,6 +272,7 @@ func (p *discoveryProtocol) addGroup(digest types.ConfigDigest, onodes []ragetyp
 				p.locked.bootstrappers[bs.ID] = make(map[ragetypes.Address]int)
 			}
 			p.locked.bootstrappers[bs.ID][addr]++
+			p.resolver.add(addr)
 		}
 	}
 	for _, pid := range newGroup.peerIDs() {
@@ -413,6 +419,9 @@ func (p *discoveryProtocol) removeGroup(digest types.ConfigDigest) error {
 
 	for _, binfo := range goneGroup.bootstrapperNodes {
 		bid := binfo.ID
+		for _, addr := range binfo.Addrs {
+			p.resolver.remove(addr)
+		}
 
 		p.locked.numGroupsByBootstrapper[bid]--
 		if p.locked.numGroupsByBootstrapper[bid] == 0 {
@@ -447,10 +456,10 @@ func (p *discoveryProtocol) FindPeer(peer ragetypes.PeerID) ([]ragetypes.Address
 	defer p.lock.RUnlock()
 	var addrs []ragetypes.Address
 	// The addresses we know from local configuration take priority — useful for overriding addresses in disaster
-	// scenarios
+	// scenarios. DNS names among them are replaced by what they currently resolve to.
 	if baddrs, ok := p.locked.bootstrappers[peer]; ok {
 		for baddr := range baddrs {
-			addrs = append(addrs, baddr)
+			addrs = append(addrs, p.resolver.addresses(baddr)...)
 		}
 	}
 	// Followed by the addresses obtained by the best announcement
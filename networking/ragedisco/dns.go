@@ -0,0 +1,349 @@
+package ragedisco
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+const (
+	// How often hostnames and SRV records are re-resolved. The Go resolver
+	// doesn't expose TTLs, so we re-resolve on a fixed schedule.
+	dnsRefreshInterval = 5 * time.Minute
+	// How long to wait before retrying a failed resolution
+	dnsRetryInterval = 30 * time.Second
+
+	// Upper bound on the number of addresses a single bootstrapper address
+	// may resolve to
+	maxResolvedAddrsPerBootstrapperAddr = 16
+)
+
+type srvTarget struct {
+	host     string
+	port     uint16
+	priority uint16
+	weight   uint16
+}
+
+type dnsResolver interface {
+	lookupIPs(ctx context.Context, host string) ([]netip.Addr, error)
+	lookupSRV(ctx context.Context, name string) ([]srvTarget, error)
+}
+
+// newDNSResolver returns a resolver backed by net.DefaultResolver, so that
+// names are resolved just like when dialing them, honoring /etc/hosts and
+// the search domains and options from /etc/resolv.conf.
+func newDNSResolver() dnsResolver {
+	return goDNSResolver{net.DefaultResolver}
+}
+
+type goDNSResolver struct {
+	resolver *net.Resolver
+}
+
+func (r goDNSResolver) lookupIPs(ctx context.Context, host string) ([]netip.Addr, error) {
+	ipAddrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]netip.Addr, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		ip, ok := netip.AddrFromSlice(ipAddr.IP)
+		if !ok {
+			continue
+		}
+		// IPv6 zones are dropped, bootstrappers aren't link-local
+		ips = append(ips, ip.Unmap())
+	}
+	return ips, nil
+}
+
+func (r goDNSResolver) lookupSRV(ctx context.Context, name string) ([]srvTarget, error) {
+	_, srvs, err := r.resolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]srvTarget, 0, len(srvs))
+	for _, srv := range srvs {
+		targets = append(targets, srvTarget{strings.TrimSuffix(srv.Target, "."), srv.Port, srv.Priority, srv.Weight})
+	}
+	return targets, nil
+}
+
+// sortSRVTargets orders targets by ascending priority. Within a priority,
+// targets with higher weight come first.
+func sortSRVTargets(targets []srvTarget) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].priority != targets[j].priority {
+			return targets[i].priority < targets[j].priority
+		}
+		return targets[i].weight > targets[j].weight
+	})
+}
+
+// interleaveAddrFamilies orders addresses alternating between IPv6 and IPv4,
+// starting with IPv6, as recommended for happy eyeballs dialing (RFC 8305).
+func interleaveAddrFamilies(addrPorts []netip.AddrPort) []netip.AddrPort {
+	var v6, v4 []netip.AddrPort
+	for _, ap := range addrPorts {
+		if ap.Addr().Is4() {
+			v4 = append(v4, ap)
+		} else {
+			v6 = append(v6, ap)
+		}
+	}
+	result := make([]netip.AddrPort, 0, len(addrPorts))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			result = append(result, v6[i])
+		}
+		if i < len(v4) {
+			result = append(result, v4[i])
+		}
+	}
+	return result
+}
+
+// srvName returns the SRV name contained in addr, if any. SRV names are
+// given without a port and start with an underscore, e.g.
+// "_ragep2p._tcp.example.com".
+func srvName(addr ragetypes.Address) (string, bool) {
+	s := string(addr)
+	if strings.HasPrefix(s, "_") && !strings.Contains(s, ":") {
+		return s, true
+	}
+	return "", false
+}
+
+// needsResolution reports whether addr is an SRV name or a <host>:<port>
+// address whose host isn't an IP literal.
+func needsResolution(addr ragetypes.Address) bool {
+	if _, ok := srvName(addr); ok {
+		return true
+	}
+	host, _, err := net.SplitHostPort(string(addr))
+	if err != nil {
+		return false
+	}
+	_, err = netip.ParseAddr(host)
+	return err != nil
+}
+
+type bootstrapperResolverEntry struct {
+	// number of bootstrapper entries across groups that use this address
+	refs     int
+	resolved []ragetypes.Address
+	// when the address should be resolved next
+	due time.Time
+}
+
+// bootstrapperResolver keeps the DNS names among the bootstrapper addresses
+// resolved, so that bootstrappers can change their IPs without us having to
+// restart. Every address is resolved as soon as it is added and then again
+// every dnsRefreshInterval.
+type bootstrapperResolver struct {
+	resolver dnsResolver
+	logger   loghelper.LoggerWithContext
+	chWake   chan struct{}
+
+	mutex   sync.Mutex
+	entries map[ragetypes.Address]*bootstrapperResolverEntry
+}
+
+func newBootstrapperResolver(resolver dnsResolver, logger loghelper.LoggerWithContext) *bootstrapperResolver {
+	return &bootstrapperResolver{
+		resolver,
+		logger.MakeChild(commontypes.LogFields{"in": "bootstrapperResolver"}),
+		make(chan struct{}, 1),
+
+		sync.Mutex{},
+		make(map[ragetypes.Address]*bootstrapperResolverEntry),
+	}
+}
+
+// add starts tracking addr. Calls to add must be matched by calls to remove.
+// Addresses that don't need resolution are ignored.
+func (r *bootstrapperResolver) add(addr ragetypes.Address) {
+	if !needsResolution(addr) {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if e, ok := r.entries[addr]; ok {
+		e.refs++
+		return
+	}
+	r.entries[addr] = &bootstrapperResolverEntry{1, nil, time.Time{}}
+	select {
+	case r.chWake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *bootstrapperResolver) remove(addr ragetypes.Address) {
+	if !needsResolution(addr) {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	e, ok := r.entries[addr]
+	if !ok {
+		return
+	}
+	e.refs--
+	if e.refs <= 0 {
+		delete(r.entries, addr)
+	}
+}
+
+// addresses returns the addresses to dial for addr. As long as a hostname
+// hasn't been resolved successfully, it is returned as is and left to the
+// dialer to resolve. SRV names can't be dialed, so nothing is returned for
+// them until they have been resolved.
+func (r *bootstrapperResolver) addresses(addr ragetypes.Address) []ragetypes.Address {
+	if !needsResolution(addr) {
+		return []ragetypes.Address{addr}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if e, ok := r.entries[addr]; ok && len(e.resolved) != 0 {
+		return append([]ragetypes.Address{}, e.resolved...)
+	}
+	if _, ok := srvName(addr); ok {
+		return nil
+	}
+	return []ragetypes.Address{addr}
+}
+
+func (r *bootstrapperResolver) run(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := now.Add(dnsRefreshInterval)
+		var due []ragetypes.Address
+		r.mutex.Lock()
+		for addr, e := range r.entries {
+			if !e.due.After(now) {
+				due = append(due, addr)
+			} else if e.due.Before(next) {
+				next = e.due
+			}
+		}
+		r.mutex.Unlock()
+
+		if len(due) != 0 {
+			for _, addr := range due {
+				r.refresh(ctx, addr)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-r.chWake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (r *bootstrapperResolver) refresh(ctx context.Context, addr ragetypes.Address) {
+	resolved, err := r.resolve(ctx, addr)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	e, ok := r.entries[addr]
+	if !ok {
+		// removed in the meantime
+		return
+	}
+	if err != nil {
+		e.due = time.Now().Add(dnsRetryInterval)
+		if ctx.Err() == nil {
+			r.logger.Warn("Failed to resolve bootstrapper address, will retry", commontypes.LogFields{
+				"address":    addr,
+				"previously": e.resolved,
+				"retryIn":    dnsRetryInterval.String(),
+				"error":      err,
+			})
+		}
+		return
+	}
+	e.due = time.Now().Add(dnsRefreshInterval)
+	if !equalAddrs(e.resolved, resolved) {
+		r.logger.Info("Resolved bootstrapper address", commontypes.LogFields{
+			"address":    addr,
+			"resolved":   resolved,
+			"previously": e.resolved,
+		})
+		e.resolved = resolved
+	}
+}
+
+// resolve returns the IP addresses that addr currently resolves to. The targets of SRV records
+// are ordered by priority and weight, and the IPs of each target alternate
+// between address families.
+func (r *bootstrapperResolver) resolve(ctx context.Context, addr ragetypes.Address) ([]ragetypes.Address, error) {
+	var targets []srvTarget
+	if name, ok := srvName(addr); ok {
+		var err error
+		targets, err = r.resolver.lookupSRV(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up SRV records for %q: %w", name, err)
+		}
+		sortSRVTargets(targets)
+	} else {
+		host, portStr, err := net.SplitHostPort(string(addr))
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in address %q: %w", addr, err)
+		}
+		targets = []srvTarget{{host, uint16(port), 0, 0}}
+	}
+
+	var resolved []ragetypes.Address
+	var lastErr error
+	for _, target := range targets {
+		ips, err := r.resolver.lookupIPs(ctx, target.host)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to look up %q: %w", target.host, err)
+			continue
+		}
+		addrPorts := make([]netip.AddrPort, 0, len(ips))
+		for _, ip := range ips {
+			addrPorts = append(addrPorts, netip.AddrPortFrom(ip, target.port))
+		}
+		for _, ap := range interleaveAddrFamilies(addrPorts) {
+			resolved = append(resolved, ragetypes.Address(ap.String()))
+		}
+	}
+	resolved = dedup(resolved)
+	if len(resolved) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("%q resolved to no addresses", addr)
+		}
+		return nil, lastErr
+	}
+	if len(resolved) > maxResolvedAddrsPerBootstrapperAddr {
+		resolved = resolved[:maxResolvedAddrsPerBootstrapperAddr]
+	}
+	return resolved, nil
+}
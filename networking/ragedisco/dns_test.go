@@ -0,0 +1,265 @@
+package ragedisco
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+// fakeDNSResolver resolves names from maps that can be changed at any time.
+// Unknown names fail to resolve.
+type fakeDNSResolver struct {
+	mutex   sync.Mutex
+	ips     map[string][]netip.Addr
+	targets map[string][]srvTarget
+	lookups int
+}
+
+func newFakeDNSResolver() *fakeDNSResolver {
+	return &fakeDNSResolver{sync.Mutex{}, map[string][]netip.Addr{}, map[string][]srvTarget{}, 0}
+}
+
+func (r *fakeDNSResolver) setIPs(host string, ips ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(ips) == 0 {
+		delete(r.ips, host)
+		return
+	}
+	r.ips[host] = nil
+	for _, ip := range ips {
+		r.ips[host] = append(r.ips[host], netip.MustParseAddr(ip))
+	}
+}
+
+func (r *fakeDNSResolver) lookupIPs(ctx context.Context, host string) ([]netip.Addr, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lookups++
+	ips, ok := r.ips[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %q", host)
+	}
+	return ips, nil
+}
+
+func (r *fakeDNSResolver) lookupSRV(ctx context.Context, name string) ([]srvTarget, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lookups++
+	targets, ok := r.targets[name]
+	if !ok {
+		return nil, fmt.Errorf("no SRV records for %q", name)
+	}
+	return append([]srvTarget{}, targets...), nil
+}
+
+func newTestBootstrapperResolver(resolver dnsResolver) *bootstrapperResolver {
+	return newBootstrapperResolver(resolver, loghelper.MakeRootLoggerWithContext(nopLogger{}))
+}
+
+func TestNeedsResolution(t *testing.T) {
+	for addr, expected := range map[ragetypes.Address]bool{
+		"10.0.0.1:1":                false,
+		"[2001:db8::1]:1":           false,
+		"bootstrapper.example:1":    true,
+		"_ragep2p._tcp.example.com": true,
+		"no-port.example.com":       false,
+	} {
+		if needsResolution(addr) != expected {
+			t.Errorf("needsResolution(%q) is %v, expected %v", addr, !expected, expected)
+		}
+	}
+	if name, ok := srvName("_ragep2p._tcp.example.com"); !ok || name != "_ragep2p._tcp.example.com" {
+		t.Errorf("SRV name not recognized: %q, %v", name, ok)
+	}
+	if _, ok := srvName("_ragep2p._tcp.example.com:1"); ok {
+		t.Error("address with port recognized as SRV name")
+	}
+}
+
+func TestSortSRVTargets(t *testing.T) {
+	targets := []srvTarget{
+		{"c", 1, 20, 5},
+		{"a", 1, 10, 1},
+		{"d", 1, 20, 5},
+		{"b", 1, 10, 9},
+	}
+	sortSRVTargets(targets)
+	var hosts []string
+	for _, target := range targets {
+		hosts = append(hosts, target.host)
+	}
+	// by priority, then by descending weight, ties keep their order
+	if fmt.Sprint(hosts) != "[b a c d]" {
+		t.Errorf("sorted targets to %v", hosts)
+	}
+}
+
+func TestInterleaveAddrFamilies(t *testing.T) {
+	var addrPorts []netip.AddrPort
+	for _, s := range []string{"10.0.0.1:1", "10.0.0.2:1", "10.0.0.3:1", "[2001:db8::1]:1", "[2001:db8::2]:1"} {
+		addrPorts = append(addrPorts, netip.MustParseAddrPort(s))
+	}
+	expected := "[[2001:db8::1]:1 10.0.0.1:1 [2001:db8::2]:1 10.0.0.2:1 10.0.0.3:1]"
+	if interleaved := interleaveAddrFamilies(addrPorts); fmt.Sprint(interleaved) != expected {
+		t.Errorf("interleaved to %v, expected %v", interleaved, expected)
+	}
+}
+
+func TestBootstrapperResolverResolve(t *testing.T) {
+	dns := newFakeDNSResolver()
+	dns.setIPs("one.example", "10.0.0.1", "2001:db8::1", "10.0.0.2")
+	dns.setIPs("two.example", "10.0.0.3", "10.0.0.1")
+	dns.targets["_ragep2p._tcp.example"] = []srvTarget{
+		{"two.example", 1000, 20, 0},
+		{"missing.example", 3000, 10, 0},
+		{"one.example", 1000, 10, 0},
+	}
+	r := newTestBootstrapperResolver(dns)
+	ctx := context.Background()
+
+	resolved, err := r.resolve(ctx, "one.example:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(resolved) != "[[2001:db8::1]:1 10.0.0.1:1 10.0.0.2:1]" {
+		t.Errorf("resolved hostname to %v", resolved)
+	}
+
+	// targets that fail to resolve are skipped, duplicates are dropped
+	resolved, err = r.resolve(ctx, "_ragep2p._tcp.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(resolved) != "[[2001:db8::1]:1000 10.0.0.1:1000 10.0.0.2:1000 10.0.0.3:1000]" {
+		t.Errorf("resolved SRV name to %v", resolved)
+	}
+
+	for _, addr := range []ragetypes.Address{"missing.example:1", "_missing._tcp.example", "one.example:port"} {
+		if resolved, err := r.resolve(ctx, addr); err == nil {
+			t.Errorf("resolved %q to %v", addr, resolved)
+		}
+	}
+
+	var many []string
+	for i := 0; i < 2*maxResolvedAddrsPerBootstrapperAddr; i++ {
+		many = append(many, fmt.Sprintf("10.1.0.%d", i))
+	}
+	dns.setIPs("many.example", many...)
+	resolved, err = r.resolve(ctx, "many.example:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != maxResolvedAddrsPerBootstrapperAddr {
+		t.Errorf("resolved to %v addresses, expected %v", len(resolved), maxResolvedAddrsPerBootstrapperAddr)
+	}
+}
+
+func TestBootstrapperResolverAddresses(t *testing.T) {
+	dns := newFakeDNSResolver()
+	r := newTestBootstrapperResolver(dns)
+	ctx := context.Background()
+
+	// IP addresses are passed through without being tracked
+	r.add("10.0.0.1:1")
+	if addrs := r.addresses("10.0.0.1:1"); fmt.Sprint(addrs) != "[10.0.0.1:1]" {
+		t.Errorf("addresses of IP address are %v", addrs)
+	}
+
+	// until a name has been resolved, hostnames are left to the dialer and
+	// SRV names yield nothing
+	r.add("host.example:1")
+	r.add("_ragep2p._tcp.example")
+	if addrs := r.addresses("host.example:1"); fmt.Sprint(addrs) != "[host.example:1]" {
+		t.Errorf("addresses of unresolved hostname are %v", addrs)
+	}
+	if addrs := r.addresses("_ragep2p._tcp.example"); len(addrs) != 0 {
+		t.Errorf("addresses of unresolved SRV name are %v", addrs)
+	}
+
+	dns.setIPs("host.example", "10.0.0.2")
+	dns.mutex.Lock()
+	dns.targets["_ragep2p._tcp.example"] = []srvTarget{{"host.example", 2, 0, 0}}
+	dns.mutex.Unlock()
+	// without the run loop, refresh manually
+	r.refresh(ctx, "host.example:1")
+	r.refresh(ctx, "_ragep2p._tcp.example")
+	if addrs := r.addresses("host.example:1"); fmt.Sprint(addrs) != "[10.0.0.2:1]" {
+		t.Errorf("addresses of resolved hostname are %v", addrs)
+	}
+	if addrs := r.addresses("_ragep2p._tcp.example"); fmt.Sprint(addrs) != "[10.0.0.2:2]" {
+		t.Errorf("addresses of resolved SRV name are %v", addrs)
+	}
+
+	// a failed refresh keeps the previous resolution
+	dns.setIPs("host.example")
+	r.refresh(ctx, "host.example:1")
+	if addrs := r.addresses("host.example:1"); fmt.Sprint(addrs) != "[10.0.0.2:1]" {
+		t.Errorf("addresses after failed refresh are %v", addrs)
+	}
+
+	// entries are reference counted
+	r.add("host.example:1")
+	r.remove("host.example:1")
+	if addrs := r.addresses("host.example:1"); fmt.Sprint(addrs) != "[10.0.0.2:1]" {
+		t.Errorf("addresses after removing one of two references are %v", addrs)
+	}
+	r.remove("host.example:1")
+	if addrs := r.addresses("host.example:1"); fmt.Sprint(addrs) != "[host.example:1]" {
+		t.Errorf("addresses after removing all references are %v", addrs)
+	}
+}
+
+func TestBootstrapperResolverResolvesAddedNames(t *testing.T) {
+	dns := newFakeDNSResolver()
+	dns.setIPs("host.example", "10.0.0.1")
+	r := newTestBootstrapperResolver(dns)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.run(ctx)
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	// the run loop resolves names as soon as they are added
+	r.add("host.example:1")
+	deadline := time.Now().Add(10 * time.Second)
+	for fmt.Sprint(r.addresses("host.example:1")) != "[10.0.0.1:1]" {
+		if time.Now().After(deadline) {
+			t.Fatalf("hostname wasn't resolved, addresses are %v", r.addresses("host.example:1"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// and not again before dnsRefreshInterval has passed
+	dns.mutex.Lock()
+	lookups := dns.lookups
+	dns.mutex.Unlock()
+	r.add("10.0.0.2:1")
+	time.Sleep(50 * time.Millisecond)
+	dns.mutex.Lock()
+	defer dns.mutex.Unlock()
+	if dns.lookups != lookups {
+		t.Errorf("resolved %v more times", dns.lookups-lookups)
+	}
+}
@@ -0,0 +1,359 @@
+package ragedisco
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/ragep2p"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+	"github.com/smartcontractkit/libocr/subprocesses"
+)
+
+// GroupDiscoverer is a ragep2p.Discoverer that discovers the peers of the
+// groups registered with AddGroup.
+type GroupDiscoverer interface {
+	ragep2p.Discoverer
+	AddGroup(digest types.ConfigDigest, onodes []ragetypes.PeerID, bnodes []ragetypes.PeerInfo) error
+	RemoveGroup(digest types.ConfigDigest) error
+}
+
+var _ GroupDiscoverer = &Ragep2pDiscoverer{}
+
+// AddressBook maps peers to their addresses.
+type AddressBook struct {
+	// Peers contains plain, unauthenticated addresses.
+	Peers map[ragetypes.PeerID][]ragetypes.Address
+	// Announcements contains pre-signed address cards, see
+	// NewSignedAnnouncement. Announcements are verified when the address
+	// book is loaded. If a peer has both an entry in Peers and an
+	// Announcement, the addresses of the Announcement are tried first.
+	Announcements []Announcement
+}
+
+// AddressBookSource returns the current AddressBook. It is called once on
+// start and then whenever the address book is reloaded.
+type AddressBookSource func() (AddressBook, error)
+
+// AddressBookFromFile returns an AddressBookSource that reads a JSON file of
+// the form
+//
+//	{
+//	  "peers": {"12D3KooW...": ["10.0.0.1:6690"]},
+//	  "announcements": ["<base64 encoded Announcement.MarshalBinary()>"]
+//	}
+//
+// The file is read anew on every reload.
+func AddressBookFromFile(path string) AddressBookSource {
+	return func() (AddressBook, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return AddressBook{}, err
+		}
+		var raw struct {
+			Peers         map[ragetypes.PeerID][]ragetypes.Address `json:"peers"`
+			Announcements [][]byte                                 `json:"announcements"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return AddressBook{}, fmt.Errorf("failed to parse address book %q: %w", path, err)
+		}
+		anns := make([]Announcement, 0, len(raw.Announcements))
+		for i, b := range raw.Announcements {
+			var ann Announcement
+			if err := ann.UnmarshalBinary(b); err != nil {
+				return AddressBook{}, fmt.Errorf("failed to decode announcement %d in address book %q: %w", i, path, err)
+			}
+			anns = append(anns, ann)
+		}
+		return AddressBook{raw.Peers, anns}, nil
+	}
+}
+
+type staticDiscovererState int
+
+const (
+	_ staticDiscovererState = iota
+	staticDiscovererUnstarted
+	staticDiscovererStarted
+	staticDiscovererClosed
+)
+
+// StaticDiscoverer is a GroupDiscoverer that looks up peer addresses in a
+// static, hot-reloadable AddressBook instead of gossiping announcements with
+// other peers. It is meant for private deployments where all peer addresses
+// are known in advance.
+//
+// Like with Ragep2pDiscoverer, FindPeer only returns addresses for peers that
+// are part of a registered group. Addresses of bootstrappers given to
+// AddGroup take priority over the address book.
+type StaticDiscoverer struct {
+	source         AddressBookSource
+	reloadInterval time.Duration
+
+	logger    loghelper.LoggerWithContext
+	resolver  *bootstrapperResolver
+	proc      subprocesses.Subprocesses
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+
+	stateMu sync.Mutex
+	state   staticDiscovererState
+
+	// serializes reloads, which can be triggered by Reload and reloadLoop
+	// concurrently
+	reloadMu sync.Mutex
+
+	lock            sync.RWMutex
+	groups          map[types.ConfigDigest]*group
+	numGroupsByPeer map[ragetypes.PeerID]int
+	bootstrappers   map[ragetypes.PeerID]map[ragetypes.Address]int
+	addresses       map[ragetypes.PeerID][]ragetypes.Address
+	// best verified announcement per peer across all reloads
+	bestAnnouncement map[ragetypes.PeerID]Announcement
+}
+
+var _ GroupDiscoverer = &StaticDiscoverer{}
+
+// NewStaticDiscoverer creates a StaticDiscoverer that reads its address book
+// from source. If reloadInterval is positive, the address book is reloaded
+// periodically. Reload can be used to trigger a reload at any time.
+func NewStaticDiscoverer(source AddressBookSource, reloadInterval time.Duration) *StaticDiscoverer {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	return &StaticDiscoverer{
+		source,
+		reloadInterval,
+
+		nil, // logger, filled on Start()
+		nil, // resolver, filled on Start()
+		subprocesses.Subprocesses{},
+		ctx,
+		ctxCancel,
+
+		sync.Mutex{},
+		staticDiscovererUnstarted,
+
+		sync.Mutex{},
+
+		sync.RWMutex{},
+		make(map[types.ConfigDigest]*group),
+		make(map[ragetypes.PeerID]int),
+		make(map[ragetypes.PeerID]map[ragetypes.Address]int),
+		make(map[ragetypes.PeerID][]ragetypes.Address),
+		make(map[ragetypes.PeerID]Announcement),
+	}
+}
+
+func (s *StaticDiscoverer) Start(_ *ragep2p.Host, _ ed25519.PrivateKey, logger loghelper.LoggerWithContext) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.state != staticDiscovererUnstarted {
+		return fmt.Errorf("cannot start StaticDiscoverer that is not unstarted, state was: %v", s.state)
+	}
+	s.logger = logger.MakeChild(commontypes.LogFields{"in": "StaticDiscoverer"})
+	s.resolver = newBootstrapperResolver(newDNSResolver(), s.logger)
+
+	if err := s.reload(); err != nil {
+		return fmt.Errorf("failed to load address book: %w", err)
+	}
+
+	s.state = staticDiscovererStarted
+	s.proc.Go(func() { s.resolver.run(s.ctx) })
+	if s.reloadInterval > 0 {
+		s.proc.Go(s.reloadLoop)
+	}
+	return nil
+}
+
+func (s *StaticDiscoverer) reloadLoop() {
+	ticker := time.NewTicker(s.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.reload(); err != nil {
+				s.logger.Warn("Failed to reload address book, keeping previous one", reason(err))
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Reload reads the address book from the source and replaces the current one
+// if it is valid. Signed announcements with invalid signatures are dropped.
+// If an announcement's counter is lower than that of the best announcement
+// seen so far for the same peer, the latter is kept, so that a stale address
+// card cannot override a newer one.
+func (s *StaticDiscoverer) Reload() error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.state != staticDiscovererStarted {
+		return fmt.Errorf("cannot reload StaticDiscoverer that is not started, state was: %v", s.state)
+	}
+	return s.reload()
+}
+
+func (s *StaticDiscoverer) reload() error {
+	// Held throughout, so that a reload that read an older address book
+	// cannot overwrite the result of one that read a newer one, and so that
+	// source isn't called concurrently.
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	book, err := s.source()
+	if err != nil {
+		return err
+	}
+
+	addresses := make(map[ragetypes.PeerID][]ragetypes.Address, len(book.Peers))
+	for pid, addrs := range book.Peers {
+		addresses[pid] = addrs
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	bestAnnouncements := make(map[ragetypes.PeerID]Announcement)
+	for _, ann := range book.Announcements {
+		if err := ann.verify(); err != nil {
+			s.logger.Warn("Dropping invalid announcement from address book", commontypes.LogFields{
+				"announcement": ann,
+				"error":        err,
+			})
+			continue
+		}
+		pid, err := ann.PeerID()
+		if err != nil {
+			s.logger.Warn("Dropping announcement with invalid public key from address book", commontypes.LogFields{
+				"announcement": ann,
+				"error":        err,
+			})
+			continue
+		}
+		if best, ok := s.bestAnnouncement[pid]; ok && ann.Counter < best.Counter {
+			s.logger.Warn("Ignoring stale announcement from address book, keeping previous one", commontypes.LogFields{
+				"announcement": ann,
+				"bestCounter":  best.Counter,
+			})
+			ann = best
+		}
+		if best, ok := bestAnnouncements[pid]; ok && best.Counter >= ann.Counter {
+			continue
+		}
+		bestAnnouncements[pid] = ann
+	}
+	for pid, ann := range bestAnnouncements {
+		addresses[pid] = dedup(append(append([]ragetypes.Address{}, ann.Addrs...), addresses[pid]...))
+	}
+	// Peers whose announcements have been removed from the address book are
+	// forgotten.
+	s.bestAnnouncement = bestAnnouncements
+
+	s.addresses = addresses
+	s.logger.Info("Loaded address book", commontypes.LogFields{
+		"peers":         len(addresses),
+		"announcements": len(bestAnnouncements),
+	})
+	return nil
+}
+
+func (s *StaticDiscoverer) Close() error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.state != staticDiscovererStarted {
+		return fmt.Errorf("cannot close StaticDiscoverer that is not started, state was: %v", s.state)
+	}
+	s.state = staticDiscovererClosed
+
+	s.ctxCancel()
+	s.proc.Wait()
+	return nil
+}
+
+func (s *StaticDiscoverer) AddGroup(digest types.ConfigDigest, onodes []ragetypes.PeerID, bnodes []ragetypes.PeerInfo) error {
+	s.logger.Info("StaticDiscoverer: Adding group", commontypes.LogFields{
+		"configDigest": digest,
+		"oracles":      onodes,
+		"bootstraps":   bnodes,
+	})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exists := s.groups[digest]; exists {
+		return fmt.Errorf("asked to add group with digest we already have (digest: %s)", digest.Hex())
+	}
+	newGroup := group{oracleNodes: onodes, bootstrapperNodes: bnodes}
+	s.groups[digest] = &newGroup
+	for _, pid := range newGroup.peerIDs() {
+		s.numGroupsByPeer[pid]++
+	}
+	for _, bs := range bnodes {
+		if _, exists := s.bootstrappers[bs.ID]; !exists {
+			s.bootstrappers[bs.ID] = make(map[ragetypes.Address]int)
+		}
+		for _, addr := range bs.Addrs {
+			s.bootstrappers[bs.ID][addr]++
+			s.resolver.add(addr)
+		}
+	}
+	return nil
+}
+
+// RemoveGroup should not block or panic even if the discoverer is closed.
+func (s *StaticDiscoverer) RemoveGroup(digest types.ConfigDigest) error {
+	s.logger.Info("StaticDiscoverer: Removing group", commontypes.LogFields{"configDigest": digest})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	goneGroup, exists := s.groups[digest]
+	if !exists {
+		return fmt.Errorf("can't remove group that is not registered (digest: %s)", digest.Hex())
+	}
+	delete(s.groups, digest)
+
+	for _, pid := range goneGroup.peerIDs() {
+		s.numGroupsByPeer[pid]--
+		if s.numGroupsByPeer[pid] == 0 {
+			delete(s.numGroupsByPeer, pid)
+		}
+	}
+	for _, bs := range goneGroup.bootstrapperNodes {
+		for _, addr := range bs.Addrs {
+			s.resolver.remove(addr)
+			s.bootstrappers[bs.ID][addr]--
+			if s.bootstrappers[bs.ID][addr] == 0 {
+				delete(s.bootstrappers[bs.ID], addr)
+			}
+		}
+		if len(s.bootstrappers[bs.ID]) == 0 {
+			delete(s.bootstrappers, bs.ID)
+		}
+	}
+	return nil
+}
+
+func (s *StaticDiscoverer) FindPeer(peer ragetypes.PeerID) ([]ragetypes.Address, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.numGroupsByPeer[peer] == 0 {
+		return nil, nil
+	}
+
+	var addrs []ragetypes.Address
+	// The addresses we know from local configuration take priority
+	for baddr := range s.bootstrappers[peer] {
+		addrs = append(addrs, s.resolver.addresses(baddr)...)
+	}
+	addrs = append(addrs, s.addresses[peer]...)
+	return dedup(addrs), nil
+}
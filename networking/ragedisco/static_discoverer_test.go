@@ -0,0 +1,280 @@
+package ragedisco_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/networking/ragedisco"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+type testPeer struct {
+	id ragetypes.PeerID
+	sk ed25519.PrivateKey
+}
+
+func newTestPeer(t *testing.T) testPeer {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := ragetypes.PeerIDFromPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testPeer{id, sk}
+}
+
+func (p testPeer) announce(t *testing.T, counter uint64, addrs ...ragetypes.Address) ragedisco.Announcement {
+	ann, err := ragedisco.NewSignedAnnouncement(p.sk, addrs, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ann
+}
+
+// startStaticDiscoverer starts a StaticDiscoverer with a single group
+// consisting of peers.
+func startStaticDiscoverer(t *testing.T, source ragedisco.AddressBookSource, reloadInterval time.Duration, peers ...testPeer) *ragedisco.StaticDiscoverer {
+	d := ragedisco.NewStaticDiscoverer(source, reloadInterval)
+	if err := d.Start(nil, nil, loghelper.MakeRootLoggerWithContext(nopLogger{})); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	var ids []ragetypes.PeerID
+	for _, p := range peers {
+		ids = append(ids, p.id)
+	}
+	if err := d.AddGroup(types.ConfigDigest{1}, ids, nil); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func checkAddresses(t *testing.T, d *ragedisco.StaticDiscoverer, peer testPeer, expected ...ragetypes.Address) {
+	t.Helper()
+	addrs, err := d.FindPeer(peer.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(addrs) != fmt.Sprint(expected) {
+		t.Errorf("found addresses %v, expected %v", addrs, expected)
+	}
+}
+
+// mutableSource is an AddressBookSource whose AddressBook can be swapped.
+type mutableSource struct {
+	mutex sync.Mutex
+	book  ragedisco.AddressBook
+}
+
+func (s *mutableSource) set(book ragedisco.AddressBook) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.book = book
+}
+
+func (s *mutableSource) source() (ragedisco.AddressBook, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.book, nil
+}
+
+func TestStaticDiscovererVerifiesAnnouncements(t *testing.T) {
+	signed, forged, wrongKey, outsider := newTestPeer(t), newTestPeer(t), newTestPeer(t), newTestPeer(t)
+
+	forgedAnn := forged.announce(t, 1, "10.0.0.2:1")
+	forgedAnn.Addrs = []ragetypes.Address{"10.6.6.6:1"}
+	wrongKeyAnn := wrongKey.announce(t, 1, "10.0.0.3:1")
+	wrongKeyAnn.PublicKey = signed.sk.Public().(ed25519.PublicKey)
+
+	source := &mutableSource{}
+	source.set(ragedisco.AddressBook{
+		map[ragetypes.PeerID][]ragetypes.Address{
+			signed.id:   {"10.1.0.1:1"},
+			forged.id:   {"10.1.0.2:1"},
+			outsider.id: {"10.1.0.4:1"},
+		},
+		[]ragedisco.Announcement{
+			signed.announce(t, 1, "10.0.0.1:1", "10.1.0.1:1"),
+			forgedAnn,
+			wrongKeyAnn,
+			outsider.announce(t, 1, "10.0.0.4:1"),
+		},
+	})
+	d := startStaticDiscoverer(t, source.source, 0, signed, forged, wrongKey)
+
+	// announced addresses come first
+	checkAddresses(t, d, signed, "10.0.0.1:1", "10.1.0.1:1")
+	// invalid announcements are dropped
+	checkAddresses(t, d, forged, "10.1.0.2:1")
+	checkAddresses(t, d, wrongKey)
+	// peers outside of any group aren't found
+	checkAddresses(t, d, outsider)
+}
+
+func TestStaticDiscovererKeepsNewestAnnouncement(t *testing.T) {
+	peer := newTestPeer(t)
+	source := &mutableSource{}
+	source.set(ragedisco.AddressBook{nil, []ragedisco.Announcement{
+		peer.announce(t, 5, "10.0.0.5:1"),
+		peer.announce(t, 4, "10.0.0.4:1"),
+	}})
+	d := startStaticDiscoverer(t, source.source, 0, peer)
+	checkAddresses(t, d, peer, "10.0.0.5:1")
+
+	// a stale announcement doesn't replace a newer one seen before
+	source.set(ragedisco.AddressBook{nil, []ragedisco.Announcement{peer.announce(t, 3, "10.0.0.3:1")}})
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkAddresses(t, d, peer, "10.0.0.5:1")
+
+	source.set(ragedisco.AddressBook{nil, []ragedisco.Announcement{peer.announce(t, 6, "10.0.0.6:1")}})
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkAddresses(t, d, peer, "10.0.0.6:1")
+
+	// once removed from the address book, the peer is forgotten
+	source.set(ragedisco.AddressBook{})
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkAddresses(t, d, peer)
+}
+
+func writeAddressBook(t *testing.T, path string, peers map[ragetypes.PeerID][]ragetypes.Address, anns ...ragedisco.Announcement) {
+	var raw struct {
+		Peers         map[ragetypes.PeerID][]ragetypes.Address `json:"peers"`
+		Announcements [][]byte                                 `json:"announcements"`
+	}
+	raw.Peers = peers
+	for _, ann := range anns {
+		b, err := ann.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw.Announcements = append(raw.Announcements, b)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename so that the discoverer never reads a partially written file
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticDiscovererHotReload(t *testing.T) {
+	a, b := newTestPeer(t), newTestPeer(t)
+	path := filepath.Join(t.TempDir(), "addressbook.json")
+	writeAddressBook(t, path, map[ragetypes.PeerID][]ragetypes.Address{a.id: {"10.0.0.1:1"}})
+
+	d := startStaticDiscoverer(t, ragedisco.AddressBookFromFile(path), 5*time.Millisecond, a, b)
+	checkAddresses(t, d, a, "10.0.0.1:1")
+	checkAddresses(t, d, b)
+
+	// Reload and the reload loop run concurrently
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if err := d.Reload(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+
+	writeAddressBook(t, path, map[ragetypes.PeerID][]ragetypes.Address{a.id: {"10.0.0.2:1"}}, b.announce(t, 1, "10.0.1.1:1"))
+	waitForAddresses(t, d, a, "10.0.0.2:1")
+	waitForAddresses(t, d, b, "10.0.1.1:1")
+	close(stop)
+	wg.Wait()
+
+	// an invalid address book is rejected and the previous one kept
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Reload(); err == nil {
+		t.Error("invalid address book was loaded")
+	}
+	time.Sleep(20 * time.Millisecond)
+	checkAddresses(t, d, a, "10.0.0.2:1")
+	checkAddresses(t, d, b, "10.0.1.1:1")
+}
+
+func waitForAddresses(t *testing.T, d *ragedisco.StaticDiscoverer, peer testPeer, expected ...ragetypes.Address) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		addrs, err := d.FindPeer(peer.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(addrs) == fmt.Sprint(expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("found addresses %v, expected %v", addrs, expected)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStaticDiscovererLifecycle(t *testing.T) {
+	source := &mutableSource{}
+	d := ragedisco.NewStaticDiscoverer(source.source, 0)
+	if err := d.Reload(); err == nil {
+		t.Error("unstarted discoverer reloaded")
+	}
+	if err := d.Start(nil, nil, loghelper.MakeRootLoggerWithContext(nopLogger{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(nil, nil, loghelper.MakeRootLoggerWithContext(nopLogger{})); err == nil {
+		t.Error("discoverer started twice")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Reload(); err == nil {
+		t.Error("closed discoverer reloaded")
+	}
+
+	failing := ragedisco.NewStaticDiscoverer(func() (ragedisco.AddressBook, error) {
+		return ragedisco.AddressBook{}, fmt.Errorf("unavailable")
+	}, 0)
+	if err := failing.Start(nil, nil, loghelper.MakeRootLoggerWithContext(nopLogger{})); err == nil {
+		t.Error("discoverer started without address book")
+	}
+}
@@ -0,0 +1,125 @@
+// Package byzantine provides a commontypes.BinaryNetworkEndpoint decorator
+// that can be programmed to make an oracle misbehave on the wire. It is meant
+// for testing that a deployment (and in particular a custom ReportingPlugin)
+// keeps working in the presence of up to f Byzantine oracles, without having
+// to maintain a forked node.
+//
+// The decorator operates on serialized messages: outgoing payloads are
+// decoded into their protobuf representation, tampered with, and re-encoded.
+// Receiving oracles thus exercise the same deserialization and validation
+// code paths they would exercise when talking to a real malicious oracle.
+//
+// Only outgoing messages are affected. Incoming messages are passed through
+// unchanged.
+package byzantine
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+)
+
+// Protocol identifies the wire format of the messages passing through an
+// endpoint.
+type Protocol int
+
+const (
+	_ Protocol = iota
+	ProtocolOCR2
+	ProtocolOCR3
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolOCR2:
+		return "OCR2"
+	case ProtocolOCR3:
+		return "OCR3"
+	default:
+		return fmt.Sprintf("Protocol(%d)", int(p))
+	}
+}
+
+// Behavior describes how a Byzantine endpoint tampers with outgoing messages.
+// The zero value describes an honest endpoint.
+//
+// Probabilities are evaluated independently for every outgoing message (and,
+// for broadcasts, for every recipient), using a pseudo-random source seeded
+// with Seed.
+type Behavior struct {
+	// Seed for the endpoint's source of randomness
+	Seed int64
+
+	// Probability with which a broadcast MessageEpochStart, MessageRoundStart
+	// or MessageProposal (OCR3), or MessageObserveReq, MessageReportReq or
+	// MessageFinal (OCR2) is equivocated. An equivocated broadcast delivers
+	// the original payload to some recipients and a conflicting payload to
+	// the EquivocationTargets. The conflicting payload omits the last element
+	// of the first repeated field that has more than one element (e.g. one of
+	// the attributed signed observations of a proposal), or, if there is no
+	// such field, has an extra byte appended to the first bytes field (e.g. the
+	// query).
+	EquivocationProbability float64
+	// Recipients of the conflicting payload. If empty, the oracles in the upper
+	// half of the oracle id range receive the conflicting payload.
+	EquivocationTargets []commontypes.OracleID
+
+	// Probability with which an outgoing message is followed by a replay of a
+	// previously sent message from an earlier epoch.
+	ReplayProbability float64
+
+	// Probability with which every signature contained in an outgoing message
+	// gets a bit flipped.
+	SignatureCorruptionProbability float64
+
+	// Probability with which an outgoing message is inflated by appending
+	// OversizePadding bytes to every bytes field it contains. This is meant to
+	// produce messages that fail the receiver's CheckSize. Note that the
+	// underlying endpoint may drop messages exceeding its MaxMessageLength
+	// before they ever reach the receiver.
+	OversizeProbability float64
+	OversizePadding     int
+
+	// If set, the endpoint drops all messages that only the leader sends, i.e.
+	// the oracle goes silent whenever it is the leader.
+	SilentAsLeader bool
+}
+
+func (b Behavior) honest() bool {
+	return b.EquivocationProbability == 0 &&
+		b.ReplayProbability == 0 &&
+		b.SignatureCorruptionProbability == 0 &&
+		b.OversizeProbability == 0 &&
+		!b.SilentAsLeader
+}
+
+func checkProbability(name string, p float64) error {
+	if !(0 <= p && p <= 1) {
+		return fmt.Errorf("%v (%v) must be in [0, 1]", name, p)
+	}
+	return nil
+}
+
+// Validate checks that b is well-formed.
+func (b Behavior) Validate() error {
+	for _, p := range []struct {
+		name  string
+		value float64
+	}{
+		{"EquivocationProbability", b.EquivocationProbability},
+		{"ReplayProbability", b.ReplayProbability},
+		{"SignatureCorruptionProbability", b.SignatureCorruptionProbability},
+		{"OversizeProbability", b.OversizeProbability},
+	} {
+		if err := checkProbability(p.name, p.value); err != nil {
+			return err
+		}
+	}
+	if b.OversizePadding < 0 {
+		return fmt.Errorf("OversizePadding (%v) must be non-negative", b.OversizePadding)
+	}
+	if b.OversizeProbability > 0 && b.OversizePadding == 0 {
+		return fmt.Errorf("OversizePadding must be positive if OversizeProbability is positive")
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+package byzantine
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	ocr2serialization "github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr2/serialization"
+	ocr3serialization "github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"google.golang.org/protobuf/proto"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+type delivery struct {
+	payload []byte
+	// -1 for broadcasts
+	to int
+}
+
+// recordingEndpoint records the messages sent through it.
+type recordingEndpoint struct {
+	mutex      sync.Mutex
+	deliveries []delivery
+	closed     bool
+}
+
+var _ commontypes.BinaryNetworkEndpoint = (*recordingEndpoint)(nil)
+
+func (e *recordingEndpoint) SendTo(payload []byte, to commontypes.OracleID) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deliveries = append(e.deliveries, delivery{payload, int(to)})
+}
+
+func (e *recordingEndpoint) Broadcast(payload []byte) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deliveries = append(e.deliveries, delivery{payload, -1})
+}
+
+func (e *recordingEndpoint) Receive() <-chan commontypes.BinaryMessageWithSender {
+	return nil
+}
+
+func (e *recordingEndpoint) Start() error {
+	return nil
+}
+
+func (e *recordingEndpoint) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.closed = true
+	return nil
+}
+
+// take returns and forgets the deliveries recorded so far.
+func (e *recordingEndpoint) take() []delivery {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	deliveries := e.deliveries
+	e.deliveries = nil
+	return deliveries
+}
+
+func newTestEndpoint(t *testing.T, protocol Protocol, n int, behavior Behavior) (*Endpoint, *recordingEndpoint) {
+	t.Helper()
+	recording := &recordingEndpoint{}
+	endpoint, err := NewEndpoint(recording, protocol, n, behavior, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return endpoint, recording
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func ocr3Proposal(t *testing.T, epoch uint64, observers ...uint32) []byte {
+	var asos []*ocr3serialization.AttributedSignedObservation
+	for _, observer := range observers {
+		asos = append(asos, &ocr3serialization.AttributedSignedObservation{
+			SignedObservation: &ocr3serialization.SignedObservation{
+				Observation: []byte(fmt.Sprintf("observation %v", observer)),
+				Signature:   []byte{0xaa, 0xbb},
+			},
+			Observer: observer,
+		})
+	}
+	return marshal(t, &ocr3serialization.MessageWrapper{
+		Msg: &ocr3serialization.MessageWrapper_MessageProposal{
+			MessageProposal: &ocr3serialization.MessageProposal{
+				Epoch:                        epoch,
+				SeqNr:                        1,
+				AttributedSignedObservations: asos,
+			},
+		},
+	})
+}
+
+func ocr3RoundStart(t *testing.T, epoch uint64, query []byte) []byte {
+	return marshal(t, &ocr3serialization.MessageWrapper{
+		Msg: &ocr3serialization.MessageWrapper_MessageRoundStart{
+			MessageRoundStart: &ocr3serialization.MessageRoundStart{Epoch: epoch, SeqNr: 1, Query: query},
+		},
+	})
+}
+
+func ocr3Prepare(t *testing.T, epoch uint64) []byte {
+	return marshal(t, &ocr3serialization.MessageWrapper{
+		Msg: &ocr3serialization.MessageWrapper_MessagePrepare{
+			MessagePrepare: &ocr3serialization.MessagePrepare{Epoch: epoch, SeqNr: 1, Signature: []byte{0xaa, 0xbb}},
+		},
+	})
+}
+
+func unmarshalOCR3(t *testing.T, payload []byte) *ocr3serialization.MessageWrapper {
+	t.Helper()
+	wrapper := &ocr3serialization.MessageWrapper{}
+	if err := proto.Unmarshal(payload, wrapper); err != nil {
+		t.Fatal(err)
+	}
+	return wrapper
+}
+
+func TestBehaviorValidate(t *testing.T) {
+	for _, b := range []Behavior{
+		{},
+		{EquivocationProbability: 1, ReplayProbability: 0.5, SilentAsLeader: true},
+		{OversizeProbability: 1, OversizePadding: 1},
+	} {
+		if err := b.Validate(); err != nil {
+			t.Errorf("%+v: %v", b, err)
+		}
+	}
+	for _, b := range []Behavior{
+		{EquivocationProbability: -0.1},
+		{ReplayProbability: 1.1},
+		{SignatureCorruptionProbability: 2},
+		{OversizePadding: -1},
+		{OversizeProbability: 0.5},
+	} {
+		if err := b.Validate(); err == nil {
+			t.Errorf("%+v is valid", b)
+		}
+	}
+
+	if _, err := NewEndpoint(&recordingEndpoint{}, ProtocolOCR3, 4, Behavior{ReplayProbability: 2}, nopLogger{}); err == nil {
+		t.Error("created endpoint with invalid behavior")
+	}
+	if _, err := NewEndpoint(&recordingEndpoint{}, Protocol(0), 4, Behavior{}, nopLogger{}); err == nil {
+		t.Error("created endpoint with unknown protocol")
+	}
+	if _, err := NewEndpoint(&recordingEndpoint{}, ProtocolOCR3, 0, Behavior{}, nopLogger{}); err == nil {
+		t.Error("created endpoint for zero oracles")
+	}
+	endpoint, _ := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{})
+	if err := endpoint.SetBehavior(Behavior{EquivocationProbability: -1}); err == nil {
+		t.Error("set invalid behavior")
+	}
+}
+
+func TestEndpointHonest(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{})
+	payload := ocr3Proposal(t, 1, 0, 1)
+
+	endpoint.Broadcast(payload)
+	endpoint.SendTo(payload, 2)
+	deliveries := recording.take()
+	if len(deliveries) != 2 || deliveries[0].to != -1 || deliveries[1].to != 2 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	for _, d := range deliveries {
+		if !bytes.Equal(d.payload, payload) {
+			t.Error("honest endpoint changed payload")
+		}
+	}
+
+	if err := endpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !recording.closed {
+		t.Error("underlying endpoint wasn't closed")
+	}
+	if statuses := endpoint.PeerStatuses(); statuses != nil {
+		t.Errorf("unexpected peer statuses %v", statuses)
+	}
+}
+
+func TestEndpointPassesThroughUndecodableMessages(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{
+		SignatureCorruptionProbability: 1,
+		SilentAsLeader:                 true,
+	})
+	payload := []byte("not a protobuf \xff\xff")
+	endpoint.SendTo(payload, 1)
+	if deliveries := recording.take(); len(deliveries) != 1 || !bytes.Equal(deliveries[0].payload, payload) {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestEndpointEquivocates(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{EquivocationProbability: 1})
+
+	// by default, the upper half of the oracles receives the conflicting
+	// payload, which is missing the last attributed signed observation
+	payload := ocr3Proposal(t, 1, 0, 1, 2)
+	endpoint.Broadcast(payload)
+	deliveries := recording.take()
+	if len(deliveries) != 4 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	for to, d := range deliveries {
+		if d.to != to {
+			t.Fatalf("delivery %v went to %v", to, d.to)
+		}
+		asos := unmarshalOCR3(t, d.payload).GetMessageProposal().GetAttributedSignedObservations()
+		if to < 2 && !bytes.Equal(d.payload, payload) {
+			t.Errorf("oracle %v received conflicting payload", to)
+		}
+		if to >= 2 && (len(asos) != 2 || asos[1].Observer != 1) {
+			t.Errorf("oracle %v received observations %v", to, asos)
+		}
+	}
+
+	// an explicit target, and an empty query gets an extra byte
+	if err := endpoint.SetBehavior(Behavior{EquivocationProbability: 1, EquivocationTargets: []commontypes.OracleID{1}}); err != nil {
+		t.Fatal(err)
+	}
+	endpoint.Broadcast(ocr3RoundStart(t, 1, nil))
+	for to, d := range recording.take() {
+		query := unmarshalOCR3(t, d.payload).GetMessageRoundStart().GetQuery()
+		if to == 1 && !bytes.Equal(query, []byte{0x01}) {
+			t.Errorf("oracle %v received query %x", to, query)
+		}
+		if to != 1 && len(query) != 0 {
+			t.Errorf("oracle %v received conflicting query %x", to, query)
+		}
+	}
+
+	// messages that followers send too are never equivocated
+	payload = ocr3Prepare(t, 1)
+	endpoint.Broadcast(payload)
+	for to, d := range recording.take() {
+		if !bytes.Equal(d.payload, payload) {
+			t.Errorf("oracle %v received conflicting prepare", to)
+		}
+	}
+
+	// neither are messages sent to a single oracle
+	payload = ocr3Proposal(t, 1, 0, 1)
+	endpoint.SendTo(payload, 1)
+	if deliveries := recording.take(); len(deliveries) != 1 || !bytes.Equal(deliveries[0].payload, payload) {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestEndpointEquivocatesOCR2(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR2, 4, Behavior{EquivocationProbability: 1})
+	endpoint.Broadcast(marshal(t, &ocr2serialization.MessageWrapper{
+		Msg: &ocr2serialization.MessageWrapper_MessageObserveReq{
+			MessageObserveReq: &ocr2serialization.MessageObserveReq{Epoch: 1, Round: 1, Query: []byte("query")},
+		},
+	}))
+	deliveries := recording.take()
+	if len(deliveries) != 4 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	for to, d := range deliveries {
+		wrapper := &ocr2serialization.MessageWrapper{}
+		if err := proto.Unmarshal(d.payload, wrapper); err != nil {
+			t.Fatal(err)
+		}
+		expected := "query"
+		if to >= 2 {
+			expected = "query\x01"
+		}
+		if query := string(wrapper.GetMessageObserveReq().GetQuery()); query != expected {
+			t.Errorf("oracle %v received query %q, expected %q", to, query, expected)
+		}
+	}
+}
+
+func TestEndpointCorruptsSignatures(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{SignatureCorruptionProbability: 1})
+
+	endpoint.SendTo(ocr3Prepare(t, 1), 0)
+	endpoint.SendTo(ocr3Proposal(t, 1, 0, 1), 0)
+	deliveries := recording.take()
+	if len(deliveries) != 2 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	if sig := unmarshalOCR3(t, deliveries[0].payload).GetMessagePrepare().GetSignature(); !bytes.Equal(sig, []byte{0xab, 0xbb}) {
+		t.Errorf("prepare signature is %x", sig)
+	}
+	// nested signatures are corrupted as well, other fields are left alone
+	for _, aso := range unmarshalOCR3(t, deliveries[1].payload).GetMessageProposal().GetAttributedSignedObservations() {
+		so := aso.GetSignedObservation()
+		if !bytes.Equal(so.GetSignature(), []byte{0xab, 0xbb}) {
+			t.Errorf("observation signature is %x", so.GetSignature())
+		}
+		if string(so.GetObservation()) != fmt.Sprintf("observation %v", aso.Observer) {
+			t.Errorf("observation changed to %q", so.GetObservation())
+		}
+	}
+}
+
+func TestEndpointInflates(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{OversizeProbability: 1, OversizePadding: 1000})
+
+	endpoint.SendTo(ocr3Proposal(t, 1, 0), 0)
+	deliveries := recording.take()
+	if len(deliveries) != 1 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	so := unmarshalOCR3(t, deliveries[0].payload).GetMessageProposal().GetAttributedSignedObservations()[0].GetSignedObservation()
+	if len(so.GetObservation()) != len("observation 0")+1000 || len(so.GetSignature()) != 2+1000 {
+		t.Errorf("inflated to observation of length %v and signature of length %v", len(so.GetObservation()), len(so.GetSignature()))
+	}
+}
+
+func TestEndpointSilentAsLeader(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{SilentAsLeader: true})
+
+	endpoint.Broadcast(ocr3Proposal(t, 1, 0))
+	endpoint.SendTo(ocr3RoundStart(t, 1, nil), 1)
+	if deliveries := recording.take(); len(deliveries) != 0 {
+		t.Errorf("leader messages were sent: %+v", deliveries)
+	}
+
+	payload := ocr3Prepare(t, 1)
+	endpoint.Broadcast(payload)
+	if deliveries := recording.take(); len(deliveries) != 4 {
+		t.Errorf("unexpected deliveries of prepare %+v", deliveries)
+	}
+}
+
+func TestEndpointReplays(t *testing.T) {
+	endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, Behavior{ReplayProbability: 1})
+
+	// nothing to replay yet
+	first := ocr3Prepare(t, 1)
+	endpoint.SendTo(first, 0)
+	if deliveries := recording.take(); len(deliveries) != 1 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+
+	// messages from the same epoch aren't replayed
+	endpoint.SendTo(ocr3Prepare(t, 1), 0)
+	if deliveries := recording.take(); len(deliveries) != 1 {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+
+	second := ocr3Prepare(t, 2)
+	endpoint.SendTo(second, 3)
+	deliveries := recording.take()
+	if len(deliveries) != 2 || !bytes.Equal(deliveries[0].payload, second) || !bytes.Equal(deliveries[1].payload, first) || deliveries[1].to != 3 {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestEndpointSeed(t *testing.T) {
+	behavior := Behavior{Seed: 42, SignatureCorruptionProbability: 0.5}
+	run := func() []bool {
+		endpoint, recording := newTestEndpoint(t, ProtocolOCR3, 4, behavior)
+		payload := ocr3Prepare(t, 1)
+		var corrupted []bool
+		for i := 0; i < 64; i++ {
+			endpoint.SendTo(payload, 0)
+			deliveries := recording.take()
+			corrupted = append(corrupted, !bytes.Equal(deliveries[0].payload, payload))
+		}
+		return corrupted
+	}
+	a, b := run(), run()
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("endpoints with the same seed behaved differently:\n%v\n%v", a, b)
+	}
+	count := 0
+	for _, c := range a {
+		if c {
+			count++
+		}
+	}
+	if count == 0 || count == len(a) {
+		t.Errorf("corrupted %v out of %v messages", count, len(a))
+	}
+}
+
+type testFactory struct {
+	endpoint *recordingEndpoint
+}
+
+func (f *testFactory) NewEndpoint(types.ConfigDigest, []string, []commontypes.BootstrapperLocator, int, types.BinaryNetworkEndpointLimits) (commontypes.BinaryNetworkEndpoint, error) {
+	return f.endpoint, nil
+}
+
+func (f *testFactory) PeerID() string {
+	return "peer"
+}
+
+func TestFactory(t *testing.T) {
+	if _, err := NewFactory(&testFactory{}, Protocol(3), Behavior{}, nopLogger{}); err == nil {
+		t.Error("created factory with unknown protocol")
+	}
+	if _, err := NewFactory(&testFactory{}, ProtocolOCR3, Behavior{OversizeProbability: 1}, nopLogger{}); err == nil {
+		t.Error("created factory with invalid behavior")
+	}
+
+	recording := &recordingEndpoint{}
+	factory, err := NewFactory(&testFactory{recording}, ProtocolOCR3, Behavior{}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factory.PeerID() != "peer" {
+		t.Errorf("unexpected peer id %v", factory.PeerID())
+	}
+	endpoint, err := factory.NewEndpoint(types.ConfigDigest{}, []string{"a", "b", "c", "d"}, nil, 1, types.BinaryNetworkEndpointLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.(*Endpoint).n != 4 {
+		t.Errorf("endpoint is for %v oracles", endpoint.(*Endpoint).n)
+	}
+
+	// the factory's behavior applies to endpoints created earlier
+	if err := factory.SetBehavior(Behavior{SilentAsLeader: true}); err != nil {
+		t.Fatal(err)
+	}
+	endpoint.Broadcast(ocr3Proposal(t, 1, 0))
+	if deliveries := recording.take(); len(deliveries) != 0 {
+		t.Errorf("leader messages were sent: %+v", deliveries)
+	}
+}
+
+type reportingEndpoint struct {
+	recordingEndpoint
+}
+
+func (e *reportingEndpoint) PeerStatuses() []types.PeerStatus {
+	return []types.PeerStatus{{OracleID: 1, PeerID: "peer", Connected: true}}
+}
+
+func TestEndpointPeerStatuses(t *testing.T) {
+	endpoint, err := NewEndpoint(&reportingEndpoint{}, ProtocolOCR3, 4, Behavior{}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses := endpoint.PeerStatuses(); len(statuses) != 1 || statuses[0].PeerID != "peer" {
+		t.Errorf("unexpected peer statuses %+v", statuses)
+	}
+}
@@ -0,0 +1,296 @@
+package byzantine
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Maximum number of previously sent messages kept around for replays
+const replayHistorySize = 256
+
+type sentMessage struct {
+	epoch   uint64
+	payload []byte
+}
+
+// behaviorCell holds a Behavior that can be swapped out while endpoints are
+// running. It is shared between a Factory and the endpoints it creates.
+type behaviorCell struct {
+	mutex    sync.Mutex
+	behavior Behavior
+	// bumped every time behavior changes, so that endpoints know when to
+	// reseed
+	version uint64
+}
+
+func (c *behaviorCell) get() (Behavior, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.behavior, c.version
+}
+
+func (c *behaviorCell) set(b Behavior) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.behavior = b
+	c.version++
+	return nil
+}
+
+// Endpoint wraps a commontypes.BinaryNetworkEndpoint and tampers with the
+// messages sent through it according to its Behavior.
+type Endpoint struct {
+	endpoint commontypes.BinaryNetworkEndpoint
+	codec    codec
+	n        int
+	behavior *behaviorCell
+	logger   loghelper.LoggerWithContext
+
+	mutex   sync.Mutex
+	version uint64
+	rng     *rand.Rand
+	history []sentMessage
+}
+
+var _ commontypes.BinaryNetworkEndpoint = (*Endpoint)(nil)
+var _ types.PeerStatusReporter = (*Endpoint)(nil)
+
+// NewEndpoint wraps endpoint, which connects n oracles speaking protocol.
+func NewEndpoint(
+	endpoint commontypes.BinaryNetworkEndpoint,
+	protocol Protocol,
+	n int,
+	behavior Behavior,
+	logger commontypes.Logger,
+) (*Endpoint, error) {
+	cell := &behaviorCell{}
+	if err := cell.set(behavior); err != nil {
+		return nil, err
+	}
+	return newEndpoint(endpoint, protocol, n, cell, logger)
+}
+
+func newEndpoint(
+	endpoint commontypes.BinaryNetworkEndpoint,
+	protocol Protocol,
+	n int,
+	behavior *behaviorCell,
+	logger commontypes.Logger,
+) (*Endpoint, error) {
+	codec, ok := codecs[protocol]
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol %v", protocol)
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("n (%v) must be positive", n)
+	}
+	return &Endpoint{
+		endpoint,
+		codec,
+		n,
+		behavior,
+		loghelper.MakeRootLoggerWithContext(logger).MakeChild(commontypes.LogFields{
+			"byzantine": protocol.String(),
+		}),
+
+		sync.Mutex{},
+		0,
+		nil,
+		nil,
+	}, nil
+}
+
+// SetBehavior changes the endpoint's behavior. Takes effect for all
+// subsequently sent messages.
+func (e *Endpoint) SetBehavior(behavior Behavior) error {
+	return e.behavior.set(behavior)
+}
+
+// must hold mutex
+func (e *Endpoint) currentBehavior() Behavior {
+	b, version := e.behavior.get()
+	if e.rng == nil || e.version != version {
+		e.rng = rand.New(rand.NewSource(b.Seed))
+		e.version = version
+	}
+	return b
+}
+
+// must hold mutex
+func (e *Endpoint) chance(p float64) bool {
+	// Don't consume randomness for disabled behaviors, so that enabling
+	// one behavior doesn't change the randomness seen by another.
+	if p <= 0 {
+		return false
+	}
+	return e.rng.Float64() < p
+}
+
+func (e *Endpoint) isEquivocationTarget(b Behavior, to commontypes.OracleID) bool {
+	if len(b.EquivocationTargets) == 0 {
+		return int(to) >= (e.n+1)/2
+	}
+	for _, t := range b.EquivocationTargets {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveries computes the payloads to send to the oracle "to" in place of
+// payload.
+//
+// must hold mutex
+func (e *Endpoint) deliveries(payload []byte, to commontypes.OracleID, equivocate bool) [][]byte {
+	b := e.currentBehavior()
+
+	dm, err := e.codec.decode(payload)
+	if err != nil {
+		// Not something we understand. Pass it through untouched.
+		e.logger.Warn("Byzantine Endpoint: could not decode outgoing message, sending as is", commontypes.LogFields{
+			"error": err,
+		})
+		return [][]byte{payload}
+	}
+
+	fields := commontypes.LogFields{
+		"to":   to,
+		"type": dm.typ,
+	}
+
+	leaderMessage := e.codec.leaderMessageTypes[dm.typ]
+	if b.SilentAsLeader && leaderMessage {
+		e.logger.Debug("Byzantine Endpoint: staying silent as leader", fields)
+		return nil
+	}
+
+	tampered := false
+	tamper := func(what string, f func(protoreflect.Message) bool) {
+		var ok bool
+		dm, ok = dm.tamper(f)
+		if ok {
+			tampered = true
+			e.logger.Debug(fmt.Sprintf("Byzantine Endpoint: %s", what), fields)
+		}
+	}
+
+	if equivocate && leaderMessage && e.isEquivocationTarget(b, to) {
+		tamper("equivocating", conflict)
+	}
+	if e.chance(b.SignatureCorruptionProbability) {
+		tamper("corrupting signatures", corruptSignatures)
+	}
+	if e.chance(b.OversizeProbability) {
+		tamper("inflating message", inflate(b.OversizePadding))
+	}
+
+	if tampered {
+		payload, err = dm.encode()
+		if err != nil {
+			e.logger.Error("Byzantine Endpoint: could not encode tampered message, dropping it", commontypes.LogFields{
+				"to":    to,
+				"type":  dm.typ,
+				"error": err,
+			})
+			return nil
+		}
+	}
+
+	result := [][]byte{payload}
+
+	epoch, hasEpoch := dm.epoch()
+	if !hasEpoch {
+		return result
+	}
+
+	if e.chance(b.ReplayProbability) {
+		var stale [][]byte
+		for _, m := range e.history {
+			if m.epoch < epoch {
+				stale = append(stale, m.payload)
+			}
+		}
+		if len(stale) != 0 {
+			e.logger.Debug("Byzantine Endpoint: replaying message from stale epoch", fields)
+			result = append(result, stale[e.rng.Intn(len(stale))])
+		}
+	}
+
+	e.history = append(e.history, sentMessage{epoch, payload})
+	if len(e.history) > replayHistorySize {
+		e.history = e.history[len(e.history)-replayHistorySize:]
+	}
+
+	return result
+}
+
+// SendTo sends payload to "to", after tampering with it.
+func (e *Endpoint) SendTo(payload []byte, to commontypes.OracleID) {
+	e.mutex.Lock()
+	deliveries := e.deliveries(payload, to, false)
+	e.mutex.Unlock()
+
+	for _, d := range deliveries {
+		e.endpoint.SendTo(d, to)
+	}
+}
+
+// Broadcast sends payload to all oracles, after tampering with it. Unless the
+// endpoint behaves honestly, Broadcast tampers with the payload separately for
+// each recipient and sends the results through SendTo of the underlying
+// endpoint.
+func (e *Endpoint) Broadcast(payload []byte) {
+	e.mutex.Lock()
+	b := e.currentBehavior()
+	if b.honest() {
+		e.mutex.Unlock()
+		e.endpoint.Broadcast(payload)
+		return
+	}
+	equivocate := e.chance(b.EquivocationProbability)
+	deliveries := make([][][]byte, e.n)
+	for to := 0; to < e.n; to++ {
+		deliveries[to] = e.deliveries(payload, commontypes.OracleID(to), equivocate)
+	}
+	e.mutex.Unlock()
+
+	for to, ds := range deliveries {
+		for _, d := range ds {
+			e.endpoint.SendTo(d, commontypes.OracleID(to))
+		}
+	}
+}
+
+// Receive passes through to the underlying endpoint.
+func (e *Endpoint) Receive() <-chan commontypes.BinaryMessageWithSender {
+	return e.endpoint.Receive()
+}
+
+// PeerStatuses passes through to the underlying endpoint. Returns nil if the
+// underlying endpoint doesn't report peer status.
+func (e *Endpoint) PeerStatuses() []types.PeerStatus {
+	if reporter, ok := e.endpoint.(types.PeerStatusReporter); ok {
+		return reporter.PeerStatuses()
+	}
+	return nil
+}
+
+// Start starts the underlying endpoint.
+func (e *Endpoint) Start() error {
+	return e.endpoint.Start()
+}
+
+// Close closes the underlying endpoint.
+func (e *Endpoint) Close() error {
+	return e.endpoint.Close()
+}
@@ -0,0 +1,71 @@
+package byzantine
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// Factory wraps a types.BinaryNetworkEndpointFactory such that all endpoints
+// it creates are Byzantine Endpoints sharing the same Behavior.
+type Factory struct {
+	factory  types.BinaryNetworkEndpointFactory
+	protocol Protocol
+	behavior *behaviorCell
+	logger   commontypes.Logger
+}
+
+var _ types.BinaryNetworkEndpointFactory = (*Factory)(nil)
+
+// NewFactory wraps factory. protocol must match the oracle the factory is
+// passed to, i.e. ProtocolOCR2 for OCR2OracleArgs and ProtocolOCR3 for
+// OCR3OracleArgs and MercuryOracleArgs.
+func NewFactory(
+	factory types.BinaryNetworkEndpointFactory,
+	protocol Protocol,
+	behavior Behavior,
+	logger commontypes.Logger,
+) (*Factory, error) {
+	if _, ok := codecs[protocol]; !ok {
+		return nil, fmt.Errorf("unknown protocol %v", protocol)
+	}
+	cell := &behaviorCell{}
+	if err := cell.set(behavior); err != nil {
+		return nil, err
+	}
+	return &Factory{factory, protocol, cell, logger}, nil
+}
+
+// SetBehavior changes the behavior of all endpoints created by the factory,
+// including those created in the past.
+func (f *Factory) SetBehavior(behavior Behavior) error {
+	return f.behavior.set(behavior)
+}
+
+func (f *Factory) NewEndpoint(
+	cd types.ConfigDigest,
+	peerIDs []string,
+	v2bootstrappers []commontypes.BootstrapperLocator,
+	failureThreshold int,
+	limits types.BinaryNetworkEndpointLimits,
+) (commontypes.BinaryNetworkEndpoint, error) {
+	endpoint, err := f.factory.NewEndpoint(cd, peerIDs, v2bootstrappers, failureThreshold, limits)
+	if err != nil {
+		return nil, err
+	}
+	logger := loghelper.MakeRootLoggerWithContext(f.logger).MakeChild(commontypes.LogFields{
+		"configDigest": cd,
+	})
+	byzEndpoint, err := newEndpoint(endpoint, f.protocol, len(peerIDs), f.behavior, logger)
+	if err != nil {
+		loghelper.CloseLogError(endpoint, logger, "Byzantine Factory: failed to close endpoint")
+		return nil, err
+	}
+	return byzEndpoint, nil
+}
+
+func (f *Factory) PeerID() string {
+	return f.factory.PeerID()
+}
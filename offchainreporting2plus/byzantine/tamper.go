@@ -0,0 +1,225 @@
+package byzantine
+
+import (
+	"fmt"
+	"strings"
+
+	ocr2serialization "github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr2/serialization"
+	ocr3serialization "github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type codec struct {
+	newWrapper func() proto.Message
+	// Types of messages that only the leader sends. These are the ones we
+	// equivocate on and that we drop when going silent as leader.
+	leaderMessageTypes map[string]bool
+}
+
+var codecs = map[Protocol]codec{
+	ProtocolOCR2: {
+		func() proto.Message { return &ocr2serialization.MessageWrapper{} },
+		map[string]bool{
+			"MessageObserveReq": true,
+			"MessageReportReq":  true,
+			"MessageFinal":      true,
+		},
+	},
+	ProtocolOCR3: {
+		func() proto.Message { return &ocr3serialization.MessageWrapper{} },
+		map[string]bool{
+			"MessageEpochStart": true,
+			"MessageRoundStart": true,
+			"MessageProposal":   true,
+		},
+	},
+}
+
+// decodedMessage is a protobuf MessageWrapper together with the message it
+// wraps.
+type decodedMessage struct {
+	wrapper proto.Message
+	// type of the wrapped message, e.g. "MessageProposal"
+	typ string
+}
+
+func (c codec) decode(payload []byte) (decodedMessage, error) {
+	wrapper := c.newWrapper()
+	if err := proto.Unmarshal(payload, wrapper); err != nil {
+		return decodedMessage{}, fmt.Errorf("could not unmarshal protobuf: %w", err)
+	}
+	fd, err := wrappedField(wrapper.ProtoReflect())
+	if err != nil {
+		return decodedMessage{}, err
+	}
+	return decodedMessage{wrapper, string(fd.Message().Name())}, nil
+}
+
+func wrappedField(wrapper protoreflect.Message) (protoreflect.FieldDescriptor, error) {
+	oneof := wrapper.Descriptor().Oneofs().ByName("msg")
+	if oneof == nil {
+		return nil, fmt.Errorf("MessageWrapper has no msg oneof")
+	}
+	fd := wrapper.WhichOneof(oneof)
+	if fd == nil || fd.Message() == nil {
+		return nil, fmt.Errorf("MessageWrapper does not wrap a message")
+	}
+	return fd, nil
+}
+
+// tamper returns a copy of dm with f applied to the wrapped message.
+func (dm decodedMessage) tamper(f func(protoreflect.Message) bool) (decodedMessage, bool) {
+	wrapper := proto.Clone(dm.wrapper)
+	rw := wrapper.ProtoReflect()
+	fd, err := wrappedField(rw)
+	if err != nil {
+		// assertion
+		panic(err)
+	}
+	if !f(rw.Mutable(fd).Message()) {
+		return dm, false
+	}
+	return decodedMessage{wrapper, dm.typ}, true
+}
+
+func (dm decodedMessage) encode() ([]byte, error) {
+	return proto.Marshal(dm.wrapper)
+}
+
+// epoch returns the epoch of the wrapped message, if it has one.
+func (dm decodedMessage) epoch() (uint64, bool) {
+	rw := dm.wrapper.ProtoReflect()
+	fd, err := wrappedField(rw)
+	if err != nil {
+		return 0, false
+	}
+	m := rw.Get(fd).Message()
+	efd := m.Descriptor().Fields().ByName("epoch")
+	if efd == nil {
+		return 0, false
+	}
+	switch efd.Kind() {
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		return m.Get(efd).Uint(), true
+	default:
+		return 0, false
+	}
+}
+
+// walk calls f on every populated field of m and, recursively, of the
+// messages nested in m, in field number order. walk stops early once f
+// returns true, and returns whether it stopped early.
+func walk(m protoreflect.Message, f func(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool) bool {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		if f(m, fd) {
+			return true
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() {
+			continue
+		}
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				if walk(list.Get(j).Message(), f) {
+					return true
+				}
+			}
+		} else if walk(m.Mutable(fd).Message(), f) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateBytes replaces every value of the bytes field fd with g(value).
+func updateBytes(m protoreflect.Message, fd protoreflect.FieldDescriptor, g func([]byte) []byte) {
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		for j := 0; j < list.Len(); j++ {
+			list.Set(j, protoreflect.ValueOfBytes(g(list.Get(j).Bytes())))
+		}
+	} else {
+		m.Set(fd, protoreflect.ValueOfBytes(g(m.Get(fd).Bytes())))
+	}
+}
+
+// corruptSignatures flips a bit in every signature contained in m.
+func corruptSignatures(m protoreflect.Message) bool {
+	corrupted := false
+	walk(m, func(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+		if fd.Kind() != protoreflect.BytesKind || !strings.Contains(string(fd.Name()), "signature") {
+			return false
+		}
+		updateBytes(m, fd, func(sig []byte) []byte {
+			if len(sig) == 0 {
+				return []byte{0x01}
+			}
+			corrupt := append([]byte(nil), sig...)
+			corrupt[0] ^= 0x01
+			return corrupt
+		})
+		corrupted = true
+		return false
+	})
+	return corrupted
+}
+
+// inflate appends padding zero bytes to every bytes field contained in m.
+func inflate(padding int) func(protoreflect.Message) bool {
+	return func(m protoreflect.Message) bool {
+		inflated := false
+		walk(m, func(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+			if fd.Kind() != protoreflect.BytesKind {
+				return false
+			}
+			updateBytes(m, fd, func(b []byte) []byte {
+				return append(append([]byte(nil), b...), make([]byte, padding)...)
+			})
+			inflated = true
+			return false
+		})
+		return inflated
+	}
+}
+
+// conflict turns m into a conflicting version of itself, see
+// Behavior.EquivocationProbability.
+func conflict(m protoreflect.Message) bool {
+	if walk(m, func(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+		if !fd.IsList() {
+			return false
+		}
+		list := m.Mutable(fd).List()
+		if list.Len() <= 1 {
+			return false
+		}
+		list.Truncate(list.Len() - 1)
+		return true
+	}) {
+		return true
+	}
+
+	// m.Has is false for empty bytes fields, so we have to look at the
+	// descriptor directly. (e.g. an empty query)
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Kind() == protoreflect.BytesKind && !fd.IsList() {
+			m.Set(fd, protoreflect.ValueOfBytes(append(append([]byte(nil), m.Get(fd).Bytes()...), 0x01)))
+			return true
+		}
+	}
+	return walk(m, func(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+		if fd.Kind() != protoreflect.BytesKind || fd.IsList() {
+			return false
+		}
+		m.Set(fd, protoreflect.ValueOfBytes(append(append([]byte(nil), m.Get(fd).Bytes()...), 0x01)))
+		return true
+	})
+}
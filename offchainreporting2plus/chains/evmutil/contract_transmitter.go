@@ -0,0 +1,190 @@
+package evmutil
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/smartcontractkit/libocr/gethwrappers2/ocr2aggregator"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+func makeTransmitABI() abi.ABI {
+	abi, err := abi.JSON(strings.NewReader(ocr2aggregator.OCR2AggregatorABI))
+	if err != nil {
+		// assertion
+		panic(fmt.Sprintf("could not parse aggregator ABI: %s", err.Error()))
+	}
+	return abi
+}
+
+var transmitABI = makeTransmitABI()
+
+// PackTransmit packs the calldata for calling transmit on an
+// OCR2Aggregator-style contract. signatures must be 65-byte signatures as
+// produced by EVMOnchainKeyring, from at most 32 distinct oracles.
+func PackTransmit(repctx types.ReportContext, report types.Report, signatures []types.AttributedOnchainSignature) ([]byte, error) {
+	if len(signatures) > 32 {
+		return nil, fmt.Errorf("too many signatures (%v), at most 32 fit into rawVs", len(signatures))
+	}
+	rs := make([][32]byte, 0, len(signatures))
+	ss := make([][32]byte, 0, len(signatures))
+	var vs [32]byte
+	for i, as := range signatures {
+		r, s, v, err := SplitSignature(as.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("signature of oracle %v: %w", as.Signer, err)
+		}
+		rs = append(rs, r)
+		ss = append(ss, s)
+		vs[i] = v
+	}
+	return transmitABI.Pack("transmit", RawReportContext(repctx), []byte(report), rs, ss, vs)
+}
+
+// OCR3ContractTransmitter transmits OCR3 reports to an OCR2Aggregator-style
+// contract, mapping each report onto an OCR2 report context.
+//
+// Transmit returns as soon as the transaction has been sent, without waiting
+// for it to be included in a block. Calls to Transmit may be concurrent;
+// transactions are sent one at a time so that they carry consecutive nonces.
+type OCR3ContractTransmitter[RI any] struct {
+	contractAddress common.Address
+	contract        *bind.BoundContract
+	transactOpts    bind.TransactOpts
+	reportContext   ReportContextEncoding[RI]
+	gasEstimator    ethereum.GasEstimator
+	nonceManager    NonceManager
+
+	sendMutex sync.Mutex
+}
+
+var _ ocr3types.ContractTransmitter[struct{}] = (*OCR3ContractTransmitter[struct{}])(nil)
+
+// NewOCR3ContractTransmitter returns a transmitter for the contract at
+// contractAddress. Transactions are sent from transactOpts.From and signed by
+// transactOpts.Signer; use e.g. bind.NewKeyedTransactorWithChainID to create
+// these. transactOpts may also fix fee parameters, otherwise they are
+// suggested by backend. The other fields of transactOpts are ignored.
+//
+// gasEstimator and nonceManager are optional. If nil, gas is estimated by
+// backend and nonces are managed by NewNonceManager(backend,
+// transactOpts.From). Pass a wrapper around backend as gasEstimator to e.g.
+// add a safety margin to its estimates.
+func NewOCR3ContractTransmitter[RI any](
+	contractAddress common.Address,
+	backend bind.ContractBackend,
+	transactOpts *bind.TransactOpts,
+	reportContext ReportContextEncoding[RI],
+	gasEstimator ethereum.GasEstimator,
+	nonceManager NonceManager,
+) (*OCR3ContractTransmitter[RI], error) {
+	if transactOpts == nil || transactOpts.Signer == nil {
+		return nil, fmt.Errorf("transactOpts must have a Signer")
+	}
+	if reportContext == nil {
+		return nil, fmt.Errorf("reportContext must not be nil")
+	}
+	if gasEstimator == nil {
+		gasEstimator = backend
+	}
+	if nonceManager == nil {
+		nonceManager = NewNonceManager(backend, transactOpts.From)
+	}
+	return &OCR3ContractTransmitter[RI]{
+		contractAddress,
+		bind.NewBoundContract(contractAddress, transmitABI, backend, backend, backend),
+		bind.TransactOpts{
+			From:      transactOpts.From,
+			Signer:    transactOpts.Signer,
+			GasPrice:  transactOpts.GasPrice,
+			GasFeeCap: transactOpts.GasFeeCap,
+			GasTipCap: transactOpts.GasTipCap,
+		},
+		reportContext,
+		gasEstimator,
+		nonceManager,
+		sync.Mutex{},
+	}, nil
+}
+
+func (t *OCR3ContractTransmitter[RI]) Transmit(
+	ctx context.Context,
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	reportWithInfo ocr3types.ReportWithInfo[RI],
+	aoss []types.AttributedOnchainSignature,
+) error {
+	_, err := t.transmit(ctx, configDigest, seqNr, reportWithInfo, aoss)
+	return err
+}
+
+// TransmitTransaction is like Transmit, but also returns the transaction
+// that was sent, e.g. for waiting on its receipt with bind.WaitMined.
+func (t *OCR3ContractTransmitter[RI]) TransmitTransaction(
+	ctx context.Context,
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	reportWithInfo ocr3types.ReportWithInfo[RI],
+	aoss []types.AttributedOnchainSignature,
+) (*ethtypes.Transaction, error) {
+	return t.transmit(ctx, configDigest, seqNr, reportWithInfo, aoss)
+}
+
+func (t *OCR3ContractTransmitter[RI]) transmit(
+	ctx context.Context,
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	reportWithInfo ocr3types.ReportWithInfo[RI],
+	aoss []types.AttributedOnchainSignature,
+) (*ethtypes.Transaction, error) {
+	repctx, err := t.reportContext(configDigest, seqNr, reportWithInfo)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode report context: %w", err)
+	}
+	calldata, err := PackTransmit(repctx, reportWithInfo.Report, aoss)
+	if err != nil {
+		return nil, fmt.Errorf("could not pack transmit calldata: %w", err)
+	}
+
+	gasLimit, err := t.gasEstimator.EstimateGas(ctx, ethereum.CallMsg{
+		From: t.transactOpts.From,
+		To:   &t.contractAddress,
+		Data: calldata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not estimate gas: %w", err)
+	}
+
+	t.sendMutex.Lock()
+	defer t.sendMutex.Unlock()
+
+	nonce, err := t.nonceManager.Reserve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not reserve nonce: %w", err)
+	}
+
+	opts := t.transactOpts
+	opts.Context = ctx
+	opts.GasLimit = gasLimit
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	tx, err := t.contract.RawTransact(&opts, calldata)
+	if err != nil {
+		t.nonceManager.Release(nonce)
+		return nil, fmt.Errorf("could not send transmit transaction: %w", err)
+	}
+	return tx, nil
+}
+
+func (t *OCR3ContractTransmitter[RI]) FromAccount() (types.Account, error) {
+	return types.Account(t.transactOpts.From.Hex()), nil
+}
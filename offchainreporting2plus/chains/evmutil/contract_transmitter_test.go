@@ -0,0 +1,242 @@
+package evmutil_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/gethwrappers2/ocr2aggregator"
+	"github.com/smartcontractkit/libocr/offchainreporting2/reportingplugin/median"
+	"github.com/smartcontractkit/libocr/offchainreporting2/reportingplugin/median/evmreportcodec"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/chains/evmutil"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+const (
+	n = 4
+	f = 1
+)
+
+var simulatedChainID = big.NewInt(1337)
+
+type fixture struct {
+	backend      *backends.SimulatedBackend
+	owner        *bind.TransactOpts
+	aggregator   *ocr2aggregator.OCR2Aggregator
+	address      common.Address
+	configDigest types.ConfigDigest
+	keyrings     []*evmutil.OCR3OnchainKeyring[struct{}]
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// setup deploys an OCR2Aggregator on a simulated chain and configures it with
+// n fresh signers. The owner account is the transmitter of oracle 0.
+func setup(t *testing.T) *fixture {
+	ownerKey := newKey(t)
+	owner, err := bind.NewKeyedTransactorWithChainID(ownerKey, simulatedChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		owner.From: {Balance: new(big.Int).Lsh(big.NewInt(1), 100)},
+	}, 30_000_000)
+	t.Cleanup(func() { _ = backend.Close() })
+
+	onchainConfig := median.OnchainConfig{big.NewInt(0), big.NewInt(1_000_000)}
+	address, _, aggregator, err := ocr2aggregator.DeployOCR2Aggregator(
+		owner,
+		backend,
+		common.Address{0x1},
+		onchainConfig.Min,
+		onchainConfig.Max,
+		common.Address{},
+		common.Address{},
+		8,
+		"test",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	keyrings := make([]*evmutil.OCR3OnchainKeyring[struct{}], 0, n)
+	signers := make([]common.Address, 0, n)
+	transmitters := []common.Address{owner.From}
+	for i := 0; i < n; i++ {
+		keyring := evmutil.NewOCR3OnchainKeyring[struct{}](
+			evmutil.NewEVMOnchainKeyring(newKey(t)),
+			evmutil.SeqNrReportContext[struct{}],
+		)
+		keyrings = append(keyrings, keyring)
+		signers = append(signers, common.BytesToAddress(keyring.PublicKey()))
+		if i != 0 {
+			transmitters = append(transmitters, crypto.PubkeyToAddress(newKey(t).PublicKey))
+		}
+	}
+	encodedOnchainConfig, err := median.StandardOnchainConfigCodec{}.Encode(onchainConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aggregator.SetConfig(owner, signers, transmitters, f, encodedOnchainConfig, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	details, err := aggregator.LatestConfigDetails(&bind.CallOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &fixture{
+		backend,
+		owner,
+		aggregator,
+		address,
+		details.ConfigDigest,
+		keyrings,
+	}
+}
+
+func (fx *fixture) report(t *testing.T, value int64) ocr3types.ReportWithInfo[struct{}] {
+	paos := make([]median.ParsedAttributedObservation, 0, 2*f+1)
+	for i := 0; i < 2*f+1; i++ {
+		paos = append(paos, median.ParsedAttributedObservation{
+			1,
+			big.NewInt(value),
+			big.NewInt(0),
+			commontypes.OracleID(i),
+		})
+	}
+	report, err := evmreportcodec.ReportCodec{}.BuildReport(paos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ocr3types.ReportWithInfo[struct{}]{report, struct{}{}}
+}
+
+// sign returns signatures from f+1 oracles, as required by the contract.
+func (fx *fixture) sign(t *testing.T, seqNr uint64, rwi ocr3types.ReportWithInfo[struct{}]) []types.AttributedOnchainSignature {
+	aoss := make([]types.AttributedOnchainSignature, 0, f+1)
+	for i := 0; i < f+1; i++ {
+		signature, err := fx.keyrings[i].Sign(fx.configDigest, seqNr, rwi)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fx.keyrings[n-1].Verify(fx.keyrings[i].PublicKey(), fx.configDigest, seqNr, rwi, signature) {
+			t.Fatalf("signature of oracle %v doesn't verify", i)
+		}
+		aoss = append(aoss, types.AttributedOnchainSignature{signature, commontypes.OracleID(i)})
+	}
+	return aoss
+}
+
+func (fx *fixture) requireSuccess(t *testing.T, tx *ethtypes.Transaction) {
+	receipt, err := fx.backend.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %v reverted", tx.Hash())
+	}
+}
+
+func (fx *fixture) requireLatest(t *testing.T, seqNr uint64, answer int64) {
+	latest, err := fx.aggregator.LatestTransmissionDetails(&bind.CallOpts{From: fx.owner.From})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ConfigDigest != fx.configDigest {
+		t.Errorf("latest config digest is %x, expected %x", latest.ConfigDigest, fx.configDigest)
+	}
+	if epochAndRound := uint64(latest.Epoch)<<8 | uint64(latest.Round); epochAndRound != seqNr {
+		t.Errorf("latest epoch and round encode %v, expected seqNr %v", epochAndRound, seqNr)
+	}
+	if latest.LatestAnswer.Cmp(big.NewInt(answer)) != 0 {
+		t.Errorf("latest answer is %v, expected %v", latest.LatestAnswer, answer)
+	}
+}
+
+func TestOCR3ContractTransmitter(t *testing.T) {
+	ctx := context.Background()
+	fx := setup(t)
+
+	transmitter, err := evmutil.NewOCR3ContractTransmitter[struct{}](
+		fx.address,
+		fx.backend,
+		fx.owner,
+		evmutil.SeqNrReportContext[struct{}],
+		nil,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account, err := transmitter.FromAccount(); err != nil || account != types.Account(fx.owner.From.Hex()) {
+		t.Fatalf("FromAccount returned %v, %v", account, err)
+	}
+
+	t.Run("accepted by the contract", func(t *testing.T) {
+		// seqNr 300 has a non-zero epoch and round
+		rwi := fx.report(t, 42)
+		tx, err := transmitter.TransmitTransaction(ctx, fx.configDigest, 300, rwi, fx.sign(t, 300, rwi))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fx.backend.Commit()
+		fx.requireSuccess(t, tx)
+		fx.requireLatest(t, 300, 42)
+	})
+
+	t.Run("consecutive nonces before mining", func(t *testing.T) {
+		var txs []*ethtypes.Transaction
+		for _, seqNr := range []uint64{301, 302} {
+			rwi := fx.report(t, int64(seqNr))
+			tx, err := transmitter.TransmitTransaction(ctx, fx.configDigest, seqNr, rwi, fx.sign(t, seqNr, rwi))
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, tx)
+		}
+		if txs[1].Nonce() != txs[0].Nonce()+1 {
+			t.Fatalf("nonces %v and %v aren't consecutive", txs[0].Nonce(), txs[1].Nonce())
+		}
+		fx.backend.Commit()
+		for _, tx := range txs {
+			fx.requireSuccess(t, tx)
+		}
+		fx.requireLatest(t, 302, 302)
+	})
+
+	t.Run("stale report is rejected", func(t *testing.T) {
+		rwi := fx.report(t, 7)
+		if err := transmitter.Transmit(ctx, fx.configDigest, 300, rwi, fx.sign(t, 300, rwi)); err == nil {
+			t.Fatal("expected an error")
+		}
+		// the failed transmission must not leave a nonce gap
+		rwi = fx.report(t, 303)
+		tx, err := transmitter.TransmitTransaction(ctx, fx.configDigest, 303, rwi, fx.sign(t, 303, rwi))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fx.backend.Commit()
+		fx.requireSuccess(t, tx)
+		fx.requireLatest(t, 303, 303)
+	})
+}
@@ -0,0 +1,73 @@
+package evmutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceManager assigns nonces to the transactions sent from one account.
+// Implementations must be safe for concurrent use.
+type NonceManager interface {
+	// Reserve returns the nonce for the next transaction.
+	Reserve(ctx context.Context) (uint64, error)
+	// Release is called with a nonce returned by Reserve if the transaction
+	// using it could not be sent, so that the nonce can be reused.
+	Release(nonce uint64)
+}
+
+// PendingNonceReader is implemented by go-ethereum's bind.ContractBackend,
+// ethclient.Client and the simulated backend.
+type PendingNonceReader interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+type nonceManager struct {
+	backend PendingNonceReader
+	account common.Address
+
+	mutex sync.Mutex
+	// next nonce to hand out, valid if synced is true
+	next   uint64
+	synced bool
+}
+
+var _ NonceManager = (*nonceManager)(nil)
+
+// NewNonceManager returns a NonceManager that hands out consecutive nonces,
+// starting from the account's pending nonce. It resynchronizes with the
+// pending nonce whenever that gets ahead, e.g. because other software sends
+// transactions from the same account, and after a nonce was released.
+func NewNonceManager(backend PendingNonceReader, account common.Address) NonceManager {
+	return &nonceManager{backend: backend, account: account}
+}
+
+func (nm *nonceManager) Reserve(ctx context.Context) (uint64, error) {
+	pending, err := nm.backend.PendingNonceAt(ctx, nm.account)
+	if err != nil {
+		return 0, err
+	}
+
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	if !nm.synced || nm.next < pending {
+		nm.next = pending
+		nm.synced = true
+	}
+	nonce := nm.next
+	nm.next++
+	return nonce, nil
+}
+
+func (nm *nonceManager) Release(nonce uint64) {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	if nm.synced && nonce+1 == nm.next {
+		nm.next = nonce
+	} else {
+		// a later nonce is already in use, leaving a gap. Let the node tell
+		// us what it needs next.
+		nm.synced = false
+	}
+}
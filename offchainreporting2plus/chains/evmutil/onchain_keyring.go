@@ -0,0 +1,120 @@
+package evmutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+const signatureLength = 65
+
+// ReportHash is the hash that OCR2Aggregator-style contracts expect report
+// signatures over, i.e.
+// keccak256(abi.encode(keccak256(report), reportContext)) with reportContext
+// of type bytes32[3]. All four values are static 32-byte words, so abi.encode
+// doesn't add any offsets or lengths and yields the same bytes as
+// abi.encodePacked: the plain concatenation of the words, which is what we
+// hash.
+func ReportHash(repctx types.ReportContext, report types.Report) common.Hash {
+	rawRepctx := RawReportContext(repctx)
+	return crypto.Keccak256Hash(
+		crypto.Keccak256(report),
+		rawRepctx[0][:],
+		rawRepctx[1][:],
+		rawRepctx[2][:],
+	)
+}
+
+// EVMOnchainKeyring signs OCR2 reports with a secp256k1 key, for verification
+// by OCR2Aggregator-style contracts. Its public key is the signer's 20-byte
+// address.
+type EVMOnchainKeyring struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+var _ types.OnchainKeyring = (*EVMOnchainKeyring)(nil)
+
+func NewEVMOnchainKeyring(privateKey *ecdsa.PrivateKey) *EVMOnchainKeyring {
+	return &EVMOnchainKeyring{privateKey}
+}
+
+func (ok *EVMOnchainKeyring) PublicKey() types.OnchainPublicKey {
+	address := crypto.PubkeyToAddress(ok.privateKey.PublicKey)
+	return types.OnchainPublicKey(address[:])
+}
+
+// Sign returns a 65-byte signature r || s || v with v in {0, 1}. The
+// contract adds 27 to v before recovering the signer.
+func (ok *EVMOnchainKeyring) Sign(repctx types.ReportContext, report types.Report) (signature []byte, err error) {
+	return crypto.Sign(ReportHash(repctx, report).Bytes(), ok.privateKey)
+}
+
+func (ok *EVMOnchainKeyring) Verify(publicKey types.OnchainPublicKey, repctx types.ReportContext, report types.Report, signature []byte) bool {
+	return EVMOnchainVerifier{}.Verify(publicKey, repctx, report, signature)
+}
+
+func (ok *EVMOnchainKeyring) MaxSignatureLength() int {
+	return signatureLength
+}
+
+// EVMOnchainVerifier verifies signatures made by EVMOnchainKeyring. Unlike
+// the keyring, it doesn't need a private key, e.g. for verifying signatures
+// in an oracle whose key is held by a remote signer.
+type EVMOnchainVerifier struct{}
+
+func (EVMOnchainVerifier) Verify(publicKey types.OnchainPublicKey, repctx types.ReportContext, report types.Report, signature []byte) bool {
+	if len(signature) != signatureLength {
+		return false
+	}
+	recovered, err := crypto.SigToPub(ReportHash(repctx, report).Bytes(), signature)
+	if err != nil {
+		return false
+	}
+	address := crypto.PubkeyToAddress(*recovered)
+	return bytes.Equal(address[:], publicKey)
+}
+
+// OCR3OnchainKeyring adapts an OCR2 OnchainKeyring, such as
+// EVMOnchainKeyring, to OCR3 by mapping every report onto an OCR2 report
+// context.
+type OCR3OnchainKeyring[RI any] struct {
+	keyring       types.OnchainKeyring
+	reportContext ReportContextEncoding[RI]
+}
+
+var _ ocr3types.OnchainKeyring[struct{}] = (*OCR3OnchainKeyring[struct{}])(nil)
+
+// NewOCR3OnchainKeyring returns a keyring that signs with keyring. Use the same
+// reportContext as for the OCR3ContractTransmitter.
+func NewOCR3OnchainKeyring[RI any](keyring types.OnchainKeyring, reportContext ReportContextEncoding[RI]) *OCR3OnchainKeyring[RI] {
+	return &OCR3OnchainKeyring[RI]{keyring, reportContext}
+}
+
+func (ok *OCR3OnchainKeyring[RI]) PublicKey() types.OnchainPublicKey {
+	return ok.keyring.PublicKey()
+}
+
+func (ok *OCR3OnchainKeyring[RI]) Sign(configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI]) (signature []byte, err error) {
+	repctx, err := ok.reportContext(configDigest, seqNr, reportWithInfo)
+	if err != nil {
+		return nil, err
+	}
+	return ok.keyring.Sign(repctx, reportWithInfo.Report)
+}
+
+func (ok *OCR3OnchainKeyring[RI]) Verify(publicKey types.OnchainPublicKey, configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI], signature []byte) bool {
+	repctx, err := ok.reportContext(configDigest, seqNr, reportWithInfo)
+	if err != nil {
+		return false
+	}
+	return ok.keyring.Verify(publicKey, repctx, reportWithInfo.Report, signature)
+}
+
+func (ok *OCR3OnchainKeyring[RI]) MaxSignatureLength() int {
+	return ok.keyring.MaxSignatureLength()
+}
@@ -0,0 +1,68 @@
+package evmutil
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ReportContextEncoding maps an OCR3 report onto the OCR2 report context that
+// OCR2Aggregator-style contracts sign over and check. Such contracts reject
+// reports whose (epoch, round) doesn't exceed that of the latest accepted
+// report, so the encoding must be strictly increasing in the order in which
+// reports should be accepted.
+type ReportContextEncoding[RI any] func(
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	reportWithInfo ocr3types.ReportWithInfo[RI],
+) (types.ReportContext, error)
+
+// MaxSeqNrReportContextSeqNr is the largest sequence number
+// SeqNrReportContext can encode, since the contract stores epoch and round in
+// 40 bits.
+const MaxSeqNrReportContextSeqNr = 1<<40 - 1
+
+// SeqNrReportContext encodes seqNr as epoch seqNr>>8 and round seqNr&0xff, so
+// that the 40-bit epochAndRound value seen by the contract equals seqNr. Use it
+// if the plugin generates at most one report per seqNr.
+func SeqNrReportContext[RI any](
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	_ ocr3types.ReportWithInfo[RI],
+) (types.ReportContext, error) {
+	if seqNr > MaxSeqNrReportContextSeqNr {
+		return types.ReportContext{}, fmt.Errorf("seqNr %v too large for report context, max is %v", seqNr, uint64(MaxSeqNrReportContextSeqNr))
+	}
+	return types.ReportContext{
+		types.ReportTimestamp{
+			configDigest,
+			uint32(seqNr >> 8),
+			uint8(seqNr),
+		},
+		[32]byte{},
+	}, nil
+}
+
+// EpochRoundReportInfo is implemented by report infos that carry their own
+// epoch and round, like those of plugins ported from OCR2.
+type EpochRoundReportInfo interface {
+	EpochRound() (epoch uint32, round uint8)
+}
+
+// InfoReportContext takes epoch and round from the report info.
+func InfoReportContext[RI EpochRoundReportInfo](
+	configDigest types.ConfigDigest,
+	_ uint64,
+	reportWithInfo ocr3types.ReportWithInfo[RI],
+) (types.ReportContext, error) {
+	epoch, round := reportWithInfo.Info.EpochRound()
+	return types.ReportContext{
+		types.ReportTimestamp{
+			configDigest,
+			epoch,
+			round,
+		},
+		[32]byte{},
+	}, nil
+}
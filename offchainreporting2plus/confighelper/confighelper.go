@@ -403,6 +403,8 @@ func ContractSetConfigArgsForTestsOCR3(
 			maxDurationObservation,
 			maxDurationShouldAcceptAttestedReport,
 			maxDurationShouldTransmitAcceptedReport,
+			ocr3config.LeaderSelectionConfig{},
+			ocr3config.CompressionNone,
 			f,
 			onchainConfig,
 			types.ConfigDigest{},
@@ -0,0 +1,79 @@
+package confighelper
+
+import (
+	"io"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ContractSetConfigArgsReencryptingSharedSecret returns setConfig args that
+// are identical to change, except that the shared secret is encrypted anew
+// for sharedSecretEncryptionPublicKeys. The shared secret is decrypted using
+// offchainKeyring, which must belong to one of the oracles in change. Use this
+// to replace an oracle's ConfigEncryptionPublicKey without building the whole
+// config from scratch. configinspect.ReencryptSharedSecret additionally
+// returns a diff of the old and new config for review.
+//
+// All encryptions share a single ephemeral key, so they are all replaced.
+// sharedSecretEncryptionPublicKeys must therefore contain one key for every
+// oracle, in the same order as the oracles in change, not just the replaced
+// ones.
+//
+// If a key is replaced because its secret key was compromised, the adversary
+// may already know the shared secret. Use
+// ContractSetConfigArgsWithFreshSharedSecret in that case.
+func ContractSetConfigArgsReencryptingSharedSecret(
+	change types.ContractConfig,
+	offchainKeyring types.OffchainKeyring,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+) (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f_ uint8,
+	onchainConfig_ []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	// change is already live, so we don't second-guess its parameters
+	sharedConfig, _, err := ocr2config.SharedConfigFromContractConfigWithOffchainKeyring(true, change, offchainKeyring)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	if err := config.CheckConfigEncryptionPublicKeys(sharedSecretEncryptionPublicKeys, sharedConfig.N()); err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	return ocr2config.XXXContractSetConfigArgsFromSharedConfig(sharedConfig, sharedSecretEncryptionPublicKeys)
+}
+
+// ContractSetConfigArgsWithFreshSharedSecret is like
+// ContractSetConfigArgsReencryptingSharedSecret, but draws a new shared secret
+// from rand instead of decrypting the existing one.
+func ContractSetConfigArgsWithFreshSharedSecret(
+	change types.ContractConfig,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+	rand io.Reader,
+) (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f_ uint8,
+	onchainConfig_ []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	publicConfig, err := ocr2config.PublicConfigFromContractConfig(true, change)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	if err := config.CheckConfigEncryptionPublicKeys(sharedSecretEncryptionPublicKeys, publicConfig.N()); err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	sharedSecret, err := config.NewSharedSecret(rand)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	return ocr2config.XXXContractSetConfigArgsFromSharedConfig(ocr2config.SharedConfig{publicConfig, sharedSecret}, sharedSecretEncryptionPublicKeys)
+}
@@ -0,0 +1,396 @@
+// Package configinspect decodes OCR2 and OCR3 ContractConfigs into a
+// human-readable form and diffs them field by field. This is meant for
+// reviewing config changes, e.g. a setConfig transaction proposed to a
+// multisig, since the OffchainConfig is otherwise opaque.
+package configinspect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// Config is a decoded ContractConfig. It covers both OCR2 and OCR3, fields
+// that only exist in one of them are left empty for the other.
+type Config struct {
+	ConfigDigest          types.ConfigDigest
+	ConfigCount           uint64
+	OffchainConfigVersion uint64
+
+	F                int
+	OracleIdentities []confighelper.OracleIdentity
+	S                []int
+	RMax             uint64
+	// Timings contains the DeltaX and MaxDurationX parameters in the order in
+	// which they appear in the protocol's PublicConfig.
+	Timings []Timing
+
+	// Only set for OCR3
+	LeaderSelection *ocr3confighelper.LeaderSelectionConfig
+	// Only set for OCR3
+	Compression *ocr3confighelper.Compression
+
+	SharedSecretEncryptions SharedSecretEncryptions
+
+	ReportingPluginConfig []byte
+	OnchainConfig         []byte
+}
+
+// SharedSecretEncryptions is the shared secret, encrypted for every oracle's
+// ConfigEncryptionPublicKey. The encryptions change whenever the secret is
+// re-encrypted, but SharedSecretHash only changes with the secret itself.
+type SharedSecretEncryptions struct {
+	DiffieHellmanPoint [32]byte
+	SharedSecretHash   [32]byte
+	Encryptions        [][config.SharedSecretSize]byte
+}
+
+func sharedSecretEncryptions(e config.SharedSecretEncryptions) SharedSecretEncryptions {
+	encryptions := [][config.SharedSecretSize]byte{}
+	for _, enc := range e.Encryptions {
+		encryptions = append(encryptions, enc)
+	}
+	return SharedSecretEncryptions{
+		e.DiffieHellmanPoint,
+		e.SharedSecretHash,
+		encryptions,
+	}
+}
+
+type Timing struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Protocol returns "OCR2" or "OCR3" depending on the OffchainConfigVersion.
+func (c Config) Protocol() string {
+	switch c.OffchainConfigVersion {
+	case config.OCR2OffchainConfigVersion:
+		return "OCR2"
+	case config.OCR3OffchainConfigVersion:
+		return "OCR3"
+	default:
+		return fmt.Sprintf("unknown (offchainConfigVersion %v)", c.OffchainConfigVersion)
+	}
+}
+
+// Decode decodes contractConfig with the same code the oracles use, picking
+// OCR2 or OCR3 based on its OffchainConfigVersion.
+func Decode(skipResourceExhaustionChecks bool, contractConfig types.ContractConfig) (Config, error) {
+	switch contractConfig.OffchainConfigVersion {
+	case config.OCR2OffchainConfigVersion:
+		pc, err := confighelper.PublicConfigFromContractConfig(skipResourceExhaustionChecks, contractConfig)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to decode OCR2 config: %w", err)
+		}
+		encs, err := ocr2config.SharedSecretEncryptionsFromContractConfig(skipResourceExhaustionChecks, contractConfig)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to decode OCR2 config: %w", err)
+		}
+		return Config{
+			contractConfig.ConfigDigest,
+			contractConfig.ConfigCount,
+			contractConfig.OffchainConfigVersion,
+
+			pc.F,
+			pc.OracleIdentities,
+			pc.S,
+			uint64(pc.RMax),
+			[]Timing{
+				{"DeltaProgress", pc.DeltaProgress},
+				{"DeltaResend", pc.DeltaResend},
+				{"DeltaRound", pc.DeltaRound},
+				{"DeltaGrace", pc.DeltaGrace},
+				{"DeltaStage", pc.DeltaStage},
+				{"MaxDurationQuery", pc.MaxDurationQuery},
+				{"MaxDurationObservation", pc.MaxDurationObservation},
+				{"MaxDurationReport", pc.MaxDurationReport},
+				{"MaxDurationShouldAcceptFinalizedReport", pc.MaxDurationShouldAcceptFinalizedReport},
+				{"MaxDurationShouldTransmitAcceptedReport", pc.MaxDurationShouldTransmitAcceptedReport},
+			},
+
+			nil,
+			nil,
+
+			sharedSecretEncryptions(encs),
+
+			pc.ReportingPluginConfig,
+			pc.OnchainConfig,
+		}, nil
+	case config.OCR3OffchainConfigVersion:
+		pc, err := ocr3confighelper.PublicConfigFromContractConfig(skipResourceExhaustionChecks, contractConfig)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to decode OCR3 config: %w", err)
+		}
+		encs, err := ocr3config.SharedSecretEncryptionsFromContractConfig(skipResourceExhaustionChecks, contractConfig)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to decode OCR3 config: %w", err)
+		}
+		return Config{
+			contractConfig.ConfigDigest,
+			contractConfig.ConfigCount,
+			contractConfig.OffchainConfigVersion,
+
+			pc.F,
+			pc.OracleIdentities,
+			pc.S,
+			pc.RMax,
+			[]Timing{
+				{"DeltaProgress", pc.DeltaProgress},
+				{"DeltaResend", pc.DeltaResend},
+				{"DeltaInitial", pc.DeltaInitial},
+				{"DeltaRound", pc.DeltaRound},
+				{"DeltaGrace", pc.DeltaGrace},
+				{"DeltaCertifiedCommitRequest", pc.DeltaCertifiedCommitRequest},
+				{"DeltaStage", pc.DeltaStage},
+				{"MaxDurationQuery", pc.MaxDurationQuery},
+				{"MaxDurationObservation", pc.MaxDurationObservation},
+				{"MaxDurationShouldAcceptAttestedReport", pc.MaxDurationShouldAcceptAttestedReport},
+				{"MaxDurationShouldTransmitAcceptedReport", pc.MaxDurationShouldTransmitAcceptedReport},
+			},
+
+			&pc.LeaderSelection,
+			&pc.Compression,
+
+			sharedSecretEncryptions(encs),
+
+			pc.ReportingPluginConfig,
+			pc.OnchainConfig,
+		}, nil
+	default:
+		return Config{}, fmt.Errorf("unsupported OffchainConfigVersion %v, expected %v (OCR2) or %v (OCR3)",
+			contractConfig.OffchainConfigVersion, config.OCR2OffchainConfigVersion, config.OCR3OffchainConfigVersion)
+	}
+}
+
+func hexBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+type jsonOracleIdentity struct {
+	OffchainPublicKey string `json:"offchainPublicKey"`
+	OnchainPublicKey  string `json:"onchainPublicKey"`
+	PeerID            string `json:"peerID"`
+	TransmitAccount   string `json:"transmitAccount"`
+}
+
+type jsonLeaderSelection struct {
+	Weights []int `json:"weights"`
+}
+
+type jsonSharedSecretEncryptions struct {
+	DiffieHellmanPoint string   `json:"diffieHellmanPoint"`
+	SharedSecretHash   string   `json:"sharedSecretHash"`
+	Encryptions        []string `json:"encryptions"`
+}
+
+type jsonConfig struct {
+	Protocol                string                      `json:"protocol"`
+	ConfigDigest            string                      `json:"configDigest"`
+	ConfigCount             uint64                      `json:"configCount"`
+	OffchainConfigVersion   uint64                      `json:"offchainConfigVersion"`
+	N                       int                         `json:"n"`
+	F                       int                         `json:"f"`
+	OracleIdentities        []jsonOracleIdentity        `json:"oracleIdentities"`
+	S                       []int                       `json:"s"`
+	RMax                    uint64                      `json:"rMax"`
+	Timings                 map[string]string           `json:"timings"`
+	LeaderSelection         *jsonLeaderSelection        `json:"leaderSelection,omitempty"`
+	Compression             string                      `json:"compression,omitempty"`
+	SharedSecretEncryptions jsonSharedSecretEncryptions `json:"sharedSecretEncryptions"`
+	ReportingPluginConfig   string                      `json:"reportingPluginConfig"`
+	OnchainConfig           string                      `json:"onchainConfig"`
+}
+
+// MarshalJSON encodes keys and binary blobs as 0x-prefixed hex and durations
+// as strings like "1m30s".
+func (c Config) MarshalJSON() ([]byte, error) {
+	identities := []jsonOracleIdentity{}
+	for _, id := range c.OracleIdentities {
+		identities = append(identities, jsonOracleIdentity{
+			hexBytes(id.OffchainPublicKey[:]),
+			hexBytes(id.OnchainPublicKey),
+			id.PeerID,
+			string(id.TransmitAccount),
+		})
+	}
+	timings := map[string]string{}
+	for _, t := range c.Timings {
+		timings[t.Name] = t.Duration.String()
+	}
+	var leaderSelection *jsonLeaderSelection
+	if c.LeaderSelection != nil {
+		leaderSelection = &jsonLeaderSelection{
+			c.LeaderSelection.Weights,
+		}
+	}
+	compression := ""
+	if c.Compression != nil {
+		compression = c.Compression.String()
+	}
+	encryptions := []string{}
+	for _, enc := range c.SharedSecretEncryptions.Encryptions {
+		encryptions = append(encryptions, hexBytes(enc[:]))
+	}
+	return json.Marshal(jsonConfig{
+		c.Protocol(),
+		c.ConfigDigest.Hex(),
+		c.ConfigCount,
+		c.OffchainConfigVersion,
+		len(c.OracleIdentities),
+		c.F,
+		identities,
+		c.S,
+		c.RMax,
+		timings,
+		leaderSelection,
+		compression,
+		jsonSharedSecretEncryptions{
+			hexBytes(c.SharedSecretEncryptions.DiffieHellmanPoint[:]),
+			hexBytes(c.SharedSecretEncryptions.SharedSecretHash[:]),
+			encryptions,
+		},
+		hexBytes(c.ReportingPluginConfig),
+		hexBytes(c.OnchainConfig),
+	})
+}
+
+// Change is a field whose value differs between two Configs. Old or New is
+// empty if the field only exists in one of them, e.g. because an oracle was
+// added.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c Change) String() string {
+	from, to := c.Old, c.New
+	if from == "" {
+		from = "(none)"
+	}
+	if to == "" {
+		to = "(none)"
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, from, to)
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+func fields(c Config) []field {
+	fs := []field{
+		{"Protocol", c.Protocol()},
+		{"ConfigDigest", c.ConfigDigest.Hex()},
+		{"ConfigCount", fmt.Sprint(c.ConfigCount)},
+		{"OffchainConfigVersion", fmt.Sprint(c.OffchainConfigVersion)},
+		{"N", fmt.Sprint(len(c.OracleIdentities))},
+		{"F", fmt.Sprint(c.F)},
+		{"S", fmt.Sprint(c.S)},
+		{"RMax", fmt.Sprint(c.RMax)},
+	}
+	for _, t := range c.Timings {
+		fs = append(fs, field{t.Name, t.Duration.String()})
+	}
+	if c.LeaderSelection != nil {
+		fs = append(fs, field{"LeaderSelection.Weights", fmt.Sprint(c.LeaderSelection.Weights)})
+	}
+	if c.Compression != nil {
+		fs = append(fs, field{"Compression", c.Compression.String()})
+	}
+	for i, id := range c.OracleIdentities {
+		fs = append(fs,
+			field{fmt.Sprintf("OracleIdentities[%d].OffchainPublicKey", i), hexBytes(id.OffchainPublicKey[:])},
+			field{fmt.Sprintf("OracleIdentities[%d].OnchainPublicKey", i), hexBytes(id.OnchainPublicKey)},
+			field{fmt.Sprintf("OracleIdentities[%d].PeerID", i), id.PeerID},
+			field{fmt.Sprintf("OracleIdentities[%d].TransmitAccount", i), string(id.TransmitAccount)},
+		)
+	}
+	fs = append(fs,
+		field{"SharedSecretEncryptions.SharedSecretHash", hexBytes(c.SharedSecretEncryptions.SharedSecretHash[:])},
+		field{"SharedSecretEncryptions.DiffieHellmanPoint", hexBytes(c.SharedSecretEncryptions.DiffieHellmanPoint[:])},
+	)
+	for i, enc := range c.SharedSecretEncryptions.Encryptions {
+		fs = append(fs, field{fmt.Sprintf("SharedSecretEncryptions.Encryptions[%d]", i), hexBytes(enc[:])})
+	}
+	fs = append(fs,
+		field{"ReportingPluginConfig", hexBytes(c.ReportingPluginConfig)},
+		field{"OnchainConfig", hexBytes(c.OnchainConfig)},
+	)
+	return fs
+}
+
+// Diff compares from and to field by field. Oracles are compared by index,
+// since an oracle's index is its identity in the protocol. ConfigDigest and
+// ConfigCount are included, so they show up as changed unless from and to
+// stem from the same setConfig.
+func Diff(from, to Config) []Change {
+	oldFields := fields(from)
+	newFields := fields(to)
+	newValues := map[string]string{}
+	for _, f := range newFields {
+		newValues[f.name] = f.value
+	}
+	oldValues := map[string]string{}
+
+	changes := []Change{}
+	for _, f := range oldFields {
+		oldValues[f.name] = f.value
+		if newValue, ok := newValues[f.name]; !ok || newValue != f.value {
+			changes = append(changes, Change{f.name, f.value, newValue})
+		}
+	}
+	for _, f := range newFields {
+		if _, ok := oldValues[f.name]; !ok {
+			changes = append(changes, Change{f.name, "", f.value})
+		}
+	}
+	return changes
+}
+
+// MovedOracles describes oracles that appear at different indices in from and
+// to, identified by their PeerID. Diff reports these as changes of every
+// affected index, which is easy to misread.
+func MovedOracles(from, to Config) []string {
+	newIndices := map[string]int{}
+	for i, id := range to.OracleIdentities {
+		newIndices[id.PeerID] = i
+	}
+	moved := []string{}
+	for i, id := range from.OracleIdentities {
+		if j, ok := newIndices[id.PeerID]; ok && i != j {
+			moved = append(moved, fmt.Sprintf("oracle with PeerID %s moved from index %d to %d", id.PeerID, i, j))
+		}
+	}
+	return moved
+}
+
+// FormatDiff renders the output of Diff and MovedOracles, one change per
+// line, and notes whether the shared secret was merely re-encrypted.
+func FormatDiff(from, to Config) string {
+	var sb strings.Builder
+	for _, c := range Diff(from, to) {
+		sb.WriteString(c.String())
+		sb.WriteString("\n")
+	}
+	for _, m := range MovedOracles(from, to) {
+		sb.WriteString(m)
+		sb.WriteString("\n")
+	}
+	if from.SharedSecretEncryptions.SharedSecretHash == to.SharedSecretEncryptions.SharedSecretHash &&
+		from.SharedSecretEncryptions.DiffieHellmanPoint != to.SharedSecretEncryptions.DiffieHellmanPoint {
+		sb.WriteString("shared secret is unchanged, but was encrypted anew\n")
+	}
+	return sb.String()
+}
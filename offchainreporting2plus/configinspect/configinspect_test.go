@@ -0,0 +1,324 @@
+package configinspect_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/configinspect"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+func decode(t *testing.T, contractConfig types.ContractConfig) configinspect.Config {
+	t.Helper()
+	c, err := configinspect.Decode(false, contractConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func timing(c configinspect.Config, name string) (time.Duration, bool) {
+	for _, t := range c.Timings {
+		if t.Name == name {
+			return t.Duration, true
+		}
+	}
+	return 0, false
+}
+
+func findChange(changes []configinspect.Change, field string) (configinspect.Change, bool) {
+	for _, c := range changes {
+		if c.Field == field {
+			return c, true
+		}
+	}
+	return configinspect.Change{}, false
+}
+
+func TestDecode(t *testing.T) {
+	oracles, _ := newTestOracles(t, 4)
+
+	for _, test := range []struct {
+		protocol       string
+		contractConfig types.ContractConfig
+		// timings that only exist in this protocol
+		timings     []string
+		missing     []string
+		deltaGrace  time.Duration
+		hasOCR3Args bool
+	}{
+		{"OCR2", newOCR2ContractConfig(t, oracles), []string{"MaxDurationReport", "MaxDurationShouldAcceptFinalizedReport"}, []string{"DeltaInitial", "DeltaCertifiedCommitRequest"}, 100 * time.Millisecond, false},
+		{"OCR3", newOCR3ContractConfig(t, oracles), []string{"DeltaInitial", "DeltaCertifiedCommitRequest", "MaxDurationShouldAcceptAttestedReport"}, []string{"MaxDurationReport"}, 50 * time.Millisecond, true},
+	} {
+		t.Run(test.protocol, func(t *testing.T) {
+			c := decode(t, test.contractConfig)
+			if c.Protocol() != test.protocol || c.ConfigDigest != test.contractConfig.ConfigDigest || c.ConfigCount != 1 {
+				t.Errorf("unexpected header %v %v %v", c.Protocol(), c.ConfigDigest, c.ConfigCount)
+			}
+			if c.F != 1 || !reflect.DeepEqual(c.S, []int{1, 1, 1, 1}) || len(c.OracleIdentities) != 4 {
+				t.Errorf("unexpected F %v, S %v or %v oracles", c.F, c.S, len(c.OracleIdentities))
+			}
+			for i, id := range c.OracleIdentities {
+				if !reflect.DeepEqual(id, oracles[i].OracleIdentity) {
+					t.Errorf("oracle %v decoded as %+v, expected %+v", i, id, oracles[i].OracleIdentity)
+				}
+			}
+			if d, ok := timing(c, "DeltaProgress"); !ok || d != 10*time.Second {
+				t.Errorf("DeltaProgress decoded as %v", d)
+			}
+			if d, ok := timing(c, "DeltaGrace"); !ok || d != test.deltaGrace {
+				t.Errorf("DeltaGrace decoded as %v", d)
+			}
+			for _, name := range test.timings {
+				if _, ok := timing(c, name); !ok {
+					t.Errorf("missing timing %v", name)
+				}
+			}
+			for _, name := range test.missing {
+				if _, ok := timing(c, name); ok {
+					t.Errorf("unexpected timing %v", name)
+				}
+			}
+			if (c.LeaderSelection != nil) != test.hasOCR3Args || (c.Compression != nil) != test.hasOCR3Args {
+				t.Errorf("unexpected LeaderSelection %v and Compression %v", c.LeaderSelection, c.Compression)
+			}
+			if string(c.ReportingPluginConfig) != "plugin config" || string(c.OnchainConfig) != "onchain config" {
+				t.Errorf("unexpected plugin config %q or onchain config %q", c.ReportingPluginConfig, c.OnchainConfig)
+			}
+			if len(c.SharedSecretEncryptions.Encryptions) != 4 {
+				t.Errorf("got %v shared secret encryptions", len(c.SharedSecretEncryptions.Encryptions))
+			}
+		})
+	}
+
+	cc := newOCR3ContractConfig(t, oracles)
+	cc.OffchainConfigVersion = 1
+	if _, err := configinspect.Decode(false, cc); err == nil || !strings.Contains(err.Error(), "unsupported OffchainConfigVersion 1") {
+		t.Errorf("unexpected error %v for unknown version", err)
+	}
+	cc = newOCR3ContractConfig(t, oracles)
+	cc.OffchainConfig = cc.OffchainConfig[:len(cc.OffchainConfig)/2]
+	if _, err := configinspect.Decode(false, cc); err == nil || !strings.Contains(err.Error(), "failed to decode OCR3 config") {
+		t.Errorf("unexpected error %v for truncated offchain config", err)
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	oracles, _ := newTestOracles(t, 4)
+	for _, contractConfig := range []types.ContractConfig{newOCR2ContractConfig(t, oracles), newOCR3ContractConfig(t, oracles)} {
+		c := decode(t, contractConfig)
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Protocol         string
+			ConfigDigest     string
+			N                int
+			OracleIdentities []struct {
+				OnchainPublicKey string
+				PeerID           string
+			}
+			Timings                 map[string]string
+			LeaderSelection         *struct{ Weights []int }
+			Compression             string
+			SharedSecretEncryptions struct{ Encryptions []string }
+			ReportingPluginConfig   string
+		}
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Protocol != c.Protocol() || decoded.ConfigDigest != c.ConfigDigest.Hex() || decoded.N != 4 {
+			t.Errorf("unexpected header in %s", b)
+		}
+		if len(decoded.OracleIdentities) != 4 || decoded.OracleIdentities[1].OnchainPublicKey != "0x0202020202020202020202020202020202020202" ||
+			decoded.OracleIdentities[1].PeerID != oracles[1].PeerID {
+			t.Errorf("unexpected oracle identities in %s", b)
+		}
+		if decoded.Timings["DeltaProgress"] != "10s" || len(decoded.Timings) != len(c.Timings) {
+			t.Errorf("unexpected timings %v", decoded.Timings)
+		}
+		if (decoded.LeaderSelection != nil) != (c.LeaderSelection != nil) || (decoded.Compression != "") != (c.Compression != nil) {
+			t.Errorf("unexpected leader selection or compression in %s", b)
+		}
+		if len(decoded.SharedSecretEncryptions.Encryptions) != 4 || decoded.ReportingPluginConfig != "0x706c7567696e20636f6e666967" {
+			t.Errorf("unexpected blobs in %s", b)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oracles, _ := newTestOracles(t, 4)
+	from := decode(t, newOCR3ContractConfig(t, oracles))
+
+	if changes := configinspect.Diff(from, from); len(changes) != 0 {
+		t.Errorf("config differs from itself: %v", changes)
+	}
+	if s := configinspect.FormatDiff(from, from); s != "" {
+		t.Errorf("config differs from itself: %q", s)
+	}
+
+	// a new setConfig with the same parameters changes the shared secret,
+	// but nothing else
+	again := decode(t, newOCR3ContractConfig(t, oracles))
+	for _, c := range configinspect.Diff(from, again) {
+		if !strings.HasPrefix(c.Field, "SharedSecretEncryptions.") {
+			t.Errorf("unexpected change %v", c)
+		}
+	}
+
+	to := from
+	to.ConfigCount = 2
+	to.Timings = append([]configinspect.Timing{}, from.Timings...)
+	to.Timings[0].Duration = 20 * time.Second
+	to.ReportingPluginConfig = []byte("new plugin config")
+	to.OracleIdentities = append([]confighelper.OracleIdentity{}, from.OracleIdentities...)
+	to.OracleIdentities[2].TransmitAccount = "new transmitter"
+	changes := configinspect.Diff(from, to)
+	for _, expected := range []configinspect.Change{
+		{"ConfigCount", "1", "2"},
+		{"DeltaProgress", "10s", "20s"},
+		{"ReportingPluginConfig", "0x706c7567696e20636f6e666967", "0x6e657720706c7567696e20636f6e666967"},
+		{"OracleIdentities[2].TransmitAccount", "transmitter 2", "new transmitter"},
+	} {
+		if c, ok := findChange(changes, expected.Field); !ok || c != expected {
+			t.Errorf("got change %+v, expected %+v", c, expected)
+		}
+	}
+	if len(changes) != 4 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	if s := configinspect.FormatDiff(from, to); !strings.Contains(s, "DeltaProgress: 10s -> 20s\n") {
+		t.Errorf("unexpected formatted diff %q", s)
+	}
+
+	// going from OCR2 to OCR3 adds and removes fields
+	ocr2 := decode(t, newOCR2ContractConfig(t, oracles))
+	changes = configinspect.Diff(ocr2, from)
+	for _, expected := range []configinspect.Change{
+		{"Protocol", "OCR2", "OCR3"},
+		{"MaxDurationReport", "1s", ""},
+		{"DeltaInitial", "", "1s"},
+	} {
+		if c, ok := findChange(changes, expected.Field); !ok || c != expected {
+			t.Errorf("got change %+v, expected %+v", c, expected)
+		}
+	}
+	if c, _ := findChange(changes, "MaxDurationReport"); c.String() != "MaxDurationReport: 1s -> (none)" {
+		t.Errorf("unexpected formatting of removed field: %q", c.String())
+	}
+}
+
+func TestDiffOracles(t *testing.T) {
+	oracles, _ := newTestOracles(t, 7)
+	from := decode(t, newOCR3ContractConfig(t, oracles[:4]))
+
+	// adding an oracle adds fields, without changing existing oracles
+	added := from
+	added.OracleIdentities = append(append([]confighelper.OracleIdentity{}, from.OracleIdentities...), oracles[4].OracleIdentity)
+	changes := configinspect.Diff(from, added)
+	if c, ok := findChange(changes, "N"); !ok || c != (configinspect.Change{"N", "4", "5"}) {
+		t.Errorf("unexpected change of N %+v", c)
+	}
+	if c, ok := findChange(changes, "OracleIdentities[4].PeerID"); !ok || c.Old != "" || c.New != oracles[4].PeerID {
+		t.Errorf("unexpected change of new oracle %+v", c)
+	}
+	for _, c := range changes {
+		if strings.HasPrefix(c.Field, "OracleIdentities[") && !strings.HasPrefix(c.Field, "OracleIdentities[4]") {
+			t.Errorf("unexpected change of existing oracle %v", c)
+		}
+	}
+	if moved := configinspect.MovedOracles(from, added); len(moved) != 0 {
+		t.Errorf("unexpected moved oracles %v", moved)
+	}
+
+	// swapping two oracles changes both indices, which MovedOracles explains
+	swapped := from
+	swapped.OracleIdentities = append([]confighelper.OracleIdentity{}, from.OracleIdentities...)
+	swapped.OracleIdentities[0], swapped.OracleIdentities[3] = swapped.OracleIdentities[3], swapped.OracleIdentities[0]
+	if c, ok := findChange(configinspect.Diff(from, swapped), "OracleIdentities[0].PeerID"); !ok || c.New != oracles[3].PeerID {
+		t.Errorf("unexpected change of swapped oracle %+v", c)
+	}
+	moved := configinspect.MovedOracles(from, swapped)
+	expected := []string{
+		"oracle with PeerID " + oracles[0].PeerID + " moved from index 0 to 3",
+		"oracle with PeerID " + oracles[3].PeerID + " moved from index 3 to 0",
+	}
+	if !reflect.DeepEqual(moved, expected) {
+		t.Errorf("got moved oracles %v, expected %v", moved, expected)
+	}
+	if s := configinspect.FormatDiff(from, swapped); !strings.Contains(s, expected[0]+"\n") {
+		t.Errorf("formatted diff %q doesn't mention moved oracle", s)
+	}
+}
+
+func TestFormatDiffReencryption(t *testing.T) {
+	oracles, keyrings := newTestOracles(t, 4)
+	old := newOCR3ContractConfig(t, oracles)
+	var keys []types.ConfigEncryptionPublicKey
+	for _, o := range oracles {
+		keys = append(keys, o.ConfigEncryptionPublicKey)
+	}
+	reencrypted, _, err := configinspect.ReencryptSharedSecret(old, keyrings[0], keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := configinspect.FormatDiff(decode(t, old), decode(t, reencrypted))
+	if !strings.Contains(s, "shared secret is unchanged, but was encrypted anew\n") {
+		t.Errorf("formatted diff %q doesn't note re-encryption", s)
+	}
+	if strings.Contains(s, "SharedSecretHash") {
+		t.Errorf("formatted diff %q reports changed shared secret", s)
+	}
+}
+
+func TestContractConfigJSON(t *testing.T) {
+	oracles, _ := newTestOracles(t, 4)
+	contractConfig := newOCR3ContractConfig(t, oracles)
+	b, err := configinspect.ContractConfigToJSON(contractConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := configinspect.ParseContractConfigJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, contractConfig) {
+		t.Errorf("parsed %+v, expected %+v", parsed, contractConfig)
+	}
+
+	// the zero ConfigDigest is omitted, and 0x prefixes are optional
+	parsed, err = configinspect.ParseContractConfigJSON([]byte(`{"configCount": 3, "signers": ["0102", "0X03"], "transmitters": ["a"], "f": 1, "onchainConfig": "", "offchainConfigVersion": 30, "offchainConfig": "0xff"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ConfigDigest != (types.ConfigDigest{}) || parsed.ConfigCount != 3 || len(parsed.Signers) != 2 ||
+		string(parsed.Signers[1]) != "\x03" || parsed.OffchainConfigVersion != 30 || string(parsed.OffchainConfig) != "\xff" {
+		t.Errorf("unexpected parsed config %+v", parsed)
+	}
+	zero := contractConfig
+	zero.ConfigDigest = types.ConfigDigest{}
+	if b, err := configinspect.ContractConfigToJSON(zero); err != nil || strings.Contains(string(b), "configDigest") {
+		t.Errorf("zero ConfigDigest wasn't omitted: %s, %v", b, err)
+	}
+
+	for _, test := range []struct {
+		json string
+		err  string
+	}{
+		{`{`, "failed to unmarshal"},
+		{`{"configDigest": "0x01"}`, "invalid configDigest"},
+		{`{"configDigest": "zz"}`, "invalid configDigest"},
+		{`{"signers": ["0x01", "xyz"]}`, "invalid signers[1]"},
+		{`{"onchainConfig": "0x0"}`, "invalid onchainConfig"},
+		{`{"offchainConfig": "nothex"}`, "invalid offchainConfig"},
+	} {
+		if _, err := configinspect.ParseContractConfigJSON([]byte(test.json)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("parsing %s returned error %v, expected %q", test.json, err, test.err)
+		}
+	}
+}
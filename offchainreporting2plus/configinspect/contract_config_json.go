@@ -0,0 +1,111 @@
+package configinspect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ContractConfigJSON is a chain-agnostic JSON encoding of types.ContractConfig,
+// e.g. for the args of a setConfig transaction. Binary values are hex-encoded,
+// the 0x prefix is optional. ConfigDigest may be omitted for proposed configs
+// that haven't been set yet.
+type ContractConfigJSON struct {
+	ConfigDigest          string   `json:"configDigest,omitempty"`
+	ConfigCount           uint64   `json:"configCount"`
+	Signers               []string `json:"signers"`
+	Transmitters          []string `json:"transmitters"`
+	F                     uint8    `json:"f"`
+	OnchainConfig         string   `json:"onchainConfig"`
+	OffchainConfigVersion uint64   `json:"offchainConfigVersion"`
+	OffchainConfig        string   `json:"offchainConfig"`
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+}
+
+// ParseContractConfigJSON parses the JSON encoding described at
+// ContractConfigJSON.
+func ParseContractConfigJSON(b []byte) (types.ContractConfig, error) {
+	var cj ContractConfigJSON
+	if err := json.Unmarshal(b, &cj); err != nil {
+		return types.ContractConfig{}, fmt.Errorf("failed to unmarshal ContractConfig JSON: %w", err)
+	}
+
+	configDigest := types.ConfigDigest{}
+	if cj.ConfigDigest != "" {
+		b, err := decodeHex(cj.ConfigDigest)
+		if err != nil {
+			return types.ContractConfig{}, fmt.Errorf("invalid configDigest: %w", err)
+		}
+		configDigest, err = types.BytesToConfigDigest(b)
+		if err != nil {
+			return types.ContractConfig{}, fmt.Errorf("invalid configDigest: %w", err)
+		}
+	}
+
+	signers := []types.OnchainPublicKey{}
+	for i, s := range cj.Signers {
+		signer, err := decodeHex(s)
+		if err != nil {
+			return types.ContractConfig{}, fmt.Errorf("invalid signers[%d]: %w", i, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	transmitters := []types.Account{}
+	for _, t := range cj.Transmitters {
+		transmitters = append(transmitters, types.Account(t))
+	}
+
+	onchainConfig, err := decodeHex(cj.OnchainConfig)
+	if err != nil {
+		return types.ContractConfig{}, fmt.Errorf("invalid onchainConfig: %w", err)
+	}
+	offchainConfig, err := decodeHex(cj.OffchainConfig)
+	if err != nil {
+		return types.ContractConfig{}, fmt.Errorf("invalid offchainConfig: %w", err)
+	}
+
+	return types.ContractConfig{
+		configDigest,
+		cj.ConfigCount,
+		signers,
+		transmitters,
+		cj.F,
+		onchainConfig,
+		cj.OffchainConfigVersion,
+		offchainConfig,
+	}, nil
+}
+
+// ContractConfigToJSON is the inverse of ParseContractConfigJSON. A zero
+// ConfigDigest is omitted.
+func ContractConfigToJSON(contractConfig types.ContractConfig) ([]byte, error) {
+	signers := []string{}
+	for _, s := range contractConfig.Signers {
+		signers = append(signers, hexBytes(s))
+	}
+	transmitters := []string{}
+	for _, t := range contractConfig.Transmitters {
+		transmitters = append(transmitters, string(t))
+	}
+	configDigest := ""
+	if contractConfig.ConfigDigest != (types.ConfigDigest{}) {
+		configDigest = hexBytes(contractConfig.ConfigDigest[:])
+	}
+	return json.MarshalIndent(ContractConfigJSON{
+		configDigest,
+		contractConfig.ConfigCount,
+		signers,
+		transmitters,
+		contractConfig.F,
+		hexBytes(contractConfig.OnchainConfig),
+		contractConfig.OffchainConfigVersion,
+		hexBytes(contractConfig.OffchainConfig),
+	}, "", "  ")
+}
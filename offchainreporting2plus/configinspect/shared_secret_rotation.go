@@ -0,0 +1,94 @@
+package configinspect
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ReencryptSharedSecret is like
+// confighelper.ContractSetConfigArgsReencryptingSharedSecret and its OCR3
+// counterpart, picked based on the OffchainConfigVersion of contractConfig.
+// It returns the new config as a ContractConfig, along with FormatDiff of the
+// old and new config, which should show nothing but the new encryptions.
+// Since the new config hasn't been set yet, its ConfigDigest is zero and its
+// ConfigCount that of contractConfig plus one.
+func ReencryptSharedSecret(
+	contractConfig types.ContractConfig,
+	offchainKeyring types.OffchainKeyring,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+) (newContractConfig types.ContractConfig, diff string, err error) {
+	return rotateSharedSecret(contractConfig, offchainKeyring, sharedSecretEncryptionPublicKeys, nil)
+}
+
+// FreshSharedSecret is like ReencryptSharedSecret, but draws a new shared
+// secret from rand, see confighelper.ContractSetConfigArgsWithFreshSharedSecret.
+func FreshSharedSecret(
+	contractConfig types.ContractConfig,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+	rand io.Reader,
+) (newContractConfig types.ContractConfig, diff string, err error) {
+	return rotateSharedSecret(contractConfig, nil, sharedSecretEncryptionPublicKeys, rand)
+}
+
+// rotateSharedSecret re-encrypts the existing shared secret if
+// offchainKeyring is given, and draws a new one from rand otherwise.
+func rotateSharedSecret(
+	contractConfig types.ContractConfig,
+	offchainKeyring types.OffchainKeyring,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+	rand io.Reader,
+) (types.ContractConfig, string, error) {
+	// configs can only be rotated if they can be decoded
+	from, err := Decode(true, contractConfig)
+	if err != nil {
+		return types.ContractConfig{}, "", err
+	}
+
+	var (
+		signers               []types.OnchainPublicKey
+		transmitters          []types.Account
+		f                     uint8
+		onchainConfig         []byte
+		offchainConfigVersion uint64
+		offchainConfig        []byte
+	)
+	switch {
+	case from.Protocol() == "OCR2" && offchainKeyring != nil:
+		signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err =
+			confighelper.ContractSetConfigArgsReencryptingSharedSecret(contractConfig, offchainKeyring, sharedSecretEncryptionPublicKeys)
+	case from.Protocol() == "OCR2":
+		signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err =
+			confighelper.ContractSetConfigArgsWithFreshSharedSecret(contractConfig, sharedSecretEncryptionPublicKeys, rand)
+	case from.Protocol() == "OCR3" && offchainKeyring != nil:
+		signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err =
+			ocr3confighelper.ContractSetConfigArgsReencryptingSharedSecret(contractConfig, offchainKeyring, sharedSecretEncryptionPublicKeys)
+	case from.Protocol() == "OCR3":
+		signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err =
+			ocr3confighelper.ContractSetConfigArgsWithFreshSharedSecret(contractConfig, sharedSecretEncryptionPublicKeys, rand)
+	default:
+		return types.ContractConfig{}, "", fmt.Errorf("cannot rotate shared secret of %v config", from.Protocol())
+	}
+	if err != nil {
+		return types.ContractConfig{}, "", err
+	}
+
+	newContractConfig := types.ContractConfig{
+		types.ConfigDigest{},
+		contractConfig.ConfigCount + 1,
+		signers,
+		transmitters,
+		f,
+		onchainConfig,
+		offchainConfigVersion,
+		offchainConfig,
+	}
+	to, err := Decode(true, newContractConfig)
+	if err != nil {
+		return types.ContractConfig{}, "", fmt.Errorf("could not decode new config: %w", err)
+	}
+	return newContractConfig, FormatDiff(from, to), nil
+}
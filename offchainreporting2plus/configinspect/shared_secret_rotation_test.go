@@ -0,0 +1,230 @@
+package configinspect_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/configinspect"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+// testKeyring is an OffchainKeyring that can only decrypt the shared secret.
+type testKeyring struct {
+	offchainPublicKey types.OffchainPublicKey
+	secretKey         [32]byte
+}
+
+var _ types.OffchainKeyring = testKeyring{}
+
+func newTestKeyring(t *testing.T, offchainPublicKey types.OffchainPublicKey) testKeyring {
+	k := testKeyring{offchainPublicKey: offchainPublicKey}
+	if _, err := rand.Read(k.secretKey[:]); err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func (k testKeyring) OffchainSign(msg []byte) ([]byte, error) {
+	return nil, fmt.Errorf("testKeyring cannot sign")
+}
+
+func (k testKeyring) ConfigDiffieHellman(point [curve25519.PointSize]byte) (sharedPoint [curve25519.PointSize]byte, err error) {
+	p, err := curve25519.X25519(k.secretKey[:], point[:])
+	if err != nil {
+		return sharedPoint, err
+	}
+	copy(sharedPoint[:], p)
+	return sharedPoint, nil
+}
+
+func (k testKeyring) OffchainPublicKey() types.OffchainPublicKey {
+	return k.offchainPublicKey
+}
+
+func (k testKeyring) ConfigEncryptionPublicKey() (pk types.ConfigEncryptionPublicKey) {
+	p, err := curve25519.X25519(k.secretKey[:], curve25519.Basepoint)
+	if err != nil {
+		panic(err)
+	}
+	copy(pk[:], p)
+	return pk
+}
+
+func newTestOracles(t *testing.T, n int) ([]confighelper.OracleIdentityExtra, []testKeyring) {
+	var oracles []confighelper.OracleIdentityExtra
+	var keyrings []testKeyring
+	for i := 0; i < n; i++ {
+		offchainPublicKey, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, peerSecretKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		peerID, err := ragetypes.PeerIDFromPrivateKey(peerSecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyring := newTestKeyring(t, types.OffchainPublicKey(offchainPublicKey))
+		oracles = append(oracles, confighelper.OracleIdentityExtra{
+			confighelper.OracleIdentity{
+				keyring.OffchainPublicKey(),
+				types.OnchainPublicKey(bytes.Repeat([]byte{byte(i + 1)}, 20)),
+				peerID.String(),
+				types.Account(fmt.Sprintf("transmitter %v", i)),
+			},
+			keyring.ConfigEncryptionPublicKey(),
+		})
+		keyrings = append(keyrings, keyring)
+	}
+	return oracles, keyrings
+}
+
+// contractConfig turns setConfig args into the ContractConfig of the first
+// setConfig.
+func contractConfig(signers []types.OnchainPublicKey, transmitters []types.Account, f uint8, onchainConfig []byte, offchainConfigVersion uint64, offchainConfig []byte, err error) (types.ContractConfig, error) {
+	return types.ContractConfig{
+		types.ConfigDigest{1},
+		1,
+		signers,
+		transmitters,
+		f,
+		onchainConfig,
+		offchainConfigVersion,
+		offchainConfig,
+	}, err
+}
+
+func newOCR2ContractConfig(t *testing.T, oracles []confighelper.OracleIdentityExtra) types.ContractConfig {
+	cc, err := contractConfig(confighelper.ContractSetConfigArgsForTests(
+		10*time.Second, 10*time.Second, time.Second, 100*time.Millisecond, 5*time.Second, 3,
+		[]int{1, 1, 1, 1}, oracles, []byte("plugin config"),
+		time.Second, time.Second, time.Second, time.Second, time.Second,
+		1, []byte("onchain config"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cc
+}
+
+func newOCR3ContractConfig(t *testing.T, oracles []confighelper.OracleIdentityExtra) types.ContractConfig {
+	cc, err := contractConfig(ocr3confighelper.ContractSetConfigArgsForTests(
+		10*time.Second, 10*time.Second, time.Second, 100*time.Millisecond, 50*time.Millisecond, time.Second, 5*time.Second, 10,
+		[]int{1, 1, 1, 1}, oracles, []byte("plugin config"),
+		time.Second, time.Second, time.Second, time.Second,
+		1, []byte("onchain config"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cc
+}
+
+func decryptSharedSecret(contractConfig types.ContractConfig, keyring testKeyring) (*[config.SharedSecretSize]byte, error) {
+	switch contractConfig.OffchainConfigVersion {
+	case config.OCR2OffchainConfigVersion:
+		sharedConfig, _, err := ocr2config.SharedConfigFromContractConfigWithOffchainKeyring(true, contractConfig, keyring)
+		return sharedConfig.SharedSecret, err
+	case config.OCR3OffchainConfigVersion:
+		sharedConfig, _, err := ocr3config.SharedConfigFromContractConfigWithOffchainKeyring(true, contractConfig, keyring)
+		return sharedConfig.SharedSecret, err
+	}
+	return nil, fmt.Errorf("unknown OffchainConfigVersion %v", contractConfig.OffchainConfigVersion)
+}
+
+// sharedSecret decrypts the shared secret of contractConfig with keyring.
+func sharedSecret(t *testing.T, contractConfig types.ContractConfig, keyring testKeyring) [config.SharedSecretSize]byte {
+	t.Helper()
+	sharedSecret, err := decryptSharedSecret(contractConfig, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *sharedSecret
+}
+
+func TestRotateSharedSecret(t *testing.T) {
+	for _, protocol := range []string{"OCR2", "OCR3"} {
+		t.Run(protocol, func(t *testing.T) {
+			oracles, keyrings := newTestOracles(t, 4)
+			var old types.ContractConfig
+			if protocol == "OCR2" {
+				old = newOCR2ContractConfig(t, oracles)
+			} else {
+				old = newOCR3ContractConfig(t, oracles)
+			}
+			secret := sharedSecret(t, old, keyrings[0])
+
+			// oracle 2 gets a new key
+			newKeyrings := append([]testKeyring(nil), keyrings...)
+			newKeyrings[2] = newTestKeyring(t, keyrings[2].offchainPublicKey)
+			var newKeys []types.ConfigEncryptionPublicKey
+			for _, k := range newKeyrings {
+				newKeys = append(newKeys, k.ConfigEncryptionPublicKey())
+			}
+
+			reencrypted, diff, err := configinspect.ReencryptSharedSecret(old, keyrings[1], newKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reencrypted.ConfigCount != old.ConfigCount+1 {
+				t.Errorf("ConfigCount is %v, expected %v", reencrypted.ConfigCount, old.ConfigCount+1)
+			}
+			for i, k := range newKeyrings {
+				if sharedSecret(t, reencrypted, k) != secret {
+					t.Errorf("oracle %v decrypts a different shared secret", i)
+				}
+			}
+			if _, err := decryptSharedSecret(reencrypted, keyrings[2]); err == nil {
+				t.Error("old key of oracle 2 still decrypts the shared secret")
+			}
+			if !strings.Contains(diff, "shared secret is unchanged, but was encrypted anew") {
+				t.Errorf("diff doesn't note re-encryption:\n%s", diff)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(diff), "\n") {
+				if !strings.HasPrefix(line, "ConfigDigest") && !strings.HasPrefix(line, "ConfigCount") &&
+					!strings.HasPrefix(line, "SharedSecretEncryptions") && !strings.HasPrefix(line, "shared secret is unchanged") {
+					t.Errorf("diff has unexpected change %q", line)
+				}
+			}
+
+			fresh, diff, err := configinspect.FreshSharedSecret(old, newKeys, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			freshSecret := sharedSecret(t, fresh, newKeyrings[0])
+			if freshSecret == secret {
+				t.Error("fresh shared secret equals old one")
+			}
+			for i, k := range newKeyrings {
+				if sharedSecret(t, fresh, k) != freshSecret {
+					t.Errorf("oracle %v decrypts a different fresh shared secret", i)
+				}
+			}
+			if strings.Contains(diff, "shared secret is unchanged") {
+				t.Errorf("diff claims fresh shared secret is unchanged:\n%s", diff)
+			}
+
+			if _, _, err := configinspect.ReencryptSharedSecret(old, keyrings[1], newKeys[:3]); err == nil {
+				t.Error("re-encrypted for fewer keys than oracles")
+			}
+			outsider := newTestKeyring(t, types.OffchainPublicKey{1})
+			if _, _, err := configinspect.ReencryptSharedSecret(old, outsider, newKeys); err == nil {
+				t.Error("keyring outside of config decrypted shared secret")
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+// Package dbconformance is a test suite for implementations of
+// types.Database, ocr3types.Database and networking/types.DiscovererDatabase.
+// Call the Test* functions from a test of your own package:
+//
+//	func TestMyDatabase(t *testing.T) {
+//		dbconformance.TestOCR3Database(t, func(t *testing.T) ocr3types.Database {
+//			return newEmptyDatabase(t)
+//		})
+//	}
+//
+// Every subtest calls newDatabase once and expects an empty database.
+package dbconformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	nettypes "github.com/smartcontractkit/libocr/networking/types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+var (
+	digestA = types.ConfigDigest{0x00, 0x01, 0xaa}
+	digestB = types.ConfigDigest{0x00, 0x01, 0xbb}
+)
+
+func exampleConfig(configCount uint64) types.ContractConfig {
+	return types.ContractConfig{
+		digestA,
+		configCount,
+		[]types.OnchainPublicKey{{0x01, 0x02}, {0x03}, {0x04, 0x05, 0x06}, {0x07}},
+		[]types.Account{"0xa", "0xb", "0xc", "0xd"},
+		1,
+		[]byte("onchain config"),
+		3,
+		[]byte("offchain config"),
+	}
+}
+
+func examplePendingTransmission(t time.Time, report string) types.PendingTransmission {
+	return types.PendingTransmission{
+		t,
+		[32]byte{0x42, 0x43},
+		types.Report(report),
+		[]types.AttributedOnchainSignature{{[]byte("sig0"), 0}, {[]byte("sig2"), 2}},
+	}
+}
+
+// TestConfigDatabase checks ReadConfig and WriteConfig. TestOCR2Database and
+// TestOCR3Database run it, too.
+func TestConfigDatabase(t *testing.T, newDatabase func(t *testing.T) types.ConfigDatabase) {
+	ctx := context.Background()
+
+	t.Run("ReadConfig returns nil when empty", func(t *testing.T) {
+		db := newDatabase(t)
+		config, err := db.ReadConfig(ctx)
+		if err != nil {
+			t.Fatalf("ReadConfig: %v", err)
+		}
+		if config != nil {
+			t.Fatalf("ReadConfig returned %+v, expected nil", config)
+		}
+	})
+
+	t.Run("ReadConfig returns what was written last", func(t *testing.T) {
+		db := newDatabase(t)
+		for _, config := range []types.ContractConfig{exampleConfig(1), exampleConfig(2)} {
+			if err := db.WriteConfig(ctx, config); err != nil {
+				t.Fatalf("WriteConfig: %v", err)
+			}
+			read, err := db.ReadConfig(ctx)
+			if err != nil {
+				t.Fatalf("ReadConfig: %v", err)
+			}
+			if read == nil || !equalContractConfigs(*read, config) {
+				t.Fatalf("ReadConfig returned %+v, expected %+v", read, config)
+			}
+		}
+	})
+}
+
+// TestOCR2Database checks all methods of types.Database.
+func TestOCR2Database(t *testing.T, newDatabase func(t *testing.T) types.Database) {
+	ctx := context.Background()
+
+	TestConfigDatabase(t, func(t *testing.T) types.ConfigDatabase { return newDatabase(t) })
+
+	t.Run("ReadState returns nil when empty", func(t *testing.T) {
+		db := newDatabase(t)
+		state, err := db.ReadState(ctx, digestA)
+		if err != nil {
+			t.Fatalf("ReadState: %v", err)
+		}
+		if state != nil {
+			t.Fatalf("ReadState returned %+v, expected nil", state)
+		}
+	})
+
+	t.Run("ReadState returns what was written last for the config digest", func(t *testing.T) {
+		db := newDatabase(t)
+		stateA1 := types.PersistentState{1, 2, []uint32{1, 2, 3, 4}}
+		stateA2 := types.PersistentState{5, 6, []uint32{7, 8, 9, 10}}
+		stateB := types.PersistentState{11, 12, []uint32{13, 14, 15, 16}}
+		for _, write := range []struct {
+			digest types.ConfigDigest
+			state  types.PersistentState
+		}{{digestA, stateA1}, {digestB, stateB}, {digestA, stateA2}} {
+			if err := db.WriteState(ctx, write.digest, write.state); err != nil {
+				t.Fatalf("WriteState: %v", err)
+			}
+		}
+		for digest, expected := range map[types.ConfigDigest]types.PersistentState{digestA: stateA2, digestB: stateB} {
+			state, err := db.ReadState(ctx, digest)
+			if err != nil {
+				t.Fatalf("ReadState: %v", err)
+			}
+			if state == nil || !state.Equal(expected) {
+				t.Fatalf("ReadState(%v) returned %+v, expected %+v", digest, state, expected)
+			}
+		}
+	})
+
+	t.Run("pending transmissions", func(t *testing.T) {
+		db := newDatabase(t)
+		now := time.Unix(1_700_000_000, 123_456_000) // microsecond precision suffices
+
+		pending, err := db.PendingTransmissionsWithConfigDigest(ctx, digestA)
+		if err != nil {
+			t.Fatalf("PendingTransmissionsWithConfigDigest: %v", err)
+		}
+		if len(pending) != 0 {
+			t.Fatalf("PendingTransmissionsWithConfigDigest returned %v entries for empty database", len(pending))
+		}
+
+		expected := map[types.ReportTimestamp]types.PendingTransmission{
+			{digestA, 1, 1}: examplePendingTransmission(now, "a11"),
+			{digestA, 1, 2}: examplePendingTransmission(now.Add(time.Second), "a12"),
+			{digestA, 2, 1}: examplePendingTransmission(now.Add(2*time.Second), "a21"),
+		}
+		other := types.ReportTimestamp{digestB, 1, 1}
+		for ts, pt := range expected {
+			if err := db.StorePendingTransmission(ctx, ts, pt); err != nil {
+				t.Fatalf("StorePendingTransmission: %v", err)
+			}
+		}
+		if err := db.StorePendingTransmission(ctx, other, examplePendingTransmission(now, "b11")); err != nil {
+			t.Fatalf("StorePendingTransmission: %v", err)
+		}
+		checkPendingTransmissions(t, db, digestA, expected)
+
+		// overwrite
+		overwritten := types.ReportTimestamp{digestA, 1, 2}
+		expected[overwritten] = examplePendingTransmission(now.Add(3*time.Second), "a12 again")
+		if err := db.StorePendingTransmission(ctx, overwritten, expected[overwritten]); err != nil {
+			t.Fatalf("StorePendingTransmission: %v", err)
+		}
+		checkPendingTransmissions(t, db, digestA, expected)
+
+		// delete
+		deleted := types.ReportTimestamp{digestA, 2, 1}
+		delete(expected, deleted)
+		if err := db.DeletePendingTransmission(ctx, deleted); err != nil {
+			t.Fatalf("DeletePendingTransmission: %v", err)
+		}
+		checkPendingTransmissions(t, db, digestA, expected)
+
+		// deleting a missing entry is not an error
+		if err := db.DeletePendingTransmission(ctx, deleted); err != nil {
+			t.Fatalf("DeletePendingTransmission of missing entry: %v", err)
+		}
+
+		// delete by age, across config digests
+		if err := db.DeletePendingTransmissionsOlderThan(ctx, now.Add(time.Second)); err != nil {
+			t.Fatalf("DeletePendingTransmissionsOlderThan: %v", err)
+		}
+		delete(expected, types.ReportTimestamp{digestA, 1, 1})
+		checkPendingTransmissions(t, db, digestA, expected)
+		checkPendingTransmissions(t, db, digestB, map[types.ReportTimestamp]types.PendingTransmission{})
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		db := newDatabase(t)
+		concurrently(t, func(i int) error {
+			ts := types.ReportTimestamp{digestA, uint32(i), 1}
+			if err := db.StorePendingTransmission(ctx, ts, examplePendingTransmission(time.Unix(int64(i), 0), "report")); err != nil {
+				return err
+			}
+			return db.WriteState(ctx, digestA, types.PersistentState{uint32(i), uint32(i), []uint32{uint32(i)}})
+		})
+		pending, err := db.PendingTransmissionsWithConfigDigest(ctx, digestA)
+		if err != nil {
+			t.Fatalf("PendingTransmissionsWithConfigDigest: %v", err)
+		}
+		if len(pending) != concurrency {
+			t.Fatalf("PendingTransmissionsWithConfigDigest returned %v entries, expected %v", len(pending), concurrency)
+		}
+	})
+}
+
+// TestOCR3Database checks all methods of ocr3types.Database.
+func TestOCR3Database(t *testing.T, newDatabase func(t *testing.T) ocr3types.Database) {
+	ctx := context.Background()
+
+	TestConfigDatabase(t, func(t *testing.T) types.ConfigDatabase { return newDatabase(t) })
+
+	t.Run("ReadProtocolState returns nil for missing keys", func(t *testing.T) {
+		db := newDatabase(t)
+		value, err := db.ReadProtocolState(ctx, digestA, "key")
+		if err != nil {
+			t.Fatalf("ReadProtocolState: %v", err)
+		}
+		if value != nil {
+			t.Fatalf("ReadProtocolState returned %x, expected nil", value)
+		}
+	})
+
+	t.Run("ReadProtocolState returns what was written last for the config digest and key", func(t *testing.T) {
+		db := newDatabase(t)
+		writes := []struct {
+			digest types.ConfigDigest
+			key    string
+			value  []byte
+		}{
+			{digestA, "k1", []byte("a1 first")},
+			{digestA, "k2", []byte("a2")},
+			{digestB, "k1", []byte("b1")},
+			{digestA, "k1", []byte("a1 second")},
+			{digestA, "empty", []byte{}},
+		}
+		for _, w := range writes {
+			if err := db.WriteProtocolState(ctx, w.digest, w.key, w.value); err != nil {
+				t.Fatalf("WriteProtocolState: %v", err)
+			}
+		}
+		for _, r := range writes[1:] {
+			checkProtocolState(t, db, r.digest, r.key, r.value)
+		}
+	})
+
+	t.Run("WriteProtocolState with nil value deletes", func(t *testing.T) {
+		db := newDatabase(t)
+		if err := db.WriteProtocolState(ctx, digestA, "k", []byte("v")); err != nil {
+			t.Fatalf("WriteProtocolState: %v", err)
+		}
+		if err := db.WriteProtocolState(ctx, digestB, "k", []byte("v")); err != nil {
+			t.Fatalf("WriteProtocolState: %v", err)
+		}
+		if err := db.WriteProtocolState(ctx, digestA, "k", nil); err != nil {
+			t.Fatalf("WriteProtocolState: %v", err)
+		}
+		checkProtocolState(t, db, digestA, "k", nil)
+		checkProtocolState(t, db, digestB, "k", []byte("v"))
+
+		// deleting a missing key is not an error
+		if err := db.WriteProtocolState(ctx, digestA, "k", nil); err != nil {
+			t.Fatalf("WriteProtocolState deleting missing key: %v", err)
+		}
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		db := newDatabase(t)
+		concurrently(t, func(i int) error {
+			return db.WriteProtocolState(ctx, digestA, fmt.Sprintf("key%d", i), []byte{byte(i)})
+		})
+		for i := 0; i < concurrency; i++ {
+			checkProtocolState(t, db, digestA, fmt.Sprintf("key%d", i), []byte{byte(i)})
+		}
+	})
+}
+
+// TestDiscovererDatabase checks all methods of
+// networking/types.DiscovererDatabase.
+func TestDiscovererDatabase(t *testing.T, newDatabase func(t *testing.T) nettypes.DiscovererDatabase) {
+	ctx := context.Background()
+
+	t.Run("ReadAnnouncements omits missing peers", func(t *testing.T) {
+		db := newDatabase(t)
+		checkAnnouncements(t, db, nil, map[string][]byte{})
+		checkAnnouncements(t, db, []string{"p1", "p2"}, map[string][]byte{})
+	})
+
+	t.Run("ReadAnnouncements returns what was stored last", func(t *testing.T) {
+		db := newDatabase(t)
+		for _, s := range []struct {
+			peerID string
+			ann    string
+		}{{"p1", "first"}, {"p2", "p2"}, {"p3", "p3"}, {"p1", "second"}} {
+			if err := db.StoreAnnouncement(ctx, s.peerID, []byte(s.ann)); err != nil {
+				t.Fatalf("StoreAnnouncement: %v", err)
+			}
+		}
+		checkAnnouncements(t, db, []string{"p1", "p3", "p4"}, map[string][]byte{
+			"p1": []byte("second"),
+			"p3": []byte("p3"),
+		})
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		db := newDatabase(t)
+		peerIDs := []string{}
+		expected := map[string][]byte{}
+		for i := 0; i < concurrency; i++ {
+			peerID := fmt.Sprintf("peer%d", i)
+			peerIDs = append(peerIDs, peerID)
+			expected[peerID] = []byte(peerID)
+		}
+		concurrently(t, func(i int) error {
+			return db.StoreAnnouncement(ctx, peerIDs[i], expected[peerIDs[i]])
+		})
+		checkAnnouncements(t, db, peerIDs, expected)
+	})
+}
+
+const concurrency = 16
+
+func concurrently(t *testing.T, f func(i int) error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = f(i)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent call %v: %v", i, err)
+		}
+	}
+}
+
+func checkPendingTransmissions(t *testing.T, db types.Database, digest types.ConfigDigest, expected map[types.ReportTimestamp]types.PendingTransmission) {
+	t.Helper()
+	pending, err := db.PendingTransmissionsWithConfigDigest(context.Background(), digest)
+	if err != nil {
+		t.Fatalf("PendingTransmissionsWithConfigDigest: %v", err)
+	}
+	if len(pending) != len(expected) {
+		t.Fatalf("PendingTransmissionsWithConfigDigest returned %v entries, expected %v", len(pending), len(expected))
+	}
+	for ts, pt := range expected {
+		actual, ok := pending[ts]
+		if !ok {
+			t.Fatalf("PendingTransmissionsWithConfigDigest is missing %+v", ts)
+		}
+		if !equalPendingTransmissions(actual, pt) {
+			t.Fatalf("PendingTransmissionsWithConfigDigest returned %+v for %+v, expected %+v", actual, ts, pt)
+		}
+	}
+}
+
+func checkProtocolState(t *testing.T, db ocr3types.Database, digest types.ConfigDigest, key string, expected []byte) {
+	t.Helper()
+	value, err := db.ReadProtocolState(context.Background(), digest, key)
+	if err != nil {
+		t.Fatalf("ReadProtocolState: %v", err)
+	}
+	if (value == nil) != (expected == nil) || !bytes.Equal(value, expected) {
+		t.Fatalf("ReadProtocolState(%v, %q) returned %x (nil: %v), expected %x (nil: %v)", digest, key, value, value == nil, expected, expected == nil)
+	}
+}
+
+func checkAnnouncements(t *testing.T, db nettypes.DiscovererDatabase, peerIDs []string, expected map[string][]byte) {
+	t.Helper()
+	anns, err := db.ReadAnnouncements(context.Background(), peerIDs)
+	if err != nil {
+		t.Fatalf("ReadAnnouncements: %v", err)
+	}
+	if len(anns) != len(expected) {
+		t.Fatalf("ReadAnnouncements returned %v entries, expected %v", len(anns), len(expected))
+	}
+	for peerID, ann := range expected {
+		if !bytes.Equal(anns[peerID], ann) {
+			t.Fatalf("ReadAnnouncements returned %q for %v, expected %q", anns[peerID], peerID, ann)
+		}
+	}
+}
+
+func equalContractConfigs(a, b types.ContractConfig) bool {
+	if len(a.Signers) != len(b.Signers) {
+		return false
+	}
+	for i := range a.Signers {
+		if !bytes.Equal(a.Signers[i], b.Signers[i]) {
+			return false
+		}
+	}
+	return a.ConfigDigest == b.ConfigDigest &&
+		a.ConfigCount == b.ConfigCount &&
+		reflect.DeepEqual(a.Transmitters, b.Transmitters) &&
+		a.F == b.F &&
+		bytes.Equal(a.OnchainConfig, b.OnchainConfig) &&
+		a.OffchainConfigVersion == b.OffchainConfigVersion &&
+		bytes.Equal(a.OffchainConfig, b.OffchainConfig)
+}
+
+func equalPendingTransmissions(a, b types.PendingTransmission) bool {
+	if len(a.AttributedSignatures) != len(b.AttributedSignatures) {
+		return false
+	}
+	for i := range a.AttributedSignatures {
+		if a.AttributedSignatures[i].Signer != b.AttributedSignatures[i].Signer ||
+			!bytes.Equal(a.AttributedSignatures[i].Signature, b.AttributedSignatures[i].Signature) {
+			return false
+		}
+	}
+	return a.Time.Equal(b.Time) &&
+		a.ExtraHash == b.ExtraHash &&
+		bytes.Equal(a.Report, b.Report)
+}
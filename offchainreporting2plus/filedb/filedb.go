@@ -0,0 +1,479 @@
+// Package filedb provides an embedded, file-backed implementation of
+// types.Database, ocr3types.Database and networking/types.DiscovererDatabase
+// for oracles that shouldn't depend on an external database server.
+//
+// A DB keeps all its data in memory and persists every write to an
+// append-only log that is fsynced before the write returns. The log is
+// compacted automatically once it has grown well beyond the size of the live
+// data. A crash during a write leaves a torn record at the end of the log,
+// which is discarded when the DB is next opened; since the write never
+// returned, this never undoes a write the protocol has relied on, and in
+// particular never rolls back the pacemaker's epoch.
+//
+// Use one DB (i.e. one file) per oracle instance, and don't open the same file
+// from multiple processes at once.
+package filedb
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	nettypes "github.com/smartcontractkit/libocr/networking/types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// The log is compacted once it is larger than compactionMinSize and more than
+// compactionRatio times the size of the live data.
+const (
+	compactionMinSize = 1 << 20
+	compactionRatio   = 4
+)
+
+// Rough per-entry overhead of a record, used to estimate the size of the live
+// data.
+const entryOverhead = 4
+
+const (
+	keyConfig          = "config"
+	prefixOCR2State    = "ocr2/state/"
+	prefixOCR2Pending  = "ocr2/pending/"
+	prefixOCR3State    = "ocr3/state/"
+	prefixAnnouncement = "announcement/"
+)
+
+type DB struct {
+	path string
+
+	mutex sync.Mutex
+	file  *os.File // nil once closed
+	data  map[string][]byte
+	// size of the log file in bytes
+	size int64
+	// estimated size of data when written out as a log
+	liveSize int64
+	// set if the log file may be in an inconsistent state, after which we
+	// refuse further writes
+	err error
+}
+
+var (
+	_ types.Database              = (*DB)(nil)
+	_ ocr3types.Database          = (*DB)(nil)
+	_ nettypes.DiscovererDatabase = (*DB)(nil)
+)
+
+// Open opens the database at path, creating it if it doesn't exist.
+func Open(path string) (*DB, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	db := &DB{path: path, file: file}
+	if info.Size() == 0 {
+		// new database
+		if _, err := file.Write([]byte(fileMagic)); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		db.data = map[string][]byte{}
+		db.size = int64(len(fileMagic))
+		return db, nil
+	}
+
+	data, offset, err := replay(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if offset < info.Size() {
+		// discard torn record
+		if err := file.Truncate(offset); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	db.data = data
+	db.size = offset
+	for key, value := range data {
+		db.liveSize += int64(entryOverhead + len(key) + len(value))
+	}
+	return db, nil
+}
+
+// Close closes the database. Further calls to any method will fail.
+func (db *DB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.file == nil {
+		return fmt.Errorf("database already closed")
+	}
+	err := db.file.Close()
+	db.file = nil
+	return err
+}
+
+// Compact rewrites the log so that it only contains the live data. This
+// happens automatically as the log grows, so you'll only need to call Compact
+// if you want to reclaim disk space right away.
+func (db *DB) Compact() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err := db.checkWritable(); err != nil {
+		return err
+	}
+	return db.compactLocked()
+}
+
+func (db *DB) checkWritable() error {
+	if db.file == nil {
+		return fmt.Errorf("database closed")
+	}
+	if db.err != nil {
+		return fmt.Errorf("database unusable after earlier error: %w", db.err)
+	}
+	return nil
+}
+
+func (db *DB) compactLocked() error {
+	if err := writeSnapshot(db.path, db.data); err != nil {
+		return fmt.Errorf("error compacting database: %w", err)
+	}
+
+	// db.file now refers to the replaced log, writes to it would be lost
+	file, err := os.OpenFile(db.path, os.O_RDWR, 0)
+	if err != nil {
+		db.err = err
+		return fmt.Errorf("error reopening database after compaction: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		db.err = err
+		return fmt.Errorf("error reopening database after compaction: %w", err)
+	}
+	_ = db.file.Close()
+	db.file = file
+	db.size = info.Size()
+	return nil
+}
+
+// write durably applies ops as one atomic batch.
+func (db *DB) write(ctx context.Context, ops []op) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err := db.checkWritable(); err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	record := encodeRecord(ops)
+	if len(record)-recordHeaderSize > maxRecordSize {
+		return fmt.Errorf("write of %v bytes exceeds maximum of %v bytes", len(record)-recordHeaderSize, maxRecordSize)
+	}
+
+	_, err := db.file.WriteAt(record, db.size)
+	if err == nil {
+		err = db.file.Sync()
+	}
+	if err != nil {
+		// Remove whatever part of the record made it to the file. Otherwise,
+		// a later record would follow a damaged one, and the log couldn't be
+		// opened anymore.
+		if truncErr := db.file.Truncate(db.size); truncErr != nil {
+			db.err = truncErr
+		} else if syncErr := db.file.Sync(); syncErr != nil {
+			db.err = syncErr
+		}
+		return fmt.Errorf("error writing to database: %w", err)
+	}
+	db.size += int64(len(record))
+
+	for _, o := range ops {
+		if old, ok := db.data[o.key]; ok {
+			db.liveSize -= int64(entryOverhead + len(o.key) + len(old))
+		}
+		if o.kind == opPut {
+			db.liveSize += int64(entryOverhead + len(o.key) + len(o.value))
+		}
+	}
+	apply(db.data, ops)
+
+	if db.size > compactionMinSize && db.size > compactionRatio*db.liveSize {
+		// The write itself has succeeded, so a failed compaction is not an
+		// error. We'll retry on the next write.
+		_ = db.compactLocked()
+	}
+	return nil
+}
+
+func (db *DB) read(ctx context.Context, key string) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.file == nil {
+		return nil, false, fmt.Errorf("database closed")
+	}
+	value, ok := db.data[key]
+	return value, ok, nil
+}
+
+// readPrefix returns all entries whose key starts with prefix.
+func (db *DB) readPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.file == nil {
+		return nil, fmt.Errorf("database closed")
+	}
+	result := map[string][]byte{}
+	for key, value := range db.data {
+		if strings.HasPrefix(key, prefix) {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+// ContractConfig doesn't survive a JSON round trip, since ConfigDigest
+// implements TextMarshaler but not TextUnmarshaler.
+type jsonContractConfig struct {
+	ConfigDigest          []byte
+	ConfigCount           uint64
+	Signers               []types.OnchainPublicKey
+	Transmitters          []types.Account
+	F                     uint8
+	OnchainConfig         []byte
+	OffchainConfigVersion uint64
+	OffchainConfig        []byte
+}
+
+func (db *DB) ReadConfig(ctx context.Context) (*types.ContractConfig, error) {
+	raw, ok, err := db.read(ctx, keyConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var jcc jsonContractConfig
+	if err := json.Unmarshal(raw, &jcc); err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	configDigest, err := types.BytesToConfigDigest(jcc.ConfigDigest)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	return &types.ContractConfig{
+		configDigest,
+		jcc.ConfigCount,
+		jcc.Signers,
+		jcc.Transmitters,
+		jcc.F,
+		jcc.OnchainConfig,
+		jcc.OffchainConfigVersion,
+		jcc.OffchainConfig,
+	}, nil
+}
+
+func (db *DB) WriteConfig(ctx context.Context, config types.ContractConfig) error {
+	raw, err := json.Marshal(jsonContractConfig{
+		config.ConfigDigest[:],
+		config.ConfigCount,
+		config.Signers,
+		config.Transmitters,
+		config.F,
+		config.OnchainConfig,
+		config.OffchainConfigVersion,
+		config.OffchainConfig,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding config: %w", err)
+	}
+	return db.write(ctx, []op{{opPut, keyConfig, raw}})
+}
+
+func (db *DB) ReadState(ctx context.Context, configDigest types.ConfigDigest) (*types.PersistentState, error) {
+	raw, ok, err := db.read(ctx, prefixOCR2State+configDigest.Hex())
+	if err != nil || !ok {
+		return nil, err
+	}
+	var state types.PersistentState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("error decoding state: %w", err)
+	}
+	return &state, nil
+}
+
+func (db *DB) WriteState(ctx context.Context, configDigest types.ConfigDigest, state types.PersistentState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+	return db.write(ctx, []op{{opPut, prefixOCR2State + configDigest.Hex(), raw}})
+}
+
+func pendingTransmissionKey(ts types.ReportTimestamp) string {
+	return fmt.Sprintf("%s%s/%08x/%02x", prefixOCR2Pending, ts.ConfigDigest.Hex(), ts.Epoch, ts.Round)
+}
+
+func parsePendingTransmissionKey(key string) (types.ReportTimestamp, error) {
+	parts := strings.Split(strings.TrimPrefix(key, prefixOCR2Pending), "/")
+	if len(parts) != 3 {
+		return types.ReportTimestamp{}, fmt.Errorf("malformed pending transmission key %q", key)
+	}
+	configDigestBytes, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return types.ReportTimestamp{}, fmt.Errorf("malformed pending transmission key %q: %w", key, err)
+	}
+	configDigest, err := types.BytesToConfigDigest(configDigestBytes)
+	if err != nil {
+		return types.ReportTimestamp{}, fmt.Errorf("malformed pending transmission key %q: %w", key, err)
+	}
+	epoch, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return types.ReportTimestamp{}, fmt.Errorf("malformed pending transmission key %q: %w", key, err)
+	}
+	round, err := strconv.ParseUint(parts[2], 16, 8)
+	if err != nil {
+		return types.ReportTimestamp{}, fmt.Errorf("malformed pending transmission key %q: %w", key, err)
+	}
+	return types.ReportTimestamp{configDigest, uint32(epoch), uint8(round)}, nil
+}
+
+func (db *DB) StorePendingTransmission(ctx context.Context, ts types.ReportTimestamp, pt types.PendingTransmission) error {
+	raw, err := json.Marshal(pt)
+	if err != nil {
+		return fmt.Errorf("error encoding pending transmission: %w", err)
+	}
+	return db.write(ctx, []op{{opPut, pendingTransmissionKey(ts), raw}})
+}
+
+func (db *DB) pendingTransmissionsWithPrefix(ctx context.Context, prefix string) (map[string]types.PendingTransmission, error) {
+	raws, err := db.readPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]types.PendingTransmission, len(raws))
+	for key, raw := range raws {
+		var pt types.PendingTransmission
+		if err := json.Unmarshal(raw, &pt); err != nil {
+			return nil, fmt.Errorf("error decoding pending transmission %q: %w", key, err)
+		}
+		result[key] = pt
+	}
+	return result, nil
+}
+
+func (db *DB) PendingTransmissionsWithConfigDigest(ctx context.Context, configDigest types.ConfigDigest) (map[types.ReportTimestamp]types.PendingTransmission, error) {
+	pts, err := db.pendingTransmissionsWithPrefix(ctx, prefixOCR2Pending+configDigest.Hex()+"/")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[types.ReportTimestamp]types.PendingTransmission, len(pts))
+	for key, pt := range pts {
+		ts, err := parsePendingTransmissionKey(key)
+		if err != nil {
+			return nil, err
+		}
+		result[ts] = pt
+	}
+	return result, nil
+}
+
+func (db *DB) DeletePendingTransmission(ctx context.Context, ts types.ReportTimestamp) error {
+	return db.write(ctx, []op{{opDelete, pendingTransmissionKey(ts), nil}})
+}
+
+func (db *DB) DeletePendingTransmissionsOlderThan(ctx context.Context, t time.Time) error {
+	pts, err := db.pendingTransmissionsWithPrefix(ctx, prefixOCR2Pending)
+	if err != nil {
+		return err
+	}
+	var ops []op
+	for key, pt := range pts {
+		if pt.Time.Before(t) {
+			ops = append(ops, op{opDelete, key, nil})
+		}
+	}
+	// If a pending transmission is stored concurrently under one of these
+	// keys, we might delete the new one. The protocol doesn't do that.
+	return db.write(ctx, ops)
+}
+
+func protocolStateKey(configDigest types.ConfigDigest, key string) string {
+	return prefixOCR3State + configDigest.Hex() + "/" + key
+}
+
+func (db *DB) ReadProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string) ([]byte, error) {
+	value, ok, err := db.read(ctx, protocolStateKey(configDigest, key))
+	if err != nil || !ok {
+		return nil, err
+	}
+	return copyBytes(value), nil
+}
+
+func (db *DB) WriteProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string, value []byte) error {
+	if value == nil {
+		return db.write(ctx, []op{{opDelete, protocolStateKey(configDigest, key), nil}})
+	}
+	return db.write(ctx, []op{{opPut, protocolStateKey(configDigest, key), copyBytes(value)}})
+}
+
+func (db *DB) StoreAnnouncement(ctx context.Context, peerID string, ann []byte) error {
+	return db.write(ctx, []op{{opPut, prefixAnnouncement + peerID, copyBytes(ann)}})
+}
+
+func (db *DB) ReadAnnouncements(ctx context.Context, peerIDs []string) (map[string][]byte, error) {
+	result := map[string][]byte{}
+	for _, peerID := range peerIDs {
+		ann, ok, err := db.read(ctx, prefixAnnouncement+peerID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result[peerID] = copyBytes(ann)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package filedb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	nettypes "github.com/smartcontractkit/libocr/networking/types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/dbconformance"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/filedb"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+func open(t *testing.T) *filedb.DB {
+	db, err := filedb.Open(filepath.Join(t.TempDir(), "ocr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("OCR2", func(t *testing.T) {
+		dbconformance.TestOCR2Database(t, func(t *testing.T) types.Database {
+			return open(t)
+		})
+	})
+	t.Run("OCR3", func(t *testing.T) {
+		dbconformance.TestOCR3Database(t, func(t *testing.T) ocr3types.Database {
+			return open(t)
+		})
+	})
+	t.Run("Discoverer", func(t *testing.T) {
+		dbconformance.TestDiscovererDatabase(t, func(t *testing.T) nettypes.DiscovererDatabase {
+			return open(t)
+		})
+	})
+}
@@ -0,0 +1,249 @@
+package filedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The log file starts with fileMagic, followed by records. Each record is
+//
+//	length          uint32, big endian, length of payload
+//	payloadChecksum uint32, big endian, CRC-32C of payload
+//	headerChecksum  uint32, big endian, CRC-32C of the preceding 8 bytes
+//	payload         length bytes
+//
+// and the payload is a sequence of operations
+//
+//	kind     byte, opPut or opDelete
+//	key      uvarint length followed by bytes
+//	value    uvarint length followed by bytes, only for opPut
+//
+// A record is applied all-or-nothing, so a batch of operations written as one
+// record can never be observed partially.
+
+const fileMagic = "OCRKVDB1"
+
+const recordHeaderSize = 12
+
+// Records larger than this are treated as corrupt when reading the log.
+const maxRecordSize = 64 << 20
+
+const (
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type op struct {
+	kind  byte
+	key   string
+	value []byte
+}
+
+func encodeRecord(ops []op) []byte {
+	var payload bytes.Buffer
+	for _, o := range ops {
+		payload.WriteByte(o.kind)
+		payload.Write(binary.AppendUvarint(nil, uint64(len(o.key))))
+		payload.WriteString(o.key)
+		if o.kind == opPut {
+			payload.Write(binary.AppendUvarint(nil, uint64(len(o.value))))
+			payload.Write(o.value)
+		}
+	}
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+payload.Len())
+	binary.BigEndian.PutUint32(record[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload.Bytes(), crcTable))
+	binary.BigEndian.PutUint32(record[8:12], crc32.Checksum(record[0:8], crcTable))
+	return append(record, payload.Bytes()...)
+}
+
+func decodePayload(payload []byte) ([]op, error) {
+	var ops []op
+	r := bytes.NewReader(payload)
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+	for r.Len() > 0 {
+		kind, _ := r.ReadByte()
+		key, err := readBytes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key: %w", err)
+		}
+		switch kind {
+		case opPut:
+			value, err := readBytes()
+			if err != nil {
+				return nil, fmt.Errorf("invalid value: %w", err)
+			}
+			ops = append(ops, op{kind, string(key), value})
+		case opDelete:
+			ops = append(ops, op{kind, string(key), nil})
+		default:
+			return nil, fmt.Errorf("unknown operation %v", kind)
+		}
+	}
+	return ops, nil
+}
+
+func apply(data map[string][]byte, ops []op) {
+	for _, o := range ops {
+		switch o.kind {
+		case opPut:
+			data[o.key] = o.value
+		case opDelete:
+			delete(data, o.key)
+		}
+	}
+}
+
+// replay reads the log in f into a map. It returns the offset just past the
+// last intact record.
+//
+// A torn record at the end of the log, as left behind by a crash during a
+// write, is ignored: the write it belongs to never completed, so nobody can
+// have relied on it. Depending on the file system, a torn record is cut short
+// or padded with zeros. A damaged record followed by further data, on the
+// other hand, cannot result from a crash, and replay errors rather than
+// silently dropping the acknowledged writes after it, which could e.g. roll
+// back the pacemaker's epoch.
+func replay(f *os.File) (map[string][]byte, int64, error) {
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(content) < len(fileMagic) || string(content[:len(fileMagic)]) != fileMagic {
+		return nil, 0, fmt.Errorf("%v is not a database file", f.Name())
+	}
+
+	data := map[string][]byte{}
+	offset := len(fileMagic)
+	for offset < len(content) {
+		rest := content[offset:]
+		if len(rest) < recordHeaderSize {
+			break // torn header
+		}
+		if crc32.Checksum(rest[0:8], crcTable) != binary.BigEndian.Uint32(rest[8:12]) {
+			if allZero(rest) {
+				break // zero-padded torn header
+			}
+			return nil, 0, fmt.Errorf("record at offset %v of %v has a damaged header", offset, f.Name())
+		}
+		length := binary.BigEndian.Uint32(rest[0:4])
+		if length > maxRecordSize {
+			return nil, 0, fmt.Errorf("record at offset %v of %v has invalid length %v", offset, f.Name(), length)
+		}
+		end := recordHeaderSize + int(length)
+		if len(rest) < end {
+			break // torn payload
+		}
+		payload := rest[recordHeaderSize:end]
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(rest[4:8]) {
+			if allZero(rest[end:]) {
+				break // torn or zero-padded payload
+			}
+			return nil, 0, fmt.Errorf("record at offset %v of %v is damaged", offset, f.Name())
+		}
+		ops, err := decodePayload(payload)
+		if err != nil {
+			return nil, 0, fmt.Errorf("record at offset %v of %v is malformed: %w", offset, f.Name(), err)
+		}
+		apply(data, ops)
+		offset += end
+	}
+	return data, int64(offset), nil
+}
+
+func allZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Snapshots are split into records of roughly this size.
+const snapshotRecordSize = 1 << 20
+
+// writeSnapshot atomically replaces the file at path with a log that puts
+// every entry of data.
+func writeSnapshot(path string, data map[string][]byte) error {
+	var records [][]op
+	{
+		var ops []op
+		size := 0
+		for key, value := range data {
+			ops = append(ops, op{opPut, key, value})
+			size += len(key) + len(value)
+			if size >= snapshotRecordSize {
+				records = append(records, ops)
+				ops, size = nil, 0
+			}
+		}
+		if len(ops) != 0 {
+			records = append(records, ops)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	cleanup := func(err error) error {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := tmp.Write([]byte(fileMagic)); err != nil {
+		return cleanup(err)
+	}
+	for _, ops := range records {
+		if _, err := tmp.Write(encodeRecord(ops)); err != nil {
+			return cleanup(err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes a preceding creation or rename of a file in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package ocr3config
+
+import (
+	"fmt"
+)
+
+// Compression determines how the query, observation and outcome payloads of
+// protocol messages are compressed on the wire. Signatures always cover the
+// uncompressed payloads, and the ReportingPlugin only ever sees uncompressed
+// payloads.
+type Compression uint32
+
+const (
+	CompressionNone Compression = iota
+	CompressionSnappy
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionZstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("Compression(%d)", uint32(c))
+}
+
+func (c Compression) IsValid() bool {
+	switch c {
+	case CompressionNone, CompressionSnappy, CompressionZstd:
+		return true
+	}
+	return false
+}
+
+// MaxCompressionOverhead is the largest number of bytes by which a payload
+// may grow on the wire when compression is enabled, including any growth of
+// the payload's length prefix.
+const MaxCompressionOverhead = 2
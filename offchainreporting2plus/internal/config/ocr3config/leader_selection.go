@@ -0,0 +1,57 @@
+package ocr3config
+
+import (
+	"fmt"
+)
+
+// MaxLeaderWeight is the largest weight an oracle may have in
+// LeaderSelectionConfig.Weights.
+const MaxLeaderWeight = 100
+
+// LeaderSelectionConfig determines how the pacemaker picks the leader of each
+// epoch. The zero value picks every oracle equally often, exactly like earlier
+// versions of the protocol did.
+//
+// Leader selection only depends on the config and the epoch number, so that
+// all oracles agree on the leader of every epoch.
+//
+// There is deliberately no automatic demotion of leaders that time out.
+// Demotion would have to be computed from a history of failed epochs that
+// all honest oracles agree on, and the protocol doesn't reach agreement on
+// any such history: NewEpochWish messages are only seen by some oracles, and
+// even the epoch in which a seqNr was committed can differ between oracles.
+// Oracles demoting based on their own views would disagree on leaders, which
+// costs the very DeltaProgress that demotion is meant to save. Instead,
+// lower the Weights of slow oracles, or set them to 0, with a config change.
+type LeaderSelectionConfig struct {
+	// Weights[i] is the relative frequency with which oracle i is picked as
+	// leader. Oracles with weight 0 are never picked, e.g. because they are
+	// transmit-only or read-only nodes. If Weights is empty, every oracle has
+	// weight 1. Otherwise, Weights must have one entry per oracle, and more
+	// than F oracles must have positive weight, so that some honest oracle
+	// is always eligible.
+	Weights []int
+}
+
+// CheckLeaderSelectionConfig checks cfg for a protocol instance with n
+// oracles, f of which may be faulty.
+func CheckLeaderSelectionConfig(cfg LeaderSelectionConfig, n int, f int) error {
+	if len(cfg.Weights) != 0 {
+		if len(cfg.Weights) != n {
+			return fmt.Errorf("LeaderSelection.Weights must be empty or have one entry per oracle: %v ≠ %v", len(cfg.Weights), n)
+		}
+		eligible := 0
+		for i, w := range cfg.Weights {
+			if !(0 <= w && w <= MaxLeaderWeight) {
+				return fmt.Errorf("LeaderSelection.Weights[%v] (%v) must be between 0 and %v", i, w, MaxLeaderWeight)
+			}
+			if w > 0 {
+				eligible++
+			}
+		}
+		if !(eligible > f) {
+			return fmt.Errorf("LeaderSelection.Weights must give positive weight to more than F (%v) oracles, but only %v have positive weight", f, eligible)
+		}
+	}
+	return nil
+}
@@ -42,6 +42,8 @@ type OffchainConfigProto struct {
 	MaxDurationShouldAcceptAttestedReportNanoseconds   uint64                        `protobuf:"varint,37,opt,name=max_duration_should_accept_attested_report_nanoseconds,json=maxDurationShouldAcceptAttestedReportNanoseconds,proto3" json:"max_duration_should_accept_attested_report_nanoseconds,omitempty"`
 	MaxDurationShouldTransmitAcceptedReportNanoseconds uint64                        `protobuf:"varint,38,opt,name=max_duration_should_transmit_accepted_report_nanoseconds,json=maxDurationShouldTransmitAcceptedReportNanoseconds,proto3" json:"max_duration_should_transmit_accepted_report_nanoseconds,omitempty"`
 	SharedSecretEncryptions                            *SharedSecretEncryptionsProto `protobuf:"bytes,39,opt,name=shared_secret_encryptions,json=sharedSecretEncryptions,proto3" json:"shared_secret_encryptions,omitempty"`
+	LeaderSelection                                    *LeaderSelectionConfigProto   `protobuf:"bytes,42,opt,name=leader_selection,json=leaderSelection,proto3" json:"leader_selection,omitempty"`
+	Compression                                        uint32                        `protobuf:"varint,43,opt,name=compression,proto3" json:"compression,omitempty"`
 }
 
 func (x *OffchainConfigProto) Reset() {
@@ -195,6 +197,20 @@ func (x *OffchainConfigProto) GetSharedSecretEncryptions() *SharedSecretEncrypti
 	return nil
 }
 
+func (x *OffchainConfigProto) GetLeaderSelection() *LeaderSelectionConfigProto {
+	if x != nil {
+		return x.LeaderSelection
+	}
+	return nil
+}
+
+func (x *OffchainConfigProto) GetCompression() uint32 {
+	if x != nil {
+		return x.Compression
+	}
+	return 0
+}
+
 type SharedSecretEncryptionsProto struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -258,6 +274,53 @@ func (x *SharedSecretEncryptionsProto) GetEncryptions() [][]byte {
 	return nil
 }
 
+type LeaderSelectionConfigProto struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Weights []uint32 `protobuf:"varint,1,rep,packed,name=weights,proto3" json:"weights,omitempty"`
+}
+
+func (x *LeaderSelectionConfigProto) Reset() {
+	*x = LeaderSelectionConfigProto{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_offchainreporting3_offchain_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LeaderSelectionConfigProto) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderSelectionConfigProto) ProtoMessage() {}
+
+func (x *LeaderSelectionConfigProto) ProtoReflect() protoreflect.Message {
+	mi := &file_offchainreporting3_offchain_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderSelectionConfigProto.ProtoReflect.Descriptor instead.
+func (*LeaderSelectionConfigProto) Descriptor() ([]byte, []int) {
+	return file_offchainreporting3_offchain_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LeaderSelectionConfigProto) GetWeights() []uint32 {
+	if x != nil {
+		return x.Weights
+	}
+	return nil
+}
+
 var File_offchainreporting3_offchain_config_proto protoreflect.FileDescriptor
 
 var file_offchainreporting3_offchain_config_proto_rawDesc = []byte{
@@ -265,7 +328,7 @@ var file_offchainreporting3_offchain_config_proto_rawDesc = []byte{
 	0x69, 0x6e, 0x67, 0x33, 0x5f, 0x6f, 0x66, 0x66, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x63, 0x6f,
 	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x19, 0x6f, 0x66, 0x66, 0x63,
 	0x68, 0x61, 0x69, 0x6e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x33, 0x5f, 0x63,
-	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0xf4, 0x08, 0x0a, 0x13, 0x4f, 0x66, 0x66, 0x63, 0x68, 0x61,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0xf8, 0x09, 0x0a, 0x13, 0x4f, 0x66, 0x66, 0x63, 0x68, 0x61,
 	0x69, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x3c, 0x0a,
 	0x1a, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x5f,
 	0x6e, 0x61, 0x6e, 0x6f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x19, 0x20, 0x01, 0x28,
@@ -336,19 +399,31 @@ var file_offchainreporting3_offchain_config_proto_rawDesc = []byte{
 	0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f,
 	0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x52, 0x17, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53,
 	0x65, 0x63, 0x72, 0x65, 0x74, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73,
-	0x4a, 0x04, 0x08, 0x01, 0x10, 0x11, 0x4a, 0x04, 0x08, 0x11, 0x10, 0x19, 0x22, 0x9c, 0x01, 0x0a,
-	0x1c, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x45, 0x6e, 0x63,
-	0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x2e, 0x0a,
-	0x12, 0x64, 0x69, 0x66, 0x66, 0x69, 0x65, 0x48, 0x65, 0x6c, 0x6c, 0x6d, 0x61, 0x6e, 0x50, 0x6f,
-	0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x64, 0x69, 0x66, 0x66, 0x69,
-	0x65, 0x48, 0x65, 0x6c, 0x6c, 0x6d, 0x61, 0x6e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x2a, 0x0a,
-	0x10, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x48, 0x61, 0x73,
-	0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x10, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53,
-	0x65, 0x63, 0x72, 0x65, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x6e, 0x63,
-	0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0b,
-	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x0e, 0x5a, 0x0c, 0x2e,
-	0x3b, 0x6f, 0x63, 0x72, 0x33, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x12, 0x60, 0x0a, 0x10, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x35, 0x2e, 0x6f, 0x66, 0x66,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x33, 0x5f,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x53, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x52, 0x0f, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x11, 0x4a, 0x04, 0x08, 0x11, 0x10, 0x19,
+	0x22, 0x9c, 0x01, 0x0a, 0x1c, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x2e, 0x0a, 0x12, 0x64, 0x69, 0x66, 0x66, 0x69, 0x65, 0x48, 0x65, 0x6c, 0x6c, 0x6d,
+	0x61, 0x6e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x64,
+	0x69, 0x66, 0x66, 0x69, 0x65, 0x48, 0x65, 0x6c, 0x6c, 0x6d, 0x61, 0x6e, 0x50, 0x6f, 0x69, 0x6e,
+	0x74, 0x12, 0x2a, 0x0a, 0x10, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x48, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x10, 0x73, 0x68, 0x61,
+	0x72, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a,
+	0x0b, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0c, 0x52, 0x0b, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22,
+	0x3c, 0x0a, 0x1a, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x18, 0x0a,
+	0x07, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x07,
+	0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x04, 0x42, 0x0e, 0x5a,
+	0x0c, 0x2e, 0x3b, 0x6f, 0x63, 0x72, 0x33, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -363,18 +438,20 @@ func file_offchainreporting3_offchain_config_proto_rawDescGZIP() []byte {
 	return file_offchainreporting3_offchain_config_proto_rawDescData
 }
 
-var file_offchainreporting3_offchain_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_offchainreporting3_offchain_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_offchainreporting3_offchain_config_proto_goTypes = []interface{}{
 	(*OffchainConfigProto)(nil),          // 0: offchainreporting3_config.OffchainConfigProto
 	(*SharedSecretEncryptionsProto)(nil), // 1: offchainreporting3_config.SharedSecretEncryptionsProto
+	(*LeaderSelectionConfigProto)(nil),   // 2: offchainreporting3_config.LeaderSelectionConfigProto
 }
 var file_offchainreporting3_offchain_config_proto_depIdxs = []int32{
 	1, // 0: offchainreporting3_config.OffchainConfigProto.shared_secret_encryptions:type_name -> offchainreporting3_config.SharedSecretEncryptionsProto
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2, // 1: offchainreporting3_config.OffchainConfigProto.leader_selection:type_name -> offchainreporting3_config.LeaderSelectionConfigProto
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_offchainreporting3_offchain_config_proto_init() }
@@ -407,6 +484,18 @@ func file_offchainreporting3_offchain_config_proto_init() {
 				return nil
 			}
 		}
+		file_offchainreporting3_offchain_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LeaderSelectionConfigProto); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -414,7 +503,7 @@ func file_offchainreporting3_offchain_config_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_offchainreporting3_offchain_config_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -82,6 +82,13 @@ type PublicConfig struct {
 	MaxDurationShouldAcceptAttestedReport   time.Duration
 	MaxDurationShouldTransmitAcceptedReport time.Duration
 
+	// LeaderSelection determines how the leader of each epoch is picked.
+	LeaderSelection LeaderSelectionConfig
+
+	// Compression determines how payloads of protocol messages are compressed
+	// on the wire.
+	Compression Compression
+
 	// The maximum number of oracles that are assumed to be faulty while the
 	// protocol can retain liveness and safety. Unless you really know what
 	// you’re doing, be sure to set this to floor((n-1)/3) where n is the total
@@ -170,6 +177,8 @@ func publicConfigFromContractConfig(skipResourceExhaustionChecks bool, change ty
 		oc.MaxDurationObservation,
 		oc.MaxDurationShouldAcceptAttestedReport,
 		oc.MaxDurationShouldTransmitAcceptedReport,
+		oc.LeaderSelection,
+		oc.Compression,
 
 		int(change.F),
 		change.OnchainConfig,
@@ -358,6 +367,14 @@ func checkPublicConfigParameters(cfg PublicConfig) error {
 		}
 	}
 
+	if err := CheckLeaderSelectionConfig(cfg.LeaderSelection, cfg.N(), cfg.F); err != nil {
+		return err
+	}
+
+	if !cfg.Compression.IsValid() {
+		return fmt.Errorf("unknown Compression (%v)", cfg.Compression)
+	}
+
 	return nil
 }
 
@@ -36,6 +36,8 @@ type offchainConfig struct {
 	MaxDurationShouldAcceptAttestedReport   time.Duration
 	MaxDurationShouldTransmitAcceptedReport time.Duration
 	SharedSecretEncryptions                 config.SharedSecretEncryptions
+	LeaderSelection                         LeaderSelectionConfig
+	Compression                             Compression
 }
 
 func checkSize(serializedOffchainConfig []byte) error {
@@ -100,6 +102,8 @@ func deprotoOffchainConfig(
 		return offchainConfig{}, fmt.Errorf("could not unmarshal shared protobuf: %w", err)
 	}
 
+	leaderSelection := deprotoLeaderSelectionConfig(offchainConfigProto.GetLeaderSelection())
+
 	return offchainConfig{
 		time.Duration(offchainConfigProto.GetDeltaProgressNanoseconds()),
 		time.Duration(offchainConfigProto.GetDeltaResendNanoseconds()),
@@ -118,9 +122,21 @@ func deprotoOffchainConfig(
 		time.Duration(offchainConfigProto.GetMaxDurationShouldAcceptAttestedReportNanoseconds()),
 		time.Duration(offchainConfigProto.GetMaxDurationShouldTransmitAcceptedReportNanoseconds()),
 		sharedSecretEncryptions,
+		leaderSelection,
+		Compression(offchainConfigProto.GetCompression()),
 	}, nil
 }
 
+func deprotoLeaderSelectionConfig(leaderSelectionProto *LeaderSelectionConfigProto) LeaderSelectionConfig {
+	var weights []int
+	for _, w := range leaderSelectionProto.GetWeights() {
+		weights = append(weights, int(w))
+	}
+	return LeaderSelectionConfig{
+		weights,
+	}
+}
+
 func deprotoSharedSecretEncryptions(sharedSecretEncryptionsProto *SharedSecretEncryptionsProto) (config.SharedSecretEncryptions, error) {
 	var diffieHellmanPoint [curve25519.PointSize]byte
 	if len(diffieHellmanPoint) != len(sharedSecretEncryptionsProto.GetDiffieHellmanPoint()) {
@@ -162,6 +178,7 @@ func enprotoOffchainConfig(o offchainConfig) OffchainConfigProto {
 		offchainPublicKeys = append(offchainPublicKeys, k[:])
 	}
 	sharedSecretEncryptions := enprotoSharedSecretEncryptions(o.SharedSecretEncryptions)
+	leaderSelection := enprotoLeaderSelectionConfig(o.LeaderSelection)
 	return OffchainConfigProto{
 		// zero-initialize protobuf built-ins
 		protoimpl.MessageState{},
@@ -185,6 +202,28 @@ func enprotoOffchainConfig(o offchainConfig) OffchainConfigProto {
 		uint64(o.MaxDurationShouldAcceptAttestedReport),
 		uint64(o.MaxDurationShouldTransmitAcceptedReport),
 		&sharedSecretEncryptions,
+		leaderSelection,
+		uint32(o.Compression),
+	}
+}
+
+// enprotoLeaderSelectionConfig returns nil for the zero value, so that configs
+// that don't use leader selection serialize exactly like before.
+func enprotoLeaderSelectionConfig(c LeaderSelectionConfig) *LeaderSelectionConfigProto {
+	if len(c.Weights) == 0 {
+		return nil
+	}
+	weights := make([]uint32, len(c.Weights))
+	for i, w := range c.Weights {
+		weights[i] = uint32(w)
+	}
+	return &LeaderSelectionConfigProto{
+		// zero-initialize protobuf built-ins
+		protoimpl.MessageState{},
+		0,
+		nil,
+		// fields
+		weights,
 	}
 }
 
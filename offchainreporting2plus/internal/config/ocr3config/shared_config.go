@@ -105,6 +105,45 @@ func SharedConfigFromContractConfig[RI any](
 
 }
 
+// SharedConfigFromContractConfigWithOffchainKeyring is like
+// SharedConfigFromContractConfig, but only requires the OffchainKeyring of one
+// of the oracles. It's meant for tooling that operates on existing configs,
+// not for the oracles themselves.
+func SharedConfigFromContractConfigWithOffchainKeyring(
+	skipResourceExhaustionChecks bool,
+	change types.ContractConfig,
+	offchainKeyring types.OffchainKeyring,
+) (SharedConfig, commontypes.OracleID, error) {
+	publicConfig, encSharedSecret, err := publicConfigFromContractConfig(skipResourceExhaustionChecks, change)
+	if err != nil {
+		return SharedConfig{}, 0, err
+	}
+
+	offchainPublicKey := offchainKeyring.OffchainPublicKey()
+	for i, identity := range publicConfig.OracleIdentities {
+		if identity.OffchainPublicKey != offchainPublicKey {
+			continue
+		}
+		oracleID := commontypes.OracleID(i)
+		x, err := encSharedSecret.Decrypt(oracleID, offchainKeyring)
+		if err != nil {
+			return SharedConfig{}, 0, fmt.Errorf("could not decrypt shared secret: %w", err)
+		}
+		return SharedConfig{
+			publicConfig,
+			x,
+		}, oracleID, nil
+	}
+	return SharedConfig{}, 0, fmt.Errorf("could not find OffchainPublicKey %x in publicConfig", offchainPublicKey)
+}
+
+// SharedSecretEncryptionsFromContractConfig returns the encryptions of the
+// shared secret contained in change.
+func SharedSecretEncryptionsFromContractConfig(skipResourceExhaustionChecks bool, change types.ContractConfig) (config.SharedSecretEncryptions, error) {
+	_, encSharedSecret, err := publicConfigFromContractConfig(skipResourceExhaustionChecks, change)
+	return encSharedSecret, err
+}
+
 func XXXContractSetConfigArgsFromSharedConfig(
 	c SharedConfig,
 	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
@@ -150,6 +189,8 @@ func XXXContractSetConfigArgsFromSharedConfig(
 			c.SharedSecret,
 			cryptorand.Reader,
 		),
+		c.LeaderSelection,
+		c.Compression,
 	}).serialize()
 	err = nil
 	return
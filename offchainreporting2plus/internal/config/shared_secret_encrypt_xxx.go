@@ -2,6 +2,7 @@ package config
 
 import (
 	"crypto/aes"
+	"crypto/rand"
 	"fmt"
 	"io"
 
@@ -11,6 +12,19 @@ import (
 	"golang.org/x/crypto/curve25519"
 )
 
+// CheckConfigEncryptionPublicKey returns an error if pk is not usable for
+// encrypting the shared secret. XXXEncryptSharedSecret panics on such keys.
+func CheckConfigEncryptionPublicKey(pk types.ConfigEncryptionPublicKey) error {
+	var sk [32]byte
+	if _, err := io.ReadFull(rand.Reader, sk[:]); err != nil {
+		return fmt.Errorf("could not produce entropy: %w", err)
+	}
+	if _, err := curve25519.X25519(sk[:], pk[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
 // XXXEncryptSharedSecretInternal constructs a SharedSecretEncryptions from
 // a set of SharedSecretEncryptionPublicKeys, the sharedSecret, and an
 // ephemeral secret key sk
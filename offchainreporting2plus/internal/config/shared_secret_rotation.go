@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// CheckConfigEncryptionPublicKeys returns an error unless keys contains a
+// usable ConfigEncryptionPublicKey for each of the n oracles of a config.
+func CheckConfigEncryptionPublicKeys(keys []types.ConfigEncryptionPublicKey, n int) error {
+	if len(keys) != n {
+		return fmt.Errorf("got %v ConfigEncryptionPublicKeys, but config has %v oracles", len(keys), n)
+	}
+	for i, pk := range keys {
+		if err := CheckConfigEncryptionPublicKey(pk); err != nil {
+			return fmt.Errorf("oracle %v has invalid ConfigEncryptionPublicKey: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// NewSharedSecret draws a shared secret from rand.
+func NewSharedSecret(rand io.Reader) (*[SharedSecretSize]byte, error) {
+	var sharedSecret [SharedSecretSize]byte
+	if _, err := io.ReadFull(rand, sharedSecret[:]); err != nil {
+		return nil, fmt.Errorf("could not produce entropy for shared secret: %w", err)
+	}
+	return &sharedSecret, nil
+}
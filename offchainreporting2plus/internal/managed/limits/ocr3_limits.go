@@ -56,14 +56,26 @@ func ocr3limits(cfg ocr3config.PublicConfig, pluginLimits ocr3types.ReportingPlu
 	const sigOverhead = 10
 	const overhead = 256
 
-	maxLenCertifiedPrepareOrCommit := add(mul(ed25519.SignatureSize+sigOverhead, cfg.ByzQuorumSize()), pluginLimits.MaxOutcomeLength, overhead)
+	// With compression enabled, queries, observations, and outcomes may be
+	// slightly longer on the wire than in memory. (Reports aren't sent over
+	// the wire, they are derived from outcomes.)
+	maxQueryLength := pluginLimits.MaxQueryLength
+	maxObservationLength := pluginLimits.MaxObservationLength
+	maxOutcomeLength := pluginLimits.MaxOutcomeLength
+	if cfg.Compression != ocr3config.CompressionNone {
+		maxQueryLength = add(maxQueryLength, ocr3config.MaxCompressionOverhead)
+		maxObservationLength = add(maxObservationLength, ocr3config.MaxCompressionOverhead)
+		maxOutcomeLength = add(maxOutcomeLength, ocr3config.MaxCompressionOverhead)
+	}
+
+	maxLenCertifiedPrepareOrCommit := add(mul(ed25519.SignatureSize+sigOverhead, cfg.ByzQuorumSize()), maxOutcomeLength, overhead)
 
 	maxLenMsgNewEpoch := overhead
 	maxLenMsgEpochStartRequest := add(maxLenCertifiedPrepareOrCommit, overhead)
 	maxLenMsgEpochStart := add(maxLenCertifiedPrepareOrCommit, mul(ed25519.SignatureSize+sigOverhead, cfg.ByzQuorumSize()), overhead)
-	maxLenMsgRoundStart := add(pluginLimits.MaxQueryLength, overhead)
-	maxLenMsgObservation := add(pluginLimits.MaxObservationLength, overhead)
-	maxLenMsgProposal := add(mul(add(pluginLimits.MaxObservationLength, ed25519.SignatureSize+sigOverhead), cfg.N()), overhead)
+	maxLenMsgRoundStart := add(maxQueryLength, overhead)
+	maxLenMsgObservation := add(maxObservationLength, overhead)
+	maxLenMsgProposal := add(mul(add(maxObservationLength, ed25519.SignatureSize+sigOverhead), cfg.N()), overhead)
 	maxLenMsgPrepare := overhead
 	maxLenMsgCommit := overhead
 	maxLenMsgReportSignatures := add(mul(add(maxSigLen, sigOverhead), pluginLimits.MaxReportCount), overhead)
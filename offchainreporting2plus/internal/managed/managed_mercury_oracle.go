@@ -2,6 +2,7 @@ package managed
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -11,6 +12,7 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/managed/limits"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/mercuryshim"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
@@ -40,6 +42,7 @@ func RunManagedMercuryOracle(
 	offchainKeyring types.OffchainKeyring,
 	onchainKeyring types.OnchainKeyring,
 	mercuryPluginFactory ocr3types.MercuryPluginFactory,
+	statusTracker *protocol.StatusTracker,
 ) {
 	subs := subprocesses.Subprocesses{}
 	defer subs.Wait()
@@ -177,6 +180,7 @@ func RunManagedMercuryOracle(
 				reportingPluginLimits,
 				sharedConfig.N(),
 				sharedConfig.F,
+				sharedConfig.Compression,
 			)
 			if err := netEndpoint.Start(); err != nil {
 				logger.Error("ManagedMercuryOracle: error during netEndpoint.Start()", commontypes.LogFields{
@@ -191,6 +195,8 @@ func RunManagedMercuryOracle(
 				"ManagedMercuryOracle: error during netEndpoint.Close()",
 			)
 
+			defer trackPeerStatus(statusTracker, binNetEndpoint)()
+
 			reportingPluginConfig := ocr3types.ReportingPluginConfig{
 				sharedConfig.ConfigDigest,
 				oid,
@@ -212,18 +218,24 @@ func RunManagedMercuryOracle(
 
 			protocol.RunOracle[mercuryshim.MercuryReportInfo](
 				ctx,
+				clock.Real(),
 				sharedConfig,
 				mercuryshim.NewMercuryOCR3ContractTransmitter(contractTransmitter),
 				&shim.SerializingOCR3Database{database},
 				oid,
+				nil,
 				localConfig,
 				childLogger,
 				registerer,
 				netEndpoint,
 				offchainKeyring,
 				ocr3OnchainKeyring,
+				rand.Reader,
 				shim.LimitCheckOCR3ReportingPlugin[mercuryshim.MercuryReportInfo]{reportingPlugin, reportingPluginLimits},
+				statusTracker,
 				shim.MakeOCR3TelemetrySender(chTelemetrySend, childLogger),
+				nil,
+				nil,
 			)
 		},
 		localConfig,
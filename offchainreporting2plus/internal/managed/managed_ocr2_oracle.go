@@ -15,6 +15,7 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 )
 
@@ -38,6 +39,7 @@ func RunManagedOCR2Oracle(
 	offchainKeyring types.OffchainKeyring,
 	onchainKeyring types.OnchainKeyring,
 	reportingPluginFactory types.ReportingPluginFactory,
+	tracer trace.Tracer,
 ) {
 	subs := subprocesses.Subprocesses{}
 	defer subs.Wait()
@@ -224,6 +226,7 @@ func RunManagedOCR2Oracle(
 				shim.LimitCheckReportingPlugin{reportingPlugin, reportingPluginInfo.Limits},
 				reportQuorum,
 				shim.MakeOCR2TelemetrySender(chTelemetrySend, childLogger),
+				tracer,
 			)
 		},
 		localConfig,
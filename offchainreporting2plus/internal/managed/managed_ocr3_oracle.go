@@ -2,6 +2,7 @@ package managed
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,12 +11,14 @@ import (
 	"github.com/smartcontractkit/libocr/internal/metricshelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/managed/limits"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 )
 
@@ -30,6 +33,7 @@ func RunManagedOCR3Oracle[RI any](
 	configTracker types.ContractConfigTracker,
 	contractTransmitter ocr3types.ContractTransmitter[RI],
 	database ocr3types.Database,
+	journal ocr3types.Journal[RI],
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
 	metricsRegisterer prometheus.Registerer,
@@ -39,6 +43,9 @@ func RunManagedOCR3Oracle[RI any](
 	offchainKeyring types.OffchainKeyring,
 	onchainKeyring ocr3types.OnchainKeyring[RI],
 	reportingPluginFactory ocr3types.ReportingPluginFactory[RI],
+	statusTracker *protocol.StatusTracker,
+	tracer trace.Tracer,
+	transmissionOutboxReportInfoCodec ocr3types.ReportInfoCodec[RI],
 ) {
 	subs := subprocesses.Subprocesses{}
 	defer subs.Wait()
@@ -177,6 +184,7 @@ func RunManagedOCR3Oracle[RI any](
 				reportingPluginInfo.Limits,
 				sharedConfig.N(),
 				sharedConfig.F,
+				sharedConfig.Compression,
 			)
 			if err := netEndpoint.Start(); err != nil {
 				logger.Error("ManagedOCR3Oracle: error during netEndpoint.Start()", commontypes.LogFields{
@@ -191,20 +199,48 @@ func RunManagedOCR3Oracle[RI any](
 				"ManagedOCR3Oracle: error during netEndpoint.Close()",
 			)
 
+			defer trackPeerStatus(statusTracker, binNetEndpoint)()
+
+			var protocolJournal protocol.Journal[RI]
+			if journal != nil {
+				protocolJournal = shim.NewSerializingOCR3Journal[RI](sharedConfig.ConfigDigest, journal, childLogger)
+			}
+
+			var transmissionOutbox protocol.TransmissionOutbox[RI]
+			if transmissionOutboxReportInfoCodec != nil {
+				outbox := &shim.SerializingOCR3TransmissionOutbox[RI]{database, transmissionOutboxReportInfoCodec}
+				func() {
+					ctx, cancel := context.WithTimeout(ctx, localConfig.DatabaseTimeout)
+					defer cancel()
+					if err := outbox.DeleteStalePendingTransmissions(ctx, sharedConfig.ConfigDigest); err != nil {
+						logger.ErrorIfNotCanceled("ManagedOCR3Oracle: error deleting pending transmissions of old configs", ctx, commontypes.LogFields{
+							"error": err,
+						})
+					}
+				}()
+				transmissionOutbox = outbox
+			}
+
 			protocol.RunOracle[RI](
 				ctx,
+				clock.Real(),
 				sharedConfig,
 				contractTransmitter,
 				&shim.SerializingOCR3Database{database},
 				oid,
+				protocolJournal,
 				localConfig,
 				childLogger,
 				registerer,
 				netEndpoint,
 				offchainKeyring,
 				onchainKeyring,
+				rand.Reader,
 				shim.LimitCheckOCR3ReportingPlugin[RI]{reportingPlugin, reportingPluginInfo.Limits},
+				statusTracker,
 				shim.MakeOCR3TelemetrySender(chTelemetrySend, childLogger),
+				tracer,
+				transmissionOutbox,
 			)
 		},
 		localConfig,
@@ -213,6 +249,19 @@ func RunManagedOCR3Oracle[RI any](
 	)
 }
 
+// trackPeerStatus makes statusTracker report the status of binNetEndpoint's
+// streams, if binNetEndpoint supports it. The returned function undoes this.
+func trackPeerStatus(statusTracker *protocol.StatusTracker, binNetEndpoint commontypes.BinaryNetworkEndpoint) func() {
+	reporter, ok := binNetEndpoint.(types.PeerStatusReporter)
+	if !ok {
+		return func() {}
+	}
+	statusTracker.SetPeerStatusSource(reporter.PeerStatuses)
+	return func() {
+		statusTracker.SetPeerStatusSource(nil)
+	}
+}
+
 func validateOCR3ReportingPluginLimits(limits ocr3types.ReportingPluginLimits) error {
 	var err error
 	if !(0 <= limits.MaxQueryLength && limits.MaxQueryLength <= ocr3types.MaxMaxQueryLength) {
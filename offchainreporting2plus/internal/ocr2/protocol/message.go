@@ -3,6 +3,7 @@ package protocol
 import (
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // EventToPacemaker is the interface used to pass in-process events to the
@@ -309,6 +310,8 @@ func (msg MessageFinalEcho) processReportFinalization(repfin *reportFinalization
 
 type EventFinal struct {
 	MessageFinal
+	// SpanContext of the round that produced MessageFinal
+	SpanContext trace.SpanContext
 }
 
 var _ EventToReportFinalization = EventFinal{} // implements EventToReportFinalization
@@ -325,6 +328,9 @@ type EventTransmit struct {
 	Round          uint8
 	H              [32]byte
 	AttestedReport AttestedReportMany
+	// SpanContext of the round that produced the report, invalid if we
+	// didn't participate in that round
+	SpanContext trace.SpanContext
 }
 
 var _ EventToTransmission = (*EventTransmit)(nil) // implements EventToTransmission
@@ -1,11 +1,17 @@
 package protocol
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/metricshelper"
 )
 
+// Buckets for histograms measuring durations, from 1ms to ~33s
+var durationBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
 type pacemakerMetrics struct {
 	registerer prometheus.Registerer
 	epoch      prometheus.Gauge
@@ -37,3 +43,133 @@ func (pm *pacemakerMetrics) Close() {
 	pm.registerer.Unregister(pm.epoch)
 	pm.registerer.Unregister(pm.leader)
 }
+
+// Several subprotocols call the ReportingPlugin and verify signatures. Each of
+// them registers its own collectors for these metrics, distinguished by the
+// "subprotocol" label.
+
+func newReportingPluginDurationMetric(registerer prometheus.Registerer,
+	logger commontypes.Logger, subprotocol string) *prometheus.HistogramVec {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "ocr2_reporting_plugin_duration_seconds",
+		Help:        "The duration of calls to the ReportingPlugin",
+		ConstLabels: prometheus.Labels{"subprotocol": subprotocol},
+		Buckets:     durationBuckets,
+	}, []string{"function", "success"})
+	metricshelper.RegisterOrLogError(logger, registerer, duration, "ocr2_reporting_plugin_duration_seconds")
+	return duration
+}
+
+func newSignatureVerificationFailuresMetric(registerer prometheus.Registerer,
+	logger commontypes.Logger, subprotocol string) *prometheus.CounterVec {
+	failures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "ocr2_signature_verification_failures_total",
+		Help:        "The total number of messages dropped because they carried invalid signatures, by sender",
+		ConstLabels: prometheus.Labels{"subprotocol": subprotocol},
+	}, []string{"message", "sender"})
+	metricshelper.RegisterOrLogError(logger, registerer, failures, "ocr2_signature_verification_failures_total")
+	return failures
+}
+
+func observeReportingPluginDuration(duration *prometheus.HistogramVec, function string, start time.Time, success bool) {
+	duration.WithLabelValues(function, strconv.FormatBool(success)).Observe(time.Since(start).Seconds())
+}
+
+func incSignatureVerificationFailures(failures *prometheus.CounterVec, message string, sender commontypes.OracleID) {
+	failures.WithLabelValues(message, strconv.Itoa(int(sender))).Inc()
+}
+
+// reportGenerationMetrics are owned by the pacemaker, since it starts a new
+// report generation instance for every epoch.
+type reportGenerationMetrics struct {
+	registerer                    prometheus.Registerer
+	roundDuration                 prometheus.Histogram
+	observationsPerRound          prometheus.Histogram
+	signatureVerificationFailures *prometheus.CounterVec
+	reportingPluginDuration       *prometheus.HistogramVec
+}
+
+func newReportGenerationMetrics(registerer prometheus.Registerer,
+	logger commontypes.Logger) reportGenerationMetrics {
+	roundDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr2_round_duration_seconds",
+		Help:    "The time from receiving a MessageObserveReq to completing the round",
+		Buckets: durationBuckets,
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, roundDuration, "ocr2_round_duration_seconds")
+
+	observationsPerRound := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr2_observations_per_round",
+		Help:    "The number of observations contained in accepted MessageReportReqs",
+		Buckets: prometheus.LinearBuckets(1, 3, 11),
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, observationsPerRound, "ocr2_observations_per_round")
+
+	return reportGenerationMetrics{
+		registerer,
+		roundDuration,
+		observationsPerRound,
+		newSignatureVerificationFailuresMetric(registerer, logger, "repgen"),
+		newReportingPluginDurationMetric(registerer, logger, "repgen"),
+	}
+}
+
+func (rm *reportGenerationMetrics) Close() {
+	rm.registerer.Unregister(rm.roundDuration)
+	rm.registerer.Unregister(rm.observationsPerRound)
+	rm.registerer.Unregister(rm.signatureVerificationFailures)
+	rm.registerer.Unregister(rm.reportingPluginDuration)
+}
+
+type reportFinalizationMetrics struct {
+	registerer                    prometheus.Registerer
+	signatureVerificationFailures *prometheus.CounterVec
+}
+
+func newReportFinalizationMetrics(registerer prometheus.Registerer,
+	logger commontypes.Logger) reportFinalizationMetrics {
+	return reportFinalizationMetrics{
+		registerer,
+		newSignatureVerificationFailuresMetric(registerer, logger, "repfin"),
+	}
+}
+
+func (rm *reportFinalizationMetrics) Close() {
+	rm.registerer.Unregister(rm.signatureVerificationFailures)
+}
+
+type transmissionMetrics struct {
+	registerer              prometheus.Registerer
+	transmitDuration        prometheus.Histogram
+	transmitFailures        prometheus.Counter
+	reportingPluginDuration *prometheus.HistogramVec
+}
+
+func newTransmissionMetrics(registerer prometheus.Registerer,
+	logger commontypes.Logger) transmissionMetrics {
+	transmitDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr2_transmit_duration_seconds",
+		Help:    "The duration of calls to ContractTransmitter.Transmit",
+		Buckets: durationBuckets,
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, transmitDuration, "ocr2_transmit_duration_seconds")
+
+	transmitFailures := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ocr2_transmit_failures_total",
+		Help: "The total number of calls to ContractTransmitter.Transmit that returned an error",
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, transmitFailures, "ocr2_transmit_failures_total")
+
+	return transmissionMetrics{
+		registerer,
+		transmitDuration,
+		transmitFailures,
+		newReportingPluginDurationMetric(registerer, logger, "transmission"),
+	}
+}
+
+func (tm *transmissionMetrics) Close() {
+	tm.registerer.Unregister(tm.transmitDuration)
+	tm.registerer.Unregister(tm.transmitFailures)
+	tm.registerer.Unregister(tm.reportingPluginDuration)
+}
@@ -0,0 +1,108 @@
+package protocol
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/smartcontractkit/libocr/commontypes"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+// errorLogger remembers the errors logged to it.
+type errorLogger struct {
+	nopLogger
+	mutex  sync.Mutex
+	errors []string
+}
+
+func (l *errorLogger) Error(msg string, fields commontypes.LogFields) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.errors = append(l.errors, msg)
+}
+
+func TestMetricsRegisterAndUnregister(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	logger := &errorLogger{}
+
+	// all subprotocols of an instance share the registerer
+	pace := newPacemakerMetrics(registry, logger)
+	repgen := newReportGenerationMetrics(registry, logger)
+	repfin := newReportFinalizationMetrics(registry, logger)
+	transmission := newTransmissionMetrics(registry, logger)
+	if len(logger.errors) != 0 {
+		t.Fatalf("errors while registering metrics: %v", logger.errors)
+	}
+
+	start := time.Now()
+	observeReportingPluginDuration(repgen.reportingPluginDuration, "Observation", start, true)
+	observeReportingPluginDuration(repgen.reportingPluginDuration, "Report", start, false)
+	observeReportingPluginDuration(transmission.reportingPluginDuration, "ShouldTransmitAcceptedReport", start, true)
+	incSignatureVerificationFailures(repgen.signatureVerificationFailures, "MessageObserve", 3)
+	incSignatureVerificationFailures(repgen.signatureVerificationFailures, "MessageObserve", 3)
+	incSignatureVerificationFailures(repfin.signatureVerificationFailures, "MessageFinalEcho", 1)
+	transmission.transmitFailures.Inc()
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP ocr2_signature_verification_failures_total The total number of messages dropped because they carried invalid signatures, by sender
+# TYPE ocr2_signature_verification_failures_total counter
+ocr2_signature_verification_failures_total{message="MessageFinalEcho",sender="1",subprotocol="repfin"} 1
+ocr2_signature_verification_failures_total{message="MessageObserve",sender="3",subprotocol="repgen"} 2
+# HELP ocr2_transmit_failures_total The total number of calls to ContractTransmitter.Transmit that returned an error
+# TYPE ocr2_transmit_failures_total counter
+ocr2_transmit_failures_total 1
+`), "ocr2_signature_verification_failures_total", "ocr2_transmit_failures_total"); err != nil {
+		t.Error(err)
+	}
+
+	// the reporting plugin duration is reported separately for each
+	// subprotocol, function and outcome
+	if count, err := testutil.GatherAndCount(registry, "ocr2_reporting_plugin_duration_seconds"); err != nil || count != 3 {
+		t.Errorf("gathered %v reporting plugin durations, error: %v", count, err)
+	}
+
+	pace.Close()
+	repgen.Close()
+	repfin.Close()
+	transmission.Close()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		t.Errorf("%v is still registered after closing", family.GetName())
+	}
+}
+
+func TestMetricsOfSuccessiveInstances(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	logger := &errorLogger{}
+
+	// a new protocol instance registers its metrics after the previous
+	// instance unregistered its own
+	for i := 0; i < 2; i++ {
+		repgen := newReportGenerationMetrics(registry, logger)
+		repfin := newReportFinalizationMetrics(registry, logger)
+		incSignatureVerificationFailures(repfin.signatureVerificationFailures, "MessageFinalEcho", 1)
+		if value := testutil.ToFloat64(repfin.signatureVerificationFailures.WithLabelValues("MessageFinalEcho", "1")); value != 1 {
+			t.Errorf("instance %v reports %v failures", i, value)
+		}
+		repgen.Close()
+		repfin.Close()
+	}
+	if len(logger.errors) != 0 {
+		t.Errorf("errors while registering metrics: %v", logger.errors)
+	}
+}
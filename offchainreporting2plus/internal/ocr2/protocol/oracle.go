@@ -9,6 +9,8 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const futureMessageBufferSize = 10 // big enough for a couple of full rounds of repgen protocol
@@ -18,6 +20,8 @@ const futureMessageBufferSize = 10 // big enough for a couple of full rounds of
 //
 // RunOracle runs forever until ctx is cancelled. It will only shut down
 // after all its sub-goroutines have exited.
+//
+// tracer may be nil, in which case no spans are emitted.
 func RunOracle(
 	ctx context.Context,
 
@@ -34,7 +38,12 @@ func RunOracle(
 	reportingPlugin types.ReportingPlugin,
 	reportQuorum int,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
 ) {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("")
+	}
+
 	o := oracleState{
 		ctx: ctx,
 
@@ -51,6 +60,7 @@ func RunOracle(
 		reportingPlugin:     reportingPlugin,
 		reportQuorum:        reportQuorum,
 		telemetrySender:     telemetrySender,
+		tracer:              tracer,
 	}
 	o.run()
 }
@@ -71,6 +81,7 @@ type oracleState struct {
 	reportingPlugin     types.ReportingPlugin
 	reportQuorum        int
 	telemetrySender     TelemetrySender
+	tracer              trace.Tracer
 
 	bufferedMessages          []*MessageBuffer
 	chNetToPacemaker          chan<- MessageToPacemakerWithSender
@@ -168,6 +179,7 @@ func (o *oracleState) run() {
 			o.reportingPlugin,
 			o.reportQuorum,
 			o.telemetrySender,
+			o.tracer,
 		)
 	})
 	o.subprocesses.Go(func() {
@@ -180,6 +192,7 @@ func (o *oracleState) run() {
 			o.config,
 			o.onchainKeyring,
 			o.logger,
+			o.metricsRegisterer,
 			o.netEndpoint,
 			o.reportQuorum,
 		)
@@ -195,7 +208,9 @@ func (o *oracleState) run() {
 			o.id,
 			o.localConfig,
 			o.logger,
+			o.metricsRegisterer,
 			o.reportingPlugin,
+			o.tracer,
 			o.contractTransmitter,
 		)
 	})
@@ -15,6 +15,7 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr2/protocol/persist"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -45,12 +46,13 @@ func RunPacemaker(
 	reportingPlugin types.ReportingPlugin,
 	reportQuorum int,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
 ) {
 	pace := makePacemakerState(
 		ctx, subprocesses, chNetToPacemaker, chNetToReportGeneration, chPacemakerToOracle,
 		chReportGenerationToReportFinalization, config, contractTransmitter, database,
 		id, localConfig, logger, metricsRegisterer, netSender, offchainKeyring, onchainKeyring, reportingPlugin,
-		reportQuorum, telemetrySender,
+		reportQuorum, telemetrySender, tracer,
 	)
 	pace.run()
 }
@@ -72,6 +74,7 @@ func makePacemakerState(
 	reportingPlugin types.ReportingPlugin,
 	reportQuorum int,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
 ) pacemakerState {
 	return pacemakerState{
 		ctx:          ctx,
@@ -88,12 +91,14 @@ func makePacemakerState(
 		localConfig:                            localConfig,
 		logger:                                 logger,
 		metrics:                                newPacemakerMetrics(metricsRegisterer, logger),
+		reportGenerationMetrics:                newReportGenerationMetrics(metricsRegisterer, logger),
 		netSender:                              netSender,
 		offchainKeyring:                        offchainKeyring,
 		onchainKeyring:                         onchainKeyring,
 		reportingPlugin:                        reportingPlugin,
 		reportQuorum:                           reportQuorum,
 		telemetrySender:                        telemetrySender,
+		tracer:                                 tracer,
 
 		newepoch: make([]uint32, config.N()),
 	}
@@ -120,7 +125,9 @@ type pacemakerState struct {
 	onchainKeyring                         types.OnchainKeyring
 	reportingPlugin                        types.ReportingPlugin
 	reportQuorum                           int
+	reportGenerationMetrics                reportGenerationMetrics
 	telemetrySender                        TelemetrySender
+	tracer                                 trace.Tracer
 	// Test use only: send testBlocker an event to halt the pacemaker event loop,
 	// send testUnblocker an event to resume it.
 	testBlocker   chan eventTestBlock
@@ -234,6 +241,7 @@ func (pace *pacemakerState) run() {
 			pace.logger.Info("Pacemaker: winding down", nil)
 			pace.reportGenerationSubprocess.Wait()
 			pace.metrics.Close()
+			pace.reportGenerationMetrics.Close()
 			pace.logger.Info("Pacemaker: exiting", nil)
 			return
 		default:
@@ -490,12 +498,14 @@ func (pace *pacemakerState) spawnReportGeneration() {
 			l,
 			localConfig,
 			logger,
+			metrics,
 			netSender,
 			offchainKeyring,
 			onchainKeyring,
 			reportingPlugin,
 			reportQuorum,
-			telemetrySender := pace.subprocesses,
+			telemetrySender,
+			tracer := pace.subprocesses,
 			pace.chNetToReportGeneration,
 			pace.chReportGenerationToReportFinalization,
 			pace.config,
@@ -505,12 +515,14 @@ func (pace *pacemakerState) spawnReportGeneration() {
 			pace.l,
 			pace.localConfig,
 			pace.logger,
+			pace.reportGenerationMetrics,
 			pace.netSender,
 			pace.offchainKeyring,
 			pace.onchainKeyring,
 			pace.reportingPlugin,
 			pace.reportQuorum,
-			pace.telemetrySender
+			pace.telemetrySender,
+			pace.tracer
 
 		pace.reportGenerationSubprocess.Go(func() {
 			defer cancelReportGeneration()
@@ -528,12 +540,14 @@ func (pace *pacemakerState) spawnReportGeneration() {
 				l,
 				localConfig,
 				logger,
+				metrics,
 				netSender,
 				offchainKeyring,
 				onchainKeyring,
 				reportingPlugin,
 				reportQuorum,
 				telemetrySender,
+				tracer,
 			)
 		})
 	}
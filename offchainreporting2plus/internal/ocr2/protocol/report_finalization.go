@@ -5,10 +5,12 @@ import (
 	"math"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func RunReportFinalization(
@@ -20,12 +22,13 @@ func RunReportFinalization(
 	config ocr2config.SharedConfig,
 	contractSigner types.OnchainKeyring,
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender,
 	reportQuorum int,
 ) {
 	newReportFinalizationState(ctx, chNetToReportFinalization,
 		chReportFinalizationToTransmission, chReportGenerationToReportFinalization,
-		config, contractSigner, logger, netSender, reportQuorum).run()
+		config, contractSigner, logger, metricsRegisterer, netSender, reportQuorum).run()
 }
 
 const minExpirationAgeRounds int = 10
@@ -41,6 +44,7 @@ type reportFinalizationState struct {
 	config                                 ocr2config.SharedConfig
 	contractSigner                         types.OnchainKeyring
 	logger                                 loghelper.LoggerWithContext
+	metrics                                reportFinalizationMetrics
 	netSender                              NetworkSender
 	reportQuorum                           int
 
@@ -63,6 +67,7 @@ func (repfin *reportFinalizationState) run() {
 		select {
 		case <-repfin.ctx.Done():
 			repfin.logger.Info("ReportFinalization: exiting", nil)
+			repfin.metrics.Close()
 			return
 		default:
 		}
@@ -103,6 +108,7 @@ func (repfin *reportFinalizationState) messageFinalEcho(
 		},
 	)
 	if err != nil {
+		incSignatureVerificationFailures(repfin.metrics.signatureVerificationFailures, "MessageFinalEcho", sender)
 		repfin.logger.Warn("error while verifying signatures on attested report", commontypes.LogFields{
 			"msg":    msg,
 			"sender": sender,
@@ -111,7 +117,7 @@ func (repfin *reportFinalizationState) messageFinalEcho(
 		return
 	}
 
-	repfin.finalize(msg.MessageFinal)
+	repfin.finalize(msg.MessageFinal, trace.SpanContext{})
 }
 
 func (repfin *reportFinalizationState) eventFinal(ev EventFinal) {
@@ -127,10 +133,10 @@ func (repfin *reportFinalizationState) eventFinal(ev EventFinal) {
 		return
 	}
 
-	repfin.finalize(ev.MessageFinal)
+	repfin.finalize(ev.MessageFinal, ev.SpanContext)
 }
 
-func (repfin *reportFinalizationState) finalize(msg MessageFinal) {
+func (repfin *reportFinalizationState) finalize(msg MessageFinal, spanContext trace.SpanContext) {
 	repfin.logger.Debug("finalizing report", commontypes.LogFields{
 		"epoch": msg.Epoch,
 		"round": msg.Round,
@@ -146,7 +152,13 @@ func (repfin *reportFinalizationState) finalize(msg MessageFinal) {
 	repfin.netSender.Broadcast(MessageFinalEcho{msg}) // send [ FINALECHO, e, r, O] to all p_j ∈ P
 
 	select {
-	case repfin.chReportFinalizationToTransmission <- EventTransmit(msg):
+	case repfin.chReportFinalizationToTransmission <- EventTransmit{
+		msg.Epoch,
+		msg.Round,
+		msg.H,
+		msg.AttestedReport,
+		spanContext,
+	}:
 	case <-repfin.ctx.Done():
 	}
 
@@ -207,6 +219,7 @@ func newReportFinalizationState(
 	config ocr2config.SharedConfig,
 	contractSigner types.OnchainKeyring,
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender,
 	reportQuorum int,
 ) *reportFinalizationState {
@@ -219,6 +232,7 @@ func newReportFinalizationState(
 		config,
 		contractSigner,
 		logger,
+		newReportFinalizationMetrics(metricsRegisterer, logger),
 		netSender,
 		reportQuorum,
 
@@ -9,6 +9,7 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Report Generation protocol corresponding to alg. 2 & 3.
@@ -26,12 +27,14 @@ func RunReportGeneration(
 	l commontypes.OracleID,
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
+	metrics reportGenerationMetrics,
 	netSender NetworkSender,
 	offchainKeyring types.OffchainKeyring,
 	onchainKeyring types.OnchainKeyring,
 	reportingPlugin types.ReportingPlugin,
 	reportQuorum int,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
 ) {
 	repgen := reportGenerationState{
 		ctx:          ctx,
@@ -47,12 +50,14 @@ func RunReportGeneration(
 		l:                                      l,
 		localConfig:                            localConfig,
 		logger:                                 logger.MakeChild(commontypes.LogFields{"epoch": e, "leader": l}),
+		metrics:                                metrics,
 		netSender:                              netSender,
 		offchainKeyring:                        offchainKeyring,
 		onchainKeyring:                         onchainKeyring,
 		reportingPlugin:                        reportingPlugin,
 		reportQuorum:                           reportQuorum,
 		telemetrySender:                        telemetrySender,
+		tracer:                                 tracer,
 	}
 	repgen.run()
 }
@@ -71,12 +76,14 @@ type reportGenerationState struct {
 	l                                      commontypes.OracleID // Current leader number
 	localConfig                            types.LocalConfig
 	logger                                 loghelper.LoggerWithContext
+	metrics                                reportGenerationMetrics
 	netSender                              NetworkSender
 	offchainKeyring                        types.OffchainKeyring
 	onchainKeyring                         types.OnchainKeyring
 	reportingPlugin                        types.ReportingPlugin
 	reportQuorum                           int
 	telemetrySender                        TelemetrySender
+	tracer                                 trace.Tracer
 
 	leaderState   leaderState
 	followerState followerState
@@ -125,6 +132,13 @@ type followerState struct {
 	// completedRound tracks whether the current oracle has completed the current
 	// round
 	completedRound bool
+
+	// roundStartedAt is when we received the MessageObserveReq for the current
+	// round
+	roundStartedAt time.Time
+
+	// span covers the current round until it completes
+	span trace.Span
 }
 
 // Run starts the event loop for the report-generation protocol
@@ -164,6 +178,7 @@ func (repgen *reportGenerationState) run() {
 				"e": repgen.e,
 				"l": repgen.l,
 			})
+			repgen.endRoundSpan(false)
 			return
 		default:
 		}
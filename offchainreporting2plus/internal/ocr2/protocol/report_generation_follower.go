@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/smartcontractkit/libocr/commontypes"
@@ -115,6 +116,8 @@ func (repgen *reportGenerationState) messageObserveReq(msg MessageObserveReq, se
 	// and has no impact on the transmission process.)
 	repgen.followerState.sentReport = false
 	repgen.followerState.completedRound = false
+	repgen.followerState.roundStartedAt = time.Now()
+	repgen.startRoundSpan()
 
 	repgen.telemetrySender.RoundStarted(
 		repgen.config.ConfigDigest,
@@ -125,7 +128,8 @@ func (repgen *reportGenerationState) messageObserveReq(msg MessageObserveReq, se
 
 	var o types.Observation
 	{
-		ctx, cancel := context.WithTimeout(repgen.ctx, repgen.config.MaxDurationObservation)
+		ctx, span := startPluginSpan(repgen.roundContext(), repgen.tracer, "Observation")
+		ctx, cancel := context.WithTimeout(ctx, repgen.config.MaxDurationObservation)
 		defer cancel()
 
 		ins := loghelper.NewIfNotStopped(
@@ -137,8 +141,11 @@ func (repgen *reportGenerationState) messageObserveReq(msg MessageObserveReq, se
 			},
 		)
 
+		start := time.Now()
 		var err error
 		o, err = repgen.reportingPlugin.Observation(ctx, repgen.followerReportTimestamp(), msg.Query)
+		observeReportingPluginDuration(repgen.metrics.reportingPluginDuration, "Observation", start, err == nil)
+		endPluginSpan(span, err)
 
 		ins.Stop()
 
@@ -223,6 +230,8 @@ func (repgen *reportGenerationState) messageReportReq(msg MessageReportReq, send
 		return
 	}
 
+	repgen.metrics.observationsPerRound.Observe(float64(len(msg.AttributedSignedObservations)))
+
 	aos := []types.AttributedObservation{}
 	for _, aso := range msg.AttributedSignedObservations {
 		aos = append(aos, types.AttributedObservation{
@@ -234,7 +243,8 @@ func (repgen *reportGenerationState) messageReportReq(msg MessageReportReq, send
 	var shouldReport bool
 	var report types.Report
 	{
-		ctx, cancel := context.WithTimeout(repgen.ctx, repgen.config.MaxDurationReport)
+		ctx, span := startPluginSpan(repgen.roundContext(), repgen.tracer, "Report")
+		ctx, cancel := context.WithTimeout(ctx, repgen.config.MaxDurationReport)
 		defer cancel()
 
 		ins := loghelper.NewIfNotStopped(
@@ -246,6 +256,7 @@ func (repgen *reportGenerationState) messageReportReq(msg MessageReportReq, send
 			},
 		)
 
+		start := time.Now()
 		var err error
 		shouldReport, report, err = repgen.reportingPlugin.Report(
 			ctx,
@@ -253,6 +264,8 @@ func (repgen *reportGenerationState) messageReportReq(msg MessageReportReq, send
 			msg.Query,
 			aos,
 		)
+		observeReportingPluginDuration(repgen.metrics.reportingPluginDuration, "Report", start, err == nil)
+		endPluginSpan(span, err)
 
 		ins.Stop()
 
@@ -352,6 +365,7 @@ func (repgen *reportGenerationState) messageFinal(
 		repgen.config.OracleIdentities,
 		types.ReportContext{repgen.followerReportTimestamp(), msg.H},
 	); err != nil {
+		incSignatureVerificationFailures(repgen.metrics.signatureVerificationFailures, "MessageFinal", sender)
 		repgen.logger.Error("could not validate signatures on attested report in MessageFinal",
 			commontypes.LogFields{
 				"error":  err,
@@ -361,7 +375,7 @@ func (repgen *reportGenerationState) messageFinal(
 	}
 
 	select {
-	case repgen.chReportGenerationToReportFinalization <- EventFinal{msg}:
+	case repgen.chReportGenerationToReportFinalization <- EventFinal{msg, repgen.roundSpanContext()}:
 	case <-repgen.ctx.Done():
 	}
 	repgen.completeRound()
@@ -376,6 +390,8 @@ func (repgen *reportGenerationState) completeRound() {
 		"round": repgen.followerState.r,
 	})
 	repgen.followerState.completedRound = true
+	repgen.metrics.roundDuration.Observe(time.Since(repgen.followerState.roundStartedAt).Seconds())
+	repgen.endRoundSpan(true)
 
 	select {
 	case repgen.chReportGenerationToPacemaker <- EventProgress{}:
@@ -403,6 +419,8 @@ func (repgen *reportGenerationState) verifyReportReq(msg MessageReportReq) error
 			}
 			observerOffchainPublicKey := repgen.config.OracleIdentities[obs.Observer].OffchainPublicKey
 			if err := obs.SignedObservation.Verify(repgen.followerReportTimestamp(), msg.Query, observerOffchainPublicKey); err != nil {
+				// the leader should have checked this signature, so we hold the leader responsible
+				incSignatureVerificationFailures(repgen.metrics.signatureVerificationFailures, "MessageReportReq", repgen.l)
 				return errors.Errorf("invalid signed observation: %s", err)
 			}
 		}
@@ -7,6 +7,7 @@ import (
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 ///////////////////////////////////////////////////////////
@@ -80,7 +81,10 @@ func (repgen *reportGenerationState) startRound() {
 			actualMaxDurationQuery = repgen.localConfig.MinOCR2MaxDurationQuery
 		}
 
-		ctx, cancel := context.WithTimeout(repgen.ctx, actualMaxDurationQuery)
+		ctx, span := repgen.tracer.Start(repgen.ctx, "ReportingPlugin.Query",
+			trace.WithAttributes(roundAttributes(repgen.leaderReportTimestamp())...),
+		)
+		ctx, cancel := context.WithTimeout(ctx, actualMaxDurationQuery)
 		defer cancel()
 
 		ins := loghelper.NewIfNotStopped(
@@ -92,8 +96,11 @@ func (repgen *reportGenerationState) startRound() {
 			},
 		)
 
+		start := time.Now()
 		var err error
 		query, err = repgen.reportingPlugin.Query(ctx, repgen.leaderReportTimestamp())
+		observeReportingPluginDuration(repgen.metrics.reportingPluginDuration, "Query", start, err == nil)
+		endPluginSpan(span, err)
 
 		ins.Stop()
 
@@ -166,6 +173,7 @@ func (repgen *reportGenerationState) messageObserve(msg MessageObserve, sender c
 	}
 
 	if err := msg.SignedObservation.Verify(repgen.leaderReportTimestamp(), repgen.leaderState.q, repgen.config.OracleIdentities[sender].OffchainPublicKey); err != nil {
+		incSignatureVerificationFailures(repgen.metrics.signatureVerificationFailures, "MessageObserve", sender)
 		repgen.logger.Warn("MessageObserve carries invalid SignedObservation", commontypes.LogFields{
 			"round":  repgen.leaderState.r,
 			"sender": sender,
@@ -289,6 +297,7 @@ func (repgen *reportGenerationState) messageReport(msg MessageReport, sender com
 		},
 	)
 	if err != nil {
+		incSignatureVerificationFailures(repgen.metrics.signatureVerificationFailures, "MessageReport", sender)
 		repgen.logger.Error("could not validate signature", commontypes.LogFields{
 			"round": repgen.leaderState.r,
 			"error": err,
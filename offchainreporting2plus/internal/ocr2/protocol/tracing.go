@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"context"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Each round produces one trace per follower: a root ReportGeneration span for
+// the (epoch, round) pair, and Transmission child spans once the report has
+// been finalized. Calls to the ReportingPlugin and ContractTransmitter get
+// their own child spans, and the ctx passed to them carries these spans so
+// that plugins can attach their own. The leader's call to
+// ReportingPlugin.Query gets a root span of its own.
+
+const (
+	attributeConfigDigest = attribute.Key("ocr.config_digest")
+	attributeEpoch        = attribute.Key("ocr.epoch")
+	attributeRound        = attribute.Key("ocr.round")
+	attributeLeader       = attribute.Key("ocr.leader")
+)
+
+func roundAttributes(ts types.ReportTimestamp) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attributeConfigDigest.String(ts.ConfigDigest.Hex()),
+		attributeEpoch.Int64(int64(ts.Epoch)),
+		attributeRound.Int(int(ts.Round)),
+	}
+}
+
+// startPluginSpan starts a child span of ctx for a call to the named
+// ReportingPlugin method. endPluginSpan must be called with the call's error.
+func startPluginSpan(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "ReportingPlugin."+name)
+}
+
+func endPluginSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startRoundSpan starts the span for the round the follower has just entered,
+// ending the span of the previous round if that round never completed.
+func (repgen *reportGenerationState) startRoundSpan() {
+	repgen.endRoundSpan(false)
+	_, repgen.followerState.span = repgen.tracer.Start(repgen.ctx, "ReportGeneration",
+		trace.WithAttributes(roundAttributes(repgen.followerReportTimestamp())...),
+		trace.WithAttributes(attributeLeader.Int(int(repgen.l))),
+	)
+}
+
+// roundContext returns repgen.ctx carrying the span of the current round.
+func (repgen *reportGenerationState) roundContext() context.Context {
+	if repgen.followerState.span == nil {
+		return repgen.ctx
+	}
+	return trace.ContextWithSpan(repgen.ctx, repgen.followerState.span)
+}
+
+// roundSpanContext returns the SpanContext of the current round, which is
+// invalid if there is no such span.
+func (repgen *reportGenerationState) roundSpanContext() trace.SpanContext {
+	if repgen.followerState.span == nil {
+		return trace.SpanContext{}
+	}
+	return repgen.followerState.span.SpanContext()
+}
+
+// endRoundSpan ends the span of the current round, if any.
+func (repgen *reportGenerationState) endRoundSpan(completed bool) {
+	if repgen.followerState.span == nil {
+		return
+	}
+	repgen.followerState.span.SetAttributes(attribute.Bool("ocr.completed", completed))
+	repgen.followerState.span.End()
+	repgen.followerState.span = nil
+}
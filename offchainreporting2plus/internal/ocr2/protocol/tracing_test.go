@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocrtracetest"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRoundSpans(t *testing.T) {
+	recorder := ocrtracetest.NewRecorder()
+	digest := types.ConfigDigest{0x00, 0x02, 0xaa}
+	repgen := &reportGenerationState{
+		ctx:    context.Background(),
+		config: ocr2config.SharedConfig{PublicConfig: ocr2config.PublicConfig{ConfigDigest: digest}},
+		e:      5,
+		l:      2,
+		tracer: recorder.Tracer(),
+	}
+
+	if repgen.roundContext() != repgen.ctx || repgen.roundSpanContext().IsValid() {
+		t.Fatal("round context without a round span")
+	}
+
+	repgen.followerState.r = 1
+	repgen.startRoundSpan()
+	spanContext := repgen.roundSpanContext()
+	if !spanContext.IsValid() {
+		t.Fatal("invalid span context for round 1")
+	}
+
+	// plugin calls are children of the round span
+	_, pluginSpan := startPluginSpan(repgen.roundContext(), repgen.tracer, "Observation")
+	endPluginSpan(pluginSpan, nil)
+	_, pluginSpan = startPluginSpan(repgen.roundContext(), repgen.tracer, "Report")
+	endPluginSpan(pluginSpan, fmt.Errorf("boom"))
+
+	// entering the next round ends the span of the incomplete round
+	repgen.followerState.r = 2
+	repgen.startRoundSpan()
+	repgen.endRoundSpan(true)
+	repgen.endRoundSpan(true) // no-op without a span
+
+	rounds := recorder.SpansNamed("ReportGeneration")
+	if len(rounds) != 2 {
+		t.Fatalf("recorded %v round spans", len(rounds))
+	}
+	for i, round := range rounds {
+		expected := map[attribute.Key]attribute.Value{
+			attributeConfigDigest: attribute.StringValue(digest.Hex()),
+			attributeEpoch:        attribute.Int64Value(5),
+			attributeRound:        attribute.IntValue(i + 1),
+			attributeLeader:       attribute.IntValue(2),
+			"ocr.completed":       attribute.BoolValue(i == 1),
+		}
+		for key, value := range expected {
+			if actual, ok := attributeValue(round, key); !ok || actual != value {
+				t.Errorf("round span %v has %v=%v, expected %v", i, key, actual.Emit(), value.Emit())
+			}
+		}
+	}
+	if rounds[0].SpanContext.SpanID() != spanContext.SpanID() {
+		t.Error("first round span doesn't match the reported span context")
+	}
+	if rounds[0].SpanContext.TraceID() == rounds[1].SpanContext.TraceID() {
+		t.Error("rounds share a trace")
+	}
+
+	observation := recorder.SpansNamed("ReportingPlugin.Observation")
+	report := recorder.SpansNamed("ReportingPlugin.Report")
+	if len(observation) != 1 || len(report) != 1 {
+		t.Fatalf("recorded %v Observation and %v Report spans", len(observation), len(report))
+	}
+	for _, span := range []tracetest.SpanStub{observation[0], report[0]} {
+		if span.Parent.SpanID() != spanContext.SpanID() {
+			t.Errorf("%v isn't a child of the round span", span.Name)
+		}
+	}
+	if observation[0].Status.Code != codes.Unset {
+		t.Errorf("successful call has status %v", observation[0].Status)
+	}
+	if report[0].Status.Code != codes.Error || report[0].Status.Description != "boom" {
+		t.Errorf("failed call has status %v", report[0].Status)
+	}
+}
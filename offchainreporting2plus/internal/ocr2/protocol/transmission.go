@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr2config"
@@ -12,6 +13,8 @@ import (
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/permutation"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -36,7 +39,9 @@ func RunTransmission(
 	id commontypes.OracleID,
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	reportingPlugin types.ReportingPlugin,
+	tracer trace.Tracer,
 	transmitter types.ContractTransmitter,
 ) {
 	t := transmissionState{
@@ -49,8 +54,12 @@ func RunTransmission(
 		id:                                 id,
 		localConfig:                        localConfig,
 		logger:                             logger,
+		metrics:                            newTransmissionMetrics(metricsRegisterer, logger),
 		reportingPlugin:                    reportingPlugin,
+		tracer:                             tracer,
 		transmitter:                        transmitter,
+
+		spanContexts: map[EpochRound]trace.SpanContext{},
 	}
 	t.run()
 }
@@ -65,12 +74,18 @@ type transmissionState struct {
 	id                                 commontypes.OracleID
 	localConfig                        types.LocalConfig
 	logger                             loghelper.LoggerWithContext
+	metrics                            transmissionMetrics
 	reportingPlugin                    types.ReportingPlugin
+	tracer                             trace.Tracer
 	transmitter                        types.ContractTransmitter
 
 	chPersist chan<- persist.TransmissionDBUpdate
 	times     MinHeapTimeToPendingTransmission
 	tTransmit <-chan time.Time
+	// spanContexts tracks the SpanContexts of the rounds that produced the
+	// pending transmissions in times. Transmissions restored from the
+	// database have no entry.
+	spanContexts map[EpochRound]trace.SpanContext
 }
 
 // run runs the event loop for the local transmission protocol
@@ -103,6 +118,7 @@ func (t *transmissionState) run() {
 		select {
 		case <-chDone:
 			t.logger.Info("Transmission: exiting", nil)
+			t.metrics.Close()
 			return
 		default:
 		}
@@ -146,8 +162,12 @@ func (t *transmissionState) eventTransmit(ev EventTransmit) {
 
 	ts := types.ReportTimestamp{t.config.ConfigDigest, ev.Epoch, ev.Round}
 
+	acceptCtx, acceptSpan := t.startSpan("Transmission.accept", ev.SpanContext, ts)
+	defer acceptSpan.End()
+
 	{
-		ctx, cancel := context.WithTimeout(t.ctx, t.config.MaxDurationShouldAcceptFinalizedReport)
+		ctx, span := startPluginSpan(acceptCtx, t.tracer, "ShouldAcceptFinalizedReport")
+		ctx, cancel := context.WithTimeout(ctx, t.config.MaxDurationShouldAcceptFinalizedReport)
 		defer cancel()
 
 		ins := loghelper.NewIfNotStopped(
@@ -159,11 +179,14 @@ func (t *transmissionState) eventTransmit(ev EventTransmit) {
 			},
 		)
 
+		start := time.Now()
 		shouldAccept, err := t.reportingPlugin.ShouldAcceptFinalizedReport(
 			ctx,
 			ts,
 			ev.AttestedReport.Report,
 		)
+		observeReportingPluginDuration(t.metrics.reportingPluginDuration, "ShouldAcceptFinalizedReport", start, err == nil)
+		endPluginSpan(span, err)
 
 		ins.Stop()
 
@@ -206,6 +229,9 @@ func (t *transmissionState) eventTransmit(ev EventTransmit) {
 	}
 
 	t.times.Push(MinHeapTimeToPendingTransmissionItem{ts, transmission})
+	if ev.SpanContext.IsValid() {
+		t.spanContexts[EpochRound{ev.Epoch, ev.Round}] = ev.SpanContext
+	}
 
 	next := t.times.Peek()
 	if (EpochRound{ev.Epoch, ev.Round}) == (EpochRound{next.Epoch, next.Round}) {
@@ -227,6 +253,11 @@ func (t *transmissionState) eventTTransmitTimeout() {
 	}
 	item := t.times.Pop()
 
+	epochRound := EpochRound{item.Epoch, item.Round}
+	transmissionCtx, transmissionSpan := t.startSpan("Transmission.transmit", t.spanContexts[epochRound], item.ReportTimestamp)
+	defer transmissionSpan.End()
+	delete(t.spanContexts, epochRound)
+
 	select {
 	case t.chPersist <- persist.TransmissionDBUpdate{
 		types.ReportTimestamp{
@@ -241,8 +272,9 @@ func (t *transmissionState) eventTTransmitTimeout() {
 	}
 
 	{
+		ctx, span := startPluginSpan(transmissionCtx, t.tracer, "ShouldTransmitAcceptedReport")
 		ctx, cancel := context.WithTimeout(
-			t.ctx,
+			ctx,
 			t.config.MaxDurationShouldTransmitAcceptedReport,
 		)
 		defer cancel()
@@ -256,11 +288,14 @@ func (t *transmissionState) eventTTransmitTimeout() {
 			},
 		)
 
+		start := time.Now()
 		shouldTransmit, err := t.reportingPlugin.ShouldTransmitAcceptedReport(
 			ctx,
 			item.ReportTimestamp,
 			item.Report,
 		)
+		observeReportingPluginDuration(t.metrics.reportingPluginDuration, "ShouldTransmitAcceptedReport", start, err == nil)
+		endPluginSpan(span, err)
 
 		ins.Stop()
 
@@ -281,8 +316,11 @@ func (t *transmissionState) eventTTransmitTimeout() {
 	})
 
 	{
+		ctx, span := t.tracer.Start(transmissionCtx, "ContractTransmitter.Transmit")
+		defer span.End()
+
 		ctx, cancel := context.WithTimeout(
-			t.ctx,
+			ctx,
 			t.localConfig.ContractTransmitterTransmitTimeout,
 		)
 		defer cancel()
@@ -296,6 +334,7 @@ func (t *transmissionState) eventTTransmitTimeout() {
 			},
 		)
 
+		start := time.Now()
 		err := t.transmitter.Transmit(
 			ctx,
 			types.ReportContext{
@@ -305,10 +344,13 @@ func (t *transmissionState) eventTTransmitTimeout() {
 			item.Report,
 			item.AttributedSignatures,
 		)
+		t.metrics.transmitDuration.Observe(time.Since(start).Seconds())
 
 		ins.Stop()
 
 		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			t.metrics.transmitFailures.Inc()
 			t.logger.Error("eventTTransmitTimeout: ContractTransmitter.Transmit error", commontypes.LogFields{"error": err})
 			return
 		}
@@ -321,6 +363,16 @@ func (t *transmissionState) eventTTransmitTimeout() {
 	})
 }
 
+// startSpan starts a span for handling the report with timestamp ts. The span
+// is a child of the span of the round that produced the report, if any.
+func (t *transmissionState) startSpan(name string, roundSpanContext trace.SpanContext, ts types.ReportTimestamp) (context.Context, trace.Span) {
+	return t.tracer.Start(
+		trace.ContextWithSpanContext(t.ctx, roundSpanContext),
+		name,
+		trace.WithAttributes(roundAttributes(ts)...),
+	)
+}
+
 func (t *transmissionState) transmitDelay(epoch uint32, round uint8) *time.Duration {
 	// No need for HMAC. Since we use Keccak256, prepending
 	// with key gives us a PRF already.
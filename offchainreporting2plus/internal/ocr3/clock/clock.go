@@ -0,0 +1,62 @@
+// Package clock abstracts the passage of time for the OCR3 protocol. In
+// production the protocol runs on the system clock; simulations swap in a
+// Virtual clock so that timeouts fire deterministically.
+package clock
+
+import "time"
+
+// Clock provides the subset of the time package used by the protocol.
+//
+// All its functions should be thread-safe.
+type Clock interface {
+	Now() time.Time
+	// After behaves like time.After
+	After(d time.Duration) <-chan time.Time
+	// NewTimer behaves like time.NewTimer
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer behaves like *time.Timer. In particular, Stop does not drain the
+// channel returned by C.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real returns a Clock backed by the system clock.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+var _ Clock = realClock{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
@@ -0,0 +1,205 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Virtual is a Clock whose time only moves when Advance, AdvanceTo or FireNext
+// is called. Timers fire (in deadline order, ties broken by tag and then by
+// creation order) while the clock is being advanced past their deadline.
+type Virtual struct {
+	mutex  sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers map[*virtualTimer]struct{}
+}
+
+var _ Clock = (*Virtual)(nil)
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{
+		now:    start,
+		timers: map[*virtualTimer]struct{}{},
+	}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.now
+}
+
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	return v.NewTimer(d).C()
+}
+
+func (v *Virtual) NewTimer(d time.Duration) Timer {
+	return v.newTimer(0, d)
+}
+
+func (v *Virtual) newTimer(tag int, d time.Duration) Timer {
+	t := &virtualTimer{
+		clock: v,
+		c:     make(chan time.Time, 1),
+		tag:   tag,
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.arm(t, d)
+	return t
+}
+
+// Tagged returns a Clock that shares v's time, but whose timers carry tag.
+// Among timers with the same deadline, those with lower tags fire first. This
+// makes the firing order independent of the order in which concurrent users of
+// v create their timers, as long as each user has its own tag.
+func (v *Virtual) Tagged(tag int) Clock {
+	return taggedVirtual{v, tag}
+}
+
+type taggedVirtual struct {
+	clock *Virtual
+	tag   int
+}
+
+func (tv taggedVirtual) Now() time.Time {
+	return tv.clock.Now()
+}
+
+func (tv taggedVirtual) After(d time.Duration) <-chan time.Time {
+	return tv.NewTimer(d).C()
+}
+
+func (tv taggedVirtual) NewTimer(d time.Duration) Timer {
+	return tv.clock.newTimer(tv.tag, d)
+}
+
+// NextDeadline returns the earliest deadline among all pending timers. ok is
+// false if there are no pending timers.
+func (v *Virtual) NextDeadline() (deadline time.Time, ok bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if next := v.next(); next != nil {
+		return next.deadline, true
+	}
+	return time.Time{}, false
+}
+
+// Advance moves the clock forward by d, firing all timers whose deadline is
+// reached along the way.
+func (v *Virtual) Advance(d time.Duration) {
+	v.AdvanceTo(v.Now().Add(d))
+}
+
+// AdvanceTo moves the clock forward to t, firing all timers whose deadline is
+// at or before t. AdvanceTo never moves the clock backwards, but will still
+// fire timers that are due at the current time.
+func (v *Virtual) AdvanceTo(t time.Time) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for {
+		next := v.next()
+		if next == nil || next.deadline.After(t) {
+			break
+		}
+		if v.now.Before(next.deadline) {
+			v.now = next.deadline
+		}
+		v.fire(next)
+	}
+	if v.now.Before(t) {
+		v.now = t
+	}
+}
+
+// FireNext fires the pending timer that is due first, provided its deadline
+// is at or before until, and moves the clock forward to its deadline. Unlike
+// AdvanceTo, it fires at most one timer, even if others are due at the same
+// time. Returns whether a timer fired.
+func (v *Virtual) FireNext(until time.Time) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	next := v.next()
+	if next == nil || next.deadline.After(until) {
+		return false
+	}
+	if v.now.Before(next.deadline) {
+		v.now = next.deadline
+	}
+	v.fire(next)
+	return true
+}
+
+// must hold mutex
+func (v *Virtual) next() *virtualTimer {
+	var next *virtualTimer
+	for t := range v.timers {
+		if next == nil || t.firesBefore(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// must hold mutex
+func (v *Virtual) arm(t *virtualTimer, d time.Duration) {
+	v.seq++
+	t.seq = v.seq
+	t.deadline = v.now.Add(d)
+	if d <= 0 {
+		v.fire(t)
+		return
+	}
+	v.timers[t] = struct{}{}
+}
+
+// must hold mutex
+func (v *Virtual) fire(t *virtualTimer) {
+	delete(v.timers, t)
+	// like the runtime, drop the tick if the receiver hasn't consumed the
+	// previous one
+	select {
+	case t.c <- v.now:
+	default:
+	}
+}
+
+type virtualTimer struct {
+	clock    *Virtual
+	c        chan time.Time
+	tag      int
+	seq      uint64
+	deadline time.Time
+}
+
+func (t *virtualTimer) firesBefore(other *virtualTimer) bool {
+	if !t.deadline.Equal(other.deadline) {
+		return t.deadline.Before(other.deadline)
+	}
+	if t.tag != other.tag {
+		return t.tag < other.tag
+	}
+	return t.seq < other.seq
+}
+
+func (t *virtualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return pending
+}
+
+func (t *virtualTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.clock.arm(t, d)
+	return pending
+}
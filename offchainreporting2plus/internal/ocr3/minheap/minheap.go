@@ -32,6 +32,13 @@ func (h *MinHeap[T]) Len() int {
 	return h.internal.Len()
 }
 
+// Items returns a copy of the items in the heap, in no particular order.
+func (h *MinHeap[T]) Items() []T {
+	items := make([]T, len(h.internal.items))
+	copy(items, h.internal.items)
+	return items
+}
+
 // Implements heap.Interface and uses interface{} all over the place.
 type minHeapInternal[T any] struct {
 	lessFn LessFn[T]
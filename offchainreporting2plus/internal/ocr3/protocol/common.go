@@ -5,21 +5,31 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const ReportingPluginTimeoutWarningGracePeriod = 100 * time.Millisecond
 
 func callPlugin[T any](
 	ctx context.Context,
+	clock clock.Clock,
+	duration *prometheus.HistogramVec,
 	logger loghelper.LoggerWithContext,
 	logFields commontypes.LogFields,
 	name string,
 	maxDuration time.Duration,
+	tracer trace.Tracer,
 	f func(context.Context) (T, error),
 ) (T, bool) {
-	pluginCtx, cancel := context.WithTimeout(ctx, maxDuration)
+	pluginCtx, span := tracer.Start(ctx, "ReportingPlugin."+name)
+	defer span.End()
+
+	pluginCtx, cancel := context.WithTimeout(pluginCtx, maxDuration)
 	defer cancel()
 
 	ins := loghelper.NewIfNotStopped(
@@ -32,11 +42,14 @@ func callPlugin[T any](
 		},
 	)
 
+	start := clock.Now()
 	result, err := f(pluginCtx)
+	observeReportingPluginDuration(duration, name, clock.Now().Sub(start), err == nil)
 
 	ins.Stop()
 
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		logger.MakeChild(logFields).ErrorIfNotCanceled(fmt.Sprintf("call to ReportingPlugin.%s errored", name), ctx, commontypes.LogFields{
 			"error": err,
 		})
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"context"
+	"time"
 
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 )
@@ -20,3 +21,27 @@ type Database interface {
 	ReadCert(ctx context.Context, configDigest types.ConfigDigest) (CertifiedPrepareOrCommit, error)
 	WriteCert(ctx context.Context, configDigest types.ConfigDigest, cert CertifiedPrepareOrCommit) error
 }
+
+// PendingTransmission is an attested report that has been accepted for
+// transmission, but not been transmitted yet.
+type PendingTransmission[RI any] struct {
+	SeqNr          uint64
+	Index          int
+	AttestedReport AttestedReportMany[RI]
+	// When the next attempt at transmitting the report is due
+	Deadline time.Time
+	// Number of failed attempts at transmitting the report so far
+	FailedAttempts int
+}
+
+// TransmissionOutbox persists the reports that the transmission protocol has
+// accepted for transmission, so that they are transmitted even if the oracle
+// restarts in the meantime.
+type TransmissionOutbox[RI any] interface {
+	ReadPendingTransmissions(ctx context.Context, configDigest types.ConfigDigest) ([]PendingTransmission[RI], error)
+	// Inserts pendingTransmission, or replaces the pending transmission with
+	// the same SeqNr and Index.
+	WritePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, pendingTransmission PendingTransmission[RI]) error
+	// Deleting a pending transmission that doesn't exist is not an error.
+	DeletePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, seqNr uint64, index int) error
+}
@@ -0,0 +1,245 @@
+package protocol
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// Names of journaled events and timers
+const (
+	journalEventNewEpochStart    = "NewEpochStart"
+	journalEventCommittedOutcome = "CommittedOutcome"
+	journalEventProgress         = "Progress"
+	journalEventNewEpochRequest  = "NewEpochRequest"
+
+	journalTimerTInitial       = "TInitial"
+	journalTimerTGrace         = "TGrace"
+	journalTimerTRound         = "TRound"
+	journalTimerTProgress      = "TProgress"
+	journalTimerTResend        = "TResend"
+	journalTimerMissingOutcome = "MissingOutcome"
+)
+
+// JournalEntry is the protocol-level counterpart of ocr3types.JournalEntry.
+// Which fields are set depends on Kind.
+type JournalEntry[RI any] struct {
+	Time        time.Time
+	Subprotocol ocr3types.JournalSubprotocol
+	Kind        ocr3types.JournalEntryKind
+
+	Sender     commontypes.OracleID
+	Message    Message[RI]
+	Name       string
+	Epoch      uint64
+	Leader     commontypes.OracleID
+	SeqNr      uint64
+	Cert       CertifiedPrepareOrCommit
+	PluginCall *ocr3types.JournalPluginCall[RI]
+	Randomness []byte
+}
+
+// Journal records the inputs of the pacemaker, outcome generation, and report
+// attestation protocols. Since each of these protocols is driven by a single
+// event loop, replaying the inputs of a protocol in the order in which they
+// were recorded reproduces its execution.
+//
+// Record is called synchronously from the protocols' event loops and must not
+// block for long.
+//
+// All its functions should be thread-safe.
+type Journal[RI any] interface {
+	Record(entry JournalEntry[RI])
+}
+
+// journalRecorder is used by a single subprotocol to record its inputs. A
+// journalRecorder with a nil journal does nothing.
+type journalRecorder[RI any] struct {
+	journal     Journal[RI]
+	clock       clock.Clock
+	subprotocol ocr3types.JournalSubprotocol
+}
+
+func (jr journalRecorder[RI]) record(entry JournalEntry[RI]) {
+	if jr.journal == nil {
+		return
+	}
+	entry.Time = jr.clock.Now()
+	entry.Subprotocol = jr.subprotocol
+	jr.journal.Record(entry)
+}
+
+func (jr journalRecorder[RI]) restoredCert(cert CertifiedPrepareOrCommit) {
+	jr.record(JournalEntry[RI]{Kind: ocr3types.JournalEntryKindRestoredCert, Cert: cert})
+}
+
+func (jr journalRecorder[RI]) message(msg Message[RI], sender commontypes.OracleID) {
+	jr.record(JournalEntry[RI]{Kind: ocr3types.JournalEntryKindMessage, Sender: sender, Message: msg})
+}
+
+func (jr journalRecorder[RI]) event(ev interface{}) {
+	entry := JournalEntry[RI]{Kind: ocr3types.JournalEntryKindEvent}
+	switch ev := ev.(type) {
+	case EventNewEpochStart[RI]:
+		entry.Name = journalEventNewEpochStart
+		entry.Epoch = ev.Epoch
+		entry.Leader = ev.Leader
+	case EventCommittedOutcome[RI]:
+		entry.Name = journalEventCommittedOutcome
+		certifiedCommit := ev.CertifiedCommit
+		entry.Cert = &certifiedCommit
+	case EventProgress[RI]:
+		entry.Name = journalEventProgress
+	case EventNewEpochRequest[RI]:
+		entry.Name = journalEventNewEpochRequest
+	default:
+		// assertion
+		panic("unknown event type")
+	}
+	jr.record(entry)
+}
+
+func (jr journalRecorder[RI]) timer(name string, seqNr uint64) {
+	jr.record(JournalEntry[RI]{Kind: ocr3types.JournalEntryKindTimer, Name: name, SeqNr: seqNr})
+}
+
+func (jr journalRecorder[RI]) pluginCall(call ocr3types.JournalPluginCall[RI]) {
+	jr.record(JournalEntry[RI]{Kind: ocr3types.JournalEntryKindPluginCall, PluginCall: &call})
+}
+
+// randomness wraps r such that everything read from it is recorded.
+func (jr journalRecorder[RI]) randomness(r io.Reader) io.Reader {
+	if jr.journal == nil {
+		return r
+	}
+	return journalingReader[RI]{r, jr}
+}
+
+type journalingReader[RI any] struct {
+	reader io.Reader
+	jr     journalRecorder[RI]
+}
+
+func (r journalingReader[RI]) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.jr.record(JournalEntry[RI]{
+			Kind:       ocr3types.JournalEntryKindRandomness,
+			Randomness: append([]byte(nil), p[:n]...),
+		})
+	}
+	return n, err
+}
+
+// journalingReportingPlugin records all calls made by outcome generation and
+// report attestation to the wrapped ReportingPlugin.
+type journalingReportingPlugin[RI any] struct {
+	plugin ocr3types.ReportingPlugin[RI]
+	outgen journalRecorder[RI]
+	repatt journalRecorder[RI]
+}
+
+var _ ocr3types.ReportingPlugin[struct{}] = (*journalingReportingPlugin[struct{}])(nil)
+
+func newJournalingReportingPlugin[RI any](plugin ocr3types.ReportingPlugin[RI], journal Journal[RI], clock clock.Clock) ocr3types.ReportingPlugin[RI] {
+	if journal == nil {
+		return plugin
+	}
+	return &journalingReportingPlugin[RI]{
+		plugin,
+		journalRecorder[RI]{journal, clock, ocr3types.JournalSubprotocolOutcomeGeneration},
+		journalRecorder[RI]{journal, clock, ocr3types.JournalSubprotocolReportAttestation},
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (p *journalingReportingPlugin[RI]) Query(ctx context.Context, outctx ocr3types.OutcomeContext) (types.Query, error) {
+	query, err := p.plugin.Query(ctx, outctx)
+	p.outgen.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method:  "Query",
+		Inputs:  ocr3types.JournalPluginInputs{OutcomeContext: &outctx},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Query: query, Error: errorString(err)},
+	})
+	return query, err
+}
+
+func (p *journalingReportingPlugin[RI]) Observation(ctx context.Context, outctx ocr3types.OutcomeContext, query types.Query) (types.Observation, error) {
+	observation, err := p.plugin.Observation(ctx, outctx, query)
+	p.outgen.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method:  "Observation",
+		Inputs:  ocr3types.JournalPluginInputs{OutcomeContext: &outctx, Query: query},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Observation: observation, Error: errorString(err)},
+	})
+	return observation, err
+}
+
+func (p *journalingReportingPlugin[RI]) ValidateObservation(outctx ocr3types.OutcomeContext, query types.Query, ao types.AttributedObservation) error {
+	err := p.plugin.ValidateObservation(outctx, query, ao)
+	p.outgen.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method: "ValidateObservation",
+		Inputs: ocr3types.JournalPluginInputs{
+			OutcomeContext:         &outctx,
+			Query:                  query,
+			AttributedObservations: []types.AttributedObservation{ao},
+		},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Error: errorString(err)},
+	})
+	return err
+}
+
+func (p *journalingReportingPlugin[RI]) ObservationQuorum(outctx ocr3types.OutcomeContext, query types.Query) (ocr3types.Quorum, error) {
+	quorum, err := p.plugin.ObservationQuorum(outctx, query)
+	p.outgen.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method:  "ObservationQuorum",
+		Inputs:  ocr3types.JournalPluginInputs{OutcomeContext: &outctx, Query: query},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Quorum: quorum, Error: errorString(err)},
+	})
+	return quorum, err
+}
+
+func (p *journalingReportingPlugin[RI]) Outcome(outctx ocr3types.OutcomeContext, query types.Query, aos []types.AttributedObservation) (ocr3types.Outcome, error) {
+	outcome, err := p.plugin.Outcome(outctx, query, aos)
+	p.outgen.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method: "Outcome",
+		Inputs: ocr3types.JournalPluginInputs{
+			OutcomeContext:         &outctx,
+			Query:                  query,
+			AttributedObservations: aos,
+		},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Outcome: outcome, Error: errorString(err)},
+	})
+	return outcome, err
+}
+
+func (p *journalingReportingPlugin[RI]) Reports(seqNr uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportWithInfo[RI], error) {
+	reports, err := p.plugin.Reports(seqNr, outcome)
+	p.repatt.pluginCall(ocr3types.JournalPluginCall[RI]{
+		Method:  "Reports",
+		Inputs:  ocr3types.JournalPluginInputs{SeqNr: seqNr, Outcome: outcome},
+		Outputs: ocr3types.JournalPluginOutputs[RI]{Reports: reports, Error: errorString(err)},
+	})
+	return reports, err
+}
+
+func (p *journalingReportingPlugin[RI]) ShouldAcceptAttestedReport(ctx context.Context, seqNr uint64, rwi ocr3types.ReportWithInfo[RI]) (bool, error) {
+	return p.plugin.ShouldAcceptAttestedReport(ctx, seqNr, rwi)
+}
+
+func (p *journalingReportingPlugin[RI]) ShouldTransmitAcceptedReport(ctx context.Context, seqNr uint64, rwi ocr3types.ReportWithInfo[RI]) (bool, error) {
+	return p.plugin.ShouldTransmitAcceptedReport(ctx, seqNr, rwi)
+}
+
+func (p *journalingReportingPlugin[RI]) Close() error {
+	return p.plugin.Close()
+}
@@ -0,0 +1,515 @@
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/scheduler"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// JournalReplayOutbound is a message sent by the replayed oracle.
+type JournalReplayOutbound[RI any] struct {
+	Broadcast bool
+	// Only meaningful if Broadcast is false
+	To      commontypes.OracleID
+	Message Message[RI]
+}
+
+// JournalReplayResult describes the outcome of ReplayJournal.
+type JournalReplayResult[RI any] struct {
+	// State of outcome generation at the end of the replay
+	Epoch          uint64
+	Leader         commontypes.OracleID
+	SeqNr          uint64
+	CommittedSeqNr uint64
+	FollowerPhase  string
+	LeaderPhase    string
+
+	// Highest sequence number for which report attestation attested reports
+	// at the end of the replay
+	HighestAttestedSeqNr uint64
+
+	// Sequence numbers of the outcomes committed by outcome generation during
+	// the replay
+	CommittedSeqNrs []uint64
+	// Sequence numbers of the reports attested by report attestation during
+	// the replay, one entry per report
+	AttestedSeqNrs []uint64
+	// Messages sent during the replay
+	Outbound []JournalReplayOutbound[RI]
+
+	// Human-readable descriptions of points at which the replayed execution
+	// deviated from the recorded execution, e.g. because the protocol made a
+	// call to the ReportingPlugin that wasn't recorded.
+	Divergences []string
+}
+
+// ReplayJournal feeds the outcome generation and report attestation entries
+// of a journal back through fresh instances of outcomeGenerationState and
+// reportAttestationState. Calls to the ReportingPlugin are served from the
+// recorded plugin calls, random choices from the recorded randomness, timers
+// only fire when the journal says they did, and messages sent by the replayed
+// instances are captured instead of being sent.
+// Pacemaker entries are ignored, the pacemaker's effect on outcome generation
+// is captured by the recorded events.
+//
+// The protocol logs to logger as it normally would, which makes it possible
+// to reconstruct after the fact why, e.g., a proposal was refused.
+//
+// The keyrings must belong to the oracle that recorded the journal.
+func ReplayJournal[RI any](
+	ctx context.Context,
+
+	config ocr3config.SharedConfig,
+	entries []JournalEntry[RI],
+	id commontypes.OracleID,
+	localConfig types.LocalConfig,
+	logger loghelper.LoggerWithContext,
+	offchainKeyring types.OffchainKeyring,
+	onchainKeyring ocr3types.OnchainKeyring[RI],
+) JournalReplayResult[RI] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	logger = logger.MakeChild(commontypes.LogFields{"replay": true})
+
+	result := JournalReplayResult[RI]{}
+	var resultMutex sync.Mutex
+	diverged := func(format string, args ...interface{}) {
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		result.Divergences = append(result.Divergences, fmt.Sprintf(format, args...))
+	}
+
+	var restoredCert CertifiedPrepareOrCommit = &CertifiedCommit{}
+	var recordedCommittedSeqNrs []uint64
+	var calls []ocr3types.JournalPluginCall[RI]
+	var randomness []byte
+	for _, entry := range entries {
+		switch entry.Kind {
+		case ocr3types.JournalEntryKindRestoredCert:
+			if entry.Cert != nil {
+				restoredCert = entry.Cert
+			}
+		case ocr3types.JournalEntryKindPluginCall:
+			calls = append(calls, *entry.PluginCall)
+		case ocr3types.JournalEntryKindRandomness:
+			if entry.Subprotocol == ocr3types.JournalSubprotocolReportAttestation {
+				randomness = append(randomness, entry.Randomness...)
+			}
+		case ocr3types.JournalEntryKindEvent:
+			if entry.Subprotocol == ocr3types.JournalSubprotocolReportAttestation && entry.Name == journalEventCommittedOutcome {
+				if commit, ok := entry.Cert.(*CertifiedCommit); ok {
+					recordedCommittedSeqNrs = append(recordedCommittedSeqNrs, commit.SeqNr)
+				}
+			}
+		}
+	}
+
+	clk := clock.NewVirtual(clock.Real().Now())
+	if len(entries) != 0 {
+		clk = clock.NewVirtual(entries[0].Time)
+	}
+
+	plugin := &replayReportingPlugin[RI]{calls: calls, diverged: diverged}
+	replayedRandomness := &replayRandomness{randomness: randomness, diverged: diverged}
+	netSender := &replayNetworkSender[RI]{}
+
+	chOutcomeGenerationToPacemaker := make(chan EventToPacemaker[RI])
+	chOutcomeGenerationToReportAttestation := make(chan EventToReportAttestation[RI])
+	chReportAttestationToTransmission := make(chan EventToTransmission[RI])
+
+	var drainers sync.WaitGroup
+	drainers.Add(3)
+	go func() {
+		defer drainers.Done()
+		for range chOutcomeGenerationToPacemaker {
+		}
+	}()
+	go func() {
+		defer drainers.Done()
+		for ev := range chOutcomeGenerationToReportAttestation {
+			if ev, ok := ev.(EventCommittedOutcome[RI]); ok {
+				resultMutex.Lock()
+				result.CommittedSeqNrs = append(result.CommittedSeqNrs, ev.CertifiedCommit.SeqNr)
+				resultMutex.Unlock()
+			}
+		}
+	}()
+	go func() {
+		defer drainers.Done()
+		for ev := range chReportAttestationToTransmission {
+			if ev, ok := ev.(EventAttestedReport[RI]); ok {
+				resultMutex.Lock()
+				result.AttestedSeqNrs = append(result.AttestedSeqNrs, ev.SeqNr)
+				resultMutex.Unlock()
+			}
+		}
+	}()
+
+	// replayed rounds must not show up next to the live ones
+	tracer := noop.NewTracerProvider().Tracer("")
+
+	outgen := &outcomeGenerationState[RI]{
+		ctx: ctx,
+
+		chOutcomeGenerationToPacemaker:         chOutcomeGenerationToPacemaker,
+		chOutcomeGenerationToReportAttestation: chOutcomeGenerationToReportAttestation,
+		clock:                                  clk,
+		config:                                 config,
+		database:                               replayDatabase{},
+		id:                                     id,
+		localConfig:                            localConfig,
+		logger:                                 logger.MakeUpdated(commontypes.LogFields{"proto": "outgen"}),
+		metrics:                                newOutcomeGenerationMetrics(prometheus.NewRegistry(), logger),
+		netSender:                              netSender,
+		offchainKeyring:                        offchainKeyring,
+		reportingPlugin:                        plugin,
+		telemetrySender:                        replayTelemetrySender{},
+		tracer:                                 tracer,
+	}
+	outgen.initialize(restoredCert)
+
+	sched := scheduler.NewScheduler[EventMissingOutcome[RI]](clk)
+	repatt := newReportAttestationState[RI](
+		ctx,
+
+		nil,
+		nil,
+		chReportAttestationToTransmission,
+		clk,
+		config,
+		nil,
+		journalRecorder[RI]{},
+		logger,
+		prometheus.NewRegistry(),
+		netSender,
+		onchainKeyring,
+		replayedRandomness,
+		plugin,
+		sched,
+		nil,
+		tracer,
+	)
+
+	for i, entry := range entries {
+		if entry.Time.After(clk.Now()) {
+			clk.AdvanceTo(entry.Time)
+		}
+		if err := replayEntry(outgen, repatt, entry); err != nil {
+			diverged("entry %d (%v %v %v): %v", i, entry.Subprotocol, entry.Kind, entry.Name, err)
+			if _, ok := err.(replayPanic); ok {
+				break
+			}
+		}
+	}
+
+	// Outgen and repatt only send on these channels from within replayEntry,
+	// so we can close them now.
+	close(chOutcomeGenerationToPacemaker)
+	close(chOutcomeGenerationToReportAttestation)
+	close(chReportAttestationToTransmission)
+	drainers.Wait()
+	sched.Close()
+	outgen.metrics.Close()
+	repatt.metrics.Close()
+
+	for _, call := range plugin.calls[plugin.next:] {
+		diverged("recorded call to ReportingPlugin.%s was not replayed", call.Method)
+	}
+	if len(replayedRandomness.randomness) != 0 {
+		diverged("%d bytes of recorded randomness were not replayed", len(replayedRandomness.randomness))
+	}
+	if !reflect.DeepEqual(recordedCommittedSeqNrs, result.CommittedSeqNrs) {
+		diverged("recorded committed seqNrs %v differ from replayed committed seqNrs %v", recordedCommittedSeqNrs, result.CommittedSeqNrs)
+	}
+
+	result.Epoch = outgen.sharedState.e
+	result.Leader = outgen.sharedState.l
+	result.SeqNr = outgen.sharedState.seqNr
+	result.CommittedSeqNr = outgen.sharedState.committedSeqNr
+	result.FollowerPhase = string(outgen.followerState.phase)
+	result.LeaderPhase = string(outgen.leaderState.phase)
+	result.HighestAttestedSeqNr = repatt.highestAttestedSeqNr
+	result.Outbound = netSender.outbound
+	return result
+}
+
+type replayPanic struct {
+	value interface{}
+}
+
+func (p replayPanic) Error() string {
+	return fmt.Sprintf("protocol panicked: %v", p.value)
+}
+
+func replayEntry[RI any](outgen *outcomeGenerationState[RI], repatt *reportAttestationState[RI], entry JournalEntry[RI]) (err error) {
+	// A journal that doesn't match the protocol state (e.g. because it is
+	// incomplete) may violate assumptions the protocol makes about its
+	// inputs. We don't want to take down the caller in that case.
+	defer func() {
+		if r := recover(); r != nil {
+			err = replayPanic{r}
+		}
+	}()
+
+	switch entry.Subprotocol {
+	case ocr3types.JournalSubprotocolOutcomeGeneration:
+		switch entry.Kind {
+		case ocr3types.JournalEntryKindMessage:
+			msg, ok := entry.Message.(MessageToOutcomeGeneration[RI])
+			if !ok {
+				return fmt.Errorf("message of type %T is not for outcome generation", entry.Message)
+			}
+			outgen.messageToOutcomeGeneration(MessageToOutcomeGenerationWithSender[RI]{msg, entry.Sender})
+		case ocr3types.JournalEntryKindEvent:
+			if entry.Name != journalEventNewEpochStart {
+				return fmt.Errorf("unknown event")
+			}
+			outgen.eventNewEpochStart(EventNewEpochStart[RI]{entry.Epoch, entry.Leader})
+		case ocr3types.JournalEntryKindTimer:
+			switch entry.Name {
+			case journalTimerTInitial:
+				outgen.eventTInitialTimeout()
+			case journalTimerTGrace:
+				outgen.eventTGraceTimeout()
+			case journalTimerTRound:
+				outgen.eventTRoundTimeout()
+			default:
+				return fmt.Errorf("unknown timer")
+			}
+		}
+	case ocr3types.JournalSubprotocolReportAttestation:
+		switch entry.Kind {
+		case ocr3types.JournalEntryKindMessage:
+			msg, ok := entry.Message.(MessageToReportAttestation[RI])
+			if !ok {
+				return fmt.Errorf("message of type %T is not for report attestation", entry.Message)
+			}
+			msg.processReportAttestation(repatt, entry.Sender)
+		case ocr3types.JournalEntryKindEvent:
+			commit, ok := entry.Cert.(*CertifiedCommit)
+			if entry.Name != journalEventCommittedOutcome || !ok {
+				return fmt.Errorf("unknown event")
+			}
+			repatt.eventCommittedOutcome(EventCommittedOutcome[RI]{*commit, trace.SpanContext{}})
+		case ocr3types.JournalEntryKindTimer:
+			if entry.Name != journalTimerMissingOutcome {
+				return fmt.Errorf("unknown timer")
+			}
+			repatt.eventMissingOutcome(EventMissingOutcome[RI]{entry.SeqNr})
+		}
+	}
+	return nil
+}
+
+// replayReportingPlugin serves calls from recorded plugin calls, in the order
+// in which they were recorded.
+type replayReportingPlugin[RI any] struct {
+	mutex    sync.Mutex
+	calls    []ocr3types.JournalPluginCall[RI]
+	next     int
+	diverged func(format string, args ...interface{})
+}
+
+var _ ocr3types.ReportingPlugin[struct{}] = (*replayReportingPlugin[struct{}])(nil)
+
+func (p *replayReportingPlugin[RI]) call(method string, inputs ocr3types.JournalPluginInputs) (ocr3types.JournalPluginOutputs[RI], error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i := p.next; i < len(p.calls); i++ {
+		if p.calls[i].Method != method {
+			continue
+		}
+		for _, skipped := range p.calls[p.next:i] {
+			p.diverged("recorded call to ReportingPlugin.%s was skipped", skipped.Method)
+		}
+		p.next = i + 1
+		if !reflect.DeepEqual(normalizeJournalPluginInputs(p.calls[i].Inputs), normalizeJournalPluginInputs(inputs)) {
+			p.diverged("ReportingPlugin.%s was called with inputs %+v, but the recorded inputs are %+v", method, inputs, p.calls[i].Inputs)
+		}
+		var err error
+		if p.calls[i].Outputs.Error != "" {
+			err = fmt.Errorf("%s", p.calls[i].Outputs.Error)
+		}
+		return p.calls[i].Outputs, err
+	}
+
+	p.diverged("ReportingPlugin.%s was called, but there is no matching recorded call", method)
+	return ocr3types.JournalPluginOutputs[RI]{}, fmt.Errorf("no recorded call to %s", method)
+}
+
+// normalizeJournalPluginInputs maps empty byte slices to nil, since a
+// serialized journal doesn't distinguish between the two.
+func normalizeJournalPluginInputs(inputs ocr3types.JournalPluginInputs) ocr3types.JournalPluginInputs {
+	if inputs.OutcomeContext != nil {
+		outctx := *inputs.OutcomeContext
+		if len(outctx.PreviousOutcome) == 0 {
+			outctx.PreviousOutcome = nil
+		}
+		inputs.OutcomeContext = &outctx
+	}
+	if len(inputs.Query) == 0 {
+		inputs.Query = nil
+	}
+	if len(inputs.Outcome) == 0 {
+		inputs.Outcome = nil
+	}
+	if len(inputs.AttributedObservations) == 0 {
+		inputs.AttributedObservations = nil
+	} else {
+		aos := make([]types.AttributedObservation, 0, len(inputs.AttributedObservations))
+		for _, ao := range inputs.AttributedObservations {
+			if len(ao.Observation) == 0 {
+				ao.Observation = nil
+			}
+			aos = append(aos, ao)
+		}
+		inputs.AttributedObservations = aos
+	}
+	return inputs
+}
+
+func (p *replayReportingPlugin[RI]) Query(ctx context.Context, outctx ocr3types.OutcomeContext) (types.Query, error) {
+	outputs, err := p.call("Query", ocr3types.JournalPluginInputs{OutcomeContext: &outctx})
+	return outputs.Query, err
+}
+
+func (p *replayReportingPlugin[RI]) Observation(ctx context.Context, outctx ocr3types.OutcomeContext, query types.Query) (types.Observation, error) {
+	outputs, err := p.call("Observation", ocr3types.JournalPluginInputs{OutcomeContext: &outctx, Query: query})
+	return outputs.Observation, err
+}
+
+func (p *replayReportingPlugin[RI]) ValidateObservation(outctx ocr3types.OutcomeContext, query types.Query, ao types.AttributedObservation) error {
+	_, err := p.call("ValidateObservation", ocr3types.JournalPluginInputs{
+		OutcomeContext:         &outctx,
+		Query:                  query,
+		AttributedObservations: []types.AttributedObservation{ao},
+	})
+	return err
+}
+
+func (p *replayReportingPlugin[RI]) ObservationQuorum(outctx ocr3types.OutcomeContext, query types.Query) (ocr3types.Quorum, error) {
+	outputs, err := p.call("ObservationQuorum", ocr3types.JournalPluginInputs{OutcomeContext: &outctx, Query: query})
+	return outputs.Quorum, err
+}
+
+func (p *replayReportingPlugin[RI]) Outcome(outctx ocr3types.OutcomeContext, query types.Query, aos []types.AttributedObservation) (ocr3types.Outcome, error) {
+	outputs, err := p.call("Outcome", ocr3types.JournalPluginInputs{
+		OutcomeContext:         &outctx,
+		Query:                  query,
+		AttributedObservations: aos,
+	})
+	return outputs.Outcome, err
+}
+
+func (p *replayReportingPlugin[RI]) Reports(seqNr uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportWithInfo[RI], error) {
+	outputs, err := p.call("Reports", ocr3types.JournalPluginInputs{SeqNr: seqNr, Outcome: outcome})
+	return outputs.Reports, err
+}
+
+func (p *replayReportingPlugin[RI]) ShouldAcceptAttestedReport(context.Context, uint64, ocr3types.ReportWithInfo[RI]) (bool, error) {
+	return false, fmt.Errorf("ShouldAcceptAttestedReport is not journaled")
+}
+
+func (p *replayReportingPlugin[RI]) ShouldTransmitAcceptedReport(context.Context, uint64, ocr3types.ReportWithInfo[RI]) (bool, error) {
+	return false, fmt.Errorf("ShouldTransmitAcceptedReport is not journaled")
+}
+
+func (p *replayReportingPlugin[RI]) Close() error {
+	return nil
+}
+
+// replayRandomness serves reads from recorded randomness, in the order in
+// which it was recorded. Once the recorded randomness is exhausted, it falls
+// back to crypto/rand.
+type replayRandomness struct {
+	mutex      sync.Mutex
+	randomness []byte
+	diverged   func(format string, args ...interface{})
+}
+
+var _ io.Reader = (*replayRandomness)(nil)
+
+func (r *replayRandomness) Read(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.randomness) < len(p) {
+		r.diverged("protocol read %d bytes of randomness, but only %d recorded bytes are left", len(p), len(r.randomness))
+		r.randomness = nil
+		return rand.Read(p)
+	}
+	n := copy(p, r.randomness)
+	r.randomness = r.randomness[n:]
+	return n, nil
+}
+
+type replayNetworkSender[RI any] struct {
+	mutex    sync.Mutex
+	outbound []JournalReplayOutbound[RI]
+}
+
+var _ NetworkSender[struct{}] = (*replayNetworkSender[struct{}])(nil)
+
+func (s *replayNetworkSender[RI]) SendTo(msg Message[RI], to commontypes.OracleID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.outbound = append(s.outbound, JournalReplayOutbound[RI]{false, to, msg})
+}
+
+func (s *replayNetworkSender[RI]) Broadcast(msg Message[RI]) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.outbound = append(s.outbound, JournalReplayOutbound[RI]{true, 0, msg})
+}
+
+// replayDatabase discards all writes. Replays never read from the database.
+type replayDatabase struct{}
+
+var _ Database = replayDatabase{}
+
+func (replayDatabase) ReadConfig(ctx context.Context) (*types.ContractConfig, error) {
+	return nil, nil
+}
+
+func (replayDatabase) WriteConfig(ctx context.Context, config types.ContractConfig) error {
+	return nil
+}
+
+func (replayDatabase) ReadPacemakerState(ctx context.Context, configDigest types.ConfigDigest) (PacemakerState, error) {
+	return PacemakerState{}, nil
+}
+
+func (replayDatabase) WritePacemakerState(ctx context.Context, configDigest types.ConfigDigest, state PacemakerState) error {
+	return nil
+}
+
+func (replayDatabase) ReadCert(ctx context.Context, configDigest types.ConfigDigest) (CertifiedPrepareOrCommit, error) {
+	return nil, nil
+}
+
+func (replayDatabase) WriteCert(ctx context.Context, configDigest types.ConfigDigest, cert CertifiedPrepareOrCommit) error {
+	return nil
+}
+
+type replayTelemetrySender struct{}
+
+var _ TelemetrySender = replayTelemetrySender{}
+
+func (replayTelemetrySender) RoundStarted(types.ConfigDigest, uint64, uint64, uint64, commontypes.OracleID) {
+}
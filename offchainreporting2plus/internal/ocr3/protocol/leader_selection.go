@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/permutation"
+)
+
+// leaderSelector picks the leader of each epoch according to an
+// ocr3config.LeaderSelectionConfig. The leader only depends on the config and
+// the epoch, so all oracles agree on it.
+type leaderSelector struct {
+	key [16]byte
+	// slots contains every oracle as often as its weight
+	slots []commontypes.OracleID
+}
+
+func newLeaderSelector(config ocr3config.SharedConfig) *leaderSelector {
+	var slots []commontypes.OracleID
+	if len(config.LeaderSelection.Weights) == 0 {
+		for i := 0; i < config.N(); i++ {
+			slots = append(slots, commontypes.OracleID(i))
+		}
+	} else {
+		for i, w := range config.LeaderSelection.Weights {
+			for j := 0; j < w; j++ {
+				slots = append(slots, commontypes.OracleID(i))
+			}
+		}
+	}
+	return &leaderSelector{
+		config.LeaderSelectionKey(),
+		slots,
+	}
+}
+
+// leader returns the leader of epoch. Without weights, this is the same as
+// Leader(epoch, n, key).
+//
+// We cut the sequence of epochs into spans of len(slots) epochs and assign
+// the slots to the epochs of each span in a pseudo-random order.
+func (ls *leaderSelector) leader(epoch uint64) commontypes.OracleID {
+	w := uint64(len(ls.slots))
+	span := epoch / w
+	epochInSpan := epoch % w
+
+	mac := hmac.New(sha256.New, ls.key[:])
+	_ = binary.Write(mac, binary.BigEndian, span)
+
+	var permutationKey [16]byte
+	copy(permutationKey[:], mac.Sum(nil))
+	pi := permutation.Permutation(len(ls.slots), permutationKey)
+
+	return ls.slots[pi[epochInSpan]]
+}
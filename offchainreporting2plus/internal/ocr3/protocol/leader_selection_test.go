@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+)
+
+func sharedConfig(n int, weights []int) ocr3config.SharedConfig {
+	sharedSecret := [config.SharedSecretSize]byte{1, 2, 3}
+	return ocr3config.SharedConfig{
+		ocr3config.PublicConfig{
+			OracleIdentities: make([]config.OracleIdentity, n),
+			F:                1,
+			LeaderSelection:  ocr3config.LeaderSelectionConfig{weights},
+		},
+		&sharedSecret,
+	}
+}
+
+func TestLeaderSelectionDefaultMatchesLeader(t *testing.T) {
+	for _, n := range []int{4, 7, 31} {
+		cfg := sharedConfig(n, nil)
+		ls := newLeaderSelector(cfg)
+		for epoch := uint64(0); epoch < 1000; epoch++ {
+			if got, expected := ls.leader(epoch), Leader(epoch, n, cfg.LeaderSelectionKey()); got != expected {
+				t.Fatalf("n=%v epoch %v: leader %v, Leader returns %v", n, epoch, got, expected)
+			}
+		}
+	}
+}
+
+func TestLeaderSelectionWeights(t *testing.T) {
+	weights := []int{3, 0, 1, 2, 0}
+	total := 6
+	ls := newLeaderSelector(sharedConfig(len(weights), weights))
+
+	for span := uint64(0); span < 100; span++ {
+		counts := make([]int, len(weights))
+		for epoch := span * uint64(total); epoch < (span+1)*uint64(total); epoch++ {
+			counts[ls.leader(epoch)]++
+		}
+		// every span of len(slots) epochs picks every oracle exactly as
+		// often as its weight, and never those with weight 0
+		for i, w := range weights {
+			if counts[i] != w {
+				t.Fatalf("span %v picks oracle %v %v times, expected %v", span, i, counts[i], w)
+			}
+		}
+	}
+}
+
+func TestLeaderSelectionAgreement(t *testing.T) {
+	// each oracle builds its own leaderSelector from its copy of the config
+	weights := []int{1, 5, 0, 2}
+	oracles := []*leaderSelector{
+		newLeaderSelector(sharedConfig(len(weights), weights)),
+		newLeaderSelector(sharedConfig(len(weights), append([]int(nil), weights...))),
+	}
+	leaders := map[commontypes.OracleID]bool{}
+	for epoch := uint64(0); epoch < 1000; epoch++ {
+		leader := oracles[0].leader(epoch)
+		leaders[leader] = true
+		if other := oracles[1].leader(epoch); other != leader {
+			t.Fatalf("oracles disagree on leader of epoch %v: %v vs %v", epoch, leader, other)
+		}
+		// calling leader again or out of order doesn't change the result
+		if again := oracles[0].leader(epoch); again != leader {
+			t.Fatalf("leader of epoch %v changed from %v to %v", epoch, leader, again)
+		}
+	}
+	if len(leaders) != 3 {
+		t.Errorf("expected 3 distinct leaders, got %v", leaders)
+	}
+
+	// a different shared secret gives a different order
+	other := sharedConfig(len(weights), weights)
+	other.SharedSecret = &[config.SharedSecretSize]byte{4, 5, 6}
+	ls := newLeaderSelector(other)
+	differ := false
+	for epoch := uint64(0); epoch < 100; epoch++ {
+		if ls.leader(epoch) != oracles[0].leader(epoch) {
+			differ = true
+		}
+	}
+	if !differ {
+		t.Error("leaders don't depend on the shared secret")
+	}
+}
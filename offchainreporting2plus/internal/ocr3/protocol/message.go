@@ -7,6 +7,7 @@ import (
 	"github.com/smartcontractkit/libocr/internal/byzquorum"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EventToPacemaker[RI any] interface {
@@ -35,6 +36,8 @@ type EventToOutcomeGeneration[RI any] interface {
 
 type EventNewEpochStart[RI any] struct {
 	Epoch uint64
+	// Leader of the epoch, as picked by the pacemaker
+	Leader commontypes.OracleID
 }
 
 var _ EventToOutcomeGeneration[struct{}] = EventNewEpochStart[struct{}]{}
@@ -414,6 +417,8 @@ func (ev EventMissingOutcome[RI]) processReportAttestation(repatt *reportAttesta
 
 type EventCommittedOutcome[RI any] struct {
 	CertifiedCommit CertifiedCommit
+	// Span of the round that produced the outcome, invalid if there is none
+	SpanContext trace.SpanContext
 }
 
 var _ EventToReportAttestation[struct{}] = EventCommittedOutcome[struct{}]{} // implements EventToReportAttestation
@@ -426,6 +431,8 @@ type EventAttestedReport[RI any] struct {
 	SeqNr          uint64
 	Index          int
 	AttestedReport AttestedReportMany[RI]
+	// Span of the report attestation that produced the attested report
+	SpanContext trace.SpanContext
 }
 
 var _ EventToTransmission[struct{}] = EventAttestedReport[struct{}]{} // implements EventToTransmission
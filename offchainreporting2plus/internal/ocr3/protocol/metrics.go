@@ -1,11 +1,18 @@
 package protocol
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/metricshelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol/pool"
 )
 
+// Buckets for histograms measuring durations, from 1ms to ~33s
+var durationBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
 type pacemakerMetrics struct {
 	registerer prometheus.Registerer
 	epoch      prometheus.Gauge
@@ -39,9 +46,51 @@ func (pm *pacemakerMetrics) Close() {
 	pm.registerer.Unregister(pm.leader)
 }
 
+// Several subprotocols call the ReportingPlugin and verify signatures. Each of
+// them registers its own collectors for these metrics, distinguished by the
+// "subprotocol" label.
+
+func newReportingPluginDurationMetric(registerer prometheus.Registerer,
+	logger commontypes.Logger, subprotocol string) *prometheus.HistogramVec {
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "ocr3_reporting_plugin_duration_seconds",
+		Help:        "The duration of calls to the ReportingPlugin",
+		ConstLabels: prometheus.Labels{"subprotocol": subprotocol},
+		Buckets:     durationBuckets,
+	}, []string{"function", "success"})
+	metricshelper.RegisterOrLogError(logger, registerer, duration, "ocr3_reporting_plugin_duration_seconds")
+	return duration
+}
+
+func newSignatureVerificationFailuresMetric(registerer prometheus.Registerer,
+	logger commontypes.Logger, subprotocol string) *prometheus.CounterVec {
+
+	failures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "ocr3_signature_verification_failures_total",
+		Help:        "The total number of messages dropped because they carried invalid signatures, by sender",
+		ConstLabels: prometheus.Labels{"subprotocol": subprotocol},
+	}, []string{"message", "sender"})
+	metricshelper.RegisterOrLogError(logger, registerer, failures, "ocr3_signature_verification_failures_total")
+	return failures
+}
+
+func observeReportingPluginDuration(duration *prometheus.HistogramVec, function string, elapsed time.Duration, success bool) {
+	duration.WithLabelValues(function, strconv.FormatBool(success)).Observe(elapsed.Seconds())
+}
+
+func incSignatureVerificationFailures(failures *prometheus.CounterVec, message string, sender commontypes.OracleID) {
+	failures.WithLabelValues(message, strconv.Itoa(int(sender))).Inc()
+}
+
 type outcomeGenerationMetrics struct {
-	registerer     prometheus.Registerer
-	committedSeqNr prometheus.Gauge
+	registerer                    prometheus.Registerer
+	committedSeqNr                prometheus.Gauge
+	roundDuration                 prometheus.Histogram
+	observationsPerRound          prometheus.Histogram
+	poolEntries                   *prometheus.GaugeVec
+	signatureVerificationFailures *prometheus.CounterVec
+	reportingPluginDuration       *prometheus.HistogramVec
 }
 
 func newOutcomeGenerationMetrics(registerer prometheus.Registerer,
@@ -53,12 +102,145 @@ func newOutcomeGenerationMetrics(registerer prometheus.Registerer,
 	})
 	metricshelper.RegisterOrLogError(logger, registerer, committedSeqNr, "ocr3_committed_sequence_number")
 
+	roundDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr3_round_duration_seconds",
+		Help:    "The time from processing a MessageRoundStart to committing the round's outcome",
+		Buckets: durationBuckets,
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, roundDuration, "ocr3_round_duration_seconds")
+
+	observationsPerRound := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr3_observations_per_round",
+		Help:    "The number of observations contained in accepted proposals",
+		Buckets: prometheus.LinearBuckets(1, 3, 11),
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, observationsPerRound, "ocr3_observations_per_round")
+
+	poolEntries := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ocr3_pool_entries",
+		Help: "The number of messages held in the outcome generation pools, by pool and sender",
+	}, []string{"pool", "sender"})
+	metricshelper.RegisterOrLogError(logger, registerer, poolEntries, "ocr3_pool_entries")
+
 	return outcomeGenerationMetrics{
 		registerer,
 		committedSeqNr,
+		roundDuration,
+		observationsPerRound,
+		poolEntries,
+		newSignatureVerificationFailuresMetric(registerer, logger, "outgen"),
+		newReportingPluginDurationMetric(registerer, logger, "outgen"),
 	}
 }
 
 func (om *outcomeGenerationMetrics) Close() {
 	om.registerer.Unregister(om.committedSeqNr)
+	om.registerer.Unregister(om.roundDuration)
+	om.registerer.Unregister(om.observationsPerRound)
+	om.registerer.Unregister(om.poolEntries)
+	om.registerer.Unregister(om.signatureVerificationFailures)
+	om.registerer.Unregister(om.reportingPluginDuration)
+}
+
+func (outgen *outcomeGenerationState[RI]) updatePoolEntriesMetric() {
+	n := outgen.config.N()
+	setPoolEntries(outgen.metrics.poolEntries, "roundStart", outgen.followerState.roundStartPool, n)
+	setPoolEntries(outgen.metrics.poolEntries, "proposal", outgen.followerState.proposalPool, n)
+	setPoolEntries(outgen.metrics.poolEntries, "prepare", outgen.followerState.preparePool, n)
+	setPoolEntries(outgen.metrics.poolEntries, "commit", outgen.followerState.commitPool, n)
+}
+
+func setPoolEntries[T any](poolEntries *prometheus.GaugeVec, name string, p *pool.Pool[T], n int) {
+	if p == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		poolEntries.WithLabelValues(name, strconv.Itoa(i)).Set(float64(p.Count(commontypes.OracleID(i))))
+	}
+}
+
+type reportAttestationMetrics struct {
+	registerer                    prometheus.Registerer
+	commitToAttestedReport        prometheus.Histogram
+	signatureVerificationFailures *prometheus.CounterVec
+	reportingPluginDuration       *prometheus.HistogramVec
+}
+
+func newReportAttestationMetrics(registerer prometheus.Registerer,
+	logger commontypes.Logger) reportAttestationMetrics {
+
+	commitToAttestedReport := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr3_commit_to_attested_report_seconds",
+		Help:    "The time from receiving a committed outcome from outcome generation to having attested reports for it",
+		Buckets: durationBuckets,
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, commitToAttestedReport, "ocr3_commit_to_attested_report_seconds")
+
+	return reportAttestationMetrics{
+		registerer,
+		commitToAttestedReport,
+		newSignatureVerificationFailuresMetric(registerer, logger, "repatt"),
+		newReportingPluginDurationMetric(registerer, logger, "repatt"),
+	}
+}
+
+func (rm *reportAttestationMetrics) Close() {
+	rm.registerer.Unregister(rm.commitToAttestedReport)
+	rm.registerer.Unregister(rm.signatureVerificationFailures)
+	rm.registerer.Unregister(rm.reportingPluginDuration)
+}
+
+type transmissionMetrics struct {
+	registerer              prometheus.Registerer
+	transmitDuration        prometheus.Histogram
+	transmitFailures        prometheus.Counter
+	transmitRetries         prometheus.Counter
+	pendingTransmissions    prometheus.Gauge
+	reportingPluginDuration *prometheus.HistogramVec
+}
+
+func newTransmissionMetrics(registerer prometheus.Registerer,
+	logger commontypes.Logger) transmissionMetrics {
+
+	transmitDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr3_transmit_duration_seconds",
+		Help:    "The duration of calls to ContractTransmitter.Transmit",
+		Buckets: durationBuckets,
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, transmitDuration, "ocr3_transmit_duration_seconds")
+
+	transmitFailures := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ocr3_transmit_failures_total",
+		Help: "The total number of calls to ContractTransmitter.Transmit that returned an error",
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, transmitFailures, "ocr3_transmit_failures_total")
+
+	transmitRetries := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ocr3_transmit_retries_total",
+		Help: "The total number of retries scheduled after a failed attempt at transmitting a report",
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, transmitRetries, "ocr3_transmit_retries_total")
+
+	pendingTransmissions := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ocr3_pending_transmissions",
+		Help: "The number of reports accepted for transmission that have not been transmitted yet",
+	})
+	metricshelper.RegisterOrLogError(logger, registerer, pendingTransmissions, "ocr3_pending_transmissions")
+
+	return transmissionMetrics{
+		registerer,
+		transmitDuration,
+		transmitFailures,
+		transmitRetries,
+		pendingTransmissions,
+		newReportingPluginDurationMetric(registerer, logger, "transmission"),
+	}
+}
+
+func (tm *transmissionMetrics) Close() {
+	tm.registerer.Unregister(tm.transmitDuration)
+	tm.registerer.Unregister(tm.transmitFailures)
+	tm.registerer.Unregister(tm.transmitRetries)
+	tm.registerer.Unregister(tm.pendingTransmissions)
+	tm.registerer.Unregister(tm.reportingPluginDuration)
 }
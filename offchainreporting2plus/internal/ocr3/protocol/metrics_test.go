@@ -0,0 +1,113 @@
+package protocol
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol/pool"
+)
+
+// errorLogger remembers the errors logged to it.
+type errorLogger struct {
+	nopLogger
+	mutex  sync.Mutex
+	errors []string
+}
+
+func (l *errorLogger) Error(msg string, fields commontypes.LogFields) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.errors = append(l.errors, msg)
+}
+
+func TestMetricsRegisterAndUnregister(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	logger := &errorLogger{}
+
+	// all subprotocols of an instance share the registerer
+	pace := newPacemakerMetrics(registry, logger)
+	outgen := newOutcomeGenerationMetrics(registry, logger)
+	repatt := newReportAttestationMetrics(registry, logger)
+	transmission := newTransmissionMetrics(registry, logger)
+	if len(logger.errors) != 0 {
+		t.Fatalf("errors while registering metrics: %v", logger.errors)
+	}
+
+	observeReportingPluginDuration(outgen.reportingPluginDuration, "Outcome", 20*time.Millisecond, true)
+	observeReportingPluginDuration(repatt.reportingPluginDuration, "Reports", time.Millisecond, false)
+	observeReportingPluginDuration(transmission.reportingPluginDuration, "ShouldTransmitAcceptedReport", time.Millisecond, true)
+	incSignatureVerificationFailures(outgen.signatureVerificationFailures, "MessagePrepare", 3)
+	incSignatureVerificationFailures(outgen.signatureVerificationFailures, "MessagePrepare", 3)
+	incSignatureVerificationFailures(repatt.signatureVerificationFailures, "MessageReportSignatures", 1)
+	transmission.transmitFailures.Inc()
+
+	preparePool := pool.NewPool[struct{}](10)
+	preparePool.Put(1, 2, struct{}{})
+	preparePool.Put(2, 2, struct{}{})
+	setPoolEntries(outgen.poolEntries, "prepare", preparePool, 4)
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP ocr3_signature_verification_failures_total The total number of messages dropped because they carried invalid signatures, by sender
+# TYPE ocr3_signature_verification_failures_total counter
+ocr3_signature_verification_failures_total{message="MessagePrepare",sender="3",subprotocol="outgen"} 2
+ocr3_signature_verification_failures_total{message="MessageReportSignatures",sender="1",subprotocol="repatt"} 1
+# HELP ocr3_transmit_failures_total The total number of calls to ContractTransmitter.Transmit that returned an error
+# TYPE ocr3_transmit_failures_total counter
+ocr3_transmit_failures_total 1
+# HELP ocr3_pool_entries The number of messages held in the outcome generation pools, by pool and sender
+# TYPE ocr3_pool_entries gauge
+ocr3_pool_entries{pool="prepare",sender="0"} 0
+ocr3_pool_entries{pool="prepare",sender="1"} 0
+ocr3_pool_entries{pool="prepare",sender="2"} 2
+ocr3_pool_entries{pool="prepare",sender="3"} 0
+`), "ocr3_signature_verification_failures_total", "ocr3_transmit_failures_total", "ocr3_pool_entries"); err != nil {
+		t.Error(err)
+	}
+
+	// the reporting plugin duration is reported separately for each
+	// subprotocol, function and outcome
+	if count := testutil.CollectAndCount(outgen.reportingPluginDuration); count != 1 {
+		t.Errorf("outgen reported %v reporting plugin durations", count)
+	}
+	if count, err := testutil.GatherAndCount(registry, "ocr3_reporting_plugin_duration_seconds"); err != nil || count != 3 {
+		t.Errorf("gathered %v reporting plugin durations, error: %v", count, err)
+	}
+
+	pace.Close()
+	outgen.Close()
+	repatt.Close()
+	transmission.Close()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		t.Errorf("%v is still registered after closing", family.GetName())
+	}
+}
+
+func TestMetricsOfSuccessiveInstances(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	logger := &errorLogger{}
+
+	// a new protocol instance registers its metrics after the previous
+	// instance unregistered its own
+	for i := 0; i < 2; i++ {
+		outgen := newOutcomeGenerationMetrics(registry, logger)
+		repatt := newReportAttestationMetrics(registry, logger)
+		incSignatureVerificationFailures(repatt.signatureVerificationFailures, "MessageReportSignatures", 1)
+		if value := testutil.ToFloat64(repatt.signatureVerificationFailures.WithLabelValues("MessageReportSignatures", "1")); value != 1 {
+			t.Errorf("instance %v reports %v failures", i, value)
+		}
+		outgen.Close()
+		repatt.Close()
+	}
+	if len(logger.errors) != 0 {
+		t.Errorf("errors while registering metrics: %v", logger.errors)
+	}
+}
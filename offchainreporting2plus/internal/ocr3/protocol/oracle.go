@@ -3,15 +3,19 @@ package protocol
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // RunOracle runs one oracle instance of the offchain reporting protocol and manages
@@ -19,37 +23,58 @@ import (
 //
 // RunOracle runs forever until ctx is cancelled. It will only shut down
 // after all its sub-goroutines have exited.
+//
+// randomness is the source of the protocol's random choices, normally
+// crypto/rand.Reader. tracer may be nil, in which case no spans are emitted.
+// transmissionOutbox may be nil, in which case pending transmissions are lost
+// on restart.
 func RunOracle[RI any](
 	ctx context.Context,
 
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	contractTransmitter ocr3types.ContractTransmitter[RI],
 	database Database,
 	id commontypes.OracleID,
+	journal Journal[RI],
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
 	metricsRegisterer prometheus.Registerer,
 	netEndpoint NetworkEndpoint[RI],
 	offchainKeyring types.OffchainKeyring,
 	onchainKeyring ocr3types.OnchainKeyring[RI],
+	randomness io.Reader,
 	reportingPlugin ocr3types.ReportingPlugin[RI],
+	statusTracker *StatusTracker,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
+	transmissionOutbox TransmissionOutbox[RI],
 ) {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("")
+	}
+
 	o := oracleState[RI]{
 		ctx: ctx,
 
+		clock:               clock,
 		config:              config,
 		contractTransmitter: contractTransmitter,
 		database:            database,
 		id:                  id,
+		journal:             journal,
 		localConfig:         localConfig,
 		logger:              logger,
 		metricsRegisterer:   metricsRegisterer,
 		netEndpoint:         netEndpoint,
 		offchainKeyring:     offchainKeyring,
 		onchainKeyring:      onchainKeyring,
-		reportingPlugin:     reportingPlugin,
+		randomness:          randomness,
+		reportingPlugin:     newJournalingReportingPlugin(reportingPlugin, journal, clock),
+		statusTracker:       statusTracker,
 		telemetrySender:     telemetrySender,
+		tracer:              tracer,
+		transmissionOutbox:  transmissionOutbox,
 	}
 	o.run()
 }
@@ -57,18 +82,24 @@ func RunOracle[RI any](
 type oracleState[RI any] struct {
 	ctx context.Context
 
+	clock               clock.Clock
 	config              ocr3config.SharedConfig
 	contractTransmitter ocr3types.ContractTransmitter[RI]
 	database            Database
 	id                  commontypes.OracleID
+	journal             Journal[RI]
 	localConfig         types.LocalConfig
 	logger              loghelper.LoggerWithContext
 	metricsRegisterer   prometheus.Registerer
 	netEndpoint         NetworkEndpoint[RI]
 	offchainKeyring     types.OffchainKeyring
 	onchainKeyring      ocr3types.OnchainKeyring[RI]
+	randomness          io.Reader
 	reportingPlugin     ocr3types.ReportingPlugin[RI]
+	statusTracker       *StatusTracker
 	telemetrySender     TelemetrySender
+	tracer              trace.Tracer
+	transmissionOutbox  TransmissionOutbox[RI]
 
 	chNetToPacemaker         chan<- MessageToPacemakerWithSender[RI]
 	chNetToOutcomeGeneration chan<- MessageToOutcomeGenerationWithSender[RI]
@@ -142,6 +173,9 @@ func (o *oracleState[RI]) run() {
 	o.childCtx, o.childCancel = context.WithCancel(context.Background())
 	defer o.childCancel()
 
+	o.statusTracker.start(o.config.ConfigDigest, o.id)
+	defer o.statusTracker.stop()
+
 	paceState, cert, err := o.restoreFromDatabase()
 	if err != nil {
 		o.logger.Info("restoreFromDatabase returned an error, exiting oracle", commontypes.LogFields{
@@ -157,14 +191,17 @@ func (o *oracleState[RI]) run() {
 			chNetToPacemaker,
 			chPacemakerToOutcomeGeneration,
 			chOutcomeGenerationToPacemaker,
+			o.clock,
 			o.config,
 			o.database,
 			o.id,
+			o.journal,
 			o.localConfig,
 			o.logger,
 			o.metricsRegisterer,
 			o.netEndpoint,
 			o.offchainKeyring,
+			o.statusTracker,
 			o.telemetrySender,
 
 			paceState,
@@ -178,16 +215,20 @@ func (o *oracleState[RI]) run() {
 			chPacemakerToOutcomeGeneration,
 			chOutcomeGenerationToPacemaker,
 			chOutcomeGenerationToReportAttestation,
+			o.clock,
 			o.config,
 			o.database,
 			o.id,
+			o.journal,
 			o.localConfig,
 			o.logger,
 			o.metricsRegisterer,
 			o.netEndpoint,
 			o.offchainKeyring,
 			o.reportingPlugin,
+			o.statusTracker,
 			o.telemetrySender,
+			o.tracer,
 
 			cert,
 		)
@@ -200,12 +241,18 @@ func (o *oracleState[RI]) run() {
 			chNetToReportAttestation,
 			chOutcomeGenerationToReportAttestation,
 			chReportAttestationToTransmission,
+			o.clock,
 			o.config,
 			o.contractTransmitter,
+			o.journal,
 			o.logger,
+			o.metricsRegisterer,
 			o.netEndpoint,
 			o.onchainKeyring,
+			o.randomness,
 			o.reportingPlugin,
+			o.statusTracker,
+			o.tracer,
 		)
 	})
 	o.subprocesses.Go(func() {
@@ -214,12 +261,17 @@ func (o *oracleState[RI]) run() {
 			&o.subprocesses,
 
 			chReportAttestationToTransmission,
+			o.clock,
 			o.config,
 			o.contractTransmitter,
 			o.id,
 			o.localConfig,
 			o.logger,
+			o.metricsRegisterer,
 			o.reportingPlugin,
+			o.statusTracker,
+			o.tracer,
+			o.transmissionOutbox,
 		)
 	})
 
@@ -256,7 +308,7 @@ func (o *oracleState[RI]) run() {
 	}
 }
 
-func tryUntilSuccess[T any](ctx context.Context, logger commontypes.Logger, retryPeriod time.Duration, fnTimeout time.Duration, fnName string, fn func(context.Context) (T, error)) (T, error) {
+func tryUntilSuccess[T any](ctx context.Context, clock clock.Clock, logger commontypes.Logger, retryPeriod time.Duration, fnTimeout time.Duration, fnName string, fn func(context.Context) (T, error)) (T, error) {
 	for {
 		var result T
 		var err error
@@ -274,7 +326,7 @@ func tryUntilSuccess[T any](ctx context.Context, logger commontypes.Logger, retr
 		})
 
 		select {
-		case <-time.After(retryPeriod):
+		case <-clock.After(retryPeriod):
 		case <-ctx.Done():
 			var zero T
 			return zero, ctx.Err()
@@ -287,6 +339,7 @@ func (o *oracleState[RI]) restoreFromDatabase() (PacemakerState, CertifiedPrepar
 
 	paceState, err := tryUntilSuccess[PacemakerState](
 		o.ctx,
+		o.clock,
 		o.logger,
 		retryPeriod,
 		o.localConfig.DatabaseTimeout,
@@ -305,6 +358,7 @@ func (o *oracleState[RI]) restoreFromDatabase() (PacemakerState, CertifiedPrepar
 
 	cert, err := tryUntilSuccess[CertifiedPrepareOrCommit](
 		o.ctx,
+		o.clock,
 		o.logger,
 		retryPeriod,
 		o.localConfig.DatabaseTimeout,
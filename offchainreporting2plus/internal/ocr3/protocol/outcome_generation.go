@@ -3,14 +3,17 @@ package protocol
 import (
 	"context"
 	"github.com/prometheus/client_golang/prometheus"
+	"sort"
 	"time"
 
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol/pool"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Identifies an instance of the outcome generation protocol
@@ -29,16 +32,20 @@ func RunOutcomeGeneration[RI any](
 	chPacemakerToOutcomeGeneration <-chan EventToOutcomeGeneration[RI],
 	chOutcomeGenerationToPacemaker chan<- EventToPacemaker[RI],
 	chOutcomeGenerationToReportAttestation chan<- EventToReportAttestation[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	database Database,
 	id commontypes.OracleID,
+	journal Journal[RI],
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
 	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender[RI],
 	offchainKeyring types.OffchainKeyring,
 	reportingPlugin ocr3types.ReportingPlugin[RI],
+	statusTracker *StatusTracker,
 	telemetrySender TelemetrySender,
+	tracer trace.Tracer,
 
 	restoredCert CertifiedPrepareOrCommit,
 ) {
@@ -49,16 +56,20 @@ func RunOutcomeGeneration[RI any](
 		chPacemakerToOutcomeGeneration:         chPacemakerToOutcomeGeneration,
 		chOutcomeGenerationToPacemaker:         chOutcomeGenerationToPacemaker,
 		chOutcomeGenerationToReportAttestation: chOutcomeGenerationToReportAttestation,
+		clock:                                  clock,
 		config:                                 config,
 		database:                               database,
 		id:                                     id,
+		journal:                                journalRecorder[RI]{journal, clock, ocr3types.JournalSubprotocolOutcomeGeneration},
 		localConfig:                            localConfig,
 		logger:                                 logger.MakeUpdated(commontypes.LogFields{"proto": "outgen"}),
 		metrics:                                newOutcomeGenerationMetrics(metricsRegisterer, logger),
 		netSender:                              netSender,
 		offchainKeyring:                        offchainKeyring,
 		reportingPlugin:                        reportingPlugin,
+		statusTracker:                          statusTracker,
 		telemetrySender:                        telemetrySender,
+		tracer:                                 tracer,
 	}
 	outgen.run(restoredCert)
 }
@@ -70,21 +81,26 @@ type outcomeGenerationState[RI any] struct {
 	chPacemakerToOutcomeGeneration         <-chan EventToOutcomeGeneration[RI]
 	chOutcomeGenerationToPacemaker         chan<- EventToPacemaker[RI]
 	chOutcomeGenerationToReportAttestation chan<- EventToReportAttestation[RI]
+	clock                                  clock.Clock
 	config                                 ocr3config.SharedConfig
 	database                               Database
 	id                                     commontypes.OracleID
+	journal                                journalRecorder[RI]
 	localConfig                            types.LocalConfig
 	logger                                 loghelper.LoggerWithContext
 	metrics                                outcomeGenerationMetrics
 	netSender                              NetworkSender[RI]
 	offchainKeyring                        types.OffchainKeyring
 	reportingPlugin                        ocr3types.ReportingPlugin[RI]
+	statusTracker                          *StatusTracker
 	telemetrySender                        TelemetrySender
+	tracer                                 trace.Tracer
 
 	bufferedMessages []*MessageBuffer[RI]
 	leaderState      leaderState[RI]
 	followerState    followerState[RI]
 	sharedState      sharedState
+	roundSpan        roundSpan
 }
 
 type leaderState[RI any] struct {
@@ -113,6 +129,9 @@ type followerState[RI any] struct {
 	roundStartPool *pool.Pool[MessageRoundStart[RI]]
 
 	query *types.Query
+	// when we processed the MessageRoundStart for the current round, zero
+	// in case of a re-proposal
+	roundStartedAt time.Time
 
 	proposalPool *pool.Pool[MessageProposal[RI]]
 
@@ -146,6 +165,56 @@ type sharedState struct {
 func (outgen *outcomeGenerationState[RI]) run(restoredCert CertifiedPrepareOrCommit) {
 	outgen.logger.Info("OutcomeGeneration: running", nil)
 
+	outgen.journal.restoredCert(restoredCert)
+	outgen.initialize(restoredCert)
+	outgen.publishStatus()
+
+	// Event Loop
+	chDone := outgen.ctx.Done()
+	for {
+		select {
+		case msg := <-outgen.chNetToOutcomeGeneration:
+			outgen.journal.message(msg.msg, msg.sender)
+			outgen.messageToOutcomeGeneration(msg)
+		case ev := <-outgen.chPacemakerToOutcomeGeneration:
+			outgen.journal.event(ev)
+			ev.processOutcomeGeneration(outgen)
+		case <-outgen.followerState.tInitial:
+			outgen.journal.timer(journalTimerTInitial, 0)
+			outgen.eventTInitialTimeout()
+		case <-outgen.leaderState.tGrace:
+			outgen.journal.timer(journalTimerTGrace, 0)
+			outgen.eventTGraceTimeout()
+		case <-outgen.leaderState.tRound:
+			outgen.journal.timer(journalTimerTRound, 0)
+			outgen.eventTRoundTimeout()
+		case <-chDone:
+		}
+
+		outgen.publishStatus()
+
+		// ensure prompt exit
+		select {
+		case <-chDone:
+			outgen.logger.Info("OutcomeGeneration: winding down", commontypes.LogFields{
+				"e": outgen.sharedState.e,
+				"l": outgen.sharedState.l,
+			})
+			outgen.endRoundSpan(false)
+			outgen.metrics.Close()
+			outgen.logger.Info("OutcomeGeneration: exiting", commontypes.LogFields{
+				"e": outgen.sharedState.e,
+				"l": outgen.sharedState.l,
+			})
+			return
+		default:
+		}
+	}
+}
+
+// initialize sets up the state for a fresh instance that restored
+// restoredCert from the database.
+func (outgen *outcomeGenerationState[RI]) initialize(restoredCert CertifiedPrepareOrCommit) {
 	for i := 0; i < outgen.config.N(); i++ {
 		outgen.bufferedMessages = append(outgen.bufferedMessages, NewMessageBuffer[RI](futureMessageBufferSize))
 	}
@@ -166,6 +235,7 @@ func (outgen *outcomeGenerationState[RI]) run(restoredCert CertifiedPrepareOrCom
 		nil,
 		nil,
 		nil,
+		time.Time{},
 		nil,
 		outcomeAndDigests{},
 		restoredCert,
@@ -183,40 +253,6 @@ func (outgen *outcomeGenerationState[RI]) run(restoredCert CertifiedPrepareOrCom
 		0,
 		nil,
 	}
-
-	// Event Loop
-	chDone := outgen.ctx.Done()
-	for {
-		select {
-		case msg := <-outgen.chNetToOutcomeGeneration:
-			outgen.messageToOutcomeGeneration(msg)
-		case ev := <-outgen.chPacemakerToOutcomeGeneration:
-			ev.processOutcomeGeneration(outgen)
-		case <-outgen.followerState.tInitial:
-			outgen.eventTInitialTimeout()
-		case <-outgen.leaderState.tGrace:
-			outgen.eventTGraceTimeout()
-		case <-outgen.leaderState.tRound:
-			outgen.eventTRoundTimeout()
-		case <-chDone:
-		}
-
-		// ensure prompt exit
-		select {
-		case <-chDone:
-			outgen.logger.Info("OutcomeGeneration: winding down", commontypes.LogFields{
-				"e": outgen.sharedState.e,
-				"l": outgen.sharedState.l,
-			})
-			outgen.metrics.Close()
-			outgen.logger.Info("OutcomeGeneration: exiting", commontypes.LogFields{
-				"e": outgen.sharedState.e,
-				"l": outgen.sharedState.l,
-			})
-			return
-		default:
-		}
-	}
 }
 
 func (outgen *outcomeGenerationState[RI]) messageToOutcomeGeneration(msg MessageToOutcomeGenerationWithSender[RI]) {
@@ -275,8 +311,10 @@ func (outgen *outcomeGenerationState[RI]) eventNewEpochStart(ev EventNewEpochSta
 		"epoch": ev.Epoch,
 	})
 
+	outgen.endRoundSpan(false)
+
 	outgen.sharedState.e = ev.Epoch
-	outgen.sharedState.l = Leader(outgen.sharedState.e, outgen.config.N(), outgen.config.LeaderSelectionKey())
+	outgen.sharedState.l = ev.Leader
 
 	outgen.logger = outgen.logger.MakeUpdated(commontypes.LogFields{
 		"e": outgen.sharedState.e,
@@ -287,13 +325,14 @@ func (outgen *outcomeGenerationState[RI]) eventNewEpochStart(ev EventNewEpochSta
 	outgen.sharedState.seqNr = 0
 
 	outgen.followerState.phase = outgenFollowerPhaseNewEpoch
-	outgen.followerState.tInitial = time.After(outgen.config.DeltaInitial)
+	outgen.followerState.tInitial = outgen.clock.After(outgen.config.DeltaInitial)
 	outgen.followerState.outcome = outcomeAndDigests{}
 
 	outgen.followerState.roundStartPool = pool.NewPool[MessageRoundStart[RI]](poolSize)
 	outgen.followerState.proposalPool = pool.NewPool[MessageProposal[RI]](poolSize)
 	outgen.followerState.preparePool = pool.NewPool[PrepareSignature](poolSize)
 	outgen.followerState.commitPool = pool.NewPool[CommitSignature](poolSize)
+	outgen.updatePoolEntriesMetric()
 
 	outgen.leaderState.phase = outgenLeaderPhaseNewEpoch
 	outgen.leaderState.epochStartRequests = map[commontypes.OracleID]*epochStartRequest[RI]{}
@@ -327,7 +366,7 @@ func (outgen *outcomeGenerationState[RI]) eventNewEpochStart(ev EventNewEpochSta
 	}, outgen.sharedState.l)
 
 	if outgen.id == outgen.sharedState.l {
-		outgen.leaderState.tRound = time.After(outgen.config.DeltaRound)
+		outgen.leaderState.tRound = outgen.clock.After(outgen.config.DeltaRound)
 	}
 
 	outgen.unbufferMessages()
@@ -410,7 +449,9 @@ func callPluginFromOutcomeGeneration[T any, RI any](
 	f func(context.Context, ocr3types.OutcomeContext) (T, error),
 ) (T, bool) {
 	return callPlugin[T](
-		outgen.ctx,
+		outgen.roundContext(outgen.sharedState.e, outctx.SeqNr),
+		outgen.clock,
+		outgen.metrics.reportingPluginDuration,
 		outgen.logger,
 		commontypes.LogFields{
 			"seqNr": outctx.SeqNr,
@@ -418,8 +459,21 @@ func callPluginFromOutcomeGeneration[T any, RI any](
 		},
 		name,
 		maxDuration,
+		outgen.tracer,
 		func(ctx context.Context) (T, error) {
 			return f(ctx, outctx)
 		},
 	)
 }
+
+// sortedOracleIDs returns the keys of m in ascending order. Building messages
+// and certificates by iterating over maps in this order, rather than in Go's
+// randomized map order, makes an oracle's output depend only on its inputs.
+func sortedOracleIDs[V any](m map[commontypes.OracleID]V) []commontypes.OracleID {
+	oracleIDs := make([]commontypes.OracleID, 0, len(m))
+	for oracleID := range m {
+		oracleIDs = append(oracleIDs, oracleID)
+	}
+	sort.Slice(oracleIDs, func(i, j int) bool { return oracleIDs[i] < oracleIDs[j] })
+	return oracleIDs
+}
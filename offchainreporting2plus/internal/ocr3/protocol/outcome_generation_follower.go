@@ -2,11 +2,13 @@ package protocol
 
 import (
 	"context"
+	"time"
 
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol/pool"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type outgenFollowerPhase string
@@ -62,6 +64,7 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStart(msg MessageEpochStar
 			outgen.config.ByzQuorumSize(),
 		)
 		if err != nil {
+			incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageEpochStart", sender)
 			outgen.logger.Warn("dropping MessageEpochStart containing invalid StartRoundQuorumCertificate", commontypes.LogFields{
 				"error": err,
 			})
@@ -109,6 +112,7 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStart(msg MessageEpochStar
 		outgen.sharedState.observationQuorum = nil
 
 		outgen.followerState.phase = outgenFollowerPhaseSentPrepare
+		outgen.followerState.roundStartedAt = time.Time{}
 		outgen.followerState.outcome = outcomeAndDigests{
 			prepareQc.Outcome,
 			outcomeInputsDigest,
@@ -131,6 +135,7 @@ func (outgen *outcomeGenerationState[RI]) startSubsequentFollowerRound() {
 
 	outgen.followerState.phase = outgenFollowerPhaseNewRound
 	outgen.followerState.query = nil
+	outgen.followerState.roundStartedAt = time.Time{}
 	outgen.followerState.outcome = outcomeAndDigests{}
 
 	outgen.tryProcessRoundStartPool()
@@ -165,6 +170,8 @@ func (outgen *outcomeGenerationState[RI]) messageRoundStart(msg MessageRoundStar
 		return
 	}
 
+	outgen.updatePoolEntriesMetric()
+
 	outgen.logger.Debug("pooled MessageRoundStart", commontypes.LogFields{
 		"seqNr": outgen.sharedState.seqNr,
 	})
@@ -201,6 +208,7 @@ func (outgen *outcomeGenerationState[RI]) tryProcessRoundStartPool() {
 	msg := poolEntries[outgen.sharedState.l].Item
 
 	outgen.followerState.query = &msg.Query
+	outgen.followerState.roundStartedAt = outgen.clock.Now()
 
 	outctx := outgen.OutcomeCtx(outgen.sharedState.seqNr)
 
@@ -284,6 +292,8 @@ func (outgen *outcomeGenerationState[RI]) messageProposal(msg MessageProposal[RI
 		return
 	}
 
+	outgen.updatePoolEntriesMetric()
+
 	outgen.logger.Debug("pooled MessageProposal", commontypes.LogFields{
 		"seqNr": outgen.sharedState.seqNr,
 	})
@@ -352,6 +362,8 @@ func (outgen *outcomeGenerationState[RI]) tryProcessProposalPool() {
 			seen[aso.Observer] = true
 
 			if err := aso.SignedObservation.Verify(outgen.ID(), outgen.sharedState.seqNr, *outgen.followerState.query, outgen.config.OracleIdentities[aso.Observer].OffchainPublicKey); err != nil {
+				// the leader should have checked this signature, so we hold the leader responsible
+				incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageProposal", outgen.sharedState.l)
 				outgen.logger.Warn("dropping MessageProposal that contains signed observation with invalid signature", commontypes.LogFields{
 					"seqNr": outgen.sharedState.seqNr,
 					"error": err,
@@ -386,6 +398,8 @@ func (outgen *outcomeGenerationState[RI]) tryProcessProposalPool() {
 		}
 	}
 
+	outgen.metrics.observationsPerRound.Observe(float64(len(attributedObservations)))
+
 	outcomeInputsDigest := MakeOutcomeInputsDigest(
 		outgen.ID(),
 		outgen.sharedState.committedOutcome,
@@ -462,6 +476,8 @@ func (outgen *outcomeGenerationState[RI]) messagePrepare(msg MessagePrepare[RI],
 		return
 	}
 
+	outgen.updatePoolEntriesMetric()
+
 	outgen.logger.Debug("pooled MessagePrepare", commontypes.LogFields{
 		"sender":   sender,
 		"seqNr":    outgen.sharedState.seqNr,
@@ -500,6 +516,7 @@ func (outgen *outcomeGenerationState[RI]) tryProcessPreparePool() {
 		ok := err == nil
 		outgen.followerState.preparePool.StoreVerified(outgen.sharedState.seqNr, sender, ok)
 		if !ok {
+			incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessagePrepare", sender)
 			outgen.logger.Warn("dropping invalid MessagePrepare", commontypes.LogFields{
 				"sender": sender,
 				"seqNr":  outgen.sharedState.seqNr,
@@ -509,7 +526,8 @@ func (outgen *outcomeGenerationState[RI]) tryProcessPreparePool() {
 	}
 
 	var prepareQuorumCertificate []AttributedPrepareSignature
-	for sender, preparePoolEntry := range poolEntries {
+	for _, sender := range sortedOracleIDs(poolEntries) {
+		preparePoolEntry := poolEntries[sender]
 		if preparePoolEntry.Verified != nil && *preparePoolEntry.Verified {
 			prepareQuorumCertificate = append(prepareQuorumCertificate, AttributedPrepareSignature{
 				preparePoolEntry.Item,
@@ -583,6 +601,8 @@ func (outgen *outcomeGenerationState[RI]) messageCommit(msg MessageCommit[RI], s
 		return
 	}
 
+	outgen.updatePoolEntriesMetric()
+
 	outgen.logger.Debug("pooled MessageCommit", commontypes.LogFields{
 		"sender":   sender,
 		"seqNr":    outgen.sharedState.seqNr,
@@ -620,6 +640,7 @@ func (outgen *outcomeGenerationState[RI]) tryProcessCommitPool() {
 		ok := err == nil
 		commitPoolEntry.Verified = &ok
 		if !ok {
+			incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageCommit", sender)
 			outgen.logger.Warn("dropping invalid MessageCommit", commontypes.LogFields{
 				"sender": sender,
 				"seqNr":  outgen.sharedState.seqNr,
@@ -629,7 +650,8 @@ func (outgen *outcomeGenerationState[RI]) tryProcessCommitPool() {
 	}
 
 	var commitQuorumCertificate []AttributedCommitSignature
-	for sender, commitPoolEntry := range poolEntries {
+	for _, sender := range sortedOracleIDs(poolEntries) {
+		commitPoolEntry := poolEntries[sender]
 		if commitPoolEntry.Verified != nil && *commitPoolEntry.Verified {
 			commitQuorumCertificate = append(commitQuorumCertificate, AttributedCommitSignature{
 				commitPoolEntry.Item,
@@ -645,6 +667,10 @@ func (outgen *outcomeGenerationState[RI]) tryProcessCommitPool() {
 		return
 	}
 
+	if !outgen.followerState.roundStartedAt.IsZero() {
+		outgen.metrics.roundDuration.Observe(outgen.clock.Now().Sub(outgen.followerState.roundStartedAt).Seconds())
+	}
+
 	outgen.commit(CertifiedCommit{
 		outgen.sharedState.e,
 		outgen.sharedState.seqNr,
@@ -712,8 +738,15 @@ func (outgen *outcomeGenerationState[RI]) commit(commit CertifiedCommit) {
 			"seqNr": commit.SeqNr,
 		})
 
+		// If we committed the outcome of a round we took part in, continue
+		// that round's trace in report attestation.
+		var spanContext trace.SpanContext
+		if outgen.roundSpan.seqNr == commit.SeqNr {
+			spanContext = outgen.endRoundSpan(true)
+		}
+
 		select {
-		case outgen.chOutcomeGenerationToReportAttestation <- EventCommittedOutcome[RI]{commit}:
+		case outgen.chOutcomeGenerationToReportAttestation <- EventCommittedOutcome[RI]{commit, spanContext}:
 		case <-outgen.ctx.Done():
 			return
 		}
@@ -723,6 +756,7 @@ func (outgen *outcomeGenerationState[RI]) commit(commit CertifiedCommit) {
 	outgen.followerState.proposalPool.ReapCompleted(outgen.sharedState.committedSeqNr)
 	outgen.followerState.preparePool.ReapCompleted(outgen.sharedState.committedSeqNr)
 	outgen.followerState.commitPool.ReapCompleted(outgen.sharedState.committedSeqNr)
+	outgen.updatePoolEntriesMetric()
 }
 
 func (outgen *outcomeGenerationState[RI]) persistCert() (ok bool) {
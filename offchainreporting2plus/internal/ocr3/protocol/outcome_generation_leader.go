@@ -2,7 +2,6 @@ package protocol
 
 import (
 	"context"
-	"time"
 
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
@@ -57,6 +56,7 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStartRequest(msg MessageEp
 		outgen.ID(),
 		outgen.config.OracleIdentities[sender].OffchainPublicKey,
 	); err != nil {
+		incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageEpochStartRequest", sender)
 		outgen.leaderState.epochStartRequests[sender].bad = true
 		outgen.logger.Warn("MessageEpochStartRequest.SignedHighestCertifiedTimestamp is invalid", commontypes.LogFields{
 			"sender": sender,
@@ -79,7 +79,8 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStartRequest(msg MessageEp
 
 	goodCount := 0
 	var maxSender *commontypes.OracleID
-	for sender, epochStartRequest := range outgen.leaderState.epochStartRequests {
+	for _, sender := range sortedOracleIDs(outgen.leaderState.epochStartRequests) {
+		epochStartRequest := outgen.leaderState.epochStartRequests[sender]
 		if epochStartRequest.bad {
 			continue
 		}
@@ -112,6 +113,7 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStartRequest(msg MessageEp
 		outgen.config.OracleIdentities,
 		outgen.config.ByzQuorumSize(),
 	); err != nil {
+		incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageEpochStartRequest", *maxSender)
 		maxRequest.bad = true
 		outgen.logger.Warn("MessageEpochStartRequest.HighestCertified is invalid", commontypes.LogFields{
 			"sender": *maxSender,
@@ -122,7 +124,8 @@ func (outgen *outcomeGenerationState[RI]) messageEpochStartRequest(msg MessageEp
 
 	highestCertifiedProof := make([]AttributedSignedHighestCertifiedTimestamp, 0, outgen.config.ByzQuorumSize())
 	contributors := make([]commontypes.OracleID, 0, outgen.config.ByzQuorumSize())
-	for sender, epochStartRequest := range outgen.leaderState.epochStartRequests {
+	for _, sender := range sortedOracleIDs(outgen.leaderState.epochStartRequests) {
+		epochStartRequest := outgen.leaderState.epochStartRequests[sender]
 		if epochStartRequest.bad {
 			continue
 		}
@@ -211,7 +214,7 @@ func (outgen *outcomeGenerationState[RI]) startSubsequentLeaderRound() {
 
 	outgen.leaderState.observations = map[commontypes.OracleID]*SignedObservation{}
 
-	outgen.leaderState.tRound = time.After(outgen.config.DeltaRound)
+	outgen.leaderState.tRound = outgen.clock.After(outgen.config.DeltaRound)
 
 	outgen.leaderState.phase = outgenLeaderPhaseSentRoundStart
 	outgen.logger.Debug("broadcasting MessageRoundStart", commontypes.LogFields{
@@ -273,6 +276,7 @@ func (outgen *outcomeGenerationState[RI]) messageObservation(msg MessageObservat
 	}
 
 	if err := msg.SignedObservation.Verify(outgen.ID(), outgen.sharedState.seqNr, outgen.leaderState.query, outgen.config.OracleIdentities[sender].OffchainPublicKey); err != nil {
+		incSignatureVerificationFailures(outgen.metrics.signatureVerificationFailures, "MessageObservation", sender)
 		outgen.logger.Warn("dropping MessageObservation carrying invalid SignedObservation", commontypes.LogFields{
 			"sender": sender,
 			"seqNr":  outgen.sharedState.seqNr,
@@ -327,7 +331,7 @@ func (outgen *outcomeGenerationState[RI]) messageObservation(msg MessageObservat
 			"observationQuorum": quorum,
 		})
 		outgen.leaderState.phase = outgenLeaderPhaseGrace
-		outgen.leaderState.tGrace = time.After(outgen.config.DeltaGrace)
+		outgen.leaderState.tGrace = outgen.clock.After(outgen.config.DeltaGrace)
 	}
 }
 
@@ -341,8 +345,8 @@ func (outgen *outcomeGenerationState[RI]) eventTGraceTimeout() {
 	}
 	asos := make([]AttributedSignedObservation, 0, outgen.config.N())
 	contributors := make([]commontypes.OracleID, 0, outgen.config.N())
-	for oid, so := range outgen.leaderState.observations {
-		if so != nil {
+	for _, oid := range sortedOracleIDs(outgen.leaderState.observations) {
+		if so := outgen.leaderState.observations[oid]; so != nil {
 			asos = append(asos, AttributedSignedObservation{
 				*so,
 				commontypes.OracleID(oid),
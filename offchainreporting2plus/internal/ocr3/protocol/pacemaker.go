@@ -13,6 +13,8 @@ import (
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/permutation"
 )
@@ -23,14 +25,17 @@ func RunPacemaker[RI any](
 	chNetToPacemaker <-chan MessageToPacemakerWithSender[RI],
 	chPacemakerToOutcomeGeneration chan<- EventToOutcomeGeneration[RI],
 	chOutcomeGenerationToPacemaker <-chan EventToPacemaker[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	database Database,
 	id commontypes.OracleID,
+	journal Journal[RI],
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
 	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender[RI],
 	offchainKeyring types.OffchainKeyring,
+	statusTracker *StatusTracker,
 	telemetrySender TelemetrySender,
 
 	restoredState PacemakerState,
@@ -38,9 +43,9 @@ func RunPacemaker[RI any](
 	pace := makePacemakerState[RI](
 		ctx, chNetToPacemaker,
 		chPacemakerToOutcomeGeneration, chOutcomeGenerationToPacemaker,
-		config, database,
-		id, localConfig, logger, metricsRegisterer, netSender, offchainKeyring,
-		telemetrySender,
+		clock, config, database,
+		id, journal, localConfig, logger, metricsRegisterer, netSender, offchainKeyring,
+		statusTracker, telemetrySender,
 	)
 	pace.run(restoredState)
 }
@@ -50,13 +55,16 @@ func makePacemakerState[RI any](
 	chNetToPacemaker <-chan MessageToPacemakerWithSender[RI],
 	chPacemakerToOutcomeGeneration chan<- EventToOutcomeGeneration[RI],
 	chOutcomeGenerationToPacemaker <-chan EventToPacemaker[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	database Database, id commontypes.OracleID,
+	journal Journal[RI],
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
 	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender[RI],
 	offchainKeyring types.OffchainKeyring,
+	statusTracker *StatusTracker,
 	telemetrySender TelemetrySender,
 ) pacemakerState[RI] {
 	return pacemakerState[RI]{
@@ -65,17 +73,21 @@ func makePacemakerState[RI any](
 		chNetToPacemaker:               chNetToPacemaker,
 		chPacemakerToOutcomeGeneration: chPacemakerToOutcomeGeneration,
 		chOutcomeGenerationToPacemaker: chOutcomeGenerationToPacemaker,
+		clock:                          clock,
 		config:                         config,
 		database:                       database,
 		id:                             id,
+		journal:                        journalRecorder[RI]{journal, clock, ocr3types.JournalSubprotocolPacemaker},
 		localConfig:                    localConfig,
 		logger:                         logger.MakeUpdated(commontypes.LogFields{"proto": "pacemaker"}),
 		metrics:                        newPacemakerMetrics(metricsRegisterer, logger),
 		netSender:                      netSender,
 		offchainKeyring:                offchainKeyring,
+		statusTracker:                  statusTracker,
 		telemetrySender:                telemetrySender,
 
 		newEpochWishes: make([]uint64, config.N()),
+		leaderSelector: newLeaderSelector(config),
 	}
 }
 
@@ -85,14 +97,17 @@ type pacemakerState[RI any] struct {
 	chNetToPacemaker               <-chan MessageToPacemakerWithSender[RI]
 	chPacemakerToOutcomeGeneration chan<- EventToOutcomeGeneration[RI]
 	chOutcomeGenerationToPacemaker <-chan EventToPacemaker[RI]
+	clock                          clock.Clock
 	config                         ocr3config.SharedConfig
 	database                       Database
 	id                             commontypes.OracleID
+	journal                        journalRecorder[RI]
 	localConfig                    types.LocalConfig
 	logger                         loghelper.LoggerWithContext
 	metrics                        pacemakerMetrics
 	netSender                      NetworkSender[RI]
 	offchainKeyring                types.OffchainKeyring
+	statusTracker                  *StatusTracker
 	telemetrySender                TelemetrySender
 	// Test use only: send testBlocker an event to halt the pacemaker event loop,
 	// send testUnblocker an event to resume it.
@@ -109,6 +124,9 @@ type pacemakerState[RI any] struct {
 	// l is the index of the leader for the current epoch
 	l commontypes.OracleID
 
+	// leaderSelector picks l
+	leaderSelector *leaderSelector
+
 	// newEpochWishes[j] is the highest epoch number oracle j has sent in a
 	// NewEpochWish message
 	newEpochWishes []uint64
@@ -137,14 +155,16 @@ func (pace *pacemakerState[RI]) run(restoredState PacemakerState) {
 		pace.ne = restoredState.HighestSentNewEpochWish
 		pace.e = restoredState.Epoch
 	}
-	pace.l = Leader(pace.e, pace.config.N(), pace.config.LeaderSelectionKey())
+	pace.l = pace.leaderSelector.leader(pace.e)
 
-	pace.tProgress = time.After(pace.config.DeltaProgress)
+	pace.tProgress = pace.clock.After(pace.config.DeltaProgress)
 
 	pace.sendNewEpochWish()
 
 	pace.notifyOutcomeGenerationOfNewEpoch = true
 
+	pace.publishStatus()
+
 	// Initialization complete
 
 	// Take a reference to the ctx.Done channel once, here, to avoid taking the
@@ -161,21 +181,27 @@ func (pace *pacemakerState[RI]) run(restoredState PacemakerState) {
 		}
 
 		select {
-		case nilOrChPacemakerToOutcomeGeneration <- EventNewEpochStart[RI]{pace.e}:
+		case nilOrChPacemakerToOutcomeGeneration <- EventNewEpochStart[RI]{pace.e, pace.l}:
 			pace.notifyOutcomeGenerationOfNewEpoch = false
 		case msg := <-pace.chNetToPacemaker:
+			pace.journal.message(msg.msg, msg.sender)
 			msg.msg.processPacemaker(pace, msg.sender)
 		case ev := <-pace.chOutcomeGenerationToPacemaker:
+			pace.journal.event(ev)
 			ev.processPacemaker(pace)
 		case <-pace.tResend:
+			pace.journal.timer(journalTimerTResend, 0)
 			pace.eventTResendTimeout()
 		case <-pace.tProgress:
+			pace.journal.timer(journalTimerTProgress, 0)
 			pace.eventTProgressTimeout()
 		case <-pace.testBlocker:
 			<-pace.testUnblocker
 		case <-chDone:
 		}
 
+		pace.publishStatus()
+
 		// ensure prompt exit
 		select {
 		case <-chDone:
@@ -189,12 +215,12 @@ func (pace *pacemakerState[RI]) run(restoredState PacemakerState) {
 }
 
 func (pace *pacemakerState[RI]) eventProgress() {
-	pace.tProgress = time.After(pace.config.DeltaProgress)
+	pace.tProgress = pace.clock.After(pace.config.DeltaProgress)
 }
 
 func (pace *pacemakerState[RI]) sendNewEpochWish() {
 	pace.netSender.Broadcast(MessageNewEpochWish[RI]{pace.ne})
-	pace.tResend = time.After(pace.config.DeltaResend)
+	pace.tResend = pace.clock.After(pace.config.DeltaResend)
 }
 
 func (pace *pacemakerState[RI]) eventTResendTimeout() {
@@ -302,14 +328,14 @@ func (pace *pacemakerState[RI]) messageNewEpochWish(msg MessageNewEpochWish[RI],
 		pace.logger.Debug("moving to new epoch", commontypes.LogFields{
 			"newEpoch": switchToEpoch,
 		})
-		l := Leader(switchToEpoch, pace.config.N(), pace.config.LeaderSelectionKey())
+		l := pace.leaderSelector.leader(switchToEpoch)
 		pace.e, pace.l = switchToEpoch, l // (e, l) ← (ē, leader(ē))
 		if pace.ne < pace.e {             // ne ← max{ne, e}
 			pace.ne = pace.e
 		}
 		pace.metrics.epoch.Set(float64(pace.e))
 		pace.metrics.leader.Set(float64(pace.l))
-		pace.tProgress = time.After(pace.config.DeltaProgress) // restart timer T_{progress}
+		pace.tProgress = pace.clock.After(pace.config.DeltaProgress) // restart timer T_{progress}
 
 		pace.notifyOutcomeGenerationOfNewEpoch = true // invoke event newEpochStart(e, l)
 	}
@@ -340,7 +366,8 @@ func sortedGreaterThan(xs []uint64, y uint64) (rv []uint64) {
 	return rv
 }
 
-// Leader will produce an oracle id for the given epoch.
+// Leader will produce an oracle id for the given epoch. It doesn't take
+// LeaderSelectionConfig into account, see leaderSelector for that.
 func Leader(epoch uint64, n int, key [16]byte) (leader commontypes.OracleID) {
 	span := epoch / uint64(n)
 	epochInSpan := epoch % uint64(n)
@@ -100,3 +100,8 @@ func (p *Pool[M]) EntriesWithMinSeqNr() map[commontypes.OracleID]*Entry[M] {
 	}
 	return p.Entries(minSeqNr)
 }
+
+// Count returns the number of entries held for sender.
+func (p *Pool[M]) Count(sender commontypes.OracleID) int {
+	return p.count[sender]
+}
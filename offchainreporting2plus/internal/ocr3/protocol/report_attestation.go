@@ -3,6 +3,7 @@ package protocol
 import (
 	"context"
 	"crypto/rand"
+	"io"
 	"math"
 	"math/big"
 	"runtime"
@@ -10,12 +11,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/scheduler"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func RunReportAttestation[RI any](
@@ -24,19 +30,27 @@ func RunReportAttestation[RI any](
 	chNetToReportAttestation <-chan MessageToReportAttestationWithSender[RI],
 	chOutcomeGenerationToReportAttestation <-chan EventToReportAttestation[RI],
 	chReportAttestationToTransmission chan<- EventToTransmission[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	contractTransmitter ocr3types.ContractTransmitter[RI],
+	journal Journal[RI],
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender[RI],
 	onchainKeyring ocr3types.OnchainKeyring[RI],
+	randomness io.Reader,
 	reportingPlugin ocr3types.ReportingPlugin[RI],
+	statusTracker *StatusTracker,
+	tracer trace.Tracer,
 ) {
-	sched := scheduler.NewScheduler[EventMissingOutcome[RI]]()
+	sched := scheduler.NewScheduler[EventMissingOutcome[RI]](clock)
 	defer sched.Close()
 
+	jr := journalRecorder[RI]{journal, clock, ocr3types.JournalSubprotocolReportAttestation}
 	newReportAttestationState(ctx, chNetToReportAttestation,
 		chOutcomeGenerationToReportAttestation, chReportAttestationToTransmission,
-		config, contractTransmitter, logger, netSender, onchainKeyring, reportingPlugin, sched).run()
+		clock, config, contractTransmitter, jr,
+		logger, metricsRegisterer, netSender, onchainKeyring, jr.randomness(randomness), reportingPlugin, sched, statusTracker, tracer).run()
 }
 
 const expiryMinRounds int = 10
@@ -53,14 +67,21 @@ type reportAttestationState[RI any] struct {
 	chNetToReportAttestation               <-chan MessageToReportAttestationWithSender[RI]
 	chOutcomeGenerationToReportAttestation <-chan EventToReportAttestation[RI]
 	chReportAttestationToTransmission      chan<- EventToTransmission[RI]
+	clock                                  clock.Clock
 	config                                 ocr3config.SharedConfig
 	contractTransmitter                    ocr3types.ContractTransmitter[RI]
+	journal                                journalRecorder[RI]
 	logger                                 loghelper.LoggerWithContext
+	metrics                                reportAttestationMetrics
 	netSender                              NetworkSender[RI]
 	onchainKeyring                         ocr3types.OnchainKeyring[RI]
+	randomness                             io.Reader
 	reportingPlugin                        ocr3types.ReportingPlugin[RI]
 
-	scheduler *scheduler.Scheduler[EventMissingOutcome[RI]]
+	scheduler     *scheduler.Scheduler[EventMissingOutcome[RI]]
+	statusTracker *StatusTracker
+	tracer        trace.Tracer
+
 	// reap() is used to prevent unbounded state growth of rounds
 	rounds map[uint64]*round[RI]
 	// highest sequence number for which we have attested reports
@@ -76,6 +97,11 @@ type round[RI any] struct {
 	oracles         []oracle // always initialized to be of length n
 	startedFetch    bool
 	complete        bool
+	// when outcome generation handed us the committed outcome, zero if we
+	// fetched the certified commit from another oracle instead
+	committedAt time.Time
+	// started once we have the reports, ended once they are attested
+	span trace.Span
 }
 
 // oracle contains information about interactions with oracles (self & others)
@@ -93,19 +119,28 @@ func (repatt *reportAttestationState[RI]) run() {
 	for {
 		select {
 		case msg := <-repatt.chNetToReportAttestation:
+			repatt.journal.message(msg.msg, msg.sender)
 			msg.msg.processReportAttestation(repatt, msg.sender)
 		case ev := <-repatt.chOutcomeGenerationToReportAttestation:
+			repatt.journal.event(ev)
 			ev.processReportAttestation(repatt)
 		case ev := <-repatt.scheduler.Scheduled():
+			repatt.journal.timer(journalTimerMissingOutcome, ev.SeqNr)
 			ev.processReportAttestation(repatt)
 		case <-repatt.ctx.Done():
 		}
 
+		repatt.publishStatus()
+
 		// ensure prompt exit
 		select {
 		case <-repatt.ctx.Done():
 			repatt.logger.Info("ReportAttestation: exiting", nil)
 			repatt.scheduler.Close()
+			for _, round := range repatt.rounds {
+				round.abandonSpan()
+			}
+			repatt.metrics.Close()
 			return
 		default:
 		}
@@ -143,6 +178,8 @@ func (repatt *reportAttestationState[RI]) messageReportSignatures(
 			make([]oracle, repatt.config.N()),
 			false,
 			false,
+			time.Time{},
+			nil,
 		}
 	}
 
@@ -227,6 +264,7 @@ func (repatt *reportAttestationState[RI]) messageCertifiedCommit(msg MessageCert
 	}
 
 	if err := msg.CertifiedCommit.Verify(repatt.config.ConfigDigest, repatt.config.OracleIdentities, repatt.config.ByzQuorumSize()); err != nil {
+		incSignatureVerificationFailures(repatt.metrics.signatureVerificationFailures, "MessageCertifiedCommit", sender)
 		repatt.logger.Warn("dropping MessageCertifiedCommit with invalid certified commit", commontypes.LogFields{
 			"seqNr":  msg.CertifiedCommit.SeqNr,
 			"sender": sender,
@@ -239,7 +277,7 @@ func (repatt *reportAttestationState[RI]) messageCertifiedCommit(msg MessageCert
 		"sender": sender,
 	})
 
-	repatt.receivedCertifiedCommit(msg.CertifiedCommit)
+	repatt.receivedCertifiedCommit(msg.CertifiedCommit, trace.SpanContext{})
 }
 
 func (repatt *reportAttestationState[RI]) tryRequestCertifiedCommit(seqNr uint64) {
@@ -261,7 +299,7 @@ func (repatt *reportAttestationState[RI]) tryRequestCertifiedCommit(seqNr uint64
 		return
 	}
 
-	randomIndex, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
+	randomIndex, err := rand.Int(repatt.randomness, big.NewInt(int64(len(candidates))))
 	if err != nil {
 		repatt.logger.Critical("unexpected error returned by rand.Int", commontypes.LogFields{
 			"error": err,
@@ -324,6 +362,9 @@ func (repatt *reportAttestationState[RI]) tryComplete(seqNr uint64) {
 				oracle.signatures,
 			)
 			oracle.validSignatures = &validSignatures
+			if !validSignatures {
+				incSignatureVerificationFailures(repatt.metrics.signatureVerificationFailures, "MessageReportSignatures", commontypes.OracleID(oracleID))
+			}
 		}
 		if oracle.validSignatures != nil && *oracle.validSignatures {
 			goodSigs++
@@ -354,6 +395,16 @@ func (repatt *reportAttestationState[RI]) tryComplete(seqNr uint64) {
 	}
 
 	repatt.rounds[seqNr].complete = true
+	if committedAt := repatt.rounds[seqNr].committedAt; !committedAt.IsZero() {
+		repatt.metrics.commitToAttestedReport.Observe(repatt.clock.Now().Sub(committedAt).Seconds())
+	}
+
+	var spanContext trace.SpanContext
+	if span := repatt.rounds[seqNr].span; span != nil {
+		span.SetAttributes(attribute.Bool("ocr.attested", true))
+		span.End()
+		spanContext = span.SpanContext()
+	}
 
 	repatt.logger.Debug("sending attested reports to transmission protocol", commontypes.LogFields{
 		"seqNr":   seqNr,
@@ -369,6 +420,7 @@ func (repatt *reportAttestationState[RI]) tryComplete(seqNr uint64) {
 				reportsWithInfo[i],
 				aossPerReport[i],
 			},
+			spanContext,
 		}:
 		case <-repatt.ctx.Done():
 		}
@@ -427,10 +479,17 @@ func (repatt *reportAttestationState[RI]) verifySignatures(publicKey types.Oncha
 }
 
 func (repatt *reportAttestationState[RI]) eventCommittedOutcome(ev EventCommittedOutcome[RI]) {
-	repatt.receivedCertifiedCommit(ev.CertifiedCommit)
+	committedAt := repatt.clock.Now()
+	repatt.receivedCertifiedCommit(ev.CertifiedCommit, ev.SpanContext)
+	if round := repatt.rounds[ev.CertifiedCommit.SeqNr]; round != nil && round.committedAt.IsZero() {
+		round.committedAt = committedAt
+	}
 }
 
-func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommit CertifiedCommit) {
+// receivedCertifiedCommit continues the trace of the round that produced
+// certifiedCommit if roundSpanContext is valid, and starts a new trace
+// otherwise.
+func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommit CertifiedCommit, roundSpanContext trace.SpanContext) {
 	if repatt.rounds[certifiedCommit.SeqNr] != nil && repatt.rounds[certifiedCommit.SeqNr].reportsWithInfo != nil {
 		repatt.logger.Debug("dropping CertifiedCommit for which we already have reports", commontypes.LogFields{
 			"seqNr": certifiedCommit.SeqNr,
@@ -438,12 +497,21 @@ func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommi
 		return
 	}
 
+	ctx, span := repatt.tracer.Start(
+		trace.ContextWithSpanContext(repatt.ctx, roundSpanContext),
+		"ReportAttestation",
+		trace.WithAttributes(roundAttributes(repatt.config.ConfigDigest, certifiedCommit.Epoch(), certifiedCommit.SeqNr)...),
+	)
+
 	reportsWithInfo, ok := callPlugin[[]ocr3types.ReportWithInfo[RI]](
-		repatt.ctx,
+		ctx,
+		repatt.clock,
+		repatt.metrics.reportingPluginDuration,
 		repatt.logger,
 		commontypes.LogFields{"seqNr": certifiedCommit.SeqNr},
 		"Reports",
 		0, // Reports is a pure function and should finish "instantly"
+		repatt.tracer,
 		func(context.Context) ([]ocr3types.ReportWithInfo[RI], error) {
 			return repatt.reportingPlugin.Reports(
 				certifiedCommit.SeqNr,
@@ -452,6 +520,8 @@ func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommi
 		},
 	)
 	if !ok {
+		span.SetStatus(codes.Error, "ReportingPlugin.Reports failed")
+		span.End()
 		return
 	}
 
@@ -459,6 +529,7 @@ func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommi
 		repatt.logger.Info("ReportingPlugin.Reports returned no reports, skipping", commontypes.LogFields{
 			"seqNr": certifiedCommit.SeqNr,
 		})
+		span.End()
 		return
 	}
 
@@ -471,6 +542,8 @@ func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommi
 				"index": i,
 				"error": err,
 			})
+			span.SetStatus(codes.Error, "error while signing report")
+			span.End()
 			return
 		}
 		sigs = append(sigs, sig)
@@ -483,10 +556,13 @@ func (repatt *reportAttestationState[RI]) receivedCertifiedCommit(certifiedCommi
 			make([]oracle, repatt.config.N()),
 			false,
 			false,
+			time.Time{},
+			nil,
 		}
 	}
 	repatt.rounds[certifiedCommit.SeqNr].certifiedCommit = &certifiedCommit
 	repatt.rounds[certifiedCommit.SeqNr].reportsWithInfo = reportsWithInfo
+	repatt.rounds[certifiedCommit.SeqNr].span = span
 
 	repatt.logger.Debug("broadcasting MessageReportSignatures", commontypes.LogFields{
 		"seqNr": certifiedCommit.SeqNr,
@@ -535,6 +611,7 @@ func (repatt *reportAttestationState[RI]) reap() {
 	// https://go-review.googlesource.com/c/go/+/25049/
 	for seqNr := range repatt.rounds {
 		if repatt.isBeyondExpiry(seqNr) {
+			repatt.rounds[seqNr].abandonSpan()
 			delete(repatt.rounds, seqNr)
 		}
 	}
@@ -572,13 +649,19 @@ func newReportAttestationState[RI any](
 	chNetToReportAttestation <-chan MessageToReportAttestationWithSender[RI],
 	chOutcomeGenerationToReportAttestation <-chan EventToReportAttestation[RI],
 	chReportAttestationToTransmission chan<- EventToTransmission[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	contractTransmitter ocr3types.ContractTransmitter[RI],
+	journal journalRecorder[RI],
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	netSender NetworkSender[RI],
 	onchainKeyring ocr3types.OnchainKeyring[RI],
+	randomness io.Reader,
 	reportingPlugin ocr3types.ReportingPlugin[RI],
 	sched *scheduler.Scheduler[EventMissingOutcome[RI]],
+	statusTracker *StatusTracker,
+	tracer trace.Tracer,
 ) *reportAttestationState[RI] {
 	return &reportAttestationState[RI]{
 		ctx,
@@ -586,14 +669,20 @@ func newReportAttestationState[RI any](
 		chNetToReportAttestation,
 		chOutcomeGenerationToReportAttestation,
 		chReportAttestationToTransmission,
+		clock,
 		config,
 		contractTransmitter,
+		journal,
 		logger.MakeUpdated(commontypes.LogFields{"proto": "repatt"}),
+		newReportAttestationMetrics(metricsRegisterer, logger),
 		netSender,
 		onchainKeyring,
+		randomness,
 		reportingPlugin,
 
 		sched,
+		statusTracker,
+		tracer,
 		map[uint64]*round[RI]{},
 		0,
 		make([]uint64, config.N()),
@@ -0,0 +1,186 @@
+package protocol
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// StatusTracker collects the snapshots of their state that the subprotocols
+// of a running protocol instance publish, so that they can be inspected from
+// other goroutines. Subprotocols publish at the end of every iteration of
+// their event loop. Reading the status thus never waits on the protocol, even
+// if an event loop is stuck, e.g. in a call to the ReportingPlugin.
+//
+// A StatusTracker can be reused across protocol instances, but only one
+// instance may use it at a time. A nil *StatusTracker is valid and discards
+// all snapshots.
+type StatusTracker struct {
+	mutex        sync.Mutex
+	status       ocr3types.Status
+	peerStatuses func() []types.PeerStatus
+}
+
+func NewStatusTracker() *StatusTracker {
+	return &StatusTracker{}
+}
+
+// Status returns the latest snapshot.
+func (st *StatusTracker) Status() ocr3types.Status {
+	if st == nil {
+		return ocr3types.Status{}
+	}
+	st.mutex.Lock()
+	status := st.status
+	peerStatuses := st.peerStatuses
+	st.mutex.Unlock()
+
+	if status.Running && peerStatuses != nil {
+		status.Peers = peerStatuses()
+	}
+	return status
+}
+
+// SetPeerStatusSource sets the function used to fill in Status.Peers. Pass nil
+// to clear it.
+func (st *StatusTracker) SetPeerStatusSource(peerStatuses func() []types.PeerStatus) {
+	if st == nil {
+		return
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.peerStatuses = peerStatuses
+}
+
+func (st *StatusTracker) update(f func(status *ocr3types.Status)) {
+	if st == nil {
+		return
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	f(&st.status)
+}
+
+func (st *StatusTracker) start(configDigest types.ConfigDigest, id commontypes.OracleID) {
+	st.update(func(status *ocr3types.Status) {
+		*status = ocr3types.Status{
+			Running:      true,
+			ConfigDigest: configDigest,
+			OracleID:     id,
+		}
+	})
+}
+
+func (st *StatusTracker) stop() {
+	st.update(func(status *ocr3types.Status) {
+		*status = ocr3types.Status{}
+	})
+}
+
+func (st *StatusTracker) setPacemaker(pacemaker ocr3types.PacemakerStatus) {
+	st.update(func(status *ocr3types.Status) {
+		status.Pacemaker = pacemaker
+	})
+}
+
+func (st *StatusTracker) setOutcomeGeneration(outgen ocr3types.OutcomeGenerationStatus) {
+	st.update(func(status *ocr3types.Status) {
+		status.OutcomeGeneration = outgen
+	})
+}
+
+func (st *StatusTracker) setReportAttestation(repatt ocr3types.ReportAttestationStatus) {
+	st.update(func(status *ocr3types.Status) {
+		status.ReportAttestation = repatt
+	})
+}
+
+func (st *StatusTracker) setTransmission(transmission ocr3types.TransmissionStatus) {
+	st.update(func(status *ocr3types.Status) {
+		status.Transmission = transmission
+	})
+}
+
+func (pace *pacemakerState[RI]) publishStatus() {
+	pace.statusTracker.setPacemaker(ocr3types.PacemakerStatus{
+		pace.e,
+		pace.l,
+		pace.ne,
+	})
+}
+
+func (outgen *outcomeGenerationState[RI]) publishStatus() {
+	outgen.statusTracker.setOutcomeGeneration(ocr3types.OutcomeGenerationStatus{
+		outgen.sharedState.e,
+		outgen.sharedState.l,
+		string(outgen.leaderState.phase),
+		string(outgen.followerState.phase),
+		outgen.sharedState.firstSeqNrOfEpoch,
+		outgen.sharedState.seqNr,
+		outgen.sharedState.committedSeqNr,
+	})
+}
+
+func (repatt *reportAttestationState[RI]) publishStatus() {
+	if repatt.statusTracker == nil {
+		// avoid building a snapshot nobody will look at
+		return
+	}
+
+	rounds := make([]ocr3types.ReportAttestationRoundStatus, 0, len(repatt.rounds))
+	for seqNr, round := range repatt.rounds {
+		var signaturesFrom, validSignaturesFrom []int
+		for i, o := range round.oracles {
+			if o.signatures != nil {
+				signaturesFrom = append(signaturesFrom, i)
+			}
+			if o.validSignatures != nil && *o.validSignatures {
+				validSignaturesFrom = append(validSignaturesFrom, i)
+			}
+		}
+		rounds = append(rounds, ocr3types.ReportAttestationRoundStatus{
+			seqNr,
+			round.certifiedCommit != nil,
+			len(round.reportsWithInfo),
+			signaturesFrom,
+			validSignaturesFrom,
+			round.startedFetch,
+			round.complete,
+		})
+	}
+	sort.Slice(rounds, func(i, j int) bool {
+		return rounds[i].SeqNr < rounds[j].SeqNr
+	})
+
+	repatt.statusTracker.setReportAttestation(ocr3types.ReportAttestationStatus{
+		repatt.highestAttestedSeqNr,
+		rounds,
+	})
+}
+
+func (t *transmissionState[RI]) publishStatus() {
+	if t.statusTracker == nil {
+		// avoid collecting pending transmissions for nothing
+		return
+	}
+
+	pending := make([]ocr3types.PendingTransmission, 0, len(t.pending))
+	for key, pt := range t.pending {
+		_, transmitting := t.transmitting[key]
+		pending = append(pending, ocr3types.PendingTransmission{
+			pt.SeqNr,
+			pt.Index,
+			pt.Deadline,
+			pt.FailedAttempts,
+			transmitting,
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Deadline.Before(pending[j].Deadline)
+	})
+
+	t.statusTracker.setTransmission(ocr3types.TransmissionStatus{pending})
+}
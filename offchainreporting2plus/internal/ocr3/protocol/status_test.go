@@ -0,0 +1,67 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+func TestStatusTracker(t *testing.T) {
+	st := NewStatusTracker()
+	peers := []types.PeerStatus{{OracleID: 1, PeerID: "peer", Connected: true}}
+	peerStatusCalls := 0
+	st.SetPeerStatusSource(func() []types.PeerStatus {
+		peerStatusCalls++
+		return peers
+	})
+
+	// peers are only reported for a running instance
+	if status := st.Status(); status.Running || status.Peers != nil || peerStatusCalls != 0 {
+		t.Fatalf("unexpected status before start: %+v", status)
+	}
+
+	digest := types.ConfigDigest{0x00, 0x03, 0xaa}
+	st.start(digest, 2)
+	st.setPacemaker(ocr3types.PacemakerStatus{Epoch: 3, Leader: 1, HighestSentNewEpochWish: 4})
+	st.setOutcomeGeneration(ocr3types.OutcomeGenerationStatus{Epoch: 3, Leader: 1, SeqNr: 10, CommittedSeqNr: 9})
+	st.setReportAttestation(ocr3types.ReportAttestationStatus{HighestAttestedSeqNr: 8})
+	st.setTransmission(ocr3types.TransmissionStatus{Pending: []ocr3types.PendingTransmission{{SeqNr: 8}}})
+	status := st.Status()
+	if !status.Running || status.ConfigDigest != digest || status.OracleID != 2 {
+		t.Errorf("unexpected status after start: %+v", status)
+	}
+	if status.Pacemaker.HighestSentNewEpochWish != 4 || status.OutcomeGeneration.SeqNr != 10 ||
+		status.ReportAttestation.HighestAttestedSeqNr != 8 || len(status.Transmission.Pending) != 1 {
+		t.Errorf("unexpected subprotocol status: %+v", status)
+	}
+	if len(status.Peers) != 1 || status.Peers[0] != peers[0] {
+		t.Errorf("unexpected peers %+v", status.Peers)
+	}
+
+	st.SetPeerStatusSource(nil)
+	if status := st.Status(); status.Peers != nil {
+		t.Errorf("unexpected peers after clearing source: %+v", status.Peers)
+	}
+
+	// stopping resets everything, a new instance starts from scratch
+	st.stop()
+	if status := st.Status(); status.Running || status.OutcomeGeneration.SeqNr != 0 {
+		t.Errorf("unexpected status after stop: %+v", status)
+	}
+	st.start(types.ConfigDigest{0x00, 0x03, 0xbb}, 2)
+	if status := st.Status(); status.Pacemaker.Epoch != 0 || status.Transmission.Pending != nil {
+		t.Errorf("status of previous instance leaked: %+v", status)
+	}
+}
+
+func TestNilStatusTracker(t *testing.T) {
+	var st *StatusTracker
+	st.SetPeerStatusSource(func() []types.PeerStatus { return nil })
+	st.start(types.ConfigDigest{}, 1)
+	st.setOutcomeGeneration(ocr3types.OutcomeGenerationStatus{SeqNr: 1})
+	if status := st.Status(); status.Running {
+		t.Errorf("nil tracker reported status %+v", status)
+	}
+	st.stop()
+}
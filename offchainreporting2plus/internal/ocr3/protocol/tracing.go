@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"context"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Each round produces one trace per oracle: a root OutcomeGeneration span for
+// the (epoch, seqNr) pair, a ReportAttestation child span for the committed
+// outcome, and Transmission child spans for each attested report. Calls to the
+// ReportingPlugin and ContractTransmitter get their own child spans, and the
+// ctx passed to them carries these spans so that plugins can attach their own.
+
+const (
+	attributeConfigDigest = attribute.Key("ocr.config_digest")
+	attributeEpoch        = attribute.Key("ocr.epoch")
+	attributeLeader       = attribute.Key("ocr.leader")
+	attributeSeqNr        = attribute.Key("ocr.seq_nr")
+	attributeReportIndex  = attribute.Key("ocr.report_index")
+)
+
+func roundAttributes(configDigest types.ConfigDigest, epoch uint64, seqNr uint64) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attributeConfigDigest.String(configDigest.Hex()),
+		attributeEpoch.Int64(int64(epoch)),
+		attributeSeqNr.Int64(int64(seqNr)),
+	}
+}
+
+type roundSpan struct {
+	span  trace.Span
+	epoch uint64
+	seqNr uint64
+}
+
+// roundContext returns outgen.ctx carrying the span for the given round,
+// starting the span if needed. A span for a different round that is still
+// open is ended, since that round won't be committed by this instance anymore.
+func (outgen *outcomeGenerationState[RI]) roundContext(epoch uint64, seqNr uint64) context.Context {
+	if outgen.roundSpan.span == nil || outgen.roundSpan.epoch != epoch || outgen.roundSpan.seqNr != seqNr {
+		outgen.endRoundSpan(false)
+		_, span := outgen.tracer.Start(outgen.ctx, "OutcomeGeneration",
+			trace.WithAttributes(roundAttributes(outgen.config.ConfigDigest, epoch, seqNr)...),
+			trace.WithAttributes(attributeLeader.Int(int(outgen.sharedState.l))),
+		)
+		outgen.roundSpan = roundSpan{span, epoch, seqNr}
+	}
+	return trace.ContextWithSpan(outgen.ctx, outgen.roundSpan.span)
+}
+
+// endRoundSpan ends the span of the current round, if any, and returns its
+// SpanContext.
+func (outgen *outcomeGenerationState[RI]) endRoundSpan(committed bool) trace.SpanContext {
+	if outgen.roundSpan.span == nil {
+		return trace.SpanContext{}
+	}
+	spanContext := outgen.roundSpan.span.SpanContext()
+	outgen.roundSpan.span.SetAttributes(attribute.Bool("ocr.committed", committed))
+	outgen.roundSpan.span.End()
+	outgen.roundSpan = roundSpan{}
+	return spanContext
+}
+
+// abandonSpan ends the span of a round that was never attested, e.g. because it
+// expired or the protocol instance is shutting down.
+func (rnd *round[RI]) abandonSpan() {
+	if rnd.span == nil || rnd.complete {
+		return
+	}
+	rnd.span.SetAttributes(attribute.Bool("ocr.attested", false))
+	rnd.span.End()
+}
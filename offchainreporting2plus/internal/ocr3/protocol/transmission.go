@@ -7,14 +7,18 @@ import (
 	"encoding/binary"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/scheduler"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/permutation"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const ContractTransmitterTimeoutWarningGracePeriod = 50 * time.Millisecond
@@ -24,14 +28,19 @@ func RunTransmission[RI any](
 	subprocesses *subprocesses.Subprocesses,
 
 	chReportAttestationToTransmission <-chan EventToTransmission[RI],
+	clock clock.Clock,
 	config ocr3config.SharedConfig,
 	contractTransmitter ocr3types.ContractTransmitter[RI],
 	id commontypes.OracleID,
 	localConfig types.LocalConfig,
 	logger loghelper.LoggerWithContext,
+	metricsRegisterer prometheus.Registerer,
 	reportingPlugin ocr3types.ReportingPlugin[RI],
+	statusTracker *StatusTracker,
+	tracer trace.Tracer,
+	transmissionOutbox TransmissionOutbox[RI],
 ) {
-	sched := scheduler.NewScheduler[EventAttestedReport[RI]]()
+	sched := scheduler.NewScheduler[EventAttestedReport[RI]](clock)
 	defer sched.Close()
 
 	t := transmissionState[RI]{
@@ -39,14 +48,24 @@ func RunTransmission[RI any](
 		subprocesses,
 
 		chReportAttestationToTransmission,
+		make(chan transmitResult),
+		clock,
 		config,
 		contractTransmitter,
 		id,
 		localConfig,
 		logger.MakeUpdated(commontypes.LogFields{"proto": "transmission"}),
+		newTransmissionMetrics(metricsRegisterer, logger),
 		reportingPlugin,
+		transmissionOutbox,
 
 		sched,
+		statusTracker,
+		tracer,
+
+		map[transmissionKey]*PendingTransmission[RI]{},
+		nil,
+		map[transmissionKey]struct{}{},
 	}
 	t.run()
 }
@@ -57,20 +76,58 @@ type transmissionState[RI any] struct {
 	subprocesses *subprocesses.Subprocesses
 
 	chReportAttestationToTransmission <-chan EventToTransmission[RI]
+	chTransmitResult                  chan transmitResult
+	clock                             clock.Clock
 	config                            ocr3config.SharedConfig
 	contractTransmitter               ocr3types.ContractTransmitter[RI]
 	id                                commontypes.OracleID
 	localConfig                       types.LocalConfig
 	logger                            loghelper.LoggerWithContext
+	metrics                           transmissionMetrics
 	reportingPlugin                   ocr3types.ReportingPlugin[RI]
+	transmissionOutbox                TransmissionOutbox[RI] // may be nil
+
+	scheduler     *scheduler.Scheduler[EventAttestedReport[RI]]
+	statusTracker *StatusTracker
+	tracer        trace.Tracer
+
+	// all reports accepted for transmission that haven't been transmitted
+	// yet, whether they are scheduled, queued, or being transmitted
+	pending map[transmissionKey]*PendingTransmission[RI]
+	// reports that are due, but wait for an ongoing transmission to finish
+	queue []EventAttestedReport[RI]
+	// reports currently being transmitted
+	transmitting map[transmissionKey]struct{}
+}
 
-	scheduler *scheduler.Scheduler[EventAttestedReport[RI]]
+type transmissionKey struct {
+	seqNr uint64
+	index int
+}
+
+type transmitOutcome int
+
+const (
+	_ transmitOutcome = iota
+	// the report was transmitted
+	transmitOutcomeTransmitted
+	// the ReportingPlugin decided against transmitting the report
+	transmitOutcomeDeclined
+	// the attempt at transmitting the report failed and may be retried
+	transmitOutcomeFailed
+)
+
+type transmitResult struct {
+	key     transmissionKey
+	outcome transmitOutcome
 }
 
 // run runs the event loop for the local transmission protocol
 func (t *transmissionState[RI]) run() {
 	t.logger.Info("Transmission: running", nil)
 
+	t.restorePendingTransmissions()
+
 	chDone := t.ctx.Done()
 	for {
 		select {
@@ -78,13 +135,18 @@ func (t *transmissionState[RI]) run() {
 			ev.processTransmission(t)
 		case ev := <-t.scheduler.Scheduled():
 			t.scheduled(ev)
+		case result := <-t.chTransmitResult:
+			t.transmitted(result)
 		case <-chDone:
 		}
 
+		t.publishStatus()
+
 		// ensure prompt exit
 		select {
 		case <-chDone:
 			t.logger.Info("Transmission: exiting", nil)
+			t.metrics.Close()
 			return
 		default:
 		}
@@ -92,10 +154,15 @@ func (t *transmissionState[RI]) run() {
 }
 
 func (t *transmissionState[RI]) eventAttestedReport(ev EventAttestedReport[RI]) {
-	now := time.Now()
+	now := t.clock.Now()
+
+	ctx, span := t.startSpan("Transmission.accept", ev)
+	defer span.End()
 
 	shouldAccept, ok := callPlugin[bool](
-		t.ctx,
+		ctx,
+		t.clock,
+		t.metrics.reportingPluginDuration,
 		t.logger,
 		commontypes.LogFields{
 			"seqNr": ev.SeqNr,
@@ -103,6 +170,7 @@ func (t *transmissionState[RI]) eventAttestedReport(ev EventAttestedReport[RI])
 		},
 		"ShouldAcceptAttestedReport",
 		t.config.MaxDurationShouldAcceptAttestedReport,
+		t.tracer,
 		func(ctx context.Context) (bool, error) {
 			return t.reportingPlugin.ShouldAcceptAttestedReport(
 				ctx,
@@ -133,17 +201,223 @@ func (t *transmissionState[RI]) eventAttestedReport(ev EventAttestedReport[RI])
 	}
 	delay := *delayMaybe
 
+	key := transmissionKey{ev.SeqNr, ev.Index}
+	if _, ok := t.pending[key]; ok {
+		t.logger.Debug("dropping EventAttestedReport because report is already pending transmission", commontypes.LogFields{
+			"seqNr": ev.SeqNr,
+			"index": ev.Index,
+		})
+		return
+	}
+
 	t.logger.Debug("accepted AttestedReport for transmission", commontypes.LogFields{
 		"seqNr": ev.SeqNr,
 		"index": ev.Index,
 		"delay": delay.String(),
 	})
-	t.scheduler.ScheduleDeadline(ev, now.Add(delay))
+	deadline := now.Add(delay)
+	t.pending[key] = &PendingTransmission[RI]{
+		ev.SeqNr,
+		ev.Index,
+		ev.AttestedReport,
+		deadline,
+		0,
+	}
+	t.persistPendingTransmission(t.pending[key])
+	t.scheduler.ScheduleDeadline(ev, deadline)
+}
+
+// restorePendingTransmissions schedules the pending transmissions persisted
+// by a previous run.
+func (t *transmissionState[RI]) restorePendingTransmissions() {
+	if t.transmissionOutbox == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.localConfig.DatabaseTimeout)
+	defer cancel()
+	pendingTransmissions, err := t.transmissionOutbox.ReadPendingTransmissions(ctx, t.config.ConfigDigest)
+	if err != nil {
+		t.logger.Error("Transmission: error reading pending transmissions from database", commontypes.LogFields{
+			"error": err,
+		})
+		return
+	}
+
+	for i := range pendingTransmissions {
+		pt := pendingTransmissions[i]
+		t.pending[transmissionKey{pt.SeqNr, pt.Index}] = &pt
+		t.scheduler.ScheduleDeadline(EventAttestedReport[RI]{
+			pt.SeqNr,
+			pt.Index,
+			pt.AttestedReport,
+			trace.SpanContext{},
+		}, pt.Deadline)
+	}
+	if len(pendingTransmissions) > 0 {
+		t.logger.Info("Transmission: restored pending transmissions from database", commontypes.LogFields{
+			"count": len(pendingTransmissions),
+		})
+	}
+	t.metrics.pendingTransmissions.Set(float64(len(t.pending)))
+}
+
+// persistPendingTransmission writes pt to the outbox. Failures are logged,
+// but otherwise ignored: we can still transmit the report as long as we don't
+// restart.
+func (t *transmissionState[RI]) persistPendingTransmission(pt *PendingTransmission[RI]) {
+	t.metrics.pendingTransmissions.Set(float64(len(t.pending)))
+
+	if t.transmissionOutbox == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.localConfig.DatabaseTimeout)
+	defer cancel()
+	if err := t.transmissionOutbox.WritePendingTransmission(ctx, t.config.ConfigDigest, *pt); err != nil {
+		t.logger.Error("Transmission: error writing pending transmission to database", commontypes.LogFields{
+			"seqNr": pt.SeqNr,
+			"index": pt.Index,
+			"error": err,
+		})
+	}
+}
+
+// unpersistPendingTransmission deletes the pending transmission with key from
+// the outbox. Failures are logged, but otherwise ignored: at worst, the report
+// is transmitted again after a restart.
+func (t *transmissionState[RI]) unpersistPendingTransmission(key transmissionKey) {
+	t.metrics.pendingTransmissions.Set(float64(len(t.pending)))
+
+	if t.transmissionOutbox == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.localConfig.DatabaseTimeout)
+	defer cancel()
+	if err := t.transmissionOutbox.DeletePendingTransmission(ctx, t.config.ConfigDigest, key.seqNr, key.index); err != nil {
+		t.logger.Error("Transmission: error deleting pending transmission from database", commontypes.LogFields{
+			"seqNr": key.seqNr,
+			"index": key.index,
+			"error": err,
+		})
+	}
+}
+
+func (t *transmissionState[RI]) maxConcurrentTransmits() int {
+	if t.localConfig.ContractTransmitterMaxConcurrentTransmits > 0 {
+		return t.localConfig.ContractTransmitterMaxConcurrentTransmits
+	}
+	return 1
 }
 
 func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
+	if _, ok := t.pending[transmissionKey{ev.SeqNr, ev.Index}]; !ok {
+		// assertion
+		t.logger.Critical("assertion violated: scheduled transmission is not pending", commontypes.LogFields{
+			"seqNr": ev.SeqNr,
+			"index": ev.Index,
+		})
+		return
+	}
+
+	if len(t.transmitting) >= t.maxConcurrentTransmits() {
+		t.logger.Debug("queueing report, too many ongoing transmissions", commontypes.LogFields{
+			"seqNr":        ev.SeqNr,
+			"index":        ev.Index,
+			"transmitting": len(t.transmitting),
+		})
+		t.queue = append(t.queue, ev)
+		return
+	}
+	t.startTransmit(ev)
+}
+
+// startTransmit transmits ev in the background, reporting the result on
+// chTransmitResult.
+func (t *transmissionState[RI]) startTransmit(ev EventAttestedReport[RI]) {
+	key := transmissionKey{ev.SeqNr, ev.Index}
+	t.transmitting[key] = struct{}{}
+	t.subprocesses.Go(func() {
+		result := transmitResult{key, t.transmit(ev)}
+		select {
+		case t.chTransmitResult <- result:
+		case <-t.ctx.Done():
+		}
+	})
+}
+
+func (t *transmissionState[RI]) transmitted(result transmitResult) {
+	delete(t.transmitting, result.key)
+
+	pt, ok := t.pending[result.key]
+	if !ok {
+		// assertion
+		t.logger.Critical("assertion violated: transmitted report is not pending", commontypes.LogFields{
+			"seqNr": result.key.seqNr,
+			"index": result.key.index,
+		})
+	} else if result.outcome == transmitOutcomeFailed && pt.FailedAttempts < t.localConfig.ContractTransmitterTransmitRetries {
+		pt.FailedAttempts++
+		delay := t.retryDelay(pt.FailedAttempts)
+		pt.Deadline = t.clock.Now().Add(delay)
+		t.logger.Info("Transmission: scheduling retry", commontypes.LogFields{
+			"seqNr":          pt.SeqNr,
+			"index":          pt.Index,
+			"failedAttempts": pt.FailedAttempts,
+			"delay":          delay.String(),
+		})
+		t.metrics.transmitRetries.Inc()
+		t.persistPendingTransmission(pt)
+		t.scheduler.ScheduleDeadline(EventAttestedReport[RI]{
+			pt.SeqNr,
+			pt.Index,
+			pt.AttestedReport,
+			trace.SpanContext{},
+		}, pt.Deadline)
+	} else {
+		if result.outcome == transmitOutcomeFailed {
+			t.logger.Warn("Transmission: giving up on report", commontypes.LogFields{
+				"seqNr":          pt.SeqNr,
+				"index":          pt.Index,
+				"failedAttempts": pt.FailedAttempts + 1,
+			})
+		}
+		delete(t.pending, result.key)
+		t.unpersistPendingTransmission(result.key)
+	}
+
+	for len(t.queue) > 0 && len(t.transmitting) < t.maxConcurrentTransmits() {
+		ev := t.queue[0]
+		t.queue = t.queue[1:]
+		t.startTransmit(ev)
+	}
+}
+
+// retryDelay returns the delay before the retry following the given number
+// of failed attempts.
+func (t *transmissionState[RI]) retryDelay(failedAttempts int) time.Duration {
+	delay := t.localConfig.ContractTransmitterTransmitRetryDelay
+	for i := 1; i < failedAttempts && delay < t.localConfig.ContractTransmitterTransmitMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.localConfig.ContractTransmitterTransmitMaxRetryDelay {
+		delay = t.localConfig.ContractTransmitterTransmitMaxRetryDelay
+	}
+	return delay
+}
+
+// transmit consults the ReportingPlugin and, if it agrees, transmits the
+// report. It runs outside of the event loop and must only access immutable
+// state.
+func (t *transmissionState[RI]) transmit(ev EventAttestedReport[RI]) transmitOutcome {
+	transmissionCtx, span := t.startSpan("Transmission.transmit", ev)
+	defer span.End()
+
 	shouldTransmit, ok := callPlugin[bool](
-		t.ctx,
+		transmissionCtx,
+		t.clock,
+		t.metrics.reportingPluginDuration,
 		t.logger,
 		commontypes.LogFields{
 			"seqNr": ev.SeqNr,
@@ -151,6 +425,7 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 		},
 		"ShouldTransmitAcceptedReport",
 		t.config.MaxDurationShouldTransmitAcceptedReport,
+		t.tracer,
 		func(ctx context.Context) (bool, error) {
 			return t.reportingPlugin.ShouldTransmitAcceptedReport(
 				ctx,
@@ -160,7 +435,7 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 		},
 	)
 	if !ok {
-		return
+		return transmitOutcomeFailed
 	}
 
 	if !shouldTransmit {
@@ -168,7 +443,7 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 			"seqNr": ev.SeqNr,
 			"index": ev.Index,
 		})
-		return
+		return transmitOutcomeDeclined
 	}
 
 	t.logger.Debug("transmitting report", commontypes.LogFields{
@@ -177,8 +452,11 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 	})
 
 	{
+		ctx, transmitSpan := t.tracer.Start(transmissionCtx, "ContractTransmitter.Transmit")
+		defer transmitSpan.End()
+
 		ctx, cancel := context.WithTimeout(
-			t.ctx,
+			ctx,
 			t.localConfig.ContractTransmitterTransmitTimeout,
 		)
 		defer cancel()
@@ -194,6 +472,7 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 			},
 		)
 
+		start := t.clock.Now()
 		err := t.contractTransmitter.Transmit(
 			ctx,
 			t.config.ConfigDigest,
@@ -201,12 +480,19 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 			ev.AttestedReport.ReportWithInfo,
 			ev.AttestedReport.AttributedSignatures,
 		)
+		t.metrics.transmitDuration.Observe(t.clock.Now().Sub(start).Seconds())
 
 		ins.Stop()
 
 		if err != nil {
-			t.logger.Error("ContractTransmitter.Transmit error", commontypes.LogFields{"error": err})
-			return
+			transmitSpan.SetStatus(codes.Error, err.Error())
+			t.metrics.transmitFailures.Inc()
+			t.logger.Error("ContractTransmitter.Transmit error", commontypes.LogFields{
+				"seqNr": ev.SeqNr,
+				"index": ev.Index,
+				"error": err,
+			})
+			return transmitOutcomeFailed
 		}
 
 	}
@@ -215,6 +501,21 @@ func (t *transmissionState[RI]) scheduled(ev EventAttestedReport[RI]) {
 		"seqNr": ev.SeqNr,
 		"index": ev.Index,
 	})
+	return transmitOutcomeTransmitted
+}
+
+// startSpan starts a span for handling ev. The span is a child of the span
+// under which ev was attested, if any.
+func (t *transmissionState[RI]) startSpan(name string, ev EventAttestedReport[RI]) (context.Context, trace.Span) {
+	return t.tracer.Start(
+		trace.ContextWithSpanContext(t.ctx, ev.SpanContext),
+		name,
+		trace.WithAttributes(
+			attributeConfigDigest.String(t.config.ConfigDigest.Hex()),
+			attributeSeqNr.Int64(int64(ev.SeqNr)),
+			attributeReportIndex.Int(ev.Index),
+		),
+	)
 }
 
 func (t *transmissionState[RI]) transmitDelay(seqNr uint64, index int) *time.Duration {
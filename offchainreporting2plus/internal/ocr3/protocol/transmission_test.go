@@ -0,0 +1,337 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+// acceptingPlugin accepts and transmits every report. Its other methods must
+// not be called.
+type acceptingPlugin struct {
+	ocr3types.ReportingPlugin[struct{}]
+}
+
+func (acceptingPlugin) ShouldAcceptAttestedReport(context.Context, uint64, ocr3types.ReportWithInfo[struct{}]) (bool, error) {
+	return true, nil
+}
+
+func (acceptingPlugin) ShouldTransmitAcceptedReport(context.Context, uint64, ocr3types.ReportWithInfo[struct{}]) (bool, error) {
+	return true, nil
+}
+
+// testTransmitter fails the first failures calls to Transmit and blocks each
+// call for delay.
+type testTransmitter struct {
+	delay time.Duration
+
+	mutex       sync.Mutex
+	failures    int
+	calls       map[uint64][]time.Time
+	transmitted map[uint64]bool
+	inflight    int
+	maxInflight int
+}
+
+func (tt *testTransmitter) Transmit(ctx context.Context, configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[struct{}], signatures []types.AttributedOnchainSignature) error {
+	tt.mutex.Lock()
+	tt.calls[seqNr] = append(tt.calls[seqNr], time.Now())
+	tt.inflight++
+	tt.maxInflight = max(tt.maxInflight, tt.inflight)
+	tt.mutex.Unlock()
+
+	time.Sleep(tt.delay)
+
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	tt.inflight--
+	if tt.failures > 0 {
+		tt.failures--
+		return fmt.Errorf("transmission failed")
+	}
+	tt.transmitted[seqNr] = true
+	return nil
+}
+
+func (tt *testTransmitter) FromAccount() (types.Account, error) {
+	return "test", nil
+}
+
+func (tt *testTransmitter) callsOf(seqNr uint64) []time.Time {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	return append([]time.Time(nil), tt.calls[seqNr]...)
+}
+
+// memoryOutbox is a TransmissionOutbox that remembers the largest
+// FailedAttempts it has seen.
+type memoryOutbox struct {
+	mutex             sync.Mutex
+	pending           map[types.ConfigDigest]map[transmissionKey]PendingTransmission[struct{}]
+	maxFailedAttempts int
+}
+
+var _ TransmissionOutbox[struct{}] = (*memoryOutbox)(nil)
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{pending: map[types.ConfigDigest]map[transmissionKey]PendingTransmission[struct{}]{}}
+}
+
+func (o *memoryOutbox) ReadPendingTransmissions(ctx context.Context, configDigest types.ConfigDigest) ([]PendingTransmission[struct{}], error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var pts []PendingTransmission[struct{}]
+	for _, pt := range o.pending[configDigest] {
+		pts = append(pts, pt)
+	}
+	return pts, nil
+}
+
+func (o *memoryOutbox) WritePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, pt PendingTransmission[struct{}]) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.pending[configDigest] == nil {
+		o.pending[configDigest] = map[transmissionKey]PendingTransmission[struct{}]{}
+	}
+	o.pending[configDigest][transmissionKey{pt.SeqNr, pt.Index}] = pt
+	o.maxFailedAttempts = max(o.maxFailedAttempts, pt.FailedAttempts)
+	return nil
+}
+
+func (o *memoryOutbox) DeletePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, seqNr uint64, index int) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	delete(o.pending[configDigest], transmissionKey{seqNr, index})
+	return nil
+}
+
+func (o *memoryOutbox) count(configDigest types.ConfigDigest) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.pending[configDigest])
+}
+
+var transmissionTestConfigDigest = types.ConfigDigest{1}
+
+func transmissionTestLocalConfig() types.LocalConfig {
+	return types.LocalConfig{
+		ContractTransmitterTransmitTimeout: time.Second,
+		DatabaseTimeout:                    time.Second,
+	}
+}
+
+// runTestTransmission runs the transmission protocol of the only oracle of a
+// single-oracle config until the test ends.
+func runTestTransmission(t *testing.T, localConfig types.LocalConfig, transmitter *testTransmitter, outbox TransmissionOutbox[struct{}]) chan<- EventToTransmission[struct{}] {
+	transmitter.calls = map[uint64][]time.Time{}
+	transmitter.transmitted = map[uint64]bool{}
+
+	sharedSecret := [config.SharedSecretSize]byte{1}
+	sharedConfig := ocr3config.SharedConfig{
+		ocr3config.PublicConfig{
+			DeltaStage:                              time.Second,
+			S:                                       []int{1},
+			OracleIdentities:                        make([]config.OracleIdentity, 1),
+			MaxDurationShouldAcceptAttestedReport:   time.Second,
+			MaxDurationShouldTransmitAcceptedReport: time.Second,
+			F:                                       0,
+			ConfigDigest:                            transmissionTestConfigDigest,
+		},
+		&sharedSecret,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subs := subprocesses.Subprocesses{}
+	ch := make(chan EventToTransmission[struct{}])
+	subs.Go(func() {
+		RunTransmission[struct{}](
+			ctx,
+			&subs,
+			ch,
+			clock.Real(),
+			sharedConfig,
+			transmitter,
+			0,
+			localConfig,
+			loghelper.MakeRootLoggerWithContext(nopLogger{}),
+			prometheus.NewRegistry(),
+			acceptingPlugin{},
+			NewStatusTracker(),
+			noop.NewTracerProvider().Tracer(""),
+			outbox,
+		)
+	})
+	t.Cleanup(func() {
+		cancel()
+		subs.Wait()
+	})
+	return ch
+}
+
+func attestedReport(seqNr uint64) EventAttestedReport[struct{}] {
+	return EventAttestedReport[struct{}]{
+		SeqNr: seqNr,
+		AttestedReport: AttestedReportMany[struct{}]{
+			ocr3types.ReportWithInfo[struct{}]{[]byte(fmt.Sprintf("report %v", seqNr)), struct{}{}},
+			nil,
+		},
+	}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTransmissionRetryDelay(t *testing.T) {
+	ts := transmissionState[struct{}]{localConfig: types.LocalConfig{
+		ContractTransmitterTransmitRetryDelay:    time.Second,
+		ContractTransmitterTransmitMaxRetryDelay: 5 * time.Second,
+	}}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, e := range expected {
+		if d := ts.retryDelay(i + 1); d != e {
+			t.Errorf("delay after %v failed attempts is %v, expected %v", i+1, d, e)
+		}
+	}
+	// a huge number of failed attempts doesn't overflow
+	if d := ts.retryDelay(1000); d != 5*time.Second {
+		t.Errorf("delay after 1000 failed attempts is %v", d)
+	}
+}
+
+func TestTransmissionRetries(t *testing.T) {
+	localConfig := transmissionTestLocalConfig()
+	localConfig.ContractTransmitterTransmitRetries = 5
+	localConfig.ContractTransmitterTransmitRetryDelay = 20 * time.Millisecond
+	localConfig.ContractTransmitterTransmitMaxRetryDelay = 40 * time.Millisecond
+
+	transmitter := &testTransmitter{failures: 3}
+	outbox := newMemoryOutbox()
+	ch := runTestTransmission(t, localConfig, transmitter, outbox)
+	ch <- attestedReport(1)
+
+	eventually(t, "the report is transmitted", func() bool {
+		transmitter.mutex.Lock()
+		defer transmitter.mutex.Unlock()
+		return transmitter.transmitted[1]
+	})
+	calls := transmitter.callsOf(1)
+	if len(calls) != 4 {
+		t.Fatalf("Transmit called %v times, expected 4", len(calls))
+	}
+	for i, minDelay := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond} {
+		if d := calls[i+1].Sub(calls[i]); d < minDelay {
+			t.Errorf("retry %v came after %v, expected at least %v", i+1, d, minDelay)
+		}
+	}
+	eventually(t, "the report is deleted from the outbox", func() bool {
+		return outbox.count(transmissionTestConfigDigest) == 0
+	})
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+	if outbox.maxFailedAttempts != 3 {
+		t.Errorf("outbox saw %v failed attempts, expected 3", outbox.maxFailedAttempts)
+	}
+}
+
+func TestTransmissionGivesUp(t *testing.T) {
+	localConfig := transmissionTestLocalConfig()
+	localConfig.ContractTransmitterTransmitRetries = 2
+	localConfig.ContractTransmitterTransmitRetryDelay = time.Millisecond
+	localConfig.ContractTransmitterTransmitMaxRetryDelay = time.Millisecond
+
+	transmitter := &testTransmitter{failures: 100}
+	outbox := newMemoryOutbox()
+	ch := runTestTransmission(t, localConfig, transmitter, outbox)
+	ch <- attestedReport(1)
+
+	eventually(t, "the report is deleted from the outbox", func() bool {
+		return len(transmitter.callsOf(1)) > 0 && outbox.count(transmissionTestConfigDigest) == 0
+	})
+	// give a fourth attempt the chance to happen
+	time.Sleep(50 * time.Millisecond)
+	if calls := transmitter.callsOf(1); len(calls) != 3 {
+		t.Errorf("Transmit called %v times, expected 3", len(calls))
+	}
+}
+
+func TestTransmissionMaxConcurrentTransmits(t *testing.T) {
+	for _, maxConcurrent := range []int{0, 1, 3} {
+		localConfig := transmissionTestLocalConfig()
+		localConfig.ContractTransmitterMaxConcurrentTransmits = maxConcurrent
+
+		transmitter := &testTransmitter{delay: 20 * time.Millisecond}
+		ch := runTestTransmission(t, localConfig, transmitter, nil)
+		const reports = 6
+		for seqNr := uint64(1); seqNr <= reports; seqNr++ {
+			ch <- attestedReport(seqNr)
+		}
+
+		eventually(t, "all reports are transmitted", func() bool {
+			transmitter.mutex.Lock()
+			defer transmitter.mutex.Unlock()
+			return len(transmitter.transmitted) == reports
+		})
+		transmitter.mutex.Lock()
+		if expected := max(maxConcurrent, 1); transmitter.maxInflight != expected {
+			t.Errorf("ContractTransmitterMaxConcurrentTransmits %v: %v concurrent transmissions, expected %v", maxConcurrent, transmitter.maxInflight, expected)
+		}
+		transmitter.mutex.Unlock()
+	}
+}
+
+func TestTransmissionResumesFromOutbox(t *testing.T) {
+	outbox := newMemoryOutbox()
+	otherConfigDigest := types.ConfigDigest{2}
+	for _, configDigest := range []types.ConfigDigest{transmissionTestConfigDigest, otherConfigDigest} {
+		ev := attestedReport(7)
+		if err := outbox.WritePendingTransmission(context.Background(), configDigest, PendingTransmission[struct{}]{
+			ev.SeqNr,
+			ev.Index,
+			ev.AttestedReport,
+			time.Now(),
+			1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transmitter := &testTransmitter{}
+	runTestTransmission(t, transmissionTestLocalConfig(), transmitter, outbox)
+
+	eventually(t, "the restored report is transmitted and deleted", func() bool {
+		return len(transmitter.callsOf(7)) == 1 && outbox.count(transmissionTestConfigDigest) == 0
+	})
+	if outbox.count(otherConfigDigest) != 1 {
+		t.Error("pending transmission of other config digest was touched")
+	}
+}
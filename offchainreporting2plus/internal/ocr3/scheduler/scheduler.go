@@ -2,13 +2,15 @@ package scheduler
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/minheap"
 	"github.com/smartcontractkit/libocr/subprocesses"
 )
 
-type itemWithDeadline[T any] struct {
+type ItemWithDeadline[T any] struct {
 	Item     T
 	Deadline time.Time
 }
@@ -17,37 +19,44 @@ type Scheduler[T any] struct {
 	subs   subprocesses.Subprocesses
 	ctx    context.Context
 	cancel context.CancelFunc
+	clock  clock.Clock
 
-	in  chan<- itemWithDeadline[T]
+	in  chan<- ItemWithDeadline[T]
 	out <-chan T
+
+	chPendingRequest chan<- chan<- []ItemWithDeadline[T]
 }
 
-func NewScheduler[T any]() *Scheduler[T] {
+func NewScheduler[T any](clock clock.Clock) *Scheduler[T] {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	in := make(chan itemWithDeadline[T])
+	in := make(chan ItemWithDeadline[T])
 	out := make(chan T)
+	chPendingRequest := make(chan chan<- []ItemWithDeadline[T])
 
 	scheduler := &Scheduler[T]{
 		subprocesses.Subprocesses{},
 		ctx,
 		cancel,
+		clock,
 
 		in,
 		out,
+
+		chPendingRequest,
 	}
 
 	scheduler.subs.Go(func() {
 		// create an expired timer
-		timer := time.NewTimer(0)
+		timer := clock.NewTimer(0)
 		defer timer.Stop()
-		<-timer.C
+		<-timer.C()
 
-		heap := minheap.NewMinHeap(func(a, b itemWithDeadline[T]) bool {
+		heap := minheap.NewMinHeap(func(a, b ItemWithDeadline[T]) bool {
 			return a.Deadline.Before(b.Deadline)
 		})
 
-		var pendingItem T
+		var pendingItem ItemWithDeadline[T]
 		var maybeOut chan<- T
 
 		for {
@@ -56,25 +65,34 @@ func NewScheduler[T any]() *Scheduler[T] {
 				if maybeOut == nil {
 					if heap.Len() == 0 {
 						// the timer must be stopped already
-						timer.Reset(time.Until(item.Deadline))
+						timer.Reset(item.Deadline.Sub(clock.Now()))
 					} else if heap.Peek().Deadline.After(item.Deadline) {
 						// we're dealing with the new minimum
 						if timer.Stop() {
 							// timer hasn't fired yet
-							timer.Reset(time.Until(item.Deadline))
+							timer.Reset(item.Deadline.Sub(clock.Now()))
 						} // else: timer has fired. no need to do anything since
 						//   we will handle <-timer.C in an upcoming loop iteration
 					}
 				}
 				heap.Push(item)
-			case <-timer.C:
-				pendingItem = heap.Pop().Item
+			case <-timer.C():
+				pendingItem = heap.Pop()
 				maybeOut = out
-			case maybeOut <- pendingItem:
+			case maybeOut <- pendingItem.Item:
 				maybeOut = nil
 				if heap.Len() != 0 {
-					timer.Reset(time.Until(heap.Peek().Deadline))
+					timer.Reset(heap.Peek().Deadline.Sub(clock.Now()))
 				}
+			case chResponse := <-chPendingRequest:
+				items := heap.Items()
+				if maybeOut != nil {
+					items = append(items, pendingItem)
+				}
+				sort.Slice(items, func(i, j int) bool {
+					return items[i].Deadline.Before(items[j].Deadline)
+				})
+				chResponse <- items
 			case <-ctx.Done():
 				return
 			}
@@ -86,19 +104,33 @@ func NewScheduler[T any]() *Scheduler[T] {
 
 func (s *Scheduler[T]) ScheduleDeadline(item T, deadline time.Time) {
 	select {
-	case s.in <- itemWithDeadline[T]{item, deadline}:
+	case s.in <- ItemWithDeadline[T]{item, deadline}:
 	case <-s.ctx.Done():
 	}
 }
 
 func (s *Scheduler[T]) ScheduleDelay(item T, delay time.Duration) {
-	s.ScheduleDeadline(item, time.Now().Add(delay))
+	s.ScheduleDeadline(item, s.clock.Now().Add(delay))
 }
 
 func (s *Scheduler[T]) Scheduled() <-chan T {
 	return s.out
 }
 
+// Pending returns the items that have been scheduled but not yet been
+// received from Scheduled, ordered by deadline. Returns nil if the scheduler
+// is closed.
+func (s *Scheduler[T]) Pending() []ItemWithDeadline[T] {
+	// buffered so that the scheduler never blocks on the response
+	chResponse := make(chan []ItemWithDeadline[T], 1)
+	select {
+	case s.chPendingRequest <- chResponse:
+		return <-chResponse
+	case <-s.ctx.Done():
+		return nil
+	}
+}
+
 func (s *Scheduler[T]) Close() {
 	s.cancel()
 	s.subs.Wait()
@@ -0,0 +1,259 @@
+package serialization
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// With compression enabled, every non-empty query, observation, and outcome
+// payload is prefixed with one of these bytes. We only compress a payload if
+// that makes it smaller, so a payload never grows by more than the prefix.
+// Empty payloads are left alone.
+const (
+	payloadPrefixRaw        byte = 0
+	payloadPrefixCompressed byte = 1
+)
+
+var zstdEncoder, zstdDecoder = func() (*zstd.Encoder, *zstd.Decoder) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		panic(fmt.Sprintf("could not create zstd encoder: %v", err))
+	}
+	decoder, err := zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(0),
+		zstd.WithDecoderMaxMemory(uint64(max(ocr3types.MaxMaxQueryLength, ocr3types.MaxMaxObservationLength, ocr3types.MaxMaxOutcomeLength))),
+		// DecodeAll never grows dst beyond its capacity, which
+		// decompressPayload sets to the payload's limit
+		zstd.WithDecodeAllCapLimit(true),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("could not create zstd decoder: %v", err))
+	}
+	return encoder, decoder
+}()
+
+// SerializeCompressed is like Serialize, but compresses the query,
+// observation, and outcome payloads of the message on the wire. Since
+// signatures are computed over the uncompressed payloads, the receiver must
+// decompress before it can verify anything. The returned MessageWrapper
+// contains the uncompressed payloads.
+func SerializeCompressed[RI any](m protocol.Message[RI], compression ocr3config.Compression) (b []byte, pbm *MessageWrapper, err error) {
+	if compression == ocr3config.CompressionNone {
+		return Serialize(m)
+	}
+	pbm, err = toProtoMessage(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	compressed := proto.Clone(pbm).(*MessageWrapper)
+	for _, payload := range payloadsOfMessageWrapper(compressed) {
+		*payload.data, err = compressPayload(*payload.data, compression)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	b, err = proto.Marshal(compressed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, pbm, nil
+}
+
+// DeserializeCompressed is the inverse of SerializeCompressed. It rejects
+// queries, observations, and outcomes that would decompress to more than
+// limits.MaxQueryLength, limits.MaxObservationLength, and
+// limits.MaxOutcomeLength bytes, respectively. The returned MessageWrapper
+// contains the uncompressed payloads.
+func DeserializeCompressed[RI any](b []byte, compression ocr3config.Compression, limits ocr3types.ReportingPluginLimits) (protocol.Message[RI], *MessageWrapper, error) {
+	if compression == ocr3config.CompressionNone {
+		return Deserialize[RI](b)
+	}
+	pbm := &MessageWrapper{}
+	err := proto.Unmarshal(b, pbm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshal protobuf: %w", err)
+	}
+	for _, payload := range payloadsOfMessageWrapper(pbm) {
+		*payload.data, err = decompressPayload(*payload.data, compression, payload.kind.maxLength(limits))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not decompress %v: %w", payload.kind, err)
+		}
+	}
+	m, err := messageWrapperFromProtoMessage[RI](pbm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not translate protobuf to protocol.Message: %w", err)
+	}
+	return m, pbm, nil
+}
+
+func compressPayload(payload []byte, compression ocr3config.Compression) ([]byte, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+
+	var compressed []byte
+	switch compression {
+	case ocr3config.CompressionSnappy:
+		compressed = snappy.Encode(nil, payload)
+	case ocr3config.CompressionZstd:
+		compressed = zstdEncoder.EncodeAll(payload, nil)
+	default:
+		return nil, fmt.Errorf("unknown compression %v", compression)
+	}
+
+	if len(compressed) < len(payload) {
+		return append([]byte{payloadPrefixCompressed}, compressed...), nil
+	} else {
+		return append([]byte{payloadPrefixRaw}, payload...), nil
+	}
+}
+
+func decompressPayload(payload []byte, compression ocr3config.Compression, maxPayloadLength int) ([]byte, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+
+	switch payload[0] {
+	case payloadPrefixRaw:
+		return payload[1:], nil
+	case payloadPrefixCompressed:
+	default:
+		return nil, fmt.Errorf("unknown payload prefix %v", payload[0])
+	}
+
+	compressed := payload[1:]
+	var decompressed []byte
+	switch compression {
+	case ocr3config.CompressionSnappy:
+		// check the length before decoding so that we don't allocate
+		// unbounded amounts of memory
+		decodedLen, err := snappy.DecodedLen(compressed)
+		if err != nil {
+			return nil, err
+		}
+		if decodedLen > maxPayloadLength {
+			return nil, fmt.Errorf("decompressed payload would be %v bytes long, but at most %v are allowed", decodedLen, maxPayloadLength)
+		}
+		decompressed, err = snappy.Decode(nil, compressed)
+		if err != nil {
+			return nil, err
+		}
+	case ocr3config.CompressionZstd:
+		// We always write the content size into the frame header. Check it
+		// before decoding so that we don't allocate unbounded amounts of
+		// memory. Since zstdDecoder doesn't grow dst beyond its capacity,
+		// a lying header can't make us decode more than it claims either.
+		var header zstd.Header
+		if err := header.Decode(compressed); err != nil {
+			return nil, err
+		}
+		if !header.HasFCS {
+			return nil, fmt.Errorf("zstd frame header lacks content size")
+		}
+		if header.FrameContentSize > uint64(maxPayloadLength) {
+			return nil, fmt.Errorf("decompressed payload would be %v bytes long, but at most %v are allowed", header.FrameContentSize, maxPayloadLength)
+		}
+		var err error
+		decompressed, err = zstdDecoder.DecodeAll(compressed, make([]byte, 0, header.FrameContentSize))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression %v", compression)
+	}
+
+	if len(decompressed) > maxPayloadLength {
+		return nil, fmt.Errorf("decompressed payload is %v bytes long, but at most %v are allowed", len(decompressed), maxPayloadLength)
+	}
+	return decompressed, nil
+}
+
+type payloadKind int
+
+const (
+	payloadKindQuery payloadKind = iota
+	payloadKindObservation
+	payloadKindOutcome
+)
+
+func (k payloadKind) String() string {
+	switch k {
+	case payloadKindQuery:
+		return "query"
+	case payloadKindObservation:
+		return "observation"
+	case payloadKindOutcome:
+		return "outcome"
+	}
+	return fmt.Sprintf("payloadKind(%d)", int(k))
+}
+
+func (k payloadKind) maxLength(limits ocr3types.ReportingPluginLimits) int {
+	switch k {
+	case payloadKindQuery:
+		return limits.MaxQueryLength
+	case payloadKindObservation:
+		return limits.MaxObservationLength
+	case payloadKindOutcome:
+		return limits.MaxOutcomeLength
+	}
+	return 0
+}
+
+type payload struct {
+	kind payloadKind
+	data *[]byte
+}
+
+// payloadsOfMessageWrapper returns pointers to all query, observation, and
+// outcome payloads contained in m.
+func payloadsOfMessageWrapper(m *MessageWrapper) []payload {
+	switch msg := m.GetMsg().(type) {
+	case *MessageWrapper_MessageEpochStartRequest:
+		return payloadsOfCertifiedPrepareOrCommit(msg.MessageEpochStartRequest.GetHighestCertified())
+	case *MessageWrapper_MessageEpochStart:
+		return payloadsOfCertifiedPrepareOrCommit(msg.MessageEpochStart.GetEpochStartProof().GetHighestCertified())
+	case *MessageWrapper_MessageRoundStart:
+		if msg.MessageRoundStart != nil {
+			return []payload{{payloadKindQuery, &msg.MessageRoundStart.Query}}
+		}
+	case *MessageWrapper_MessageObservation:
+		if so := msg.MessageObservation.GetSignedObservation(); so != nil {
+			return []payload{{payloadKindObservation, &so.Observation}}
+		}
+	case *MessageWrapper_MessageProposal:
+		var payloads []payload
+		for _, aso := range msg.MessageProposal.GetAttributedSignedObservations() {
+			if so := aso.GetSignedObservation(); so != nil {
+				payloads = append(payloads, payload{payloadKindObservation, &so.Observation})
+			}
+		}
+		return payloads
+	case *MessageWrapper_MessageCertifiedCommit:
+		if cc := msg.MessageCertifiedCommit.GetCertifiedCommit(); cc != nil {
+			return []payload{{payloadKindOutcome, &cc.Outcome}}
+		}
+	}
+	return nil
+}
+
+func payloadsOfCertifiedPrepareOrCommit(m *CertifiedPrepareOrCommit) []payload {
+	switch poc := m.GetPrepareOrCommit().(type) {
+	case *CertifiedPrepareOrCommit_Prepare:
+		if poc.Prepare != nil {
+			return []payload{{payloadKindOutcome, &poc.Prepare.Outcome}}
+		}
+	case *CertifiedPrepareOrCommit_Commit:
+		if poc.Commit != nil {
+			return []payload{{payloadKindOutcome, &poc.Commit.Outcome}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package serialization
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+)
+
+var compressions = []ocr3config.Compression{ocr3config.CompressionSnappy, ocr3config.CompressionZstd}
+
+var testLimits = ocr3types.ReportingPluginLimits{
+	MaxQueryLength:       10_000,
+	MaxObservationLength: 10_000,
+	MaxOutcomeLength:     10_000,
+	MaxReportLength:      10_000,
+	MaxReportCount:       10,
+}
+
+func compressible(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+func incompressible(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	messages := []protocol.Message[struct{}]{
+		protocol.MessageRoundStart[struct{}]{1, 2, compressible('q', 1000)},
+		protocol.MessageRoundStart[struct{}]{1, 2, nil},
+		protocol.MessageObservation[struct{}]{1, 2, protocol.SignedObservation{incompressible(t, 1000), []byte("sig")}},
+		protocol.MessageProposal[struct{}]{1, 2, []protocol.AttributedSignedObservation{
+			{protocol.SignedObservation{compressible('a', 1000), []byte("sig")}, 0},
+			{protocol.SignedObservation{incompressible(t, 10), []byte("sig")}, 1},
+			{protocol.SignedObservation{nil, []byte("sig")}, 2},
+		}},
+		protocol.MessageCertifiedCommit[struct{}]{protocol.CertifiedCommit{3, 4, compressible('o', 1000), nil}},
+		protocol.MessageEpochStartRequest[struct{}]{
+			5,
+			&protocol.CertifiedPrepare{3, 4, protocol.OutcomeInputsDigest{1}, compressible('p', 1000), nil},
+			protocol.SignedHighestCertifiedTimestamp{},
+		},
+	}
+
+	for _, compression := range compressions {
+		for _, m := range messages {
+			uncompressed, _, err := Serialize(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, pbm, err := SerializeCompressed(m, compression)
+			if err != nil {
+				t.Fatalf("%v: %v", compression, err)
+			}
+
+			m2, pbm2, err := DeserializeCompressed[struct{}](b, compression, testLimits)
+			if err != nil {
+				t.Fatalf("%v: could not deserialize %T: %v", compression, m, err)
+			}
+			// both MessageWrappers hold the uncompressed payloads
+			for _, x := range []interface{}{pbm, pbm2, m2} {
+				var reserialized []byte
+				switch x := x.(type) {
+				case *MessageWrapper:
+					m3, err := messageWrapperFromProtoMessage[struct{}](x)
+					if err != nil {
+						t.Fatal(err)
+					}
+					reserialized, _, err = Serialize(m3)
+					if err != nil {
+						t.Fatal(err)
+					}
+				case protocol.Message[struct{}]:
+					reserialized, _, err = Serialize(x)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				if !bytes.Equal(reserialized, uncompressed) {
+					t.Errorf("%v: %T doesn't survive round trip", compression, m)
+				}
+			}
+		}
+	}
+}
+
+func TestCompressPayloadPrefix(t *testing.T) {
+	for _, compression := range compressions {
+		if payload, err := compressPayload(nil, compression); err != nil || len(payload) != 0 {
+			t.Errorf("%v: empty payload compresses to %x, %v", compression, payload, err)
+		}
+
+		raw := compressible('x', 1000)
+		payload, err := compressPayload(raw, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload[0] != payloadPrefixCompressed || len(payload) >= len(raw) {
+			t.Errorf("%v: compressible payload has prefix %v and length %v", compression, payload[0], len(payload))
+		}
+
+		// payloads that don't get smaller are sent as is
+		raw = incompressible(t, 1000)
+		payload, err = compressPayload(raw, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload[0] != payloadPrefixRaw || !bytes.Equal(payload[1:], raw) {
+			t.Errorf("%v: incompressible payload has prefix %v", compression, payload[0])
+		}
+		if len(payload)-len(raw) > ocr3config.MaxCompressionOverhead {
+			t.Errorf("%v: payload grew by %v bytes", compression, len(payload)-len(raw))
+		}
+
+		if _, err := decompressPayload([]byte{2, 0}, compression, 1000); err == nil {
+			t.Errorf("%v: unknown prefix accepted", compression)
+		}
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	bomb := compressible(0, 1<<20)
+	for _, compression := range compressions {
+		payload, err := compressPayload(bomb, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload[0] != payloadPrefixCompressed {
+			t.Fatal("bomb wasn't compressed")
+		}
+		if _, err := decompressPayload(payload, compression, len(bomb)-1); err == nil {
+			t.Errorf("%v: bomb decompressed", compression)
+		}
+		if decompressed, err := decompressPayload(payload, compression, len(bomb)); err != nil || !bytes.Equal(decompressed, bomb) {
+			t.Errorf("%v: payload at limit doesn't decompress: %v", compression, err)
+		}
+
+		// the limit depends on the type of the payload: a query that fits
+		// the observation limit is still rejected
+		limits := testLimits
+		limits.MaxObservationLength = len(bomb)
+		b, _, err := SerializeCompressed[struct{}](protocol.MessageRoundStart[struct{}]{1, 2, bomb}, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := DeserializeCompressed[struct{}](b, compression, limits); err == nil {
+			t.Errorf("%v: query exceeding MaxQueryLength accepted", compression)
+		}
+		limits.MaxQueryLength = len(bomb)
+		if _, _, err := DeserializeCompressed[struct{}](b, compression, limits); err != nil {
+			t.Errorf("%v: query within MaxQueryLength rejected: %v", compression, err)
+		}
+	}
+}
+
+func TestDecompressionBombWithLyingHeader(t *testing.T) {
+	bomb := compressible(0, 1<<20)
+	frame := zstdEncoder.EncodeAll(bomb, nil)
+
+	// overwrite the frame content size with a small value
+	var header zstd.Header
+	if err := header.Decode(frame); err != nil {
+		t.Fatal(err)
+	}
+	fhd := frame[4]
+	if fhd>>6 != 2 {
+		t.Fatalf("expected 4 byte frame content size, frame header descriptor is %08b", fhd)
+	}
+	offset := 5
+	if fhd&(1<<5) == 0 { // window descriptor present
+		offset++
+	}
+	offset += []int{0, 1, 2, 4}[fhd&3] // dictionary id
+	binary.LittleEndian.PutUint32(frame[offset:], 100)
+	if err := header.Decode(frame); err != nil || header.FrameContentSize != 100 {
+		t.Fatalf("could not forge header: %v", err)
+	}
+
+	payload := append([]byte{payloadPrefixCompressed}, frame...)
+	if _, err := decompressPayload(payload, ocr3config.CompressionZstd, 1000); err == nil {
+		t.Error("bomb with lying header decompressed")
+	}
+}
@@ -0,0 +1,113 @@
+package shim
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// SerializingOCR3Journal serializes protocol.JournalEntry values and appends
+// them to an ocr3types.Journal under the instance's ConfigDigest.
+type SerializingOCR3Journal[RI any] struct {
+	configDigest types.ConfigDigest
+	journal      ocr3types.Journal[RI]
+	logger       commontypes.Logger
+}
+
+var _ protocol.Journal[struct{}] = (*SerializingOCR3Journal[struct{}])(nil)
+
+func NewSerializingOCR3Journal[RI any](
+	configDigest types.ConfigDigest,
+	journal ocr3types.Journal[RI],
+	logger commontypes.Logger,
+) *SerializingOCR3Journal[RI] {
+	return &SerializingOCR3Journal[RI]{configDigest, journal, logger}
+}
+
+func (j *SerializingOCR3Journal[RI]) Record(entry protocol.JournalEntry[RI]) {
+	serialized, err := SerializeOCR3JournalEntry(entry)
+	if err != nil {
+		j.logger.Error("SerializingOCR3Journal: failed to serialize journal entry", commontypes.LogFields{
+			"error": err,
+			"kind":  entry.Kind,
+			"name":  entry.Name,
+		})
+		return
+	}
+	if err := j.journal.Append(j.configDigest, serialized); err != nil {
+		j.logger.Error("SerializingOCR3Journal: failed to append journal entry", commontypes.LogFields{
+			"error": err,
+			"kind":  entry.Kind,
+			"name":  entry.Name,
+		})
+	}
+}
+
+func SerializeOCR3JournalEntry[RI any](entry protocol.JournalEntry[RI]) (ocr3types.JournalEntry[RI], error) {
+	result := ocr3types.JournalEntry[RI]{
+		Time:        entry.Time,
+		Subprotocol: entry.Subprotocol,
+		Kind:        entry.Kind,
+		Sender:      entry.Sender,
+		Name:        entry.Name,
+		Epoch:       entry.Epoch,
+		Leader:      entry.Leader,
+		SeqNr:       entry.SeqNr,
+		PluginCall:  entry.PluginCall,
+		Randomness:  entry.Randomness,
+	}
+	if entry.Message != nil {
+		raw, _, err := serialization.Serialize(entry.Message)
+		if err != nil {
+			return ocr3types.JournalEntry[RI]{}, err
+		}
+		result.Message = raw
+	}
+	if entry.Cert != nil {
+		raw, err := proto.Marshal(serialization.CertifiedPrepareOrCommitToProtoMessage(entry.Cert))
+		if err != nil {
+			return ocr3types.JournalEntry[RI]{}, err
+		}
+		result.Cert = raw
+	}
+	return result, nil
+}
+
+func DeserializeOCR3JournalEntry[RI any](entry ocr3types.JournalEntry[RI]) (protocol.JournalEntry[RI], error) {
+	result := protocol.JournalEntry[RI]{
+		Time:        entry.Time,
+		Subprotocol: entry.Subprotocol,
+		Kind:        entry.Kind,
+		Sender:      entry.Sender,
+		Name:        entry.Name,
+		Epoch:       entry.Epoch,
+		Leader:      entry.Leader,
+		SeqNr:       entry.SeqNr,
+		PluginCall:  entry.PluginCall,
+		Randomness:  entry.Randomness,
+	}
+	if len(entry.Message) != 0 {
+		m, _, err := serialization.Deserialize[RI](entry.Message)
+		if err != nil {
+			return protocol.JournalEntry[RI]{}, fmt.Errorf("could not deserialize message: %w", err)
+		}
+		result.Message = m
+	}
+	if len(entry.Cert) != 0 {
+		p := serialization.CertifiedPrepareOrCommit{}
+		if err := proto.Unmarshal(entry.Cert, &p); err != nil {
+			return protocol.JournalEntry[RI]{}, fmt.Errorf("could not unmarshal cert: %w", err)
+		}
+		cert, err := serialization.CertifiedPrepareOrCommitFromProtoMessage(&p)
+		if err != nil {
+			return protocol.JournalEntry[RI]{}, fmt.Errorf("could not deserialize cert: %w", err)
+		}
+		result.Cert = cert
+	}
+	return result, nil
+}
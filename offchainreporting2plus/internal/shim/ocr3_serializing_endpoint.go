@@ -8,6 +8,7 @@ import (
 
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
@@ -23,6 +24,7 @@ type OCR3SerializingEndpoint[RI any] struct {
 	logger       commontypes.Logger
 	pluginLimits ocr3types.ReportingPluginLimits
 	n, f         int
+	compression  ocr3config.Compression
 
 	mutex        sync.Mutex
 	subprocesses subprocesses.Subprocesses
@@ -44,6 +46,7 @@ func NewOCR3SerializingEndpoint[RI any](
 	logger commontypes.Logger,
 	pluginLimits ocr3types.ReportingPluginLimits,
 	n, f int,
+	compression ocr3config.Compression,
 ) *OCR3SerializingEndpoint[RI] {
 	return &OCR3SerializingEndpoint[RI]{
 		chTelemetry,
@@ -53,6 +56,7 @@ func NewOCR3SerializingEndpoint[RI any](
 		logger,
 		pluginLimits,
 		n, f,
+		compression,
 
 		sync.Mutex{},
 		subprocesses.Subprocesses{},
@@ -89,7 +93,7 @@ func (n *OCR3SerializingEndpoint[RI]) serialize(msg protocol.Message[RI]) ([]byt
 		})
 		return nil, nil
 	}
-	sMsg, pbm, err := serialization.Serialize(msg)
+	sMsg, pbm, err := serialization.SerializeCompressed(msg, n.compression)
 	if err != nil {
 		n.logger.Error("OCR3SerializingEndpoint: Failed to serialize", commontypes.LogFields{
 			"message": msg,
@@ -100,7 +104,7 @@ func (n *OCR3SerializingEndpoint[RI]) serialize(msg protocol.Message[RI]) ([]byt
 }
 
 func (n *OCR3SerializingEndpoint[RI]) deserialize(raw []byte) (protocol.Message[RI], *serialization.MessageWrapper, error) {
-	m, pbm, err := serialization.Deserialize[RI](raw)
+	m, pbm, err := serialization.DeserializeCompressed[RI](raw, n.compression, n.pluginLimits)
 	if err != nil {
 		return nil, nil, err
 	}
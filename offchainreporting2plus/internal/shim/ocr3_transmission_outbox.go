@@ -0,0 +1,276 @@
+package shim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// SerializingOCR3TransmissionOutbox stores pending transmissions as protocol
+// state of an ocr3types.Database. Each pending transmission is stored under its
+// own key, with its report info encoded by ReportInfoCodec. A separate index
+// key lists the stored pending transmissions, since the database cannot
+// enumerate keys. For the same reason, the config digests that have an index
+// are listed under the zero config digest, so that
+// DeleteStalePendingTransmissions can find them.
+type SerializingOCR3TransmissionOutbox[RI any] struct {
+	BinaryDb        ocr3types.Database
+	ReportInfoCodec ocr3types.ReportInfoCodec[RI]
+}
+
+var _ protocol.TransmissionOutbox[struct{}] = (*SerializingOCR3TransmissionOutbox[struct{}])(nil)
+
+const (
+	transmissionOutboxIndexKey         = "transmission_outbox_index"
+	transmissionOutboxEntryKeyPrefix   = "transmission_outbox_entry/"
+	transmissionOutboxConfigDigestsKey = "transmission_outbox_config_digests"
+)
+
+type transmissionOutboxIndexEntry struct {
+	SeqNr uint64
+	Index int
+}
+
+func transmissionOutboxEntryKey(seqNr uint64, index int) string {
+	return fmt.Sprintf("%s%d/%d", transmissionOutboxEntryKeyPrefix, seqNr, index)
+}
+
+type serializedPendingTransmission struct {
+	SeqNr                uint64
+	Index                int
+	Report               []byte
+	Info                 []byte
+	AttributedSignatures []types.AttributedOnchainSignature
+	Deadline             time.Time
+	FailedAttempts       int
+}
+
+func (db *SerializingOCR3TransmissionOutbox[RI]) readIndex(ctx context.Context, configDigest types.ConfigDigest) ([]transmissionOutboxIndexEntry, error) {
+	raw, err := db.BinaryDb.ReadProtocolState(ctx, configDigest, transmissionOutboxIndexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var index []transmissionOutboxIndexEntry
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return nil, fmt.Errorf("could not decode transmission outbox index: %w", err)
+	}
+	return index, nil
+}
+
+// Writing an empty index is the same as deleting it.
+func (db *SerializingOCR3TransmissionOutbox[RI]) writeIndex(ctx context.Context, configDigest types.ConfigDigest, index []transmissionOutboxIndexEntry) error {
+	if len(index) == 0 {
+		return db.BinaryDb.WriteProtocolState(ctx, configDigest, transmissionOutboxIndexKey, nil)
+	}
+
+	raw, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return db.BinaryDb.WriteProtocolState(ctx, configDigest, transmissionOutboxIndexKey, raw)
+}
+
+func (db *SerializingOCR3TransmissionOutbox[RI]) readConfigDigests(ctx context.Context) ([]types.ConfigDigest, error) {
+	raw, err := db.BinaryDb.ReadProtocolState(ctx, types.ConfigDigest{}, transmissionOutboxConfigDigestsKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw)%len(types.ConfigDigest{}) != 0 {
+		return nil, fmt.Errorf("transmission outbox config digests have invalid length %v", len(raw))
+	}
+
+	var configDigests []types.ConfigDigest
+	for len(raw) > 0 {
+		var configDigest types.ConfigDigest
+		raw = raw[copy(configDigest[:], raw):]
+		configDigests = append(configDigests, configDigest)
+	}
+	return configDigests, nil
+}
+
+func (db *SerializingOCR3TransmissionOutbox[RI]) writeConfigDigests(ctx context.Context, configDigests []types.ConfigDigest) error {
+	var raw []byte
+	for _, configDigest := range configDigests {
+		raw = append(raw, configDigest[:]...)
+	}
+	return db.BinaryDb.WriteProtocolState(ctx, types.ConfigDigest{}, transmissionOutboxConfigDigestsKey, raw)
+}
+
+// Pending transmissions listed in the index whose entry is missing are
+// skipped.
+func (db *SerializingOCR3TransmissionOutbox[RI]) ReadPendingTransmissions(ctx context.Context, configDigest types.ConfigDigest) ([]protocol.PendingTransmission[RI], error) {
+	index, err := db.readIndex(ctx, configDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	var pendingTransmissions []protocol.PendingTransmission[RI]
+	for _, ie := range index {
+		raw, err := db.BinaryDb.ReadProtocolState(ctx, configDigest, transmissionOutboxEntryKey(ie.SeqNr, ie.Index))
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		var spt serializedPendingTransmission
+		if err := json.Unmarshal(raw, &spt); err != nil {
+			return nil, fmt.Errorf("could not decode pending transmission for seqNr %v, index %v: %w", ie.SeqNr, ie.Index, err)
+		}
+		info, err := db.ReportInfoCodec.DecodeReportInfo(spt.Info)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode report info of pending transmission for seqNr %v, index %v: %w", ie.SeqNr, ie.Index, err)
+		}
+
+		pendingTransmissions = append(pendingTransmissions, protocol.PendingTransmission[RI]{
+			spt.SeqNr,
+			spt.Index,
+			protocol.AttestedReportMany[RI]{
+				ocr3types.ReportWithInfo[RI]{
+					spt.Report,
+					info,
+				},
+				spt.AttributedSignatures,
+			},
+			spt.Deadline,
+			spt.FailedAttempts,
+		})
+	}
+	return pendingTransmissions, nil
+}
+
+// The entry is written before it is added to the index, so that the index
+// never refers to a missing entry. A crash in between leaves behind an entry
+// that is never read, at worst.
+func (db *SerializingOCR3TransmissionOutbox[RI]) WritePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, pendingTransmission protocol.PendingTransmission[RI]) error {
+	info, err := db.ReportInfoCodec.EncodeReportInfo(pendingTransmission.AttestedReport.ReportWithInfo.Info)
+	if err != nil {
+		return fmt.Errorf("could not encode report info: %w", err)
+	}
+
+	raw, err := json.Marshal(serializedPendingTransmission{
+		pendingTransmission.SeqNr,
+		pendingTransmission.Index,
+		pendingTransmission.AttestedReport.ReportWithInfo.Report,
+		info,
+		pendingTransmission.AttestedReport.AttributedSignatures,
+		pendingTransmission.Deadline,
+		pendingTransmission.FailedAttempts,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := db.BinaryDb.WriteProtocolState(ctx, configDigest, transmissionOutboxEntryKey(pendingTransmission.SeqNr, pendingTransmission.Index), raw); err != nil {
+		return err
+	}
+
+	index, err := db.readIndex(ctx, configDigest)
+	if err != nil {
+		return err
+	}
+	if len(index) == 0 {
+		// Record configDigest before its index is written, so that the index
+		// can always be found by DeleteStalePendingTransmissions.
+		configDigests, err := db.readConfigDigests(ctx)
+		if err != nil {
+			return err
+		}
+		known := false
+		for _, existing := range configDigests {
+			if existing == configDigest {
+				known = true
+				break
+			}
+		}
+		if !known {
+			if err := db.writeConfigDigests(ctx, append(configDigests, configDigest)); err != nil {
+				return err
+			}
+		}
+	}
+	ie := transmissionOutboxIndexEntry{pendingTransmission.SeqNr, pendingTransmission.Index}
+	for _, existing := range index {
+		if existing == ie {
+			return nil
+		}
+	}
+	index = append(index, ie)
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].SeqNr != index[j].SeqNr {
+			return index[i].SeqNr < index[j].SeqNr
+		}
+		return index[i].Index < index[j].Index
+	})
+	return db.writeIndex(ctx, configDigest, index)
+}
+
+// The entry is removed from the index before it is deleted, for the same reason
+// as in WritePendingTransmission.
+func (db *SerializingOCR3TransmissionOutbox[RI]) DeletePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, seqNr uint64, index int) error {
+	entries, err := db.readIndex(ctx, configDigest)
+	if err != nil {
+		return err
+	}
+	ie := transmissionOutboxIndexEntry{seqNr, index}
+	remaining := entries[:0]
+	for _, existing := range entries {
+		if existing != ie {
+			remaining = append(remaining, existing)
+		}
+	}
+	if len(remaining) != len(entries) {
+		if err := db.writeIndex(ctx, configDigest, remaining); err != nil {
+			return err
+		}
+	}
+
+	return db.BinaryDb.WriteProtocolState(ctx, configDigest, transmissionOutboxEntryKey(seqNr, index), nil)
+}
+
+// DeleteStalePendingTransmissions deletes the pending transmissions of all
+// config digests other than configDigest. Reports attested under an old
+// config won't be transmitted anymore once a new config has taken effect, so
+// call this whenever that happens.
+func (db *SerializingOCR3TransmissionOutbox[RI]) DeleteStalePendingTransmissions(ctx context.Context, configDigest types.ConfigDigest) error {
+	configDigests, err := db.readConfigDigests(ctx)
+	if err != nil {
+		return err
+	}
+
+	remaining := configDigests[:0]
+	for _, stale := range configDigests {
+		if stale == configDigest {
+			remaining = append(remaining, stale)
+			continue
+		}
+		index, err := db.readIndex(ctx, stale)
+		if err != nil {
+			return err
+		}
+		for _, ie := range index {
+			if err := db.BinaryDb.WriteProtocolState(ctx, stale, transmissionOutboxEntryKey(ie.SeqNr, ie.Index), nil); err != nil {
+				return err
+			}
+		}
+		// The index goes last, so that we can pick up where we left off if
+		// we fail midway.
+		if err := db.writeIndex(ctx, stale, nil); err != nil {
+			return err
+		}
+	}
+	return db.writeConfigDigests(ctx, remaining)
+}
@@ -0,0 +1,170 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// memoryDatabase is an in-memory ocr3types.Database without config storage.
+type memoryDatabase struct {
+	types.ConfigDatabase
+
+	mutex sync.Mutex
+	state map[types.ConfigDigest]map[string][]byte
+}
+
+var _ ocr3types.Database = (*memoryDatabase)(nil)
+
+func (db *memoryDatabase) ReadProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string) ([]byte, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.state[configDigest][key], nil
+}
+
+func (db *memoryDatabase) WriteProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string, value []byte) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.state == nil {
+		db.state = map[types.ConfigDigest]map[string][]byte{}
+	}
+	if db.state[configDigest] == nil {
+		db.state[configDigest] = map[string][]byte{}
+	}
+	if value == nil {
+		delete(db.state[configDigest], key)
+		if len(db.state[configDigest]) == 0 {
+			delete(db.state, configDigest)
+		}
+	} else {
+		db.state[configDigest][key] = value
+	}
+	return nil
+}
+
+type stringReportInfoCodec struct{}
+
+func (stringReportInfoCodec) EncodeReportInfo(info string) ([]byte, error) {
+	return []byte(info), nil
+}
+
+func (stringReportInfoCodec) DecodeReportInfo(raw []byte) (string, error) {
+	return string(raw), nil
+}
+
+func pendingTransmission(seqNr uint64, index int) protocol.PendingTransmission[string] {
+	return protocol.PendingTransmission[string]{
+		seqNr,
+		index,
+		protocol.AttestedReportMany[string]{
+			ocr3types.ReportWithInfo[string]{[]byte(fmt.Sprintf("report %v/%v", seqNr, index)), fmt.Sprintf("info %v/%v", seqNr, index)},
+			[]types.AttributedOnchainSignature{{[]byte("signature"), 1}},
+		},
+		time.Unix(1_700_000_000, 0).UTC(),
+		int(seqNr),
+	}
+}
+
+func checkPendingTransmissions(t *testing.T, outbox *SerializingOCR3TransmissionOutbox[string], configDigest types.ConfigDigest, expected ...protocol.PendingTransmission[string]) {
+	t.Helper()
+	pts, err := outbox.ReadPendingTransmissions(context.Background(), configDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != len(expected) {
+		t.Fatalf("read %v pending transmissions, expected %v", len(pts), len(expected))
+	}
+	for i := range pts {
+		if fmt.Sprint(pts[i]) != fmt.Sprint(expected[i]) {
+			t.Errorf("read %v, expected %v", pts[i], expected[i])
+		}
+	}
+}
+
+func TestTransmissionOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := &memoryDatabase{}
+	outbox := &SerializingOCR3TransmissionOutbox[string]{db, stringReportInfoCodec{}}
+	configDigest := types.ConfigDigest{1}
+
+	checkPendingTransmissions(t, outbox, configDigest)
+
+	// pending transmissions are read back in order
+	for _, pt := range []protocol.PendingTransmission[string]{
+		pendingTransmission(2, 0), pendingTransmission(1, 1), pendingTransmission(1, 0),
+	} {
+		if err := outbox.WritePendingTransmission(ctx, configDigest, pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPendingTransmissions(t, outbox, configDigest, pendingTransmission(1, 0), pendingTransmission(1, 1), pendingTransmission(2, 0))
+
+	// a restarted oracle sees the same pending transmissions
+	restarted := &SerializingOCR3TransmissionOutbox[string]{db, stringReportInfoCodec{}}
+	checkPendingTransmissions(t, restarted, configDigest, pendingTransmission(1, 0), pendingTransmission(1, 1), pendingTransmission(2, 0))
+
+	// writing again replaces
+	updated := pendingTransmission(1, 1)
+	updated.FailedAttempts = 5
+	if err := restarted.WritePendingTransmission(ctx, configDigest, updated); err != nil {
+		t.Fatal(err)
+	}
+	checkPendingTransmissions(t, restarted, configDigest, pendingTransmission(1, 0), updated, pendingTransmission(2, 0))
+
+	// deleting transmitted reports removes their entries
+	for _, key := range [][2]int{{1, 0}, {1, 1}, {3, 0}} {
+		if err := restarted.DeletePendingTransmission(ctx, configDigest, uint64(key[0]), key[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPendingTransmissions(t, restarted, configDigest, pendingTransmission(2, 0))
+	if err := restarted.DeletePendingTransmission(ctx, configDigest, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	checkPendingTransmissions(t, restarted, configDigest)
+	if _, ok := db.state[configDigest]; ok {
+		t.Errorf("keys left behind after deleting all pending transmissions: %v", db.state[configDigest])
+	}
+}
+
+func TestTransmissionOutboxDeletesStalePendingTransmissions(t *testing.T) {
+	ctx := context.Background()
+	db := &memoryDatabase{}
+	outbox := &SerializingOCR3TransmissionOutbox[string]{db, stringReportInfoCodec{}}
+	oldConfigDigest, newConfigDigest := types.ConfigDigest{1}, types.ConfigDigest{2}
+
+	for _, configDigest := range []types.ConfigDigest{oldConfigDigest, newConfigDigest} {
+		for seqNr := uint64(1); seqNr <= 3; seqNr++ {
+			if err := outbox.WritePendingTransmission(ctx, configDigest, pendingTransmission(seqNr, 0)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := outbox.DeleteStalePendingTransmissions(ctx, newConfigDigest); err != nil {
+		t.Fatal(err)
+	}
+	checkPendingTransmissions(t, outbox, newConfigDigest, pendingTransmission(1, 0), pendingTransmission(2, 0), pendingTransmission(3, 0))
+	if _, ok := db.state[oldConfigDigest]; ok {
+		t.Errorf("keys of old config digest left behind: %v", db.state[oldConfigDigest])
+	}
+
+	// once the new config's transmissions are done, nothing is left
+	for seqNr := uint64(1); seqNr <= 3; seqNr++ {
+		if err := outbox.DeletePendingTransmission(ctx, newConfigDigest, seqNr, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := outbox.DeleteStalePendingTransmissions(ctx, types.ConfigDigest{3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.state) != 0 {
+		t.Errorf("keys left behind: %v", db.state)
+	}
+}
@@ -0,0 +1,366 @@
+package ocr3confighelper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smartcontractkit/libocr/internal/byzquorum"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ConfigBuilder builds setConfig args for OCR3 and, unlike
+// ContractSetConfigArgsForTests, is meant for production use. Parameters are
+// set with the chainable setter methods. Check and Build verify the
+// relationships between parameters that the protocol depends on, in addition
+// to the checks every oracle performs when it receives a new config.
+//
+// A ConfigBuilder is not thread-safe.
+type ConfigBuilder struct {
+	deltaProgress               time.Duration
+	deltaResend                 time.Duration
+	deltaInitial                time.Duration
+	deltaRound                  time.Duration
+	deltaGrace                  time.Duration
+	deltaCertifiedCommitRequest time.Duration
+	deltaStage                  time.Duration
+	rMax                        uint64
+	s                           []int
+	oracles                     []confighelper.OracleIdentityExtra
+	f                           int
+
+	reportingPluginConfig []byte
+	onchainConfig         []byte
+
+	maxDurationQuery                        time.Duration
+	maxDurationObservation                  time.Duration
+	maxDurationShouldAcceptAttestedReport   time.Duration
+	maxDurationShouldTransmitAcceptedReport time.Duration
+
+	auxiliaryArgs AuxiliaryArgs
+}
+
+// NewConfigBuilder returns an empty ConfigBuilder. There are no defaults, all
+// durations and RMax must be set explicitly.
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// Oracles sets the oracles of the protocol instance and the number of them
+// that may be faulty.
+func (b *ConfigBuilder) Oracles(oracles []confighelper.OracleIdentityExtra, f int) *ConfigBuilder {
+	b.oracles = oracles
+	b.f = f
+	return b
+}
+
+func (b *ConfigBuilder) DeltaProgress(d time.Duration) *ConfigBuilder {
+	b.deltaProgress = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaResend(d time.Duration) *ConfigBuilder {
+	b.deltaResend = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaInitial(d time.Duration) *ConfigBuilder {
+	b.deltaInitial = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaRound(d time.Duration) *ConfigBuilder {
+	b.deltaRound = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaGrace(d time.Duration) *ConfigBuilder {
+	b.deltaGrace = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaCertifiedCommitRequest(d time.Duration) *ConfigBuilder {
+	b.deltaCertifiedCommitRequest = d
+	return b
+}
+
+func (b *ConfigBuilder) DeltaStage(d time.Duration) *ConfigBuilder {
+	b.deltaStage = d
+	return b
+}
+
+func (b *ConfigBuilder) RMax(rMax uint64) *ConfigBuilder {
+	b.rMax = rMax
+	return b
+}
+
+// TransmissionSchedule sets S, see PublicConfig.
+func (b *ConfigBuilder) TransmissionSchedule(s []int) *ConfigBuilder {
+	b.s = s
+	return b
+}
+
+func (b *ConfigBuilder) ReportingPluginConfig(reportingPluginConfig []byte) *ConfigBuilder {
+	b.reportingPluginConfig = reportingPluginConfig
+	return b
+}
+
+func (b *ConfigBuilder) OnchainConfig(onchainConfig []byte) *ConfigBuilder {
+	b.onchainConfig = onchainConfig
+	return b
+}
+
+// MaxDurations sets the maximum durations of the ReportingPlugin methods.
+func (b *ConfigBuilder) MaxDurations(
+	query time.Duration,
+	observation time.Duration,
+	shouldAcceptAttestedReport time.Duration,
+	shouldTransmitAcceptedReport time.Duration,
+) *ConfigBuilder {
+	b.maxDurationQuery = query
+	b.maxDurationObservation = observation
+	b.maxDurationShouldAcceptAttestedReport = shouldAcceptAttestedReport
+	b.maxDurationShouldTransmitAcceptedReport = shouldTransmitAcceptedReport
+	return b
+}
+
+// AuxiliaryArgs sets the leader selection, compression and source of
+// randomness for the shared secret.
+func (b *ConfigBuilder) AuxiliaryArgs(auxiliaryArgs AuxiliaryArgs) *ConfigBuilder {
+	b.auxiliaryArgs = auxiliaryArgs
+	return b
+}
+
+// ConfigReport describes the effective timings of a config. All estimates
+// ignore network latency and assume that the ReportingPlugin uses its
+// MaxDurations in full.
+type ConfigReport struct {
+	N             int
+	F             int
+	ByzQuorumSize int
+
+	// The leader starts rounds no more often than every MinRoundInterval.
+	MinRoundInterval time.Duration
+	// EstimatedRoundInterval additionally accounts for the leader waiting for
+	// the query and the observations.
+	EstimatedRoundInterval time.Duration
+	// How long an epoch lasts if its leader is correct and reaches RMax
+	// rounds
+	EstimatedEpochDuration time.Duration
+	// Time from the last progress until the next outcome is committed if a
+	// leader crashes and its successor is correct
+	LeaderFailureRecovery time.Duration
+	// Like LeaderFailureRecovery, but with F faulty leaders in a row that
+	// each stall their epoch for as long as possible
+	WorstCaseLeaderFailureRecovery time.Duration
+	// Time until the last stage of the transmission schedule starts
+	TransmissionScheduleDuration time.Duration
+
+	// Warnings about parameter choices that are permissible but likely
+	// unintended
+	Warnings []string
+}
+
+func (r ConfigReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "oracles: N=%d F=%d byzantine quorum=%d\n", r.N, r.F, r.ByzQuorumSize)
+	fmt.Fprintf(&sb, "minimum round interval:            %v\n", r.MinRoundInterval)
+	fmt.Fprintf(&sb, "estimated round interval:          %v\n", r.EstimatedRoundInterval)
+	fmt.Fprintf(&sb, "estimated epoch duration:          %v\n", r.EstimatedEpochDuration)
+	fmt.Fprintf(&sb, "leader failure recovery:           %v\n", r.LeaderFailureRecovery)
+	fmt.Fprintf(&sb, "worst-case leader failure recovery: %v (F=%d faulty leaders in a row)\n", r.WorstCaseLeaderFailureRecovery, r.F)
+	fmt.Fprintf(&sb, "transmission schedule duration:    %v\n", r.TransmissionScheduleDuration)
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&sb, "warning: %s\n", w)
+	}
+	return sb.String()
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// checkInvariants checks the relationships between parameters that the
+// protocol depends on for liveness. These are stricter than the checks the
+// oracles perform on a new config.
+func (b *ConfigBuilder) checkInvariants() error {
+	n := len(b.oracles)
+	if n == 0 {
+		return fmt.Errorf("no oracles")
+	}
+	for i, o := range b.oracles {
+		if err := config.CheckConfigEncryptionPublicKey(o.ConfigEncryptionPublicKey); err != nil {
+			return fmt.Errorf("oracle %v has invalid ConfigEncryptionPublicKey: %w", i, err)
+		}
+	}
+	if !(0 <= b.f && 3*b.f < n) {
+		return fmt.Errorf("F (%v) must be non-negative and less than N/3 (N = %v)", b.f, n)
+	}
+
+	if !(b.deltaRound+b.deltaGrace < b.deltaProgress) {
+		return fmt.Errorf("DeltaProgress (%v) must be greater than DeltaRound (%v) + DeltaGrace (%v)",
+			b.deltaProgress, b.deltaRound, b.deltaGrace)
+	}
+	if sum := b.maxDurationQuery + b.maxDurationObservation + b.deltaGrace; !(sum < b.deltaProgress) {
+		return fmt.Errorf("MaxDurationQuery (%v) + MaxDurationObservation (%v) + DeltaGrace (%v) must be less than DeltaProgress (%v)",
+			b.maxDurationQuery, b.maxDurationObservation, b.deltaGrace, b.deltaProgress)
+	}
+	if sum := b.maxDurationShouldAcceptAttestedReport + b.maxDurationShouldTransmitAcceptedReport; !(sum < b.deltaProgress) {
+		return fmt.Errorf("MaxDurationShouldAcceptAttestedReport (%v) + MaxDurationShouldTransmitAcceptedReport (%v) must be less than DeltaProgress (%v)",
+			b.maxDurationShouldAcceptAttestedReport, b.maxDurationShouldTransmitAcceptedReport, b.deltaProgress)
+	}
+
+	if !(1 <= len(b.s) && len(b.s) <= n) {
+		return fmt.Errorf("len(S) (%v) must be between 1 and N (%v)", len(b.s), n)
+	}
+	sumS := 0
+	for i, s := range b.s {
+		if s < 0 {
+			return fmt.Errorf("S[%v] (%v) must be non-negative", i, s)
+		}
+		sumS += s
+	}
+	if sumS != n {
+		return fmt.Errorf("sum of S (%v) must equal N (%v)", sumS, n)
+	}
+
+	if b.rMax == 0 {
+		return fmt.Errorf("RMax must be greater than zero")
+	}
+	return nil
+}
+
+func (b *ConfigBuilder) report() ConfigReport {
+	n := len(b.oracles)
+	minRoundInterval := maxDuration(b.deltaRound, b.deltaGrace)
+	roundInterval := maxDuration(b.deltaRound, b.maxDurationQuery+b.maxDurationObservation+b.deltaGrace)
+	var epochDuration time.Duration
+	if b.rMax <= uint64(1<<63-1)/uint64(roundInterval+1) {
+		epochDuration = time.Duration(b.rMax) * roundInterval
+	}
+	transmissionScheduleDuration := time.Duration(0)
+	if len(b.s) > 1 {
+		transmissionScheduleDuration = time.Duration(len(b.s)-1) * b.deltaStage
+	}
+
+	var warnings []string
+	if maxF := (n - 1) / 3; b.f < maxF {
+		warnings = append(warnings, fmt.Sprintf("F (%v) is lower than the %v faulty oracles that N (%v) oracles can tolerate", b.f, maxF, n))
+	}
+	if sum := b.maxDurationShouldAcceptAttestedReport + b.maxDurationShouldTransmitAcceptedReport; sum >= roundInterval {
+		warnings = append(warnings, fmt.Sprintf("MaxDurationShouldAcceptAttestedReport + MaxDurationShouldTransmitAcceptedReport (%v) is not less than the estimated round interval (%v), transmissions may pile up if every round produces a report", sum, roundInterval))
+	}
+	if b.deltaInitial > b.deltaProgress {
+		warnings = append(warnings, fmt.Sprintf("DeltaInitial (%v) is greater than DeltaProgress (%v), crashed leaders will be detected slower than stalled ones", b.deltaInitial, b.deltaProgress))
+	}
+	if b.deltaResend > b.deltaProgress {
+		warnings = append(warnings, fmt.Sprintf("DeltaResend (%v) is greater than DeltaProgress (%v), recovering oracles will take long to rejoin", b.deltaResend, b.deltaProgress))
+	}
+	if b.rMax < 10 {
+		warnings = append(warnings, fmt.Sprintf("RMax (%v) is low, epochs and thus leaders will change very frequently", b.rMax))
+	}
+	if transmissionScheduleDuration > b.deltaProgress {
+		warnings = append(warnings, fmt.Sprintf("the transmission schedule takes %v to reach its last stage, which is longer than DeltaProgress (%v)", transmissionScheduleDuration, b.deltaProgress))
+	}
+
+	return ConfigReport{
+		n,
+		b.f,
+		byzquorum.Size(n, b.f),
+
+		minRoundInterval,
+		roundInterval,
+		epochDuration,
+		b.deltaProgress + b.deltaInitial + roundInterval,
+		time.Duration(b.f+1)*b.deltaProgress + b.deltaInitial + roundInterval,
+		transmissionScheduleDuration,
+
+		warnings,
+	}
+}
+
+// Check verifies the config and returns a report of its effective timings.
+// It returns an error if the config violates an invariant or would be
+// rejected by the oracles.
+func (b *ConfigBuilder) Check() (ConfigReport, error) {
+	if err := b.checkInvariants(); err != nil {
+		return ConfigReport{}, err
+	}
+	if _, _, _, _, _, _, err := b.build(); err != nil {
+		return ConfigReport{}, err
+	}
+	return b.report(), nil
+}
+
+// Build verifies the config like Check and returns the corresponding setConfig
+// args.
+func (b *ConfigBuilder) Build() (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f uint8,
+	onchainConfig []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	if err := b.checkInvariants(); err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	return b.build()
+}
+
+func (b *ConfigBuilder) build() (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f uint8,
+	onchainConfig []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err = ContractSetConfigArgsForTestsWithAuxiliaryArgs(
+		b.deltaProgress,
+		b.deltaResend,
+		b.deltaInitial,
+		b.deltaRound,
+		b.deltaGrace,
+		b.deltaCertifiedCommitRequest,
+		b.deltaStage,
+		b.rMax,
+		b.s,
+		b.oracles,
+		b.reportingPluginConfig,
+		b.maxDurationQuery,
+		b.maxDurationObservation,
+		b.maxDurationShouldAcceptAttestedReport,
+		b.maxDurationShouldTransmitAcceptedReport,
+		b.f,
+		b.onchainConfig,
+		b.auxiliaryArgs,
+	)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+
+	// Decode the config just like the oracles will, so that we catch
+	// everything they would reject, e.g. duplicate keys.
+	if _, err := ocr3config.PublicConfigFromContractConfig(false, types.ContractConfig{
+		types.ConfigDigest{},
+		0,
+		signers,
+		transmitters,
+		f,
+		onchainConfig,
+		offchainConfigVersion,
+		offchainConfig,
+	}); err != nil {
+		return nil, nil, 0, nil, 0, nil, fmt.Errorf("oracles would reject config: %w", err)
+	}
+	return signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, nil
+}
@@ -0,0 +1,229 @@
+package ocr3confighelper_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	ragetypes "github.com/smartcontractkit/libocr/ragep2p/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+func newTestOracles(t *testing.T, n int) []confighelper.OracleIdentityExtra {
+	var oracles []confighelper.OracleIdentityExtra
+	for i := 0; i < n; i++ {
+		offchainPublicKey, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, peerSecretKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		peerID, err := ragetypes.PeerIDFromPrivateKey(peerSecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var secretKey [32]byte
+		if _, err := rand.Read(secretKey[:]); err != nil {
+			t.Fatal(err)
+		}
+		publicKey, err := curve25519.X25519(secretKey[:], curve25519.Basepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var configEncryptionPublicKey types.ConfigEncryptionPublicKey
+		copy(configEncryptionPublicKey[:], publicKey)
+		oracles = append(oracles, confighelper.OracleIdentityExtra{
+			confighelper.OracleIdentity{
+				types.OffchainPublicKey(offchainPublicKey),
+				types.OnchainPublicKey(bytes.Repeat([]byte{byte(i + 1)}, 20)),
+				peerID.String(),
+				types.Account(fmt.Sprintf("transmitter %v", i)),
+			},
+			configEncryptionPublicKey,
+		})
+	}
+	return oracles
+}
+
+// newTestBuilder returns a builder for a valid config with 4 oracles.
+func newTestBuilder(oracles []confighelper.OracleIdentityExtra) *ocr3confighelper.ConfigBuilder {
+	return ocr3confighelper.NewConfigBuilder().
+		Oracles(oracles, 1).
+		DeltaProgress(10*time.Second).
+		DeltaResend(10*time.Second).
+		DeltaInitial(3*time.Second).
+		DeltaRound(time.Second).
+		DeltaGrace(200*time.Millisecond).
+		DeltaCertifiedCommitRequest(time.Second).
+		DeltaStage(5*time.Second).
+		RMax(100).
+		TransmissionSchedule([]int{1, 1, 2}).
+		ReportingPluginConfig([]byte("plugin config")).
+		OnchainConfig([]byte("onchain config")).
+		MaxDurations(time.Second, time.Second, time.Second, time.Second)
+}
+
+func TestConfigBuilderBuild(t *testing.T) {
+	oracles := newTestOracles(t, 4)
+	signers, transmitters, f, onchainConfig, offchainConfigVersion, offchainConfig, err := newTestBuilder(oracles).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != 4 || len(transmitters) != 4 || f != 1 {
+		t.Fatalf("unexpected setConfig args: %v signers, %v transmitters, f = %v", len(signers), len(transmitters), f)
+	}
+
+	publicConfig, err := ocr3config.PublicConfigFromContractConfig(false, types.ContractConfig{
+		types.ConfigDigest{},
+		1,
+		signers,
+		transmitters,
+		f,
+		onchainConfig,
+		offchainConfigVersion,
+		offchainConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicConfig.DeltaProgress != 10*time.Second || publicConfig.DeltaRound != time.Second ||
+		publicConfig.DeltaStage != 5*time.Second || publicConfig.RMax != 100 ||
+		!reflect.DeepEqual(publicConfig.S, []int{1, 1, 2}) || publicConfig.F != 1 ||
+		publicConfig.MaxDurationObservation != time.Second ||
+		!bytes.Equal(publicConfig.ReportingPluginConfig, []byte("plugin config")) {
+		t.Errorf("unexpected public config %+v", publicConfig)
+	}
+	for i, transmitter := range transmitters {
+		if transmitter != oracles[i].TransmitAccount {
+			t.Errorf("transmitter %v is %v", i, transmitter)
+		}
+	}
+}
+
+func TestConfigBuilderReport(t *testing.T) {
+	report, err := newTestBuilder(newTestOracles(t, 4)).Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ocr3confighelper.ConfigReport{
+		N:             4,
+		F:             1,
+		ByzQuorumSize: 3,
+
+		MinRoundInterval: time.Second,
+		// MaxDurationQuery + MaxDurationObservation + DeltaGrace
+		EstimatedRoundInterval: 2200 * time.Millisecond,
+		EstimatedEpochDuration: 220 * time.Second,
+		// DeltaProgress + DeltaInitial + round interval
+		LeaderFailureRecovery: 15200 * time.Millisecond,
+		// (F+1) * DeltaProgress + DeltaInitial + round interval
+		WorstCaseLeaderFailureRecovery: 25200 * time.Millisecond,
+		// (len(S)-1) * DeltaStage
+		TransmissionScheduleDuration: 10 * time.Second,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("got report %+v, expected %+v", report, expected)
+	}
+
+	s := report.String()
+	for _, line := range []string{
+		"oracles: N=4 F=1 byzantine quorum=3\n",
+		"estimated round interval:          2.2s\n",
+		"worst-case leader failure recovery: 25.2s (F=1 faulty leaders in a row)\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("report %q doesn't contain %q", s, line)
+		}
+	}
+	if strings.Contains(s, "warning") {
+		t.Errorf("unexpected warnings in report %q", s)
+	}
+}
+
+func TestConfigBuilderWarnings(t *testing.T) {
+	oracles := newTestOracles(t, 7)
+	report, err := newTestBuilder(oracles).
+		Oracles(oracles, 1).
+		TransmissionSchedule([]int{1, 1, 1, 4}).
+		DeltaInitial(20*time.Second).
+		DeltaResend(20*time.Second).
+		RMax(5).
+		MaxDurations(100*time.Millisecond, 100*time.Millisecond, 2*time.Second, 2*time.Second).
+		Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, warning := range []string{
+		"F (1) is lower than the 2 faulty oracles that N (7) oracles can tolerate",
+		"is not less than the estimated round interval",
+		"DeltaInitial (20s) is greater than DeltaProgress (10s)",
+		"DeltaResend (20s) is greater than DeltaProgress (10s)",
+		"RMax (5) is low",
+		"the transmission schedule takes 15s to reach its last stage",
+	} {
+		found := false
+		for _, w := range report.Warnings {
+			found = found || strings.Contains(w, warning)
+		}
+		if !found {
+			t.Errorf("no warning %q in %q", warning, report.Warnings)
+		}
+	}
+	if !strings.Contains(report.String(), "warning: RMax (5) is low") {
+		t.Errorf("report %q doesn't contain warnings", report.String())
+	}
+}
+
+func TestConfigBuilderInvariants(t *testing.T) {
+	oracles := newTestOracles(t, 4)
+	invalidKey := append([]confighelper.OracleIdentityExtra{}, oracles...)
+	invalidKey[2].ConfigEncryptionPublicKey = types.ConfigEncryptionPublicKey{}
+	duplicate := append([]confighelper.OracleIdentityExtra{}, oracles...)
+	duplicate[3] = duplicate[0]
+
+	for _, test := range []struct {
+		name   string
+		modify func(b *ocr3confighelper.ConfigBuilder)
+		err    string
+	}{
+		{"NoOracles", func(b *ocr3confighelper.ConfigBuilder) { b.Oracles(nil, 0) }, "no oracles"},
+		{"InvalidConfigEncryptionPublicKey", func(b *ocr3confighelper.ConfigBuilder) { b.Oracles(invalidKey, 1) }, "oracle 2 has invalid ConfigEncryptionPublicKey"},
+		{"FTooLarge", func(b *ocr3confighelper.ConfigBuilder) { b.Oracles(oracles, 2) }, "F (2) must be non-negative and less than N/3 (N = 4)"},
+		{"NegativeF", func(b *ocr3confighelper.ConfigBuilder) { b.Oracles(oracles, -1) }, "F (-1) must be non-negative"},
+		{"DeltaProgressTooSmall", func(b *ocr3confighelper.ConfigBuilder) { b.DeltaProgress(1200 * time.Millisecond) }, "DeltaProgress (1.2s) must be greater than DeltaRound (1s) + DeltaGrace (200ms)"},
+		{"ObservationExceedsRoundBudget", func(b *ocr3confighelper.ConfigBuilder) {
+			b.MaxDurations(5*time.Second, 5*time.Second, time.Second, time.Second)
+		}, "MaxDurationQuery (5s) + MaxDurationObservation (5s) + DeltaGrace (200ms) must be less than DeltaProgress (10s)"},
+		{"TransmissionExceedsRoundBudget", func(b *ocr3confighelper.ConfigBuilder) {
+			b.MaxDurations(time.Second, time.Second, 5*time.Second, 5*time.Second)
+		}, "MaxDurationShouldAcceptAttestedReport (5s) + MaxDurationShouldTransmitAcceptedReport (5s) must be less than DeltaProgress (10s)"},
+		{"EmptyS", func(b *ocr3confighelper.ConfigBuilder) { b.TransmissionSchedule(nil) }, "len(S) (0) must be between 1 and N (4)"},
+		{"SLongerThanN", func(b *ocr3confighelper.ConfigBuilder) { b.TransmissionSchedule([]int{1, 1, 1, 1, 0}) }, "len(S) (5) must be between 1 and N (4)"},
+		{"NegativeS", func(b *ocr3confighelper.ConfigBuilder) { b.TransmissionSchedule([]int{5, -1}) }, "S[1] (-1) must be non-negative"},
+		{"SumOfSNotN", func(b *ocr3confighelper.ConfigBuilder) { b.TransmissionSchedule([]int{1, 1}) }, "sum of S (2) must equal N (4)"},
+		{"ZeroRMax", func(b *ocr3confighelper.ConfigBuilder) { b.RMax(0) }, "RMax must be greater than zero"},
+		{"DuplicateOracles", func(b *ocr3confighelper.ConfigBuilder) { b.Oracles(duplicate, 1) }, "oracles would reject config"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b := newTestBuilder(oracles)
+			test.modify(b)
+			if _, err := b.Check(); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Check returned error %v, expected %q", err, test.err)
+			}
+			if _, _, _, _, _, _, err := b.Build(); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Build returned error %v, expected %q", err, test.err)
+			}
+		})
+	}
+}
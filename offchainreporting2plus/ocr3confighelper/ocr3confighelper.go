@@ -32,11 +32,47 @@ type PublicConfig struct {
 	MaxDurationShouldAcceptAttestedReport   time.Duration
 	MaxDurationShouldTransmitAcceptedReport time.Duration
 
+	LeaderSelection LeaderSelectionConfig
+	Compression     Compression
+
 	F             int
 	OnchainConfig []byte
 	ConfigDigest  types.ConfigDigest
 }
 
+// LeaderSelectionConfig determines how the leader of each epoch is picked. The
+// zero value picks every oracle equally often, as in earlier versions of the
+// protocol.
+//
+// Leaders that repeatedly time out aren't demoted automatically, since oracles
+// don't agree on a history of failed epochs and would end up disagreeing on
+// leaders. Lower the weights of slow oracles with a config change instead.
+type LeaderSelectionConfig struct {
+	// Weights[i] is the relative frequency with which oracle i is picked as
+	// leader, at most MaxLeaderWeight. Oracles with weight 0 are never
+	// picked. If empty, every oracle has weight 1. Otherwise, there must be
+	// one weight per oracle, and more than F of them must be positive.
+	Weights []int
+}
+
+// Compression determines how the query, observation and outcome payloads of
+// protocol messages are compressed on the wire. Signatures cover the
+// uncompressed payloads, so ReportingPlugins are unaffected by the choice.
+type Compression uint32
+
+const (
+	CompressionNone   = Compression(ocr3config.CompressionNone)
+	CompressionSnappy = Compression(ocr3config.CompressionSnappy)
+	CompressionZstd   = Compression(ocr3config.CompressionZstd)
+)
+
+func (c Compression) String() string {
+	return ocr3config.Compression(c).String()
+}
+
+// MaxLeaderWeight is the largest permissible leader weight.
+const MaxLeaderWeight = ocr3config.MaxLeaderWeight
+
 func (pc PublicConfig) N() int {
 	return len(pc.OracleIdentities)
 }
@@ -71,6 +107,10 @@ func PublicConfigFromContractConfig(skipResourceExhaustionChecks bool, change ty
 		internalPublicConfig.MaxDurationObservation,
 		internalPublicConfig.MaxDurationShouldAcceptAttestedReport,
 		internalPublicConfig.MaxDurationShouldTransmitAcceptedReport,
+		LeaderSelectionConfig{
+			internalPublicConfig.LeaderSelection.Weights,
+		},
+		Compression(internalPublicConfig.Compression),
 		internalPublicConfig.F,
 		internalPublicConfig.OnchainConfig,
 		internalPublicConfig.ConfigDigest,
@@ -125,7 +165,8 @@ func ContractSetConfigArgsForTestsMercuryV02(
 }
 
 // ContractSetConfigArgsForTestsOCR3 generates setConfig args for OCR3. Only use
-// this for testing, *not* for production.
+// this for testing, *not* for production. For production, use
+// NewConfigBuilder.
 func ContractSetConfigArgsForTests(
 	deltaProgress time.Duration,
 	deltaResend time.Duration,
@@ -152,6 +193,76 @@ func ContractSetConfigArgsForTests(
 	offchainConfigVersion uint64,
 	offchainConfig []byte,
 	err error,
+) {
+	return ContractSetConfigArgsForTestsWithAuxiliaryArgs(
+		deltaProgress,
+		deltaResend,
+		deltaInitial,
+		deltaRound,
+		deltaGrace,
+		deltaCertifiedCommitRequest,
+		deltaStage,
+		rMax,
+		s,
+		oracles,
+		reportingPluginConfig,
+		maxDurationQuery,
+		maxDurationObservation,
+		maxDurationShouldAcceptAttestedReport,
+		maxDurationShouldTransmitAcceptedReport,
+		f,
+		onchainConfig,
+		AuxiliaryArgs{},
+	)
+}
+
+// AuxiliaryArgs provides keyword-style extra configuration for calls to
+// ContractSetConfigArgsForTestsWithAuxiliaryArgs. The zero value results in
+// the same config as ContractSetConfigArgsForTests.
+type AuxiliaryArgs struct {
+	// Source of randomness for the shared secret. Defaults to crypto/rand.
+	RNG             io.Reader
+	LeaderSelection LeaderSelectionConfig
+	Compression     Compression
+}
+
+func (a AuxiliaryArgs) rng() io.Reader {
+	if a.RNG == nil {
+		return rand.Reader
+	}
+	return a.RNG
+}
+
+// ContractSetConfigArgsForTestsWithAuxiliaryArgs is like
+// ContractSetConfigArgsForTests, but also takes AuxiliaryArgs. Only use this
+// for testing, *not* for production.
+func ContractSetConfigArgsForTestsWithAuxiliaryArgs(
+	deltaProgress time.Duration,
+	deltaResend time.Duration,
+	deltaInitial time.Duration,
+	deltaRound time.Duration,
+	deltaGrace time.Duration,
+	deltaCertifiedCommitRequest time.Duration,
+	deltaStage time.Duration,
+	rMax uint64,
+	s []int,
+	oracles []confighelper.OracleIdentityExtra,
+	reportingPluginConfig []byte,
+	maxDurationQuery time.Duration,
+	maxDurationObservation time.Duration,
+	maxDurationShouldAcceptAttestedReport time.Duration,
+	maxDurationShouldTransmitAcceptedReport time.Duration,
+	f int,
+	onchainConfig []byte,
+	auxiliaryArgs AuxiliaryArgs,
+) (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f_ uint8,
+	onchainConfig_ []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
 ) {
 	identities := []config.OracleIdentity{}
 	configEncryptionPublicKeys := []types.ConfigEncryptionPublicKey{}
@@ -166,7 +277,7 @@ func ContractSetConfigArgsForTests(
 	}
 
 	sharedSecret := [config.SharedSecretSize]byte{}
-	if _, err := io.ReadFull(rand.Reader, sharedSecret[:]); err != nil {
+	if _, err := io.ReadFull(auxiliaryArgs.rng(), sharedSecret[:]); err != nil {
 		return nil, nil, 0, nil, 0, nil, err
 	}
 
@@ -187,6 +298,10 @@ func ContractSetConfigArgsForTests(
 			maxDurationObservation,
 			maxDurationShouldAcceptAttestedReport,
 			maxDurationShouldTransmitAcceptedReport,
+			ocr3config.LeaderSelectionConfig{
+				auxiliaryArgs.LeaderSelection.Weights,
+			},
+			ocr3config.Compression(auxiliaryArgs.Compression),
 			f,
 			onchainConfig,
 			types.ConfigDigest{},
@@ -0,0 +1,65 @@
+package ocr3confighelper
+
+import (
+	"io"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ContractSetConfigArgsReencryptingSharedSecret is the OCR3 version of
+// confighelper.ContractSetConfigArgsReencryptingSharedSecret, see there.
+func ContractSetConfigArgsReencryptingSharedSecret(
+	change types.ContractConfig,
+	offchainKeyring types.OffchainKeyring,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+) (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f_ uint8,
+	onchainConfig_ []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	// change is already live, so we don't second-guess its parameters
+	sharedConfig, _, err := ocr3config.SharedConfigFromContractConfigWithOffchainKeyring(true, change, offchainKeyring)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	if err := config.CheckConfigEncryptionPublicKeys(sharedSecretEncryptionPublicKeys, sharedConfig.N()); err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	return ocr3config.XXXContractSetConfigArgsFromSharedConfig(sharedConfig, sharedSecretEncryptionPublicKeys)
+}
+
+// ContractSetConfigArgsWithFreshSharedSecret is like
+// ContractSetConfigArgsReencryptingSharedSecret, but draws a new shared secret
+// from rand instead of decrypting the existing one.
+func ContractSetConfigArgsWithFreshSharedSecret(
+	change types.ContractConfig,
+	sharedSecretEncryptionPublicKeys []types.ConfigEncryptionPublicKey,
+	rand io.Reader,
+) (
+	signers []types.OnchainPublicKey,
+	transmitters []types.Account,
+	f_ uint8,
+	onchainConfig_ []byte,
+	offchainConfigVersion uint64,
+	offchainConfig []byte,
+	err error,
+) {
+	publicConfig, err := ocr3config.PublicConfigFromContractConfig(true, change)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	if err := config.CheckConfigEncryptionPublicKeys(sharedSecretEncryptionPublicKeys, publicConfig.N()); err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	sharedSecret, err := config.NewSharedSecret(rand)
+	if err != nil {
+		return nil, nil, 0, nil, 0, nil, err
+	}
+	return ocr3config.XXXContractSetConfigArgsFromSharedConfig(ocr3config.SharedConfig{publicConfig, sharedSecret}, sharedSecretEncryptionPublicKeys)
+}
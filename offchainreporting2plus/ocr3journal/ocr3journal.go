@@ -0,0 +1,134 @@
+// Package ocr3journal provides a file-backed ocr3types.Journal and a way of
+// replaying the recorded journal of an OCR3 protocol instance offline.
+//
+// To record a journal, set OCR3OracleArgs.Journal, e.g. to a FileJournal. To
+// replay it, read the entries for the relevant config digest with ReadFile and
+// pass them to Replay together with the contract config and keyrings of the
+// oracle that recorded them. Replay runs the oracle's outcome generation and
+// report attestation protocols on the recorded inputs and logs exactly as the
+// oracle did at the time, which makes it possible to reconstruct why, e.g., a
+// proposal was refused or a sequence number never got attested.
+package ocr3journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// FileJournal appends journal entries as JSON lines to one file per config
+// digest in a directory. Files are named after the hex-encoded config digest
+// with a ".jsonl" extension.
+type FileJournal[RI any] struct {
+	dir string
+
+	mutex  sync.Mutex
+	files  map[types.ConfigDigest]*os.File
+	closed bool
+}
+
+var _ ocr3types.Journal[struct{}] = (*FileJournal[struct{}])(nil)
+
+// NewFileJournal creates a FileJournal writing to dir, which is created if it
+// doesn't exist yet.
+func NewFileJournal[RI any](dir string) (*FileJournal[RI], error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create journal directory: %w", err)
+	}
+	return &FileJournal[RI]{
+		dir,
+		sync.Mutex{},
+		map[types.ConfigDigest]*os.File{},
+		false,
+	}, nil
+}
+
+// Path returns the path of the file holding the entries for configDigest.
+func (j *FileJournal[RI]) Path(configDigest types.ConfigDigest) string {
+	return filepath.Join(j.dir, configDigest.Hex()+".jsonl")
+}
+
+func (j *FileJournal[RI]) Append(configDigest types.ConfigDigest, entry ocr3types.JournalEntry[RI]) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal journal entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.closed {
+		return fmt.Errorf("journal is closed")
+	}
+
+	f, ok := j.files[configDigest]
+	if !ok {
+		f, err = os.OpenFile(j.Path(configDigest), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("could not open journal file: %w", err)
+		}
+		j.files[configDigest] = f
+	}
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("could not write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes all files held open by the journal. Subsequent calls to Append
+// fail.
+func (j *FileJournal[RI]) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.closed = true
+	var firstErr error
+	for configDigest, f := range j.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(j.files, configDigest)
+	}
+	return firstErr
+}
+
+// ReadEntries reads JSON lines as written by FileJournal. A truncated last
+// line, as left behind by a crash during Append, is ignored.
+func ReadEntries[RI any](r io.Reader) ([]ocr3types.JournalEntry[RI], error) {
+	var entries []ocr3types.JournalEntry[RI]
+	br := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// line without terminating newline is incomplete
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry ocr3types.JournalEntry[RI]
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("could not unmarshal journal entry on line %d: %w", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// ReadFile reads the entries stored in the file at path.
+func ReadFile[RI any](path string) ([]ocr3types.JournalEntry[RI], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEntries[RI](f)
+}
@@ -0,0 +1,184 @@
+package ocr3journal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+var (
+	digestA = types.ConfigDigest{0x00, 0x03, 0xaa}
+	digestB = types.ConfigDigest{0x00, 0x03, 0xbb}
+)
+
+func testEntries() []ocr3types.JournalEntry[string] {
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return []ocr3types.JournalEntry[string]{
+		{
+			Time:        start,
+			Subprotocol: ocr3types.JournalSubprotocolOutcomeGeneration,
+			Kind:        ocr3types.JournalEntryKindMessage,
+			Sender:      2,
+			Message:     []byte{0x01, 0x02},
+		},
+		{
+			Time:        start.Add(time.Second),
+			Subprotocol: ocr3types.JournalSubprotocolReportAttestation,
+			Kind:        ocr3types.JournalEntryKindPluginCall,
+			PluginCall: &ocr3types.JournalPluginCall[string]{
+				Method: "Reports",
+				Inputs: ocr3types.JournalPluginInputs{SeqNr: 3, Outcome: ocr3types.Outcome("outcome")},
+				Outputs: ocr3types.JournalPluginOutputs[string]{
+					Reports: []ocr3types.ReportWithInfo[string]{{types.Report("report"), "info"}},
+				},
+			},
+		},
+		{
+			Time:        start.Add(2 * time.Second),
+			Subprotocol: ocr3types.JournalSubprotocolReportAttestation,
+			Kind:        ocr3types.JournalEntryKindPluginCall,
+			PluginCall: &ocr3types.JournalPluginCall[string]{
+				Method: "Reports",
+				Inputs: ocr3types.JournalPluginInputs{SeqNr: 4},
+				// empty, not nil
+				Outputs: ocr3types.JournalPluginOutputs[string]{Reports: []ocr3types.ReportWithInfo[string]{}},
+			},
+		},
+		{
+			Time:        start.Add(3 * time.Second),
+			Subprotocol: ocr3types.JournalSubprotocolReportAttestation,
+			Kind:        ocr3types.JournalEntryKindRandomness,
+			Randomness:  []byte{0x07},
+		},
+	}
+}
+
+func TestFileJournal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "journal")
+	journal, err := NewFileJournal[string](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := testEntries()
+	for _, entry := range entries {
+		if err := journal.Append(digestA, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journal.Append(digestB, entries[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if journal.Path(digestA) != filepath.Join(dir, digestA.Hex()+".jsonl") {
+		t.Errorf("unexpected path %v", journal.Path(digestA))
+	}
+	read, err := ReadFile[string](journal.Path(digestA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, entries) {
+		t.Errorf("read entries %+v, expected %+v", read, entries)
+	}
+	if read[2].PluginCall.Outputs.Reports == nil {
+		t.Error("empty reports were read back as nil")
+	}
+	read, err = ReadFile[string](journal.Path(digestB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, entries[:1]) {
+		t.Errorf("read entries %+v for second config digest", read)
+	}
+
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Append(digestA, entries[0]); err == nil {
+		t.Error("appended to closed journal")
+	}
+
+	// appending after a restart continues the existing file
+	journal, err = NewFileJournal[string](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if err := journal.Append(digestB, entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	read, err = ReadFile[string](journal.Path(digestB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, entries[:2]) {
+		t.Errorf("read entries %+v after restart", read)
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := NewFileJournal[string](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := testEntries()
+	for _, entry := range entries {
+		if err := journal.Append(digestA, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(journal.Path(digestA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a truncated last line is ignored
+	truncated := string(raw[:len(raw)-10])
+	read, err := ReadEntries[string](strings.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, entries[:len(entries)-1]) {
+		t.Errorf("read entries %+v from truncated journal", read)
+	}
+
+	// a damaged line in the middle isn't
+	lines := strings.SplitAfter(string(raw), "\n")
+	lines[1] = "{not json\n"
+	if _, err := ReadEntries[string](strings.NewReader(strings.Join(lines, ""))); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := ReadFile[string](filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Error("read missing file")
+	}
+}
+
+func TestReplayRejectsInvalidArgs(t *testing.T) {
+	if _, err := Replay(context.Background(), ReplayArgs[string]{
+		Entries: testEntries(),
+		Logger:  nopLogger{},
+	}); err == nil {
+		t.Error("replayed with invalid contract config")
+	}
+}
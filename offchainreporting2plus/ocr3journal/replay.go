@@ -0,0 +1,138 @@
+package ocr3journal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ReplayArgs contains everything needed to replay the journal of an OCR3
+// protocol instance. The keyrings, PeerID and TransmitAccount must be those of
+// the oracle that recorded the journal.
+type ReplayArgs[RI any] struct {
+	// The contract config of the protocol instance. Its config digest should
+	// match the config digest the entries were recorded under.
+	ContractConfig types.ContractConfig
+
+	// The entries recorded for the protocol instance, in the order in which
+	// they were recorded.
+	Entries []ocr3types.JournalEntry[RI]
+
+	// LocalConfig the oracle was running with at the time.
+	LocalConfig types.LocalConfig
+
+	// Logger receives the logs of the replayed protocol.
+	Logger commontypes.Logger
+
+	OffchainKeyring types.OffchainKeyring
+	OnchainKeyring  ocr3types.OnchainKeyring[RI]
+	PeerID          string
+	TransmitAccount types.Account
+}
+
+// ReplayOutbound is a message the replayed oracle sent.
+type ReplayOutbound struct {
+	Broadcast bool
+	// Only meaningful if Broadcast is false
+	To commontypes.OracleID
+	// Serialized message, in the same format as sent over the network
+	Message []byte
+}
+
+// ReplayResult describes the state of the replayed oracle at the end of the
+// replay, what it did during the replay, and where the replay diverged from
+// the recorded execution.
+type ReplayResult struct {
+	Epoch          uint64
+	Leader         commontypes.OracleID
+	SeqNr          uint64
+	CommittedSeqNr uint64
+	FollowerPhase  string
+	LeaderPhase    string
+
+	HighestAttestedSeqNr uint64
+
+	CommittedSeqNrs []uint64
+	AttestedSeqNrs  []uint64
+	Outbound        []ReplayOutbound
+
+	// Empty if the replay faithfully reproduced the recorded execution
+	Divergences []string
+}
+
+// Replay feeds the outcome generation and report attestation entries of a
+// journal back through the protocol. Calls to the ReportingPlugin are served
+// from the recorded values, no actual ReportingPlugin is needed. Pacemaker
+// entries are ignored.
+//
+// Replay returns an error if the arguments are invalid. Problems with the
+// journal itself are reported in ReplayResult.Divergences.
+func Replay[RI any](ctx context.Context, args ReplayArgs[RI]) (ReplayResult, error) {
+	sharedConfig, oid, err := ocr3config.SharedConfigFromContractConfig(
+		args.LocalConfig.DevelopmentMode == types.EnableDangerousDevelopmentMode,
+		args.ContractConfig,
+		args.OffchainKeyring,
+		args.OnchainKeyring,
+		args.PeerID,
+		args.TransmitAccount,
+	)
+	if err != nil {
+		return ReplayResult{}, fmt.Errorf("could not compute shared config: %w", err)
+	}
+
+	entries := make([]protocol.JournalEntry[RI], 0, len(args.Entries))
+	for i, entry := range args.Entries {
+		deserialized, err := shim.DeserializeOCR3JournalEntry(entry)
+		if err != nil {
+			return ReplayResult{}, fmt.Errorf("could not deserialize entry %d: %w", i, err)
+		}
+		entries = append(entries, deserialized)
+	}
+
+	logger := loghelper.MakeRootLoggerWithContext(args.Logger).MakeChild(commontypes.LogFields{
+		"configDigest": sharedConfig.ConfigDigest,
+		"oid":          oid,
+	})
+
+	r := protocol.ReplayJournal[RI](
+		ctx,
+
+		sharedConfig,
+		entries,
+		oid,
+		args.LocalConfig,
+		logger,
+		args.OffchainKeyring,
+		args.OnchainKeyring,
+	)
+
+	result := ReplayResult{
+		r.Epoch,
+		r.Leader,
+		r.SeqNr,
+		r.CommittedSeqNr,
+		r.FollowerPhase,
+		r.LeaderPhase,
+		r.HighestAttestedSeqNr,
+		r.CommittedSeqNrs,
+		r.AttestedSeqNrs,
+		make([]ReplayOutbound, 0, len(r.Outbound)),
+		r.Divergences,
+	}
+	for _, out := range r.Outbound {
+		raw, _, err := serialization.Serialize(out.Message)
+		if err != nil {
+			return ReplayResult{}, fmt.Errorf("could not serialize outbound message: %w", err)
+		}
+		result.Outbound = append(result.Outbound, ReplayOutbound{out.Broadcast, out.To, raw})
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package ocr3simulation
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// database is an in-memory protocol.Database and protocol.TransmissionOutbox.
+// It reports every certified commit it persists to the simulation.
+type database[RI any] struct {
+	sim *Simulation[RI]
+	id  commontypes.OracleID
+
+	mutex          sync.Mutex
+	config         *types.ContractConfig
+	pacemakerState map[types.ConfigDigest]protocol.PacemakerState
+	cert           map[types.ConfigDigest]protocol.CertifiedPrepareOrCommit
+	outbox         map[types.ConfigDigest]map[outboxKey]protocol.PendingTransmission[RI]
+}
+
+type outboxKey struct {
+	seqNr uint64
+	index int
+}
+
+var _ protocol.Database = (*database[struct{}])(nil)
+var _ protocol.TransmissionOutbox[struct{}] = (*database[struct{}])(nil)
+
+func newDatabase[RI any](sim *Simulation[RI], id commontypes.OracleID) *database[RI] {
+	return &database[RI]{
+		sim:            sim,
+		id:             id,
+		pacemakerState: map[types.ConfigDigest]protocol.PacemakerState{},
+		cert:           map[types.ConfigDigest]protocol.CertifiedPrepareOrCommit{},
+		outbox:         map[types.ConfigDigest]map[outboxKey]protocol.PendingTransmission[RI]{},
+	}
+}
+
+func (db *database[RI]) ReadConfig(ctx context.Context) (*types.ContractConfig, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.config, nil
+}
+
+func (db *database[RI]) WriteConfig(ctx context.Context, config types.ContractConfig) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.config = &config
+	return nil
+}
+
+func (db *database[RI]) ReadPacemakerState(ctx context.Context, configDigest types.ConfigDigest) (protocol.PacemakerState, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.pacemakerState[configDigest], nil
+}
+
+func (db *database[RI]) WritePacemakerState(ctx context.Context, configDigest types.ConfigDigest, state protocol.PacemakerState) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.pacemakerState[configDigest] = state
+	return nil
+}
+
+func (db *database[RI]) ReadCert(ctx context.Context, configDigest types.ConfigDigest) (protocol.CertifiedPrepareOrCommit, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.cert[configDigest], nil
+}
+
+func (db *database[RI]) WriteCert(ctx context.Context, configDigest types.ConfigDigest, cert protocol.CertifiedPrepareOrCommit) error {
+	db.mutex.Lock()
+	db.cert[configDigest] = cert
+	db.mutex.Unlock()
+
+	if commit, ok := cert.(*protocol.CertifiedCommit); ok && !commit.IsGenesis() {
+		db.sim.recordCommit(CommittedOutcome{
+			db.id,
+			commit.CommitEpoch,
+			commit.SeqNr,
+			append(ocr3types.Outcome(nil), commit.Outcome...),
+			db.sim.clock.Now(),
+		})
+	}
+	return nil
+}
+
+func (db *database[RI]) ReadPendingTransmissions(ctx context.Context, configDigest types.ConfigDigest) ([]protocol.PendingTransmission[RI], error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	pendingTransmissions := make([]protocol.PendingTransmission[RI], 0, len(db.outbox[configDigest]))
+	for _, pt := range db.outbox[configDigest] {
+		pendingTransmissions = append(pendingTransmissions, pt)
+	}
+	sort.Slice(pendingTransmissions, func(i, j int) bool {
+		if pendingTransmissions[i].SeqNr != pendingTransmissions[j].SeqNr {
+			return pendingTransmissions[i].SeqNr < pendingTransmissions[j].SeqNr
+		}
+		return pendingTransmissions[i].Index < pendingTransmissions[j].Index
+	})
+	return pendingTransmissions, nil
+}
+
+func (db *database[RI]) WritePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, pendingTransmission protocol.PendingTransmission[RI]) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.outbox[configDigest] == nil {
+		db.outbox[configDigest] = map[outboxKey]protocol.PendingTransmission[RI]{}
+	}
+	db.outbox[configDigest][outboxKey{pendingTransmission.SeqNr, pendingTransmission.Index}] = pendingTransmission
+	return nil
+}
+
+func (db *database[RI]) DeletePendingTransmission(ctx context.Context, configDigest types.ConfigDigest, seqNr uint64, index int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	delete(db.outbox[configDigest], outboxKey{seqNr, index})
+	if len(db.outbox[configDigest]) == 0 {
+		delete(db.outbox, configDigest)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package ocr3simulation
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3journal"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+)
+
+// memoryJournal keeps the recorded entries in memory.
+type memoryJournal struct {
+	mutex   sync.Mutex
+	entries []protocol.JournalEntry[struct{}]
+}
+
+func (j *memoryJournal) Record(entry protocol.JournalEntry[struct{}]) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+// runJournaled runs a simulation whose oracles record their journals, and
+// returns the entries each oracle recorded after a round trip through a
+// FileJournal, together with the status of each oracle at the end of the run.
+func runJournaled(t *testing.T, cfg Config, d time.Duration) (*Simulation[struct{}], [][]protocol.JournalEntry[struct{}], []ocr3types.Status) {
+	t.Helper()
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	var journals []*memoryJournal
+	for i := 0; i < cfg.N; i++ {
+		journals = append(journals, &memoryJournal{})
+	}
+	sim, err := newSimulation[struct{}](cfg, counterPluginFactory{}, func(id commontypes.OracleID) protocol.Journal[struct{}] {
+		return journals[id]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(d)
+	var statuses []ocr3types.Status
+	for i := 0; i < cfg.N; i++ {
+		statuses = append(statuses, sim.Status(commontypes.OracleID(i)))
+	}
+	if err := sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make([][]protocol.JournalEntry[struct{}], 0, cfg.N)
+	for _, journal := range journals {
+		fileJournal, err := ocr3journal.NewFileJournal[struct{}](t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		serializing := shim.NewSerializingOCR3Journal[struct{}](sim.ConfigDigest(), fileJournal, nopLogger{})
+		for _, entry := range journal.entries {
+			serializing.Record(entry)
+		}
+		if err := fileJournal.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ocr3journal.ReadFile[struct{}](fileJournal.Path(sim.ConfigDigest()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(raw) != len(journal.entries) {
+			t.Fatalf("read %v entries, recorded %v", len(raw), len(journal.entries))
+		}
+		var oracleEntries []protocol.JournalEntry[struct{}]
+		for _, entry := range raw {
+			deserialized, err := shim.DeserializeOCR3JournalEntry(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oracleEntries = append(oracleEntries, deserialized)
+		}
+		entries = append(entries, oracleEntries)
+	}
+	return sim, entries, statuses
+}
+
+func replay(sim *Simulation[struct{}], id commontypes.OracleID, entries []protocol.JournalEntry[struct{}]) protocol.JournalReplayResult[struct{}] {
+	return protocol.ReplayJournal[struct{}](
+		context.Background(),
+		sim.sharedConfig,
+		entries,
+		id,
+		localConfig,
+		loghelper.MakeRootLoggerWithContext(nopLogger{}),
+		sim.offchainKeyrings[id],
+		sim.onchainKeyrings[id],
+	)
+}
+
+func TestJournalReplay(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"reliable network": {N: 4, F: 1, Seed: 1},
+		"lossy network": {
+			N:    4,
+			F:    1,
+			Seed: 42,
+			LinkRules: []LinkRule{
+				{
+					DropProbability:    0.05,
+					MinDelay:           10 * time.Millisecond,
+					MaxDelay:           300 * time.Millisecond,
+					ReorderProbability: 0.1,
+					ReorderDelay:       500 * time.Millisecond,
+				},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sim, entries, statuses := runJournaled(t, cfg, 20*time.Second)
+
+			for i := 0; i < cfg.N; i++ {
+				id := commontypes.OracleID(i)
+				kinds := map[ocr3types.JournalEntryKind]int{}
+				for _, entry := range entries[i] {
+					kinds[entry.Kind]++
+				}
+				if kinds[ocr3types.JournalEntryKindMessage] == 0 || kinds[ocr3types.JournalEntryKindPluginCall] == 0 || kinds[ocr3types.JournalEntryKindEvent] == 0 {
+					t.Fatalf("oracle %v recorded entries of kinds %v", id, kinds)
+				}
+
+				result := replay(sim, id, entries[i])
+				if len(result.Divergences) != 0 {
+					t.Errorf("replay of oracle %v diverged: %v", id, result.Divergences)
+				}
+				if result.CommittedSeqNr != sim.HighestCommittedSeqNr(id) || len(result.CommittedSeqNrs) == 0 {
+					t.Errorf("replay of oracle %v committed up to seqNr %v (%v), simulation up to %v",
+						id, result.CommittedSeqNr, result.CommittedSeqNrs, sim.HighestCommittedSeqNr(id))
+				}
+				if status := statuses[i].OutcomeGeneration; result.Epoch != status.Epoch || result.SeqNr != status.SeqNr || result.FollowerPhase != status.FollowerPhase {
+					t.Errorf("replay of oracle %v ended in epoch %v, seqNr %v, phase %v, simulation in %+v",
+						id, result.Epoch, result.SeqNr, result.FollowerPhase, status)
+				}
+				if len(result.AttestedSeqNrs) == 0 || len(result.Outbound) == 0 {
+					t.Errorf("replay of oracle %v attested %v and sent %v messages", id, result.AttestedSeqNrs, len(result.Outbound))
+				}
+			}
+		})
+	}
+}
+
+func TestJournalReplayDetectsDivergence(t *testing.T) {
+	sim, entries, _ := runJournaled(t, Config{N: 4, F: 1, Seed: 1}, 10*time.Second)
+
+	// drop the first recorded Observation call
+	var tampered []protocol.JournalEntry[struct{}]
+	dropped := false
+	for _, entry := range entries[0] {
+		if !dropped && entry.Kind == ocr3types.JournalEntryKindPluginCall && entry.PluginCall.Method == "Observation" {
+			dropped = true
+			continue
+		}
+		tampered = append(tampered, entry)
+	}
+	if !dropped {
+		t.Fatal("no Observation call recorded")
+	}
+
+	result := replay(sim, 0, tampered)
+	if len(result.Divergences) == 0 {
+		t.Fatal("replay of tampered journal didn't diverge")
+	}
+	if !strings.Contains(result.Divergences[0], "ReportingPlugin.Observation") {
+		t.Errorf("unexpected divergences %v", result.Divergences)
+	}
+
+	// recorded calls the replay doesn't make are reported, too
+	var last protocol.JournalEntry[struct{}]
+	for _, entry := range entries[0] {
+		if entry.Kind == ocr3types.JournalEntryKindPluginCall {
+			last = entry
+		}
+	}
+	result = replay(sim, 0, append(append([]protocol.JournalEntry[struct{}]{}, entries[0]...), last))
+	if len(result.Divergences) != 1 || !strings.Contains(result.Divergences[0], "was not replayed") {
+		t.Errorf("unexpected divergences %v", result.Divergences)
+	}
+
+	if result := replay(sim, 0, entries[0]); !reflect.DeepEqual(result.Divergences, []string(nil)) {
+		t.Errorf("replay of untampered journal diverged: %v", result.Divergences)
+	}
+}
@@ -0,0 +1,104 @@
+package ocr3simulation
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+type offchainKeyring struct {
+	signingKey    ed25519.PrivateKey
+	encryptionKey [curve25519.ScalarSize]byte
+}
+
+var _ types.OffchainKeyring = (*offchainKeyring)(nil)
+
+func newOffchainKeyring(rand io.Reader) (*offchainKeyring, error) {
+	_, signingKey, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate offchain signing key: %w", err)
+	}
+	kr := &offchainKeyring{signingKey: signingKey}
+	if _, err := io.ReadFull(rand, kr.encryptionKey[:]); err != nil {
+		return nil, fmt.Errorf("could not generate config encryption key: %w", err)
+	}
+	return kr, nil
+}
+
+func (kr *offchainKeyring) OffchainSign(msg []byte) (signature []byte, err error) {
+	return ed25519.Sign(kr.signingKey, msg), nil
+}
+
+func (kr *offchainKeyring) ConfigDiffieHellman(point [curve25519.PointSize]byte) (sharedPoint [curve25519.PointSize]byte, err error) {
+	p, err := curve25519.X25519(kr.encryptionKey[:], point[:])
+	if err != nil {
+		return [curve25519.PointSize]byte{}, err
+	}
+	copy(sharedPoint[:], p)
+	return sharedPoint, nil
+}
+
+func (kr *offchainKeyring) OffchainPublicKey() types.OffchainPublicKey {
+	var pk types.OffchainPublicKey
+	copy(pk[:], kr.signingKey.Public().(ed25519.PublicKey))
+	return pk
+}
+
+func (kr *offchainKeyring) ConfigEncryptionPublicKey() types.ConfigEncryptionPublicKey {
+	pk, err := curve25519.X25519(kr.encryptionKey[:], curve25519.Basepoint)
+	if err != nil {
+		// assertion
+		panic(err)
+	}
+	var result types.ConfigEncryptionPublicKey
+	copy(result[:], pk)
+	return result
+}
+
+// onchainKeyring signs reports with ed25519. It is not tied to any particular
+// chain.
+type onchainKeyring[RI any] struct {
+	signingKey ed25519.PrivateKey
+}
+
+var _ ocr3types.OnchainKeyring[struct{}] = (*onchainKeyring[struct{}])(nil)
+
+func newOnchainKeyring[RI any](rand io.Reader) (*onchainKeyring[RI], error) {
+	_, signingKey, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate onchain signing key: %w", err)
+	}
+	return &onchainKeyring[RI]{signingKey}, nil
+}
+
+func (kr *onchainKeyring[RI]) PublicKey() types.OnchainPublicKey {
+	return types.OnchainPublicKey(kr.signingKey.Public().(ed25519.PublicKey))
+}
+
+func onchainSignatureMsg(configDigest types.ConfigDigest, seqNr uint64, report types.Report) []byte {
+	msg := make([]byte, 0, len(configDigest)+8+len(report))
+	msg = append(msg, configDigest[:]...)
+	msg = binary.BigEndian.AppendUint64(msg, seqNr)
+	msg = append(msg, report...)
+	return msg
+}
+
+func (kr *onchainKeyring[RI]) Sign(configDigest types.ConfigDigest, seqNr uint64, rwi ocr3types.ReportWithInfo[RI]) (signature []byte, err error) {
+	return ed25519.Sign(kr.signingKey, onchainSignatureMsg(configDigest, seqNr, rwi.Report)), nil
+}
+
+func (kr *onchainKeyring[RI]) Verify(publicKey types.OnchainPublicKey, configDigest types.ConfigDigest, seqNr uint64, rwi ocr3types.ReportWithInfo[RI], signature []byte) bool {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(publicKey), onchainSignatureMsg(configDigest, seqNr, rwi.Report), signature)
+}
+
+func (kr *onchainKeyring[RI]) MaxSignatureLength() int {
+	return ed25519.SignatureSize
+}
@@ -0,0 +1,298 @@
+package ocr3simulation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/serialization"
+)
+
+// LinkRule describes how the simulated network treats the messages it
+// matches.
+type LinkRule struct {
+	// From and To restrict the rule to messages sent by/to the given oracles.
+	// An empty list matches every oracle.
+	From []commontypes.OracleID
+	To   []commontypes.OracleID
+	// MessageTypes restricts the rule to messages of the given types, e.g.
+	// "MessageProposal" or "MessageEpochStart". An empty list matches every
+	// message type.
+	MessageTypes []string
+	// The rule only applies to messages sent during the virtual time window
+	// [Start+ActiveFrom, Start+ActiveUntil). A zero ActiveUntil leaves the
+	// window open-ended.
+	ActiveFrom  time.Duration
+	ActiveUntil time.Duration
+
+	// Probability with which a matching message is dropped
+	DropProbability float64
+	// Matching messages that aren't dropped are delayed by a duration drawn
+	// uniformly at random from [MinDelay, MaxDelay].
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// Probability with which a matching message is held back for an
+	// additional ReorderDelay, allowing messages sent after it to overtake
+	// it.
+	ReorderProbability float64
+	ReorderDelay       time.Duration
+}
+
+func (r *LinkRule) matches(from commontypes.OracleID, to commontypes.OracleID, msgType string, sinceStart time.Duration) bool {
+	if sinceStart < r.ActiveFrom || (r.ActiveUntil != 0 && r.ActiveUntil <= sinceStart) {
+		return false
+	}
+	if len(r.From) != 0 && !containsOracle(r.From, from) {
+		return false
+	}
+	if len(r.To) != 0 && !containsOracle(r.To, to) {
+		return false
+	}
+	if len(r.MessageTypes) != 0 {
+		found := false
+		for _, t := range r.MessageTypes {
+			if t == msgType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsOracle(oracles []commontypes.OracleID, oracle commontypes.OracleID) bool {
+	for _, o := range oracles {
+		if o == oracle {
+			return true
+		}
+	}
+	return false
+}
+
+// messageType returns the name of msg's type without package and type
+// parameters, e.g. "MessageProposal".
+func messageType(msg interface{}) string {
+	name := reflect.TypeOf(msg).Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// messageKey identifies a message sent on the simulated network. It only
+// depends on the message and on how many identical messages have been sent on
+// the same link before, not on the order in which the goroutines of the
+// sending oracle happened to send their messages.
+type messageKey struct {
+	from       commontypes.OracleID
+	to         commontypes.OracleID
+	digest     [sha256.Size]byte
+	occurrence uint64
+}
+
+func (k messageKey) less(other messageKey) bool {
+	if k.from != other.from {
+		return k.from < other.from
+	}
+	if k.to != other.to {
+		return k.to < other.to
+	}
+	if c := bytes.Compare(k.digest[:], other.digest[:]); c != 0 {
+		return c < 0
+	}
+	return k.occurrence < other.occurrence
+}
+
+type inFlightMessage[RI any] struct {
+	deadline time.Time
+	key      messageKey
+	msg      protocol.Message[RI]
+}
+
+func (m *inFlightMessage[RI]) deliveredBefore(other *inFlightMessage[RI]) bool {
+	if !m.deadline.Equal(other.deadline) {
+		return m.deadline.Before(other.deadline)
+	}
+	return m.key.less(other.key)
+}
+
+// network sits in front of a protocol.SimpleNetwork and holds back every
+// message until the virtual clock reaches its delivery time.
+type network[RI any] struct {
+	sim    *Simulation[RI]
+	simple *protocol.SimpleNetwork[RI]
+	seed   int64
+	rules  []LinkRule
+
+	mutex       sync.Mutex
+	closed      bool
+	occurrences map[messageKey]uint64
+	inFlight    []inFlightMessage[RI]
+}
+
+func newNetwork[RI any](sim *Simulation[RI], n int, seed int64, rules []LinkRule) *network[RI] {
+	return &network[RI]{
+		sim:         sim,
+		simple:      protocol.NewSimpleNetwork[RI](n),
+		seed:        seed,
+		rules:       rules,
+		occurrences: map[messageKey]uint64{},
+	}
+}
+
+func (net *network[RI]) endpoint(id commontypes.OracleID) protocol.NetworkEndpoint[RI] {
+	return &endpoint[RI]{net, id}
+}
+
+// messageDigest hashes the wire encoding of msg.
+func messageDigest[RI any](msg protocol.Message[RI]) [sha256.Size]byte {
+	b, _, err := serialization.Serialize(msg)
+	if err != nil {
+		// can't happen for messages sent by honest oracles, but fall back to
+		// something deterministic anyway
+		b = []byte(fmt.Sprintf("%#v", msg))
+	}
+	return sha256.Sum256(b)
+}
+
+// messageRand returns the source of randomness for the message with key. It
+// only depends on the seed and key.
+func (net *network[RI]) messageRand(key messageKey) *rand.Rand {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.BigEndian, net.seed)
+	_ = binary.Write(h, binary.BigEndian, uint8(key.from))
+	_ = binary.Write(h, binary.BigEndian, uint8(key.to))
+	_, _ = h.Write(key.digest[:])
+	_ = binary.Write(h, binary.BigEndian, key.occurrence)
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+func (net *network[RI]) send(from commontypes.OracleID, to commontypes.OracleID, msg protocol.Message[RI]) {
+	now := net.sim.clock.Now()
+	msgType := messageType(msg)
+	key := messageKey{from, to, messageDigest(msg), 0}
+
+	net.mutex.Lock()
+	defer net.mutex.Unlock()
+
+	if net.closed {
+		return
+	}
+
+	key.occurrence = net.occurrences[key]
+	net.occurrences[key]++
+
+	rng := net.messageRand(key)
+	delay := time.Duration(0)
+	for i := range net.rules {
+		rule := &net.rules[i]
+		if !rule.matches(from, to, msgType, now.Sub(Start)) {
+			continue
+		}
+		if rng.Float64() < rule.DropProbability {
+			return
+		}
+		delay = rule.MinDelay
+		if rule.MaxDelay > rule.MinDelay {
+			delay += time.Duration(rng.Int63n(int64(rule.MaxDelay - rule.MinDelay + 1)))
+		}
+		if rng.Float64() < rule.ReorderProbability {
+			delay += rule.ReorderDelay
+		}
+		break
+	}
+
+	net.inFlight = append(net.inFlight, inFlightMessage[RI]{
+		now.Add(delay),
+		key,
+		msg,
+	})
+}
+
+// must hold mutex
+func (net *network[RI]) next() int {
+	next := -1
+	for i := range net.inFlight {
+		if next < 0 || net.inFlight[i].deliveredBefore(&net.inFlight[next]) {
+			next = i
+		}
+	}
+	return next
+}
+
+// nextDeadline returns the delivery time of the next in-flight message.
+func (net *network[RI]) nextDeadline() (time.Time, bool) {
+	net.mutex.Lock()
+	defer net.mutex.Unlock()
+	next := net.next()
+	if next < 0 {
+		return time.Time{}, false
+	}
+	return net.inFlight[next].deadline, true
+}
+
+// deliverNext delivers the next in-flight message, provided its delivery time
+// is at or before now. Messages are delivered in order of delivery time, ties
+// broken by messageKey. Returns true if a message was delivered.
+func (net *network[RI]) deliverNext(now time.Time) bool {
+	net.mutex.Lock()
+	next := net.next()
+	if net.closed || next < 0 || net.inFlight[next].deadline.After(now) {
+		net.mutex.Unlock()
+		return false
+	}
+	m := net.inFlight[next]
+	net.inFlight = append(net.inFlight[:next], net.inFlight[next+1:]...)
+	net.mutex.Unlock()
+
+	endpoint, _ := net.simple.Endpoint(m.key.from)
+	// Blocks if the receiver's buffer is full. This is fine since
+	// oracles continuously drain their network channel.
+	endpoint.SendTo(m.msg, m.key.to)
+	return true
+}
+
+func (net *network[RI]) close() {
+	net.mutex.Lock()
+	defer net.mutex.Unlock()
+	net.closed = true
+	net.inFlight = nil
+}
+
+type endpoint[RI any] struct {
+	net *network[RI]
+	id  commontypes.OracleID
+}
+
+var _ protocol.NetworkEndpoint[struct{}] = (*endpoint[struct{}])(nil)
+
+func (e *endpoint[RI]) SendTo(msg protocol.Message[RI], to commontypes.OracleID) {
+	e.net.send(e.id, to, msg)
+}
+
+func (e *endpoint[RI]) Broadcast(msg protocol.Message[RI]) {
+	for to := 0; to < e.net.sim.config.N; to++ {
+		e.net.send(e.id, commontypes.OracleID(to), msg)
+	}
+}
+
+func (e *endpoint[RI]) Receive() <-chan protocol.MessageWithSender[RI] {
+	endpoint, _ := e.net.simple.Endpoint(e.id)
+	return endpoint.Receive()
+}
+
+func (e *endpoint[RI]) Start() error { return nil }
+
+func (e *endpoint[RI]) Close() error { return nil }
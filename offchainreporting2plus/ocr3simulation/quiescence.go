@@ -0,0 +1,180 @@
+package ocr3simulation
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// goroutineTracker finds out whether all goroutines belonging to the oracles
+// are blocked, i.e. whether the oracles are done reacting to the last timer or
+// message. It inspects the goroutine dump provided by runtime.Stack instead of
+// waiting for some amount of real time, so that the simulation never races
+// with the oracles.
+//
+// The oracles' goroutines are found by following the "created by ... in
+// goroutine N" lines of the dump, starting at the goroutines that run the
+// oracles.
+type goroutineTracker struct {
+	mutex sync.Mutex
+	// ids of goroutines known to belong to the oracles. Since goroutine ids
+	// aren't reused, we can remember the ids of goroutines that have exited
+	// for as long as they may have living children we haven't seen yet.
+	tracked map[uint64]struct{}
+	buf     []byte
+}
+
+func newGoroutineTracker() *goroutineTracker {
+	return &goroutineTracker{
+		tracked: map[uint64]struct{}{},
+		buf:     make([]byte, 64*1024),
+	}
+}
+
+// register adds the calling goroutine and, from then on, its descendants.
+func (gt *goroutineTracker) register() {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	g, ok := parseGoroutineHeader(buf)
+	if !ok {
+		// assertion
+		panic("could not parse goroutine header: " + string(buf))
+	}
+	gt.mutex.Lock()
+	defer gt.mutex.Unlock()
+	gt.tracked[g.id] = struct{}{}
+}
+
+// quiescent reports whether every tracked goroutine is blocked.
+func (gt *goroutineTracker) quiescent() bool {
+	gt.mutex.Lock()
+	defer gt.mutex.Unlock()
+
+	for {
+		n := runtime.Stack(gt.buf, true)
+		if n < len(gt.buf) {
+			gt.buf = gt.buf[:cap(gt.buf)]
+			return gt.quiescentLocked(gt.buf[:n])
+		}
+		gt.buf = make([]byte, 2*len(gt.buf))
+	}
+}
+
+// must hold mutex
+func (gt *goroutineTracker) quiescentLocked(dump []byte) bool {
+	var goroutines []goroutine
+	for _, record := range bytes.Split(dump, []byte("\n\n")) {
+		if g, ok := parseGoroutine(record); ok {
+			goroutines = append(goroutines, g)
+		}
+	}
+
+	// Add descendants of tracked goroutines until nothing changes.
+	// Goroutines are listed in no particular order, so a child may appear
+	// before its parent.
+	for changed := true; changed; {
+		changed = false
+		for _, g := range goroutines {
+			if _, ok := gt.tracked[g.id]; ok {
+				continue
+			}
+			if _, ok := gt.tracked[g.parent]; ok {
+				gt.tracked[g.id] = struct{}{}
+				changed = true
+			}
+		}
+	}
+
+	alive := make(map[uint64]struct{}, len(goroutines))
+	result := true
+	for _, g := range goroutines {
+		if _, ok := gt.tracked[g.id]; !ok {
+			continue
+		}
+		alive[g.id] = struct{}{}
+		if !g.blocked {
+			result = false
+		}
+	}
+	// the living children of exited goroutines have been added above
+	for id := range gt.tracked {
+		if _, ok := alive[id]; !ok {
+			delete(gt.tracked, id)
+		}
+	}
+	return result
+}
+
+type goroutine struct {
+	id      uint64
+	parent  uint64
+	blocked bool
+}
+
+// Goroutine states in which a goroutine can only proceed once another
+// goroutine acts. Anything else, e.g. "running", "runnable" or "syscall",
+// counts as busy.
+var blockedStates = map[string]bool{
+	"chan receive":            true,
+	"chan receive (nil chan)": true,
+	"chan send":               true,
+	"chan send (nil chan)":    true,
+	"select":                  true,
+	"select (no cases)":       true,
+	"semacquire":              true,
+	"sync.Cond.Wait":          true,
+	"sync.Mutex.Lock":         true,
+	"sync.RWMutex.Lock":       true,
+	"sync.RWMutex.RLock":      true,
+	"sync.WaitGroup.Wait":     true,
+}
+
+// parseGoroutineHeader parses the first line of a goroutine's record in a
+// dump, e.g. "goroutine 7 [chan receive, 2 minutes]:".
+func parseGoroutineHeader(record []byte) (goroutine, bool) {
+	line, _, _ := bytes.Cut(record, []byte("\n"))
+	rest, ok := bytes.CutPrefix(line, []byte("goroutine "))
+	if !ok {
+		return goroutine{}, false
+	}
+	idStr, rest, ok := bytes.Cut(rest, []byte(" ["))
+	if !ok {
+		return goroutine{}, false
+	}
+	id, err := strconv.ParseUint(string(idStr), 10, 64)
+	if err != nil {
+		return goroutine{}, false
+	}
+	state, _, ok := bytes.Cut(rest, []byte("]"))
+	if !ok {
+		return goroutine{}, false
+	}
+	state, _, _ = bytes.Cut(state, []byte(","))
+	return goroutine{id, 0, blockedStates[string(state)]}, true
+}
+
+// parseGoroutine parses a goroutine's record in a dump. The parent is taken
+// from the "created by ... in goroutine N" line, if any.
+func parseGoroutine(record []byte) (goroutine, bool) {
+	record = bytes.TrimLeft(record, "\n")
+	g, ok := parseGoroutineHeader(record)
+	if !ok {
+		return goroutine{}, false
+	}
+	for _, line := range bytes.Split(record, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("created by ")) {
+			continue
+		}
+		i := bytes.LastIndex(line, []byte(" in goroutine "))
+		if i < 0 {
+			continue
+		}
+		parent, err := strconv.ParseUint(string(line[i+len(" in goroutine "):]), 10, 64)
+		if err != nil {
+			continue
+		}
+		g.parent = parent
+	}
+	return g, true
+}
@@ -0,0 +1,518 @@
+// Package ocr3simulation runs a complete OCR3 protocol instance in a single
+// process. All oracles share a virtual clock and a simulated network, so a run
+// only advances when the simulation driver advances it. This makes it
+// possible to replay a run from a single seed, e.g. to reproduce a consensus
+// bug or to check that a ReportingPlugin makes progress under adverse network
+// conditions.
+//
+// The simulation reuses the production protocol code (protocol.RunOracle)
+// unchanged. Keys, the config digest, the shared secret, the oracles' random
+// choices and every network decision (delay, drop, reorder) are derived from
+// Config.Seed. The simulation hands the oracles one timer or message at a
+// time and waits until all their goroutines are blocked again before handing
+// them the next one, so runs with the same Config and a deterministic
+// ReportingPlugin yield the same results. No real time passes while waiting:
+// the simulation inspects the state of the oracles' goroutines instead.
+//
+// Messages are delivered through protocol.SimpleNetwork, which logs every
+// message using the standard library's log package. Use log.SetOutput to
+// silence it.
+package ocr3simulation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/config/ocr3config"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/clock"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/shim"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3confighelper"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config describes a simulated protocol instance. Zero-valued durations,
+// RMax and S are replaced by the defaults documented on each field.
+type Config struct {
+	// Number of oracles
+	N int
+	// Maximum number of faulty oracles, must satisfy 3F < N
+	F int
+	// Seed from which all randomness of the simulation is derived
+	Seed int64
+
+	// Protocol parameters, see ocr3confighelper.PublicConfig for their
+	// meaning.
+	DeltaProgress               time.Duration // default: 10s
+	DeltaResend                 time.Duration // default: 10s
+	DeltaInitial                time.Duration // default: 3s
+	DeltaRound                  time.Duration // default: 1s
+	DeltaGrace                  time.Duration // default: 200ms
+	DeltaCertifiedCommitRequest time.Duration // default: 1s
+	DeltaStage                  time.Duration // default: 5s
+	RMax                        uint64        // default: 100
+	S                           []int         // default: [N], i.e. everybody transmits
+	LeaderSelection             ocr3confighelper.LeaderSelectionConfig
+
+	ReportingPluginConfig []byte
+	OnchainConfig         []byte
+
+	// Note that ReportingPlugin calls are bounded by these durations in
+	// real time, not virtual time. For reproducible runs, ReportingPlugins
+	// must not block, e.g. on their context.
+	MaxDurationQuery                        time.Duration // default: 1s
+	MaxDurationObservation                  time.Duration // default: 1s
+	MaxDurationShouldAcceptAttestedReport   time.Duration // default: 1s
+	MaxDurationShouldTransmitAcceptedReport time.Duration // default: 1s
+
+	// LinkRules are consulted in order for every message sent. The first
+	// matching rule determines how the message is treated. Messages that
+	// don't match any rule are delivered without delay.
+	LinkRules []LinkRule
+
+	// Logger receives the logs of all oracles, annotated with their oracle
+	// id. May be nil, in which case logs are discarded.
+	Logger commontypes.Logger
+
+	// Tracer receives the spans of all oracles. May be nil, in which case no
+	// spans are emitted. Span timestamps are in real time, not virtual time.
+	Tracer trace.Tracer
+}
+
+// LocalConfig of all simulated oracles
+var localConfig = types.LocalConfig{
+	BlockchainTimeout:                  time.Second,
+	ContractConfigConfirmations:        1,
+	ContractConfigTrackerPollInterval:  time.Second,
+	ContractTransmitterTransmitTimeout: time.Second,
+	DatabaseTimeout:                    time.Second,
+}
+
+// Start is the virtual time at which every simulation begins.
+var Start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// CommittedOutcome records that an oracle committed an outcome.
+type CommittedOutcome struct {
+	Oracle  commontypes.OracleID
+	Epoch   uint64
+	SeqNr   uint64
+	Outcome ocr3types.Outcome
+	// Virtual time of the commit
+	Time time.Time
+}
+
+// TransmittedReport records a call to ContractTransmitter.Transmit.
+type TransmittedReport[RI any] struct {
+	Oracle               commontypes.OracleID
+	ConfigDigest         types.ConfigDigest
+	SeqNr                uint64
+	ReportWithInfo       ocr3types.ReportWithInfo[RI]
+	AttributedSignatures []types.AttributedOnchainSignature
+	// Virtual time of the transmission
+	Time time.Time
+}
+
+type Simulation[RI any] struct {
+	config           Config
+	sharedConfig     ocr3config.SharedConfig
+	offchainKeyrings []*offchainKeyring
+	onchainKeyrings  []*onchainKeyring[RI]
+	clock            *clock.Virtual
+	network          *network[RI]
+
+	statusTrackers []*protocol.StatusTracker
+	registries     []*prometheus.Registry
+
+	// goroutines tracks the oracles' goroutines, see settle()
+	goroutines *goroutineTracker
+
+	mutex              sync.Mutex
+	committedOutcomes  []CommittedOutcome
+	transmittedReports []TransmittedReport[RI]
+
+	subprocesses subprocesses.Subprocesses
+	cancel       context.CancelFunc
+	closed       bool
+}
+
+// New sets up N oracles running the given ReportingPlugin. The oracles are
+// started immediately, but make no progress until Run is called.
+func New[RI any](cfg Config, reportingPluginFactory ocr3types.ReportingPluginFactory[RI]) (*Simulation[RI], error) {
+	return newSimulation(cfg, reportingPluginFactory, nil)
+}
+
+// newSimulation is like New. If journal is non-nil, the oracles record their
+// journals to the journals it returns.
+func newSimulation[RI any](
+	cfg Config,
+	reportingPluginFactory ocr3types.ReportingPluginFactory[RI],
+	journal func(commontypes.OracleID) protocol.Journal[RI],
+) (*Simulation[RI], error) {
+	cfg = withDefaults(cfg)
+	if !(0 <= cfg.F && 3*cfg.F < cfg.N) {
+		return nil, fmt.Errorf("F (%v) must be non-negative and less than N/3 (N = %v)", cfg.F, cfg.N)
+	}
+	if !(cfg.N <= types.MaxOracles) {
+		return nil, fmt.Errorf("N (%v) must be less than or equal MaxOracles (%v)", cfg.N, types.MaxOracles)
+	}
+	leaderSelection := ocr3config.LeaderSelectionConfig{
+		Weights: cfg.LeaderSelection.Weights,
+	}
+	if err := ocr3config.CheckLeaderSelectionConfig(leaderSelection, cfg.N, cfg.F); err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	offchainKeyrings := make([]*offchainKeyring, 0, cfg.N)
+	onchainKeyrings := make([]*onchainKeyring[RI], 0, cfg.N)
+	identities := make([]config.OracleIdentity, 0, cfg.N)
+	for i := 0; i < cfg.N; i++ {
+		offKr, err := newOffchainKeyring(rng)
+		if err != nil {
+			return nil, err
+		}
+		onKr, err := newOnchainKeyring[RI](rng)
+		if err != nil {
+			return nil, err
+		}
+		offchainKeyrings = append(offchainKeyrings, offKr)
+		onchainKeyrings = append(onchainKeyrings, onKr)
+		identities = append(identities, config.OracleIdentity{
+			OffchainPublicKey: offKr.OffchainPublicKey(),
+			OnchainPublicKey:  onKr.PublicKey(),
+			PeerID:            fmt.Sprintf("simulated-peer-%d", i),
+			TransmitAccount:   types.Account(fmt.Sprintf("simulated-transmitter-%d", i)),
+		})
+	}
+
+	var configDigest types.ConfigDigest
+	_, _ = rng.Read(configDigest[:])
+	var sharedSecret [config.SharedSecretSize]byte
+	_, _ = rng.Read(sharedSecret[:])
+	randomnessSeeds := make([]int64, 0, cfg.N)
+	for i := 0; i < cfg.N; i++ {
+		randomnessSeeds = append(randomnessSeeds, rng.Int63())
+	}
+
+	sharedConfig := ocr3config.SharedConfig{
+		PublicConfig: ocr3config.PublicConfig{
+			DeltaProgress:                           cfg.DeltaProgress,
+			DeltaResend:                             cfg.DeltaResend,
+			DeltaInitial:                            cfg.DeltaInitial,
+			DeltaRound:                              cfg.DeltaRound,
+			DeltaGrace:                              cfg.DeltaGrace,
+			DeltaCertifiedCommitRequest:             cfg.DeltaCertifiedCommitRequest,
+			DeltaStage:                              cfg.DeltaStage,
+			RMax:                                    cfg.RMax,
+			S:                                       cfg.S,
+			OracleIdentities:                        identities,
+			ReportingPluginConfig:                   cfg.ReportingPluginConfig,
+			MaxDurationQuery:                        cfg.MaxDurationQuery,
+			MaxDurationObservation:                  cfg.MaxDurationObservation,
+			MaxDurationShouldAcceptAttestedReport:   cfg.MaxDurationShouldAcceptAttestedReport,
+			MaxDurationShouldTransmitAcceptedReport: cfg.MaxDurationShouldTransmitAcceptedReport,
+			LeaderSelection:                         leaderSelection,
+			F:                                       cfg.F,
+			OnchainConfig:                           cfg.OnchainConfig,
+			ConfigDigest:                            configDigest,
+		},
+		SharedSecret: &sharedSecret,
+	}
+
+	sim := &Simulation[RI]{
+		config:           cfg,
+		sharedConfig:     sharedConfig,
+		offchainKeyrings: offchainKeyrings,
+		onchainKeyrings:  onchainKeyrings,
+		clock:            clock.NewVirtual(Start),
+		goroutines:       newGoroutineTracker(),
+	}
+	sim.network = newNetwork[RI](sim, cfg.N, cfg.Seed, cfg.LinkRules)
+	for i := 0; i < cfg.N; i++ {
+		sim.statusTrackers = append(sim.statusTrackers, protocol.NewStatusTracker())
+		sim.registries = append(sim.registries, prometheus.NewRegistry())
+	}
+
+	type oracleSetup struct {
+		plugin ocr3types.ReportingPlugin[RI]
+		limits ocr3types.ReportingPluginLimits
+	}
+	setups := make([]oracleSetup, 0, cfg.N)
+	for i := 0; i < cfg.N; i++ {
+		plugin, info, err := reportingPluginFactory.NewReportingPlugin(ocr3types.ReportingPluginConfig{
+			ConfigDigest:                            configDigest,
+			OracleID:                                commontypes.OracleID(i),
+			N:                                       cfg.N,
+			F:                                       cfg.F,
+			OnchainConfig:                           cfg.OnchainConfig,
+			OffchainConfig:                          cfg.ReportingPluginConfig,
+			EstimatedRoundInterval:                  cfg.DeltaRound,
+			MaxDurationQuery:                        cfg.MaxDurationQuery,
+			MaxDurationObservation:                  cfg.MaxDurationObservation,
+			MaxDurationShouldAcceptAttestedReport:   cfg.MaxDurationShouldAcceptAttestedReport,
+			MaxDurationShouldTransmitAcceptedReport: cfg.MaxDurationShouldTransmitAcceptedReport,
+		})
+		if err != nil {
+			for _, setup := range setups {
+				_ = setup.plugin.Close()
+			}
+			return nil, fmt.Errorf("error during NewReportingPlugin() for oracle %v: %w", i, err)
+		}
+		setups = append(setups, oracleSetup{plugin, info.Limits})
+	}
+
+	var rootLogger commontypes.Logger = nopLogger{}
+	if cfg.Logger != nil {
+		rootLogger = cfg.Logger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sim.cancel = cancel
+	var registered sync.WaitGroup
+	registered.Add(cfg.N)
+	for i := 0; i < cfg.N; i++ {
+		i := i
+		id := commontypes.OracleID(i)
+		logger := loghelper.MakeRootLoggerWithContext(rootLogger).MakeChild(commontypes.LogFields{"oid": id})
+		db := newDatabase[RI](sim, id)
+		var oracleJournal protocol.Journal[RI]
+		if journal != nil {
+			oracleJournal = journal(id)
+		}
+		sim.subprocesses.Go(func() {
+			sim.goroutines.register()
+			registered.Done()
+			defer loghelper.CloseLogError(setups[i].plugin, logger, "Simulation: error during reportingPlugin.Close()")
+			protocol.RunOracle[RI](
+				ctx,
+				sim.clock.Tagged(i),
+				sharedConfig,
+				&contractTransmitter[RI]{sim, id},
+				db,
+				id,
+				oracleJournal,
+				localConfig,
+				logger,
+				sim.registries[i],
+				sim.network.endpoint(id),
+				offchainKeyrings[i],
+				onchainKeyrings[i],
+				rand.New(rand.NewSource(randomnessSeeds[i])),
+				shim.LimitCheckOCR3ReportingPlugin[RI]{
+					Plugin: setups[i].plugin,
+					Limits: setups[i].limits,
+				},
+				sim.statusTrackers[i],
+				nopTelemetrySender{},
+				cfg.Tracer,
+				db,
+			)
+		})
+	}
+	registered.Wait()
+
+	return sim, nil
+}
+
+func withDefaults(cfg Config) Config {
+	defaultDuration := func(d *time.Duration, dflt time.Duration) {
+		if *d == 0 {
+			*d = dflt
+		}
+	}
+	defaultDuration(&cfg.DeltaProgress, 10*time.Second)
+	defaultDuration(&cfg.DeltaResend, 10*time.Second)
+	defaultDuration(&cfg.DeltaInitial, 3*time.Second)
+	defaultDuration(&cfg.DeltaRound, 1*time.Second)
+	defaultDuration(&cfg.DeltaGrace, 200*time.Millisecond)
+	defaultDuration(&cfg.DeltaCertifiedCommitRequest, 1*time.Second)
+	defaultDuration(&cfg.DeltaStage, 5*time.Second)
+	defaultDuration(&cfg.MaxDurationQuery, 1*time.Second)
+	defaultDuration(&cfg.MaxDurationObservation, 1*time.Second)
+	defaultDuration(&cfg.MaxDurationShouldAcceptAttestedReport, 1*time.Second)
+	defaultDuration(&cfg.MaxDurationShouldTransmitAcceptedReport, 1*time.Second)
+	if cfg.RMax == 0 {
+		cfg.RMax = 100
+	}
+	if len(cfg.S) == 0 {
+		cfg.S = []int{cfg.N}
+	}
+	return cfg
+}
+
+// ConfigDigest returns the config digest of the simulated protocol instance.
+func (sim *Simulation[RI]) ConfigDigest() types.ConfigDigest {
+	return sim.sharedConfig.ConfigDigest
+}
+
+// Now returns the current virtual time.
+func (sim *Simulation[RI]) Now() time.Time {
+	return sim.clock.Now()
+}
+
+// Run advances the virtual clock by d. Timers and messages that are due are
+// handed to the oracles one at a time, timers before messages that are due at
+// the same time. After each, Run waits for all oracles to settle before
+// advancing further.
+func (sim *Simulation[RI]) Run(d time.Duration) {
+	end := sim.clock.Now().Add(d)
+	for {
+		sim.settle()
+
+		timerNext, timerOk := sim.clock.NextDeadline()
+		netNext, netOk := sim.network.nextDeadline()
+		if timerOk && !timerNext.After(end) && (!netOk || !netNext.Before(timerNext)) {
+			sim.clock.FireNext(end)
+			continue
+		}
+		if netOk && !netNext.After(end) {
+			// no timer is due at or before netNext
+			sim.clock.AdvanceTo(netNext)
+			sim.network.deliverNext(netNext)
+			continue
+		}
+		// no timer or message is due at or before end
+		sim.clock.AdvanceTo(end)
+		return
+	}
+}
+
+// settle blocks until every goroutine of every oracle is blocked, i.e. until
+// the oracles are done reacting to the last timer or message.
+func (sim *Simulation[RI]) settle() {
+	for !sim.goroutines.quiescent() {
+		runtime.Gosched()
+	}
+}
+
+// CommittedOutcomes returns all outcomes committed so far, ordered by the
+// virtual time of the commit, then by oracle and seqNr.
+func (sim *Simulation[RI]) CommittedOutcomes() []CommittedOutcome {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return append([]CommittedOutcome(nil), sim.committedOutcomes...)
+}
+
+// TransmittedReports returns all reports transmitted so far, ordered by the
+// virtual time of the transmission, then by oracle, seqNr and report. (An
+// oracle may transmit several reports concurrently.)
+func (sim *Simulation[RI]) TransmittedReports() []TransmittedReport[RI] {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return append([]TransmittedReport[RI](nil), sim.transmittedReports...)
+}
+
+// HighestCommittedSeqNr returns the highest sequence number committed by the
+// given oracle.
+func (sim *Simulation[RI]) HighestCommittedSeqNr(oracle commontypes.OracleID) uint64 {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	var highest uint64
+	for _, co := range sim.committedOutcomes {
+		if co.Oracle == oracle && co.SeqNr > highest {
+			highest = co.SeqNr
+		}
+	}
+	return highest
+}
+
+// Status returns a snapshot of the protocol state of the given oracle.
+func (sim *Simulation[RI]) Status(oracle commontypes.OracleID) ocr3types.Status {
+	return sim.statusTrackers[oracle].Status()
+}
+
+// Metrics returns the prometheus metrics of the given oracle. The protocol
+// unregisters its metrics when the simulation is closed.
+func (sim *Simulation[RI]) Metrics(oracle commontypes.OracleID) prometheus.Gatherer {
+	return sim.registries[oracle]
+}
+
+func (sim *Simulation[RI]) recordCommit(co CommittedOutcome) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	i := len(sim.committedOutcomes)
+	for i > 0 && committedBefore(co, sim.committedOutcomes[i-1]) {
+		i--
+	}
+	sim.committedOutcomes = append(sim.committedOutcomes, CommittedOutcome{})
+	copy(sim.committedOutcomes[i+1:], sim.committedOutcomes[i:])
+	sim.committedOutcomes[i] = co
+}
+
+func committedBefore(a CommittedOutcome, b CommittedOutcome) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.Before(b.Time)
+	}
+	if a.Oracle != b.Oracle {
+		return a.Oracle < b.Oracle
+	}
+	return a.SeqNr < b.SeqNr
+}
+
+func (sim *Simulation[RI]) recordTransmission(tr TransmittedReport[RI]) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	i := len(sim.transmittedReports)
+	for i > 0 && transmittedBefore(tr, sim.transmittedReports[i-1]) {
+		i--
+	}
+	sim.transmittedReports = append(sim.transmittedReports, TransmittedReport[RI]{})
+	copy(sim.transmittedReports[i+1:], sim.transmittedReports[i:])
+	sim.transmittedReports[i] = tr
+}
+
+func transmittedBefore[RI any](a TransmittedReport[RI], b TransmittedReport[RI]) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.Before(b.Time)
+	}
+	if a.Oracle != b.Oracle {
+		return a.Oracle < b.Oracle
+	}
+	if a.SeqNr != b.SeqNr {
+		return a.SeqNr < b.SeqNr
+	}
+	return bytes.Compare(a.ReportWithInfo.Report, b.ReportWithInfo.Report) < 0
+}
+
+// Close shuts down all oracles. Can safely be called multiple times.
+func (sim *Simulation[RI]) Close() error {
+	sim.mutex.Lock()
+	if sim.closed {
+		sim.mutex.Unlock()
+		return nil
+	}
+	sim.closed = true
+	sim.mutex.Unlock()
+
+	sim.cancel()
+	sim.network.close()
+	sim.subprocesses.Wait()
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+type nopTelemetrySender struct{}
+
+func (nopTelemetrySender) RoundStarted(types.ConfigDigest, uint64, uint64, uint64, commontypes.OracleID) {
+}
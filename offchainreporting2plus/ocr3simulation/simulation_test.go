@@ -0,0 +1,237 @@
+package ocr3simulation
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// counterPlugin produces one report per seqNr containing the seqNr.
+type counterPlugin struct{}
+
+var _ ocr3types.ReportingPlugin[struct{}] = counterPlugin{}
+
+func (counterPlugin) Query(context.Context, ocr3types.OutcomeContext) (types.Query, error) {
+	return nil, nil
+}
+
+func (counterPlugin) Observation(_ context.Context, outctx ocr3types.OutcomeContext, _ types.Query) (types.Observation, error) {
+	return binary.BigEndian.AppendUint64(nil, outctx.SeqNr), nil
+}
+
+func (counterPlugin) ValidateObservation(ocr3types.OutcomeContext, types.Query, types.AttributedObservation) error {
+	return nil
+}
+
+func (counterPlugin) ObservationQuorum(ocr3types.OutcomeContext, types.Query) (ocr3types.Quorum, error) {
+	return ocr3types.QuorumTwoFPlusOne, nil
+}
+
+func (counterPlugin) Outcome(outctx ocr3types.OutcomeContext, _ types.Query, _ []types.AttributedObservation) (ocr3types.Outcome, error) {
+	return binary.BigEndian.AppendUint64(nil, outctx.SeqNr), nil
+}
+
+func (counterPlugin) Reports(_ uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportWithInfo[struct{}], error) {
+	return []ocr3types.ReportWithInfo[struct{}]{{types.Report(outcome), struct{}{}}}, nil
+}
+
+func (counterPlugin) ShouldAcceptAttestedReport(context.Context, uint64, ocr3types.ReportWithInfo[struct{}]) (bool, error) {
+	return true, nil
+}
+
+func (counterPlugin) ShouldTransmitAcceptedReport(context.Context, uint64, ocr3types.ReportWithInfo[struct{}]) (bool, error) {
+	return true, nil
+}
+
+func (counterPlugin) Close() error {
+	return nil
+}
+
+type counterPluginFactory struct{}
+
+func (counterPluginFactory) NewReportingPlugin(ocr3types.ReportingPluginConfig) (ocr3types.ReportingPlugin[struct{}], ocr3types.ReportingPluginInfo, error) {
+	return counterPlugin{}, ocr3types.ReportingPluginInfo{
+		"counter",
+		ocr3types.ReportingPluginLimits{100, 100, 100, 100, 10},
+	}, nil
+}
+
+func run(t *testing.T, cfg Config, d time.Duration) *Simulation[struct{}] {
+	t.Helper()
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	sim, err := New[struct{}](cfg, counterPluginFactory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(d)
+	if err := sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+func TestSimulationMakesProgress(t *testing.T) {
+	cfg := Config{N: 4, F: 1, Seed: 1}
+	sim := run(t, cfg, 10*time.Second)
+
+	for i := 0; i < cfg.N; i++ {
+		if seqNr := sim.HighestCommittedSeqNr(commontypes.OracleID(i)); seqNr < 3 {
+			t.Errorf("oracle %v committed only up to seqNr %v", i, seqNr)
+		}
+	}
+	if len(sim.TransmittedReports()) == 0 {
+		t.Error("no reports transmitted")
+	}
+	for _, tr := range sim.TransmittedReports() {
+		if got := binary.BigEndian.Uint64(tr.ReportWithInfo.Report); got != tr.SeqNr {
+			t.Errorf("report for seqNr %v contains seqNr %v", tr.SeqNr, got)
+		}
+	}
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	cfg := Config{
+		N:    4,
+		F:    1,
+		Seed: 42,
+		LinkRules: []LinkRule{
+			{
+				DropProbability:    0.05,
+				MinDelay:           10 * time.Millisecond,
+				MaxDelay:           300 * time.Millisecond,
+				ReorderProbability: 0.1,
+				ReorderDelay:       500 * time.Millisecond,
+			},
+		},
+	}
+
+	first := run(t, cfg, 20*time.Second)
+	second := run(t, cfg, 20*time.Second)
+
+	if len(first.CommittedOutcomes()) == 0 {
+		t.Fatal("no outcomes committed")
+	}
+	if !reflect.DeepEqual(first.CommittedOutcomes(), second.CommittedOutcomes()) {
+		t.Error("runs with the same seed committed different outcomes")
+	}
+	if !reflect.DeepEqual(first.TransmittedReports(), second.TransmittedReports()) {
+		t.Error("runs with the same seed transmitted different reports")
+	}
+}
+
+func TestSimulationStatus(t *testing.T) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	cfg := Config{N: 4, F: 1, Seed: 1}
+	sim, err := New[struct{}](cfg, counterPluginFactory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(10 * time.Second)
+
+	for i := 0; i < cfg.N; i++ {
+		id := commontypes.OracleID(i)
+		status := sim.Status(id)
+		if !status.Running || status.ConfigDigest != sim.ConfigDigest() || status.OracleID != id {
+			t.Fatalf("unexpected status of oracle %v: %+v", id, status)
+		}
+		if status.Pacemaker.Epoch != status.OutcomeGeneration.Epoch || status.Pacemaker.Leader != status.OutcomeGeneration.Leader {
+			t.Errorf("pacemaker %+v and outcome generation %+v of oracle %v disagree", status.Pacemaker, status.OutcomeGeneration, id)
+		}
+		if status.OutcomeGeneration.CommittedSeqNr != sim.HighestCommittedSeqNr(id) {
+			t.Errorf("oracle %v reports committed seqNr %v, but committed up to %v", id, status.OutcomeGeneration.CommittedSeqNr, sim.HighestCommittedSeqNr(id))
+		}
+		if status.ReportAttestation.HighestAttestedSeqNr == 0 || len(status.ReportAttestation.Rounds) == 0 {
+			t.Errorf("unexpected report attestation status of oracle %v: %+v", id, status.ReportAttestation)
+		}
+		for j := 1; j < len(status.ReportAttestation.Rounds); j++ {
+			if status.ReportAttestation.Rounds[j-1].SeqNr >= status.ReportAttestation.Rounds[j].SeqNr {
+				t.Errorf("report attestation rounds of oracle %v aren't ordered by seqNr", id)
+			}
+		}
+	}
+
+	if err := sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < cfg.N; i++ {
+		if status := sim.Status(commontypes.OracleID(i)); status.Running {
+			t.Errorf("oracle %v still running after close: %+v", i, status)
+		}
+	}
+}
+
+func TestSimulationMetrics(t *testing.T) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	cfg := Config{N: 4, F: 1, Seed: 1}
+	sim, err := New[struct{}](cfg, counterPluginFactory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Run(10 * time.Second)
+
+	for i := 0; i < cfg.N; i++ {
+		id := commontypes.OracleID(i)
+		families, err := sim.Metrics(id).Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := map[string]*dto.MetricFamily{}
+		for _, family := range families {
+			byName[family.GetName()] = family
+		}
+
+		committed, ok := byName["ocr3_committed_sequence_number"]
+		if !ok || committed.GetMetric()[0].GetGauge().GetValue() != float64(sim.HighestCommittedSeqNr(id)) {
+			t.Errorf("oracle %v reports committed seqNr %v, but committed up to %v", id, committed, sim.HighestCommittedSeqNr(id))
+		}
+		for _, name := range []string{
+			"ocr3_round_duration_seconds",
+			"ocr3_commit_to_attested_report_seconds",
+			"ocr3_transmit_duration_seconds",
+		} {
+			if family, ok := byName[name]; !ok || family.GetMetric()[0].GetHistogram().GetSampleCount() == 0 {
+				t.Errorf("oracle %v didn't observe %v", id, name)
+			}
+		}
+		subprotocols := map[string]bool{}
+		for _, metric := range byName["ocr3_reporting_plugin_duration_seconds"].GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "subprotocol" && metric.GetHistogram().GetSampleCount() != 0 {
+					subprotocols[label.GetValue()] = true
+				}
+			}
+		}
+		if !subprotocols["outgen"] || !subprotocols["repatt"] || !subprotocols["transmission"] {
+			t.Errorf("oracle %v observed reporting plugin durations only for %v", id, subprotocols)
+		}
+	}
+
+	if err := sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+	families, err := sim.Metrics(0).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 0 {
+		t.Errorf("%v metrics still registered after close", len(families))
+	}
+}
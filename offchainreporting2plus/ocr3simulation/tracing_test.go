@@ -0,0 +1,118 @@
+package ocr3simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocrtracetest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestSimulationTracing(t *testing.T) {
+	recorder := ocrtracetest.NewRecorder()
+	cfg := Config{N: 4, F: 1, Seed: 1, Tracer: recorder.Tracer()}
+	sim := run(t, cfg, 10*time.Second)
+
+	spans := recorder.Spans()
+	byID := map[trace.SpanID]tracetest.SpanStub{}
+	for _, span := range spans {
+		byID[span.SpanContext.SpanID()] = span
+	}
+
+	// every span is a child of the span named here, within the same trace.
+	// OutcomeGeneration spans are roots.
+	parents := map[string]string{
+		"OutcomeGeneration":                            "",
+		"ReportAttestation":                            "OutcomeGeneration",
+		"Transmission.accept":                          "ReportAttestation",
+		"Transmission.transmit":                        "ReportAttestation",
+		"ContractTransmitter.Transmit":                 "Transmission.transmit",
+		"ReportingPlugin.Query":                        "OutcomeGeneration",
+		"ReportingPlugin.Observation":                  "OutcomeGeneration",
+		"ReportingPlugin.ValidateObservation":          "OutcomeGeneration",
+		"ReportingPlugin.ObservationQuorum":            "OutcomeGeneration",
+		"ReportingPlugin.Outcome":                      "OutcomeGeneration",
+		"ReportingPlugin.Reports":                      "ReportAttestation",
+		"ReportingPlugin.ShouldAcceptAttestedReport":   "Transmission.accept",
+		"ReportingPlugin.ShouldTransmitAcceptedReport": "Transmission.transmit",
+	}
+	counts := map[string]int{}
+	for _, span := range spans {
+		counts[span.Name]++
+		expected, ok := parents[span.Name]
+		if !ok {
+			t.Errorf("unexpected span %v", span.Name)
+			continue
+		}
+		if expected == "" {
+			if span.Parent.IsValid() {
+				t.Errorf("%v span has a parent", span.Name)
+			}
+			continue
+		}
+		parent, ok := byID[span.Parent.SpanID()]
+		if !ok || parent.Name != expected {
+			t.Errorf("%v span has parent %q, expected %v", span.Name, parent.Name, expected)
+			continue
+		}
+		if parent.SpanContext.TraceID() != span.SpanContext.TraceID() {
+			t.Errorf("%v span isn't in the trace of its parent", span.Name)
+		}
+		if seqNr, ok := attributeValue(span, "ocr.seq_nr"); ok {
+			if parentSeqNr, _ := attributeValue(parent, "ocr.seq_nr"); parentSeqNr != seqNr {
+				t.Errorf("%v span for seqNr %v has parent for seqNr %v", span.Name, seqNr.Emit(), parentSeqNr.Emit())
+			}
+		}
+	}
+	for _, name := range []string{
+		"OutcomeGeneration",
+		"ReportAttestation",
+		"Transmission.transmit",
+		"ContractTransmitter.Transmit",
+		"ReportingPlugin.Query",
+		"ReportingPlugin.Observation",
+		"ReportingPlugin.Outcome",
+		"ReportingPlugin.Reports",
+		"ReportingPlugin.ShouldAcceptAttestedReport",
+		"ReportingPlugin.ShouldTransmitAcceptedReport",
+	} {
+		if counts[name] == 0 {
+			t.Errorf("no %v spans recorded", name)
+		}
+	}
+
+	// every committed outcome has a committed round span
+	committed := 0
+	for _, span := range recorder.SpansNamed("OutcomeGeneration") {
+		if digest, _ := attributeValue(span, "ocr.config_digest"); digest.AsString() != sim.ConfigDigest().Hex() {
+			t.Errorf("round span for config digest %v", digest.Emit())
+		}
+		if value, _ := attributeValue(span, "ocr.committed"); value.AsBool() {
+			committed++
+		}
+	}
+	if committed != len(sim.CommittedOutcomes()) {
+		t.Errorf("%v committed round spans for %v committed outcomes", committed, len(sim.CommittedOutcomes()))
+	}
+	if counts["ContractTransmitter.Transmit"] != len(sim.TransmittedReports()) {
+		t.Errorf("%v Transmit spans for %v transmitted reports", counts["ContractTransmitter.Transmit"], len(sim.TransmittedReports()))
+	}
+}
+
+func TestSimulationWithoutTracer(t *testing.T) {
+	sim := run(t, Config{N: 4, F: 1, Seed: 1}, 5*time.Second)
+	if len(sim.CommittedOutcomes()) == 0 {
+		t.Error("no outcomes committed without a tracer")
+	}
+}
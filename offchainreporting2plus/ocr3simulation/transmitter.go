@@ -0,0 +1,41 @@
+package ocr3simulation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// contractTransmitter records every transmission with the simulation instead
+// of sending it anywhere.
+type contractTransmitter[RI any] struct {
+	sim *Simulation[RI]
+	id  commontypes.OracleID
+}
+
+var _ ocr3types.ContractTransmitter[struct{}] = (*contractTransmitter[struct{}])(nil)
+
+func (ct *contractTransmitter[RI]) Transmit(
+	ctx context.Context,
+	configDigest types.ConfigDigest,
+	seqNr uint64,
+	rwi ocr3types.ReportWithInfo[RI],
+	aos []types.AttributedOnchainSignature,
+) error {
+	ct.sim.recordTransmission(TransmittedReport[RI]{
+		ct.id,
+		configDigest,
+		seqNr,
+		rwi,
+		append([]types.AttributedOnchainSignature(nil), aos...),
+		ct.sim.clock.Now(),
+	})
+	return nil
+}
+
+func (ct *contractTransmitter[RI]) FromAccount() (types.Account, error) {
+	return types.Account(fmt.Sprintf("simulated-transmitter-%d", ct.id)), nil
+}
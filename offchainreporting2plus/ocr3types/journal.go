@@ -0,0 +1,101 @@
+package ocr3types
+
+import (
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// Journal persists a record of everything that flows into an OCR3 protocol
+// instance: inbound messages, calls to the ReportingPlugin together with their
+// inputs and outputs, timer expiries, random choices, and events passed
+// between the subprotocols. A journal can later be fed back through the
+// protocol offline, see package ocr3journal.
+//
+// Append is called synchronously from the protocol's event loops and should
+// return quickly.
+//
+// All its functions should be thread-safe.
+type Journal[RI any] interface {
+	Append(configDigest types.ConfigDigest, entry JournalEntry[RI]) error
+}
+
+type JournalSubprotocol string
+
+const (
+	JournalSubprotocolPacemaker         JournalSubprotocol = "pacemaker"
+	JournalSubprotocolOutcomeGeneration JournalSubprotocol = "outgen"
+	JournalSubprotocolReportAttestation JournalSubprotocol = "repatt"
+)
+
+type JournalEntryKind string
+
+const (
+	// The certificate an instance restored from the database when it started.
+	// Uses Cert.
+	JournalEntryKindRestoredCert JournalEntryKind = "RestoredCert"
+	// A message received from the network. Uses Sender and Message.
+	JournalEntryKindMessage JournalEntryKind = "Message"
+	// An event passed from one subprotocol to another. Uses Name and, depending
+	// on the event, Epoch and Leader, or Cert.
+	JournalEntryKindEvent JournalEntryKind = "Event"
+	// The expiry of a timer. Uses Name and, depending on the timer, SeqNr.
+	JournalEntryKindTimer JournalEntryKind = "Timer"
+	// A call to the ReportingPlugin. Uses PluginCall.
+	JournalEntryKindPluginCall JournalEntryKind = "PluginCall"
+	// Randomness drawn by a subprotocol, e.g. to pick the oracle to request a
+	// certified commit from. Uses Randomness.
+	JournalEntryKindRandomness JournalEntryKind = "Randomness"
+)
+
+// JournalEntry is a single entry in a Journal. Which fields are set depends
+// on Kind.
+type JournalEntry[RI any] struct {
+	Time        time.Time
+	Subprotocol JournalSubprotocol
+	Kind        JournalEntryKind
+
+	Sender commontypes.OracleID `json:",omitempty"`
+	// Serialized message, in the same format as sent over the network
+	Message []byte `json:",omitempty"`
+	// Name of the event or timer, e.g. "NewEpochStart" or "TRound"
+	Name  string `json:",omitempty"`
+	Epoch uint64 `json:",omitempty"`
+	// Leader of the epoch, set for "NewEpochStart" events
+	Leader commontypes.OracleID `json:",omitempty"`
+	SeqNr  uint64               `json:",omitempty"`
+	// Serialized certified prepare or commit, in the same format as stored
+	// in the Database
+	Cert       []byte                 `json:",omitempty"`
+	PluginCall *JournalPluginCall[RI] `json:",omitempty"`
+	Randomness []byte                 `json:",omitempty"`
+}
+
+// JournalPluginCall records a call to one of the ReportingPlugin's methods.
+// Inputs and Outputs only have the fields set that are relevant to Method.
+type JournalPluginCall[RI any] struct {
+	Method  string
+	Inputs  JournalPluginInputs
+	Outputs JournalPluginOutputs[RI]
+}
+
+type JournalPluginInputs struct {
+	OutcomeContext         *OutcomeContext               `json:",omitempty"`
+	Query                  types.Query                   `json:",omitempty"`
+	AttributedObservations []types.AttributedObservation `json:",omitempty"`
+	SeqNr                  uint64                        `json:",omitempty"`
+	Outcome                Outcome                       `json:",omitempty"`
+}
+
+type JournalPluginOutputs[RI any] struct {
+	Query       types.Query       `json:",omitempty"`
+	Observation types.Observation `json:",omitempty"`
+	Quorum      Quorum            `json:",omitempty"`
+	Outcome     Outcome           `json:",omitempty"`
+	// Reports distinguishes between nil and empty, so we must not omit it
+	Reports []ReportWithInfo[RI]
+	// The error returned by the ReportingPlugin, if any. For
+	// ValidateObservation, this is the validation result.
+	Error string `json:",omitempty"`
+}
@@ -0,0 +1,93 @@
+package ocr3types
+
+import (
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// Status is a read-only snapshot of the state of an OCR3 oracle, meant for
+// operators trying to understand what an oracle is doing. It is assembled from
+// snapshots that the protocol's subprotocols publish independently, so the
+// parts of a Status may reflect slightly different points in time.
+type Status struct {
+	// Whether a protocol instance is currently running. If false, all other
+	// fields are zero.
+	Running bool
+
+	ConfigDigest types.ConfigDigest
+	OracleID     commontypes.OracleID
+
+	Pacemaker         PacemakerStatus
+	OutcomeGeneration OutcomeGenerationStatus
+	ReportAttestation ReportAttestationStatus
+	Transmission      TransmissionStatus
+
+	// Status of the network streams to the other oracles. Empty if the
+	// network endpoint doesn't report peer status.
+	Peers []types.PeerStatus
+}
+
+type PacemakerStatus struct {
+	Epoch  uint64
+	Leader commontypes.OracleID
+	// Highest epoch this oracle has broadcast a NewEpochWish for
+	HighestSentNewEpochWish uint64
+}
+
+type OutcomeGenerationStatus struct {
+	Epoch         uint64
+	Leader        commontypes.OracleID
+	LeaderPhase   string
+	FollowerPhase string
+
+	FirstSeqNrOfEpoch uint64
+	SeqNr             uint64
+	CommittedSeqNr    uint64
+}
+
+type ReportAttestationStatus struct {
+	// Highest sequence number for which reports have been attested
+	HighestAttestedSeqNr uint64
+	// One entry per sequence number report attestation keeps state for,
+	// ordered by sequence number
+	Rounds []ReportAttestationRoundStatus
+}
+
+type ReportAttestationRoundStatus struct {
+	SeqNr uint64
+	// Whether we know the certified commit for this sequence number, and
+	// thus the reports
+	HaveCertifiedCommit bool
+	Reports             int
+	// Ids of the oracles from which we have received report signatures. (We
+	// don't use []commontypes.OracleID since encoding/json would encode it as
+	// a base64 string.)
+	SignaturesFrom []int
+	// Ids of the oracles whose report signatures have been verified to be
+	// valid
+	ValidSignaturesFrom []int
+	// Whether we have started fetching the certified commit from other
+	// oracles
+	StartedFetch bool
+	// Whether the reports have been attested
+	Complete bool
+}
+
+type TransmissionStatus struct {
+	// Attested reports accepted for transmission that haven't been
+	// transmitted yet, ordered by deadline
+	Pending []PendingTransmission
+}
+
+type PendingTransmission struct {
+	SeqNr uint64
+	Index int
+	// When the next attempt at transmitting the report is due
+	Deadline time.Time
+	// Number of failed attempts at transmitting the report so far
+	FailedAttempts int
+	// Whether an attempt at transmitting the report is under way
+	Transmitting bool
+}
@@ -53,3 +53,13 @@ type OnchainKeyring[RI any] interface {
 	// Maximum length of a signature
 	MaxSignatureLength() int
 }
+
+// ReportInfoCodec encodes report infos so that they can be persisted, e.g.
+// by the transmission outbox. DecodeReportInfo(EncodeReportInfo(info)) must
+// return a value equivalent to info.
+//
+// All its functions should be thread-safe.
+type ReportInfoCodec[RI any] interface {
+	EncodeReportInfo(RI) ([]byte, error)
+	DecodeReportInfo([]byte) (RI, error)
+}
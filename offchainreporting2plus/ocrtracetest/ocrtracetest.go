@@ -0,0 +1,68 @@
+// Package ocrtracetest records the OpenTelemetry spans emitted by OCR2 and
+// OCR3 oracles in memory, so that tests can inspect them.
+//
+// Pass Recorder.Tracer() as OCR2OracleArgs.Tracer, OCR3OracleArgs.Tracer, or
+// ocr3simulation.Config.Tracer, run the oracles, and inspect
+// Recorder.Spans(). Spans are recorded synchronously when they end.
+package ocrtracetest
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/smartcontractkit/libocr/offchainreporting2plus"
+
+// Recorder is a tracer provider that keeps all ended spans in memory. It is
+// safe for concurrent use.
+type Recorder struct {
+	exporter *tracetest.InMemoryExporter
+	provider *sdktrace.TracerProvider
+}
+
+func NewRecorder() *Recorder {
+	exporter := tracetest.NewInMemoryExporter()
+	return &Recorder{
+		exporter,
+		sdktrace.NewTracerProvider(
+			sdktrace.WithSyncer(exporter),
+			sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		),
+	}
+}
+
+// Tracer returns a tracer whose spans are recorded by r.
+func (r *Recorder) Tracer() trace.Tracer {
+	return r.provider.Tracer(instrumentationName)
+}
+
+// Spans returns all spans that have ended so far, in the order in which they
+// ended.
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.exporter.GetSpans()
+}
+
+// SpansNamed returns the spans with the given name that have ended so far, in
+// the order in which they ended.
+func (r *Recorder) SpansNamed(name string) tracetest.SpanStubs {
+	var result tracetest.SpanStubs
+	for _, span := range r.exporter.GetSpans() {
+		if span.Name == name {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+// Reset discards all spans recorded so far.
+func (r *Recorder) Reset() {
+	r.exporter.Reset()
+}
+
+// Shutdown stops recording. Spans that end afterwards are dropped.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	return r.provider.Shutdown(ctx)
+}
@@ -0,0 +1,44 @@
+package ocrtracetest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	tracer := recorder.Tracer()
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	if len(recorder.Spans()) != 0 {
+		t.Fatal("recorded spans that haven't ended")
+	}
+	child.End()
+	parent.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 || spans[0].Name != "child" || spans[1].Name != "parent" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("child isn't linked to parent")
+	}
+	if named := recorder.SpansNamed("parent"); len(named) != 1 || named[0].Name != "parent" {
+		t.Errorf("unexpected spans named parent %+v", named)
+	}
+
+	recorder.Reset()
+	if len(recorder.Spans()) != 0 {
+		t.Error("spans left after reset")
+	}
+
+	if err := recorder.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, span := tracer.Start(context.Background(), "late")
+	span.End()
+	if len(recorder.Spans()) != 0 {
+		t.Error("recorded span after shutdown")
+	}
+}
@@ -9,15 +9,18 @@ import (
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/managed"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/internal/ocr3/protocol"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OracleArgs interface {
 	oracleArgsMarker()
 	localConfig() types.LocalConfig
-	runManaged(ctx context.Context)
+	statusEnabled() bool
+	runManaged(ctx context.Context, statusTracker *protocol.StatusTracker)
 }
 
 // OCR2OracleArgs contains the configuration and services a caller must provide, in
@@ -67,13 +70,19 @@ type OCR2OracleArgs struct {
 	// ReportingPluginFactory creates ReportingPlugins that determine the
 	// "application logic" used in a OCR2 protocol instance.
 	ReportingPluginFactory types.ReportingPluginFactory
+
+	// Tracer is used to emit a span for each round, with child spans for
+	// calls to the ReportingPlugin and ContractTransmitter. This may be nil.
+	Tracer trace.Tracer
 }
 
 func (OCR2OracleArgs) oracleArgsMarker() {}
 
 func (args OCR2OracleArgs) localConfig() types.LocalConfig { return args.LocalConfig }
 
-func (args OCR2OracleArgs) runManaged(ctx context.Context) {
+func (OCR2OracleArgs) statusEnabled() bool { return false }
+
+func (args OCR2OracleArgs) runManaged(ctx context.Context, _ *protocol.StatusTracker) {
 	logger := loghelper.MakeRootLoggerWithContext(args.Logger)
 
 	managed.RunManagedOCR2Oracle(
@@ -92,6 +101,7 @@ func (args OCR2OracleArgs) runManaged(ctx context.Context) {
 		args.OffchainKeyring,
 		args.OnchainKeyring,
 		args.ReportingPluginFactory,
+		args.Tracer,
 	)
 }
 
@@ -142,13 +152,20 @@ type MercuryOracleArgs struct {
 	// ReportingPluginFactory creates ReportingPlugins that determine the
 	// "application logic" used in an OCR protocol instance.
 	MercuryPluginFactory ocr3types.MercuryPluginFactory
+
+	// EnableStatus makes the oracle keep a snapshot of its protocol state up
+	// to date, see StatusOracle. This has a small cost on every iteration of
+	// the protocol's event loops, so it is off by default.
+	EnableStatus bool
 }
 
 func (MercuryOracleArgs) oracleArgsMarker() {}
 
 func (args MercuryOracleArgs) localConfig() types.LocalConfig { return args.LocalConfig }
 
-func (args MercuryOracleArgs) runManaged(ctx context.Context) {
+func (args MercuryOracleArgs) statusEnabled() bool { return args.EnableStatus }
+
+func (args MercuryOracleArgs) runManaged(ctx context.Context, statusTracker *protocol.StatusTracker) {
 	logger := loghelper.MakeRootLoggerWithContext(args.Logger)
 
 	managed.RunManagedMercuryOracle(
@@ -167,6 +184,7 @@ func (args MercuryOracleArgs) runManaged(ctx context.Context) {
 		args.OffchainKeyring,
 		args.OnchainKeyring,
 		args.MercuryPluginFactory,
+		statusTracker,
 	)
 }
 
@@ -188,6 +206,10 @@ type OCR3OracleArgs[RI any] struct {
 	// Database provides persistent storage.
 	Database ocr3types.Database
 
+	// Journal records the inputs of the protocol for offline replay, see
+	// package ocr3journal. This may be nil.
+	Journal ocr3types.Journal[RI]
+
 	// LocalConfig contains oracle-specific configuration details which are not
 	// mandated by the on-chain configuration specification via OffchainAggregatoo.SetConfig.
 	LocalConfig types.LocalConfig
@@ -215,13 +237,31 @@ type OCR3OracleArgs[RI any] struct {
 	// PluginFactory creates Plugins that determine the "application logic" used
 	// in a protocol instance.
 	ReportingPluginFactory ocr3types.ReportingPluginFactory[RI]
+
+	// Tracer is used to emit a trace for each round, with child spans for
+	// calls to the ReportingPlugin and ContractTransmitter. This may be nil.
+	Tracer trace.Tracer
+
+	// EnableStatus makes the oracle keep a snapshot of its protocol state up
+	// to date, see StatusOracle. This has a small cost on every iteration of
+	// the protocol's event loops, so it is off by default.
+	EnableStatus bool
+
+	// TransmissionOutboxReportInfoCodec enables the transmission outbox: if
+	// set, reports accepted for transmission are persisted in Database, using
+	// the codec to encode their infos, and are still transmitted after the
+	// oracle restarts. If nil, which is the default, pending transmissions are
+	// lost on restart.
+	TransmissionOutboxReportInfoCodec ocr3types.ReportInfoCodec[RI]
 }
 
 func (OCR3OracleArgs[RI]) oracleArgsMarker() {}
 
 func (args OCR3OracleArgs[RI]) localConfig() types.LocalConfig { return args.LocalConfig }
 
-func (args OCR3OracleArgs[RI]) runManaged(ctx context.Context) {
+func (args OCR3OracleArgs[RI]) statusEnabled() bool { return args.EnableStatus }
+
+func (args OCR3OracleArgs[RI]) runManaged(ctx context.Context, statusTracker *protocol.StatusTracker) {
 	logger := loghelper.MakeRootLoggerWithContext(args.Logger)
 
 	managed.RunManagedOCR3Oracle(
@@ -231,6 +271,7 @@ func (args OCR3OracleArgs[RI]) runManaged(ctx context.Context) {
 		args.ContractConfigTracker,
 		args.ContractTransmitter,
 		args.Database,
+		args.Journal,
 		args.LocalConfig,
 		logger,
 		args.MetricsRegisterer,
@@ -240,6 +281,9 @@ func (args OCR3OracleArgs[RI]) runManaged(ctx context.Context) {
 		args.OffchainKeyring,
 		args.OnchainKeyring,
 		args.ReportingPluginFactory,
+		statusTracker,
+		args.Tracer,
+		args.TransmissionOutboxReportInfoCodec,
 	)
 }
 
@@ -256,6 +300,15 @@ type Oracle interface {
 	Close() error
 }
 
+// StatusOracle is implemented by the Oracles returned by NewOracle.
+type StatusOracle interface {
+	Oracle
+	// Status returns a read-only snapshot of the oracle's protocol state. Only
+	// supported for oracles created from OCR3OracleArgs or MercuryOracleArgs
+	// with EnableStatus set. See also NewStatusHandler.
+	Status() (ocr3types.Status, error)
+}
+
 type oracle struct {
 	lock sync.Mutex
 
@@ -268,20 +321,28 @@ type oracle struct {
 
 	// cancel sends a cancel message to all subprocesses, via a context.Context
 	cancel context.CancelFunc
+
+	// nil unless the oracle args enable Status()
+	statusTracker *protocol.StatusTracker
 }
 
 // NewOracle returns a newly initialized Oracle using the provided services
-// and configuration.
+// and configuration. The returned Oracle also implements StatusOracle.
 func NewOracle(args OracleArgs) (Oracle, error) {
 	if err := SanityCheckLocalConfig(args.localConfig()); err != nil {
 		return nil, fmt.Errorf("bad local config while creating new oracle: %w", err)
 	}
+	var statusTracker *protocol.StatusTracker
+	if args.statusEnabled() {
+		statusTracker = protocol.NewStatusTracker()
+	}
 	return &oracle{
 		sync.Mutex{},
 		oracleStateUnstarted,
 		args,
 		subprocesses.Subprocesses{},
 		nil,
+		statusTracker,
 	}, nil
 }
 
@@ -300,7 +361,7 @@ func (o *oracle) Start() error {
 	o.subprocesses.Go(func() {
 		defer cancel()
 
-		o.oracleArgs.runManaged(ctx)
+		o.oracleArgs.runManaged(ctx, o.statusTracker)
 	})
 	return nil
 }
@@ -323,3 +384,13 @@ func (o *oracle) Close() error {
 	o.subprocesses.Wait()
 	return nil
 }
+
+var _ StatusOracle = (*oracle)(nil)
+
+// Status returns a read-only snapshot of the oracle's protocol state.
+func (o *oracle) Status() (ocr3types.Status, error) {
+	if o.statusTracker == nil {
+		return ocr3types.Status{}, fmt.Errorf("status is only supported for OCR3 and Mercury oracles with EnableStatus set")
+	}
+	return o.statusTracker.Status(), nil
+}
@@ -0,0 +1,344 @@
+package pluginconformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// TestOCR2ReportingPlugin checks that plugins created by factory
+//
+//   - declare ReportingPluginLimits within the protocol's bounds, and the same
+//     limits for every oracle
+//   - return queries, observations and reports within those limits
+//   - compute identical reports at every oracle from any 2f+1 or more
+//     observations, i.e. that Report is a pure function of its inputs
+//   - tolerate up to f malformed observations in Report
+//   - return from functions taking a context within MaxDuration* of their
+//     respective call, and promptly when the context is already cancelled
+//
+// OCR2 plugins have no equivalent of ValidateObservation and
+// ObservationQuorum, so those checks of TestOCR3ReportingPlugin don't apply.
+//
+// config is passed to the factory once for every oracle, with OracleID set
+// accordingly. N, F and all MaxDuration* fields must be set.
+func TestOCR2ReportingPlugin(t *testing.T, factory types.ReportingPluginFactory, config types.ReportingPluginConfig, opts Options) {
+	opts = opts.withDefaults()
+	if err := checkOCR2Config(config); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	t.Run("Limits", func(t *testing.T) {
+		h := newOCR2Harness(t, factory, config, opts)
+		checkOCR2Limits(t, h.limits)
+	})
+
+	t.Run("Rounds", func(t *testing.T) {
+		h := newOCR2Harness(t, factory, config, opts)
+		for i := 0; i < opts.Rounds; i++ {
+			ts := h.reportTimestamp(i)
+			if _, ok := h.round(ts); !ok {
+				t.Fatalf("aborting after failed round with epoch %v and round %v", ts.Epoch, ts.Round)
+			}
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		h := newOCR2Harness(t, factory, config, opts)
+		ts := h.reportTimestamp(0)
+		plugin := h.plugins[0]
+
+		if _, err := callCancelled(opts, "Query", func(ctx context.Context) (types.Query, error) {
+			return plugin.Query(ctx, ts)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+		query, _ := h.query(ts)
+		if _, err := callCancelled(opts, "Observation", func(ctx context.Context) (types.Observation, error) {
+			return plugin.Observation(ctx, ts, query)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+		aos, _ := h.observations(ts, query)
+		if _, err := callCancelled(opts, "Report", func(ctx context.Context) (ocr2Report, error) {
+			shouldReport, report, err := plugin.Report(ctx, ts, query, aos)
+			return ocr2Report{shouldReport, report}, err
+		}); isViolation(err) {
+			t.Error(err)
+		}
+
+		report, ok := h.round(ts)
+		if !ok {
+			t.Fatalf("could not complete a round to obtain a report")
+		}
+		if !report.shouldReport {
+			t.Log("first round produced no report, skipping ShouldAcceptFinalizedReport and ShouldTransmitAcceptedReport")
+			return
+		}
+		if _, err := callCancelled(opts, "ShouldAcceptFinalizedReport", func(ctx context.Context) (bool, error) {
+			return plugin.ShouldAcceptFinalizedReport(ctx, ts, report.report)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+		if _, err := callCancelled(opts, "ShouldTransmitAcceptedReport", func(ctx context.Context) (bool, error) {
+			return plugin.ShouldTransmitAcceptedReport(ctx, ts, report.report)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+	})
+}
+
+func checkOCR2Config(config types.ReportingPluginConfig) error {
+	if !(0 <= config.F && 3*config.F < config.N && config.N <= types.MaxOracles) {
+		return fmt.Errorf("need 0 <= F, 3*F < N and N <= %v, got N = %v and F = %v", types.MaxOracles, config.N, config.F)
+	}
+	for name, d := range map[string]time.Duration{
+		"MaxDurationQuery":                        config.MaxDurationQuery,
+		"MaxDurationObservation":                  config.MaxDurationObservation,
+		"MaxDurationReport":                       config.MaxDurationReport,
+		"MaxDurationShouldAcceptFinalizedReport":  config.MaxDurationShouldAcceptFinalizedReport,
+		"MaxDurationShouldTransmitAcceptedReport": config.MaxDurationShouldTransmitAcceptedReport,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%v must be positive", name)
+		}
+	}
+	return nil
+}
+
+func checkOCR2Limits(t *testing.T, limits types.ReportingPluginLimits) {
+	for _, l := range []struct {
+		name  string
+		value int
+		max   int
+	}{
+		{"MaxQueryLength", limits.MaxQueryLength, types.MaxMaxQueryLength},
+		{"MaxObservationLength", limits.MaxObservationLength, types.MaxMaxObservationLength},
+		{"MaxReportLength", limits.MaxReportLength, types.MaxMaxReportLength},
+	} {
+		if !(0 <= l.value && l.value <= l.max) {
+			t.Errorf("%v (%v) out of range, should be between 0 and %v", l.name, l.value, l.max)
+		}
+	}
+}
+
+type ocr2Report struct {
+	shouldReport bool
+	report       types.Report
+}
+
+type ocr2Harness struct {
+	t       *testing.T
+	config  types.ReportingPluginConfig
+	opts    Options
+	rng     *rand.Rand
+	plugins []types.ReportingPlugin
+	limits  types.ReportingPluginLimits
+}
+
+// newOCR2Harness creates a plugin for every oracle. The plugins are closed
+// when the test finishes.
+func newOCR2Harness(t *testing.T, factory types.ReportingPluginFactory, config types.ReportingPluginConfig, opts Options) *ocr2Harness {
+	h := &ocr2Harness{t: t, config: config, opts: opts, rng: rand.New(rand.NewSource(opts.Seed))}
+	var firstInfo types.ReportingPluginInfo
+	for i := 0; i < config.N; i++ {
+		i := i
+		pluginConfig := config
+		pluginConfig.OracleID = commontypes.OracleID(i)
+		plugin, info, err := factory.NewReportingPlugin(pluginConfig)
+		if err != nil {
+			t.Fatalf("NewReportingPlugin failed for oracle %v: %v", i, err)
+		}
+		t.Cleanup(func() {
+			if _, err := callPure("Close", func() (struct{}, error) { return struct{}{}, plugin.Close() }); err != nil {
+				t.Errorf("closing plugin of oracle %v: %v", i, err)
+			}
+			// may return an error, but must not panic
+			if _, err := callPure("Close", func() (struct{}, error) { return struct{}{}, plugin.Close() }); isViolation(err) {
+				t.Errorf("closing plugin of oracle %v a second time: %v", i, err)
+			}
+		})
+		if i == 0 {
+			firstInfo = info
+		} else if info != firstInfo {
+			t.Errorf("oracle %v got ReportingPluginInfo %+v, but oracle 0 got %+v", i, info, firstInfo)
+		}
+		h.plugins = append(h.plugins, plugin)
+	}
+	h.limits = firstInfo.Limits
+	return h
+}
+
+// Rounds per epoch in reportTimestamp
+const roundsPerEpoch = 100
+
+// reportTimestamp returns the timestamp of the i-th simulated round.
+func (h *ocr2Harness) reportTimestamp(i int) types.ReportTimestamp {
+	return types.ReportTimestamp{
+		h.config.ConfigDigest,
+		uint32(1 + i/roundsPerEpoch),
+		uint8(1 + i%roundsPerEpoch),
+	}
+}
+
+func (h *ocr2Harness) leader(ts types.ReportTimestamp) types.ReportingPlugin {
+	return h.plugins[int(ts.Epoch)%len(h.plugins)]
+}
+
+func (h *ocr2Harness) query(ts types.ReportTimestamp) (types.Query, bool) {
+	query, err := callWithDeadline(h.config.MaxDurationQuery, h.opts, "Query", func(ctx context.Context) (types.Query, error) {
+		return h.leader(ts).Query(ctx, ts)
+	})
+	if err != nil {
+		h.t.Errorf("epoch %v, round %v: %v", ts.Epoch, ts.Round, err)
+		return nil, false
+	}
+	if len(query) > h.limits.MaxQueryLength {
+		h.t.Errorf("epoch %v, round %v: query has length %v, exceeding MaxQueryLength (%v)", ts.Epoch, ts.Round, len(query), h.limits.MaxQueryLength)
+		return nil, false
+	}
+	return query, true
+}
+
+// observations returns the observations of all oracles. Oracles that fail to
+// make an observation are skipped, unless they violate the contract in doing
+// so.
+func (h *ocr2Harness) observations(ts types.ReportTimestamp, query types.Query) ([]types.AttributedObservation, bool) {
+	ok := true
+	var aos []types.AttributedObservation
+	for i, plugin := range h.plugins {
+		plugin := plugin
+		observation, err := callWithDeadline(h.config.MaxDurationObservation, h.opts, "Observation", func(ctx context.Context) (types.Observation, error) {
+			return plugin.Observation(ctx, ts, query)
+		})
+		if err != nil {
+			if isViolation(err) {
+				h.t.Errorf("epoch %v, round %v, oracle %v: %v", ts.Epoch, ts.Round, i, err)
+				ok = false
+			} else {
+				h.t.Logf("epoch %v, round %v, oracle %v: Observation failed: %v", ts.Epoch, ts.Round, i, err)
+			}
+			continue
+		}
+		if len(observation) > h.limits.MaxObservationLength {
+			h.t.Errorf("epoch %v, round %v, oracle %v: observation has length %v, exceeding MaxObservationLength (%v)", ts.Epoch, ts.Round, i, len(observation), h.limits.MaxObservationLength)
+			ok = false
+			continue
+		}
+		aos = append(aos, types.AttributedObservation{observation, commontypes.OracleID(i)})
+	}
+	return aos, ok
+}
+
+// report checks that all oracles compute the same report from aos and returns
+// it.
+func (h *ocr2Harness) report(ts types.ReportTimestamp, query types.Query, aos []types.AttributedObservation) (ocr2Report, bool) {
+	var first ocr2Report
+	for i, plugin := range h.plugins {
+		plugin := plugin
+		for repetition := 0; repetition < 2; repetition++ {
+			report, err := callWithDeadline(h.config.MaxDurationReport, h.opts, "Report", func(ctx context.Context) (ocr2Report, error) {
+				shouldReport, report, err := plugin.Report(ctx, ts, query, aos)
+				return ocr2Report{shouldReport, report}, err
+			})
+			if err != nil {
+				h.t.Errorf("epoch %v, round %v, oracle %v: Report failed on %v observations: %v", ts.Epoch, ts.Round, i, len(aos), err)
+				return ocr2Report{}, false
+			}
+			if i == 0 && repetition == 0 {
+				first = report
+			} else if report.shouldReport != first.shouldReport || !bytes.Equal(report.report, first.report) {
+				h.t.Errorf("epoch %v, round %v, oracle %v: Report is not deterministic, returned (%v, %x) but (%v, %x) before", ts.Epoch, ts.Round, i, report.shouldReport, report.report, first.shouldReport, first.report)
+				return ocr2Report{}, false
+			}
+		}
+	}
+	if len(first.report) > h.limits.MaxReportLength {
+		h.t.Errorf("epoch %v, round %v: report has length %v, exceeding MaxReportLength (%v)", ts.Epoch, ts.Round, len(first.report), h.limits.MaxReportLength)
+		return ocr2Report{}, false
+	}
+	return first, true
+}
+
+// round simulates a fault-free round and additionally feeds the plugins
+// different sets of observations, some of them malformed. It returns the
+// report generated from all observations.
+func (h *ocr2Harness) round(ts types.ReportTimestamp) (ocr2Report, bool) {
+	query, ok := h.query(ts)
+	if !ok {
+		return ocr2Report{}, false
+	}
+	aos, ok := h.observations(ts, query)
+	if !ok {
+		return ocr2Report{}, false
+	}
+	quorum := 2*h.config.F + 1
+	if len(aos) < quorum {
+		h.t.Errorf("epoch %v, round %v: only %v observations, but need 2f+1 = %v", ts.Epoch, ts.Round, len(aos), quorum)
+		return ocr2Report{}, false
+	}
+
+	report, ok := h.report(ts, query, aos)
+	if !ok {
+		return ocr2Report{}, false
+	}
+
+	// A leader may pick any 2f+1 or more observations, in any order.
+	for i := 0; i < subsetsPerRound; i++ {
+		var subsetAOs []types.AttributedObservation
+		for _, j := range subset(h.rng, len(aos), quorum) {
+			subsetAOs = append(subsetAOs, aos[j])
+		}
+		if _, ok := h.report(ts, query, subsetAOs); !ok {
+			return ocr2Report{}, false
+		}
+	}
+
+	// Faulty oracles may send arbitrary observations. If Report failed on
+	// those, a single faulty oracle could prevent any reports from being
+	// generated.
+	if h.config.F > 0 {
+		for _, target := range aos {
+			for _, observation := range malformed(h.rng, target.Observation, h.limits.MaxObservationLength) {
+				var mixedAOs []types.AttributedObservation
+				for _, ao := range aos {
+					if ao.Observer == target.Observer {
+						mixedAOs = append(mixedAOs, types.AttributedObservation{observation, ao.Observer})
+					} else {
+						mixedAOs = append(mixedAOs, ao)
+					}
+				}
+				if _, ok := h.report(ts, query, mixedAOs); !ok {
+					return ocr2Report{}, false
+				}
+			}
+		}
+	}
+
+	if !report.shouldReport {
+		return report, true
+	}
+	accept, err := callWithDeadline(h.config.MaxDurationShouldAcceptFinalizedReport, h.opts, "ShouldAcceptFinalizedReport", func(ctx context.Context) (bool, error) {
+		return h.leader(ts).ShouldAcceptFinalizedReport(ctx, ts, report.report)
+	})
+	if isViolation(err) {
+		h.t.Errorf("epoch %v, round %v: %v", ts.Epoch, ts.Round, err)
+		return ocr2Report{}, false
+	}
+	if err == nil && accept {
+		_, err = callWithDeadline(h.config.MaxDurationShouldTransmitAcceptedReport, h.opts, "ShouldTransmitAcceptedReport", func(ctx context.Context) (bool, error) {
+			return h.leader(ts).ShouldTransmitAcceptedReport(ctx, ts, report.report)
+		})
+		if isViolation(err) {
+			h.t.Errorf("epoch %v, round %v: %v", ts.Epoch, ts.Round, err)
+			return ocr2Report{}, false
+		}
+	}
+	return report, true
+}
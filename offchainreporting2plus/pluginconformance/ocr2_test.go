@@ -0,0 +1,122 @@
+package pluginconformance
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ocr2MedianPlugin reports the median of the oracles' observations, which are
+// the round plus the observer's oracle id. Malformed observations are skipped.
+// It commits the given fault.
+type ocr2MedianPlugin struct {
+	oracleID commontypes.OracleID
+	fault    fault
+	calls    byte
+}
+
+var _ types.ReportingPlugin = &ocr2MedianPlugin{}
+
+func (p *ocr2MedianPlugin) Query(context.Context, types.ReportTimestamp) (types.Query, error) {
+	return nil, nil
+}
+
+func (p *ocr2MedianPlugin) Observation(ctx context.Context, ts types.ReportTimestamp, _ types.Query) (types.Observation, error) {
+	if p.fault == faultIgnoresContext {
+		ignoreContext(ctx)
+	}
+	return binary.BigEndian.AppendUint64(nil, uint64(ts.Round)*100+uint64(p.oracleID)), nil
+}
+
+func (p *ocr2MedianPlugin) Report(_ context.Context, _ types.ReportTimestamp, _ types.Query, aos []types.AttributedObservation) (bool, types.Report, error) {
+	var values []uint64
+	for _, ao := range aos {
+		if len(ao.Observation) != 8 {
+			if p.fault == faultLaxValidation {
+				return false, nil, fmt.Errorf("observation has length %v", len(ao.Observation))
+			}
+			continue
+		}
+		values = append(values, binary.BigEndian.Uint64(ao.Observation))
+	}
+	if len(values) == 0 {
+		return false, nil, nil
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	report := binary.BigEndian.AppendUint64(nil, values[len(values)/2])
+	switch p.fault {
+	case faultNondeterministic:
+		p.calls++
+		report[7] ^= p.calls
+	case faultOversizedReport:
+		report = append(report, 0x00)
+	case faultPanics:
+		panic("no report today")
+	}
+	return true, report, nil
+}
+
+func (p *ocr2MedianPlugin) ShouldAcceptFinalizedReport(context.Context, types.ReportTimestamp, types.Report) (bool, error) {
+	return true, nil
+}
+
+func (p *ocr2MedianPlugin) ShouldTransmitAcceptedReport(context.Context, types.ReportTimestamp, types.Report) (bool, error) {
+	return true, nil
+}
+
+func (p *ocr2MedianPlugin) Close() error {
+	return nil
+}
+
+type ocr2MedianPluginFactory struct {
+	fault fault
+}
+
+func (f ocr2MedianPluginFactory) NewReportingPlugin(config types.ReportingPluginConfig) (types.ReportingPlugin, types.ReportingPluginInfo, error) {
+	limits := types.ReportingPluginLimits{0, 8, 8}
+	if f.fault == faultInvalidLimits {
+		limits.MaxObservationLength = -1
+	}
+	return &ocr2MedianPlugin{config.OracleID, f.fault, 0}, types.ReportingPluginInfo{"median", false, limits}, nil
+}
+
+var ocr2Config = types.ReportingPluginConfig{
+	N:                                       4,
+	F:                                       1,
+	MaxDurationQuery:                        50 * time.Millisecond,
+	MaxDurationObservation:                  50 * time.Millisecond,
+	MaxDurationReport:                       50 * time.Millisecond,
+	MaxDurationShouldAcceptFinalizedReport:  50 * time.Millisecond,
+	MaxDurationShouldTransmitAcceptedReport: 50 * time.Millisecond,
+}
+
+func TestOCR2ReportingPluginConformant(t *testing.T) {
+	TestOCR2ReportingPlugin(t, ocr2MedianPluginFactory{}, ocr2Config, Options{Rounds: 5, Seed: 1})
+}
+
+func TestOCR2ReportingPluginViolations(t *testing.T) {
+	run := func(fault fault) func(t *testing.T) {
+		return func(t *testing.T) {
+			TestOCR2ReportingPlugin(t, ocr2MedianPluginFactory{fault}, ocr2Config, Options{Rounds: 5, Seed: 1})
+		}
+	}
+	testViolations(t, "TestOCR2ReportingPluginViolations", []violation{
+		{"Nondeterministic", run(faultNondeterministic), "Report is not deterministic"},
+		{"OversizedReport", run(faultOversizedReport), "exceeding MaxReportLength (8)"},
+		{"LaxValidation", run(faultLaxValidation), "Report failed on 4 observations"},
+		{"InvalidLimits", run(faultInvalidLimits), "MaxObservationLength (-1) out of range"},
+		{"IgnoresContext", run(faultIgnoresContext), "Observation still running"},
+		{"Panics", run(faultPanics), "Report panicked: no report today"},
+		{"InvalidConfig", func(t *testing.T) {
+			config := ocr2Config
+			config.MaxDurationReport = 0
+			TestOCR2ReportingPlugin(t, ocr2MedianPluginFactory{}, config, Options{})
+		}, "invalid config: MaxDurationReport must be positive"},
+	})
+}
@@ -0,0 +1,471 @@
+package pluginconformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/byzquorum"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// TestOCR3ReportingPlugin checks that plugins created by factory
+//
+//   - declare ReportingPluginLimits within the protocol's bounds, and the same
+//     limits for every oracle
+//   - return queries, observations, outcomes and reports within those limits
+//   - return valid, deterministic values from ObservationQuorum
+//   - agree on ValidateObservation across oracles, and compute an outcome from
+//     any quorum of observations that passed it, including malformed ones
+//   - compute identical outcomes and reports at every oracle, i.e. that Outcome
+//     and Reports are pure functions of their inputs
+//   - return from functions taking a context within MaxDuration* of their
+//     respective call, and promptly when the context is already cancelled
+//
+// config is passed to the factory once for every oracle, with OracleID set
+// accordingly. N, F and all MaxDuration* fields must be set.
+func TestOCR3ReportingPlugin[RI any](t *testing.T, factory ocr3types.ReportingPluginFactory[RI], config ocr3types.ReportingPluginConfig, opts Options) {
+	opts = opts.withDefaults()
+	if err := checkOCR3Config(config); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	t.Run("Limits", func(t *testing.T) {
+		h := newOCR3Harness(t, factory, config, opts)
+		checkOCR3Limits(t, h.limits)
+	})
+
+	t.Run("Rounds", func(t *testing.T) {
+		h := newOCR3Harness(t, factory, config, opts)
+		var previousOutcome ocr3types.Outcome
+		for seqNr := uint64(1); seqNr <= uint64(opts.Rounds); seqNr++ {
+			outcome, _, ok := h.round(seqNr, previousOutcome)
+			if !ok {
+				t.Fatalf("aborting after failed round with seqNr %v", seqNr)
+			}
+			previousOutcome = outcome
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		h := newOCR3Harness(t, factory, config, opts)
+		outctx := ocr3types.OutcomeContext{1, nil, 1, 1}
+		plugin := h.plugins[0]
+
+		if _, err := callCancelled(opts, "Query", func(ctx context.Context) (types.Query, error) {
+			return plugin.Query(ctx, outctx)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+		query, _ := h.query(outctx)
+		if _, err := callCancelled(opts, "Observation", func(ctx context.Context) (types.Observation, error) {
+			return plugin.Observation(ctx, outctx, query)
+		}); isViolation(err) {
+			t.Error(err)
+		}
+
+		_, reports, ok := h.round(1, nil)
+		if !ok {
+			t.Fatalf("could not complete a round to obtain reports")
+		}
+		if len(reports) == 0 {
+			t.Log("first round produced no reports, skipping ShouldAcceptAttestedReport and ShouldTransmitAcceptedReport")
+		}
+		for _, report := range reports {
+			report := report
+			if _, err := callCancelled(opts, "ShouldAcceptAttestedReport", func(ctx context.Context) (bool, error) {
+				return plugin.ShouldAcceptAttestedReport(ctx, 1, report)
+			}); isViolation(err) {
+				t.Error(err)
+			}
+			if _, err := callCancelled(opts, "ShouldTransmitAcceptedReport", func(ctx context.Context) (bool, error) {
+				return plugin.ShouldTransmitAcceptedReport(ctx, 1, report)
+			}); isViolation(err) {
+				t.Error(err)
+			}
+		}
+	})
+}
+
+func checkOCR3Config(config ocr3types.ReportingPluginConfig) error {
+	if !(0 <= config.F && 3*config.F < config.N && config.N <= types.MaxOracles) {
+		return fmt.Errorf("need 0 <= F, 3*F < N and N <= %v, got N = %v and F = %v", types.MaxOracles, config.N, config.F)
+	}
+	for name, d := range map[string]time.Duration{
+		"MaxDurationQuery":                        config.MaxDurationQuery,
+		"MaxDurationObservation":                  config.MaxDurationObservation,
+		"MaxDurationShouldAcceptAttestedReport":   config.MaxDurationShouldAcceptAttestedReport,
+		"MaxDurationShouldTransmitAcceptedReport": config.MaxDurationShouldTransmitAcceptedReport,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%v must be positive", name)
+		}
+	}
+	return nil
+}
+
+func checkOCR3Limits(t *testing.T, limits ocr3types.ReportingPluginLimits) {
+	for _, l := range []struct {
+		name  string
+		value int
+		max   int
+	}{
+		{"MaxQueryLength", limits.MaxQueryLength, ocr3types.MaxMaxQueryLength},
+		{"MaxObservationLength", limits.MaxObservationLength, ocr3types.MaxMaxObservationLength},
+		{"MaxOutcomeLength", limits.MaxOutcomeLength, ocr3types.MaxMaxOutcomeLength},
+		{"MaxReportLength", limits.MaxReportLength, ocr3types.MaxMaxReportLength},
+		{"MaxReportCount", limits.MaxReportCount, ocr3types.MaxMaxReportCount},
+	} {
+		if !(0 <= l.value && l.value <= l.max) {
+			t.Errorf("%v (%v) out of range, should be between 0 and %v", l.name, l.value, l.max)
+		}
+	}
+}
+
+type ocr3Harness[RI any] struct {
+	t       *testing.T
+	config  ocr3types.ReportingPluginConfig
+	opts    Options
+	rng     *rand.Rand
+	plugins []ocr3types.ReportingPlugin[RI]
+	limits  ocr3types.ReportingPluginLimits
+}
+
+// newOCR3Harness creates a plugin for every oracle. The plugins are closed
+// when the test finishes.
+func newOCR3Harness[RI any](t *testing.T, factory ocr3types.ReportingPluginFactory[RI], config ocr3types.ReportingPluginConfig, opts Options) *ocr3Harness[RI] {
+	h := &ocr3Harness[RI]{t: t, config: config, opts: opts, rng: rand.New(rand.NewSource(opts.Seed))}
+	var firstInfo ocr3types.ReportingPluginInfo
+	for i := 0; i < config.N; i++ {
+		i := i
+		pluginConfig := config
+		pluginConfig.OracleID = commontypes.OracleID(i)
+		plugin, info, err := factory.NewReportingPlugin(pluginConfig)
+		if err != nil {
+			t.Fatalf("NewReportingPlugin failed for oracle %v: %v", i, err)
+		}
+		t.Cleanup(func() {
+			if _, err := callPure("Close", func() (struct{}, error) { return struct{}{}, plugin.Close() }); err != nil {
+				t.Errorf("closing plugin of oracle %v: %v", i, err)
+			}
+			// may return an error, but must not panic
+			if _, err := callPure("Close", func() (struct{}, error) { return struct{}{}, plugin.Close() }); isViolation(err) {
+				t.Errorf("closing plugin of oracle %v a second time: %v", i, err)
+			}
+		})
+		if i == 0 {
+			firstInfo = info
+		} else if info != firstInfo {
+			t.Errorf("oracle %v got ReportingPluginInfo %+v, but oracle 0 got %+v", i, info, firstInfo)
+		}
+		h.plugins = append(h.plugins, plugin)
+	}
+	h.limits = firstInfo.Limits
+	return h
+}
+
+func (h *ocr3Harness[RI]) leader(outctx ocr3types.OutcomeContext) ocr3types.ReportingPlugin[RI] {
+	return h.plugins[int(outctx.SeqNr%uint64(len(h.plugins)))]
+}
+
+func (h *ocr3Harness[RI]) query(outctx ocr3types.OutcomeContext) (types.Query, bool) {
+	query, err := callWithDeadline(h.config.MaxDurationQuery, h.opts, "Query", func(ctx context.Context) (types.Query, error) {
+		return h.leader(outctx).Query(ctx, outctx)
+	})
+	if err != nil {
+		h.t.Errorf("seqNr %v: %v", outctx.SeqNr, err)
+		return nil, false
+	}
+	if len(query) > h.limits.MaxQueryLength {
+		h.t.Errorf("seqNr %v: query has length %v, exceeding MaxQueryLength (%v)", outctx.SeqNr, len(query), h.limits.MaxQueryLength)
+		return nil, false
+	}
+	return query, true
+}
+
+// observations returns the observations of all oracles. Oracles that fail to
+// make an observation are skipped, unless they violate the contract in doing
+// so.
+func (h *ocr3Harness[RI]) observations(outctx ocr3types.OutcomeContext, query types.Query) ([]types.AttributedObservation, bool) {
+	ok := true
+	var aos []types.AttributedObservation
+	for i, plugin := range h.plugins {
+		plugin := plugin
+		observation, err := callWithDeadline(h.config.MaxDurationObservation, h.opts, "Observation", func(ctx context.Context) (types.Observation, error) {
+			return plugin.Observation(ctx, outctx, query)
+		})
+		if err != nil {
+			if isViolation(err) {
+				h.t.Errorf("seqNr %v, oracle %v: %v", outctx.SeqNr, i, err)
+				ok = false
+			} else {
+				h.t.Logf("seqNr %v, oracle %v: Observation failed: %v", outctx.SeqNr, i, err)
+			}
+			continue
+		}
+		if len(observation) > h.limits.MaxObservationLength {
+			h.t.Errorf("seqNr %v, oracle %v: observation has length %v, exceeding MaxObservationLength (%v)", outctx.SeqNr, i, len(observation), h.limits.MaxObservationLength)
+			ok = false
+			continue
+		}
+		aos = append(aos, types.AttributedObservation{observation, commontypes.OracleID(i)})
+	}
+	return aos, ok
+}
+
+// observationQuorum checks that all oracles agree on a valid quorum and
+// returns it as a number of observations.
+func (h *ocr3Harness[RI]) observationQuorum(outctx ocr3types.OutcomeContext, query types.Query) (int, bool) {
+	var first ocr3types.Quorum
+	for i, plugin := range h.plugins {
+		for repetition := 0; repetition < 2; repetition++ {
+			quorum, err := callPure("ObservationQuorum", func() (ocr3types.Quorum, error) {
+				return plugin.ObservationQuorum(outctx, query)
+			})
+			if err != nil {
+				h.t.Errorf("seqNr %v, oracle %v: ObservationQuorum failed: %v", outctx.SeqNr, i, err)
+				return 0, false
+			}
+			if i == 0 && repetition == 0 {
+				first = quorum
+			} else if quorum != first {
+				h.t.Errorf("seqNr %v, oracle %v: ObservationQuorum is not deterministic, returned %v but %v before", outctx.SeqNr, i, quorum, first)
+				return 0, false
+			}
+		}
+	}
+
+	n, f := h.config.N, h.config.F
+	var quorum int
+	switch first {
+	case ocr3types.QuorumFPlusOne:
+		quorum = f + 1
+	case ocr3types.QuorumTwoFPlusOne:
+		quorum = 2*f + 1
+	case ocr3types.QuorumByzQuorum:
+		quorum = byzquorum.Size(n, f)
+	case ocr3types.QuorumNMinusF:
+		quorum = n - f
+	default:
+		quorum = int(first)
+	}
+	if !(0 < quorum && quorum <= n-f) {
+		h.t.Errorf("seqNr %v: ObservationQuorum returned %v, which amounts to %v observations. Should amount to between 1 and N-F = %v", outctx.SeqNr, first, quorum, n-f)
+		return 0, false
+	}
+	return quorum, true
+}
+
+// validate checks that all oracles agree on whether ao is valid.
+func (h *ocr3Harness[RI]) validate(outctx ocr3types.OutcomeContext, query types.Query, ao types.AttributedObservation) (valid bool, ok bool) {
+	for i, plugin := range h.plugins {
+		_, err := callPure("ValidateObservation", func() (struct{}, error) {
+			return struct{}{}, plugin.ValidateObservation(outctx, query, ao)
+		})
+		if isViolation(err) {
+			h.t.Errorf("seqNr %v, oracle %v: %v", outctx.SeqNr, i, err)
+			return false, false
+		}
+		if i == 0 {
+			valid = err == nil
+		} else if valid != (err == nil) {
+			h.t.Errorf("seqNr %v: oracles 0 and %v disagree on whether observation %x from oracle %v is valid (oracle %v: %v)", outctx.SeqNr, i, ao.Observation, ao.Observer, i, err)
+			return false, false
+		}
+	}
+	return valid, true
+}
+
+// outcome checks that all oracles compute the same outcome from aos and
+// returns it.
+func (h *ocr3Harness[RI]) outcome(outctx ocr3types.OutcomeContext, query types.Query, aos []types.AttributedObservation) (ocr3types.Outcome, bool) {
+	var first ocr3types.Outcome
+	for i, plugin := range h.plugins {
+		for repetition := 0; repetition < 2; repetition++ {
+			outcome, err := callPure("Outcome", func() (ocr3types.Outcome, error) {
+				return plugin.Outcome(outctx, query, aos)
+			})
+			if err != nil {
+				h.t.Errorf("seqNr %v, oracle %v: Outcome failed on %v validated observations: %v", outctx.SeqNr, i, len(aos), err)
+				return nil, false
+			}
+			if i == 0 && repetition == 0 {
+				first = outcome
+			} else if !bytes.Equal(outcome, first) {
+				h.t.Errorf("seqNr %v, oracle %v: Outcome is not deterministic, returned %x but %x before", outctx.SeqNr, i, outcome, first)
+				return nil, false
+			}
+		}
+	}
+	if len(first) > h.limits.MaxOutcomeLength {
+		h.t.Errorf("seqNr %v: outcome has length %v, exceeding MaxOutcomeLength (%v)", outctx.SeqNr, len(first), h.limits.MaxOutcomeLength)
+		return nil, false
+	}
+	return first, true
+}
+
+// reports checks that all oracles compute the same reports from outcome and
+// returns them.
+func (h *ocr3Harness[RI]) reports(seqNr uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportWithInfo[RI], bool) {
+	var first []ocr3types.ReportWithInfo[RI]
+	for i, plugin := range h.plugins {
+		for repetition := 0; repetition < 2; repetition++ {
+			reports, err := callPure("Reports", func() ([]ocr3types.ReportWithInfo[RI], error) {
+				return plugin.Reports(seqNr, outcome)
+			})
+			if err != nil {
+				h.t.Errorf("seqNr %v, oracle %v: Reports failed: %v", seqNr, i, err)
+				return nil, false
+			}
+			if i == 0 && repetition == 0 {
+				first = reports
+			} else if !equalReports(reports, first) {
+				h.t.Errorf("seqNr %v, oracle %v: Reports is not deterministic, returned %+v but %+v before", seqNr, i, reports, first)
+				return nil, false
+			}
+		}
+	}
+	if len(first) > h.limits.MaxReportCount {
+		h.t.Errorf("seqNr %v: got %v reports, exceeding MaxReportCount (%v)", seqNr, len(first), h.limits.MaxReportCount)
+		return nil, false
+	}
+	for i, report := range first {
+		if len(report.Report) > h.limits.MaxReportLength {
+			h.t.Errorf("seqNr %v: report %v has length %v, exceeding MaxReportLength (%v)", seqNr, i, len(report.Report), h.limits.MaxReportLength)
+			return nil, false
+		}
+	}
+	return first, true
+}
+
+func equalReports[RI any](a, b []ocr3types.ReportWithInfo[RI]) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Report, b[i].Report) || !reflect.DeepEqual(a[i].Info, b[i].Info) {
+			return false
+		}
+	}
+	return true
+}
+
+// round simulates a fault-free round and additionally feeds the plugins
+// malformed observations and different quorums of observations. It returns
+// the outcome of all valid observations and the reports generated from it.
+func (h *ocr3Harness[RI]) round(seqNr uint64, previousOutcome ocr3types.Outcome) (ocr3types.Outcome, []ocr3types.ReportWithInfo[RI], bool) {
+	outctx := ocr3types.OutcomeContext{seqNr, previousOutcome, 1, seqNr}
+
+	query, ok := h.query(outctx)
+	if !ok {
+		return nil, nil, false
+	}
+	aos, ok := h.observations(outctx, query)
+	if !ok {
+		return nil, nil, false
+	}
+	quorum, ok := h.observationQuorum(outctx, query)
+	if !ok {
+		return nil, nil, false
+	}
+
+	var validAOs []types.AttributedObservation
+	for _, ao := range aos {
+		valid, ok := h.validate(outctx, query, ao)
+		if !ok {
+			return nil, nil, false
+		}
+		if !valid {
+			h.t.Logf("seqNr %v: observation of oracle %v was deemed invalid", seqNr, ao.Observer)
+			continue
+		}
+		validAOs = append(validAOs, ao)
+	}
+	if len(validAOs) < quorum {
+		h.t.Errorf("seqNr %v: only %v valid observations, but quorum is %v", seqNr, len(validAOs), quorum)
+		return nil, nil, false
+	}
+
+	outcome, ok := h.outcome(outctx, query, validAOs)
+	if !ok {
+		return nil, nil, false
+	}
+
+	// A leader may pick any quorum of valid observations, in any order.
+	for i := 0; i < subsetsPerRound; i++ {
+		var subsetAOs []types.AttributedObservation
+		for _, j := range subset(h.rng, len(validAOs), quorum) {
+			subsetAOs = append(subsetAOs, validAOs[j])
+		}
+		subsetOutcome, ok := h.outcome(outctx, query, subsetAOs)
+		if !ok {
+			return nil, nil, false
+		}
+		if _, ok := h.reports(seqNr, subsetOutcome); !ok {
+			return nil, nil, false
+		}
+	}
+
+	// Faulty oracles may send arbitrary observations. Those that pass
+	// ValidateObservation must not trip up Outcome.
+	for _, target := range validAOs {
+		for _, observation := range malformed(h.rng, target.Observation, h.limits.MaxObservationLength) {
+			ao := types.AttributedObservation{observation, target.Observer}
+			valid, ok := h.validate(outctx, query, ao)
+			if !ok {
+				return nil, nil, false
+			}
+			if !valid {
+				continue
+			}
+			var mixedAOs []types.AttributedObservation
+			for _, validAO := range validAOs {
+				if validAO.Observer == ao.Observer {
+					mixedAOs = append(mixedAOs, ao)
+				} else {
+					mixedAOs = append(mixedAOs, validAO)
+				}
+			}
+			mixedOutcome, ok := h.outcome(outctx, query, mixedAOs)
+			if !ok {
+				return nil, nil, false
+			}
+			if _, ok := h.reports(seqNr, mixedOutcome); !ok {
+				return nil, nil, false
+			}
+		}
+	}
+
+	reports, ok := h.reports(seqNr, outcome)
+	if !ok {
+		return nil, nil, false
+	}
+	for i, report := range reports {
+		report := report
+		accept, err := callWithDeadline(h.config.MaxDurationShouldAcceptAttestedReport, h.opts, "ShouldAcceptAttestedReport", func(ctx context.Context) (bool, error) {
+			return h.leader(outctx).ShouldAcceptAttestedReport(ctx, seqNr, report)
+		})
+		if isViolation(err) {
+			h.t.Errorf("seqNr %v, report %v: %v", seqNr, i, err)
+			return nil, nil, false
+		}
+		if err != nil || !accept {
+			continue
+		}
+		_, err = callWithDeadline(h.config.MaxDurationShouldTransmitAcceptedReport, h.opts, "ShouldTransmitAcceptedReport", func(ctx context.Context) (bool, error) {
+			return h.leader(outctx).ShouldTransmitAcceptedReport(ctx, seqNr, report)
+		})
+		if isViolation(err) {
+			h.t.Errorf("seqNr %v, report %v: %v", seqNr, i, err)
+			return nil, nil, false
+		}
+	}
+
+	return outcome, reports, true
+}
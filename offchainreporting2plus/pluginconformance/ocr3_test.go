@@ -0,0 +1,144 @@
+package pluginconformance
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// medianPlugin reports the median of the oracles' observations, which are the
+// sequence number plus the observer's oracle id. It commits the given fault.
+type medianPlugin struct {
+	oracleID commontypes.OracleID
+	n        int
+	fault    fault
+	calls    byte
+}
+
+var _ ocr3types.ReportingPlugin[string] = &medianPlugin{}
+
+func (p *medianPlugin) Query(context.Context, ocr3types.OutcomeContext) (types.Query, error) {
+	return nil, nil
+}
+
+func (p *medianPlugin) Observation(ctx context.Context, outctx ocr3types.OutcomeContext, _ types.Query) (types.Observation, error) {
+	if p.fault == faultIgnoresContext {
+		ignoreContext(ctx)
+	}
+	return binary.BigEndian.AppendUint64(nil, outctx.SeqNr*100+uint64(p.oracleID)), nil
+}
+
+func (p *medianPlugin) ValidateObservation(_ ocr3types.OutcomeContext, _ types.Query, ao types.AttributedObservation) error {
+	if p.fault == faultDisagreeingValidation && p.oracleID == 1 && ao.Observer == 0 {
+		return fmt.Errorf("not trusting oracle 0")
+	}
+	if p.fault != faultLaxValidation && len(ao.Observation) != 8 {
+		return fmt.Errorf("observation has length %v", len(ao.Observation))
+	}
+	return nil
+}
+
+func (p *medianPlugin) ObservationQuorum(ocr3types.OutcomeContext, types.Query) (ocr3types.Quorum, error) {
+	if p.fault == faultInvalidQuorum {
+		return ocr3types.Quorum(p.n), nil
+	}
+	return ocr3types.QuorumTwoFPlusOne, nil
+}
+
+func (p *medianPlugin) Outcome(_ ocr3types.OutcomeContext, _ types.Query, aos []types.AttributedObservation) (ocr3types.Outcome, error) {
+	var values []uint64
+	for _, ao := range aos {
+		if len(ao.Observation) != 8 {
+			return nil, fmt.Errorf("observation has length %v", len(ao.Observation))
+		}
+		values = append(values, binary.BigEndian.Uint64(ao.Observation))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	outcome := binary.BigEndian.AppendUint64(nil, values[len(values)/2])
+	if p.fault == faultNondeterministic {
+		p.calls++
+		outcome[7] ^= p.calls
+	}
+	return outcome, nil
+}
+
+func (p *medianPlugin) Reports(_ uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportWithInfo[string], error) {
+	report := ocr3types.ReportWithInfo[string]{types.Report(outcome), "median"}
+	switch p.fault {
+	case faultOversizedReport:
+		report.Report = append(report.Report, 0x00)
+	case faultTooManyReports:
+		return []ocr3types.ReportWithInfo[string]{report, report}, nil
+	case faultPanics:
+		panic("no reports today")
+	}
+	return []ocr3types.ReportWithInfo[string]{report}, nil
+}
+
+func (p *medianPlugin) ShouldAcceptAttestedReport(context.Context, uint64, ocr3types.ReportWithInfo[string]) (bool, error) {
+	return true, nil
+}
+
+func (p *medianPlugin) ShouldTransmitAcceptedReport(context.Context, uint64, ocr3types.ReportWithInfo[string]) (bool, error) {
+	return true, nil
+}
+
+func (p *medianPlugin) Close() error {
+	return nil
+}
+
+type medianPluginFactory struct {
+	fault fault
+}
+
+func (f medianPluginFactory) NewReportingPlugin(config ocr3types.ReportingPluginConfig) (ocr3types.ReportingPlugin[string], ocr3types.ReportingPluginInfo, error) {
+	limits := ocr3types.ReportingPluginLimits{0, 8, 8, 8, 1}
+	if f.fault == faultInvalidLimits {
+		limits.MaxReportCount = ocr3types.MaxMaxReportCount + 1
+	}
+	return &medianPlugin{config.OracleID, config.N, f.fault, 0}, ocr3types.ReportingPluginInfo{"median", limits}, nil
+}
+
+var ocr3Config = ocr3types.ReportingPluginConfig{
+	N:                                       4,
+	F:                                       1,
+	MaxDurationQuery:                        50 * time.Millisecond,
+	MaxDurationObservation:                  50 * time.Millisecond,
+	MaxDurationShouldAcceptAttestedReport:   50 * time.Millisecond,
+	MaxDurationShouldTransmitAcceptedReport: 50 * time.Millisecond,
+}
+
+func TestOCR3ReportingPluginConformant(t *testing.T) {
+	TestOCR3ReportingPlugin[string](t, medianPluginFactory{}, ocr3Config, Options{Rounds: 5, Seed: 1})
+}
+
+func TestOCR3ReportingPluginViolations(t *testing.T) {
+	run := func(fault fault) func(t *testing.T) {
+		return func(t *testing.T) {
+			TestOCR3ReportingPlugin[string](t, medianPluginFactory{fault}, ocr3Config, Options{Rounds: 5, Seed: 1})
+		}
+	}
+	testViolations(t, "TestOCR3ReportingPluginViolations", []violation{
+		{"Nondeterministic", run(faultNondeterministic), "Outcome is not deterministic"},
+		{"OversizedReport", run(faultOversizedReport), "exceeding MaxReportLength (8)"},
+		{"TooManyReports", run(faultTooManyReports), "got 2 reports, exceeding MaxReportCount (1)"},
+		{"LaxValidation", run(faultLaxValidation), "Outcome failed on 4 validated observations"},
+		{"DisagreeingValidation", run(faultDisagreeingValidation), "oracles 0 and 1 disagree on whether observation"},
+		{"InvalidQuorum", run(faultInvalidQuorum), "Should amount to between 1 and N-F = 3"},
+		{"InvalidLimits", run(faultInvalidLimits), "MaxReportCount (2001) out of range"},
+		{"IgnoresContext", run(faultIgnoresContext), "Observation still running"},
+		{"Panics", run(faultPanics), "Reports panicked: no reports today"},
+		{"InvalidConfig", func(t *testing.T) {
+			config := ocr3Config
+			config.F = 2
+			TestOCR3ReportingPlugin[string](t, medianPluginFactory{}, config, Options{})
+		}, "invalid config: need 0 <= F, 3*F < N"},
+	})
+}
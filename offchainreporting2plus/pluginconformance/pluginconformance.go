@@ -0,0 +1,199 @@
+// Package pluginconformance is a test suite for ReportingPlugin
+// implementations. It checks the parts of the plugin contract that the OCR
+// protocols rely on for liveness but cannot enforce at runtime, e.g. that all
+// honest oracles compute the same outcome from the same inputs. Violations of
+// this contract typically show up as stalled rounds in production.
+//
+// Call TestOCR3ReportingPlugin or TestOCR2ReportingPlugin from a test of your
+// own package:
+//
+//	func TestMyPlugin(t *testing.T) {
+//		pluginconformance.TestOCR3ReportingPlugin[MyInfo](t, myFactory, ocr3types.ReportingPluginConfig{
+//			N:                                       4,
+//			F:                                       1,
+//			OffchainConfig:                          myOffchainConfig,
+//			MaxDurationQuery:                        time.Second,
+//			MaxDurationObservation:                  time.Second,
+//			MaxDurationShouldAcceptAttestedReport:   time.Second,
+//			MaxDurationShouldTransmitAcceptedReport: time.Second,
+//		}, pluginconformance.Options{})
+//	}
+//
+// The suite instantiates one plugin per oracle from the factory, all within
+// the same process, and simulates fault-free rounds between them, interspersed
+// with malformed observations. Plugins that observe external data sources
+// should be pointed at a stub that gives every oracle the same view of the
+// world, or at least a view that lets every round reach its quorum.
+package pluginconformance
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Options tune the test suite. The zero value is a reasonable default.
+type Options struct {
+	// Number of consecutive rounds to simulate. Defaults to 10.
+	Rounds int
+	// Seed for choosing observation subsets and malformed observations. Runs
+	// with the same seed exercise the plugin with the same inputs, as long as
+	// the plugin itself is deterministic.
+	Seed int64
+	// How long a function that takes a context may keep running after its
+	// context has expired before we consider it to ignore the context.
+	// Defaults to 100ms.
+	CancellationGracePeriod time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Rounds <= 0 {
+		o.Rounds = 10
+	}
+	if o.CancellationGracePeriod <= 0 {
+		o.CancellationGracePeriod = 100 * time.Millisecond
+	}
+	return o
+}
+
+// Number of random observation subsets to compute an outcome (resp. report)
+// for in each round, in addition to the set of all valid observations.
+const subsetsPerRound = 3
+
+// panicError is returned when a plugin function panics.
+type panicError struct {
+	name  string
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.name, e.value)
+}
+
+// contextIgnoredError is returned when a plugin function doesn't return in
+// time after its context expired.
+type contextIgnoredError struct {
+	name string
+	// how long after the call the context expired
+	expiry time.Duration
+	// how long after the call we gave up waiting
+	wait time.Duration
+}
+
+func (e *contextIgnoredError) Error() string {
+	if e.expiry <= 0 {
+		return fmt.Sprintf("%s still running %v after being called with a cancelled context", e.name, e.wait)
+	}
+	return fmt.Sprintf("%s still running %v after being called, although its context expired after %v", e.name, e.wait, e.expiry)
+}
+
+// isViolation reports whether err indicates a contract violation, as opposed
+// to an error the plugin is allowed to return.
+func isViolation(err error) bool {
+	switch err.(type) {
+	case *panicError, *contextIgnoredError:
+		return true
+	}
+	return false
+}
+
+// callPure calls f, turning a panic into a *panicError.
+func callPure[T any](name string, f func() (T, error)) (result T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			var zero T
+			result, err = zero, &panicError{name, p}
+		}
+	}()
+	return f()
+}
+
+// callWithContext calls f with a context that expires after expiry, and waits
+// for it to return for at most expiry plus the grace period, returning a
+// *contextIgnoredError if it doesn't. f keeps running in the background in
+// that case. A non-positive expiry results in an already cancelled context.
+func callWithContext[T any](expiry time.Duration, opts Options, name string, f func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), expiry)
+	defer cancel()
+	wait := opts.CancellationGracePeriod
+	if expiry > 0 {
+		wait += expiry
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	chResult := make(chan result, 1)
+	go func() {
+		value, err := callPure(name, func() (T, error) { return f(ctx) })
+		chResult <- result{value, err}
+	}()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case r := <-chResult:
+		return r.value, r.err
+	case <-timer.C:
+		var zero T
+		return zero, &contextIgnoredError{name, expiry, wait}
+	}
+}
+
+// callWithDeadline calls f with a context that expires after maxDuration, like
+// the protocol does.
+func callWithDeadline[T any](maxDuration time.Duration, opts Options, name string, f func(context.Context) (T, error)) (T, error) {
+	return callWithContext(maxDuration, opts, name, f)
+}
+
+// callCancelled calls f with a context that has already been cancelled.
+func callCancelled[T any](opts Options, name string, f func(context.Context) (T, error)) (T, error) {
+	return callWithContext(0, opts, name, f)
+}
+
+// malformed returns variations of a well-formed observation that a faulty
+// oracle might send instead. None of them exceed maxLength, since the
+// protocol discards longer observations before they reach the plugin.
+func malformed(rng *rand.Rand, observation []byte, maxLength int) [][]byte {
+	clip := func(b []byte) []byte {
+		if len(b) > maxLength {
+			return b[:maxLength]
+		}
+		return b
+	}
+	randomBytes := func(n int) []byte {
+		b := make([]byte, n)
+		rng.Read(b)
+		return b
+	}
+
+	result := [][]byte{
+		nil,
+		clip([]byte{0x00}),
+		clip([]byte{0xff}),
+		clip(randomBytes(1 + rng.Intn(64))),
+	}
+	if len(observation) > 0 {
+		result = append(result,
+			observation[:len(observation)/2],
+			observation[:len(observation)-1],
+			clip(randomBytes(len(observation))),
+		)
+		flipped := append([]byte{}, observation...)
+		flipped[rng.Intn(len(flipped))] ^= 1 << rng.Intn(8)
+		result = append(result, flipped)
+	}
+	if len(observation) < maxLength {
+		result = append(result, clip(append(append([]byte{}, observation...), randomBytes(1+rng.Intn(16))...)))
+	}
+	return result
+}
+
+// subset returns a random subset of the indices 0, ..., n-1 with at least
+// minSize elements, in random order.
+func subset(rng *rand.Rand, n int, minSize int) []int {
+	perm := rng.Perm(n)
+	return perm[:minSize+rng.Intn(n-minSize+1)]
+}
@@ -0,0 +1,143 @@
+package pluginconformance
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fault selects a contract violation for the test plugins to commit.
+type fault string
+
+const (
+	faultNondeterministic      fault = "nondeterministic"
+	faultOversizedReport       fault = "oversizedReport"
+	faultTooManyReports        fault = "tooManyReports"
+	faultLaxValidation         fault = "laxValidation"
+	faultInvalidQuorum         fault = "invalidQuorum"
+	faultInvalidLimits         fault = "invalidLimits"
+	faultIgnoresContext        fault = "ignoresContext"
+	faultPanics                fault = "panics"
+	faultDisagreeingValidation fault = "disagreeingValidation"
+)
+
+// ignoreContext blocks for longer than any expiry used in these tests,
+// regardless of ctx.
+func ignoreContext(ctx context.Context) {
+	time.Sleep(time.Second)
+}
+
+const violationEnv = "PLUGINCONFORMANCE_VIOLATION"
+
+type violation struct {
+	name  string
+	run   func(t *testing.T)
+	error string
+}
+
+// testViolations runs each violation in a child process of the test binary,
+// since the suite reports violations by failing the *testing.T it is given,
+// and checks that the suite fails with the expected error.
+func testViolations(t *testing.T, test string, violations []violation) {
+	for _, v := range violations {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			if os.Getenv(violationEnv) == v.name {
+				v.run(t)
+				return
+			}
+			if testing.Short() {
+				t.Skip("runs a child process")
+			}
+			cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$/^"+v.name+"$", "-test.v")
+			cmd.Env = append(os.Environ(), violationEnv+"="+v.name)
+			output, err := cmd.CombinedOutput()
+			if err == nil {
+				t.Fatalf("suite passed plugin with violation %v:\n%s", v.name, output)
+			}
+			if !strings.Contains(string(output), v.error) {
+				t.Errorf("suite output doesn't contain %q:\n%s", v.error, output)
+			}
+		})
+	}
+}
+
+func TestCallWithContext(t *testing.T) {
+	opts := Options{}.withDefaults()
+
+	value, err := callWithDeadline(time.Second, opts, "f", func(ctx context.Context) (int, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("no deadline")
+		}
+		return 1, nil
+	})
+	if value != 1 || err != nil {
+		t.Errorf("unexpected result %v, %v", value, err)
+	}
+
+	if _, err := callCancelled(opts, "f", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}); err == nil || isViolation(err) {
+		t.Errorf("unexpected error %v for function honoring its context", err)
+	}
+
+	_, err = callWithDeadline(10*time.Millisecond, opts, "f", func(ctx context.Context) (int, error) {
+		ignoreContext(ctx)
+		return 0, nil
+	})
+	if _, ok := err.(*contextIgnoredError); !ok || !strings.Contains(err.Error(), "context expired after 10ms") {
+		t.Errorf("unexpected error %v for function ignoring its context", err)
+	}
+
+	_, err = callCancelled(opts, "f", func(ctx context.Context) (int, error) {
+		panic("boom")
+	})
+	if _, ok := err.(*panicError); !ok || err.Error() != "f panicked: boom" {
+		t.Errorf("unexpected error %v for panicking function", err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	observation := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	// the protocol never passes observations longer than maxLength to the
+	// plugin
+	for _, maxLength := range []int{8, 9, 16} {
+		variations := malformed(rng, observation, maxLength)
+		if len(variations) == 0 {
+			t.Fatalf("no malformed observations for maxLength %v", maxLength)
+		}
+		for _, variation := range variations {
+			if len(variation) > maxLength {
+				t.Errorf("malformed observation %x exceeds maxLength %v", variation, maxLength)
+			}
+		}
+	}
+	for _, variation := range malformed(rng, nil, 0) {
+		if len(variation) != 0 {
+			t.Errorf("malformed observation %x exceeds maxLength 0", variation)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		s := subset(rng, 7, 3)
+		if len(s) < 3 || len(s) > 7 {
+			t.Fatalf("subset %v has wrong size", s)
+		}
+		seen := map[int]bool{}
+		for _, j := range s {
+			if j < 0 || j >= 7 || seen[j] {
+				t.Fatalf("subset %v has invalid or duplicate index", s)
+			}
+			seen[j] = true
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package remotesigner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one operation performed, or refused, by the signer.
+// It never contains the signed data itself, since reports may contain secret
+// information, only its hash.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Set for onchain signings and approvals only. Epoch and Round are set
+	// for OCR2 reports, SeqNr for OCR3 reports.
+	ConfigDigest string `json:"configDigest,omitempty"`
+	Epoch        uint32 `json:"epoch,omitempty"`
+	Round        uint8  `json:"round,omitempty"`
+	SeqNr        uint64 `json:"seqNr,omitempty"`
+	// Hex SHA-256 of the message, report or point
+	DataHash string `json:"dataHash"`
+	Allowed  bool   `json:"allowed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AuditLog records the signer's operations. If Record returns an error, the
+// operation is refused. All its functions must be thread-safe.
+type AuditLog interface {
+	Record(AuditRecord) error
+}
+
+// FileAuditLog appends records as JSON lines to a file. Records of onchain
+// signings and approvals are synced to disk before the signature or approval
+// is returned, other records are not.
+type FileAuditLog struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+var _ AuditLog = (*FileAuditLog)(nil)
+
+// OpenFileAuditLog opens the FileAuditLog at path, creating the file if
+// necessary.
+func OpenFileAuditLog(path string) (*FileAuditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log: %w", err)
+	}
+	return &FileAuditLog{sync.Mutex{}, file}, nil
+}
+
+func (l *FileAuditLog) Record(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	if record.ConfigDigest != "" {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+func (l *FileAuditLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
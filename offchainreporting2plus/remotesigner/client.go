@@ -0,0 +1,275 @@
+package remotesigner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/chains/evmutil"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"golang.org/x/crypto/curve25519"
+)
+
+// rpcConn is a connection to a signer that is established on the first call
+// and re-established on the next call after it breaks.
+type rpcConn struct {
+	network string
+	address string
+	timeout time.Duration
+	logger  commontypes.Logger
+
+	mutex     sync.Mutex
+	rpcClient *rpc.Client
+	closed    bool
+}
+
+func newRPCConn(network string, address string, timeout time.Duration, logger commontypes.Logger) *rpcConn {
+	return &rpcConn{
+		network,
+		address,
+		timeout,
+		logger,
+
+		sync.Mutex{},
+		nil,
+		false,
+	}
+}
+
+func (rc *rpcConn) connection() (*rpc.Client, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.closed {
+		return nil, fmt.Errorf("connection is closed")
+	}
+	if rc.rpcClient != nil {
+		return rc.rpcClient, nil
+	}
+	conn, err := net.DialTimeout(rc.network, rc.address, rc.timeout)
+	if err != nil {
+		return nil, err
+	}
+	rc.rpcClient = jsonrpc.NewClient(conn)
+	return rc.rpcClient, nil
+}
+
+// reset closes rpcClient so that the next call reconnects, unless another
+// call has already done so.
+func (rc *rpcConn) reset(rpcClient *rpc.Client) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.rpcClient == rpcClient {
+		rc.logger.Warn("Remote signer connection broken, reconnecting on next call", commontypes.LogFields{
+			"address": rc.address,
+		})
+		rc.rpcClient = nil
+		_ = rpcClient.Close()
+	}
+}
+
+func (rc *rpcConn) call(method string, args interface{}, reply interface{}) error {
+	rpcClient, err := rc.connection()
+	if err != nil {
+		return err
+	}
+	call := rpcClient.Go(ServiceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		var serverError rpc.ServerError
+		if call.Error != nil && !errors.As(call.Error, &serverError) {
+			// The connection is broken
+			rc.reset(rpcClient)
+		}
+		return call.Error
+	case <-time.After(rc.timeout):
+		// Responses to later calls would be stuck behind this one
+		rc.reset(rpcClient)
+		return fmt.Errorf("%s call to %s timed out after %v", method, rc.address, rc.timeout)
+	}
+}
+
+func (rc *rpcConn) Close() error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.closed = true
+	if rc.rpcClient != nil {
+		err := rc.rpcClient.Close()
+		rc.rpcClient = nil
+		return err
+	}
+	return nil
+}
+
+// OnchainVerifier verifies onchain signatures, e.g.
+// evmutil.EVMOnchainVerifier.
+type OnchainVerifier interface {
+	Verify(publicKey types.OnchainPublicKey, repctx types.ReportContext, report types.Report, signature []byte) bool
+}
+
+// Client is a types.OffchainKeyring and types.OnchainKeyring whose keys are
+// held by a remote signer. If the connection to the signer breaks, Client
+// reconnects on the next call.
+//
+// The keyring interfaces can't return errors from the public key getters,
+// so the public keys are fetched once by Dial. If the signer's keys change,
+// a new Client must be dialed.
+//
+// Every Client gets its own OCR3 session at the signer (see
+// signguard.OCR3Session), so use one Client per oracle instance.
+type Client struct {
+	conn     *rpcConn
+	verifier OnchainVerifier
+
+	offchainPublicKey         types.OffchainPublicKey
+	configEncryptionPublicKey types.ConfigEncryptionPublicKey
+	onchainPublicKey          types.OnchainPublicKey
+	maxSignatureLength        int
+}
+
+var _ types.OffchainKeyring = (*Client)(nil)
+var _ types.OnchainKeyring = (*Client)(nil)
+
+// Dial connects to the signer at address, e.g. Dial("unix",
+// "/run/ocrsigner.sock", ...). Every call to the signer fails if it hasn't
+// completed within timeout. Onchain signatures are verified locally with
+// verifier, which must match the signer's signature scheme.
+func Dial(network string, address string, timeout time.Duration, verifier OnchainVerifier, logger commontypes.Logger) (*Client, error) {
+	c := &Client{
+		conn:     newRPCConn(network, address, timeout, logger),
+		verifier: verifier,
+	}
+
+	var reply PublicKeysReply
+	if err := c.conn.call("PublicKeys", PublicKeysArgs{}, &reply); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("could not fetch public keys from signer: %w", err)
+	}
+	if len(reply.OffchainPublicKey) != len(c.offchainPublicKey) {
+		c.Close()
+		return nil, fmt.Errorf("signer returned OffchainPublicKey of wrong length %v", len(reply.OffchainPublicKey))
+	}
+	if len(reply.ConfigEncryptionPublicKey) != len(c.configEncryptionPublicKey) {
+		c.Close()
+		return nil, fmt.Errorf("signer returned ConfigEncryptionPublicKey of wrong length %v", len(reply.ConfigEncryptionPublicKey))
+	}
+	if len(reply.OnchainPublicKey) == 0 || reply.MaxSignatureLength <= 0 {
+		c.Close()
+		return nil, fmt.Errorf("signer has no onchain key")
+	}
+	copy(c.offchainPublicKey[:], reply.OffchainPublicKey)
+	copy(c.configEncryptionPublicKey[:], reply.ConfigEncryptionPublicKey)
+	c.onchainPublicKey = reply.OnchainPublicKey
+	c.maxSignatureLength = reply.MaxSignatureLength
+	return c, nil
+}
+
+// Close closes the connection to the signer. All subsequent calls fail.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) OffchainSign(msg []byte) (signature []byte, err error) {
+	var reply SignReply
+	if err := c.conn.call("OffchainSign", OffchainSignArgs{msg}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Signature, nil
+}
+
+func (c *Client) ConfigDiffieHellman(point [curve25519.PointSize]byte) (sharedPoint [curve25519.PointSize]byte, err error) {
+	var reply ConfigDiffieHellmanReply
+	if err := c.conn.call("ConfigDiffieHellman", ConfigDiffieHellmanArgs{point[:]}, &reply); err != nil {
+		return sharedPoint, err
+	}
+	if len(reply.SharedPoint) != len(sharedPoint) {
+		return sharedPoint, fmt.Errorf("signer returned shared point of wrong length %v", len(reply.SharedPoint))
+	}
+	copy(sharedPoint[:], reply.SharedPoint)
+	return sharedPoint, nil
+}
+
+func (c *Client) OffchainPublicKey() types.OffchainPublicKey {
+	return c.offchainPublicKey
+}
+
+func (c *Client) ConfigEncryptionPublicKey() types.ConfigEncryptionPublicKey {
+	return c.configEncryptionPublicKey
+}
+
+func (c *Client) PublicKey() types.OnchainPublicKey {
+	return c.onchainPublicKey
+}
+
+func (c *Client) checkSignatureLength(signature []byte) error {
+	if len(signature) > c.maxSignatureLength {
+		return fmt.Errorf("signer returned signature of length %v, exceeding MaxSignatureLength %v", len(signature), c.maxSignatureLength)
+	}
+	return nil
+}
+
+func (c *Client) Sign(repctx types.ReportContext, report types.Report) (signature []byte, err error) {
+	var reply SignReply
+	if err := c.conn.call("OnchainSign", OnchainSignArgs{encodeReportContext(repctx), report}, &reply); err != nil {
+		return nil, err
+	}
+	if err := c.checkSignatureLength(reply.Signature); err != nil {
+		return nil, err
+	}
+	return reply.Signature, nil
+}
+
+func (c *Client) Verify(publicKey types.OnchainPublicKey, repctx types.ReportContext, report types.Report, signature []byte) bool {
+	return c.verifier.Verify(publicKey, repctx, report, signature)
+}
+
+func (c *Client) MaxSignatureLength() int {
+	return c.maxSignatureLength
+}
+
+type ocr3OnchainKeyring[RI any] struct {
+	client *Client
+	// only used for verification, Client.Sign is never called through it
+	verifier *evmutil.OCR3OnchainKeyring[RI]
+}
+
+// NewOCR3OnchainKeyring returns an ocr3types.OnchainKeyring whose signatures
+// are made by the signer behind client. Since the report info isn't sent to
+// the signer, the signer maps reports onto report contexts on its own (for
+// ocrsignerd, with evmutil.SeqNrReportContext). reportContext must be the
+// same mapping; it is used to verify signatures, including those returned by
+// the signer.
+func NewOCR3OnchainKeyring[RI any](client *Client, reportContext evmutil.ReportContextEncoding[RI]) ocr3types.OnchainKeyring[RI] {
+	return &ocr3OnchainKeyring[RI]{client, evmutil.NewOCR3OnchainKeyring[RI](client, reportContext)}
+}
+
+func (ok *ocr3OnchainKeyring[RI]) PublicKey() types.OnchainPublicKey {
+	return ok.client.PublicKey()
+}
+
+func (ok *ocr3OnchainKeyring[RI]) Sign(configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI]) (signature []byte, err error) {
+	var reply SignReply
+	if err := ok.client.conn.call("OCR3OnchainSign", OCR3OnchainSignArgs{configDigest[:], seqNr, reportWithInfo.Report}, &reply); err != nil {
+		return nil, err
+	}
+	if err := ok.client.checkSignatureLength(reply.Signature); err != nil {
+		return nil, err
+	}
+	if !ok.verifier.Verify(ok.client.PublicKey(), configDigest, seqNr, reportWithInfo, reply.Signature) {
+		return nil, fmt.Errorf("signature returned by signer doesn't verify, does the signer use the same report context encoding?")
+	}
+	return reply.Signature, nil
+}
+
+func (ok *ocr3OnchainKeyring[RI]) Verify(publicKey types.OnchainPublicKey, configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI], signature []byte) bool {
+	return ok.verifier.Verify(publicKey, configDigest, seqNr, reportWithInfo, signature)
+}
+
+func (ok *ocr3OnchainKeyring[RI]) MaxSignatureLength() int {
+	return ok.client.MaxSignatureLength()
+}
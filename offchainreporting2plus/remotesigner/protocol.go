@@ -0,0 +1,110 @@
+// Package remotesigner lets oracles use keys that are held by a separate
+// signer process, e.g. one backed by an HSM, instead of keys in the oracle's
+// own memory.
+//
+// The oracle and the signer talk JSON-RPC 1.0 (as implemented by
+// net/rpc/jsonrpc) over a stream connection, typically a Unix socket that only
+// the oracle's user can access. The service is called "Signer", see the
+// XArgs and XReply types for its methods. Binary values are encoded as base64
+// strings. The protocol is simple enough to be implemented in other languages.
+//
+// Client implements types.OffchainKeyring and types.OnchainKeyring on top of
+// the protocol, and NewOCR3OnchainKeyring implements ocr3types.OnchainKeyring.
+// Signatures are verified locally, since that only needs public keys.
+//
+// Server implements the protocol on top of local keyrings. It refuses to sign
+// conflicting onchain reports, as recorded in a signguard.Guard, and records
+// every operation in an AuditLog. For threshold signing, the Server can
+// additionally require the approval of a number of approvers, Servers
+// created with NewApprover that hold no keys but have their own Guard and
+// AuditLog. cmd/ocrsignerd is a reference signer daemon built on Server.
+package remotesigner
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// ServiceName is the name of the JSON-RPC service.
+const ServiceName = "Signer"
+
+type PublicKeysArgs struct{}
+
+type PublicKeysReply struct {
+	OffchainPublicKey         []byte
+	ConfigEncryptionPublicKey []byte
+	OnchainPublicKey          []byte
+	MaxSignatureLength        int
+}
+
+type OffchainSignArgs struct {
+	Msg []byte
+}
+
+type ConfigDiffieHellmanArgs struct {
+	Point []byte
+}
+
+type ConfigDiffieHellmanReply struct {
+	SharedPoint []byte
+}
+
+// ReportContext is the wire encoding of types.ReportContext.
+type ReportContext struct {
+	ConfigDigest []byte
+	Epoch        uint32
+	Round        uint8
+	ExtraHash    []byte
+}
+
+type OnchainSignArgs struct {
+	ReportContext ReportContext
+	Report        []byte
+}
+
+// OCR3OnchainSignArgs doesn't include the report info, the signer maps the
+// report onto a report context on its own.
+type OCR3OnchainSignArgs struct {
+	ConfigDigest []byte
+	SeqNr        uint64
+	Report       []byte
+}
+
+type SignReply struct {
+	Signature []byte
+}
+
+// ApproveReply is the reply of ApproveOnchainSign and ApproveOCR3OnchainSign,
+// which take the same arguments as OnchainSign and OCR3OnchainSign. They are
+// served by approvers only. A nil error means approval.
+type ApproveReply struct{}
+
+func encodeReportContext(repctx types.ReportContext) ReportContext {
+	return ReportContext{
+		repctx.ConfigDigest[:],
+		repctx.Epoch,
+		repctx.Round,
+		repctx.ExtraHash[:],
+	}
+}
+
+func decodeReportContext(repctx ReportContext) (types.ReportContext, error) {
+	configDigest, err := types.BytesToConfigDigest(repctx.ConfigDigest)
+	if err != nil {
+		return types.ReportContext{}, err
+	}
+	var extraHash [32]byte
+	if len(repctx.ExtraHash) != len(extraHash) {
+		return types.ReportContext{}, fmt.Errorf("ExtraHash has wrong length %v", len(repctx.ExtraHash))
+	}
+	copy(extraHash[:], repctx.ExtraHash)
+	return types.ReportContext{
+		types.ReportTimestamp{
+			configDigest,
+			repctx.Epoch,
+			repctx.Round,
+		},
+		extraHash,
+	}, nil
+}
@@ -0,0 +1,442 @@
+package remotesigner_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/chains/evmutil"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/remotesigner"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/signguard"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+type nopLogger struct{}
+
+var _ commontypes.Logger = nopLogger{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+const timeout = 5 * time.Second
+
+type offchainKeyring struct {
+	signingKey    ed25519.PrivateKey
+	encryptionKey [curve25519.ScalarSize]byte
+}
+
+func (kr offchainKeyring) OffchainSign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(kr.signingKey, msg), nil
+}
+
+func (kr offchainKeyring) ConfigDiffieHellman(point [curve25519.PointSize]byte) (sharedPoint [curve25519.PointSize]byte, err error) {
+	p, err := curve25519.X25519(kr.encryptionKey[:], point[:])
+	if err != nil {
+		return sharedPoint, err
+	}
+	copy(sharedPoint[:], p)
+	return sharedPoint, nil
+}
+
+func (kr offchainKeyring) OffchainPublicKey() (pk types.OffchainPublicKey) {
+	copy(pk[:], kr.signingKey.Public().(ed25519.PublicKey))
+	return pk
+}
+
+func (kr offchainKeyring) ConfigEncryptionPublicKey() (pk types.ConfigEncryptionPublicKey) {
+	p, err := curve25519.X25519(kr.encryptionKey[:], curve25519.Basepoint)
+	if err != nil {
+		panic(err)
+	}
+	copy(pk[:], p)
+	return pk
+}
+
+func newOffchainKeyring(t *testing.T) offchainKeyring {
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return offchainKeyring{signingKey, [curve25519.ScalarSize]byte{1, 2, 3}}
+}
+
+func newOnchainKeyring(t *testing.T) *evmutil.EVMOnchainKeyring {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return evmutil.NewEVMOnchainKeyring(key)
+}
+
+// signer is a running Server together with the files it uses.
+type signer struct {
+	server   *remotesigner.Server
+	socket   string
+	auditLog string
+}
+
+func (s *signer) close() {
+	_ = s.server.Close()
+}
+
+// serve runs server on a fresh Unix socket in dir.
+func serve(t *testing.T, dir string, server *remotesigner.Server) string {
+	socket := filepath.Join(dir, "signer.sock")
+	_ = os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	return socket
+}
+
+func openGuard(t *testing.T, path string) *signguard.Guard {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		guard, err := signguard.Create(path, nopLogger{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := guard.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	guard, err := signguard.Open(path, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = guard.Close() })
+	return guard
+}
+
+func openAuditLog(t *testing.T, path string) *remotesigner.FileAuditLog {
+	auditLog, err := remotesigner.OpenFileAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = auditLog.Close() })
+	return auditLog
+}
+
+// startSigner starts a key-holding signer in dir. The watermark file and
+// audit log are reused if dir already has them.
+func startSigner(t *testing.T, dir string, offchain offchainKeyring, onchain *evmutil.EVMOnchainKeyring, approvals remotesigner.Approvals) *signer {
+	s := &signer{auditLog: filepath.Join(dir, "audit.log")}
+	server, err := remotesigner.NewServer(
+		offchain,
+		onchain,
+		evmutil.NewOCR3OnchainKeyring[struct{}](onchain, evmutil.SeqNrReportContext[struct{}]),
+		openGuard(t, filepath.Join(dir, "watermarks")),
+		approvals,
+		openAuditLog(t, s.auditLog),
+		nopLogger{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.server = server
+	s.socket = serve(t, dir, server)
+	t.Cleanup(s.close)
+	return s
+}
+
+func startApprover(t *testing.T, dir string) *signer {
+	s := &signer{auditLog: filepath.Join(dir, "audit.log")}
+	server, err := remotesigner.NewApprover(openGuard(t, filepath.Join(dir, "watermarks")), openAuditLog(t, s.auditLog), nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.server = server
+	s.socket = serve(t, dir, server)
+	t.Cleanup(s.close)
+	return s
+}
+
+func dial(t *testing.T, socket string) *remotesigner.Client {
+	client, err := remotesigner.Dial("unix", socket, timeout, evmutil.EVMOnchainVerifier{}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func repctx(epoch uint32, round uint8) types.ReportContext {
+	return types.ReportContext{types.ReportTimestamp{types.ConfigDigest{1}, epoch, round}, [32]byte{}}
+}
+
+func readAuditLog(t *testing.T, path string) []remotesigner.AuditRecord {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []remotesigner.AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record remotesigner.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestClient(t *testing.T) {
+	offchain := newOffchainKeyring(t)
+	onchain := newOnchainKeyring(t)
+	s := startSigner(t, t.TempDir(), offchain, onchain, remotesigner.Approvals{})
+	client := dial(t, s.socket)
+
+	if client.OffchainPublicKey() != offchain.OffchainPublicKey() {
+		t.Error("wrong OffchainPublicKey")
+	}
+	if client.ConfigEncryptionPublicKey() != offchain.ConfigEncryptionPublicKey() {
+		t.Error("wrong ConfigEncryptionPublicKey")
+	}
+	if !bytes.Equal(client.PublicKey(), onchain.PublicKey()) {
+		t.Error("wrong onchain PublicKey")
+	}
+	if client.MaxSignatureLength() != onchain.MaxSignatureLength() {
+		t.Error("wrong MaxSignatureLength")
+	}
+
+	msg := []byte("message")
+	signature, err := client.OffchainSign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offchainPublicKey := client.OffchainPublicKey()
+	if !ed25519.Verify(offchainPublicKey[:], msg, signature) {
+		t.Error("offchain signature doesn't verify")
+	}
+
+	point := offchainKeyring{nil, [curve25519.ScalarSize]byte{4, 5, 6}}.ConfigEncryptionPublicKey()
+	sharedPoint, err := client.ConfigDiffieHellman(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := offchain.ConfigDiffieHellman(point); sharedPoint != expected {
+		t.Error("wrong shared point")
+	}
+
+	signature, err = client.Sign(repctx(1, 1), types.Report("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.Verify(client.PublicKey(), repctx(1, 1), types.Report("a"), signature) {
+		t.Error("onchain signature doesn't verify")
+	}
+	if client.Verify(client.PublicKey(), repctx(1, 1), types.Report("b"), signature) {
+		t.Error("onchain signature verifies for another report")
+	}
+
+	if _, err := client.Sign(repctx(1, 1), types.Report("b")); err == nil {
+		t.Error("signer signed a conflicting report")
+	}
+	if _, err := client.Sign(repctx(1, 1), types.Report("a")); err != nil {
+		t.Errorf("signer refused to sign the same report again: %v", err)
+	}
+
+	// verification is local
+	s.close()
+	if !client.Verify(client.PublicKey(), repctx(1, 1), types.Report("a"), signature) {
+		t.Error("onchain signature doesn't verify without signer")
+	}
+
+	var operations []string
+	for _, record := range readAuditLog(t, s.auditLog) {
+		operations = append(operations, record.Operation)
+		if record.Operation == "OnchainSign" && record.Allowed != (record.Error == "") {
+			t.Errorf("inconsistent audit record %+v", record)
+		}
+	}
+	expected := []string{"OffchainSign", "ConfigDiffieHellman", "OnchainSign", "OnchainSign", "OnchainSign"}
+	if len(operations) != len(expected) {
+		t.Fatalf("audit log has operations %v, expected %v", operations, expected)
+	}
+	for i := range expected {
+		if operations[i] != expected[i] {
+			t.Fatalf("audit log has operations %v, expected %v", operations, expected)
+		}
+	}
+}
+
+func TestOCR3(t *testing.T) {
+	s := startSigner(t, t.TempDir(), newOffchainKeyring(t), newOnchainKeyring(t), remotesigner.Approvals{})
+	first := remotesigner.NewOCR3OnchainKeyring[struct{}](dial(t, s.socket), evmutil.SeqNrReportContext[struct{}])
+	second := remotesigner.NewOCR3OnchainKeyring[struct{}](dial(t, s.socket), evmutil.SeqNrReportContext[struct{}])
+
+	sign := func(kr ocr3types.OnchainKeyring[struct{}], seqNr uint64, report string) ([]byte, error) {
+		return kr.Sign(types.ConfigDigest{1}, seqNr, ocr3types.ReportWithInfo[struct{}]{types.Report(report), struct{}{}})
+	}
+
+	// all reports of a seqNr are signed in one session
+	for _, report := range []string{"a", "b"} {
+		signature, err := sign(first, 1, report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !second.Verify(first.PublicKey(), types.ConfigDigest{1}, 1, ocr3types.ReportWithInfo[struct{}]{types.Report(report), struct{}{}}, signature) {
+			t.Errorf("signature of report %v doesn't verify", report)
+		}
+	}
+	// but another oracle instance can't add to them
+	if _, err := sign(second, 1, "c"); err == nil {
+		t.Error("signer signed a report of another session's seqNr")
+	}
+	if _, err := sign(second, 2, "c"); err != nil {
+		t.Error(err)
+	}
+
+	// a client whose report context encoding differs from the signer's
+	// notices
+	mismatched := remotesigner.NewOCR3OnchainKeyring[struct{}](dial(t, s.socket), func(configDigest types.ConfigDigest, seqNr uint64, _ ocr3types.ReportWithInfo[struct{}]) (types.ReportContext, error) {
+		return types.ReportContext{types.ReportTimestamp{configDigest, uint32(seqNr), 0}, [32]byte{}}, nil
+	})
+	if _, err := sign(mismatched, 3, "d"); err == nil {
+		t.Error("signature with mismatched report context was accepted")
+	}
+}
+
+func TestRestart(t *testing.T) {
+	dir := t.TempDir()
+	offchain, onchain := newOffchainKeyring(t), newOnchainKeyring(t)
+	s := startSigner(t, dir, offchain, onchain, remotesigner.Approvals{})
+	if _, err := dial(t, s.socket).Sign(repctx(1, 1), types.Report("a")); err != nil {
+		t.Fatal(err)
+	}
+	s.close()
+
+	// a new signer started from the same watermark file remembers
+	s = startSigner(t, dir, offchain, onchain, remotesigner.Approvals{})
+	client := dial(t, s.socket)
+	if _, err := client.Sign(repctx(1, 1), types.Report("b")); err == nil {
+		t.Error("signer signed a report conflicting with one signed before the restart")
+	}
+	if _, err := client.Sign(repctx(1, 2), types.Report("b")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	dir := t.TempDir()
+	offchain, onchain := newOffchainKeyring(t), newOnchainKeyring(t)
+	s := startSigner(t, dir, offchain, onchain, remotesigner.Approvals{})
+	client := dial(t, s.socket)
+	s.close()
+
+	if _, err := client.OffchainSign([]byte("message")); err == nil {
+		t.Fatal("call to closed signer succeeded")
+	}
+	startSigner(t, dir, offchain, onchain, remotesigner.Approvals{})
+	if _, err := client.OffchainSign([]byte("message")); err != nil {
+		t.Fatalf("client didn't reconnect: %v", err)
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	approvers := []*signer{startApprover(t, t.TempDir()), startApprover(t, t.TempDir()), startApprover(t, t.TempDir())}
+	var addresses []remotesigner.ApproverAddress
+	for _, approver := range approvers {
+		addresses = append(addresses, remotesigner.ApproverAddress{"unix", approver.socket})
+	}
+	s := startSigner(t, t.TempDir(), newOffchainKeyring(t), newOnchainKeyring(t), remotesigner.Approvals{addresses, 2, timeout})
+	client := dial(t, s.socket)
+	ocr3Keyring := remotesigner.NewOCR3OnchainKeyring[struct{}](client, evmutil.SeqNrReportContext[struct{}])
+
+	if _, err := client.Sign(repctx(1, 1), types.Report("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ocr3Keyring.Sign(types.ConfigDigest{1}, 1, ocr3types.ReportWithInfo[struct{}]{types.Report("a"), struct{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, approver := range approvers {
+		records := readAuditLog(t, approver.auditLog)
+		if len(records) != 2 || records[0].Operation != "ApproveOnchainSign" || records[1].Operation != "ApproveOCR3OnchainSign" {
+			t.Errorf("approver has audit records %+v", records)
+		}
+	}
+
+	// conflicting reports are refused, also when requested by another
+	// client
+	if _, err := client.Sign(repctx(2, 1), types.Report("a")); err != nil {
+		t.Fatal(err)
+	}
+	refuser, err := remotesigner.Dial("unix", s.socket, timeout, evmutil.EVMOnchainVerifier{}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer refuser.Close()
+	if _, err := refuser.Sign(repctx(2, 1), types.Report("b")); err == nil {
+		t.Error("signer signed a conflicting report")
+	}
+
+	// one approver down, the other two suffice
+	approvers[0].close()
+	if _, err := client.Sign(repctx(3, 1), types.Report("a")); err != nil {
+		t.Errorf("signing with two of three approvers failed: %v", err)
+	}
+	// two approvers down
+	approvers[1].close()
+	if _, err := client.Sign(repctx(4, 1), types.Report("a")); err == nil {
+		t.Error("signer signed with only one approval")
+	}
+
+	// approvers hold no keys
+	if _, err := remotesigner.Dial("unix", approvers[2].socket, timeout, evmutil.EVMOnchainVerifier{}, nopLogger{}); err == nil {
+		t.Error("dialing an approver as signer succeeded")
+	}
+}
+
+func TestApproversRefuseConflicts(t *testing.T) {
+	approverDirs := []string{t.TempDir(), t.TempDir()}
+	var addresses []remotesigner.ApproverAddress
+	for _, dir := range approverDirs {
+		addresses = append(addresses, remotesigner.ApproverAddress{"unix", startApprover(t, dir).socket})
+	}
+	offchain, onchain := newOffchainKeyring(t), newOnchainKeyring(t)
+	approvals := remotesigner.Approvals{addresses, 2, timeout}
+
+	s := startSigner(t, t.TempDir(), offchain, onchain, approvals)
+	if _, err := dial(t, s.socket).Sign(repctx(1, 1), types.Report("a")); err != nil {
+		t.Fatal(err)
+	}
+	s.close()
+
+	// The key holder's watermark file is lost, but the approvers remember.
+	s = startSigner(t, t.TempDir(), offchain, onchain, approvals)
+	if _, err := dial(t, s.socket).Sign(repctx(1, 1), types.Report("b")); err == nil {
+		t.Error("signer signed a conflicting report the approvers had approved before")
+	}
+}
+
+func TestNewServerValidatesApprovals(t *testing.T) {
+	guard := openGuard(t, filepath.Join(t.TempDir(), "watermarks"))
+	auditLog := openAuditLog(t, filepath.Join(t.TempDir(), "audit.log"))
+	for _, approvals := range []remotesigner.Approvals{
+		{nil, 1, timeout},
+		{[]remotesigner.ApproverAddress{{"unix", "a"}}, -1, timeout},
+		{[]remotesigner.ApproverAddress{{"unix", "a"}}, 1, 0},
+	} {
+		if _, err := remotesigner.NewServer(newOffchainKeyring(t), newOnchainKeyring(t), nil, guard, approvals, auditLog, nopLogger{}); err == nil {
+			t.Errorf("NewServer accepted %+v", approvals)
+		}
+	}
+}
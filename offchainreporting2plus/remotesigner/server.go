@@ -0,0 +1,468 @@
+package remotesigner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/signguard"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/smartcontractkit/libocr/subprocesses"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Server serves the remote signer protocol using local keyrings. Every
+// OffchainSign, ConfigDiffieHellman and onchain signing is recorded in the
+// AuditLog. Onchain signings are refused if they conflict with an earlier
+// one recorded in the Guard, or if they aren't approved by enough approvers.
+// Signatures are only returned once their AuditRecord has been recorded.
+//
+// A Server created with NewApprover holds no keys and only serves approvals.
+type Server struct {
+	offchainKeyring    types.OffchainKeyring
+	onchainKeyring     types.OnchainKeyring
+	ocr3OnchainKeyring ocr3types.OnchainKeyring[struct{}]
+	guard              *signguard.Guard
+	approvals          Approvals
+	auditLog           AuditLog
+	logger             commontypes.Logger
+
+	subprocesses subprocesses.Subprocesses
+
+	mutex     sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+}
+
+// Approvals configures threshold signing: onchain reports are only signed
+// once at least Threshold of the Approvers have approved them. Approvers are
+// Servers created with NewApprover, each with its own Guard and AuditLog and
+// ideally run on separate hosts by separate operators. Conflicting reports
+// are then refused even if the key holder's watermark file is lost or the
+// key holder is misconfigured, as long as fewer than Threshold approvers are
+// affected too.
+//
+// The Server asks all approvers, in parallel, before every onchain signing,
+// after checking its own Guard. A report that is then refused for lack of
+// approvals remains recorded as signed in the Guard, which errs on the side
+// of safety.
+// Connections to approvers are not authenticated, so use Unix sockets or
+// tunnels that only the signers can access.
+type Approvals struct {
+	Approvers []ApproverAddress
+	Threshold int
+	// Limit for dialing an approver and for each call to it
+	Timeout time.Duration
+}
+
+type ApproverAddress struct {
+	Network string
+	Address string
+}
+
+// NewServer returns a Server that signs with the given keyrings. OCR3
+// reports are signed with ocr3OnchainKeyring, which may be nil if OCR3
+// isn't needed; for EVM contracts, wrap onchainKeyring with
+// evmutil.NewOCR3OnchainKeyring. Use the zero Approvals for a Server that
+// doesn't require approvals.
+func NewServer(
+	offchainKeyring types.OffchainKeyring,
+	onchainKeyring types.OnchainKeyring,
+	ocr3OnchainKeyring ocr3types.OnchainKeyring[struct{}],
+	guard *signguard.Guard,
+	approvals Approvals,
+	auditLog AuditLog,
+	logger commontypes.Logger,
+) (*Server, error) {
+	if offchainKeyring == nil || onchainKeyring == nil {
+		return nil, fmt.Errorf("a signer needs an offchain and an onchain keyring")
+	}
+	if approvals.Threshold < 0 || approvals.Threshold > len(approvals.Approvers) {
+		return nil, fmt.Errorf("approval threshold %v must be between 0 and the number of approvers %v", approvals.Threshold, len(approvals.Approvers))
+	}
+	if approvals.Threshold > 0 && approvals.Timeout <= 0 {
+		return nil, fmt.Errorf("approval timeout must be positive")
+	}
+	return newServer(offchainKeyring, onchainKeyring, ocr3OnchainKeyring, guard, approvals, auditLog, logger)
+}
+
+// NewApprover returns a Server that holds no keys and only serves
+// ApproveOnchainSign and ApproveOCR3OnchainSign, for use in another
+// Server's Approvals.
+func NewApprover(
+	guard *signguard.Guard,
+	auditLog AuditLog,
+	logger commontypes.Logger,
+) (*Server, error) {
+	return newServer(nil, nil, nil, guard, Approvals{}, auditLog, logger)
+}
+
+func newServer(
+	offchainKeyring types.OffchainKeyring,
+	onchainKeyring types.OnchainKeyring,
+	ocr3OnchainKeyring ocr3types.OnchainKeyring[struct{}],
+	guard *signguard.Guard,
+	approvals Approvals,
+	auditLog AuditLog,
+	logger commontypes.Logger,
+) (*Server, error) {
+	if guard == nil {
+		return nil, fmt.Errorf("a signer needs a Guard")
+	}
+	s := &Server{
+		offchainKeyring,
+		onchainKeyring,
+		ocr3OnchainKeyring,
+		guard,
+		approvals,
+		auditLog,
+		logger,
+
+		subprocesses.Subprocesses{},
+
+		sync.Mutex{},
+		false,
+		map[net.Listener]struct{}{},
+		map[net.Conn]struct{}{},
+	}
+	// Fail early on the errors newRPCServer would return for every
+	// connection
+	if _, err := s.newRPCServer(nil, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newRPCServer returns an rpc.Server for one connection, since OCR3 sessions
+// and connections to the approvers are per connection.
+func (s *Server) newRPCServer(session *signguard.OCR3Session, approvers []*rpcConn) (*rpc.Server, error) {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(ServiceName, &signerService{s, session, approvers}); err != nil {
+		return nil, err
+	}
+	return rpcServer, nil
+}
+
+// Serve accepts connections on listener until it fails or the Server is
+// closed. Serve always closes listener.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		listener.Close()
+		return fmt.Errorf("server is closed")
+	}
+	s.listeners[listener] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, listener)
+		s.mutex.Unlock()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.logger.Info("Remote signer accepted connection", commontypes.LogFields{"remoteAddr": conn.RemoteAddr().String()})
+		s.subprocesses.Go(func() {
+			session := s.guard.NewOCR3Session()
+			approvers := make([]*rpcConn, 0, len(s.approvals.Approvers))
+			for _, approver := range s.approvals.Approvers {
+				approvers = append(approvers, newRPCConn(approver.Network, approver.Address, s.approvals.Timeout, s.logger))
+			}
+			rpcServer, err := s.newRPCServer(session, approvers)
+			if err != nil {
+				// assertion, newServer already checked this
+				panic(err)
+			}
+			// ServeCodec waits for pending calls before returning
+			rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+			for _, approver := range approvers {
+				_ = approver.Close()
+			}
+			session.Close()
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+			s.logger.Info("Remote signer connection closed", commontypes.LogFields{"remoteAddr": conn.RemoteAddr().String()})
+		})
+	}
+}
+
+// Close stops all Serve calls, closes all connections and waits for pending
+// calls to finish.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return fmt.Errorf("server already closed")
+	}
+	s.closed = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.subprocesses.Wait()
+	return nil
+}
+
+func hashHex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// record records the outcome of an operation. It returns opErr, or an error
+// if the record couldn't be written, in which case the operation's result
+// must be withheld.
+func (s *Server) record(record AuditRecord, opErr error) error {
+	record.Time = time.Now()
+	record.Allowed = opErr == nil
+	if opErr != nil {
+		record.Error = opErr.Error()
+	}
+	if err := s.auditLog.Record(record); err != nil {
+		s.logger.Error("Remote signer failed to write audit record, refusing operation", commontypes.LogFields{
+			"operation": record.Operation,
+			"error":     err,
+		})
+		return errors.Join(opErr, fmt.Errorf("failed to write audit record: %w", err))
+	}
+	if opErr != nil {
+		s.logger.Warn("Remote signer refused operation", commontypes.LogFields{
+			"operation": record.Operation,
+			"error":     opErr,
+		})
+	}
+	return opErr
+}
+
+// signerService holds the RPC methods of one connection. It is separate from
+// Server because net/rpc complains about exported methods that aren't RPC
+// methods.
+type signerService struct {
+	s         *Server
+	session   *signguard.OCR3Session
+	approvers []*rpcConn
+}
+
+func (ss *signerService) checkSigner() error {
+	if ss.s.onchainKeyring == nil {
+		return fmt.Errorf("this signer is an approver and holds no keys")
+	}
+	return nil
+}
+
+func (ss *signerService) checkApprover() error {
+	if ss.s.onchainKeyring != nil {
+		return fmt.Errorf("this signer holds keys and doesn't serve approvals")
+	}
+	return nil
+}
+
+// approve asks all approvers to approve and returns nil if at least the
+// threshold did.
+func (ss *signerService) approve(method string, args interface{}) error {
+	if ss.s.approvals.Threshold == 0 {
+		return nil
+	}
+	errs := make([]error, len(ss.approvers))
+	var wg sync.WaitGroup
+	for i, approver := range ss.approvers {
+		i, approver := i, approver
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := approver.call(method, args, &ApproveReply{}); err != nil {
+				errs[i] = fmt.Errorf("approver %s: %w", approver.address, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	approved := 0
+	for _, err := range errs {
+		if err == nil {
+			approved++
+		}
+	}
+	if approved < ss.s.approvals.Threshold {
+		return fmt.Errorf("only %v approvers approved, %v required: %w", approved, ss.s.approvals.Threshold, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (ss *signerService) PublicKeys(args PublicKeysArgs, reply *PublicKeysReply) error {
+	if err := ss.checkSigner(); err != nil {
+		return err
+	}
+	offchainPublicKey := ss.s.offchainKeyring.OffchainPublicKey()
+	configEncryptionPublicKey := ss.s.offchainKeyring.ConfigEncryptionPublicKey()
+	*reply = PublicKeysReply{
+		offchainPublicKey[:],
+		configEncryptionPublicKey[:],
+		ss.s.onchainKeyring.PublicKey(),
+		ss.s.onchainKeyring.MaxSignatureLength(),
+	}
+	return nil
+}
+
+func (ss *signerService) OffchainSign(args OffchainSignArgs, reply *SignReply) error {
+	if err := ss.checkSigner(); err != nil {
+		return err
+	}
+	signature, err := ss.s.offchainKeyring.OffchainSign(args.Msg)
+	if err := ss.s.record(AuditRecord{Operation: "OffchainSign", DataHash: hashHex(args.Msg)}, err); err != nil {
+		return err
+	}
+	reply.Signature = signature
+	return nil
+}
+
+func (ss *signerService) ConfigDiffieHellman(args ConfigDiffieHellmanArgs, reply *ConfigDiffieHellmanReply) error {
+	if err := ss.checkSigner(); err != nil {
+		return err
+	}
+	var point [curve25519.PointSize]byte
+	var sharedPoint [curve25519.PointSize]byte
+	var err error
+	if len(args.Point) != len(point) {
+		err = fmt.Errorf("point has wrong length %v", len(args.Point))
+	} else {
+		copy(point[:], args.Point)
+		sharedPoint, err = ss.s.offchainKeyring.ConfigDiffieHellman(point)
+	}
+	if err := ss.s.record(AuditRecord{Operation: "ConfigDiffieHellman", DataHash: hashHex(args.Point)}, err); err != nil {
+		return err
+	}
+	reply.SharedPoint = sharedPoint[:]
+	return nil
+}
+
+func onchainSignRecord(operation string, args OnchainSignArgs) AuditRecord {
+	return AuditRecord{
+		Operation:    operation,
+		ConfigDigest: hex.EncodeToString(args.ReportContext.ConfigDigest),
+		Epoch:        args.ReportContext.Epoch,
+		Round:        args.ReportContext.Round,
+		DataHash:     hashHex(args.Report),
+	}
+}
+
+func ocr3OnchainSignRecord(operation string, args OCR3OnchainSignArgs) AuditRecord {
+	return AuditRecord{
+		Operation:    operation,
+		ConfigDigest: hex.EncodeToString(args.ConfigDigest),
+		SeqNr:        args.SeqNr,
+		DataHash:     hashHex(args.Report),
+	}
+}
+
+// checkOnchainSign checks the guard for an OCR2 report, for signing as well
+// as for approving.
+func (ss *signerService) checkOnchainSign(args OnchainSignArgs) (types.ReportContext, error) {
+	repctx, err := decodeReportContext(args.ReportContext)
+	if err != nil {
+		return types.ReportContext{}, err
+	}
+	return repctx, ss.s.guard.CheckOnchainSign(repctx, args.Report)
+}
+
+func (ss *signerService) checkOCR3OnchainSign(args OCR3OnchainSignArgs) (types.ConfigDigest, error) {
+	configDigest, err := types.BytesToConfigDigest(args.ConfigDigest)
+	if err != nil {
+		return types.ConfigDigest{}, err
+	}
+	return configDigest, ss.session.CheckOCR3OnchainSign(configDigest, args.SeqNr, args.Report)
+}
+
+func (ss *signerService) OnchainSign(args OnchainSignArgs, reply *SignReply) error {
+	err := ss.checkSigner()
+	var repctx types.ReportContext
+	if err == nil {
+		repctx, err = ss.checkOnchainSign(args)
+	}
+	if err == nil {
+		err = ss.approve("ApproveOnchainSign", args)
+	}
+	var signature []byte
+	if err == nil {
+		signature, err = ss.s.onchainKeyring.Sign(repctx, args.Report)
+	}
+	if err := ss.s.record(onchainSignRecord("OnchainSign", args), err); err != nil {
+		return err
+	}
+	reply.Signature = signature
+	return nil
+}
+
+func (ss *signerService) OCR3OnchainSign(args OCR3OnchainSignArgs, reply *SignReply) error {
+	err := ss.checkSigner()
+	if err == nil && ss.s.ocr3OnchainKeyring == nil {
+		err = fmt.Errorf("this signer doesn't sign OCR3 reports")
+	}
+	var configDigest types.ConfigDigest
+	if err == nil {
+		configDigest, err = ss.checkOCR3OnchainSign(args)
+	}
+	if err == nil {
+		err = ss.approve("ApproveOCR3OnchainSign", args)
+	}
+	var signature []byte
+	if err == nil {
+		signature, err = ss.s.ocr3OnchainKeyring.Sign(configDigest, args.SeqNr, ocr3types.ReportWithInfo[struct{}]{args.Report, struct{}{}})
+	}
+	if err := ss.s.record(ocr3OnchainSignRecord("OCR3OnchainSign", args), err); err != nil {
+		return err
+	}
+	reply.Signature = signature
+	return nil
+}
+
+func (ss *signerService) ApproveOnchainSign(args OnchainSignArgs, reply *ApproveReply) error {
+	err := ss.checkApprover()
+	if err == nil {
+		_, err = ss.checkOnchainSign(args)
+	}
+	return ss.s.record(onchainSignRecord("ApproveOnchainSign", args), err)
+}
+
+func (ss *signerService) ApproveOCR3OnchainSign(args OCR3OnchainSignArgs, reply *ApproveReply) error {
+	err := ss.checkApprover()
+	if err == nil {
+		_, err = ss.checkOCR3OnchainSign(args)
+	}
+	return ss.s.record(ocr3OnchainSignRecord("ApproveOCR3OnchainSign", args), err)
+}
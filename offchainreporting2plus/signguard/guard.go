@@ -0,0 +1,384 @@
+// Package signguard protects onchain signing keys against double-signing,
+// i.e. against signing two different reports for the same round. This can
+// happen when an oracle's key is misconfigured to be used by two deployments
+// at once, or when an oracle's database is restored from a backup and the
+// oracle repeats rounds it has already taken part in.
+//
+// A Guard persists a watermark of everything signed through it to its own
+// watermark file: for each ConfigDigest, the hashes of the reports signed at
+// recent positions (epoch and round for OCR2, seqNr for OCR3) and a floor at
+// or below which it refuses to sign. Wrap the oracle's onchain keyring with
+// NewOnchainKeyring or NewOCR3OnchainKeyring to refuse every report that
+// conflicts with one signed earlier, including before a restart.
+//
+// Keep the watermark file separate from the oracle's database, e.g. next to
+// the key itself, and never restore it from a backup: a restored watermark
+// file has forgotten the latest signings, just like a restored database.
+// Since a missing watermark file would silently disable the protection, Open
+// refuses to create one; use Create once when setting up a new key.
+//
+// A Guard only protects against the deployments that share it. To protect a
+// key used from several hosts, hold the key in a single remote signer (see
+// remotesigner and cmd/ocrsignerd) and use the Guard there.
+package signguard
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+const (
+	// Number of positions per ConfigDigest whose report hashes are kept.
+	// Once exceeded, the lowest position becomes the floor. OCR3 may sign
+	// reports for older seqNrs when catching up on rounds it missed, so this
+	// must comfortably exceed the number of rounds OCR3 catches up on.
+	maxPositionsPerConfigDigest = 1024
+	// Number of ConfigDigests whose report hashes are kept, in the order in
+	// which they were first signed for. Once exceeded, the oldest
+	// ConfigDigest's highest signed position becomes its floor.
+	maxDetailedConfigDigests = 8
+)
+
+// The watermark file is compacted once it has more than compactionMinRecords
+// records and more than compactionRatio times the records needed to describe
+// the watermarks.
+const (
+	compactionMinRecords = 16384
+	compactionRatio      = 4
+)
+
+type reportHash [sha256.Size]byte
+
+type configDigestState struct {
+	// Hashes of the reports signed at each position. OCR2 signs at most one
+	// report per position, OCR3 possibly several.
+	signed map[uint64][]reportHash
+	// Positions at or below floor are refused.
+	floor    uint64
+	hasFloor bool
+	// Whether the ConfigDigest is in watermarks.order
+	detailed bool
+}
+
+type watermarks struct {
+	states map[types.ConfigDigest]*configDigestState
+	// Detailed ConfigDigests, in the order in which they were first signed for
+	order []types.ConfigDigest
+	// Number of records in snapshot()
+	live int
+}
+
+func newWatermarks() *watermarks {
+	return &watermarks{map[types.ConfigDigest]*configDigestState{}, nil, 0}
+}
+
+func (w *watermarks) state(configDigest types.ConfigDigest) *configDigestState {
+	state, ok := w.states[configDigest]
+	if !ok {
+		state = &configDigestState{map[uint64][]reportHash{}, 0, false, false}
+		w.states[configDigest] = state
+	}
+	return state
+}
+
+func (w *watermarks) raiseFloor(configDigest types.ConfigDigest, floor uint64) {
+	state := w.state(configDigest)
+	if !state.hasFloor {
+		state.floor = floor
+		state.hasFloor = true
+		w.live++
+	} else if state.floor < floor {
+		state.floor = floor
+	}
+}
+
+// add records that the report with hash was signed at position, forgetting
+// old positions and ConfigDigests as needed.
+func (w *watermarks) add(configDigest types.ConfigDigest, position uint64, hash reportHash) {
+	state := w.state(configDigest)
+	if !state.detailed {
+		state.detailed = true
+		w.order = append(w.order, configDigest)
+		if len(w.order) > maxDetailedConfigDigests {
+			w.collapse(w.order[0])
+			w.order = w.order[1:]
+		}
+	}
+
+	state.signed[position] = append(state.signed[position], hash)
+	w.live++
+
+	if len(state.signed) > maxPositionsPerConfigDigest {
+		lowest := position
+		for p := range state.signed {
+			if p < lowest {
+				lowest = p
+			}
+		}
+		w.live -= len(state.signed[lowest])
+		delete(state.signed, lowest)
+		w.raiseFloor(configDigest, lowest)
+	}
+}
+
+// collapse forgets the report hashes for configDigest, raising its floor to
+// the highest position signed at.
+func (w *watermarks) collapse(configDigest types.ConfigDigest) {
+	state := w.states[configDigest]
+	for position, hashes := range state.signed {
+		w.raiseFloor(configDigest, position)
+		w.live -= len(hashes)
+	}
+	state.signed = map[uint64][]reportHash{}
+	state.detailed = false
+}
+
+// Guard refuses to sign reports that conflict with reports signed earlier.
+// All its methods are thread-safe.
+type Guard struct {
+	path   string
+	logger commontypes.Logger
+
+	mutex sync.Mutex
+	file  *os.File // nil once closed
+	// size of the watermark file in bytes
+	size int64
+	// number of records in the watermark file
+	records int
+	// set if the watermark file may be in an inconsistent state, after which
+	// we refuse to sign
+	err        error
+	watermarks *watermarks
+	// The position at which each session has signed the first report, per
+	// ConfigDigest. Only that session may add further reports at that
+	// position.
+	batches     map[batchKey]uint64
+	lastSession uint64
+}
+
+type batchKey struct {
+	configDigest types.ConfigDigest
+	session      uint64
+}
+
+// noSession is passed to claim for reports that must be the only report at
+// their position.
+const noSession = 0
+
+// Create creates a new watermark file at path and returns a Guard using it.
+// It fails if the file already exists.
+func Create(path string, logger commontypes.Logger) (*Guard, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write([]byte(fileMagic)); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return newGuard(path, logger, file, int64(len(fileMagic)), 0, newWatermarks()), nil
+}
+
+// Open returns a Guard using the existing watermark file at path.
+func Open(path string, logger commontypes.Logger) (*Guard, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	w, offset, records, err := replay(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if offset < info.Size() {
+		// discard torn record
+		if err := file.Truncate(offset); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	return newGuard(path, logger, file, offset, records, w), nil
+}
+
+func newGuard(path string, logger commontypes.Logger, file *os.File, size int64, records int, w *watermarks) *Guard {
+	return &Guard{
+		path,
+		logger,
+		sync.Mutex{},
+		file,
+		size,
+		records,
+		nil,
+		w,
+		map[batchKey]uint64{},
+		noSession,
+	}
+}
+
+// Close closes the watermark file. Further calls to any method will fail, and
+// keyrings wrapped with the Guard refuse to sign.
+func (g *Guard) Close() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.file == nil {
+		return fmt.Errorf("guard already closed")
+	}
+	err := g.file.Close()
+	g.file = nil
+	return err
+}
+
+func (g *Guard) checkWritable() error {
+	if g.file == nil {
+		return fmt.Errorf("guard closed")
+	}
+	if g.err != nil {
+		return fmt.Errorf("guard unusable after earlier error: %w", g.err)
+	}
+	return nil
+}
+
+func (g *Guard) refuse(configDigest types.ConfigDigest, err error) error {
+	g.logger.Critical("signguard: refusing to sign conflicting report, is the same key used by multiple oracles or has the database been restored from a backup?", commontypes.LogFields{
+		"configDigest": configDigest,
+		"error":        err,
+	})
+	return err
+}
+
+// claim returns nil if the report with hash may be signed at position, after
+// durably recording that it has been. Unless session is noSession, further
+// reports may be signed at position by the session that signed the first one
+// there, as OCR3 does for the reports of one seqNr.
+func (g *Guard) claim(configDigest types.ConfigDigest, position uint64, formatPosition func(uint64) string, hash reportHash, session uint64) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.checkWritable(); err != nil {
+		return err
+	}
+
+	first := true
+	if state, ok := g.watermarks.states[configDigest]; ok {
+		if state.hasFloor && position <= state.floor {
+			return g.refuse(configDigest, fmt.Errorf("refusing to sign for ConfigDigest %v at %v, which is at or below the watermark at %v",
+				configDigest, formatPosition(position), formatPosition(state.floor)))
+		}
+		if hashes, ok := state.signed[position]; ok {
+			for _, h := range hashes {
+				if h == hash {
+					// signing the same report again is fine
+					return nil
+				}
+			}
+			batch, ok := g.batches[batchKey{configDigest, session}]
+			if !(session != noSession && ok && batch == position) {
+				return g.refuse(configDigest, fmt.Errorf("refusing to sign a different report for ConfigDigest %v at %v than the one already signed",
+					configDigest, formatPosition(position)))
+			}
+			first = false
+		}
+	}
+
+	line := encodeRecord(signingRecord(configDigest, position, hash))
+	_, err := g.file.WriteAt(line, g.size)
+	if err == nil {
+		err = g.file.Sync()
+	}
+	if err != nil {
+		// Remove whatever part of the record made it to the file, so that a
+		// later record doesn't follow a damaged one.
+		if truncErr := g.file.Truncate(g.size); truncErr != nil {
+			g.err = truncErr
+		} else if syncErr := g.file.Sync(); syncErr != nil {
+			g.err = syncErr
+		}
+		return fmt.Errorf("error writing to watermark file: %w", err)
+	}
+	g.size += int64(len(line))
+	g.records++
+
+	g.watermarks.add(configDigest, position, hash)
+	if first && session != noSession {
+		g.batches[batchKey{configDigest, session}] = position
+	}
+
+	if g.records > compactionMinRecords && g.records > compactionRatio*g.watermarks.live {
+		// The signing has been recorded, so a failed compaction is not an
+		// error. We'll retry on the next signing.
+		_ = g.compactLocked()
+	}
+	return nil
+}
+
+func (g *Guard) compactLocked() error {
+	records := g.watermarks.snapshot()
+	if replaced, err := writeSnapshot(g.path, records); err != nil {
+		if replaced {
+			// The new file may or may not survive a crash, and g.file no
+			// longer is at g.path. Neither file is safe to append to.
+			g.err = err
+		}
+		return fmt.Errorf("error compacting watermark file: %w", err)
+	}
+
+	// g.file now refers to the replaced file, writes to it would be lost
+	file, err := os.OpenFile(g.path, os.O_RDWR, 0)
+	if err != nil {
+		g.err = err
+		return fmt.Errorf("error reopening watermark file after compaction: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		g.err = err
+		return fmt.Errorf("error reopening watermark file after compaction: %w", err)
+	}
+	_ = g.file.Close()
+	g.file = file
+	g.size = info.Size()
+	g.records = len(records)
+	return nil
+}
+
+func (g *Guard) newSession() uint64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.lastSession++
+	return g.lastSession
+}
+
+// endSession forgets the batches of session.
+func (g *Guard) endSession(session uint64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for key := range g.batches {
+		if key.session == session {
+			delete(g.batches, key)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package signguard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+var (
+	digestA = types.ConfigDigest{0x00, 0x01, 0xaa}
+	digestB = types.ConfigDigest{0x00, 0x01, 0xbb}
+)
+
+func create(t *testing.T) (*Guard, string) {
+	path := filepath.Join(t.TempDir(), "watermarks")
+	g, err := Create(path, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = g.Close() })
+	return g, path
+}
+
+func reopen(t *testing.T, g *Guard, path string) *Guard {
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Open(path, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = g.Close() })
+	return g
+}
+
+func repctx(configDigest types.ConfigDigest, epoch uint32, round uint8) types.ReportContext {
+	return types.ReportContext{types.ReportTimestamp{configDigest, epoch, round}, [32]byte{}}
+}
+
+func mustSucceed(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+}
+
+func mustRefuse(t *testing.T, err error) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected refusal")
+	}
+}
+
+// fakeOCR3Keyring returns the report as signature.
+type fakeOCR3Keyring struct{}
+
+func (fakeOCR3Keyring) PublicKey() types.OnchainPublicKey { return nil }
+
+func (fakeOCR3Keyring) Sign(_ types.ConfigDigest, _ uint64, rwi ocr3types.ReportWithInfo[struct{}]) ([]byte, error) {
+	return rwi.Report, nil
+}
+
+func (fakeOCR3Keyring) Verify(types.OnchainPublicKey, types.ConfigDigest, uint64, ocr3types.ReportWithInfo[struct{}], []byte) bool {
+	return true
+}
+
+func (fakeOCR3Keyring) MaxSignatureLength() int { return 0 }
+
+func sign(kr ocr3types.OnchainKeyring[struct{}], configDigest types.ConfigDigest, seqNr uint64, report string) error {
+	_, err := kr.Sign(configDigest, seqNr, ocr3types.ReportWithInfo[struct{}]{types.Report(report), struct{}{}})
+	return err
+}
+
+func TestOCR2ConflictingReport(t *testing.T) {
+	g, _ := create(t)
+
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	// the same report again
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	// a different report at the same epoch and round
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("b")))
+	// the same report with a different ExtraHash
+	differentExtraHash := repctx(digestA, 1, 1)
+	differentExtraHash.ExtraHash[0] = 1
+	mustRefuse(t, g.CheckOnchainSign(differentExtraHash, types.Report("a")))
+
+	// other positions and ConfigDigests are unaffected
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 2), types.Report("b")))
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 2, 1), types.Report("b")))
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestB, 1, 1), types.Report("b")))
+}
+
+func TestOCR3ConflictingReport(t *testing.T) {
+	g, _ := create(t)
+	first := NewOCR3OnchainKeyring[struct{}](g, fakeOCR3Keyring{})
+	second := NewOCR3OnchainKeyring[struct{}](g, fakeOCR3Keyring{})
+
+	// the first keyring may sign several reports at a seqNr
+	mustSucceed(t, sign(first, digestA, 5, "a"))
+	mustSucceed(t, sign(first, digestA, 5, "b"))
+
+	// another keyring sharing the guard may only repeat them
+	mustSucceed(t, sign(second, digestA, 5, "a"))
+	mustRefuse(t, sign(second, digestA, 5, "c"))
+
+	// once the first keyring has moved on, it can't add reports either
+	mustSucceed(t, sign(first, digestA, 6, "a"))
+	mustRefuse(t, sign(first, digestA, 5, "c"))
+	mustSucceed(t, sign(first, digestA, 5, "b"))
+
+	// the second keyring has its own batches
+	mustSucceed(t, sign(second, digestA, 7, "a"))
+	mustSucceed(t, sign(second, digestA, 7, "b"))
+	mustRefuse(t, sign(first, digestA, 7, "c"))
+
+	// OCR2 signings never start a batch
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestB, 0, 8), types.Report("a")))
+	mustRefuse(t, sign(first, digestB, 8, "b"))
+}
+
+func TestOCR3SessionClose(t *testing.T) {
+	g, _ := create(t)
+	session := g.NewOCR3Session()
+
+	mustSucceed(t, session.CheckOCR3OnchainSign(digestA, 1, types.Report("a")))
+	session.Close()
+	mustRefuse(t, session.CheckOCR3OnchainSign(digestA, 1, types.Report("b")))
+	mustSucceed(t, session.CheckOCR3OnchainSign(digestA, 1, types.Report("a")))
+}
+
+func TestRestart(t *testing.T) {
+	g, path := create(t)
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	mustSucceed(t, sign(NewOCR3OnchainKeyring[struct{}](g, fakeOCR3Keyring{}), digestB, 3, "a"))
+
+	g = reopen(t, g, path)
+
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("b")))
+	// a session after the restart can't add reports to the batch of one
+	// before it
+	kr := NewOCR3OnchainKeyring[struct{}](g, fakeOCR3Keyring{})
+	mustSucceed(t, sign(kr, digestB, 3, "a"))
+	mustRefuse(t, sign(kr, digestB, 3, "b"))
+}
+
+func TestOpenRequiresWatermarkFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing"), nopLogger{}); err == nil {
+		t.Fatal("expected Open to fail for a missing watermark file")
+	}
+
+	path := filepath.Join(t.TempDir(), "other")
+	if err := os.WriteFile(path, []byte("something else\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, nopLogger{}); err == nil {
+		t.Fatal("expected Open to fail for a file that isn't a watermark file")
+	}
+
+	_, path = create(t)
+	if _, err := Create(path, nopLogger{}); err == nil {
+		t.Fatal("expected Create to fail for an existing file")
+	}
+}
+
+func appendToFile(t *testing.T, path string, data string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTornLastRecord(t *testing.T) {
+	g, path := create(t)
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash during an append leaves part of a record behind
+	torn := string(encodeRecord(signingRecord(digestA, epochRoundPosition(1, 2), ocr2ReportHash(repctx(digestA, 1, 2), types.Report("a")))))
+	appendToFile(t, path, torn[:len(torn)/2])
+
+	g, err = Open(path, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = g.Close() })
+	if infoAfter, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if infoAfter.Size() != info.Size() {
+		t.Fatalf("torn record wasn't truncated: size %v, expected %v", infoAfter.Size(), info.Size())
+	}
+
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("b")))
+	// the torn signing never happened
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 2), types.Report("b")))
+
+	// records appended after the truncation are read back
+	g = reopen(t, g, path)
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 2), types.Report("a")))
+}
+
+func TestDamagedRecord(t *testing.T) {
+	g, path := create(t)
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a damaged record followed by others can't result from a crash
+	appendToFile(t, path, "{not json}\n")
+	appendToFile(t, path, string(encodeRecord(signingRecord(digestA, 2, reportHash{}))))
+
+	if _, err := Open(path, nopLogger{}); err == nil {
+		t.Fatal("expected Open to fail")
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	g, path := create(t)
+	ocr3 := NewOCR3OnchainKeyring[struct{}](g, fakeOCR3Keyring{})
+	for i := 0; i < maxPositionsPerConfigDigest+10; i++ {
+		mustSucceed(t, g.CheckOnchainSign(repctx(digestA, uint32(i), 0), types.Report(fmt.Sprint(i))))
+	}
+	for i := 0; i < maxDetailedConfigDigests+2; i++ {
+		configDigest := types.ConfigDigest{0x00, 0x01, byte(i)}
+		mustSucceed(t, sign(ocr3, configDigest, uint64(i), "a"))
+		mustSucceed(t, sign(ocr3, configDigest, uint64(i), "b"))
+	}
+
+	g.mutex.Lock()
+	recordsBefore := g.records
+	err := g.compactLocked()
+	recordsAfter := g.records
+	before := g.watermarks
+	g.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(recordsAfter == before.live && recordsAfter < recordsBefore) {
+		t.Fatalf("compaction left %v records, expected %v live records (had %v before)", recordsAfter, before.live, recordsBefore)
+	}
+
+	// signing keeps working after compaction, and is recorded in the
+	// compacted file
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestB, 1, 1), types.Report("a")))
+	g.mutex.Lock()
+	before = g.watermarks
+	g.mutex.Unlock()
+
+	g = reopen(t, g, path)
+	if !reflect.DeepEqual(g.watermarks, before) {
+		t.Fatal("watermarks differ after reopening the compacted file")
+	}
+	if matches, err := filepath.Glob(path + ".compact-*"); err != nil || len(matches) != 0 {
+		t.Fatalf("compaction left temporary files behind: %v, %v", matches, err)
+	}
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestB, 1, 1), types.Report("b")))
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, uint32(maxPositionsPerConfigDigest+9), 0), types.Report("b")))
+}
+
+func TestFloor(t *testing.T) {
+	g, path := create(t)
+	for i := 1; i <= maxPositionsPerConfigDigest+1; i++ {
+		mustSucceed(t, g.CheckOnchainSign(repctx(digestA, uint32(i), 0), types.Report("a")))
+	}
+
+	// the lowest position has been forgotten and is refused, even for the
+	// same report
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 0), types.Report("a")))
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 0, 5), types.Report("b")))
+	// the others are remembered
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 2, 0), types.Report("a")))
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 2, 0), types.Report("b")))
+	// positions between remembered ones are fine
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 2, 1), types.Report("b")))
+
+	g = reopen(t, g, path)
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 0), types.Report("a")))
+	mustSucceed(t, g.CheckOnchainSign(repctx(digestA, 3, 0), types.Report("a")))
+}
+
+func TestConfigDigestEviction(t *testing.T) {
+	g, path := create(t)
+	configDigest := func(i int) types.ConfigDigest { return types.ConfigDigest{0x00, 0x01, byte(i)} }
+
+	mustSucceed(t, g.CheckOnchainSign(repctx(configDigest(0), 1, 0), types.Report("a")))
+	mustSucceed(t, g.CheckOnchainSign(repctx(configDigest(0), 3, 0), types.Report("a")))
+	for i := 1; i <= maxDetailedConfigDigests; i++ {
+		mustSucceed(t, g.CheckOnchainSign(repctx(configDigest(i), 1, 0), types.Report("a")))
+	}
+
+	check := func(g *Guard) {
+		t.Helper()
+		// the oldest ConfigDigest has been collapsed into a floor at its
+		// highest signed position
+		mustRefuse(t, g.CheckOnchainSign(repctx(configDigest(0), 2, 0), types.Report("b")))
+		mustRefuse(t, g.CheckOnchainSign(repctx(configDigest(0), 3, 0), types.Report("a")))
+		// the others are still detailed
+		mustSucceed(t, g.CheckOnchainSign(repctx(configDigest(1), 1, 0), types.Report("a")))
+		mustRefuse(t, g.CheckOnchainSign(repctx(configDigest(1), 1, 0), types.Report("b")))
+	}
+	check(g)
+	g = reopen(t, g, path)
+	check(g)
+
+	// signing above the floor of the collapsed ConfigDigest is fine
+	mustSucceed(t, g.CheckOnchainSign(repctx(configDigest(0), 4, 0), types.Report("b")))
+}
+
+func TestClosedGuardRefuses(t *testing.T) {
+	g, _ := create(t)
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mustRefuse(t, g.CheckOnchainSign(repctx(digestA, 1, 1), types.Report("a")))
+	if err := g.Close(); err == nil {
+		t.Fatal("expected second Close to fail")
+	}
+}
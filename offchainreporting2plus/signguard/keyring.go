@@ -0,0 +1,148 @@
+package signguard
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// OCR2 positions are (epoch, round), ordered lexicographically.
+func epochRoundPosition(epoch uint32, round uint8) uint64 {
+	return uint64(epoch)<<8 | uint64(round)
+}
+
+func formatEpochRound(position uint64) string {
+	return fmt.Sprintf("epoch %v round %v", uint32(position>>8), uint8(position))
+}
+
+func formatSeqNr(position uint64) string {
+	return fmt.Sprintf("seqNr %v", position)
+}
+
+// The ExtraHash is signed along with the report, so it is part of what must
+// not conflict.
+func ocr2ReportHash(repctx types.ReportContext, report types.Report) reportHash {
+	h := sha256.New()
+	_, _ = h.Write(repctx.ExtraHash[:])
+	_, _ = h.Write(report)
+	var result reportHash
+	copy(result[:], h.Sum(nil))
+	return result
+}
+
+// CheckOnchainSign returns nil if the OCR2 report may be signed, after
+// durably recording that it has been, and an error explaining the refusal
+// otherwise.
+func (g *Guard) CheckOnchainSign(repctx types.ReportContext, report types.Report) error {
+	return g.claim(
+		repctx.ConfigDigest,
+		epochRoundPosition(repctx.Epoch, repctx.Round),
+		formatEpochRound,
+		ocr2ReportHash(repctx, report),
+		noSession,
+	)
+}
+
+// OCR3Session checks OCR3 reports on behalf of one oracle instance. OCR3 signs
+// all reports of a seqNr in one go, so after the first report at a seqNr, the
+// session allows further reports there, but only if it signed the first one
+// itself. Other sessions, e.g. those of a second oracle instance sharing the
+// same key through a remote signer, are refused. If an oracle restarts in the
+// middle of signing the reports of a seqNr, its new session may thus be unable
+// to sign the remaining ones; the other oracles will complete that seqNr
+// without it.
+type OCR3Session struct {
+	guard *Guard
+	id    uint64
+}
+
+// NewOCR3Session starts a new session. Use one per oracle instance, e.g. one
+// per client connection of a remote signer.
+func (g *Guard) NewOCR3Session() *OCR3Session {
+	return &OCR3Session{g, g.newSession()}
+}
+
+// CheckOCR3OnchainSign returns nil if the OCR3 report may be signed, after
+// durably recording that it has been, and an error explaining the refusal
+// otherwise. Only the report itself is hashed, not its info.
+func (s *OCR3Session) CheckOCR3OnchainSign(configDigest types.ConfigDigest, seqNr uint64, report types.Report) error {
+	return s.guard.claim(
+		configDigest,
+		seqNr,
+		formatSeqNr,
+		sha256.Sum256(report),
+		s.id,
+	)
+}
+
+// Close ends the session. Its signings remain recorded, but it can no longer
+// add reports at a seqNr.
+func (s *OCR3Session) Close() {
+	s.guard.endSession(s.id)
+}
+
+type onchainKeyring struct {
+	guard   *Guard
+	keyring types.OnchainKeyring
+}
+
+// NewOnchainKeyring returns a types.OnchainKeyring that signs with keyring,
+// but refuses to sign a report for a (ConfigDigest, epoch, round) at which
+// guard has already seen a different report signed.
+func NewOnchainKeyring(guard *Guard, keyring types.OnchainKeyring) types.OnchainKeyring {
+	return &onchainKeyring{guard, keyring}
+}
+
+func (gk *onchainKeyring) PublicKey() types.OnchainPublicKey {
+	return gk.keyring.PublicKey()
+}
+
+func (gk *onchainKeyring) Sign(repctx types.ReportContext, report types.Report) (signature []byte, err error) {
+	if err := gk.guard.CheckOnchainSign(repctx, report); err != nil {
+		return nil, err
+	}
+	return gk.keyring.Sign(repctx, report)
+}
+
+func (gk *onchainKeyring) Verify(publicKey types.OnchainPublicKey, repctx types.ReportContext, report types.Report, signature []byte) bool {
+	return gk.keyring.Verify(publicKey, repctx, report, signature)
+}
+
+func (gk *onchainKeyring) MaxSignatureLength() int {
+	return gk.keyring.MaxSignatureLength()
+}
+
+type ocr3OnchainKeyring[RI any] struct {
+	session *OCR3Session
+	keyring ocr3types.OnchainKeyring[RI]
+}
+
+// NewOCR3OnchainKeyring returns an ocr3types.OnchainKeyring that signs with
+// keyring, but refuses to sign a report for a (ConfigDigest, seqNr) at which
+// guard has already seen a different report signed, unless the keyring
+// itself signed the first report there. Each keyring has its own OCR3Session,
+// so use one keyring per oracle instance.
+func NewOCR3OnchainKeyring[RI any](guard *Guard, keyring ocr3types.OnchainKeyring[RI]) ocr3types.OnchainKeyring[RI] {
+	return &ocr3OnchainKeyring[RI]{guard.NewOCR3Session(), keyring}
+}
+
+func (gk *ocr3OnchainKeyring[RI]) PublicKey() types.OnchainPublicKey {
+	return gk.keyring.PublicKey()
+}
+
+func (gk *ocr3OnchainKeyring[RI]) Sign(configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI]) (signature []byte, err error) {
+	if err := gk.session.CheckOCR3OnchainSign(configDigest, seqNr, reportWithInfo.Report); err != nil {
+		return nil, err
+	}
+	return gk.keyring.Sign(configDigest, seqNr, reportWithInfo)
+}
+
+func (gk *ocr3OnchainKeyring[RI]) Verify(publicKey types.OnchainPublicKey, configDigest types.ConfigDigest, seqNr uint64, reportWithInfo ocr3types.ReportWithInfo[RI], signature []byte) bool {
+	return gk.keyring.Verify(publicKey, configDigest, seqNr, reportWithInfo, signature)
+}
+
+func (gk *ocr3OnchainKeyring[RI]) MaxSignatureLength() int {
+	return gk.keyring.MaxSignatureLength()
+}
@@ -0,0 +1,197 @@
+package signguard
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// The watermark file starts with fileMagic, followed by one JSON record per
+// line. A record either says that a report with ReportHash was signed at
+// Position, or, if Floor is set, that positions at or below Position are
+// refused. Floor records are only written when compacting; otherwise floors
+// are recomputed while replaying signings.
+//
+// Records are only ever appended, and every append is fsynced before the
+// signature it protects is produced.
+
+const fileMagic = "OCRSIGNGUARD1\n"
+
+type record struct {
+	ConfigDigest string `json:"configDigest"`
+	Position     uint64 `json:"position"`
+	ReportHash   string `json:"reportHash,omitempty"`
+	Floor        bool   `json:"floor,omitempty"`
+}
+
+func encodeRecord(r record) []byte {
+	line, err := json.Marshal(r)
+	if err != nil {
+		// record only contains strings, ints and bools
+		panic(fmt.Sprintf("unexpected error while marshaling record: %v", err))
+	}
+	return append(line, '\n')
+}
+
+func signingRecord(configDigest types.ConfigDigest, position uint64, hash reportHash) record {
+	return record{hex.EncodeToString(configDigest[:]), position, hex.EncodeToString(hash[:]), false}
+}
+
+func floorRecord(configDigest types.ConfigDigest, floor uint64) record {
+	return record{hex.EncodeToString(configDigest[:]), floor, "", true}
+}
+
+func decodeRecord(line []byte) (types.ConfigDigest, uint64, reportHash, bool, error) {
+	var r record
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&r); err != nil {
+		return types.ConfigDigest{}, 0, reportHash{}, false, err
+	}
+	configDigestBytes, err := hex.DecodeString(r.ConfigDigest)
+	if err != nil {
+		return types.ConfigDigest{}, 0, reportHash{}, false, fmt.Errorf("invalid configDigest: %w", err)
+	}
+	configDigest, err := types.BytesToConfigDigest(configDigestBytes)
+	if err != nil {
+		return types.ConfigDigest{}, 0, reportHash{}, false, err
+	}
+	if r.Floor {
+		if r.ReportHash != "" {
+			return types.ConfigDigest{}, 0, reportHash{}, false, fmt.Errorf("floor record has reportHash")
+		}
+		return configDigest, r.Position, reportHash{}, true, nil
+	}
+	var hash reportHash
+	hashBytes, err := hex.DecodeString(r.ReportHash)
+	if err != nil {
+		return types.ConfigDigest{}, 0, reportHash{}, false, fmt.Errorf("invalid reportHash: %w", err)
+	}
+	if len(hashBytes) != len(hash) {
+		return types.ConfigDigest{}, 0, reportHash{}, false, fmt.Errorf("reportHash has wrong length %v", len(hashBytes))
+	}
+	copy(hash[:], hashBytes)
+	return configDigest, r.Position, hash, false, nil
+}
+
+// replay reads the watermark file f into a new watermarks. It returns the
+// offset just past the last complete record and the number of records.
+//
+// As in filedb, a torn record at the end of the file, as left behind by a
+// crash during an append, is ignored: the append never completed, so the
+// report it belongs to was never signed. A damaged record followed by further
+// records cannot result from a crash, and replay errors rather than dropping
+// the signings after it.
+func replay(f *os.File) (*watermarks, int64, int, error) {
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(content) < len(fileMagic) || string(content[:len(fileMagic)]) != fileMagic {
+		return nil, 0, 0, fmt.Errorf("%v is not a watermark file", f.Name())
+	}
+
+	w := newWatermarks()
+	offset := len(fileMagic)
+	records := 0
+	for offset < len(content) {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			break // torn record
+		}
+		configDigest, position, hash, isFloor, err := decodeRecord(content[offset : offset+end])
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("record at offset %v of %v is damaged: %w", offset, f.Name(), err)
+		}
+		if isFloor {
+			w.raiseFloor(configDigest, position)
+		} else {
+			w.add(configDigest, position, hash)
+		}
+		offset += end + 1
+		records++
+	}
+	return w, int64(offset), records, nil
+}
+
+// snapshot returns the records needed to reconstruct w. Replaying them
+// yields the same floors, signings and order of ConfigDigests.
+func (w *watermarks) snapshot() []record {
+	var records []record
+	for configDigest, state := range w.states {
+		if state.hasFloor {
+			records = append(records, floorRecord(configDigest, state.floor))
+		}
+	}
+	for _, configDigest := range w.order {
+		state := w.states[configDigest]
+		positions := make([]uint64, 0, len(state.signed))
+		for position := range state.signed {
+			positions = append(positions, position)
+		}
+		sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+		for _, position := range positions {
+			for _, hash := range state.signed[position] {
+				records = append(records, signingRecord(configDigest, position, hash))
+			}
+		}
+	}
+	return records
+}
+
+// writeSnapshot atomically replaces the file at path with one containing
+// records. replaced reports whether the file at path has been replaced, even
+// if an error occurred afterwards.
+func writeSnapshot(path string, records []record) (replaced bool, err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".compact-*")
+	if err != nil {
+		return false, err
+	}
+	tmpPath := tmp.Name()
+	cleanup := func(err error) error {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(fileMagic)
+	for _, r := range records {
+		buf.Write(encodeRecord(r))
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		return false, cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return false, cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, cleanup(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return false, err
+	}
+	return true, syncDir(filepath.Dir(path))
+}
+
+// syncDir makes a preceding creation or rename of a file in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
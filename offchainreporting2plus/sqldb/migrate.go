@@ -0,0 +1,148 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dialect selects the flavour of SQL used for the schema. Queries issued by DB
+// are the same for all dialects.
+type Dialect struct {
+	name string
+}
+
+var (
+	Postgres = Dialect{"postgres"}
+	// SQLite requires SQLite 3.24 or later for upserts.
+	SQLite = Dialect{"sqlite"}
+)
+
+func (d Dialect) String() string { return d.name }
+
+//go:embed migrations
+var migrations embed.FS
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// MigrationsFS returns the schema migrations for dialect as a flat directory of
+// SQL files. File names start with the version number of the migration,
+// followed by an underscore. Use this if you manage your schema with your own
+// migration tooling rather than Migrate.
+func MigrationsFS(dialect Dialect) (fs.FS, error) {
+	dir := path.Join("migrations", dialect.name)
+	if _, err := fs.Stat(migrations, dir); err != nil {
+		return nil, fmt.Errorf("unknown dialect %q: %w", dialect.name, err)
+	}
+	return fs.Sub(migrations, dir)
+}
+
+func loadMigrations(dialect Dialect) ([]migration, error) {
+	fsys, err := MigrationsFS(dialect)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q lacks version prefix", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q has invalid version prefix: %w", name, err)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var statements []string
+		for _, statement := range strings.Split(string(content), ";") {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				statements = append(statements, statement)
+			}
+		}
+		result = append(result, migration{version, name, statements})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
+
+// Migrate brings the schema in db up to date, applying each outstanding
+// migration in its own transaction. Applied migrations are recorded in the
+// ocr_schema_migrations table.
+//
+// Migrate must not be called concurrently on the same database, e.g. from
+// several processes starting up at once.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	ms, err := loadMigrations(dialect)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS ocr_schema_migrations (version BIGINT NOT NULL PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("error creating ocr_schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	{
+		rows, err := db.QueryContext(ctx, `SELECT version FROM ocr_schema_migrations`)
+		if err != nil {
+			return fmt.Errorf("error reading ocr_schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int
+			if err := rows.Scan(&version); err != nil {
+				return fmt.Errorf("error reading ocr_schema_migrations: %w", err)
+			}
+			applied[version] = true
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error reading ocr_schema_migrations: %w", err)
+		}
+	}
+
+	for _, m := range ms {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("error applying migration %q: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, statement := range m.statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO ocr_schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
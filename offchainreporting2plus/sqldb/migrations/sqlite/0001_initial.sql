@@ -0,0 +1,49 @@
+CREATE TABLE ocr_contract_configs (
+    instance TEXT NOT NULL PRIMARY KEY,
+    config_digest BLOB NOT NULL,
+    config_count BIGINT NOT NULL,
+    signers TEXT NOT NULL,
+    transmitters TEXT NOT NULL,
+    f SMALLINT NOT NULL,
+    onchain_config BLOB NOT NULL,
+    offchain_config_version BIGINT NOT NULL,
+    offchain_config BLOB NOT NULL
+);
+
+CREATE TABLE ocr2_persistent_states (
+    instance TEXT NOT NULL,
+    config_digest BLOB NOT NULL,
+    epoch BIGINT NOT NULL,
+    highest_sent_epoch BIGINT NOT NULL,
+    highest_received_epoch TEXT NOT NULL,
+    PRIMARY KEY (instance, config_digest)
+);
+
+CREATE TABLE ocr2_pending_transmissions (
+    instance TEXT NOT NULL,
+    config_digest BLOB NOT NULL,
+    epoch BIGINT NOT NULL,
+    round SMALLINT NOT NULL,
+    time_unix_nano BIGINT NOT NULL,
+    extra_hash BLOB NOT NULL,
+    report BLOB NOT NULL,
+    attributed_signatures TEXT NOT NULL,
+    PRIMARY KEY (instance, config_digest, epoch, round)
+);
+
+CREATE INDEX ocr2_pending_transmissions_time ON ocr2_pending_transmissions (instance, time_unix_nano);
+
+CREATE TABLE ocr3_protocol_states (
+    instance TEXT NOT NULL,
+    config_digest BLOB NOT NULL,
+    key TEXT NOT NULL,
+    value BLOB NOT NULL,
+    PRIMARY KEY (instance, config_digest, key)
+);
+
+CREATE TABLE ocr_discoverer_announcements (
+    instance TEXT NOT NULL,
+    peer_id TEXT NOT NULL,
+    announcement BLOB NOT NULL,
+    PRIMARY KEY (instance, peer_id)
+);
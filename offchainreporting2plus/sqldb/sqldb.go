@@ -0,0 +1,381 @@
+// Package sqldb provides reference implementations of types.Database,
+// ocr3types.Database and networking/types.DiscovererDatabase on top of
+// database/sql. It works with Postgres and SQLite.
+//
+// Create the schema with Migrate (or apply the files from MigrationsFS with
+// your own tooling) and then construct a DB per oracle instance:
+//
+//	if err := sqldb.Migrate(ctx, db, sqldb.Postgres); err != nil { ... }
+//	database := sqldb.New(db, "my-job-42")
+//
+// All instances share the same tables, and rows are keyed by the instance
+// name passed to New. Queries use $1-style placeholders, which both Postgres
+// and SQLite drivers understand.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	nettypes "github.com/smartcontractkit/libocr/networking/types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// DB stores the state of a single oracle instance in a SQL database. All its
+// methods are thread-safe.
+type DB struct {
+	db       *sql.DB
+	instance string
+}
+
+var (
+	_ types.Database              = (*DB)(nil)
+	_ ocr3types.Database          = (*DB)(nil)
+	_ nettypes.DiscovererDatabase = (*DB)(nil)
+)
+
+// New returns a DB that stores the state of the oracle instance named
+// instance in db. The schema must already be up to date, see Migrate.
+func New(db *sql.DB, instance string) *DB {
+	return &DB{db, instance}
+}
+
+func (d *DB) ReadConfig(ctx context.Context) (*types.ContractConfig, error) {
+	var (
+		config                types.ContractConfig
+		configCount           int64
+		signers, transmitters string
+		offchainConfigVersion int64
+	)
+	err := d.db.QueryRowContext(ctx, `
+		SELECT config_digest, config_count, signers, transmitters, f, onchain_config, offchain_config_version, offchain_config
+		FROM ocr_contract_configs
+		WHERE instance = $1`,
+		d.instance,
+	).Scan(
+		&config.ConfigDigest,
+		&configCount,
+		&signers,
+		&transmitters,
+		&config.F,
+		&config.OnchainConfig,
+		&offchainConfigVersion,
+		&config.OffchainConfig,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	config.ConfigCount = uint64(configCount)
+	config.OffchainConfigVersion = uint64(offchainConfigVersion)
+	if err := json.Unmarshal([]byte(signers), &config.Signers); err != nil {
+		return nil, fmt.Errorf("error decoding signers: %w", err)
+	}
+	if err := json.Unmarshal([]byte(transmitters), &config.Transmitters); err != nil {
+		return nil, fmt.Errorf("error decoding transmitters: %w", err)
+	}
+	return &config, nil
+}
+
+func (d *DB) WriteConfig(ctx context.Context, config types.ContractConfig) error {
+	signers, err := json.Marshal(config.Signers)
+	if err != nil {
+		return fmt.Errorf("error encoding signers: %w", err)
+	}
+	transmitters, err := json.Marshal(config.Transmitters)
+	if err != nil {
+		return fmt.Errorf("error encoding transmitters: %w", err)
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO ocr_contract_configs (instance, config_digest, config_count, signers, transmitters, f, onchain_config, offchain_config_version, offchain_config)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (instance) DO UPDATE SET
+			config_digest = excluded.config_digest,
+			config_count = excluded.config_count,
+			signers = excluded.signers,
+			transmitters = excluded.transmitters,
+			f = excluded.f,
+			onchain_config = excluded.onchain_config,
+			offchain_config_version = excluded.offchain_config_version,
+			offchain_config = excluded.offchain_config`,
+		d.instance,
+		config.ConfigDigest,
+		int64(config.ConfigCount),
+		string(signers),
+		string(transmitters),
+		config.F,
+		nonNil(config.OnchainConfig),
+		int64(config.OffchainConfigVersion),
+		nonNil(config.OffchainConfig),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) ReadState(ctx context.Context, configDigest types.ConfigDigest) (*types.PersistentState, error) {
+	var (
+		state                types.PersistentState
+		highestReceivedEpoch string
+	)
+	err := d.db.QueryRowContext(ctx, `
+		SELECT epoch, highest_sent_epoch, highest_received_epoch
+		FROM ocr2_persistent_states
+		WHERE instance = $1 AND config_digest = $2`,
+		d.instance,
+		configDigest,
+	).Scan(&state.Epoch, &state.HighestSentEpoch, &highestReceivedEpoch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state: %w", err)
+	}
+	if err := json.Unmarshal([]byte(highestReceivedEpoch), &state.HighestReceivedEpoch); err != nil {
+		return nil, fmt.Errorf("error decoding highest received epoch: %w", err)
+	}
+	return &state, nil
+}
+
+func (d *DB) WriteState(ctx context.Context, configDigest types.ConfigDigest, state types.PersistentState) error {
+	highestReceivedEpoch, err := json.Marshal(state.HighestReceivedEpoch)
+	if err != nil {
+		return fmt.Errorf("error encoding highest received epoch: %w", err)
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO ocr2_persistent_states (instance, config_digest, epoch, highest_sent_epoch, highest_received_epoch)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (instance, config_digest) DO UPDATE SET
+			epoch = excluded.epoch,
+			highest_sent_epoch = excluded.highest_sent_epoch,
+			highest_received_epoch = excluded.highest_received_epoch`,
+		d.instance,
+		configDigest,
+		state.Epoch,
+		state.HighestSentEpoch,
+		string(highestReceivedEpoch),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing state: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) StorePendingTransmission(ctx context.Context, ts types.ReportTimestamp, pt types.PendingTransmission) error {
+	attributedSignatures, err := json.Marshal(pt.AttributedSignatures)
+	if err != nil {
+		return fmt.Errorf("error encoding attributed signatures: %w", err)
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO ocr2_pending_transmissions (instance, config_digest, epoch, round, time_unix_nano, extra_hash, report, attributed_signatures)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (instance, config_digest, epoch, round) DO UPDATE SET
+			time_unix_nano = excluded.time_unix_nano,
+			extra_hash = excluded.extra_hash,
+			report = excluded.report,
+			attributed_signatures = excluded.attributed_signatures`,
+		d.instance,
+		ts.ConfigDigest,
+		ts.Epoch,
+		ts.Round,
+		pt.Time.UnixNano(),
+		pt.ExtraHash[:],
+		nonNil(pt.Report),
+		string(attributedSignatures),
+	)
+	if err != nil {
+		return fmt.Errorf("error storing pending transmission: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) PendingTransmissionsWithConfigDigest(ctx context.Context, configDigest types.ConfigDigest) (map[types.ReportTimestamp]types.PendingTransmission, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT epoch, round, time_unix_nano, extra_hash, report, attributed_signatures
+		FROM ocr2_pending_transmissions
+		WHERE instance = $1 AND config_digest = $2`,
+		d.instance,
+		configDigest,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pending transmissions: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[types.ReportTimestamp]types.PendingTransmission{}
+	for rows.Next() {
+		var (
+			ts                   = types.ReportTimestamp{ConfigDigest: configDigest}
+			pt                   types.PendingTransmission
+			timeUnixNano         int64
+			extraHash            []byte
+			attributedSignatures string
+		)
+		if err := rows.Scan(&ts.Epoch, &ts.Round, &timeUnixNano, &extraHash, &pt.Report, &attributedSignatures); err != nil {
+			return nil, fmt.Errorf("error reading pending transmission: %w", err)
+		}
+		if len(extraHash) != len(pt.ExtraHash) {
+			return nil, fmt.Errorf("extra hash of pending transmission (epoch %v, round %v) has wrong length %v", ts.Epoch, ts.Round, len(extraHash))
+		}
+		copy(pt.ExtraHash[:], extraHash)
+		pt.Time = time.Unix(0, timeUnixNano)
+		if err := json.Unmarshal([]byte(attributedSignatures), &pt.AttributedSignatures); err != nil {
+			return nil, fmt.Errorf("error decoding attributed signatures: %w", err)
+		}
+		result[ts] = pt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading pending transmissions: %w", err)
+	}
+	return result, nil
+}
+
+func (d *DB) DeletePendingTransmission(ctx context.Context, ts types.ReportTimestamp) error {
+	_, err := d.db.ExecContext(ctx, `
+		DELETE FROM ocr2_pending_transmissions
+		WHERE instance = $1 AND config_digest = $2 AND epoch = $3 AND round = $4`,
+		d.instance,
+		ts.ConfigDigest,
+		ts.Epoch,
+		ts.Round,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting pending transmission: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) DeletePendingTransmissionsOlderThan(ctx context.Context, t time.Time) error {
+	_, err := d.db.ExecContext(ctx, `
+		DELETE FROM ocr2_pending_transmissions
+		WHERE instance = $1 AND time_unix_nano < $2`,
+		d.instance,
+		t.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting old pending transmissions: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) ReadProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string) ([]byte, error) {
+	var value []byte
+	err := d.db.QueryRowContext(ctx, `
+		SELECT value
+		FROM ocr3_protocol_states
+		WHERE instance = $1 AND config_digest = $2 AND key = $3`,
+		d.instance,
+		configDigest,
+		key,
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading protocol state: %w", err)
+	}
+	return nonNil(value), nil
+}
+
+func (d *DB) WriteProtocolState(ctx context.Context, configDigest types.ConfigDigest, key string, value []byte) error {
+	var err error
+	if value == nil {
+		_, err = d.db.ExecContext(ctx, `
+			DELETE FROM ocr3_protocol_states
+			WHERE instance = $1 AND config_digest = $2 AND key = $3`,
+			d.instance,
+			configDigest,
+			key,
+		)
+	} else {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO ocr3_protocol_states (instance, config_digest, key, value)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (instance, config_digest, key) DO UPDATE SET
+				value = excluded.value`,
+			d.instance,
+			configDigest,
+			key,
+			value,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing protocol state: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) StoreAnnouncement(ctx context.Context, peerID string, ann []byte) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO ocr_discoverer_announcements (instance, peer_id, announcement)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (instance, peer_id) DO UPDATE SET
+			announcement = excluded.announcement`,
+		d.instance,
+		peerID,
+		nonNil(ann),
+	)
+	if err != nil {
+		return fmt.Errorf("error storing announcement: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) ReadAnnouncements(ctx context.Context, peerIDs []string) (map[string][]byte, error) {
+	result := map[string][]byte{}
+	if len(peerIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, 0, len(peerIDs))
+	args := make([]interface{}, 0, 1+len(peerIDs))
+	args = append(args, d.instance)
+	for _, peerID := range peerIDs {
+		args = append(args, peerID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT peer_id, announcement
+		FROM ocr_discoverer_announcements
+		WHERE instance = $1 AND peer_id IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading announcements: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			peerID string
+			ann    []byte
+		)
+		if err := rows.Scan(&peerID, &ann); err != nil {
+			return nil, fmt.Errorf("error reading announcement: %w", err)
+		}
+		result[peerID] = nonNil(ann)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading announcements: %w", err)
+	}
+	return result, nil
+}
+
+// nonNil maps nil to an empty slice, since some drivers store nil []byte as
+// NULL, which the NOT NULL columns reject.
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,58 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	nettypes "github.com/smartcontractkit/libocr/networking/types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/dbconformance"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/sqldb"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// openSQLite returns a migrated SQLite database that is shared by all
+// subtests. Each subtest gets its own instance, so this also checks that
+// instances don't see each other's rows.
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ocr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	// SQLite doesn't support concurrent writers
+	db.SetMaxOpenConns(1)
+
+	if err := sqldb.Migrate(context.Background(), db, sqldb.SQLite); err != nil {
+		t.Fatal(err)
+	}
+	// migrating again is a no-op
+	if err := sqldb.Migrate(context.Background(), db, sqldb.SQLite); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	db := openSQLite(t)
+
+	t.Run("OCR2", func(t *testing.T) {
+		dbconformance.TestOCR2Database(t, func(t *testing.T) types.Database {
+			return sqldb.New(db, t.Name())
+		})
+	})
+	t.Run("OCR3", func(t *testing.T) {
+		dbconformance.TestOCR3Database(t, func(t *testing.T) ocr3types.Database {
+			return sqldb.New(db, t.Name())
+		})
+	})
+	t.Run("Discoverer", func(t *testing.T) {
+		dbconformance.TestDiscovererDatabase(t, func(t *testing.T) nettypes.DiscovererDatabase {
+			return sqldb.New(db, t.Name())
+		})
+	})
+}
@@ -0,0 +1,40 @@
+package offchainreporting2plus
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewStatusHandler returns a read-only http.Handler that serves
+// oracle.Status() as JSON in response to GET requests. It is meant to be
+// mounted on an operator-facing admin server, e.g. under /ocr3/status.
+//
+// Responds with 501 Not Implemented if the oracle doesn't support Status().
+func NewStatusHandler(oracle StatusOracle) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status, err := oracle.Status()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+
+		body, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(append(body, '\n'))
+		}
+	})
+}
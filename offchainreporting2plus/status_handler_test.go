@@ -0,0 +1,130 @@
+package offchainreporting2plus_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+type fakeStatusOracle struct {
+	status ocr3types.Status
+	err    error
+}
+
+func (o fakeStatusOracle) Start() error { return nil }
+func (o fakeStatusOracle) Close() error { return nil }
+
+func (o fakeStatusOracle) Status() (ocr3types.Status, error) {
+	return o.status, o.err
+}
+
+func serveStatus(oracle offchainreporting2plus.StatusOracle, method string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	offchainreporting2plus.NewStatusHandler(oracle).ServeHTTP(recorder, httptest.NewRequest(method, "/ocr3/status", nil))
+	return recorder
+}
+
+func TestStatusHandler(t *testing.T) {
+	status := ocr3types.Status{
+		Running:      true,
+		ConfigDigest: types.ConfigDigest{0x00, 0x03, 0xaa},
+		OracleID:     2,
+		Pacemaker:    ocr3types.PacemakerStatus{Epoch: 7, Leader: 1, HighestSentNewEpochWish: 7},
+		OutcomeGeneration: ocr3types.OutcomeGenerationStatus{
+			Epoch:          7,
+			Leader:         1,
+			SeqNr:          42,
+			CommittedSeqNr: 41,
+		},
+		Peers: []types.PeerStatus{{OracleID: 1, PeerID: "peer", Connected: true}},
+	}
+	oracle := fakeStatusOracle{status, nil}
+
+	response := serveStatus(oracle, http.MethodGet)
+	if response.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %v", response.Code)
+	}
+	if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	if cacheControl := response.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("unexpected cache control %q", cacheControl)
+	}
+	expected, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Body.String() != string(expected)+"\n" {
+		t.Errorf("served status %s, expected %s", response.Body.String(), expected)
+	}
+	// ConfigDigest is served hex-encoded
+	var decoded struct {
+		ConfigDigest      string
+		OutcomeGeneration struct{ SeqNr uint64 }
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ConfigDigest != status.ConfigDigest.Hex() || decoded.OutcomeGeneration.SeqNr != 42 {
+		t.Errorf("unexpected served status %+v", decoded)
+	}
+
+	response = serveStatus(oracle, http.MethodHead)
+	if response.Code != http.StatusOK || response.Body.Len() != 0 {
+		t.Errorf("unexpected response to HEAD: %v %q", response.Code, response.Body.String())
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		response = serveStatus(oracle, method)
+		if response.Code != http.StatusMethodNotAllowed || response.Header().Get("Allow") != "GET, HEAD" {
+			t.Errorf("unexpected response to %v: %v, Allow: %q", method, response.Code, response.Header().Get("Allow"))
+		}
+	}
+
+	response = serveStatus(fakeStatusOracle{err: fmt.Errorf("status not enabled")}, http.MethodGet)
+	if response.Code != http.StatusNotImplemented {
+		t.Errorf("unexpected status code %v for oracle without status", response.Code)
+	}
+}
+
+func TestOracleStatus(t *testing.T) {
+	localConfig := types.LocalConfig{DevelopmentMode: types.EnableDangerousDevelopmentMode}
+	for _, test := range []struct {
+		name      string
+		args      offchainreporting2plus.OracleArgs
+		supported bool
+	}{
+		{"OCR2", offchainreporting2plus.OCR2OracleArgs{LocalConfig: localConfig}, false},
+		{"OCR3 without status", offchainreporting2plus.OCR3OracleArgs[struct{}]{LocalConfig: localConfig}, false},
+		{"OCR3 with status", offchainreporting2plus.OCR3OracleArgs[struct{}]{LocalConfig: localConfig, EnableStatus: true}, true},
+		{"Mercury with status", offchainreporting2plus.MercuryOracleArgs{LocalConfig: localConfig, EnableStatus: true}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			oracle, err := offchainreporting2plus.NewOracle(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statusOracle, ok := oracle.(offchainreporting2plus.StatusOracle)
+			if !ok {
+				t.Fatal("oracle doesn't implement StatusOracle")
+			}
+			status, err := statusOracle.Status()
+			if test.supported && err != nil {
+				t.Fatal(err)
+			}
+			if !test.supported && err == nil {
+				t.Fatal("status is supported")
+			}
+			// the oracle hasn't been started
+			if status.Running {
+				t.Errorf("unexpected status %+v", status)
+			}
+		})
+	}
+}
@@ -72,7 +72,7 @@ func (c *ConfigDigest) Scan(value interface{}) error {
 	if !ok {
 		return errors.Errorf("unable to convert %v of type %T to ConfigDigest", value, value)
 	}
-	if len(b) != 16 {
+	if len(b) != len(c) {
 		return errors.Errorf("unable to convert blob 0x%x of length %v to ConfigDigest", b, len(b))
 	}
 	copy(c[:], b)
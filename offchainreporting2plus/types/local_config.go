@@ -57,6 +57,24 @@ type LocalConfig struct {
 	// Timeout for ContractTransmitter.Transmit calls.
 	ContractTransmitterTransmitTimeout time.Duration
 
+	// Number of times OCR3 retries transmitting a report after
+	// ContractTransmitter.Transmit returned an error (or the ReportingPlugin
+	// failed to decide whether to transmit it). ShouldTransmitAcceptedReport
+	// is consulted again before every retry. Zero disables retries. Ignored by
+	// OCR2.
+	ContractTransmitterTransmitRetries int
+
+	// Delay before the first retry of a failed transmission. The delay doubles
+	// with every further retry, up to ContractTransmitterTransmitMaxRetryDelay.
+	// Only relevant if ContractTransmitterTransmitRetries is positive.
+	ContractTransmitterTransmitRetryDelay    time.Duration
+	ContractTransmitterTransmitMaxRetryDelay time.Duration
+
+	// Maximum number of concurrent ContractTransmitter.Transmit calls in OCR3,
+	// so that a slow transmission doesn't hold up those of later reports. Zero
+	// is treated as one. Ignored by OCR2.
+	ContractTransmitterMaxConcurrentTransmits int
+
 	// Timeout for database interactions.
 	// (This is necessary because an oracle's operations are serialized, so
 	// blocking forever on an observation would break the oracle.)
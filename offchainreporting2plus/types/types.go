@@ -33,6 +33,31 @@ type BinaryNetworkEndpointFactory interface {
 	PeerID() string
 }
 
+// PeerStatus describes the network stream from a BinaryNetworkEndpoint to
+// another oracle.
+type PeerStatus struct {
+	OracleID commontypes.OracleID
+	PeerID   string
+	// Whether there currently is an authenticated connection to the peer over
+	// which the stream can send messages
+	Connected bool
+	// When Connected last changed. Zero if it never changed.
+	LastStateChange time.Time
+	// Number of messages sent to the peer or received from it over the stream
+	MessagesSent     uint64
+	MessagesReceived uint64
+}
+
+// PeerStatusReporter is optionally implemented by the BinaryNetworkEndpoints
+// returned by a BinaryNetworkEndpointFactory. If implemented, the status of
+// the endpoint's streams is included in the oracle's status.
+//
+// All its functions should be thread-safe.
+type PeerStatusReporter interface {
+	// One entry per other oracle, ordered by oracle id
+	PeerStatuses() []PeerStatus
+}
+
 // BootstrapperFactory creates permissioned Bootstrappers.
 //
 // All its functions should be thread-safe.
@@ -44,6 +44,34 @@ func SanityCheckLocalConfig(c types.LocalConfig) (err error) {
 			"contract transmitter transmit timeout",
 			1*time.Second, 1*time.Minute,
 		))
+	if c.ContractTransmitterTransmitRetries != 0 {
+		const maxContractTransmitterTransmitRetries = 100
+		if !(0 <= c.ContractTransmitterTransmitRetries && c.ContractTransmitterTransmitRetries <= maxContractTransmitterTransmitRetries) {
+			err = multierr.Append(err, errors.Errorf(
+				"contract transmitter transmit retries must be between 0 and %v, but is currently %v",
+				maxContractTransmitterTransmitRetries,
+				c.ContractTransmitterTransmitRetries))
+		}
+		err = multierr.Append(err,
+			boundTimeDuration(
+				c.ContractTransmitterTransmitRetryDelay,
+				"contract transmitter transmit retry delay",
+				100*time.Millisecond, 1*time.Minute,
+			))
+		err = multierr.Append(err,
+			boundTimeDuration(
+				c.ContractTransmitterTransmitMaxRetryDelay,
+				"contract transmitter transmit max retry delay",
+				c.ContractTransmitterTransmitRetryDelay, 10*time.Minute,
+			))
+	}
+	const maxContractTransmitterMaxConcurrentTransmits = 100
+	if !(0 <= c.ContractTransmitterMaxConcurrentTransmits && c.ContractTransmitterMaxConcurrentTransmits <= maxContractTransmitterMaxConcurrentTransmits) {
+		err = multierr.Append(err, errors.Errorf(
+			"contract transmitter max concurrent transmits must be between 0 and %v, but is currently %v",
+			maxContractTransmitterMaxConcurrentTransmits,
+			c.ContractTransmitterMaxConcurrentTransmits))
+	}
 	err = multierr.Append(err,
 		boundTimeDuration(
 			c.DatabaseTimeout,
@@ -0,0 +1,356 @@
+package ragep2p
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type Misbehavior int
+
+const (
+	_ Misbehavior = iota
+	// The peer sent a frame that violates the protocol, e.g. a frame with an
+	// invalid header or an oversized message.
+	MisbehaviorMalformedFrame
+	// The peer exceeded the message or byte token bucket of a Stream.
+	MisbehaviorStreamRateLimitExceeded
+	// The peer exceeded the token bucket of the connection as a whole.
+	MisbehaviorConnRateLimitExceeded
+)
+
+func (m Misbehavior) String() string {
+	switch m {
+	case MisbehaviorMalformedFrame:
+		return "MalformedFrame"
+	case MisbehaviorStreamRateLimitExceeded:
+		return "StreamRateLimitExceeded"
+	case MisbehaviorConnRateLimitExceeded:
+		return "ConnRateLimitExceeded"
+	}
+	return fmt.Sprintf("Misbehavior(%d)", int(m))
+}
+
+// A ConnectionPolicy decides which connections a Host accepts and which peers
+// it dials, in addition to the Host's built-in rate limits. Implementations
+// must be thread-safe and fast, since they are called on hot paths.
+type ConnectionPolicy interface {
+	// IncomingConnectionAccepted is called for every incoming connection as
+	// soon as it has been accepted, before anything has been read from it.
+	// If it returns an error, the connection is closed. Otherwise,
+	// IncomingConnectionClosed is called exactly once after the connection
+	// has been closed, whether or not its handshake succeeded.
+	IncomingConnectionAccepted(remoteAddr net.Addr) error
+	IncomingConnectionClosed(remoteAddr net.Addr)
+	// AllowIncoming is consulted for an incoming connection as soon as the
+	// knock has been verified, before the TLS handshake. If it returns an
+	// error, the connection is dropped.
+	AllowIncoming(other types.PeerID, remoteAddr net.Addr) error
+	// AllowDial is consulted before dialing other at address. If it returns
+	// an error, the dial is skipped.
+	AllowDial(other types.PeerID, address string) error
+	// ConnectionOpened is called once a connection has been authenticated,
+	// right before the Host starts using it. If it returns an error, the
+	// connection is closed. Otherwise, ConnectionClosed is called exactly
+	// once after the connection has been closed.
+	ConnectionOpened(other types.PeerID, remoteAddr net.Addr, incoming bool) error
+	ConnectionClosed(other types.PeerID, remoteAddr net.Addr, incoming bool)
+	// ReportMisbehavior is called whenever a peer misbehaves on an
+	// established connection. If it returns true, the connection is closed.
+	ReportMisbehavior(other types.PeerID, misbehavior Misbehavior) (disconnect bool)
+}
+
+type allowAllConnectionPolicy struct{}
+
+func (allowAllConnectionPolicy) IncomingConnectionAccepted(net.Addr) error  { return nil }
+func (allowAllConnectionPolicy) IncomingConnectionClosed(net.Addr)          {}
+func (allowAllConnectionPolicy) AllowIncoming(types.PeerID, net.Addr) error { return nil }
+func (allowAllConnectionPolicy) AllowDial(types.PeerID, string) error       { return nil }
+func (allowAllConnectionPolicy) ConnectionOpened(types.PeerID, net.Addr, bool) error {
+	return nil
+}
+func (allowAllConnectionPolicy) ConnectionClosed(types.PeerID, net.Addr, bool) {}
+func (allowAllConnectionPolicy) ReportMisbehavior(types.PeerID, Misbehavior) bool {
+	return false
+}
+
+type ConnectionPolicyConfig struct {
+	// DeniedPeers are never dialed and their connections are never accepted.
+	DeniedPeers []types.PeerID
+
+	// AllowedNetworks and DeniedNetworks contain CIDR ranges, e.g.
+	// "10.0.0.0/8". If AllowedNetworks is non-empty, only IPs in one of its
+	// ranges are allowed. IPs in DeniedNetworks are never allowed. Network
+	// rules apply to the remote IPs of incoming connections and to dial
+	// addresses that are IPs. Dial addresses with hostnames can't be checked
+	// before dialing, so outgoing connections are checked once more after the
+	// handshake, using the IP the hostname resolved to. When dialing a
+	// hostname through a proxy, the proxy resolves it and we never learn the
+	// IP, so network rules don't apply.
+	AllowedNetworks []string
+	DeniedNetworks  []string
+
+	// DeniedASNs contains autonomous system numbers that are denied. Requires
+	// LookupASN.
+	DeniedASNs []uint32
+	// LookupASN returns the autonomous system number of ip, or false if it is
+	// unknown.
+	LookupASN func(ip net.IP) (asn uint32, ok bool)
+
+	// MaxIncomingConnectionsPerIP caps the number of concurrent incoming
+	// connections from the same IP. Connections count from the moment they
+	// are accepted, so connections that are still handshaking count, too.
+	// Zero means unlimited.
+	MaxIncomingConnectionsPerIP int
+
+	// A peer that misbehaves MisbehaviorThreshold times within
+	// MisbehaviorWindow is banned for BanDuration. While banned, a peer is
+	// disconnected, its connections are not accepted and it isn't dialed.
+	// Zero MisbehaviorThreshold disables bans.
+	MisbehaviorThreshold int
+	MisbehaviorWindow    time.Duration
+	BanDuration          time.Duration
+}
+
+// BasicConnectionPolicy is a ConnectionPolicy configured through
+// ConnectionPolicyConfig.
+type BasicConnectionPolicy struct {
+	config          ConnectionPolicyConfig
+	deniedPeers     map[types.PeerID]struct{}
+	allowedNetworks []*net.IPNet
+	deniedNetworks  []*net.IPNet
+	deniedASNs      map[uint32]struct{}
+
+	mutex                 sync.Mutex
+	incomingConnsByIP     map[string]int
+	misbehaviorTimestamps map[types.PeerID][]time.Time
+	bannedUntil           map[types.PeerID]time.Time
+}
+
+var _ ConnectionPolicy = (*BasicConnectionPolicy)(nil)
+
+func NewBasicConnectionPolicy(config ConnectionPolicyConfig) (*BasicConnectionPolicy, error) {
+	if config.MaxIncomingConnectionsPerIP < 0 {
+		return nil, fmt.Errorf("MaxIncomingConnectionsPerIP must not be negative")
+	}
+	if config.MisbehaviorThreshold < 0 {
+		return nil, fmt.Errorf("MisbehaviorThreshold must not be negative")
+	}
+	if config.MisbehaviorThreshold > 0 && (config.MisbehaviorWindow <= 0 || config.BanDuration <= 0) {
+		return nil, fmt.Errorf("MisbehaviorWindow and BanDuration must be positive if MisbehaviorThreshold is set")
+	}
+	if len(config.DeniedASNs) != 0 && config.LookupASN == nil {
+		return nil, fmt.Errorf("DeniedASNs requires LookupASN")
+	}
+
+	allowedNetworks, err := parseCIDRs(config.AllowedNetworks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AllowedNetworks: %w", err)
+	}
+	deniedNetworks, err := parseCIDRs(config.DeniedNetworks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DeniedNetworks: %w", err)
+	}
+
+	deniedPeers := make(map[types.PeerID]struct{}, len(config.DeniedPeers))
+	for _, pid := range config.DeniedPeers {
+		deniedPeers[pid] = struct{}{}
+	}
+	deniedASNs := make(map[uint32]struct{}, len(config.DeniedASNs))
+	for _, asn := range config.DeniedASNs {
+		deniedASNs[asn] = struct{}{}
+	}
+
+	return &BasicConnectionPolicy{
+		config,
+		deniedPeers,
+		allowedNetworks,
+		deniedNetworks,
+		deniedASNs,
+
+		sync.Mutex{},
+		map[string]int{},
+		map[types.PeerID][]time.Time{},
+		map[types.PeerID]time.Time{},
+	}, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ipNet)
+	}
+	return result, nil
+}
+
+func ipFromAddr(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
+func (p *BasicConnectionPolicy) checkPeer(other types.PeerID) error {
+	if _, ok := p.deniedPeers[other]; ok {
+		return fmt.Errorf("peer %v is denied", other)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if until, ok := p.bannedUntil[other]; ok {
+		if time.Now().Before(until) {
+			return fmt.Errorf("peer %v is banned until %v", other, until)
+		}
+		delete(p.bannedUntil, other)
+	}
+	return nil
+}
+
+func (p *BasicConnectionPolicy) checkIP(ip net.IP) error {
+	if ip == nil {
+		return nil
+	}
+	if len(p.allowedNetworks) != 0 {
+		allowed := false
+		for _, ipNet := range p.allowedNetworks {
+			if ipNet.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("IP %v is not in an allowed network", ip)
+		}
+	}
+	for _, ipNet := range p.deniedNetworks {
+		if ipNet.Contains(ip) {
+			return fmt.Errorf("IP %v is in denied network %v", ip, ipNet)
+		}
+	}
+	if len(p.deniedASNs) != 0 {
+		if asn, ok := p.config.LookupASN(ip); ok {
+			if _, denied := p.deniedASNs[asn]; denied {
+				return fmt.Errorf("IP %v is in denied AS%d", ip, asn)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *BasicConnectionPolicy) AllowIncoming(other types.PeerID, remoteAddr net.Addr) error {
+	if err := p.checkPeer(other); err != nil {
+		return err
+	}
+	ip := ipFromAddr(remoteAddr)
+	return p.checkIP(ip)
+}
+
+func (p *BasicConnectionPolicy) AllowDial(other types.PeerID, address string) error {
+	if err := p.checkPeer(other); err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return p.checkIP(net.ParseIP(host))
+}
+
+// IncomingConnectionAccepted enforces the network rules and
+// MaxIncomingConnectionsPerIP before the handshake, so that a single IP can't
+// tie up resources with an unlimited number of handshakes.
+func (p *BasicConnectionPolicy) IncomingConnectionAccepted(remoteAddr net.Addr) error {
+	ip := ipFromAddr(remoteAddr)
+	if ip == nil {
+		return nil
+	}
+	if err := p.checkIP(ip); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	key := ip.String()
+	if p.config.MaxIncomingConnectionsPerIP != 0 && p.incomingConnsByIP[key] >= p.config.MaxIncomingConnectionsPerIP {
+		return fmt.Errorf("too many incoming connections from IP %v", ip)
+	}
+	p.incomingConnsByIP[key]++
+	return nil
+}
+
+func (p *BasicConnectionPolicy) IncomingConnectionClosed(remoteAddr net.Addr) {
+	ip := ipFromAddr(remoteAddr)
+	if ip == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	key := ip.String()
+	p.incomingConnsByIP[key]--
+	if p.incomingConnsByIP[key] <= 0 {
+		delete(p.incomingConnsByIP, key)
+	}
+}
+
+// ConnectionOpened checks the remote IPs of outgoing connections, since
+// AllowDial lets dial addresses with hostnames through. Incoming connections
+// have already been checked by IncomingConnectionAccepted.
+func (p *BasicConnectionPolicy) ConnectionOpened(other types.PeerID, remoteAddr net.Addr, incoming bool) error {
+	if err := p.checkPeer(other); err != nil {
+		return err
+	}
+	if incoming {
+		return nil
+	}
+	return p.checkIP(ipFromAddr(remoteAddr))
+}
+
+func (p *BasicConnectionPolicy) ConnectionClosed(other types.PeerID, remoteAddr net.Addr, incoming bool) {
+}
+
+func (p *BasicConnectionPolicy) ReportMisbehavior(other types.PeerID, misbehavior Misbehavior) bool {
+	if p.config.MisbehaviorThreshold == 0 {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	if until, ok := p.bannedUntil[other]; ok && now.Before(until) {
+		return true
+	}
+
+	// We only need to remember the most recent MisbehaviorThreshold
+	// timestamps.
+	timestamps := append(p.misbehaviorTimestamps[other], now)
+	if len(timestamps) > p.config.MisbehaviorThreshold {
+		timestamps = timestamps[len(timestamps)-p.config.MisbehaviorThreshold:]
+	}
+	if len(timestamps) == p.config.MisbehaviorThreshold && now.Sub(timestamps[0]) <= p.config.MisbehaviorWindow {
+		delete(p.misbehaviorTimestamps, other)
+		p.bannedUntil[other] = now.Add(p.config.BanDuration)
+		return true
+	}
+	p.misbehaviorTimestamps[other] = timestamps
+	return false
+}
+
+// IsBanned reports whether other is currently banned.
+func (p *BasicConnectionPolicy) IsBanned(other types.PeerID) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	until, ok := p.bannedUntil[other]
+	return ok && time.Now().Before(until)
+}
@@ -0,0 +1,312 @@
+package ragep2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+func tcpAddr(t *testing.T, address string) net.Addr {
+	addr, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+func newTestConnectionPolicy(t *testing.T, config ConnectionPolicyConfig) *BasicConnectionPolicy {
+	policy, err := NewBasicConnectionPolicy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestNewBasicConnectionPolicy(t *testing.T) {
+	lookupASN := func(net.IP) (uint32, bool) { return 0, false }
+	for _, config := range []ConnectionPolicyConfig{
+		{MaxIncomingConnectionsPerIP: -1},
+		{MisbehaviorThreshold: -1},
+		{MisbehaviorThreshold: 3, BanDuration: time.Minute},
+		{MisbehaviorThreshold: 3, MisbehaviorWindow: time.Minute},
+		{DeniedASNs: []uint32{64500}},
+		{AllowedNetworks: []string{"10.0.0.0"}},
+		{DeniedNetworks: []string{"10.0.0.0/33"}},
+	} {
+		if _, err := NewBasicConnectionPolicy(config); err == nil {
+			t.Errorf("invalid config %+v accepted", config)
+		}
+	}
+	if _, err := NewBasicConnectionPolicy(ConnectionPolicyConfig{DeniedASNs: []uint32{64500}, LookupASN: lookupASN}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBasicConnectionPolicyNetworks(t *testing.T) {
+	var other types.PeerID
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{
+		AllowedNetworks: []string{"10.0.0.0/8", "192.0.2.0/24", "2001:db8::/32"},
+		DeniedNetworks:  []string{"10.6.0.0/16"},
+		DeniedASNs:      []uint32{64501},
+		LookupASN: func(ip net.IP) (uint32, bool) {
+			if ip.Equal(net.ParseIP("192.0.2.66")) {
+				return 64501, true
+			}
+			if ip.Equal(net.ParseIP("192.0.2.1")) {
+				return 64500, true
+			}
+			return 0, false
+		},
+	})
+
+	for _, tc := range []struct {
+		address string
+		allowed bool
+	}{
+		{"10.1.2.3:1", true},
+		{"192.0.2.1:1", true},
+		{"[2001:db8::1]:1", true},
+		// not in AllowedNetworks
+		{"172.16.0.1:1", false},
+		{"[2001:db9::1]:1", false},
+		// in AllowedNetworks, but also in DeniedNetworks
+		{"10.6.0.1:1", false},
+		// in AllowedNetworks, but in a denied AS
+		{"192.0.2.66:1", false},
+	} {
+		addr := tcpAddr(t, tc.address)
+		checks := map[string]error{
+			"IncomingConnectionAccepted": policy.IncomingConnectionAccepted(addr),
+			"AllowIncoming":              policy.AllowIncoming(other, addr),
+			"AllowDial":                  policy.AllowDial(other, tc.address),
+			"ConnectionOpened":           policy.ConnectionOpened(other, addr, false),
+		}
+		for check, err := range checks {
+			if tc.allowed && err != nil {
+				t.Errorf("%v denied %v: %v", check, tc.address, err)
+			} else if !tc.allowed && err == nil {
+				t.Errorf("%v allowed %v", check, tc.address)
+			}
+		}
+		if checks["IncomingConnectionAccepted"] == nil {
+			policy.IncomingConnectionClosed(addr)
+		}
+	}
+
+	// Hostnames can't be checked before dialing. Once the connection has
+	// been established, its remote IP is checked.
+	if err := policy.AllowDial(other, "example.com:1"); err != nil {
+		t.Errorf("AllowDial denied hostname: %v", err)
+	}
+	if err := policy.ConnectionOpened(other, tcpAddr(t, "172.16.0.1:1"), false); err == nil {
+		t.Error("ConnectionOpened allowed outgoing connection to denied IP")
+	}
+	// the IP of a hostname dialed through a proxy is unknown
+	if err := policy.ConnectionOpened(other, proxiedAddr("example.com:1"), false); err != nil {
+		t.Errorf("ConnectionOpened denied proxied connection: %v", err)
+	}
+	if err := policy.AllowDial(other, "example.com"); err == nil {
+		t.Error("AllowDial allowed address without port")
+	}
+
+	// addresses without IPs, e.g. of the in-memory transport, aren't subject
+	// to network rules
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	if err := policy.IncomingConnectionAccepted(remote.RemoteAddr()); err != nil {
+		t.Error(err)
+	}
+	if err := policy.AllowIncoming(other, remote.RemoteAddr()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBasicConnectionPolicyDeniedPeers(t *testing.T) {
+	var denied, allowed types.PeerID
+	denied[0], allowed[0] = 1, 2
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{DeniedPeers: []types.PeerID{denied}})
+	addr := tcpAddr(t, "10.0.0.1:1")
+
+	if err := policy.AllowIncoming(denied, addr); err == nil {
+		t.Error("AllowIncoming allowed denied peer")
+	}
+	if err := policy.AllowDial(denied, "10.0.0.1:1"); err == nil {
+		t.Error("AllowDial allowed denied peer")
+	}
+	if err := policy.ConnectionOpened(denied, addr, true); err == nil {
+		t.Error("ConnectionOpened allowed denied peer")
+	}
+	if err := policy.AllowIncoming(allowed, addr); err != nil {
+		t.Error(err)
+	}
+	if err := policy.AllowDial(allowed, "10.0.0.1:1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBasicConnectionPolicyMaxIncomingConnectionsPerIP(t *testing.T) {
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{MaxIncomingConnectionsPerIP: 2})
+
+	// connections from the same IP count together, regardless of their port
+	first, second, third := tcpAddr(t, "10.0.0.1:1"), tcpAddr(t, "10.0.0.1:2"), tcpAddr(t, "10.0.0.1:3")
+	for _, addr := range []net.Addr{first, second} {
+		if err := policy.IncomingConnectionAccepted(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := policy.IncomingConnectionAccepted(third); err == nil {
+		t.Error("connection over cap accepted")
+	}
+	if err := policy.IncomingConnectionAccepted(tcpAddr(t, "10.0.0.2:1")); err != nil {
+		t.Errorf("connection from other IP denied: %v", err)
+	}
+	// connections over QUIC count, too
+	if err := policy.IncomingConnectionAccepted(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4}); err == nil {
+		t.Error("UDP connection over cap accepted")
+	}
+
+	policy.IncomingConnectionClosed(first)
+	if err := policy.IncomingConnectionAccepted(third); err != nil {
+		t.Errorf("connection below cap denied: %v", err)
+	}
+	policy.IncomingConnectionClosed(second)
+	policy.IncomingConnectionClosed(third)
+	policy.IncomingConnectionClosed(tcpAddr(t, "10.0.0.2:1"))
+	if len(policy.incomingConnsByIP) != 0 {
+		t.Errorf("counts left behind after all connections closed: %v", policy.incomingConnsByIP)
+	}
+}
+
+func TestBasicConnectionPolicyBans(t *testing.T) {
+	var other types.PeerID
+	banDuration := 100 * time.Millisecond
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{
+		MisbehaviorThreshold: 3,
+		MisbehaviorWindow:    time.Hour,
+		BanDuration:          banDuration,
+	})
+	addr := tcpAddr(t, "10.0.0.1:1")
+
+	for i := 0; i < 2; i++ {
+		if policy.ReportMisbehavior(other, MisbehaviorMalformedFrame) {
+			t.Fatalf("disconnected after %v misbehaviors", i+1)
+		}
+	}
+	if policy.IsBanned(other) {
+		t.Fatal("banned below threshold")
+	}
+	banned := time.Now()
+	if !policy.ReportMisbehavior(other, MisbehaviorStreamRateLimitExceeded) {
+		t.Fatal("not disconnected at threshold")
+	}
+	if !policy.IsBanned(other) {
+		t.Fatal("not banned at threshold")
+	}
+
+	if err := policy.AllowIncoming(other, addr); err == nil && time.Since(banned) < banDuration {
+		t.Error("AllowIncoming allowed banned peer")
+	}
+	if err := policy.AllowDial(other, "10.0.0.1:1"); err == nil && time.Since(banned) < banDuration {
+		t.Error("AllowDial allowed banned peer")
+	}
+	if err := policy.ConnectionOpened(other, addr, false); err == nil && time.Since(banned) < banDuration {
+		t.Error("ConnectionOpened allowed banned peer")
+	}
+	// a banned peer is disconnected whenever it misbehaves
+	if !policy.ReportMisbehavior(other, MisbehaviorConnRateLimitExceeded) && time.Since(banned) < banDuration {
+		t.Error("banned peer not disconnected")
+	}
+
+	time.Sleep(banDuration)
+	if policy.IsBanned(other) {
+		t.Error("still banned after BanDuration")
+	}
+	if err := policy.AllowDial(other, "10.0.0.1:1"); err != nil {
+		t.Errorf("AllowDial denied peer after ban: %v", err)
+	}
+	// the count starts over after a ban
+	if policy.ReportMisbehavior(other, MisbehaviorMalformedFrame) {
+		t.Error("disconnected after first misbehavior following ban")
+	}
+}
+
+func TestBasicConnectionPolicyMisbehaviorWindow(t *testing.T) {
+	var other types.PeerID
+	window := 50 * time.Millisecond
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{
+		MisbehaviorThreshold: 2,
+		MisbehaviorWindow:    window,
+		BanDuration:          time.Hour,
+	})
+
+	// misbehaviors that are further apart than MisbehaviorWindow don't add
+	// up to a ban
+	for i := 0; i < 3; i++ {
+		if policy.ReportMisbehavior(other, MisbehaviorMalformedFrame) {
+			t.Fatalf("banned after misbehavior %v", i+1)
+		}
+		time.Sleep(2 * window)
+	}
+	policy.ReportMisbehavior(other, MisbehaviorMalformedFrame)
+	if !policy.ReportMisbehavior(other, MisbehaviorMalformedFrame) {
+		t.Error("not banned after misbehaviors within MisbehaviorWindow")
+	}
+
+	// bans are disabled by default
+	lenient := newTestConnectionPolicy(t, ConnectionPolicyConfig{})
+	for i := 0; i < 100; i++ {
+		if lenient.ReportMisbehavior(other, MisbehaviorMalformedFrame) {
+			t.Fatal("banned although bans are disabled")
+		}
+	}
+}
+
+func TestHostConnectionPolicy(t *testing.T) {
+	discoverer := newTestDiscoverer()
+	transport := NewInMemoryTransport()
+	policy := newTestConnectionPolicy(t, ConnectionPolicyConfig{
+		MisbehaviorThreshold: 1,
+		MisbehaviorWindow:    time.Hour,
+		BanDuration:          time.Hour,
+	})
+	a := newTestHost(t, HostConfig{Transport: transport, ConnectionPolicy: policy}, "a", discoverer)
+	b := newTestHost(t, HostConfig{Transport: transport}, "b", discoverer)
+	discoverer.set(a.ID(), "a")
+	discoverer.set(b.ID(), "b")
+
+	sa := newTestStream(t, a, b.ID(), "stream")
+	sb := newTestStream(t, b, a.ID(), "stream")
+	exchange(t, sb, sa, "hello")
+
+	// b sends a message that exceeds the stream's maxMessageLength, which a
+	// reports as misbehavior
+	sbLarge, err := b.NewStream(a.ID(), "large", 10, 10, 2000, TokenBucketParams{1000, 1000}, TokenBucketParams{1_000_000, 1_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sbLarge.Close()
+	newTestStream(t, a, b.ID(), "large")
+	deadline := time.Now().Add(10 * time.Second)
+	for !policy.IsBanned(b.ID()) {
+		if time.Now().After(deadline) {
+			t.Fatal("b wasn't banned")
+		}
+		sbLarge.SendMessage(make([]byte, 1500))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a neither accepts b's connections nor dials b
+	for time.Now().Before(deadline) {
+		if health, _ := a.PeerHealth(b.ID()); !health.Connected {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if health, _ := a.PeerHealth(b.ID()); health.Connected {
+		t.Error("a reconnected to banned b")
+	}
+}
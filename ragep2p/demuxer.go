@@ -34,6 +34,7 @@ type demuxerStream struct {
 	maxMessageSize  int
 	messagesLimiter ratelimit.TokenBucket
 	bytesLimiter    ratelimit.TokenBucket
+	counters        *streamCounters
 }
 
 type demuxer struct {
@@ -62,6 +63,7 @@ func (d *demuxer) AddStream(
 	maxMessageSize int,
 	messagesLimit TokenBucketParams,
 	bytesLimit TokenBucketParams,
+	counters *streamCounters,
 ) bool {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -76,10 +78,41 @@ func (d *demuxer) AddStream(
 		maxMessageSize,
 		makeRateLimiter(messagesLimit),
 		makeRateLimiter(bytesLimit),
+		counters,
 	}
 	return true
 }
 
+// updateRateLimiter changes the parameters of tb. If the capacity grows, the
+// additional tokens are available right away, just like for a new stream.
+func updateRateLimiter(tb *ratelimit.TokenBucket, params TokenBucketParams) {
+	oldCapacity := tb.Capacity()
+	tb.SetRate(ratelimit.MillitokensPerSecond(math.Ceil(params.Rate * 1000)))
+	tb.SetCapacity(params.Capacity)
+	if params.Capacity > oldCapacity {
+		tb.AddTokens(params.Capacity - oldCapacity)
+	}
+}
+
+func (d *demuxer) UpdateStreamLimits(
+	sid streamID,
+	maxMessageSize int,
+	messagesLimit TokenBucketParams,
+	bytesLimit TokenBucketParams,
+) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s, ok := d.streams[sid]
+	if !ok {
+		return false
+	}
+	s.maxMessageSize = maxMessageSize
+	updateRateLimiter(&s.messagesLimiter, messagesLimit)
+	updateRateLimiter(&s.bytesLimiter, bytesLimit)
+	return true
+}
+
 func (d *demuxer) RemoveStream(sid streamID) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -104,10 +137,12 @@ func (d *demuxer) ShouldPush(sid streamID, size int) shouldPushResult {
 	bytesLimiterAllow := s.bytesLimiter.RemoveTokens(uint32(size))
 
 	if !messagesLimiterAllow {
+		s.counters.messagesLimitDrops.Add(1)
 		return shouldPushResultMessagesLimitExceeded
 	}
 
 	if !bytesLimiterAllow {
+		s.counters.bytesLimitDrops.Add(1)
 		return shouldPushResultBytesLimitExceeded
 	}
 
@@ -123,10 +158,14 @@ func (d *demuxer) PushMessage(sid streamID, msg []byte) pushResult {
 		return pushResultUnknownStream
 	}
 
+	s.counters.messagesReceived.Add(1)
+	s.counters.bytesReceived.Add(uint64(len(msg)))
+
 	var result pushResult
 	if s.buffer.Push(msg) == nil {
 		result = pushResultSuccess
 	} else {
+		s.counters.bufferOverflowDrops.Add(1)
 		result = pushResultDropped
 	}
 
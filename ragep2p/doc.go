@@ -32,7 +32,32 @@
 //
 // If multiple network addresses are discovered for a PeerID, ragep2p will try
 // sequentially dialing all of them until a connection is successfully
-// established.
+// established. With HostConfig.HappyEyeballsDelay set, the addresses are
+// instead raced against each other, with staggered starts, and the first
+// connection to be established wins.
+//
+// # Transports
+//
+// By default, a Host uses TCP connections. A different Transport can be set in
+// HostConfig. NewQUICTransport returns a QUIC transport which carries the
+// messages of each Stream on a separate QUIC stream, so that a Stream with a
+// large backlog doesn't hold up other Streams. InMemoryTransport connects
+// Hosts in the same process and is meant for tests. Regardless of the
+// transport, connections are authenticated with knocks and mutual TLS 1.3 as
+// described below.
+//
+// Peers behind egress proxies can dial through SOCKS5 or HTTP CONNECT proxies
+// (see ProxyConfig). The proxy merely relays the TCP connection, the TLS
+// handshake remains end-to-end.
+//
+// # Health
+//
+// Host.PeerHealth reports the state of the connection with a peer, e.g. its
+// uptime and number of reconnects, together with per-Stream traffic counters
+// and the number of incoming messages dropped by rate limits. If
+// HostConfig.PingInterval is set, peers are periodically pinged over the
+// control lane to measure round trip times. The same information is exported
+// as Prometheus metrics.
 //
 // # Thread Safety
 //
@@ -78,7 +103,8 @@
 // we enforce maximum Stream counts per peer, maximum lengths for various
 // messages, apply rate limiting at the tcp connection level as well as at the
 // individual Stream level, and have a constant bound on the number of buffered
-// messages per Stream.
+// messages per Stream. On top of that, a ConnectionPolicy can deny peers and
+// networks, cap connections per IP and temporarily ban misbehaving peers.
 //
 // ragep2p defends against tarpitting, i.e. other peers that intentionally
 // read/write from the underlying connection slowly. Host.NewStream(),
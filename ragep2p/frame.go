@@ -19,6 +19,11 @@ const (
 	frameTypeOpen
 	frameTypeClose
 	frameTypeData
+	// Ping and pong frames are only sent on the control lane. Their StreamID
+	// is zero and their payload is a pingPayloadSize byte nonce, which the
+	// pong echoes.
+	frameTypePing
+	frameTypePong
 )
 
 type frameHeader struct {
@@ -46,6 +51,8 @@ func decodeFrameHeader(encoded []byte) (frameHeader, error) {
 	case frameTypeOpen:
 	case frameTypeClose:
 	case frameTypeData:
+	case frameTypePing:
+	case frameTypePong:
 	default:
 		return frameHeader{}, errUnknownFrameType
 	}
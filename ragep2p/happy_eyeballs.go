@@ -0,0 +1,88 @@
+package ragep2p
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type happyEyeballsResult struct {
+	conn    TransportConn
+	address string
+	err     error
+}
+
+// dialHappyEyeballs races dials to addresses in the spirit of RFC 8305: dials
+// are started in order, each one delay after the previous one or as soon as
+// the previous one has failed, whichever comes first. The first dial that
+// succeeds wins, the others are cancelled and any connections they still
+// establish are closed.
+func (ho *Host) dialHappyEyeballs(
+	ctx context.Context,
+	other types.PeerID,
+	addresses []string,
+	delay time.Duration,
+	logger loghelper.LoggerWithContext,
+) (TransportConn, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	chResults := make(chan happyEyeballsResult, len(addresses))
+	next := 0
+	pending := 0
+	var chDelay <-chan time.Time
+	startNext := func() {
+		address := addresses[next]
+		next++
+		pending++
+		ho.subprocesses.Go(func() {
+			conn, err := ho.transport.Dial(ctx, address, other, hostHandshaker{ho})
+			chResults <- happyEyeballsResult{conn, address, err}
+		})
+		if next < len(addresses) {
+			chDelay = time.After(delay)
+		} else {
+			chDelay = nil
+		}
+	}
+
+	startNext()
+	var lastErr error
+	for {
+		select {
+		case <-chDelay:
+			startNext()
+		case result := <-chResults:
+			pending--
+			if result.err == nil {
+				cancel()
+				// Dials that are still in flight may succeed nonetheless.
+				ho.subprocesses.Go(func() {
+					for i := 0; i < pending; i++ {
+						if r := <-chResults; r.err == nil {
+							if err := r.conn.Close(); err != nil {
+								logger.Warn("Failed to close connection that lost the dial race", commontypes.LogFields{
+									"remoteAddr": r.address,
+									"error":      err,
+								})
+							}
+						}
+					}
+				})
+				return result.conn, result.address, nil
+			}
+
+			logger.Debug("Dial attempt failed", commontypes.LogFields{"remoteAddr": result.address, "error": result.err})
+			lastErr = result.err
+			if next < len(addresses) {
+				startNext()
+			} else if pending == 0 {
+				cancel()
+				return nil, "", fmt.Errorf("all %d dial attempts failed, last error: %w", len(addresses), lastErr)
+			}
+		}
+	}
+}
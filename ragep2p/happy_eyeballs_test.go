@@ -0,0 +1,220 @@
+package ragep2p
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type fakeDial struct {
+	duration time.Duration
+	fail     bool
+	// whether the dial carries on after its context has been cancelled
+	ignoreCancel bool
+}
+
+// fakeDialTransport dials addresses as configured, without connecting to
+// anything.
+type fakeDialTransport struct {
+	dials map[string]fakeDial
+
+	mutex   sync.Mutex
+	started []string
+	conns   map[string]*fakeTransportConn
+}
+
+var _ Transport = (*fakeDialTransport)(nil)
+
+func newFakeDialTransport(dials map[string]fakeDial) *fakeDialTransport {
+	return &fakeDialTransport{dials, sync.Mutex{}, nil, map[string]*fakeTransportConn{}}
+}
+
+func (t *fakeDialTransport) Listen(string, Handshaker) (TransportListener, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (t *fakeDialTransport) Dial(ctx context.Context, address string, other types.PeerID, handshaker Handshaker) (TransportConn, error) {
+	t.mutex.Lock()
+	t.started = append(t.started, address)
+	t.mutex.Unlock()
+
+	dial := t.dials[address]
+	if dial.ignoreCancel {
+		time.Sleep(dial.duration)
+	} else {
+		select {
+		case <-time.After(dial.duration):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if dial.fail {
+		return nil, fmt.Errorf("dial to %v failed", address)
+	}
+
+	conn := &fakeTransportConn{}
+	t.mutex.Lock()
+	t.conns[address] = conn
+	t.mutex.Unlock()
+	return conn, nil
+}
+
+func (t *fakeDialTransport) startedDials() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]string{}, t.started...)
+}
+
+type fakeTransportConn struct {
+	mutex  sync.Mutex
+	closed bool
+}
+
+func (c *fakeTransportConn) RemoteAddr() net.Addr {
+	return nil
+}
+
+func (c *fakeTransportConn) Handshake(context.Context) (AuthenticatedConn, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c *fakeTransportConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeTransportConn) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.closed
+}
+
+func dialFakeHappyEyeballs(t *testing.T, dials map[string]fakeDial, addresses []string, delay time.Duration) (*fakeDialTransport, TransportConn, string, error) {
+	_, secretKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := newFakeDialTransport(dials)
+	host, err := NewHost(HostConfig{Transport: transport}, secretKey, []string{"unused"}, newTestDiscoverer(), nopLogger{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, address, err := host.dialHappyEyeballs(ctx, types.PeerID{}, addresses, delay, loghelper.MakeRootLoggerWithContext(nopLogger{}))
+	// wait for the losers of the race
+	host.subprocesses.Wait()
+	return transport, conn, address, err
+}
+
+func TestHappyEyeballsFirstAddressWins(t *testing.T) {
+	transport, conn, address, err := dialFakeHappyEyeballs(t, map[string]fakeDial{
+		"a": {},
+		"b": {},
+	}, []string{"a", "b"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "a" || conn != transport.conns["a"] {
+		t.Errorf("dialed %v, expected a", address)
+	}
+	if started := transport.startedDials(); fmt.Sprint(started) != "[a]" {
+		t.Errorf("started dials %v, expected only a", started)
+	}
+}
+
+func TestHappyEyeballsRacesSlowAddress(t *testing.T) {
+	delay := 20 * time.Millisecond
+	start := time.Now()
+	transport, conn, address, err := dialFakeHappyEyeballs(t, map[string]fakeDial{
+		"slow": {duration: time.Hour},
+		"fast": {},
+	}, []string{"slow", "fast"}, delay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("dial completed after %v, before the second dial was due after %v", elapsed, delay)
+	}
+	if address != "fast" || conn != transport.conns["fast"] {
+		t.Errorf("dialed %v, expected fast", address)
+	}
+	if started := transport.startedDials(); fmt.Sprint(started) != "[slow fast]" {
+		t.Errorf("started dials %v", started)
+	}
+}
+
+// With a delay of an hour, the dial only completes if a failed dial starts
+// the next one right away.
+func TestHappyEyeballsFailedDialStartsNextImmediately(t *testing.T) {
+	transport, _, address, err := dialFakeHappyEyeballs(t, map[string]fakeDial{
+		"a": {fail: true},
+		"b": {fail: true},
+		"c": {},
+	}, []string{"a", "b", "c"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "c" {
+		t.Errorf("dialed %v, expected c", address)
+	}
+	if started := transport.startedDials(); fmt.Sprint(started) != "[a b c]" {
+		t.Errorf("started dials %v", started)
+	}
+}
+
+func TestHappyEyeballsAllFail(t *testing.T) {
+	_, conn, _, err := dialFakeHappyEyeballs(t, map[string]fakeDial{
+		"a": {fail: true},
+		"b": {duration: 10 * time.Millisecond, fail: true},
+	}, []string{"a", "b"}, time.Millisecond)
+	if err == nil {
+		t.Fatalf("dial succeeded with %v", conn)
+	}
+	if !strings.Contains(err.Error(), "all 2 dial attempts failed") || !strings.Contains(err.Error(), "dial to b failed") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestHappyEyeballsClosesLosers(t *testing.T) {
+	transport, _, address, err := dialFakeHappyEyeballs(t, map[string]fakeDial{
+		// still succeeds after the race has been decided
+		"late": {duration: 100 * time.Millisecond, ignoreCancel: true},
+		"fast": {},
+	}, []string{"late", "fast"}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "fast" {
+		t.Fatalf("dialed %v, expected fast", address)
+	}
+	if transport.conns["late"] == nil || !transport.conns["late"].isClosed() {
+		t.Error("connection that lost the race wasn't closed")
+	}
+	if transport.conns["fast"].isClosed() {
+		t.Error("connection that won the race was closed")
+	}
+}
+
+func TestHostDialsSecondAddress(t *testing.T) {
+	transport := NewInMemoryTransport()
+	discoverer := newTestDiscoverer()
+	a := newTestHost(t, HostConfig{Transport: transport, HappyEyeballsDelay: 10 * time.Millisecond}, "a", discoverer)
+	b := newTestHost(t, HostConfig{Transport: transport}, "b", discoverer)
+	// b's first address doesn't exist, b doesn't know how to reach a
+	discoverer.set(b.ID(), "nowhere", "b")
+
+	sa := newTestStream(t, a, b.ID(), "stream")
+	sb := newTestStream(t, b, a.ID(), "stream")
+	exchange(t, sa, sb, "hello")
+}
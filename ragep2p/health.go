@@ -0,0 +1,353 @@
+package ragep2p
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+// Size of the payload of ping and pong frames
+const pingPayloadSize = 8
+
+// PeerHealth is a snapshot of the state of the connection with a peer and of
+// the traffic on the Streams with it.
+type PeerHealth struct {
+	// Whether there currently is an authenticated connection with the peer
+	Connected bool
+	// The following three fields describe the current connection and are
+	// only set if Connected is true.
+	RemoteAddr     net.Addr
+	Incoming       bool
+	ConnectedSince time.Time
+	// When the last connection was closed. Zero if no connection has been
+	// closed yet.
+	LastDisconnect time.Time
+	// Number of connections that have been established with the peer
+	Connections uint64
+	// Number of connections that have been established after the first one
+	Reconnects uint64
+
+	// RTT as measured by the most recent ping, and an exponentially weighted
+	// moving average of RTTs. Both are zero if no pong has been received yet.
+	// Pings are only sent if HostConfig.PingInterval is positive.
+	LastRTT     time.Duration
+	SmoothedRTT time.Duration
+	// When the last pong was received. Zero if no pong has been received.
+	LastPong time.Time
+
+	// Streams contains an entry for every open Stream with the peer, ordered
+	// by name.
+	Streams []StreamHealth
+}
+
+// StreamHealth contains traffic counters for a Stream. Counters start at zero
+// when the Stream is created.
+type StreamHealth struct {
+	Name string
+	// Messages (and their total size) that have been handed to the
+	// connection for sending to the peer
+	MessagesSent uint64
+	BytesSent    uint64
+	// Messages (and their total size) that have been received from the peer
+	// and passed the Stream's rate limits
+	MessagesReceived uint64
+	BytesReceived    uint64
+	// Incoming messages that were dropped because the messages or bytes
+	// token bucket of the Stream was exhausted
+	MessagesLimitDrops uint64
+	BytesLimitDrops    uint64
+	// Incoming messages that were dropped because the Stream's incoming
+	// buffer was full and the message was the oldest in it
+	BufferOverflowDrops uint64
+}
+
+type streamCounters struct {
+	name string
+
+	messagesSent        atomic.Uint64
+	bytesSent           atomic.Uint64
+	messagesReceived    atomic.Uint64
+	bytesReceived       atomic.Uint64
+	messagesLimitDrops  atomic.Uint64
+	bytesLimitDrops     atomic.Uint64
+	bufferOverflowDrops atomic.Uint64
+}
+
+func (c *streamCounters) snapshot() StreamHealth {
+	return StreamHealth{
+		c.name,
+		c.messagesSent.Load(),
+		c.bytesSent.Load(),
+		c.messagesReceived.Load(),
+		c.bytesReceived.Load(),
+		c.messagesLimitDrops.Load(),
+		c.bytesLimitDrops.Load(),
+		c.bufferOverflowDrops.Load(),
+	}
+}
+
+// peerHealth tracks the health of the connection with a single peer. It is
+// shared by the peer's connections and Streams.
+type peerHealth struct {
+	mutex sync.Mutex
+
+	connected      bool
+	remoteAddr     net.Addr
+	incoming       bool
+	connectedSince time.Time
+	lastDisconnect time.Time
+	connections    uint64
+
+	pingOutstanding bool
+	pingNonce       uint64
+	pingSentAt      time.Time
+	lastRTT         time.Duration
+	smoothedRTT     time.Duration
+	lastPong        time.Time
+
+	streams map[streamID]*streamCounters
+}
+
+func newPeerHealth() *peerHealth {
+	return &peerHealth{streams: map[streamID]*streamCounters{}}
+}
+
+func (h *peerHealth) addStream(sid streamID, name string) *streamCounters {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	counters := &streamCounters{name: name}
+	h.streams[sid] = counters
+	return counters
+}
+
+func (h *peerHealth) removeStream(sid streamID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.streams, sid)
+}
+
+func (h *peerHealth) connectionOpened(remoteAddr net.Addr, incoming bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.connected = true
+	h.remoteAddr = remoteAddr
+	h.incoming = incoming
+	h.connectedSince = time.Now()
+	h.connections++
+	h.pingOutstanding = false
+}
+
+func (h *peerHealth) connectionClosed() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.connected = false
+	h.remoteAddr = nil
+	h.incoming = false
+	h.connectedSince = time.Time{}
+	h.lastDisconnect = time.Now()
+	h.pingOutstanding = false
+}
+
+// pingSent records that a ping is about to be sent and returns its payload.
+// Only the most recent ping is tracked, a pong for an earlier ping is ignored.
+func (h *peerHealth) pingSent() []byte {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.pingNonce++
+	h.pingOutstanding = true
+	h.pingSentAt = time.Now()
+	return binary.BigEndian.AppendUint64(nil, h.pingNonce)
+}
+
+// pongReceived updates the RTT if payload answers the outstanding ping.
+func (h *peerHealth) pongReceived(payload []byte) {
+	now := time.Now()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.pingOutstanding || binary.BigEndian.Uint64(payload) != h.pingNonce {
+		return
+	}
+	h.pingOutstanding = false
+	rtt := now.Sub(h.pingSentAt)
+	h.lastRTT = rtt
+	if h.smoothedRTT == 0 {
+		h.smoothedRTT = rtt
+	} else {
+		// same smoothing factor as TCP (RFC 6298)
+		h.smoothedRTT = (7*h.smoothedRTT + rtt) / 8
+	}
+	h.lastPong = now
+}
+
+func (h *peerHealth) snapshot() PeerHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var reconnects uint64
+	if h.connections > 0 {
+		reconnects = h.connections - 1
+	}
+	streams := make([]StreamHealth, 0, len(h.streams))
+	for _, counters := range h.streams {
+		streams = append(streams, counters.snapshot())
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].Name < streams[j].Name })
+	return PeerHealth{
+		h.connected,
+		h.remoteAddr,
+		h.incoming,
+		h.connectedSince,
+		h.lastDisconnect,
+		h.connections,
+		reconnects,
+		h.lastRTT,
+		h.smoothedRTT,
+		h.lastPong,
+		streams,
+	}
+}
+
+// PeerHealth returns a snapshot of the health of the connection with other.
+// It returns false if the Host has no open Streams with other.
+func (ho *Host) PeerHealth(other types.PeerID) (PeerHealth, bool) {
+	ho.peersMu.Lock()
+	p, ok := ho.peers[other]
+	ho.peersMu.Unlock()
+	if !ok {
+		return PeerHealth{}, false
+	}
+	return p.health.snapshot(), true
+}
+
+// AllPeerHealth returns a snapshot of the health of the connections with all
+// peers with which the Host has open Streams.
+func (ho *Host) AllPeerHealth() map[types.PeerID]PeerHealth {
+	ho.peersMu.Lock()
+	healths := make(map[types.PeerID]*peerHealth, len(ho.peers))
+	for pid, p := range ho.peers {
+		healths[pid] = p.health
+	}
+	ho.peersMu.Unlock()
+
+	result := make(map[types.PeerID]PeerHealth, len(healths))
+	for pid, h := range healths {
+		result[pid] = h.snapshot()
+	}
+	return result
+}
+
+// healthCollector exports the PeerHealth of all peers as Prometheus metrics.
+// Metrics are computed at scrape time, so that peers and Streams that go away
+// don't leave stale time series behind.
+type healthCollector struct {
+	host *Host
+
+	connected           *prometheus.Desc
+	connections         *prometheus.Desc
+	reconnects          *prometheus.Desc
+	uptime              *prometheus.Desc
+	rtt                 *prometheus.Desc
+	smoothedRTT         *prometheus.Desc
+	messagesSent        *prometheus.Desc
+	bytesSent           *prometheus.Desc
+	messagesReceived    *prometheus.Desc
+	bytesReceived       *prometheus.Desc
+	messagesLimitDrops  *prometheus.Desc
+	bytesLimitDrops     *prometheus.Desc
+	bufferOverflowDrops *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*healthCollector)(nil)
+
+func newHealthCollector(host *Host) *healthCollector {
+	constLabels := prometheus.Labels{"peerID": host.id.String()}
+	peerLabels := []string{"remotePeerID"}
+	streamLabels := []string{"remotePeerID", "streamName"}
+	desc := func(name string, help string, labels []string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, labels, constLabels)
+	}
+	return &healthCollector{
+		host,
+
+		desc("ragep2p_peer_connected", "Whether there is an authenticated connection with the peer", peerLabels),
+		desc("ragep2p_peer_connections_total", "The number of connections established with the peer", peerLabels),
+		desc("ragep2p_peer_reconnects_total", "The number of connections established with the peer after the first one", peerLabels),
+		desc("ragep2p_peer_connection_uptime_seconds", "How long the current connection with the peer has been up, zero if not connected", peerLabels),
+		desc("ragep2p_peer_rtt_seconds", "The round trip time to the peer as measured by the most recent ping", peerLabels),
+		desc("ragep2p_peer_smoothed_rtt_seconds", "The exponentially weighted moving average of round trip times to the peer", peerLabels),
+		desc("ragep2p_stream_messages_sent_total", "The number of messages handed to the connection for sending", streamLabels),
+		desc("ragep2p_stream_bytes_sent_total", "The number of bytes handed to the connection for sending", streamLabels),
+		desc("ragep2p_stream_messages_received_total", "The number of messages received that passed the stream's rate limits", streamLabels),
+		desc("ragep2p_stream_bytes_received_total", "The number of bytes received that passed the stream's rate limits", streamLabels),
+		desc("ragep2p_stream_messages_limit_drops_total", "The number of incoming messages dropped by the stream's messages token bucket", streamLabels),
+		desc("ragep2p_stream_bytes_limit_drops_total", "The number of incoming messages dropped by the stream's bytes token bucket", streamLabels),
+		desc("ragep2p_stream_buffer_overflow_drops_total", "The number of incoming messages dropped because the stream's buffer overflowed", streamLabels),
+	}
+}
+
+func (c *healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.connected,
+		c.connections,
+		c.reconnects,
+		c.uptime,
+		c.rtt,
+		c.smoothedRTT,
+		c.messagesSent,
+		c.bytesSent,
+		c.messagesReceived,
+		c.bytesReceived,
+		c.messagesLimitDrops,
+		c.bytesLimitDrops,
+		c.bufferOverflowDrops,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *healthCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for pid, health := range c.host.AllPeerHealth() {
+		remotePeerID := pid.String()
+		gauge := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, remotePeerID)
+		}
+		counter := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, remotePeerID)
+		}
+
+		connected := 0.0
+		uptime := 0.0
+		if health.Connected {
+			connected = 1
+			uptime = now.Sub(health.ConnectedSince).Seconds()
+		}
+		gauge(c.connected, connected)
+		counter(c.connections, float64(health.Connections))
+		counter(c.reconnects, float64(health.Reconnects))
+		gauge(c.uptime, uptime)
+		if !health.LastPong.IsZero() {
+			gauge(c.rtt, health.LastRTT.Seconds())
+			gauge(c.smoothedRTT, health.SmoothedRTT.Seconds())
+		}
+
+		for _, stream := range health.Streams {
+			streamCounter := func(desc *prometheus.Desc, value uint64) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), remotePeerID, stream.Name)
+			}
+			streamCounter(c.messagesSent, stream.MessagesSent)
+			streamCounter(c.bytesSent, stream.BytesSent)
+			streamCounter(c.messagesReceived, stream.MessagesReceived)
+			streamCounter(c.bytesReceived, stream.BytesReceived)
+			streamCounter(c.messagesLimitDrops, stream.MessagesLimitDrops)
+			streamCounter(c.bytesLimitDrops, stream.BytesLimitDrops)
+			streamCounter(c.bufferOverflowDrops, stream.BufferOverflowDrops)
+		}
+	}
+}
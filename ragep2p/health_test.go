@@ -0,0 +1,221 @@
+package ragep2p
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+func TestPeerHealthRTT(t *testing.T) {
+	h := newPeerHealth()
+	h.connectionOpened(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1}, true)
+
+	// a pong without outstanding ping is ignored
+	h.pongReceived(binary.BigEndian.AppendUint64(nil, 0))
+	if health := h.snapshot(); !health.LastPong.IsZero() {
+		t.Fatalf("unsolicited pong recorded: %+v", health)
+	}
+
+	stale := h.pingSent()
+	current := h.pingSent()
+	time.Sleep(10 * time.Millisecond)
+	// only the most recent ping is answered
+	h.pongReceived(stale)
+	if health := h.snapshot(); !health.LastPong.IsZero() {
+		t.Fatalf("pong for stale ping recorded: %+v", health)
+	}
+	h.pongReceived(current)
+	first := h.snapshot()
+	if first.LastPong.IsZero() || first.LastRTT < 10*time.Millisecond || first.SmoothedRTT != first.LastRTT {
+		t.Fatalf("unexpected RTT after first pong: %+v", first)
+	}
+	// a duplicate pong doesn't count twice
+	h.pongReceived(current)
+	if health := h.snapshot(); health.LastPong != first.LastPong {
+		t.Error("duplicate pong recorded")
+	}
+
+	payload := h.pingSent()
+	h.pongReceived(payload)
+	second := h.snapshot()
+	if second.LastRTT >= first.LastRTT {
+		t.Fatalf("second RTT %v isn't below first RTT %v", second.LastRTT, first.LastRTT)
+	}
+	if expected := (7*first.SmoothedRTT + second.LastRTT) / 8; second.SmoothedRTT != expected {
+		t.Errorf("smoothed RTT is %v, expected %v", second.SmoothedRTT, expected)
+	}
+
+	// a ping that was outstanding when the connection closed is forgotten
+	payload = h.pingSent()
+	h.connectionClosed()
+	h.connectionOpened(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 2}, false)
+	h.pongReceived(payload)
+	if health := h.snapshot(); health.LastRTT != second.LastRTT {
+		t.Error("pong for ping sent on previous connection recorded")
+	}
+}
+
+func TestPeerHealthConnections(t *testing.T) {
+	h := newPeerHealth()
+	if health := h.snapshot(); health.Connected || health.Connections != 0 || health.Reconnects != 0 || len(health.Streams) != 0 {
+		t.Fatalf("unexpected initial health: %+v", health)
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1}
+	h.connectionOpened(addr, true)
+	health := h.snapshot()
+	if !health.Connected || health.RemoteAddr != addr || !health.Incoming || health.ConnectedSince.IsZero() || health.Connections != 1 || health.Reconnects != 0 {
+		t.Fatalf("unexpected health after connecting: %+v", health)
+	}
+
+	h.connectionClosed()
+	health = h.snapshot()
+	if health.Connected || health.RemoteAddr != nil || !health.ConnectedSince.IsZero() || health.LastDisconnect.IsZero() {
+		t.Fatalf("unexpected health after disconnecting: %+v", health)
+	}
+
+	h.connectionOpened(addr, false)
+	health = h.snapshot()
+	if !health.Connected || health.Incoming || health.Connections != 2 || health.Reconnects != 1 {
+		t.Fatalf("unexpected health after reconnecting: %+v", health)
+	}
+
+	// streams are ordered by name
+	var b, a streamID
+	b[0], a[0] = 1, 2
+	h.addStream(b, "b").messagesSent.Add(1)
+	h.addStream(a, "a").messagesReceived.Add(2)
+	health = h.snapshot()
+	if len(health.Streams) != 2 || health.Streams[0].Name != "a" || health.Streams[0].MessagesReceived != 2 || health.Streams[1].MessagesSent != 1 {
+		t.Fatalf("unexpected streams: %+v", health.Streams)
+	}
+	h.removeStream(a)
+	if health := h.snapshot(); len(health.Streams) != 1 || health.Streams[0].Name != "b" {
+		t.Fatalf("unexpected streams after removing a: %+v", health.Streams)
+	}
+}
+
+// waitForHealth polls the health of host's peer other until ok returns true.
+func waitForHealth(t *testing.T, host *Host, other types.PeerID, ok func(PeerHealth) bool) PeerHealth {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		health, found := host.PeerHealth(other)
+		if found && ok(health) {
+			return health
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for health of peer, last was %+v", health)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHostPeerHealth(t *testing.T) {
+	transport := NewInMemoryTransport()
+	discoverer := newTestDiscoverer()
+	a := newTestHost(t, HostConfig{Transport: transport, PingInterval: 10 * time.Millisecond}, "a", discoverer)
+	b := newTestHost(t, HostConfig{Transport: transport}, "b", discoverer)
+	discoverer.set(a.ID(), "a")
+	discoverer.set(b.ID(), "b")
+
+	if _, ok := a.PeerHealth(b.ID()); ok {
+		t.Fatal("health of peer without streams")
+	}
+
+	sa := newTestStream(t, a, b.ID(), "stream")
+	sb := newTestStream(t, b, a.ID(), "stream")
+	exchange(t, sa, sb, "hello")
+
+	// b answers a's pings, although b doesn't ping itself
+	healthA := waitForHealth(t, a, b.ID(), func(h PeerHealth) bool { return !h.LastPong.IsZero() })
+	if !healthA.Connected || healthA.Connections == 0 || healthA.LastRTT <= 0 || healthA.SmoothedRTT <= 0 {
+		t.Errorf("unexpected health: %+v", healthA)
+	}
+	if len(healthA.Streams) != 1 || healthA.Streams[0].MessagesSent == 0 || healthA.Streams[0].BytesSent < uint64(len("hello")) {
+		t.Errorf("unexpected stream health: %+v", healthA.Streams)
+	}
+	healthB := waitForHealth(t, b, a.ID(), func(h PeerHealth) bool { return h.Connected })
+	if !healthB.LastPong.IsZero() {
+		t.Errorf("b received pong without pinging: %+v", healthB)
+	}
+	if len(healthB.Streams) != 1 || healthB.Streams[0].MessagesReceived == 0 || healthB.Streams[0].BytesReceived < uint64(len("hello")) {
+		t.Errorf("unexpected stream health: %+v", healthB.Streams)
+	}
+	if all := a.AllPeerHealth(); len(all) != 1 || !all[b.ID()].Connected {
+		t.Errorf("unexpected health of all peers: %+v", all)
+	}
+
+	// the collector reports the same
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(a.healthCollector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]*dto.Metric{}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metrics[family.GetName()] = metric
+		}
+	}
+	if m := metrics["ragep2p_peer_connected"]; m == nil || m.GetGauge().GetValue() != 1 {
+		t.Errorf("ragep2p_peer_connected is %v", m)
+	}
+	if m := metrics["ragep2p_peer_rtt_seconds"]; m == nil || m.GetGauge().GetValue() <= 0 {
+		t.Errorf("ragep2p_peer_rtt_seconds is %v", m)
+	}
+	if m := metrics["ragep2p_stream_messages_sent_total"]; m == nil || m.GetCounter().GetValue() == 0 {
+		t.Errorf("ragep2p_stream_messages_sent_total is %v", m)
+	}
+
+	// streams disappear from the health once closed
+	if err := sa.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.PeerHealth(b.ID()); ok {
+		t.Error("health of peer whose only stream was closed")
+	}
+}
+
+func TestHostStreamDrops(t *testing.T) {
+	transport := NewInMemoryTransport()
+	discoverer := newTestDiscoverer()
+	a := newTestHost(t, HostConfig{Transport: transport}, "a", discoverer)
+	b := newTestHost(t, HostConfig{Transport: transport}, "b", discoverer)
+	discoverer.set(a.ID(), "a")
+	discoverer.set(b.ID(), "b")
+
+	// a's streams accept a single message: "limited" because of its token
+	// bucket, "small" because of its buffer, which nobody reads from
+	limited, err := a.NewStream(b.ID(), "limited", 10, 10, 1000, TokenBucketParams{0.001, 1}, TokenBucketParams{1_000_000, 1_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limited.Close()
+	small, err := a.NewStream(b.ID(), "small", 10, 1, 1000, TokenBucketParams{1000, 1000}, TokenBucketParams{1_000_000, 1_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer small.Close()
+	toLimited := newTestStream(t, b, a.ID(), "limited")
+	toSmall := newTestStream(t, b, a.ID(), "small")
+
+	health := waitForHealth(t, a, b.ID(), func(h PeerHealth) bool {
+		toLimited.SendMessage([]byte("message"))
+		toSmall.SendMessage([]byte("message"))
+		return len(h.Streams) == 2 && h.Streams[0].MessagesLimitDrops != 0 && h.Streams[1].BufferOverflowDrops != 0
+	})
+	if health.Streams[0].MessagesReceived != 1 || health.Streams[0].BufferOverflowDrops != 0 {
+		t.Errorf("unexpected health of limited stream: %+v", health.Streams[0])
+	}
+	if health.Streams[1].MessagesLimitDrops != 0 || health.Streams[1].BytesLimitDrops != 0 {
+		t.Errorf("unexpected health of small stream: %+v", health.Streams[1])
+	}
+}
@@ -0,0 +1,77 @@
+package knock
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+func newPeer(t *testing.T) (types.PeerID, ed25519.PrivateKey) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := types.PeerIDFromPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, sk
+}
+
+func TestVerifyKnock(t *testing.T) {
+	self, _ := newPeer(t)
+	other, otherSK := newPeer(t)
+	third, _ := newPeer(t)
+
+	knck := BuildKnock(self, other, otherSK)
+	if len(knck) != KnockSize {
+		t.Fatalf("knock has length %v, expected %v", len(knck), KnockSize)
+	}
+	sender, err := VerifyKnock(self, knck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *sender != other {
+		t.Errorf("knock is from %v, expected %v", *sender, other)
+	}
+
+	// a knock only opens the door of the peer it was built for
+	if _, err := VerifyKnock(third, knck); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("knock for another peer: got %v, expected %v", err, ErrInvalidSignature)
+	}
+
+	// the sender can't be swapped out
+	forged := append([]byte{}, knck...)
+	copy(forged[1:], third[:])
+	if _, err := VerifyKnock(self, forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("knock with swapped sender: got %v, expected %v", err, ErrInvalidSignature)
+	}
+
+	tampered := append([]byte{}, knck...)
+	tampered[KnockSize-1] ^= 1
+	if _, err := VerifyKnock(self, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("knock with tampered signature: got %v, expected %v", err, ErrInvalidSignature)
+	}
+
+	selfKnock := append([]byte{}, knck...)
+	copy(selfKnock[1:], self[:])
+	if _, err := VerifyKnock(self, selfKnock); !errors.Is(err, ErrFromSelfDial) {
+		t.Errorf("knock from self: got %v, expected %v", err, ErrFromSelfDial)
+	}
+
+	wrongVersion := append([]byte{}, knck...)
+	wrongVersion[0] = 0x01
+	if _, err := VerifyKnock(self, wrongVersion); err == nil {
+		t.Error("knock with wrong version verified")
+	}
+
+	for _, length := range []int{0, KnockSize - 1, KnockSize + 1} {
+		b := make([]byte, length)
+		copy(b, knck)
+		if _, err := VerifyKnock(self, b); err == nil {
+			t.Errorf("knock of length %v verified", length)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package ragep2p
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type ProxyType int
+
+const (
+	_ ProxyType = iota
+	ProxyTypeSOCKS5
+	ProxyTypeHTTPConnect
+)
+
+func (t ProxyType) String() string {
+	switch t {
+	case ProxyTypeSOCKS5:
+		return "socks5"
+	case ProxyTypeHTTPConnect:
+		return "http-connect"
+	}
+	return fmt.Sprintf("ProxyType(%d)", int(t))
+}
+
+// Proxy is an outbound proxy through which the Host dials peers. The proxy
+// only relays the underlying connection, the knock and the TLS handshake are
+// still performed end-to-end with the remote peer.
+type Proxy struct {
+	Type ProxyType
+	// Address of the proxy in <host>:<port> form
+	Address string
+	// Username and Password are optional. They are only used if Username is
+	// non-empty.
+	Username string
+	Password string
+}
+
+func (p Proxy) check() error {
+	switch p.Type {
+	case ProxyTypeSOCKS5, ProxyTypeHTTPConnect:
+	default:
+		return fmt.Errorf("unknown proxy type %v", p.Type)
+	}
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return fmt.Errorf("invalid proxy address %q: %w", p.Address, err)
+	}
+	return nil
+}
+
+// ProxyConfig determines which proxy, if any, the Host uses for dialing a
+// peer. The zero value disables proxying. Proxies are only supported with the
+// TCP transport.
+type ProxyConfig struct {
+	// Default is used for all peers that don't have an entry in PerPeer. nil
+	// means peers are dialed directly.
+	Default *Proxy
+	// PerPeer overrides Default for individual peers. A nil entry means the
+	// peer is dialed directly.
+	PerPeer map[types.PeerID]*Proxy
+}
+
+func (c ProxyConfig) enabled() bool {
+	return c.Default != nil || len(c.PerPeer) != 0
+}
+
+func (c ProxyConfig) check() error {
+	if c.Default != nil {
+		if err := c.Default.check(); err != nil {
+			return fmt.Errorf("invalid Default proxy: %w", err)
+		}
+	}
+	for pid, p := range c.PerPeer {
+		if p == nil {
+			continue
+		}
+		if err := p.check(); err != nil {
+			return fmt.Errorf("invalid proxy for peer %v: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// proxyFor returns the proxy to use for dialing other, or nil if other should
+// be dialed directly.
+func (c ProxyConfig) proxyFor(other types.PeerID) *Proxy {
+	if p, ok := c.PerPeer[other]; ok {
+		return p
+	}
+	return c.Default
+}
+
+// dialThroughProxy returns a connection whose RemoteAddr is address rather
+// than the address of the proxy, so that the ConnectionPolicy gets to see the
+// IP of the peer. If address has a hostname, the proxy resolves it and the
+// IP of the peer remains unknown.
+func dialThroughProxy(ctx context.Context, p *Proxy, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	switch p.Type {
+	case ProxyTypeSOCKS5:
+		conn, err = dialSOCKS5(ctx, p, address)
+	case ProxyTypeHTTPConnect:
+		conn, err = dialHTTPConnect(ctx, p, address)
+	default:
+		return nil, fmt.Errorf("unknown proxy type %v", p.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var remoteAddr net.Addr = proxiedAddr(address)
+	if addrPort, err := netip.ParseAddrPort(address); err == nil {
+		remoteAddr = net.TCPAddrFromAddrPort(addrPort)
+	}
+	return proxiedConn{conn, remoteAddr}, nil
+}
+
+type proxiedConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c proxiedConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// proxiedAddr is the remote address of a connection to a hostname through a
+// proxy.
+type proxiedAddr string
+
+func (a proxiedAddr) Network() string {
+	return "tcp"
+}
+
+func (a proxiedAddr) String() string {
+	return string(a)
+}
+
+func dialSOCKS5(ctx context.Context, p *Proxy, address string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if p.Username != "" {
+		auth = &proxy.Auth{User: p.Username, Password: p.Password}
+	}
+	dialer, err := proxy.SOCKS5("tcp", p.Address, auth, &net.Dialer{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating SOCKS5 dialer: %w", err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer doesn't support contexts")
+	}
+	conn, err := contextDialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing %q through SOCKS5 proxy %q: %w", address, p.Address, err)
+	}
+	return conn, nil
+}
+
+func dialHTTPConnect(ctx context.Context, p *Proxy, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing HTTP proxy %q: %w", p.Address, err)
+	}
+
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			conn.Close()
+		}
+	}()
+
+	deadline := time.Now().Add(netTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("error during SetDeadline: %w", err)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if p.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("error sending CONNECT request to HTTP proxy %q: %w", p.Address, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CONNECT response from HTTP proxy %q: %w", p.Address, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP proxy %q refused CONNECT to %q: %s", p.Address, address, resp.Status)
+	}
+	// The remote peer doesn't send anything before it has received our knock,
+	// so there must not be any buffered data we'd lose by discarding br.
+	if br.Buffered() != 0 {
+		return nil, fmt.Errorf("HTTP proxy %q sent unexpected data after CONNECT response", p.Address)
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("error during SetDeadline: %w", err)
+	}
+
+	succeeded = true
+	return conn, nil
+}
@@ -0,0 +1,401 @@
+package ragep2p
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+// testProxy is a minimal SOCKS5 or HTTP CONNECT proxy that records the
+// addresses it is asked to connect to.
+type testProxy struct {
+	proxyType ProxyType
+	username  string
+	password  string
+	// if set, the HTTP proxy refuses CONNECT requests with this status
+	refuseStatus int
+	// sent by the HTTP proxy right after its CONNECT response
+	extraData []byte
+
+	ln      net.Listener
+	mutex   sync.Mutex
+	targets []string
+}
+
+func startTestProxy(t *testing.T, proxy *testProxy) *testProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.ln = ln
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var target string
+				var ok bool
+				br := bufio.NewReader(conn)
+				switch proxy.proxyType {
+				case ProxyTypeSOCKS5:
+					target, ok = proxy.handshakeSOCKS5(br, conn)
+				case ProxyTypeHTTPConnect:
+					target, ok = proxy.handshakeHTTPConnect(br, conn)
+				}
+				if !ok {
+					return
+				}
+				proxy.mutex.Lock()
+				proxy.targets = append(proxy.targets, target)
+				proxy.mutex.Unlock()
+
+				targetConn, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer targetConn.Close()
+				go func() {
+					_, _ = io.Copy(targetConn, br)
+					_ = targetConn.Close()
+				}()
+				_, _ = io.Copy(conn, targetConn)
+			}()
+		}
+	}()
+	return proxy
+}
+
+func (p *testProxy) config() *Proxy {
+	return &Proxy{p.proxyType, p.ln.Addr().String(), p.username, p.password}
+}
+
+func (p *testProxy) dialedTargets() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string{}, p.targets...)
+}
+
+// handshakeSOCKS5 implements the subset of RFC 1928 and RFC 1929 that
+// dialSOCKS5 uses.
+func (p *testProxy) handshakeSOCKS5(br *bufio.Reader, conn net.Conn) (string, bool) {
+	var header [2]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil || header[0] != 5 {
+		return "", false
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return "", false
+	}
+	method := byte(0x00) // no authentication
+	if p.username != "" {
+		method = 0x02 // username/password
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == method
+	}
+	if !offered {
+		_, _ = conn.Write([]byte{5, 0xff})
+		return "", false
+	}
+	if _, err := conn.Write([]byte{5, method}); err != nil {
+		return "", false
+	}
+
+	if p.username != "" {
+		readString := func() (string, bool) {
+			length, err := br.ReadByte()
+			if err != nil {
+				return "", false
+			}
+			b := make([]byte, length)
+			if _, err := io.ReadFull(br, b); err != nil {
+				return "", false
+			}
+			return string(b), true
+		}
+		if version, err := br.ReadByte(); err != nil || version != 1 {
+			return "", false
+		}
+		username, ok1 := readString()
+		password, ok2 := readString()
+		if !ok1 || !ok2 || username != p.username || password != p.password {
+			_, _ = conn.Write([]byte{1, 1})
+			return "", false
+		}
+		if _, err := conn.Write([]byte{1, 0}); err != nil {
+			return "", false
+		}
+	}
+
+	var request [4]byte
+	if _, err := io.ReadFull(br, request[:]); err != nil || request[0] != 5 || request[1] != 1 {
+		return "", false
+	}
+	var host string
+	switch request[3] {
+	case 1: // IPv4
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(br, ip); err != nil {
+			return "", false
+		}
+		host = net.IP(ip).String()
+	case 3: // domain name
+		length, err := br.ReadByte()
+		if err != nil {
+			return "", false
+		}
+		name := make([]byte, length)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return "", false
+		}
+		host = string(name)
+	default:
+		return "", false
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return "", false
+	}
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return "", false
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), true
+}
+
+func (p *testProxy) handshakeHTTPConnect(br *bufio.Reader, conn net.Conn) (string, bool) {
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		return "", false
+	}
+	respond := func(status int) bool {
+		response := fmt.Sprintf("HTTP/1.1 %d %s\r\n\r\n", status, http.StatusText(status))
+		if status == http.StatusOK {
+			// in the same write, so that the client reads it together with
+			// the response
+			response += string(p.extraData)
+		}
+		_, err := conn.Write([]byte(response))
+		return err == nil
+	}
+	if p.username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.username + ":" + p.password))
+		if req.Header.Get("Proxy-Authorization") != "Basic "+credentials {
+			respond(http.StatusProxyAuthRequired)
+			return "", false
+		}
+	}
+	if p.refuseStatus != 0 {
+		respond(p.refuseStatus)
+		return "", false
+	}
+	if !respond(http.StatusOK) {
+		return "", false
+	}
+	return req.Host, true
+}
+
+// startEchoServer returns the address of a TCP server that echoes a single
+// line back to every client.
+func startEchoServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte(line))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func checkEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "hello\n" {
+		t.Errorf("echoed %q", line)
+	}
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	echo := startEchoServer(t)
+	_, echoPort, err := net.SplitHostPort(echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, proxyType := range []ProxyType{ProxyTypeSOCKS5, ProxyTypeHTTPConnect} {
+		t.Run(proxyType.String(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			open := startTestProxy(t, &testProxy{proxyType: proxyType})
+			// hostnames are resolved by the proxy
+			for _, address := range []string{echo, net.JoinHostPort("localhost", echoPort)} {
+				conn, err := dialThroughProxy(ctx, open.config(), address)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkEcho(t, conn)
+				// the remote address is the peer's rather than the proxy's, so
+				// that the connection policy can check it
+				if conn.RemoteAddr().String() != address {
+					t.Errorf("connection to %v has remote address %v", address, conn.RemoteAddr())
+				}
+				conn.Close()
+			}
+			if targets := open.dialedTargets(); fmt.Sprint(targets) != fmt.Sprint([]string{echo, net.JoinHostPort("localhost", echoPort)}) {
+				t.Errorf("proxy dialed %v", targets)
+			}
+
+			authenticated := startTestProxy(t, &testProxy{proxyType: proxyType, username: "user", password: "secret"})
+			conn, err := dialThroughProxy(ctx, authenticated.config(), echo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEcho(t, conn)
+			conn.Close()
+
+			wrongPassword := authenticated.config()
+			wrongPassword.Password = "guess"
+			if _, err := dialThroughProxy(ctx, wrongPassword, echo); err == nil {
+				t.Error("dialed with wrong password")
+			}
+			noCredentials := authenticated.config()
+			noCredentials.Username = ""
+			if _, err := dialThroughProxy(ctx, noCredentials, echo); err == nil {
+				t.Error("dialed without credentials")
+			}
+			if len(authenticated.dialedTargets()) != 1 {
+				t.Errorf("proxy dialed %v", authenticated.dialedTargets())
+			}
+		})
+	}
+}
+
+func TestDialThroughHTTPConnectProxyFailures(t *testing.T) {
+	echo := startEchoServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	refusing := startTestProxy(t, &testProxy{proxyType: ProxyTypeHTTPConnect, refuseStatus: http.StatusForbidden})
+	if _, err := dialThroughProxy(ctx, refusing.config(), echo); err == nil {
+		t.Error("dialed through refusing proxy")
+	}
+
+	// data following the response would be lost along with the bufio.Reader
+	chatty := startTestProxy(t, &testProxy{proxyType: ProxyTypeHTTPConnect, extraData: []byte("surprise")})
+	if _, err := dialThroughProxy(ctx, chatty.config(), echo); err == nil {
+		t.Error("dialed through proxy that sent data after its response")
+	}
+
+	unreachable := &Proxy{ProxyTypeHTTPConnect, freeAddress(t, "tcp"), "", ""}
+	if _, err := dialThroughProxy(ctx, unreachable, echo); err == nil {
+		t.Error("dialed through unreachable proxy")
+	}
+}
+
+func TestProxyConfig(t *testing.T) {
+	var direct, proxied types.PeerID
+	direct[0], proxied[0] = 1, 2
+	defaultProxy := &Proxy{ProxyTypeSOCKS5, "127.0.0.1:1080", "", ""}
+	perPeerProxy := &Proxy{ProxyTypeHTTPConnect, "127.0.0.1:3128", "", ""}
+
+	if (ProxyConfig{}).enabled() {
+		t.Error("zero ProxyConfig is enabled")
+	}
+	config := ProxyConfig{defaultProxy, map[types.PeerID]*Proxy{direct: nil, proxied: perPeerProxy}}
+	if err := config.check(); err != nil {
+		t.Fatal(err)
+	}
+	if p := config.proxyFor(direct); p != nil {
+		t.Errorf("peer with nil entry is dialed through %v", p)
+	}
+	if p := config.proxyFor(proxied); p != perPeerProxy {
+		t.Errorf("peer with own proxy is dialed through %v", p)
+	}
+	var other types.PeerID
+	if p := config.proxyFor(other); p != defaultProxy {
+		t.Errorf("other peer is dialed through %v", p)
+	}
+
+	for _, invalid := range []ProxyConfig{
+		{&Proxy{ProxyType(0), "127.0.0.1:1080", "", ""}, nil},
+		{&Proxy{ProxyTypeSOCKS5, "127.0.0.1", "", ""}, nil},
+		{nil, map[types.PeerID]*Proxy{proxied: {ProxyTypeHTTPConnect, "", "", ""}}},
+	} {
+		if err := invalid.check(); err == nil {
+			t.Errorf("invalid ProxyConfig %+v passed check", invalid)
+		}
+	}
+
+	_, secretKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHost(HostConfig{Transport: NewInMemoryTransport(), Proxy: config}, secretKey, []string{"a"}, newTestDiscoverer(), nopLogger{}, nil); err == nil {
+		t.Error("proxy accepted with in-memory transport")
+	}
+}
+
+func TestHostDialsThroughProxy(t *testing.T) {
+	for _, proxyType := range []ProxyType{ProxyTypeSOCKS5, ProxyTypeHTTPConnect} {
+		t.Run(proxyType.String(), func(t *testing.T) {
+			proxy := startTestProxy(t, &testProxy{proxyType: proxyType, username: "user", password: "secret"})
+
+			addressA, addressB := freeAddress(t, "tcp"), freeAddress(t, "tcp")
+			discoverer := newTestDiscoverer()
+			// only a dials through the proxy, b's dials to a fail
+			a := newTestHost(t, HostConfig{Proxy: ProxyConfig{Default: proxy.config()}}, addressA, discoverer)
+			b := newTestHost(t, HostConfig{}, addressB, discoverer)
+			discoverer.set(a.ID(), "127.0.0.1:1")
+			discoverer.set(b.ID(), types.Address(addressB))
+
+			sa := newTestStream(t, a, b.ID(), "stream")
+			sb := newTestStream(t, b, a.ID(), "stream")
+			exchange(t, sa, sb, "to b")
+			exchange(t, sb, sa, "to a")
+			if targets := proxy.dialedTargets(); len(targets) == 0 || targets[0] != addressB {
+				t.Errorf("proxy dialed %v, expected %v", targets, addressB)
+			}
+		})
+	}
+}
@@ -17,11 +17,11 @@ import (
 
 	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/internal/metricshelper"
 	"github.com/smartcontractkit/libocr/ragep2p/internal/knock"
 	"github.com/smartcontractkit/libocr/ragep2p/internal/msgbuf"
 	"github.com/smartcontractkit/libocr/ragep2p/internal/mtls"
 	"github.com/smartcontractkit/libocr/ragep2p/internal/ratelimit"
-	"github.com/smartcontractkit/libocr/ragep2p/internal/ratelimitedconn"
 	"github.com/smartcontractkit/libocr/ragep2p/types"
 	"github.com/smartcontractkit/libocr/subprocesses"
 )
@@ -73,6 +73,7 @@ type peerStreamOpenRequest struct {
 type peerStreamOpenResponse struct {
 	chSendOnOff <-chan bool
 	demux       *demuxer
+	counters    *streamCounters
 	err         error
 }
 
@@ -80,6 +81,13 @@ type peerStreamCloseRequest struct {
 	streamID streamID
 }
 
+type peerStreamUpdateLimitsRequest struct {
+	streamID         streamID
+	maxMessageLength int
+	messagesLimit    TokenBucketParams
+	bytesLimit       TokenBucketParams
+}
+
 type peerStreamCloseResponse struct {
 	peerHasNoStreams bool
 	err              error
@@ -123,6 +131,8 @@ type peer struct {
 	chStreamToConn chan streamIDAndData
 	demuxer        *demuxer
 
+	health *peerHealth
+
 	chNewConnNotification chan<- newConnNotification
 
 	chOtherStreamStateNotification chan<- streamStateNotification
@@ -133,12 +143,37 @@ type peer struct {
 
 	chStreamCloseRequest  chan<- peerStreamCloseRequest
 	chStreamCloseResponse <-chan peerStreamCloseResponse
+
+	chStreamUpdateLimitsRequest  chan<- peerStreamUpdateLimitsRequest
+	chStreamUpdateLimitsResponse <-chan error
 }
 
 type HostConfig struct {
 	// DurationBetweenDials is the minimum duration between two dials. It is
 	// not the exact duration because of jitter.
 	DurationBetweenDials time.Duration
+	// Transport is used for listening and dialing. nil means TCP. All peers
+	// must use the same kind of transport.
+	Transport Transport
+	// Proxy configures outbound proxies for dialing peers. Only supported
+	// with the TCP transport.
+	Proxy ProxyConfig
+	// ConnectionPolicy is consulted for incoming connections and dials. nil
+	// means that all connections with known peers are allowed.
+	ConnectionPolicy ConnectionPolicy
+	// HappyEyeballsDelay enables racing dials when the Discoverer returns
+	// several addresses for a peer, e.g. because a hostname resolved to both
+	// IPv6 and IPv4 addresses. Dials to the addresses are started this long
+	// after one another and the first to succeed is used. Zero disables
+	// racing, in which case a single address is dialed per attempt, rotating
+	// through the addresses.
+	HappyEyeballsDelay time.Duration
+	// PingInterval is the interval at which ping frames are sent to measure
+	// the round trip time to peers, see Host.PeerHealth. Zero disables pings.
+	// Pongs are always sent in response to pings, but peers running versions
+	// of ragep2p that don't know about pings close the connection upon
+	// receiving one, so only enable pings once all peers have been upgraded.
+	PingInterval time.Duration
 }
 
 // A Host allows users to establish Streams with other peers identified by their
@@ -158,6 +193,11 @@ type Host struct {
 	id      types.PeerID
 	tlsCert tls.Certificate
 
+	// Derived from config
+	transport        Transport
+	connectionPolicy ConnectionPolicy
+	healthCollector  *healthCollector
+
 	// Host state
 	stateMu sync.Mutex
 	state   hostState
@@ -192,6 +232,28 @@ func NewHost(
 		return nil, err
 	}
 
+	if err := config.Proxy.check(); err != nil {
+		return nil, fmt.Errorf("invalid proxy config: %w", err)
+	}
+
+	transport := config.Transport
+	if transport == nil {
+		transport = NewTCPTransport()
+	}
+	if config.Proxy.enabled() {
+		tcp, ok := transport.(tcpTransport)
+		if !ok {
+			return nil, fmt.Errorf("proxies are only supported with the TCP transport")
+		}
+		tcp.proxy = config.Proxy
+		transport = tcp
+	}
+
+	connectionPolicy := config.ConnectionPolicy
+	if connectionPolicy == nil {
+		connectionPolicy = allowAllConnectionPolicy{}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Host{
 		config,
@@ -205,6 +267,10 @@ func NewHost(
 		id,
 		mtls.NewMinimalX509CertFromPrivateKey(secretKey),
 
+		transport,
+		connectionPolicy,
+		nil, // healthCollector, set in Start()
+
 		sync.Mutex{},
 		hostStatePending,
 
@@ -234,13 +300,18 @@ func (ho *Host) Start() error {
 	}
 	ho.state = hostStateOpen
 
+	if ho.metricsRegisterer != nil {
+		ho.healthCollector = newHealthCollector(ho)
+		metricshelper.RegisterOrLogError(ho.logger, ho.metricsRegisterer, ho.healthCollector, "ragep2p_peer_health")
+	}
+
 	ho.subprocesses.Go(func() {
 		ho.dialLoop()
 	})
 	for _, addr := range ho.listenAddresses {
-		ln, err := net.Listen("tcp", addr)
+		ln, err := ho.transport.Listen(addr, hostHandshaker{ho})
 		if err != nil {
-			return fmt.Errorf("net.Listen(%q) failed: %w", addr, err)
+			return fmt.Errorf("Listen(%q) failed: %w", addr, err)
 		}
 		ho.subprocesses.Go(func() {
 			ho.listenLoop(ln)
@@ -275,6 +346,8 @@ func (ho *Host) findOrCreatePeer(other types.PeerID) *peer {
 
 		demuxer := newDemuxer()
 
+		health := newPeerHealth()
+
 		chNewConnNotification := make(chan newConnNotification)
 
 		chOtherStreamStateNotification := make(chan streamStateNotification)
@@ -286,6 +359,9 @@ func (ho *Host) findOrCreatePeer(other types.PeerID) *peer {
 		chStreamCloseRequest := make(chan peerStreamCloseRequest)
 		chStreamCloseResponse := make(chan peerStreamCloseResponse)
 
+		chStreamUpdateLimitsRequest := make(chan peerStreamUpdateLimitsRequest)
+		chStreamUpdateLimitsResponse := make(chan error)
+
 		incomingConnsLimiter := ratelimit.NewTokenBucket(incomingConnsRateLimit(ho.config.DurationBetweenDials), 4, true)
 
 		connRateLimiter := newConnRateLimiter(logger)
@@ -312,6 +388,8 @@ func (ho *Host) findOrCreatePeer(other types.PeerID) *peer {
 			make(chan streamIDAndData),
 			demuxer,
 
+			health,
+
 			chNewConnNotification,
 
 			chOtherStreamStateNotification,
@@ -322,6 +400,9 @@ func (ho *Host) findOrCreatePeer(other types.PeerID) *peer {
 
 			chStreamCloseRequest,
 			chStreamCloseResponse,
+
+			chStreamUpdateLimitsRequest,
+			chStreamUpdateLimitsResponse,
 		}
 		ho.peers[other] = &p
 
@@ -334,10 +415,13 @@ func (ho *Host) findOrCreatePeer(other types.PeerID) *peer {
 				chOtherStreamStateNotification,
 				chSelfStreamStateNotification,
 				demuxer,
+				health,
 				chStreamOpenRequest,
 				chStreamOpenResponse,
 				chStreamCloseRequest,
 				chStreamCloseResponse,
+				chStreamUpdateLimitsRequest,
+				chStreamUpdateLimitsResponse,
 				logger,
 			)
 		})
@@ -353,10 +437,13 @@ func peerLoop(
 	chOtherStreamStateNotification <-chan streamStateNotification,
 	chSelfStreamStateNotification chan<- streamStateNotification,
 	demux *demuxer,
+	health *peerHealth,
 	chStreamOpenRequest <-chan peerStreamOpenRequest,
 	chStreamOpenResponse chan<- peerStreamOpenResponse,
 	chStreamCloseRequest <-chan peerStreamCloseRequest,
 	chStreamCloseResponse chan<- peerStreamCloseResponse,
+	chStreamUpdateLimitsRequest <-chan peerStreamUpdateLimitsRequest,
+	chStreamUpdateLimitsResponse chan<- error,
 	logger loghelper.LoggerWithContext,
 ) {
 	defer close(chDone)
@@ -463,25 +550,28 @@ func peerLoop(
 		case req := <-chStreamOpenRequest:
 			if _, ok := streams[req.streamID]; ok {
 				chStreamOpenResponse <- peerStreamOpenResponse{
+					nil,
 					nil,
 					nil,
 					fmt.Errorf("stream already exists"),
 				}
 			} else if len(streams) >= MaxStreamsPerPeer {
 				chStreamOpenResponse <- peerStreamOpenResponse{
+					nil,
 					nil,
 					nil,
 					fmt.Errorf("too many streams, expected at most %d", MaxStreamsPerPeer),
 				}
 			} else {
 				connRateLimiter.AddStream(req.messagesLimit, req.bytesLimit)
-				if !demux.AddStream(req.streamID, req.incomingBufferSize, req.maxMessageLength, req.messagesLimit, req.bytesLimit) {
+				counters := health.addStream(req.streamID, req.streamName)
+				if !demux.AddStream(req.streamID, req.incomingBufferSize, req.maxMessageLength, req.messagesLimit, req.bytesLimit, counters) {
 					logger.Warn("Assumption violation. Failed to add already existing stream to demuxer", commontypes.LogFields{
 						"streamOpenRequest": req,
 					})
 					// let's try to fix the problem by removing and adding the stream again
 					demux.RemoveStream(req.streamID)
-					demux.AddStream(req.streamID, req.incomingBufferSize, req.maxMessageLength, req.messagesLimit, req.bytesLimit)
+					demux.AddStream(req.streamID, req.incomingBufferSize, req.maxMessageLength, req.messagesLimit, req.bytesLimit, counters)
 				}
 				chOnOff := make(chan bool)
 				streams[req.streamID] = stream{
@@ -496,6 +586,7 @@ func peerLoop(
 				chStreamOpenResponse <- peerStreamOpenResponse{
 					chOnOff,
 					demux,
+					counters,
 					nil,
 				}
 			}
@@ -504,6 +595,7 @@ func peerLoop(
 			if s, ok := streams[req.streamID]; ok {
 				connRateLimiter.RemoveStream(s.messagesLimit, s.bytesLimit)
 				demux.RemoveStream(req.streamID)
+				health.removeStream(req.streamID)
 				delete(streams, req.streamID)
 				if chConnTerminated != nil {
 					pendingSelfStreamStateNotifications[req.streamID] = false
@@ -523,6 +615,18 @@ func peerLoop(
 				}
 			}
 
+		case req := <-chStreamUpdateLimitsRequest:
+			if s, ok := streams[req.streamID]; ok {
+				connRateLimiter.RemoveStream(s.messagesLimit, s.bytesLimit)
+				connRateLimiter.AddStream(req.messagesLimit, req.bytesLimit)
+				demux.UpdateStreamLimits(req.streamID, req.maxMessageLength, req.messagesLimit, req.bytesLimit)
+				s.messagesLimit, s.bytesLimit = req.messagesLimit, req.bytesLimit
+				streams[req.streamID] = s
+				chStreamUpdateLimitsResponse <- nil
+			} else {
+				chStreamUpdateLimitsResponse <- fmt.Errorf("stream not found")
+			}
+
 		case <-ctx.Done():
 			return
 		}
@@ -542,6 +646,9 @@ func (ho *Host) Close() error {
 	err := ho.discoverer.Close()
 	ho.logger.Info("Host winding down", nil)
 	ho.state = hostStateClosed
+	if ho.healthCollector != nil {
+		ho.metricsRegisterer.Unregister(ho.healthCollector)
+	}
 	ho.cancel()
 	ho.subprocesses.Wait()
 	ho.logger.Info("Host exiting", nil)
@@ -602,7 +709,8 @@ func (ho *Host) dialLoop() {
 					return
 				}
 
-				address := string(addresses[ds.next%uint(len(addresses))])
+				start := ds.next % uint(len(addresses))
+				address := string(addresses[start])
 
 				// We used to increment this only on dial error but a connection might fail after the Dial itself has
 				// succeeded (eg. this happens with self-dials where the connection is reset after the incorrect knock
@@ -610,12 +718,21 @@ func (ho *Host) dialLoop() {
 				// a fair chance to every address.
 				ds.next++
 
+				if ho.config.HappyEyeballsDelay > 0 && len(addresses) > 1 {
+					ho.dialRace(p, addresses, start)
+					return
+				}
+
 				logger := p.logger.MakeChild(commontypes.LogFields{"direction": "out", "remoteAddr": address})
 
-				dialer := net.Dialer{
-					Timeout: ho.config.DurationBetweenDials,
+				if err := ho.connectionPolicy.AllowDial(p.other, address); err != nil {
+					logger.Debug("Dial denied by connection policy", commontypes.LogFields{"error": err})
+					return
 				}
-				conn, err := dialer.DialContext(ho.ctx, "tcp", address)
+
+				dialCtx, dialCancel := context.WithTimeout(ho.ctx, ho.config.DurationBetweenDials)
+				defer dialCancel()
+				conn, err := ho.transport.Dial(dialCtx, address, p.other, hostHandshaker{ho})
 				if err != nil {
 					logger.Warn("Dial error", commontypes.LogFields{"error": err})
 					return
@@ -640,7 +757,40 @@ func (ho *Host) dialLoop() {
 	}
 }
 
-func (ho *Host) listenLoop(ln net.Listener) {
+// dialRace dials all addresses of p concurrently, starting with the one at
+// index start, and uses the connection that is established first.
+func (ho *Host) dialRace(p *peer, addresses []types.Address, start uint) {
+	logger := p.logger.MakeChild(commontypes.LogFields{"direction": "out"})
+
+	candidates := make([]string, 0, len(addresses))
+	for i := range addresses {
+		address := string(addresses[(start+uint(i))%uint(len(addresses))])
+		if err := ho.connectionPolicy.AllowDial(p.other, address); err != nil {
+			logger.Debug("Dial denied by connection policy", commontypes.LogFields{"remoteAddr": address, "error": err})
+			continue
+		}
+		candidates = append(candidates, address)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	dialCtx, dialCancel := context.WithTimeout(ho.ctx, ho.config.DurationBetweenDials)
+	defer dialCancel()
+	conn, address, err := ho.dialHappyEyeballs(dialCtx, p.other, candidates, ho.config.HappyEyeballsDelay, logger)
+	if err != nil {
+		logger.Warn("Dial error", commontypes.LogFields{"remoteAddrs": candidates, "error": err})
+		return
+	}
+
+	logger = logger.MakeChild(commontypes.LogFields{"remoteAddr": address})
+	logger.Trace("Dial succeeded", nil)
+	ho.subprocesses.Go(func() {
+		ho.handleOutgoingConnection(conn, p.other, logger)
+	})
+}
+
+func (ho *Host) listenLoop(ln TransportListener) {
 	ho.subprocesses.Go(func() {
 		<-ho.ctx.Done()
 		if err := ln.Close(); err != nil {
@@ -654,29 +804,35 @@ func (ho *Host) listenLoop(ln net.Listener) {
 			ho.logger.Info("Exiting Host.listenLoop due to error while Accepting", commontypes.LogFields{"error": err})
 			return
 		}
+		remoteAddr := conn.RemoteAddr()
+		if err := ho.connectionPolicy.IncomingConnectionAccepted(remoteAddr); err != nil {
+			ho.logger.Info("Incoming connection denied by connection policy before handshake", commontypes.LogFields{
+				"remoteAddr": remoteAddr,
+				"error":      err,
+			})
+			if err := conn.Close(); err != nil {
+				ho.logger.Warn("Failed to close incoming connection", commontypes.LogFields{"error": err})
+			}
+			continue
+		}
+		release := sync.OnceFunc(func() {
+			ho.connectionPolicy.IncomingConnectionClosed(remoteAddr)
+		})
 		ho.subprocesses.Go(func() {
-			ho.handleIncomingConnection(conn)
+			ho.handleIncomingConnection(conn, release)
 		})
 	}
 }
 
-func (ho *Host) handleOutgoingConnection(conn net.Conn, other types.PeerID, logger loghelper.LoggerWithContext) {
-	shouldClose := true
-	defer func() {
-		if shouldClose {
-			if err := safeClose(conn); err != nil {
-				logger.Warn("Failed to close outgoing connection", commontypes.LogFields{"error": err})
-			}
+func (ho *Host) handleOutgoingConnection(conn TransportConn, other types.PeerID, logger loghelper.LoggerWithContext) {
+	handshakeCtx, handshakeCancel := context.WithTimeout(ho.ctx, netTimeout)
+	defer handshakeCancel()
+	aconn, err := conn.Handshake(handshakeCtx)
+	if err != nil {
+		logger.Warn("Closing connection, error during handshake", commontypes.LogFields{"error": err})
+		if err := conn.Close(); err != nil {
+			logger.Warn("Failed to close outgoing connection", commontypes.LogFields{"error": err})
 		}
-	}()
-
-	knck := knock.BuildKnock(other, ho.id, ho.secretKey)
-	if err := conn.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
-		logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err})
-		return
-	}
-	if _, err := conn.Write(knck); err != nil {
-		logger.Warn("Error while sending knock", commontypes.LogFields{"error": err})
 		return
 	}
 
@@ -686,115 +842,85 @@ func (ho *Host) handleOutgoingConnection(conn net.Conn, other types.PeerID, logg
 	if !ok {
 		// peer must have been deleted in the time between the dial being
 		// started and now
+		if err := aconn.Close(); err != nil {
+			logger.Warn("Failed to close outgoing connection", commontypes.LogFields{"error": err})
+		}
 		return
 	}
 
-	shouldClose = false
-
-	rlConn := ratelimitedconn.NewRateLimitedConn(conn, peer.connRateLimiter, logger)
-
-	tlsConfig := newTLSConfig(
-		ho.tlsCert,
-		mtls.VerifyCertMatchesPubKey(other),
-	)
-	tlsConn := tls.Client(rlConn, tlsConfig)
-	ho.handleConnection(false, rlConn, tlsConn, peer, logger)
+	ho.handleConnection(false, aconn, conn.RemoteAddr(), peer, func() {}, logger)
 }
 
-func (ho *Host) handleIncomingConnection(conn net.Conn) {
-	remoteAddrLogFields := commontypes.LogFields{"direction": "in", "remoteAddr": conn.RemoteAddr()}
-	logger := ho.logger.MakeChild(remoteAddrLogFields)
-	shouldClose := true
+// handleIncomingConnection calls release once conn has been closed.
+func (ho *Host) handleIncomingConnection(conn TransportConn, release func()) {
+	handedOff := false
 	defer func() {
-		if shouldClose {
-			if err := safeClose(conn); err != nil {
-				logger.Warn("Failed to close incoming connection", commontypes.LogFields{"error": err})
-			}
+		if !handedOff {
+			release()
 		}
 	}()
 
-	knck := make([]byte, knock.KnockSize)
-	if err := conn.SetReadDeadline(time.Now().Add(netTimeout)); err != nil {
-		logger.Warn("Closing connection, error during SetReadDeadline", commontypes.LogFields{"error": err})
-		return
-	}
-	n, err := conn.Read(knck)
-	if err != nil {
-		logger.Warn("Error while reading knock", commontypes.LogFields{"error": err})
-		return
-	}
-	if n != knock.KnockSize {
-		logger.Warn("Knock too short", nil)
-		return
-	}
+	remoteAddrLogFields := commontypes.LogFields{"direction": "in", "remoteAddr": conn.RemoteAddr()}
+	logger := ho.logger.MakeChild(remoteAddrLogFields)
 
-	other, err := knock.VerifyKnock(ho.id, knck)
+	handshakeCtx, handshakeCancel := context.WithTimeout(ho.ctx, netTimeout)
+	defer handshakeCancel()
+	aconn, err := conn.Handshake(handshakeCtx)
 	if err != nil {
+		var errUnknown errUnknownPeer
+		var errDenied errConnectionDenied
 		if errors.Is(err, knock.ErrFromSelfDial) {
 			logger.Info("Self-dial knock, dropping connection. Someone has likely misconfigured their announce addresses.", nil)
+		} else if errors.As(err, &errUnknown) {
+			logger.Warn("Received incoming connection from an unknown peer, closing", remotePeerIDField(errUnknown.other))
+		} else if errors.As(err, &errDenied) {
+			logger.Info("Incoming connection denied by connection policy", commontypes.LogFields{
+				"remotePeerID": errDenied.other,
+				"error":        errDenied.err,
+			})
 		} else {
-			logger.Warn("Invalid knock", commontypes.LogFields{"error": err})
+			logger.Warn("Closing connection, error during handshake", commontypes.LogFields{"error": err})
+		}
+		if err := conn.Close(); err != nil {
+			logger.Warn("Failed to close incoming connection", commontypes.LogFields{"error": err})
 		}
 		return
 	}
 
 	ho.peersMu.Lock()
-	peer, ok := ho.peers[*other]
+	peer, ok := ho.peers[aconn.Other()]
 	ho.peersMu.Unlock()
 	if !ok {
-		logger.Warn("Received incoming connection from an unknown peer, closing", remotePeerIDField(*other))
+		logger.Warn("Received incoming connection from an unknown peer, closing", remotePeerIDField(aconn.Other()))
+		if err := aconn.Close(); err != nil {
+			logger.Warn("Failed to close incoming connection", commontypes.LogFields{"error": err})
+		}
 		return
 	}
 	logger = peer.logger.MakeChild(remoteAddrLogFields) // introduce remotePeerID in our logs since we now know it
-	rl := peer.connRateLimiter
-	rlConn := ratelimitedconn.NewRateLimitedConn(conn, rl, logger)
 
-	shouldClose = false
-
-	tlsConfig := newTLSConfig(
-		ho.tlsCert,
-		mtls.VerifyCertMatchesPubKey(*other),
-	)
-	tlsConn := tls.Server(rlConn, tlsConfig)
-	ho.handleConnection(true, rlConn, tlsConn, peer, logger)
+	handedOff = true
+	ho.handleConnection(true, aconn, conn.RemoteAddr(), peer, release, logger)
 }
 
-func (ho *Host) handleConnection(incoming bool, rlConn *ratelimitedconn.RateLimitedConn, tlsConn *tls.Conn, peer *peer, logger loghelper.LoggerWithContext) {
+// handleConnection calls release once aconn has been closed. release must be
+// safe to call multiple times.
+func (ho *Host) handleConnection(incoming bool, aconn AuthenticatedConn, remoteAddr net.Addr, peer *peer, release func(), logger loghelper.LoggerWithContext) {
 	shouldClose := true
 	defer func() {
 		if shouldClose {
-			if err := safeClose(tlsConn); err != nil {
+			if err := aconn.Close(); err != nil {
 				logger.Warn("Failed to close connection", commontypes.LogFields{"error": err})
 			}
+			release()
 		}
 	}()
 
-	// Handshake reads and write to the connection. Set a deadline to prevent tarpitting
-	if err := tlsConn.SetDeadline(time.Now().Add(netTimeout)); err != nil {
-		logger.Warn("Closing connection, error during SetDeadline", commontypes.LogFields{"error": err})
-		return
-	}
-	// Perform handshake so that we know the public key
-	if err := tlsConn.Handshake(); err != nil {
-		logger.Warn("Closing connection, error during Handshake", commontypes.LogFields{"error": err})
-		return
-	}
-	// Disable deadline. Whoever uses the connection next will have to set their own timeouts.
-	if err := tlsConn.SetDeadline(time.Time{}); err != nil {
-		logger.Warn("Closing connection, error during SetDeadline", commontypes.LogFields{"error": err})
-		return
-	}
-
-	// get public key
-	pubKey, err := mtls.PubKeyFromCert(tlsConn.ConnectionState().PeerCertificates[0])
-	if err != nil {
-		logger.Warn("Closing connection, error getting public key", commontypes.LogFields{"error": err})
-		return
-	}
-	if peer.other != pubKey {
+	// The transport has already checked this, but we don't take any chances
+	if peer.other != aconn.Other() {
 		logger.Warn("TLS handshake PeerID mismatch", commontypes.LogFields{
 			"expected": peer.other,
-			"actual":   types.PeerID(pubKey),
+			"actual":   aconn.Other(),
 		})
 		return
 	}
@@ -809,7 +935,10 @@ func (ho *Host) handleConnection(incoming bool, rlConn *ratelimitedconn.RateLimi
 		}
 	}
 
-	rlConn.EnableRateLimiting()
+	if err := ho.connectionPolicy.ConnectionOpened(peer.other, remoteAddr, incoming); err != nil {
+		logger.Info("Connection denied by connection policy", commontypes.LogFields{"error": err})
+		return
+	}
 
 	logger.Info("Connection established", nil)
 
@@ -822,16 +951,25 @@ func (ho *Host) handleConnection(incoming bool, rlConn *ratelimitedconn.RateLimi
 	chConnTerminated := make(chan struct{})
 	peer.connLifeCycle.connCancel = connCancel
 	peer.connLifeCycle.chConnTerminated = chConnTerminated
+	peer.health.connectionOpened(remoteAddr, incoming)
 	peer.connLifeCycle.connSubs.Go(func() {
 		defer connCancel()
+		defer release()
+		defer ho.connectionPolicy.ConnectionClosed(peer.other, remoteAddr, incoming)
+		defer peer.health.connectionClosed()
 		authenticatedConnectionLoop(
 			connCtx,
-			tlsConn,
+			aconn,
 			peer.chOtherStreamStateNotification,
 			peer.chSelfStreamStateNotification,
 			peer.demuxer,
 			peer.chStreamToConn,
 			chConnTerminated,
+			func(misbehavior Misbehavior) bool {
+				return ho.connectionPolicy.ReportMisbehavior(peer.other, misbehavior)
+			},
+			ho.config.PingInterval,
+			peer.health,
 			logger,
 		)
 	})
@@ -930,9 +1068,16 @@ func (ho *Host) NewStream(
 		p.chStreamToConn,
 		response.demux,
 		response.chSendOnOff,
+		response.counters,
 
 		p.chStreamCloseRequest,
 		p.chStreamCloseResponse,
+
+		p.chStreamUpdateLimitsRequest,
+		p.chStreamUpdateLimitsResponse,
+
+		sync.Mutex{},
+		StreamStatus{},
 	}
 
 	s.subprocesses.Go(func() {
@@ -978,9 +1123,29 @@ type Stream struct {
 	chStreamToConn chan<- streamIDAndData
 	demux          *demuxer
 	chStreamOnOff  <-chan bool
+	counters       *streamCounters
 
 	chStreamCloseRequest  chan<- peerStreamCloseRequest
 	chStreamCloseResponse <-chan peerStreamCloseResponse
+
+	chStreamUpdateLimitsRequest  chan<- peerStreamUpdateLimitsRequest
+	chStreamUpdateLimitsResponse <-chan error
+
+	statusMu sync.Mutex
+	status   StreamStatus
+}
+
+// StreamStatus describes the state of a Stream.
+type StreamStatus struct {
+	// Whether the stream is turned on, i.e. there is an authenticated
+	// connection to the other peer over which messages can be sent
+	Connected bool
+	// When Connected last changed. Zero if it never changed.
+	LastStateChange time.Time
+	// Number of messages sent to the other peer
+	MessagesSent uint64
+	// Number of messages received from the other peer
+	MessagesReceived uint64
 }
 
 // Other returns the peer ID of the stream counterparty.
@@ -993,6 +1158,19 @@ func (st *Stream) Name() string {
 	return st.name
 }
 
+// Status returns a snapshot of the stream's state.
+func (st *Stream) Status() StreamStatus {
+	st.statusMu.Lock()
+	defer st.statusMu.Unlock()
+	return st.status
+}
+
+func (st *Stream) updateStatus(f func(status *StreamStatus)) {
+	st.statusMu.Lock()
+	defer st.statusMu.Unlock()
+	f(&st.status)
+}
+
 // Best effort sending of messages. May fail without returning an error.
 func (st *Stream) SendMessage(data []byte) {
 	select {
@@ -1065,6 +1243,36 @@ func (st *Stream) Close() error {
 	return nil
 }
 
+// UpdateLimits replaces the maxMessageLength and rate limits that the stream
+// was created with. Messages already received are unaffected.
+func (st *Stream) UpdateLimits(maxMessageLength int, messagesLimit TokenBucketParams, bytesLimit TokenBucketParams) error {
+	if MaxMessageLength < maxMessageLength {
+		return fmt.Errorf("maxMessageLength %v is greater than global MaxMessageLength %v", maxMessageLength, MaxMessageLength)
+	}
+
+	st.closedMu.Lock()
+	defer st.closedMu.Unlock()
+	if st.closed {
+		return fmt.Errorf("stream is closed")
+	}
+
+	select {
+	case st.chStreamUpdateLimitsRequest <- peerStreamUpdateLimitsRequest{st.streamID, maxMessageLength, messagesLimit, bytesLimit}:
+		if err := <-st.chStreamUpdateLimitsResponse; err != nil {
+			return err
+		}
+	case <-st.ctx.Done():
+		return fmt.Errorf("stream is closed")
+	}
+
+	st.logger.Info("Stream limits updated", commontypes.LogFields{
+		"maxMessageLength": maxMessageLength,
+		"messagesLimit":    messagesLimit,
+		"bytesLimit":       bytesLimit,
+	})
+	return nil
+}
+
 func (st *Stream) receiveLoop() {
 	chSignalPending := st.demux.SignalPending(st.streamID)
 	chDone := st.ctx.Done()
@@ -1075,6 +1283,9 @@ func (st *Stream) receiveLoop() {
 			if msg != nil {
 				select {
 				case st.chReceive <- msg:
+					st.updateStatus(func(status *StreamStatus) {
+						status.MessagesReceived++
+					})
 				case <-chDone:
 				}
 			} else {
@@ -1097,6 +1308,12 @@ func (st *Stream) sendLoop() {
 	for {
 		select {
 		case onOff = <-st.chStreamOnOff:
+			st.updateStatus(func(status *StreamStatus) {
+				if status.Connected != onOff {
+					status.Connected = onOff
+					status.LastStateChange = time.Now()
+				}
+			})
 			if onOff {
 				if pendingFilled {
 					chStreamToPeerOrNil = st.chStreamToConn
@@ -1117,6 +1334,11 @@ func (st *Stream) sendLoop() {
 			}
 
 		case chStreamToPeerOrNil <- pending:
+			st.updateStatus(func(status *StreamStatus) {
+				status.MessagesSent++
+			})
+			st.counters.messagesSent.Add(1)
+			st.counters.bytesSent.Add(uint64(len(pending.Data)))
 			ringBuffer.Pop()
 			if p := ringBuffer.Peek(); p != nil {
 				pending = streamIDAndData{st.streamID, p}
@@ -1137,12 +1359,15 @@ func (st *Stream) sendLoop() {
 
 func authenticatedConnectionLoop(
 	ctx context.Context,
-	conn net.Conn,
+	aconn AuthenticatedConn,
 	chOtherStreamStateNotification chan<- streamStateNotification,
 	chSelfStreamStateNotification <-chan streamStateNotification,
 	demux *demuxer,
 	chWriteData <-chan streamIDAndData,
 	chTerminated chan<- struct{},
+	reportMisbehavior func(Misbehavior) (disconnect bool),
+	pingInterval time.Duration,
+	health *peerHealth,
 	logger loghelper.LoggerWithContext,
 ) {
 	defer func() {
@@ -1154,7 +1379,7 @@ func authenticatedConnectionLoop(
 	defer subs.Wait()
 
 	defer func() {
-		if err := safeClose(conn); err != nil {
+		if err := aconn.Close(); err != nil {
 			logger.Warn("Failed to close connection", commontypes.LogFields{"error": err})
 		}
 	}()
@@ -1162,25 +1387,51 @@ func authenticatedConnectionLoop(
 	childCtx, childCancel := context.WithCancel(ctx)
 	defer childCancel()
 
+	// Pings received by the read loop that the write loop should answer
+	chPongs := make(chan []byte, 1)
+
 	chReadTerminated := make(chan struct{})
+	var terminateReadOnce sync.Once
+	terminateRead := func() {
+		terminateReadOnce.Do(func() { close(chReadTerminated) })
+	}
 	subs.Go(func() {
+		defer terminateRead()
 		authenticatedConnectionReadLoop(
 			childCtx,
-			conn,
+			aconn.ControlLane(),
+			false,
 			chOtherStreamStateNotification,
+			chPongs,
+			health,
 			demux,
-			chReadTerminated,
+			reportMisbehavior,
 			logger,
 		)
 	})
+	if aconn.SupportsDataLanes() {
+		subs.Go(func() {
+			authenticatedConnectionAcceptDataLanesLoop(
+				childCtx,
+				aconn,
+				demux,
+				terminateRead,
+				reportMisbehavior,
+				logger,
+			)
+		})
+	}
 
 	chWriteTerminated := make(chan struct{})
 	subs.Go(func() {
 		authenticatedConnectionWriteLoop(
 			childCtx,
-			conn,
+			aconn,
 			chSelfStreamStateNotification,
 			chWriteData,
+			chPongs,
+			pingInterval,
+			health,
 			chWriteTerminated,
 			logger,
 		)
@@ -1195,18 +1446,60 @@ func authenticatedConnectionLoop(
 	logger.Info("authenticatedConnectionLoop: winding down", nil)
 }
 
-func authenticatedConnectionReadLoop(
+// authenticatedConnectionAcceptDataLanesLoop accepts the data lanes opened by
+// the remote and reads data frames from each of them.
+func authenticatedConnectionAcceptDataLanesLoop(
 	ctx context.Context,
-	conn net.Conn,
-	chOtherStreamStateNotification chan<- streamStateNotification,
+	aconn AuthenticatedConn,
 	demux *demuxer,
-	chReadTerminated chan<- struct{},
+	terminateRead func(),
+	reportMisbehavior func(Misbehavior) (disconnect bool),
 	logger loghelper.LoggerWithContext,
 ) {
-	defer close(chReadTerminated)
+	var subs subprocesses.Subprocesses
+	defer subs.Wait()
 
+	for {
+		lane, err := aconn.AcceptDataLane(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("Error accepting data lane", commontypes.LogFields{"error": err})
+			}
+			terminateRead()
+			return
+		}
+		subs.Go(func() {
+			cleanEOF := authenticatedConnectionReadLoop(
+				ctx,
+				lane,
+				true,
+				nil,
+				nil,
+				nil,
+				demux,
+				reportMisbehavior,
+				logger,
+			)
+			if !cleanEOF {
+				terminateRead()
+			}
+		})
+	}
+}
+
+func authenticatedConnectionReadLoop(
+	ctx context.Context,
+	r io.Reader,
+	dataLane bool,
+	chOtherStreamStateNotification chan<- streamStateNotification,
+	chPongs chan<- []byte,
+	health *peerHealth,
+	demux *demuxer,
+	reportMisbehavior func(Misbehavior) (disconnect bool),
+	logger loghelper.LoggerWithContext,
+) (cleanEOF bool) {
 	readInternal := func(buf []byte) bool {
-		_, err := io.ReadFull(conn, buf)
+		_, err := io.ReadFull(r, buf)
 		if err != nil {
 			logger.Warn("Error reading from connection", commontypes.LogFields{"error": err})
 			return false
@@ -1215,7 +1508,7 @@ func authenticatedConnectionReadLoop(
 	}
 
 	skipInternal := func(n uint32) bool {
-		r, err := io.Copy(io.Discard, io.LimitReader(conn, int64(n)))
+		r, err := io.Copy(io.Discard, io.LimitReader(r, int64(n)))
 		if err != nil || r != int64(n) {
 			logger.Warn("Error reading from connection", commontypes.LogFields{"error": err})
 			return false
@@ -1242,6 +1535,9 @@ func authenticatedConnectionReadLoop(
 		})
 	}
 
+	// We taper logs about pings that arrive faster than we answer them.
+	pingsDroppedTaper := loghelper.LogarithmicTaper{}
+
 	// We keep track of the number of open & close frames that we have received.
 	openCloseFramesReceived := 0
 	const maxOpenCloseFramesReceived = 2 * MaxStreamsPerPeer
@@ -1249,25 +1545,42 @@ func authenticatedConnectionReadLoop(
 	rawHeader := make([]byte, frameHeaderEncodedSize)
 
 	for {
-		if !readInternal(rawHeader) {
-			return
+		if dataLane {
+			// The remote may close a data lane between two frames
+			if _, err := io.ReadFull(r, rawHeader); err != nil {
+				if errors.Is(err, io.EOF) {
+					return true
+				}
+				logger.Warn("Error reading from connection", commontypes.LogFields{"error": err})
+				return false
+			}
+		} else if !readInternal(rawHeader) {
+			return false
 		}
 
 		header, err := decodeFrameHeader(rawHeader)
 		if err != nil {
 			logger.Warn("Error decoding header", commontypes.LogFields{"error": err})
-			return
+			reportMisbehavior(MisbehaviorMalformedFrame)
+			return false
+		}
+
+		if dataLane && header.Type != frameTypeData {
+			logWithHeader(header).Warn("authenticatedConnectionReadLoop: received non-data frame on data lane, closing connection", nil)
+			reportMisbehavior(MisbehaviorMalformedFrame)
+			return false
 		}
 
 		switch header.Type {
 		case frameTypeOpen:
 			openCloseFramesReceived++
 			if header.PayloadLength == 0 || header.PayloadLength > MaxStreamNameLength {
-				return
+				reportMisbehavior(MisbehaviorMalformedFrame)
+				return false
 			}
 			streamName := make([]byte, header.PayloadLength)
 			if !readInternal(streamName) {
-				return
+				return false
 			}
 			remoteStreamNameByID[header.StreamID] = string(streamName)
 			select {
@@ -1277,13 +1590,14 @@ func authenticatedConnectionReadLoop(
 				true,
 			}:
 			case <-ctx.Done():
-				return
+				return false
 			}
 		case frameTypeClose:
 			openCloseFramesReceived++
 			if header.PayloadLength != 0 {
 				logWithHeader(header).Warn("Frame close payload length is not zero", nil)
-				return
+				reportMisbehavior(MisbehaviorMalformedFrame)
+				return false
 			}
 			delete(remoteStreamNameByID, header.StreamID)
 			select {
@@ -1293,7 +1607,32 @@ func authenticatedConnectionReadLoop(
 				false,
 			}:
 			case <-ctx.Done():
-				return
+				return false
+			}
+		case frameTypePing, frameTypePong:
+			if header.PayloadLength != pingPayloadSize || header.StreamID != (streamID{}) {
+				logWithHeader(header).Warn("authenticatedConnectionReadLoop: malformed ping or pong frame, closing connection", nil)
+				reportMisbehavior(MisbehaviorMalformedFrame)
+				return false
+			}
+			payload := make([]byte, pingPayloadSize)
+			if !readInternal(payload) {
+				return false
+			}
+			if header.Type == frameTypePong {
+				health.pongReceived(payload)
+				break
+			}
+			select {
+			case chPongs <- payload:
+			default:
+				// The remote is pinging faster than we can answer, there's no
+				// point in queueing more pongs.
+				pingsDroppedTaper.Trigger(func(count uint64) {
+					logger.Debug("authenticatedConnectionReadLoop: dropping ping, previous one has not been answered yet", commontypes.LogFields{
+						"pingsDroppedCount": count,
+					})
+				})
 			}
 		case frameTypeData:
 			if MaxMessageLength < header.PayloadLength {
@@ -1301,7 +1640,8 @@ func authenticatedConnectionReadLoop(
 					"payloadLength":           header.PayloadLength,
 					"ragep2pMaxMessageLength": MaxMessageLength,
 				})
-				return
+				reportMisbehavior(MisbehaviorMalformedFrame)
+				return false
 			}
 			// Cast to int is safe since header.PayloadLength <= MaxMessageLength <= INT_MAX
 			switch demux.ShouldPush(header.StreamID, int(header.PayloadLength)) {
@@ -1309,7 +1649,8 @@ func authenticatedConnectionReadLoop(
 				logWithHeader(header).Warn("authenticatedConnectionReadLoop: message too big, closing connection", commontypes.LogFields{
 					"payloadLength": header.PayloadLength,
 				})
-				return
+				reportMisbehavior(MisbehaviorMalformedFrame)
+				return false
 			case shouldPushResultMessagesLimitExceeded:
 				limitsExceededTaper.Trigger(func(count uint64) {
 					logWithHeader(header).Warn("authenticatedConnectionReadLoop: message limit exceeded, dropping message", commontypes.LogFields{
@@ -1317,7 +1658,11 @@ func authenticatedConnectionReadLoop(
 					})
 				})
 				if !skipInternal(header.PayloadLength) {
-					return
+					return false
+				}
+				if reportMisbehavior(MisbehaviorStreamRateLimitExceeded) {
+					logWithHeader(header).Warn("authenticatedConnectionReadLoop: connection policy requested disconnect, closing connection", nil)
+					return false
 				}
 			case shouldPushResultBytesLimitExceeded:
 				limitsExceededTaper.Trigger(func(count uint64) {
@@ -1326,7 +1671,11 @@ func authenticatedConnectionReadLoop(
 					})
 				})
 				if !skipInternal(header.PayloadLength) {
-					return
+					return false
+				}
+				if reportMisbehavior(MisbehaviorStreamRateLimitExceeded) {
+					logWithHeader(header).Warn("authenticatedConnectionReadLoop: connection policy requested disconnect, closing connection", nil)
+					return false
 				}
 			case shouldPushResultUnknownStream:
 				unknownStreamIDTaper.Trigger(func(count uint64) {
@@ -1335,7 +1684,7 @@ func authenticatedConnectionReadLoop(
 					})
 				})
 				if !skipInternal(header.PayloadLength) {
-					return
+					return false
 				}
 			case shouldPushResultYes:
 				limitsExceededTaper.Reset(func(oldCount uint64) {
@@ -1345,7 +1694,7 @@ func authenticatedConnectionReadLoop(
 				})
 				data := make([]byte, header.PayloadLength)
 				if !readInternal(data) {
-					return
+					return false
 				}
 				switch demux.PushMessage(header.StreamID, data) {
 				case pushResultSuccess:
@@ -1366,37 +1715,95 @@ func authenticatedConnectionReadLoop(
 			logWithHeader(header).Warn("authenticatedConnectionReadLoop: peer received too many open/close frames, closing connection", commontypes.LogFields{
 				"maxOpenCloseFramesReceived": maxOpenCloseFramesReceived,
 			})
-			return
+			reportMisbehavior(MisbehaviorMalformedFrame)
+			return false
 		}
 	}
 }
 
+// Maximum number of messages queued for a single data lane. If a data lane
+// can't keep up, we drop the oldest messages, just like the demuxer does on
+// the receiving side.
+const dataLaneQueueLength = 64
+
 func authenticatedConnectionWriteLoop(
 	ctx context.Context,
-	conn net.Conn,
+	aconn AuthenticatedConn,
 	chSelfStreamStateNotification <-chan streamStateNotification,
 	chWriteData <-chan streamIDAndData,
+	chPongs <-chan []byte,
+	pingInterval time.Duration,
+	health *peerHealth,
 	chWriteTerminated chan<- struct{},
 	logger loghelper.LoggerWithContext,
 ) {
-	writeInternal := func(buf []byte) bool {
-		_, err := conn.Write(buf)
-		if err != nil {
-			logger.Warn("Error writing to connection", commontypes.LogFields{"error": err})
+	control := aconn.ControlLane()
+
+	var chPingTicker <-chan time.Time
+	if pingInterval > 0 {
+		pingTicker := time.NewTicker(pingInterval)
+		defer pingTicker.Stop()
+		chPingTicker = pingTicker.C
+	}
+
+	var terminateOnce sync.Once
+	terminate := func() {
+		terminateOnce.Do(func() {
 			// shut everything down
-			if err := safeClose(conn); err != nil {
+			if err := aconn.Close(); err != nil {
 				logger.Warn("Failed to close connection", commontypes.LogFields{"error": err})
 			}
 			close(chWriteTerminated)
+		})
+	}
+
+	writeInternal := func(buf []byte) bool {
+		_, err := control.Write(buf)
+		if err != nil {
+			logger.Warn("Error writing to connection", commontypes.LogFields{"error": err})
+			terminate()
 			return false
 		}
 		return true
 	}
 
+	// Only used if the transport supports data lanes
+	var dataLaneSubs subprocesses.Subprocesses
+	defer dataLaneSubs.Wait()
+	dataLanes := make(map[streamID]chan []byte)
+	defer func() {
+		for _, chData := range dataLanes {
+			close(chData)
+		}
+	}()
+
 	for {
 		select {
 		case data := <-chWriteData:
-			if err := conn.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+			if aconn.SupportsDataLanes() {
+				chData, ok := dataLanes[data.StreamID]
+				if !ok {
+					chData = make(chan []byte, dataLaneQueueLength)
+					dataLanes[data.StreamID] = chData
+					sid := data.StreamID
+					dataLaneSubs.Go(func() {
+						dataLaneWriteLoop(ctx, aconn, sid, chData, terminate, logger)
+					})
+				}
+				select {
+				case chData <- data.Data:
+				default:
+					// drop the oldest message to make room
+					select {
+					case <-chData:
+					default:
+					}
+					chData <- data.Data
+				}
+				continue
+			}
+
+			if err := control.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
 				logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err})
 				return
 			}
@@ -1412,7 +1819,14 @@ func authenticatedConnectionWriteLoop(
 				return
 			}
 		case notification := <-chSelfStreamStateNotification:
-			if err := conn.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+			if !notification.open {
+				if chData, ok := dataLanes[notification.streamID]; ok {
+					close(chData)
+					delete(dataLanes, notification.streamID)
+				}
+			}
+
+			if err := control.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
 				logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err})
 				return
 			}
@@ -1438,25 +1852,98 @@ func authenticatedConnectionWriteLoop(
 				return
 			}
 
+		case payload := <-chPongs:
+			if err := control.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+				logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err})
+				return
+			}
+			header := frameHeader{frameTypePong, streamID{}, pingPayloadSize}
+			if !writeInternal(header.Encode()) || !writeInternal(payload) {
+				return
+			}
+
+		case <-chPingTicker:
+			if err := control.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+				logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err})
+				return
+			}
+			header := frameHeader{frameTypePing, streamID{}, pingPayloadSize}
+			if !writeInternal(header.Encode()) || !writeInternal(health.pingSent()) {
+				return
+			}
+
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// gotta be careful about closing tls connections to make sure we don't get
-// tarpitted
-func safeClose(conn net.Conn) error {
-	// This isn't needed in more recent versions of go, but better safe than sorry!
-	errDeadline := conn.SetWriteDeadline(time.Now().Add(netTimeout))
-	errClose := conn.Close()
-	if errClose != nil {
-		return errClose
+// dataLaneWriteLoop writes the messages of a single stream to a dedicated data
+// lane, which is opened upon the first message. It returns once chData is
+// closed and drained.
+func dataLaneWriteLoop(
+	ctx context.Context,
+	aconn AuthenticatedConn,
+	sid streamID,
+	chData <-chan []byte,
+	terminate func(),
+	logger loghelper.LoggerWithContext,
+) {
+	var lane SendLane
+	defer func() {
+		if lane != nil {
+			if err := lane.Close(); err != nil {
+				logger.Debug("Failed to close data lane", commontypes.LogFields{"error": err, "streamID": sid})
+			}
+		}
+	}()
+
+	write := func(buf []byte) bool {
+		if _, err := lane.Write(buf); err != nil {
+			logger.Warn("Error writing to data lane", commontypes.LogFields{"error": err, "streamID": sid})
+			terminate()
+			return false
+		}
+		return true
 	}
-	if errDeadline != nil {
-		return errDeadline
+
+	for {
+		select {
+		case data, ok := <-chData:
+			if !ok {
+				return
+			}
+			if lane == nil {
+				openCtx, openCancel := context.WithTimeout(ctx, netTimeout)
+				var err error
+				lane, err = aconn.OpenDataLane(openCtx)
+				openCancel()
+				if err != nil {
+					logger.Warn("Error opening data lane", commontypes.LogFields{"error": err, "streamID": sid})
+					terminate()
+					return
+				}
+			}
+			if err := lane.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+				logger.Warn("Closing connection, error during SetWriteDeadline", commontypes.LogFields{"error": err, "streamID": sid})
+				terminate()
+				return
+			}
+			header := frameHeader{
+				frameTypeData,
+				sid,
+				uint32(len(data)),
+			}
+			if !write(header.Encode()) {
+				return
+			}
+			if !write(data) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
-	return nil
 }
 
 func incomingConnsRateLimit(durationBetweenDials time.Duration) ratelimit.MillitokensPerSecond {
@@ -0,0 +1,236 @@
+package ragep2p
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/knock"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/mtls"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+// A Transport establishes authenticated connections between Hosts. The
+// default transport is TCP with TLS 1.3 (see NewTCPTransport).
+//
+// Regardless of the transport, connections are authenticated the same way:
+// the dialing peer sends a knock, and both peers prove their identity through
+// mutual TLS 1.3 with certificates for the keys corresponding to their
+// PeerIDs. Transports use a Handshaker provided by the Host for this.
+type Transport interface {
+	// Listen listens for incoming connections on address.
+	Listen(address string, handshaker Handshaker) (TransportListener, error)
+	// Dial initiates an outgoing connection to the peer other at address.
+	Dial(ctx context.Context, address string, other types.PeerID, handshaker Handshaker) (TransportConn, error)
+}
+
+type TransportListener interface {
+	// Accept blocks until the next incoming connection arrives. An error
+	// indicates that the listener won't accept any further connections.
+	Accept() (TransportConn, error)
+	Close() error
+}
+
+// A TransportConn is a connection that has not been authenticated yet.
+type TransportConn interface {
+	RemoteAddr() net.Addr
+	// Handshake authenticates the connection and must be called at most once.
+	// On success, the returned AuthenticatedConn owns the connection.
+	// Otherwise, the caller must Close the TransportConn.
+	Handshake(ctx context.Context) (AuthenticatedConn, error)
+	Close() error
+}
+
+// A SendLane is the sending end of a reliable, ordered byte stream within an
+// AuthenticatedConn.
+type SendLane interface {
+	io.WriteCloser
+	SetWriteDeadline(t time.Time) error
+}
+
+// A Lane is a bidirectional SendLane.
+type Lane interface {
+	io.Reader
+	SendLane
+}
+
+// An AuthenticatedConn is a connection with a peer whose identity has been
+// verified.
+//
+// Every AuthenticatedConn has a bidirectional control lane. If the transport
+// supports independent data lanes, such as QUIC, the Host sends the messages
+// of each Stream on a separate unidirectional data lane, so that a slow Stream
+// cannot hold up the messages of other Streams. Otherwise, the Host sends all
+// messages on the control lane.
+//
+// Reads from lanes of an AuthenticatedConn must be subject to the ConnLimiter
+// of the remote peer.
+type AuthenticatedConn interface {
+	// Other returns the PeerID of the authenticated remote peer.
+	Other() types.PeerID
+	ControlLane() Lane
+	SupportsDataLanes() bool
+	// OpenDataLane and AcceptDataLane are only called if SupportsDataLanes
+	// returns true.
+	OpenDataLane(ctx context.Context) (SendLane, error)
+	AcceptDataLane(ctx context.Context) (io.Reader, error)
+	Close() error
+}
+
+// A ConnLimiter limits the number of bytes a remote peer may send us.
+type ConnLimiter interface {
+	// Allow reports whether n more bytes may be received.
+	Allow(n int) bool
+}
+
+// A Handshaker is provided by a Host to a Transport and carries out the parts
+// of the ragep2p handshake that depend on the Host's identity and peers.
+type Handshaker interface {
+	// Self returns the Host's PeerID.
+	Self() types.PeerID
+	Logger() commontypes.Logger
+	// Knock returns the knock to send when dialing other.
+	Knock(other types.PeerID) []byte
+	// VerifyKnock verifies a knock received on an incoming connection from
+	// remoteAddr and returns the dialing peer together with the limiter for
+	// its connections. Fails if the knock is invalid, the peer is unknown or
+	// the connection is denied by the Host's ConnectionPolicy.
+	VerifyKnock(remoteAddr net.Addr, knock []byte) (types.PeerID, ConnLimiter, error)
+	// Limiter returns the limiter for connections with other. Fails if other
+	// is unknown.
+	Limiter(other types.PeerID) (ConnLimiter, error)
+	// TLSConfig returns the TLS config for connections with other. If other
+	// is nil, any peer that the Host knows is accepted. In that case, the
+	// transport must check that the peer is the one that knocked.
+	TLSConfig(other *types.PeerID) *tls.Config
+}
+
+// PeerIDFromTLSConnectionState returns the PeerID of the remote peer of a
+// completed ragep2p TLS handshake.
+func PeerIDFromTLSConnectionState(state tls.ConnectionState) (types.PeerID, error) {
+	if len(state.PeerCertificates) != 1 {
+		return types.PeerID{}, fmt.Errorf("expected exactly one peer certificate, got %v", len(state.PeerCertificates))
+	}
+	pubKey, err := mtls.PubKeyFromCert(state.PeerCertificates[0])
+	if err != nil {
+		return types.PeerID{}, err
+	}
+	return types.PeerID(pubKey), nil
+}
+
+// hostHandshaker implements Handshaker for a Host.
+type hostHandshaker struct {
+	host *Host
+}
+
+var _ Handshaker = hostHandshaker{}
+
+func (hs hostHandshaker) Self() types.PeerID {
+	return hs.host.id
+}
+
+func (hs hostHandshaker) Logger() commontypes.Logger {
+	return hs.host.logger
+}
+
+func (hs hostHandshaker) Knock(other types.PeerID) []byte {
+	return knock.BuildKnock(other, hs.host.id, hs.host.secretKey)
+}
+
+func (hs hostHandshaker) VerifyKnock(remoteAddr net.Addr, knck []byte) (types.PeerID, ConnLimiter, error) {
+	other, err := knock.VerifyKnock(hs.host.id, knck)
+	if err != nil {
+		return types.PeerID{}, nil, err
+	}
+	limiter, err := hs.Limiter(*other)
+	if err != nil {
+		return types.PeerID{}, nil, err
+	}
+	if err := hs.host.connectionPolicy.AllowIncoming(*other, remoteAddr); err != nil {
+		return types.PeerID{}, nil, errConnectionDenied{*other, err}
+	}
+	return *other, limiter, nil
+}
+
+func (hs hostHandshaker) Limiter(other types.PeerID) (ConnLimiter, error) {
+	hs.host.peersMu.Lock()
+	peer, ok := hs.host.peers[other]
+	hs.host.peersMu.Unlock()
+	if !ok {
+		return nil, errUnknownPeer{other}
+	}
+	return misbehaviorReportingConnLimiter{peer.connRateLimiter, hs.host.connectionPolicy, other}, nil
+}
+
+// misbehaviorReportingConnLimiter reports to the ConnectionPolicy whenever a
+// peer exceeds its connection rate limit.
+type misbehaviorReportingConnLimiter struct {
+	limiter ConnLimiter
+	policy  ConnectionPolicy
+	other   types.PeerID
+}
+
+func (l misbehaviorReportingConnLimiter) Allow(n int) bool {
+	if l.limiter.Allow(n) {
+		return true
+	}
+	l.policy.ReportMisbehavior(l.other, MisbehaviorConnRateLimitExceeded)
+	return false
+}
+
+func (hs hostHandshaker) TLSConfig(other *types.PeerID) *tls.Config {
+	if other != nil {
+		return newTLSConfig(hs.host.tlsCert, mtls.VerifyCertMatchesPubKey(*other))
+	}
+	return newTLSConfig(hs.host.tlsCert, func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		pubKey, err := pubKeyFromRawCerts(rawCerts)
+		if err != nil {
+			return err
+		}
+		if _, err := hs.Limiter(pubKey); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+type errUnknownPeer struct {
+	other types.PeerID
+}
+
+func (e errUnknownPeer) Error() string {
+	return fmt.Sprintf("unknown peer %v", e.other)
+}
+
+type errConnectionDenied struct {
+	other types.PeerID
+	err   error
+}
+
+func (e errConnectionDenied) Error() string {
+	return fmt.Sprintf("connection from peer %v denied by connection policy: %v", e.other, e.err)
+}
+
+func (e errConnectionDenied) Unwrap() error {
+	return e.err
+}
+
+func pubKeyFromRawCerts(rawCerts [][]byte) (types.PeerID, error) {
+	if len(rawCerts) != 1 {
+		return types.PeerID{}, fmt.Errorf("required exactly one client certificate")
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return types.PeerID{}, err
+	}
+	pubKey, err := mtls.PubKeyFromCert(cert)
+	if err != nil {
+		return types.PeerID{}, err
+	}
+	return types.PeerID(pubKey), nil
+}
@@ -0,0 +1,96 @@
+package ragep2p
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+// InMemoryTransport connects Hosts within the same process without touching
+// the network, which makes it well suited for tests with many Hosts. All
+// Hosts that want to talk to each other must use the same InMemoryTransport.
+// Addresses are arbitrary strings.
+//
+// Connections are authenticated exactly like with TCP.
+type InMemoryTransport struct {
+	mutex     sync.Mutex
+	listeners map[string]*inMemoryListener
+}
+
+var _ Transport = (*InMemoryTransport)(nil)
+
+func NewInMemoryTransport() *InMemoryTransport {
+	return &InMemoryTransport{
+		sync.Mutex{},
+		make(map[string]*inMemoryListener),
+	}
+}
+
+func (t *InMemoryTransport) Listen(address string, handshaker Handshaker) (TransportListener, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.listeners[address]; ok {
+		return nil, fmt.Errorf("address %q is already in use", address)
+	}
+	l := &inMemoryListener{
+		t,
+		address,
+		handshaker,
+		make(chan net.Conn),
+		make(chan struct{}),
+		sync.Once{},
+	}
+	t.listeners[address] = l
+	return l, nil
+}
+
+func (t *InMemoryTransport) Dial(ctx context.Context, address string, other types.PeerID, handshaker Handshaker) (TransportConn, error) {
+	t.mutex.Lock()
+	l, ok := t.listeners[address]
+	t.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("connection to %q refused", address)
+	}
+
+	local, remote := net.Pipe()
+	select {
+	case l.chConns <- remote:
+		return &streamTransportConn{local, handshaker, &other}, nil
+	case <-l.chClose:
+		return nil, fmt.Errorf("connection to %q refused", address)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type inMemoryListener struct {
+	transport  *InMemoryTransport
+	address    string
+	handshaker Handshaker
+	chConns    chan net.Conn
+	chClose    chan struct{}
+	closeOnce  sync.Once
+}
+
+func (l *inMemoryListener) Accept() (TransportConn, error) {
+	select {
+	case conn := <-l.chConns:
+		return &streamTransportConn{conn, l.handshaker, nil}, nil
+	case <-l.chClose:
+		return nil, fmt.Errorf("listener on %q closed", l.address)
+	}
+}
+
+func (l *inMemoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.transport.mutex.Lock()
+		delete(l.transport.listeners, l.address)
+		l.transport.mutex.Unlock()
+		close(l.chClose)
+	})
+	return nil
+}
@@ -0,0 +1,264 @@
+package ragep2p
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/knock"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+const quicALPN = "ragep2p"
+
+type quicTransport struct{}
+
+// NewQUICTransport returns a Transport that uses QUIC. Addresses are UDP
+// addresses of the form <host>:<port>.
+//
+// Unlike with TCP, every Stream gets its own QUIC stream, so that a Stream
+// with a large backlog doesn't hold up the messages of other Streams.
+//
+// Since QUIC always starts with the TLS handshake, the knock is sent after the
+// handshake, on the control lane. This means that a QUIC listener reveals
+// itself to anybody that initiates a handshake, before having received a
+// valid knock. Peers that the Host doesn't know are still rejected during the
+// handshake.
+func NewQUICTransport() Transport {
+	return quicTransport{}
+}
+
+func quicConfig() *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout: netTimeout,
+		MaxIdleTimeout:       6 * netTimeout,
+		KeepAlivePeriod:      2 * netTimeout,
+		// the control lane
+		MaxIncomingStreams: 1,
+		// one data lane per Stream
+		MaxIncomingUniStreams: MaxStreamsPerPeer,
+	}
+}
+
+func (quicTransport) Listen(address string, handshaker Handshaker) (TransportListener, error) {
+	tlsConfig := handshaker.TLSConfig(nil)
+	tlsConfig.NextProtos = []string{quicALPN}
+	ln, err := quic.ListenAddr(address, tlsConfig, quicConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &quicListener{ln, handshaker}, nil
+}
+
+func (quicTransport) Dial(ctx context.Context, address string, other types.PeerID, handshaker Handshaker) (TransportConn, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	// QUIC dials and handshakes in one go, so we postpone the dial to
+	// Handshake.
+	return &quicOutgoingConn{udpAddr, other, handshaker}, nil
+}
+
+type quicListener struct {
+	ln         *quic.Listener
+	handshaker Handshaker
+}
+
+func (l *quicListener) Accept() (TransportConn, error) {
+	conn, err := l.ln.Accept(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &quicIncomingConn{conn, l.handshaker}, nil
+}
+
+func (l *quicListener) Close() error {
+	return l.ln.Close()
+}
+
+type quicOutgoingConn struct {
+	addr       *net.UDPAddr
+	other      types.PeerID
+	handshaker Handshaker
+}
+
+func (c *quicOutgoingConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *quicOutgoingConn) Handshake(ctx context.Context) (AuthenticatedConn, error) {
+	tlsConfig := c.handshaker.TLSConfig(&c.other)
+	tlsConfig.NextProtos = []string{quicALPN}
+	conn, err := quic.DialAddr(ctx, c.addr.String(), tlsConfig, quicConfig())
+	if err != nil {
+		return nil, fmt.Errorf("error during QUIC handshake: %w", err)
+	}
+
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			_ = conn.CloseWithError(0, "")
+		}
+	}()
+
+	if err := checkQUICPeerID(conn, c.other); err != nil {
+		return nil, err
+	}
+
+	limiter, err := c.handshaker.Limiter(c.other)
+	if err != nil {
+		return nil, err
+	}
+
+	control, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening control lane: %w", err)
+	}
+	if err := control.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+		return nil, fmt.Errorf("error during SetWriteDeadline: %w", err)
+	}
+	if _, err := control.Write(c.handshaker.Knock(c.other)); err != nil {
+		return nil, fmt.Errorf("error while sending knock: %w", err)
+	}
+
+	succeeded = true
+	return &quicAuthenticatedConn{conn, newQUICLane(control, limiter, conn, c.handshaker.Logger()), limiter, c.handshaker.Logger(), c.other}, nil
+}
+
+// Nothing to close, the connection is only established in Handshake.
+func (c *quicOutgoingConn) Close() error {
+	return nil
+}
+
+type quicIncomingConn struct {
+	conn       quic.Connection
+	handshaker Handshaker
+}
+
+func (c *quicIncomingConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *quicIncomingConn) Handshake(ctx context.Context) (AuthenticatedConn, error) {
+	control, err := c.conn.AcceptStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while accepting control lane: %w", err)
+	}
+
+	knck := make([]byte, knock.KnockSize)
+	if err := control.SetReadDeadline(time.Now().Add(netTimeout)); err != nil {
+		return nil, fmt.Errorf("error during SetReadDeadline: %w", err)
+	}
+	if _, err := io.ReadFull(control, knck); err != nil {
+		return nil, fmt.Errorf("error while reading knock: %w", err)
+	}
+	if err := control.SetReadDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("error during SetReadDeadline: %w", err)
+	}
+
+	other, limiter, err := c.handshaker.VerifyKnock(c.conn.RemoteAddr(), knck)
+	if err != nil {
+		return nil, err
+	}
+	// The TLS config only checked that the remote is some peer we know. Make
+	// sure it's the one that knocked.
+	if err := checkQUICPeerID(c.conn, other); err != nil {
+		return nil, err
+	}
+
+	return &quicAuthenticatedConn{c.conn, newQUICLane(control, limiter, c.conn, c.handshaker.Logger()), limiter, c.handshaker.Logger(), other}, nil
+}
+
+func (c *quicIncomingConn) Close() error {
+	return c.conn.CloseWithError(0, "")
+}
+
+func checkQUICPeerID(conn quic.Connection, expected types.PeerID) error {
+	actual, err := PeerIDFromTLSConnectionState(conn.ConnectionState().TLS)
+	if err != nil {
+		return fmt.Errorf("error getting public key: %w", err)
+	}
+	if actual != expected {
+		return fmt.Errorf("TLS handshake PeerID mismatch, expected %v, actual %v", expected, actual)
+	}
+	return nil
+}
+
+type quicAuthenticatedConn struct {
+	conn    quic.Connection
+	control *quicLane
+	limiter ConnLimiter
+	logger  commontypes.Logger
+	other   types.PeerID
+}
+
+var _ AuthenticatedConn = (*quicAuthenticatedConn)(nil)
+
+func (c *quicAuthenticatedConn) Other() types.PeerID {
+	return c.other
+}
+
+func (c *quicAuthenticatedConn) ControlLane() Lane {
+	return c.control
+}
+
+func (c *quicAuthenticatedConn) SupportsDataLanes() bool {
+	return true
+}
+
+func (c *quicAuthenticatedConn) OpenDataLane(ctx context.Context) (SendLane, error) {
+	return c.conn.OpenUniStreamSync(ctx)
+}
+
+func (c *quicAuthenticatedConn) AcceptDataLane(ctx context.Context) (io.Reader, error) {
+	stream, err := c.conn.AcceptUniStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedReader{stream, c.limiter, c.conn, c.logger}, nil
+}
+
+func (c *quicAuthenticatedConn) Close() error {
+	return c.conn.CloseWithError(0, "")
+}
+
+type quicLane struct {
+	quic.Stream
+	reader *rateLimitedReader
+}
+
+func newQUICLane(stream quic.Stream, limiter ConnLimiter, conn quic.Connection, logger commontypes.Logger) *quicLane {
+	return &quicLane{stream, &rateLimitedReader{stream, limiter, conn, logger}}
+}
+
+func (l *quicLane) Read(b []byte) (int, error) {
+	return l.reader.Read(b)
+}
+
+// rateLimitedReader closes the whole connection if the remote exceeds its
+// limits, just like ratelimitedconn does for TCP.
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter ConnLimiter
+	conn    quic.Connection
+	logger  commontypes.Logger
+}
+
+func (r *rateLimitedReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if !r.limiter.Allow(n) {
+		_ = r.conn.CloseWithError(0, "")
+		r.logger.Error("inbound data exceeded rate limit, connection closed", commontypes.LogFields{
+			"bytesRead": n,
+			"readError": err,
+		})
+		return 0, fmt.Errorf("inbound data exceeded rate limit, connection closed")
+	}
+	return n, err
+}
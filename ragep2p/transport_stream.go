@@ -0,0 +1,153 @@
+package ragep2p
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/smartcontractkit/libocr/ragep2p/internal/knock"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/ratelimitedconn"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+// streamTransportConn implements TransportConn for transports that provide a
+// single byte stream per connection, like TCP. The knock is sent in the clear
+// at the start of the stream, followed by a TLS handshake. All traffic is
+// carried on the resulting TLS connection.
+type streamTransportConn struct {
+	conn       net.Conn
+	handshaker Handshaker
+	// nil for incoming connections
+	other *types.PeerID
+}
+
+var _ TransportConn = (*streamTransportConn)(nil)
+
+func (c *streamTransportConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *streamTransportConn) Close() error {
+	return safeClose(c.conn)
+}
+
+func (c *streamTransportConn) Handshake(ctx context.Context) (AuthenticatedConn, error) {
+	var other types.PeerID
+	var limiter ConnLimiter
+	if c.other != nil {
+		other = *c.other
+
+		knck := c.handshaker.Knock(other)
+		if err := c.conn.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+			return nil, fmt.Errorf("error during SetWriteDeadline: %w", err)
+		}
+		if _, err := c.conn.Write(knck); err != nil {
+			return nil, fmt.Errorf("error while sending knock: %w", err)
+		}
+
+		var err error
+		limiter, err = c.handshaker.Limiter(other)
+		if err != nil {
+			// peer must have been deleted in the time between the dial being
+			// started and now
+			return nil, err
+		}
+	} else {
+		knck := make([]byte, knock.KnockSize)
+		if err := c.conn.SetReadDeadline(time.Now().Add(netTimeout)); err != nil {
+			return nil, fmt.Errorf("error during SetReadDeadline: %w", err)
+		}
+		if _, err := io.ReadFull(c.conn, knck); err != nil {
+			return nil, fmt.Errorf("error while reading knock: %w", err)
+		}
+
+		var err error
+		other, limiter, err = c.handshaker.VerifyKnock(c.conn.RemoteAddr(), knck)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rlConn := ratelimitedconn.NewRateLimitedConn(c.conn, limiter, c.handshaker.Logger())
+	tlsConfig := c.handshaker.TLSConfig(&other)
+	var tlsConn *tls.Conn
+	if c.other != nil {
+		tlsConn = tls.Client(rlConn, tlsConfig)
+	} else {
+		tlsConn = tls.Server(rlConn, tlsConfig)
+	}
+
+	// Handshake reads and write to the connection. Set a deadline to prevent tarpitting
+	if err := tlsConn.SetDeadline(time.Now().Add(netTimeout)); err != nil {
+		return nil, fmt.Errorf("error during SetDeadline: %w", err)
+	}
+	// Perform handshake so that we know the public key
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("error during TLS handshake: %w", err)
+	}
+	// Disable deadline. Whoever uses the connection next will have to set their own timeouts.
+	if err := tlsConn.SetDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("error during SetDeadline: %w", err)
+	}
+
+	actual, err := PeerIDFromTLSConnectionState(tlsConn.ConnectionState())
+	if err != nil {
+		return nil, fmt.Errorf("error getting public key: %w", err)
+	}
+	if actual != other {
+		return nil, fmt.Errorf("TLS handshake PeerID mismatch, expected %v, actual %v", other, actual)
+	}
+
+	rlConn.EnableRateLimiting()
+
+	return &streamAuthenticatedConn{tlsConn, other}, nil
+}
+
+type streamAuthenticatedConn struct {
+	tlsConn *tls.Conn
+	other   types.PeerID
+}
+
+var _ AuthenticatedConn = (*streamAuthenticatedConn)(nil)
+
+func (c *streamAuthenticatedConn) Other() types.PeerID {
+	return c.other
+}
+
+func (c *streamAuthenticatedConn) ControlLane() Lane {
+	return c.tlsConn
+}
+
+func (c *streamAuthenticatedConn) SupportsDataLanes() bool {
+	return false
+}
+
+func (c *streamAuthenticatedConn) OpenDataLane(context.Context) (SendLane, error) {
+	return nil, fmt.Errorf("data lanes are not supported")
+}
+
+func (c *streamAuthenticatedConn) AcceptDataLane(context.Context) (io.Reader, error) {
+	return nil, fmt.Errorf("data lanes are not supported")
+}
+
+func (c *streamAuthenticatedConn) Close() error {
+	return safeClose(c.tlsConn)
+}
+
+// gotta be careful about closing tls connections to make sure we don't get
+// tarpitted
+func safeClose(conn net.Conn) error {
+	// This isn't needed in more recent versions of go, but better safe than sorry!
+	errDeadline := conn.SetWriteDeadline(time.Now().Add(netTimeout))
+	errClose := conn.Close()
+	if errClose != nil {
+		return errClose
+	}
+	if errDeadline != nil {
+		return errDeadline
+	}
+	return nil
+}
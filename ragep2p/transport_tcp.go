@@ -0,0 +1,58 @@
+package ragep2p
+
+import (
+	"context"
+	"net"
+
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type tcpTransport struct {
+	proxy ProxyConfig
+}
+
+// NewTCPTransport returns the default Transport, which uses TCP connections
+// secured with TLS 1.3. Addresses are of the form <host>:<port>.
+func NewTCPTransport() Transport {
+	return tcpTransport{}
+}
+
+func (tcpTransport) Listen(address string, handshaker Handshaker) (TransportListener, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpListener{ln, handshaker}, nil
+}
+
+func (t tcpTransport) Dial(ctx context.Context, address string, other types.PeerID, handshaker Handshaker) (TransportConn, error) {
+	var conn net.Conn
+	var err error
+	if p := t.proxy.proxyFor(other); p != nil {
+		conn, err = dialThroughProxy(ctx, p, address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &streamTransportConn{conn, handshaker, &other}, nil
+}
+
+type tcpListener struct {
+	ln         net.Listener
+	handshaker Handshaker
+}
+
+func (l *tcpListener) Accept() (TransportConn, error) {
+	conn, err := l.ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &streamTransportConn{conn, l.handshaker, nil}, nil
+}
+
+func (l *tcpListener) Close() error {
+	return l.ln.Close()
+}
@@ -0,0 +1,607 @@
+package ragep2p
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/internal/loghelper"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/knock"
+	"github.com/smartcontractkit/libocr/ragep2p/internal/mtls"
+	"github.com/smartcontractkit/libocr/ragep2p/types"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Trace(string, commontypes.LogFields)    {}
+func (nopLogger) Debug(string, commontypes.LogFields)    {}
+func (nopLogger) Info(string, commontypes.LogFields)     {}
+func (nopLogger) Warn(string, commontypes.LogFields)     {}
+func (nopLogger) Error(string, commontypes.LogFields)    {}
+func (nopLogger) Critical(string, commontypes.LogFields) {}
+
+type allowAllConnLimiter struct{}
+
+func (allowAllConnLimiter) Allow(int) bool { return true }
+
+// testHandshaker is a Handshaker for a peer that knows a fixed set of other
+// peers.
+type testHandshaker struct {
+	id        types.PeerID
+	secretKey ed25519.PrivateKey
+	cert      tls.Certificate
+	known     map[types.PeerID]struct{}
+	// if set, sent instead of the peer's own knock
+	knock []byte
+}
+
+var _ Handshaker = (*testHandshaker)(nil)
+
+func newTestHandshaker(t *testing.T) *testHandshaker {
+	_, secretKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := types.PeerIDFromPrivateKey(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testHandshaker{
+		id,
+		secretKey,
+		mtls.NewMinimalX509CertFromPrivateKey(secretKey),
+		map[types.PeerID]struct{}{},
+		nil,
+	}
+}
+
+func (hs *testHandshaker) know(others ...*testHandshaker) {
+	for _, other := range others {
+		hs.known[other.id] = struct{}{}
+	}
+}
+
+func (hs *testHandshaker) Self() types.PeerID {
+	return hs.id
+}
+
+func (hs *testHandshaker) Logger() commontypes.Logger {
+	return nopLogger{}
+}
+
+func (hs *testHandshaker) Knock(other types.PeerID) []byte {
+	if hs.knock != nil {
+		return hs.knock
+	}
+	return knock.BuildKnock(other, hs.id, hs.secretKey)
+}
+
+func (hs *testHandshaker) VerifyKnock(remoteAddr net.Addr, knck []byte) (types.PeerID, ConnLimiter, error) {
+	other, err := knock.VerifyKnock(hs.id, knck)
+	if err != nil {
+		return types.PeerID{}, nil, err
+	}
+	limiter, err := hs.Limiter(*other)
+	if err != nil {
+		return types.PeerID{}, nil, err
+	}
+	return *other, limiter, nil
+}
+
+func (hs *testHandshaker) Limiter(other types.PeerID) (ConnLimiter, error) {
+	if _, ok := hs.known[other]; !ok {
+		return nil, errUnknownPeer{other}
+	}
+	return allowAllConnLimiter{}, nil
+}
+
+func (hs *testHandshaker) TLSConfig(other *types.PeerID) *tls.Config {
+	if other != nil {
+		return newTLSConfig(hs.cert, mtls.VerifyCertMatchesPubKey(*other))
+	}
+	return newTLSConfig(hs.cert, func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		pubKey, err := pubKeyFromRawCerts(rawCerts)
+		if err != nil {
+			return err
+		}
+		_, err = hs.Limiter(pubKey)
+		return err
+	})
+}
+
+type testTransport struct {
+	name          string
+	transport     Transport
+	listenAddress string
+}
+
+func testTransports() []testTransport {
+	return []testTransport{
+		{"InMemory", NewInMemoryTransport(), "listener"},
+		{"TCP", NewTCPTransport(), "127.0.0.1:0"},
+		{"QUIC", NewQUICTransport(), "127.0.0.1:0"},
+	}
+}
+
+// listen returns the listener and the address to dial it at.
+func (tt testTransport) listen(t *testing.T, handshaker Handshaker) (TransportListener, string) {
+	ln, err := tt.transport.Listen(tt.listenAddress, handshaker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	switch ln := ln.(type) {
+	case *tcpListener:
+		return ln, ln.ln.Addr().String()
+	case *quicListener:
+		return ln, ln.ln.Addr().String()
+	}
+	return ln, tt.listenAddress
+}
+
+func (tt testTransport) dialAndHandshake(address string, other types.PeerID, handshaker Handshaker) (AuthenticatedConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := tt.transport.Dial(ctx, address, other, handshaker)
+	if err != nil {
+		return nil, err
+	}
+	aconn, err := conn.Handshake(ctx)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return aconn, nil
+}
+
+type handshakeResult struct {
+	conn AuthenticatedConn
+	err  error
+}
+
+// serve handshakes all connections accepted by ln. The result of a failed
+// handshake is reported before the connection is closed.
+func serve(t *testing.T, ln TransportListener) <-chan handshakeResult {
+	chResults := make(chan handshakeResult, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				aconn, err := conn.Handshake(ctx)
+				if err == nil {
+					t.Cleanup(func() { _ = aconn.Close() })
+				}
+				chResults <- handshakeResult{aconn, err}
+				if err != nil {
+					_ = conn.Close()
+				}
+			}()
+		}
+	}()
+	return chResults
+}
+
+func TestTransportHandshake(t *testing.T) {
+	for _, tt := range testTransports() {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestHandshaker(t), newTestHandshaker(t)
+			server.know(client)
+			client.know(server)
+
+			ln, address := tt.listen(t, server)
+			chResults := serve(t, ln)
+
+			clientConn, err := tt.dialAndHandshake(address, server.id, client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clientConn.Close()
+			var serverConn AuthenticatedConn
+			select {
+			case result := <-chResults:
+				if result.err != nil {
+					t.Fatal(result.err)
+				}
+				serverConn = result.conn
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for incoming connection")
+			}
+
+			if clientConn.Other() != server.id || serverConn.Other() != client.id {
+				t.Fatalf("peers authenticated as %v and %v, expected %v and %v", clientConn.Other(), serverConn.Other(), server.id, client.id)
+			}
+			if clientConn.SupportsDataLanes() != (tt.name == "QUIC") || serverConn.SupportsDataLanes() != (tt.name == "QUIC") {
+				t.Errorf("data lanes supported: %v and %v", clientConn.SupportsDataLanes(), serverConn.SupportsDataLanes())
+			}
+
+			checkLane(t, clientConn.ControlLane(), serverConn.ControlLane(), "from client")
+			checkLane(t, serverConn.ControlLane(), clientConn.ControlLane(), "from server")
+
+			if tt.name == "QUIC" {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				sendLane, err := clientConn.OpenDataLane(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := sendLane.Write([]byte("data lane")); err != nil {
+					t.Fatal(err)
+				}
+				if err := sendLane.Close(); err != nil {
+					t.Fatal(err)
+				}
+				receiveLane, err := serverConn.AcceptDataLane(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(receiveLane)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != "data lane" {
+					t.Errorf("received %q on data lane", data)
+				}
+			}
+		})
+	}
+}
+
+func checkLane(t *testing.T, from Lane, to Lane, msg string) {
+	t.Helper()
+	go func() {
+		_, _ = from.Write([]byte(msg))
+	}()
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(to, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Errorf("received %q, expected %q", buf, msg)
+	}
+}
+
+// checkRejected checks that the dialer didn't end up with a working
+// connection and that the listener rejected the connection with an error
+// containing serverErr. If serverErr is empty, the listener must not have
+// accepted the connection at all.
+func checkRejected(t *testing.T, clientConn AuthenticatedConn, clientErr error, chResults <-chan handshakeResult, serverErr string) {
+	t.Helper()
+	if clientErr == nil {
+		// With TLS 1.3, the client completes its handshake before the server
+		// has verified the client's certificate. The rejection only shows
+		// once the server closes the connection.
+		chRead := make(chan error, 1)
+		go func() {
+			_, err := clientConn.ControlLane().Read(make([]byte, 1))
+			chRead <- err
+		}()
+		select {
+		case err := <-chRead:
+			if err == nil {
+				t.Error("dialer read from rejected connection")
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("rejected connection is still open")
+		}
+		_ = clientConn.Close()
+	}
+
+	// serve reports failed handshakes before closing the connection, so
+	// there's no need to wait.
+	select {
+	case result := <-chResults:
+		if result.err == nil {
+			t.Fatal("listener accepted connection")
+		}
+		if serverErr == "" || !strings.Contains(result.err.Error(), serverErr) {
+			t.Errorf("listener rejected connection with %q, expected %q", result.err, serverErr)
+		}
+	default:
+		if serverErr != "" {
+			t.Errorf("listener didn't reject connection, expected %q", serverErr)
+		}
+	}
+}
+
+func TestTransportRejectsUnknownPeer(t *testing.T) {
+	for _, tt := range testTransports() {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestHandshaker(t), newTestHandshaker(t)
+			client.know(server)
+
+			ln, address := tt.listen(t, server)
+			chResults := serve(t, ln)
+
+			clientConn, err := tt.dialAndHandshake(address, server.id, client)
+			serverErr := "unknown peer"
+			if tt.name == "QUIC" {
+				// rejected during the TLS handshake
+				serverErr = ""
+			}
+			checkRejected(t, clientConn, err, chResults, serverErr)
+		})
+	}
+}
+
+func TestTransportRejectsWrongPeer(t *testing.T) {
+	for _, tt := range testTransports() {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client, expected := newTestHandshaker(t), newTestHandshaker(t), newTestHandshaker(t)
+			server.know(client)
+			client.know(server, expected)
+
+			ln, address := tt.listen(t, server)
+			chResults := serve(t, ln)
+
+			// the dialer expects another peer at the address
+			clientConn, err := tt.dialAndHandshake(address, expected.id, client)
+			serverErr := knock.ErrInvalidSignature.Error()
+			if tt.name == "QUIC" {
+				// the dialer aborts the TLS handshake
+				serverErr = ""
+				if err == nil || !strings.Contains(err.Error(), "QUIC handshake") {
+					t.Errorf("dialer failed with %v, expected error during QUIC handshake", err)
+				}
+			}
+			checkRejected(t, clientConn, err, chResults, serverErr)
+		})
+	}
+}
+
+func TestTransportRejectsSelfDial(t *testing.T) {
+	for _, tt := range testTransports() {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestHandshaker(t)
+			// a Host doesn't know itself, this leaves only the knock check
+			server.know(server)
+
+			ln, address := tt.listen(t, server)
+			chResults := serve(t, ln)
+
+			clientConn, err := tt.dialAndHandshake(address, server.id, server)
+			checkRejected(t, clientConn, err, chResults, knock.ErrFromSelfDial.Error())
+		})
+	}
+}
+
+// A peer that the listener knows can't connect in the name of another peer by
+// replaying that peer's knock.
+func TestTransportRejectsReplayedKnock(t *testing.T) {
+	for _, tt := range testTransports() {
+		t.Run(tt.name, func(t *testing.T) {
+			server, victim, attacker := newTestHandshaker(t), newTestHandshaker(t), newTestHandshaker(t)
+			server.know(victim, attacker)
+			attacker.know(server)
+			attacker.knock = victim.Knock(server.id)
+
+			ln, address := tt.listen(t, server)
+			chResults := serve(t, ln)
+
+			clientConn, err := tt.dialAndHandshake(address, server.id, attacker)
+			serverErr := "TLS handshake"
+			if tt.name == "QUIC" {
+				// the TLS handshake precedes the knock
+				serverErr = "PeerID mismatch"
+			}
+			checkRejected(t, clientConn, err, chResults, serverErr)
+		})
+	}
+}
+
+func TestInMemoryTransport(t *testing.T) {
+	transport := NewInMemoryTransport()
+	server, client := newTestHandshaker(t), newTestHandshaker(t)
+
+	if _, err := transport.Dial(context.Background(), "nowhere", server.id, client); err == nil {
+		t.Error("dialed address without listener")
+	}
+
+	ln, err := transport.Listen("listener", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.Listen("listener", server); err == nil {
+		t.Error("listened twice on the same address")
+	}
+
+	// a dial blocks until the connection is accepted
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := transport.Dial(ctx, "listener", server.id, client); err == nil {
+		t.Error("dial succeeded without Accept")
+	}
+
+	if err := ln.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ln.Accept(); err == nil {
+		t.Error("closed listener accepted connection")
+	}
+	if _, err := transport.Dial(context.Background(), "listener", server.id, client); err == nil {
+		t.Error("dialed closed listener")
+	}
+	// the address can be reused
+	ln, err = transport.Listen("listener", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ln.Close()
+}
+
+// testDiscoverer returns the addresses it has been told about.
+type testDiscoverer struct {
+	mutex     sync.Mutex
+	addresses map[types.PeerID][]types.Address
+}
+
+func newTestDiscoverer() *testDiscoverer {
+	return &testDiscoverer{sync.Mutex{}, map[types.PeerID][]types.Address{}}
+}
+
+func (d *testDiscoverer) set(other types.PeerID, addresses ...types.Address) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.addresses[other] = addresses
+}
+
+func (d *testDiscoverer) Start(*Host, ed25519.PrivateKey, loghelper.LoggerWithContext) error {
+	return nil
+}
+
+func (d *testDiscoverer) Close() error {
+	return nil
+}
+
+func (d *testDiscoverer) FindPeer(other types.PeerID) ([]types.Address, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.addresses[other], nil
+}
+
+func newTestHost(t *testing.T, config HostConfig, listenAddress string, discoverer Discoverer) *Host {
+	_, secretKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DurationBetweenDials == 0 {
+		config.DurationBetweenDials = 100 * time.Millisecond
+	}
+	host, err := NewHost(config, secretKey, []string{listenAddress}, discoverer, nopLogger{}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := host.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = host.Close() })
+	return host
+}
+
+// freeAddress returns a localhost address with a port that is currently
+// unused for network, which is either "tcp" or "udp".
+func freeAddress(t *testing.T, network string) string {
+	var address string
+	switch network {
+	case "tcp":
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address = ln.Addr().String()
+		ln.Close()
+	case "udp":
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address = conn.LocalAddr().String()
+		conn.Close()
+	default:
+		t.Fatalf("unknown network %q", network)
+	}
+	return address
+}
+
+func newTestStream(t *testing.T, host *Host, other types.PeerID, name string) *Stream {
+	s, err := host.NewStream(other, name, 10, 10, 1000, TokenBucketParams{1000, 1000}, TokenBucketParams{1_000_000, 1_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// exchange sends msg from one Stream until it arrives at the other. Messages
+// are delivered on a best effort basis, e.g. a message is lost if it is in
+// flight while the connection is replaced, which is likely to happen while
+// both Hosts dial each other. Other messages received in the meantime are
+// discarded.
+func exchange(t *testing.T, from *Stream, to *Stream, msg string) {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	from.SendMessage([]byte(msg))
+	for {
+		select {
+		case received := <-to.ReceiveMessages():
+			if string(received) == msg {
+				return
+			}
+		case <-ticker.C:
+			from.SendMessage([]byte(msg))
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q on stream %q", msg, to.Name())
+		}
+	}
+}
+
+func TestHostsExchangeMessages(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		transport func() Transport
+		addresses func() (string, string)
+	}{
+		{
+			"InMemory",
+			func() Transport {
+				return NewInMemoryTransport()
+			},
+			func() (string, string) {
+				return "a", "b"
+			},
+		},
+		{
+			"TCP",
+			NewTCPTransport,
+			func() (string, string) {
+				return freeAddress(t, "tcp"), freeAddress(t, "tcp")
+			},
+		},
+		{
+			"QUIC",
+			NewQUICTransport,
+			func() (string, string) {
+				return freeAddress(t, "udp"), freeAddress(t, "udp")
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := tt.transport()
+			addressA, addressB := tt.addresses()
+			discoverer := newTestDiscoverer()
+			a := newTestHost(t, HostConfig{Transport: transport}, addressA, discoverer)
+			b := newTestHost(t, HostConfig{Transport: transport}, addressB, discoverer)
+			discoverer.set(a.ID(), types.Address(addressA))
+			discoverer.set(b.ID(), types.Address(addressB))
+
+			var streamsA, streamsB []*Stream
+			for _, name := range []string{"one", "two"} {
+				streamsA = append(streamsA, newTestStream(t, a, b.ID(), name))
+				streamsB = append(streamsB, newTestStream(t, b, a.ID(), name))
+			}
+			for i := range streamsA {
+				for j := 0; j < 3; j++ {
+					exchange(t, streamsA[i], streamsB[i], fmt.Sprintf("%v to b %v", streamsA[i].Name(), j))
+					exchange(t, streamsB[i], streamsA[i], fmt.Sprintf("%v to a %v", streamsB[i].Name(), j))
+				}
+			}
+		})
+	}
+}